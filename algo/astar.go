@@ -0,0 +1,20 @@
+package algo
+
+// AStar 使用 A* 算法寻找成本最低的路径
+// 加载图时预计算了地标 (ALT) 则使用地标下界作为启发函数，否则退化为 Dijkstra
+// 两者都不会高估剩余成本，因此结果与 Dijkstra 相同，只是搜索的节点更少
+// 叠加层新增了边时 (可能比原路网更快) 地标下界不再可采纳，同样退化为 Dijkstra
+func (g *Graph) AStar(startID, endID string, opts SearchOptions) PathResult {
+	if g.Landmarks == nil || len(g.Landmarks.IDs) == 0 || opts.Overlay.addsEdges() {
+		return g.search(startID, endID, opts, nil)
+	}
+
+	lm := g.Landmarks
+	end, ok := g.indexOf(endID)
+	if !ok {
+		return PathResult{Found: false}
+	}
+	return g.search(startID, endID, opts, func(node int32) float64 {
+		return lm.Heuristic(node, end)
+	})
+}
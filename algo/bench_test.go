@@ -0,0 +1,126 @@
+package algo_test
+
+import (
+	"flag"
+	"math/rand"
+	"sync"
+	"testing"
+	"traffic-system/algo"
+	"traffic-system/algo/gen"
+	"traffic-system/model"
+)
+
+// 路径搜索性能基准
+// 默认使用边长 30 的合成城市，-map 指定地图文件时使用真实地图：
+//
+//	go test ./algo -run '^$' -bench . -size 60
+//	go test ./algo -run '^$' -bench . -map ../map_data.json
+var (
+	benchMap   = flag.String("map", "", "基准使用的地图数据文件 (为空时使用合成城市)")
+	benchSize  = flag.Int("size", 30, "合成城市的街道网格边长")
+	benchPairs = flag.Int("pairs", 1000, "随机起终点数量")
+	benchSeed  = flag.Int64("seed", 1, "随机种子")
+)
+
+// pair 一组起终点
+type pair struct{ from, to string }
+
+var (
+	benchOnce  sync.Once
+	benchGraph *algo.Graph
+	benchQuery []pair
+)
+
+// benchSetup 构建基准使用的图，并用固定种子抽取起终点 (同一进程中只构建一次)
+func benchSetup(b *testing.B) (*algo.Graph, []pair) {
+	benchOnce.Do(func() {
+		var err error
+		if *benchMap != "" {
+			benchGraph, err = algo.LoadFromJSON(*benchMap)
+		} else {
+			cfg := gen.Sized(*benchSize)
+			cfg.Seed = *benchSeed
+			benchGraph, err = gen.Graph(cfg)
+		}
+		if err != nil || len(benchGraph.NodeList) == 0 {
+			return
+		}
+		r := rand.New(rand.NewSource(*benchSeed))
+		benchQuery = make([]pair, *benchPairs)
+		for i := range benchQuery {
+			benchQuery[i] = pair{
+				from: benchGraph.NodeList[r.Intn(len(benchGraph.NodeList))].ID,
+				to:   benchGraph.NodeList[r.Intn(len(benchGraph.NodeList))].ID,
+			}
+		}
+	})
+	if len(benchQuery) == 0 {
+		b.Fatal("构建基准路网失败")
+	}
+	return benchGraph, benchQuery
+}
+
+// benchOptions 基准使用的搜索选项 (步行 + 公交 + 地铁)
+func benchOptions() algo.SearchOptions {
+	return algo.SearchOptions{ModeMask: model.ParseModes([]string{"walk", "bus", "subway"})}
+}
+
+// runSearch 依次查询各组起终点，每次操作为一次查询
+func runSearch(b *testing.B, search func(g *algo.Graph, from, to string, opts algo.SearchOptions) algo.PathResult) {
+	g, pairs := benchSetup(b)
+	opts := benchOptions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		search(g, p.from, p.to, opts)
+	}
+}
+
+// runSearchParallel 与 runSearch 相同，但使用多个 goroutine 并发查询 (模拟高 QPS)
+func runSearchParallel(b *testing.B, search func(g *algo.Graph, from, to string, opts algo.SearchOptions) algo.PathResult) {
+	g, pairs := benchSetup(b)
+	opts := benchOptions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			p := pairs[i%len(pairs)]
+			search(g, p.from, p.to, opts)
+			i++
+		}
+	})
+}
+
+func dijkstra(g *algo.Graph, from, to string, opts algo.SearchOptions) algo.PathResult {
+	return g.DijkstraWithOptions(from, to, opts)
+}
+
+func aStar(g *algo.Graph, from, to string, opts algo.SearchOptions) algo.PathResult {
+	return g.AStar(from, to, opts)
+}
+
+func BenchmarkDijkstra(b *testing.B) { runSearch(b, dijkstra) }
+
+func BenchmarkAStar(b *testing.B) { runSearch(b, aStar) }
+
+func BenchmarkDijkstraParallel(b *testing.B) { runSearchParallel(b, dijkstra) }
+
+func BenchmarkAStarParallel(b *testing.B) { runSearchParallel(b, aStar) }
+
+// BenchmarkMatrix 10 x 10 的时间矩阵，每次操作为一次完整的矩阵查询
+func BenchmarkMatrix(b *testing.B) {
+	g, pairs := benchSetup(b)
+	var origins, destinations []string
+	for _, p := range pairs[:min(10, len(pairs))] {
+		origins = append(origins, p.from)
+		destinations = append(destinations, p.to)
+	}
+	opts := benchOptions()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Matrix(origins, destinations, opts)
+	}
+}
@@ -0,0 +1,132 @@
+package algo
+
+import (
+	"log/slog"
+	"time"
+	"traffic-system/model"
+	"traffic-system/tracing"
+	"traffic-system/utils"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 计算时间预算相关参数
+const (
+	// interruptCheckInterval 每扩展多少个节点检查一次是否超时或已取消 (避免每次都读取时钟)
+	interruptCheckInterval = 256
+
+	// ApproximateWeight 超时后近似搜索 (加权 A*) 的启发函数权重
+	// 权重越大越接近贪心搜索，扩展的节点越少，但路线可能越绕
+	ApproximateWeight = 4.0
+
+	// ApproximateShare 预算中留给近似搜索的比例：精确搜索只能用前 75%，超时后近似搜索用剩下的时间，
+	// 两次搜索加起来不超过 Budget
+	ApproximateShare = 0.25
+)
+
+// searchModes 近似搜索估算直线时间时考虑的交通方式
+var searchModes = []string{"walk", "bike", "car", "bus", "subway", "truck"}
+
+// runSearch 执行一次点到点搜索 (见 searchWithRetry)，Context 中有链路追踪时记录一个 algo.search span
+func (g *Graph) runSearch(start, end int32, opts SearchOptions, heuristic func(node int32) float64, ov *overlay) PathResult {
+	if !tracing.Active(opts.Context) {
+		return g.searchWithRetry(start, end, opts, heuristic, ov)
+	}
+	ctx, span := tracing.Start(opts.Context, "algo.search",
+		attribute.Int("search.mode_mask", opts.ModeMask),
+		attribute.Float64("search.detour_ratio", opts.DetourRatio),
+		attribute.Int64("search.budget_ms", opts.Budget.Milliseconds()),
+		attribute.Bool("search.overlay", ov != nil))
+	defer span.End()
+	opts.Context = ctx
+	result := g.searchWithRetry(start, end, opts, heuristic, ov)
+	span.SetAttributes(
+		attribute.Bool("search.found", result.Found),
+		attribute.Bool("search.timed_out", result.TimedOut),
+		attribute.Bool("search.cancelled", result.Cancelled),
+		attribute.Int("search.path_nodes", len(result.Path)),
+		attribute.Float64("search.eta", result.EstimatedTime))
+	return result
+}
+
+// searchWithRetry 执行一次点到点搜索：
+//   - 启用了椭圆剪枝但找不到路径时 (绕路比例设得太小)，退回不剪枝的完整搜索
+//   - 设置了 Budget 时，精确搜索用预算的前 (1 - ApproximateShare)，超时后改用加权 A* 在剩余的时间内找一条近似路径 (结果标记 TimedOut)
+//   - Context 取消后立即返回 (结果标记 Cancelled)，不再重试
+func (g *Graph) searchWithRetry(start, end int32, opts SearchOptions, heuristic func(node int32) float64, ov *overlay) PathResult {
+	if opts.Budget > 0 {
+		began := time.Now()
+		opts.budgetEnd = began.Add(opts.Budget)
+		opts.deadline = began.Add(time.Duration(float64(opts.Budget) * (1 - ApproximateShare)))
+	}
+	if opts.DetourRatio > 0 {
+		result := g.searchOnce(start, end, opts, heuristic, ov)
+		if result.Found || result.TimedOut || result.Cancelled {
+			return g.approximateIfTimedOut(result, start, end, opts, ov)
+		}
+		slog.Debug("椭圆剪枝后未找到路径，改为完整搜索", "detour_ratio", opts.DetourRatio)
+		addEvent(opts, "ellipse_fallback")
+		opts.DetourRatio = 0
+	}
+	return g.approximateIfTimedOut(g.searchOnce(start, end, opts, heuristic, ov), start, end, opts, ov)
+}
+
+// approximateIfTimedOut 精确搜索超时时用加权 A* 重新搜索 (不剪枝，截止时间为整个预算的结束时间)，否则原样返回
+func (g *Graph) approximateIfTimedOut(result PathResult, start, end int32, opts SearchOptions, ov *overlay) PathResult {
+	if !result.TimedOut {
+		return result
+	}
+	slog.Debug("精确搜索超时，改用近似搜索", "budget", opts.Budget)
+	addEvent(opts, "approximate_search")
+	opts.DetourRatio = 0
+	opts.deadline = opts.budgetEnd
+	approx := g.searchOnce(start, end, opts, g.approximateHeuristic(ov, end, opts), ov)
+	approx.TimedOut = true
+	return approx
+}
+
+// approximateHeuristic 近似搜索的启发函数：按所选方式中最慢的速度估算到终点的直线时间，再乘以 ApproximateWeight
+// 会高估剩余时间 (不是可采纳的)，搜索几乎直奔终点，只用于超时后尽快找到一条路径
+func (g *Graph) approximateHeuristic(ov *overlay, end int32, opts SearchOptions) func(node int32) float64 {
+	speed := 0.0
+	for _, mode := range model.FilterModesByMask(searchModes, opts.ModeMask) {
+		s := model.GetModeSpeed(mode)
+		if mode == "walk" && opts.WalkSpeed > 0 {
+			s = opts.WalkSpeed
+		}
+		if speed == 0 || s < speed {
+			speed = s
+		}
+	}
+	if speed == 0 {
+		speed = model.SpeedWalk
+	}
+	target := g.point(ov, end)
+	return func(node int32) float64 {
+		return ApproximateWeight * utils.HaversineDistance(g.point(ov, node), target) / speed
+	}
+}
+
+// interrupted 检查搜索是否应当中止 (每 interruptCheckInterval 个节点检查一次)：请求已取消，或超过了截止时间
+func (opts *SearchOptions) interrupted(settled int) (cancelled, timedOut bool) {
+	if settled%interruptCheckInterval != 0 {
+		return false, false
+	}
+	if opts.Cancelled() {
+		return true, false
+	}
+	return false, !opts.deadline.IsZero() && time.Now().After(opts.deadline)
+}
+
+// Cancelled 搜索所属的请求是否已取消 (客户端断开或服务关闭)，未设置 Context 时总是 false
+func (opts *SearchOptions) Cancelled() bool {
+	return opts.Context != nil && opts.Context.Err() != nil
+}
+
+// addEvent 在搜索的 span 上记录一个事件 (没有链路追踪时什么也不做)
+func addEvent(opts SearchOptions, name string) {
+	if opts.Context != nil {
+		trace.SpanFromContext(opts.Context).AddEvent(name)
+	}
+}
@@ -0,0 +1,167 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// GraphBuilder 在代码中逐步构建路网 (不依赖 JSON 文件或数据库)，用于测试和示例:
+//
+//	g, err := algo.NewGraphBuilder().
+//		AddNode(model.Node{ID: "a", Lat: 34.80, Lng: 113.50}).
+//		AddNode(model.Node{ID: "b", Lat: 34.81, Lng: 113.50}).
+//		AddEdge(model.Edge{From: "a", To: "b", Modes: []string{"walk"}}).
+//		Build()
+//
+// 边的处理与从数据库加载时相同：距离为 0 时按坐标计算，不是单行的步行/骑行/驾车边自动生成反向边。
+// 构建结果只取决于调用顺序，相同的调用总是得到相同的图 (包括地标)
+type GraphBuilder struct {
+	nodes     []model.Node
+	seen      map[string]bool
+	edges     []model.Edge
+	lines     []model.Line
+	aliases   []model.NodeAlias
+	landmarks int
+	shortcuts map[string]float64
+	errs      []error
+}
+
+// NewGraphBuilder 创建一个空的构建器 (默认预计算 DefaultLandmarkCount 个地标，不生成站点间的步行连接)
+func NewGraphBuilder() *GraphBuilder {
+	return &GraphBuilder{
+		seen:      make(map[string]bool),
+		landmarks: DefaultLandmarkCount,
+	}
+}
+
+// AddNode 加入一个节点，类型为空时视为 road_node
+func (b *GraphBuilder) AddNode(node model.Node) *GraphBuilder {
+	if node.ID == "" {
+		b.errs = append(b.errs, errors.New("节点 ID 不能为空"))
+		return b
+	}
+	if b.seen[node.ID] {
+		b.errs = append(b.errs, fmt.Errorf("节点 %s 重复", node.ID))
+		return b
+	}
+	if node.Type == "" {
+		node.Type = "road_node"
+	}
+	b.seen[node.ID] = true
+	b.nodes = append(b.nodes, node)
+	return b
+}
+
+// AddEdge 加入一条边，按 Oneway 自动生成反向边 (与数据库中的边相同)
+func (b *GraphBuilder) AddEdge(edge model.Edge) *GraphBuilder {
+	if !model.IsValidOneway(edge.Oneway) {
+		b.errs = append(b.errs, fmt.Errorf("边 %s -> %s 的 oneway 无效: %s", edge.From, edge.To, edge.Oneway))
+		return b
+	}
+	if !model.IsValidRoadClass(edge.RoadClass) {
+		b.errs = append(b.errs, fmt.Errorf("边 %s -> %s 的 road_class 无效: %s", edge.From, edge.To, edge.RoadClass))
+		return b
+	}
+	if !model.IsValidFootway(edge.Footway) {
+		b.errs = append(b.errs, fmt.Errorf("边 %s -> %s 的 footway 无效: %s", edge.From, edge.To, edge.Footway))
+		return b
+	}
+	if !model.IsValidConnector(edge.Connector) {
+		b.errs = append(b.errs, fmt.Errorf("边 %s -> %s 的 connector 无效: %s", edge.From, edge.To, edge.Connector))
+		return b
+	}
+	b.edges = append(b.edges, edge)
+	return b
+}
+
+// AddOneWay 加入一条所有交通方式都只能从 From 到 To 的有向边 (Oneway 为 yes，不生成反向边)
+func (b *GraphBuilder) AddOneWay(edge model.Edge) *GraphBuilder {
+	edge.Oneway = model.OnewayYes
+	return b.AddEdge(edge)
+}
+
+// AddLine 加入一条公交/地铁线路定义 (没有加入任何线路时根据边的 line_id 推导)
+func (b *GraphBuilder) AddLine(line model.Line) *GraphBuilder {
+	b.lines = append(b.lines, line)
+	return b
+}
+
+// AddAlias 为节点加入一个别名
+func (b *GraphBuilder) AddAlias(nodeID, alias string) *GraphBuilder {
+	b.aliases = append(b.aliases, model.NodeAlias{NodeID: nodeID, Alias: alias})
+	return b
+}
+
+// Landmarks 设置预计算的地标数量，0 表示不预计算 (A* 退回直线距离启发)
+func (b *GraphBuilder) Landmarks(count int) *GraphBuilder {
+	b.landmarks = count
+	return b
+}
+
+// WalkingShortcuts 像加载真实地图时一样，为相近的站点生成步行连接 (radius 为空时使用 WalkShortcutRadius)
+func (b *GraphBuilder) WalkingShortcuts(radius map[string]float64) *GraphBuilder {
+	if radius == nil {
+		radius = WalkShortcutRadius
+	}
+	b.shortcuts = radius
+	return b
+}
+
+// Build 校验并生成图 (已建立索引，可以直接用于搜索)，节点重复或边引用了不存在的节点时返回错误
+func (b *GraphBuilder) Build() (*Graph, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
+	g := NewGraph()
+	for _, node := range b.nodes {
+		g.AddNode(node)
+	}
+
+	edges := make([]model.Edge, len(b.edges))
+	for i, e := range b.edges {
+		from, to := g.Nodes[e.From], g.Nodes[e.To]
+		if from == nil || to == nil {
+			return nil, fmt.Errorf("边 %s -> %s 引用了不存在的节点", e.From, e.To)
+		}
+		if e.Dist == 0 {
+			e.Dist = utils.HaversineDistance(model.Point{Lat: from.Lat, Lng: from.Lng}, model.Point{Lat: to.Lat, Lng: to.Lng})
+		}
+		edges[i] = e
+
+		edge := e
+		g.AddEdge(&edge)
+		if reverse := reverseOf(&edge); reverse != nil {
+			g.AdjList[reverse.From] = append(g.AdjList[reverse.From], reverse)
+		}
+	}
+
+	if len(b.lines) > 0 {
+		g.SetLines(b.lines)
+	} else {
+		g.SetLines(model.DeriveLines(edges))
+	}
+	g.SetSpeedProfiles(model.DefaultSpeedProfiles())
+	g.SetAliases(b.aliases)
+	g.SetCategories(model.DefaultCategories())
+
+	if b.shortcuts != nil {
+		g.AddWalkingShortcuts(b.shortcuts)
+	}
+	g.BuildIndex()
+	if b.landmarks > 0 {
+		g.PrecomputeLandmarks(b.landmarks)
+	}
+	return g, nil
+}
+
+// MustBuild 同 Build，出错时 panic (用于固定的测试数据)
+func (b *GraphBuilder) MustBuild() *Graph {
+	g, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
@@ -0,0 +1,137 @@
+package algo_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"traffic-system/algo"
+	"traffic-system/fixture"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+func TestGraphBuilderReverseEdges(t *testing.T) {
+	g := algo.NewGraphBuilder().
+		AddNode(model.Node{ID: "a", Lat: 34.80, Lng: 113.50}).
+		AddNode(model.Node{ID: "b", Lat: 34.81, Lng: 113.50}).
+		AddNode(model.Node{ID: "c", Lat: 34.82, Lng: 113.50}).
+		AddEdge(model.Edge{From: "a", To: "b", Modes: []string{"walk"}}).
+		AddOneWay(model.Edge{From: "b", To: "c", Modes: []string{"walk"}}).
+		MustBuild()
+	opts := algo.SearchOptions{ModeMask: model.ModeWalk}
+
+	// 距离为 0 时按坐标计算
+	want := utils.HaversineDistance(model.Point{Lat: 34.80, Lng: 113.50}, model.Point{Lat: 34.81, Lng: 113.50})
+	r := g.DijkstraWithOptions("b", "a", opts)
+	if !r.Found || math.Abs(r.Distance-want) > 1e-6 {
+		t.Errorf("b -> a: Found = %v, 距离 = %.1f, 期望自动生成的反向边 (%.1f 米)", r.Found, r.Distance, want)
+	}
+	if r := g.DijkstraWithOptions("a", "c", opts); !r.Found {
+		t.Error("a -> c: 没有找到路径")
+	}
+	if r := g.DijkstraWithOptions("c", "b", opts); r.Found {
+		t.Errorf("c -> b: 单行边不应生成反向边，得到 %v", r.Path)
+	}
+}
+
+func TestGraphBuilderErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		b    *algo.GraphBuilder
+	}{
+		{"空 ID", algo.NewGraphBuilder().AddNode(model.Node{})},
+		{"节点重复", algo.NewGraphBuilder().AddNode(model.Node{ID: "a"}).AddNode(model.Node{ID: "a"})},
+		{"节点不存在", algo.NewGraphBuilder().AddNode(model.Node{ID: "a"}).AddEdge(model.Edge{From: "a", To: "z"})},
+		{"oneway 无效", algo.NewGraphBuilder().AddNode(model.Node{ID: "a"}).AddNode(model.Node{ID: "b"}).
+			AddEdge(model.Edge{From: "a", To: "b", Modes: []string{"walk"}, Oneway: "x"})},
+	}
+	for _, c := range cases {
+		if _, err := c.b.Build(); err == nil {
+			t.Errorf("%s: Build 没有返回错误", c.name)
+		}
+	}
+}
+
+func TestGraphBuilderDeterministic(t *testing.T) {
+	if a, b := fixture.Town().Version(), fixture.Town().Version(); a != b {
+		t.Errorf("两次构建的版本不同: %s, %s", a, b)
+	}
+}
+
+func TestOneWayFixture(t *testing.T) {
+	g := fixture.OneWay()
+	a, b, c := fixture.OneWayA, fixture.OneWayB, fixture.OneWayC
+	cases := []struct {
+		name     string
+		from, to string
+		mask     int
+		path     []string
+		distance float64
+	}{
+		{"驾车 A -> B 走单行道", a, b, model.ModeCar, []string{a, b}, 300},
+		{"驾车 B -> A 绕行", b, a, model.ModeCar, []string{b, c, a}, 800},
+		{"步行 A -> B 绕行", a, b, model.ModeWalk, []string{a, c, b}, 800},
+		{"步行 B -> A 绕行", b, a, model.ModeWalk, []string{b, c, a}, 800},
+	}
+	for _, tc := range cases {
+		opts := algo.SearchOptions{ModeMask: tc.mask}
+		for name, r := range map[string]algo.PathResult{
+			"Dijkstra": g.DijkstraWithOptions(tc.from, tc.to, opts),
+			"A*":       g.AStar(tc.from, tc.to, opts),
+		} {
+			if !slices.Equal(r.Path, tc.path) || math.Abs(r.Distance-tc.distance) > 1e-6 {
+				t.Errorf("%s (%s): 路径 = %v, 距离 = %.1f, 期望 %v, %.0f", tc.name, name, r.Path, r.Distance, tc.path, tc.distance)
+			}
+		}
+	}
+}
+
+func TestTownAStarMatchesDijkstra(t *testing.T) {
+	g := fixture.Town()
+	opts := algo.SearchOptions{ModeMask: model.ModeWalk}
+	for r1 := 0; r1 < 3; r1++ {
+		for c1 := 0; c1 < 3; c1++ {
+			for r2 := 0; r2 < 3; r2++ {
+				for c2 := 0; c2 < 3; c2++ {
+					from, to := fixture.TownNode(r1, c1), fixture.TownNode(r2, c2)
+					d := g.DijkstraWithOptions(from, to, opts)
+					a := g.AStar(from, to, opts)
+					// 棋盘路网中步行的最短距离为曼哈顿距离
+					want := float64(fixture.TownSpacing * (abs(r1-r2) + abs(c1-c2)))
+					if !d.Found || math.Abs(d.Distance-want) > 1e-6 {
+						t.Errorf("%s -> %s: Dijkstra 距离 = %.1f, 期望 %.0f", from, to, d.Distance, want)
+					}
+					if !a.Found || math.Abs(a.EstimatedTime-d.EstimatedTime) > 1e-6 {
+						t.Errorf("%s -> %s: A* 时间 = %.3f, Dijkstra 时间 = %.3f", from, to, a.EstimatedTime, d.EstimatedTime)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTownBusLine(t *testing.T) {
+	g := fixture.Town()
+	if line := g.Lines[fixture.TownBusLine]; line == nil || len(line.Stops) != 4 {
+		t.Fatalf("公交线路 %s 应有 4 个站点", fixture.TownBusLine)
+	}
+
+	from, to := fixture.TownStation, fixture.TownNode(1, 2)
+	want := []string{from, fixture.TownNode(1, 0), fixture.TownNode(1, 1), to}
+	r := g.AStar(from, to, algo.SearchOptions{ModeMask: model.ModeWalk | model.ModeBus})
+	if !slices.Equal(r.Path, want) || math.Abs(r.Distance-500) > 1e-6 {
+		t.Errorf("总站 -> 1-2: 路径 = %v, 距离 = %.1f, 期望 %v, 500", r.Path, r.Distance, want)
+	}
+
+	// 总站只能步行到达，不允许步行时无法出发
+	if r := g.AStar(from, to, algo.SearchOptions{ModeMask: model.ModeCar}); r.Found {
+		t.Errorf("驾车从总站出发不应找到路径，得到 %v", r.Path)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
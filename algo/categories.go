@@ -0,0 +1,88 @@
+package algo
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"traffic-system/db"
+	"traffic-system/model"
+)
+
+// maxCategoryDepth 分类层级上限 (防止数据中出现环)
+const maxCategoryDepth = 16
+
+// categoryIndex 分类索引
+type categoryIndex struct {
+	list []model.Category           // 按层级和 Sort 排序
+	byID map[string]*model.Category // 分类 ID -> 分类
+}
+
+// SetCategories 设置节点分类体系，可以在服务运行中调用
+func (g *Graph) SetCategories(categories []model.Category) {
+	index := &categoryIndex{
+		list: slices.Clone(categories),
+		byID: make(map[string]*model.Category, len(categories)),
+	}
+	slices.SortStableFunc(index.list, func(a, b model.Category) int {
+		return cmp.Or(cmp.Compare(a.Sort, b.Sort), cmp.Compare(a.ID, b.ID))
+	})
+	for i := range index.list {
+		index.byID[index.list[i].ID] = &index.list[i]
+	}
+	g.categories.Store(index)
+}
+
+// ReloadCategories 从数据库重新加载节点分类
+func (g *Graph) ReloadCategories() error {
+	var categories []model.Category
+	if err := db.DB.Find(&categories).Error; err != nil {
+		return err
+	}
+	g.SetCategories(categories)
+	return nil
+}
+
+// Categories 全部分类 (同级按 Sort 排序)
+func (g *Graph) Categories() []model.Category {
+	if index := g.categories.Load(); index != nil {
+		return index.list
+	}
+	return nil
+}
+
+// CategoryPath 分类从顶级到自身的 ID 路径，分类不存在时返回 nil
+func (g *Graph) CategoryPath(id string) []string {
+	index := g.categories.Load()
+	if index == nil {
+		return nil
+	}
+	var path []string
+	for c := index.byID[id]; c != nil && len(path) < maxCategoryDepth; c = index.byID[c.ParentID] {
+		path = append(path, c.ID)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// ResolveCategory 解析分类参数，可以是分类 ID ("subway_entrance") 或完整路径 ("transport/subway_entrance")
+// 返回分类 ID，分类不存在或路径与实际层级不符时返回 false
+func (g *Graph) ResolveCategory(s string) (string, bool) {
+	parts := strings.Split(strings.Trim(s, "/"), "/")
+	id := parts[len(parts)-1]
+	path := g.CategoryPath(id)
+	if path == nil {
+		return "", false
+	}
+	if len(parts) > 1 && !slices.Equal(parts, path) {
+		return "", false
+	}
+	return id, true
+}
+
+// InCategory 判断节点是否属于分类 category (或其下级分类)，category 为空时总是 true
+func (g *Graph) InCategory(node *model.Node, category string) bool {
+	if category == "" {
+		return true
+	}
+	return slices.Contains(g.CategoryPath(node.Type), category)
+}
@@ -0,0 +1,109 @@
+package algo
+
+import (
+	"time"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// ChargeStop 电动车路线中插入的充电站
+type ChargeStop struct {
+	NodeID       string
+	PathIndex    int     // 在路径节点序列中的位置
+	ArriveCharge float64 // 到达时的电量 (%)
+	DepartCharge float64 // 充电后的电量 (%)
+	ChargeTime   float64 // 充电时间 (秒)，已计入预计时间
+}
+
+// RouteWithCharging 电动车路线规划：剩余续航不足以到达终点时依次插入充电站
+// 每次在续航范围内、离终点更近的充电站中选择 (到站时间 + 充电时间 + 剩余直线时间) 最小的一个，
+// 充到 EVChargeTarget 后继续。返回的 EstimatedTime 包含充电时间，finalCharge 为到达终点时的电量 (%)
+// 无法到达终点 (续航不足且没有可用充电站) 时 Found 为 false
+func (g *Graph) RouteWithCharging(startID, endID string, opts SearchOptions, ev model.EV) (result PathResult, stops []ChargeStop, finalCharge float64) {
+	end := g.Nodes[endID]
+	if end == nil || g.Nodes[startID] == nil {
+		return PathResult{Found: false}, nil, 0
+	}
+	endPt := model.Point{Lat: end.Lat, Lng: end.Lng}
+
+	total := PathResult{Path: []string{startID}, Found: true}
+	cur, charge, elapsed := startID, ev.Charge, 0.0
+	visited := map[string]bool{startID: true}
+
+	for {
+		legOpts := opts
+		if !opts.DepartAt.IsZero() {
+			legOpts.DepartAt = opts.DepartAt.Add(time.Duration(elapsed * float64(time.Second)))
+		}
+
+		direct := g.AStar(cur, endID, legOpts)
+		if !direct.Found {
+			return PathResult{Found: false, TimedOut: direct.TimedOut, Cancelled: direct.Cancelled}, nil, 0
+		}
+		if driveDistance(direct) <= ev.RangeAt(charge) {
+			total = joinPaths(total, direct)
+			return total, stops, ev.ChargeAfter(charge, driveDistance(direct))
+		}
+		if len(stops) >= model.MaxEVChargeStops {
+			return PathResult{Found: false}, nil, 0
+		}
+
+		// 在续航范围内选择下一个充电站
+		curNode := g.Nodes[cur]
+		curDist := utils.HaversineDistance(model.Point{Lat: curNode.Lat, Lng: curNode.Lng}, endPt)
+		var best PathResult
+		var bestNode *model.Node
+		bestScore, bestCharge, bestTime := 0.0, 0.0, 0.0
+		for _, node := range g.Nodes {
+			if opts.Cancelled() {
+				return PathResult{Found: false, Cancelled: true}, nil, 0
+			}
+			if visited[node.ID] || !ev.CanChargeAt(node) {
+				continue
+			}
+			remain := utils.HaversineDistance(model.Point{Lat: node.Lat, Lng: node.Lng}, endPt)
+			if remain >= curDist {
+				continue // 只考虑离终点更近的充电站
+			}
+			leg := g.AStar(cur, node.ID, legOpts)
+			if !leg.Found || driveDistance(leg) > ev.RangeAt(charge) {
+				continue
+			}
+			arrive := ev.ChargeAfter(charge, driveDistance(leg))
+			chargeTime := ev.ChargeTime(arrive, model.EVChargeTarget*100, node.Power)
+			score := leg.EstimatedTime + chargeTime + remain/model.SpeedCar
+			if bestNode == nil || score < bestScore {
+				best, bestNode, bestScore = leg, node, score
+				bestCharge, bestTime = arrive, chargeTime
+			}
+		}
+		if bestNode == nil {
+			return PathResult{Found: false}, nil, 0
+		}
+
+		total = joinPaths(total, best)
+		total.EstimatedTime += bestTime
+		stops = append(stops, ChargeStop{
+			NodeID:       bestNode.ID,
+			PathIndex:    len(total.Path) - 1,
+			ArriveCharge: bestCharge,
+			DepartCharge: max(bestCharge, model.EVChargeTarget*100),
+			ChargeTime:   bestTime,
+		})
+		elapsed += best.EstimatedTime + bestTime
+		charge = max(bestCharge, model.EVChargeTarget*100)
+		cur = bestNode.ID
+		visited[cur] = true
+	}
+}
+
+// driveDistance 路径中驾车行驶的距离 (米)
+func driveDistance(result PathResult) float64 {
+	dist := 0.0
+	for _, seg := range result.Segments {
+		if model.IsDrivingMode(seg.UsedMode) {
+			dist += seg.Distance
+		}
+	}
+	return dist
+}
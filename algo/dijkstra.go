@@ -10,14 +10,17 @@ import (
 
 // PathSegment 路径段信息
 type PathSegment struct {
-	FromID   string   `json:"from_id"`
-	ToID     string   `json:"to_id"`
-	Distance float64  `json:"distance"`
-	Time     float64  `json:"time"`      // 预计时间 (秒)
-	Modes    []string `json:"modes"`     // 可用的交通方式
-	UsedMode string   `json:"used_mode"` // 实际使用的交通方式
-	LineID   string   `json:"line_id,omitempty"`
-	Desc     string   `json:"desc,omitempty"`
+	FromID    string   `json:"from_id"`
+	ToID      string   `json:"to_id"`
+	Distance  float64  `json:"distance"`
+	Time      float64  `json:"time"`      // 预计时间 (秒)
+	Modes     []string `json:"modes"`     // 可用的交通方式
+	UsedMode  string   `json:"used_mode"` // 实际使用的交通方式
+	LineID    string   `json:"line_id,omitempty"`
+	Desc      string   `json:"desc,omitempty"`
+	Fee       float64  `json:"fee,omitempty"`       // 驶入收费区域的费用 (元)
+	Footway   string   `json:"footway,omitempty"`   // 步行设施类型 (人行横道、天桥等)
+	Connector string   `json:"connector,omitempty"` // 垂直通道类型 (楼梯、扶梯、电梯)
 }
 
 // PathResult 路径规划结果
@@ -26,16 +29,22 @@ type PathResult struct {
 	Segments      []PathSegment // 路径段详情
 	Distance      float64       // 总距离 (米)
 	EstimatedTime float64       // 预计总时间 (秒)
+	Fee           float64       // 驶入收费区域的总费用 (元)
 	Found         bool          // 是否找到路径
+	TimedOut      bool          // 精确搜索超过了时间预算 (Found 时为近似路径)
+	Cancelled     bool          // 请求已取消，搜索中途放弃
 }
 
 // PriorityQueueItem 优先队列中的元素
 type PriorityQueueItem struct {
-	NodeID string
-	Cost   float64 // 时间成本 (秒)
-	Mode   string  // 到达该节点使用的交通方式
-	LineID string  // 到达该节点使用的线路ID
-	Index  int     // 在堆中的索引
+	Node     int32   // 节点下标
+	Cost     float64 // 时间成本 (秒)
+	Priority float64 // 出队优先级 (Dijkstra 等于 Cost，A* 为 Cost + 启发值)
+	Mode     string  // 到达该节点使用的交通方式
+	LineID   string  // 到达该节点使用的线路ID
+	Hops     int32   // 从起点经过的边数
+	Label    int32   // 按边搜索时的标签下标 (见 expandTurns)
+	Index    int     // 在堆中的索引
 }
 
 // PriorityQueue 实现 heap.Interface 接口的优先队列
@@ -43,8 +52,16 @@ type PriorityQueue []*PriorityQueueItem
 
 func (pq PriorityQueue) Len() int { return len(pq) }
 
+// Less 优先级相同时经过的边少的先出队，再相同时节点下标 (即节点 ID 顺序) 小的先出队，保证搜索顺序确定
 func (pq PriorityQueue) Less(i, j int) bool {
-	return pq[i].Cost < pq[j].Cost
+	a, b := pq[i], pq[j]
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	if a.Hops != b.Hops {
+		return a.Hops < b.Hops
+	}
+	return a.Node < b.Node
 }
 
 func (pq PriorityQueue) Swap(i, j int) {
@@ -72,137 +89,139 @@ func (pq *PriorityQueue) Pop() interface{} {
 
 // Dijkstra 使用 Dijkstra 算法寻找最短时间路径
 func (g *Graph) Dijkstra(startID, endID string, modeMask int) PathResult {
-	if g.Nodes[startID] == nil || g.Nodes[endID] == nil {
-		return PathResult{Found: false}
-	}
+	return g.DijkstraWithOptions(startID, endID, SearchOptions{ModeMask: modeMask})
+}
 
-	// 初始化时间成本、前驱和使用的边
-	timeCost := make(map[string]float64)
-	prev := make(map[string]string)
-	prevEdge := make(map[string]*model.Edge)
-	prevMode := make(map[string]string)   // 记录到达每个节点使用的交通方式
-	prevLineID := make(map[string]string) // 记录到达每个节点使用的线路ID
-	visited := make(map[string]bool)
+// DijkstraWithOptions 按照搜索参数 (交通方式、步行速度、避开快速路、少换乘) 寻找成本最低的路径
+func (g *Graph) DijkstraWithOptions(startID, endID string, opts SearchOptions) PathResult {
+	return g.search(startID, endID, opts, nil)
+}
 
-	for id := range g.Nodes {
-		timeCost[id] = math.Inf(1) // 无穷大
+// search 最短路径搜索的公共实现
+// heuristic 为空时即为 Dijkstra；不为空时为 A*，启发函数必须是可采纳的 (不高估剩余成本)
+func (g *Graph) search(startID, endID string, opts SearchOptions, heuristic func(node int32) float64) PathResult {
+	var ov *overlay
+	if opts.Overlay != nil {
+		ov = g.compile(opts.Overlay)
+	}
+	start, okStart := g.indexIn(ov, startID)
+	end, okEnd := g.indexIn(ov, endID)
+	if !okStart || !okEnd {
+		return PathResult{Found: false}
 	}
-	timeCost[startID] = 0
-
-	// 初始化优先队列
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-	heap.Push(&pq, &PriorityQueueItem{
-		NodeID: startID,
-		Cost:   0,
-		Mode:   "",
-		LineID: "",
-	})
 
-	// Dijkstra 主循环
-	for pq.Len() > 0 {
-		current := heap.Pop(&pq).(*PriorityQueueItem)
-		currentID := current.NodeID
+	return g.runSearch(start, end, opts, heuristic, ov)
+}
 
-		// 如果已访问过，跳过
-		if visited[currentID] {
-			continue
+// searchOnce 执行一次最短路径搜索 (节点均为整数下标)
+// ov 为本次查询临时加入的虚拟节点和边，可为空
+func (g *Graph) searchOnce(start, end int32, opts SearchOptions, heuristic func(node int32) float64, ov *overlay) PathResult {
+	// 驾车需要考虑转弯时按边搜索
+	if g.turnAware(opts) {
+		state := g.expandTurns(start, opts, ov, heuristic, g.ellipseFilter(ov, start, end, opts.DetourRatio), func(u int32) bool {
+			return u == end
+		})
+		if state.cancelled {
+			return PathResult{Found: false, Cancelled: true}
 		}
-		visited[currentID] = true
-
-		// 如果到达终点，提前退出
-		if currentID == endID {
-			break
+		if state.timedOut {
+			return PathResult{Found: false, TimedOut: true}
+		}
+		steps, ok := state.route(end)
+		if !ok {
+			return PathResult{Found: false}
 		}
+		return g.buildPath(ov, steps, opts)
+	}
 
-		// 遍历邻居
-		for _, edge := range g.GetNeighbors(currentID, modeMask) {
-			neighborID := edge.To
+	// 从池中取出搜索缓冲区 (成本、前驱和使用的边，按节点下标存放)
+	// 有虚拟节点时缓冲区大小不同，单独分配且不放回池中
+	var state *searchState
+	if ov == nil {
+		state = g.acquireState()
+		defer g.releaseState(state)
+	} else {
+		state = newSearchState(len(g.nodeIDs) + len(ov.ids))
+		state.overlay = ov
+	}
 
-			// 计算通过该边到达邻居的时间成本
-			availableModes := model.FilterModesByMask(edge.Modes, modeMask)
-			if len(availableModes) == 0 {
-				continue
-			}
+	// 到达终点后提前退出
+	g.expand(state, start, opts, heuristic, g.ellipseFilter(ov, start, end, opts.DetourRatio), func(u int32) bool {
+		return u == end
+	})
 
-			// 计算该边的时间成本，考虑换乘等待时间
-			edgeTime, usedMode := model.EstimateSegmentTime(
-				edge.Dist,
-				availableModes,
-				current.Mode,
-				current.LineID,
-				edge.LineID,
-			)
-
-			newCost := timeCost[currentID] + edgeTime
-
-			// 如果找到更快的路径
-			if newCost < timeCost[neighborID] {
-				timeCost[neighborID] = newCost
-				prev[neighborID] = currentID
-				prevEdge[neighborID] = edge
-				prevMode[neighborID] = usedMode
-				prevLineID[neighborID] = edge.LineID
-				heap.Push(&pq, &PriorityQueueItem{
-					NodeID: neighborID,
-					Cost:   newCost,
-					Mode:   usedMode,
-					LineID: edge.LineID,
-				})
-			}
-		}
+	// 请求已取消或超过了时间预算
+	if state.cancelled {
+		return PathResult{Found: false, Cancelled: true}
+	}
+	if state.timedOut {
+		return PathResult{Found: false, TimedOut: true}
 	}
 
 	// 如果没有找到路径
-	if timeCost[endID] == math.Inf(1) {
+	if math.IsInf(state.cost[end], 1) {
 		return PathResult{Found: false}
 	}
 
-	// 回溯路径和边
-	path := []string{}
-	for at := endID; at != ""; at = prev[at] {
-		path = append(path, at)
-		if at == startID {
+	// 回溯路径 (节点下标)
+	steps := []pathStep{}
+	for at := end; at != -1; at = state.prev[at] {
+		steps = append(steps, pathStep{node: at, edge: state.prevEdge[at], mode: state.prevMode[at], time: state.prevTime[at]})
+		if at == start {
 			break
 		}
 	}
-	slices.Reverse(path)
-	// 构建路径段信息
+	slices.Reverse(steps)
+	return g.buildPath(ov, steps, opts)
+}
+
+// pathStep 回溯得到的路径中的一个节点及到达它使用的边 (起点的边为空)
+type pathStep struct {
+	node int32
+	edge *model.Edge
+	mode string  // 到达该节点使用的交通方式
+	time float64 // 最后一段的预计时间
+}
+
+// buildPath 根据回溯得到的节点序列构建路径结果
+func (g *Graph) buildPath(ov *overlay, steps []pathStep, opts SearchOptions) PathResult {
+	// 构建路径段信息 (使用搜索时记录的交通方式和时间，保证与搜索结果一致)
 	var totalTime float64 = 0
 	var totalDist float64 = 0
+	var totalFee float64 = 0
+	zones := g.zones.Load()
+	path := make([]string, 0, len(steps))
 	segments := []PathSegment{}
-	currentMode := ""
-	currentLineID := ""
 
-	for i := 0; i < len(path)-1; i++ {
-		fromID := path[i]
-		toID := path[i+1]
-		edge := prevEdge[toID]
+	for i, step := range steps {
+		path = append(path, g.nodeID(ov, step.node))
+		if i == 0 {
+			continue
+		}
+		edge := step.edge
 		if edge != nil {
-			actualModes := model.FilterModesByMask(edge.Modes, modeMask)
-			segTime, usedMode := model.EstimateSegmentTime(
-				edge.Dist,
-				actualModes,
-				currentMode,
-				currentLineID,
-				edge.LineID,
-			)
+			segTime := step.time
+			fee := 0.0
+			if at, timed := opts.timeAt(totalTime); timed && model.IsDrivingMode(step.mode) {
+				fee = zones.fee(edge, opts.Vehicle, at)
+			}
 			totalTime += segTime
 			totalDist += edge.Dist
+			totalFee += fee
 
 			segments = append(segments, PathSegment{
-				FromID:   fromID,
-				ToID:     toID,
-				Distance: edge.Dist,
-				Time:     segTime,
-				Modes:    actualModes,
-				UsedMode: usedMode,
-				LineID:   edge.LineID,
-				Desc:     edge.Desc,
+				FromID:    g.nodeID(ov, steps[i-1].node),
+				ToID:      g.nodeID(ov, step.node),
+				Distance:  edge.Dist,
+				Time:      segTime,
+				Modes:     edge.AvailableModes(opts.ModeMask),
+				UsedMode:  step.mode,
+				LineID:    edge.LineID,
+				Desc:      edge.Desc,
+				Fee:       fee,
+				Footway:   edge.Footway,
+				Connector: edge.Connector,
 			})
-
-			currentMode = usedMode
-			currentLineID = edge.LineID
 		}
 	}
 
@@ -211,10 +230,149 @@ func (g *Graph) Dijkstra(startID, endID string, modeMask int) PathResult {
 		Segments:      segments,
 		Distance:      totalDist,
 		EstimatedTime: totalTime,
+		Fee:           totalFee,
 		Found:         true,
 	}
 }
 
+// expand 从起点开始按成本从低到高扩展节点，直到 done 返回 true 或队列为空
+// 搜索结果 (成本、前驱、到达时间等) 保存在 state 中
+func (g *Graph) expand(state *searchState, start int32, opts SearchOptions, heuristic func(node int32) float64, inSearchSpace func(node int32) bool, done func(u int32) bool) {
+	modeMask := opts.ModeMask
+	cost, prev, prevEdge := state.cost, state.prev, state.prevEdge
+	prevMode, prevTime, arrival, visited := state.prevMode, state.prevTime, state.arrival, state.visited
+	hops := state.hops
+
+	// 分时速度数据 (按到达节点的时刻选取)
+	speeds, profiles, zones := g.learned.Load(), g.profiles.Load(), g.zones.Load()
+
+	state.touch(start)
+	cost[start] = 0
+
+	// 初始化优先队列
+	pq := &state.pq
+	heap.Push(pq, &PriorityQueueItem{
+		Node:   start,
+		Cost:   0,
+		Mode:   "",
+		LineID: "",
+	})
+
+	// 主循环
+	settled := 0
+	for pq.Len() > 0 {
+		current := heap.Pop(pq).(*PriorityQueueItem)
+		u := current.Node
+
+		// 如果已访问过，跳过
+		if visited[u] {
+			continue
+		}
+		visited[u] = true
+
+		// 请求已取消或超过时间预算时放弃本次搜索
+		settled++
+		if cancelled, timedOut := opts.interrupted(settled); cancelled || timedOut {
+			state.cancelled, state.timedOut = cancelled, timedOut
+			return
+		}
+
+		if done(u) {
+			return
+		}
+
+		// 到达 u 的时刻决定其出边使用的分时速度
+		at, timed := opts.timeAt(arrival[u])
+
+		// 遍历邻居
+		for _, a := range g.arcs(state.overlay, u, modeMask) {
+			edge := a.edge
+			mask := modeMask
+			if edge.HasRestrictions() && !opts.Vehicle.Fits(edge) {
+				mask &^= model.ModeCar | model.ModeTruck // 车辆超高、超重或超宽
+			}
+			if timed && zones.forbids(edge, opts.Vehicle, at) {
+				mask &^= model.ModeCar | model.ModeTruck // 区域规则禁止驾车通过
+			}
+			if edge.ModeMask&mask == 0 {
+				continue
+			}
+			v := a.to
+			if inSearchSpace != nil && !inSearchSpace(v) {
+				continue
+			}
+
+			// 计算通过该边到达邻居的时间成本
+			availableModes := edge.AvailableModes(mask)
+			if len(availableModes) == 0 {
+				continue
+			}
+
+			// 计算该边的时间和搜索成本，考虑换乘等待时间和用户偏好
+			var learned model.ModeSpeeds
+			factor := 0.0
+			if timed {
+				learned, factor = opts.Speeds.lookup(edge, at.Hour()), profiles.factor(edge, at)
+				if learned == nil {
+					learned = speeds.lookup(edge, at.Hour())
+				}
+			}
+			edgeTime, edgeCost, usedMode := opts.edgeCost(edge, availableModes, current.Mode, current.LineID, learned, factor)
+			if d := g.verticalTime(u, v, edge); d > 0 {
+				edgeTime += d
+				edgeCost += d
+			}
+			if timed && model.IsDrivingMode(usedMode) {
+				edgeCost += zones.fee(edge, opts.Vehicle, at) * ZoneFeePenalty
+			}
+			if u != start {
+				d := g.signalDelay(u, usedMode)
+				edgeTime += d
+				edgeCost += d
+			}
+
+			newCost := cost[u] + edgeCost
+			newHops := hops[u] + 1
+
+			// 如果找到成本更低的路径 (成本相同时按 preferredTie 选择，结果不随读入顺序变化)
+			if newCost < cost[v]-costEpsilon || (!visited[v] && newCost <= cost[v]+costEpsilon && preferredTie(newHops, u, hops[v], prev[v])) {
+				state.touch(v)
+				cost[v] = newCost
+				hops[v] = newHops
+				prev[v] = u
+				prevEdge[v] = edge
+				prevMode[v] = usedMode
+				prevTime[v] = edgeTime
+				arrival[v] = arrival[u] + edgeTime
+				priority := newCost
+				if heuristic != nil {
+					priority += heuristic(v)
+				}
+				heap.Push(pq, &PriorityQueueItem{
+					Node:     v,
+					Cost:     newCost,
+					Priority: priority,
+					Mode:     usedMode,
+					LineID:   edge.LineID,
+					Hops:     newHops,
+				})
+			}
+		}
+	}
+}
+
+// costEpsilon 两条路径的成本相差不超过该值 (秒) 时视为相同 (忽略浮点累加误差)
+const costEpsilon = 1e-9
+
+// preferredTie 到达同一节点、成本相同的两条路径中，新路径 (经过 hops 条边、前驱为 u) 是否优于当前记录的路径
+// 经过的边少的优先，再相同时前驱节点下标 (即节点 ID 顺序) 小的优先
+func preferredTie(hops, u, curHops, curPrev int32) bool {
+	if hops != curHops {
+		return hops < curHops
+	}
+	return u < curPrev
+}
+
 // FormatPath 格式化路径结果为可读字符串
 func (g *Graph) FormatPath(result PathResult) string {
 	if !result.Found {
@@ -0,0 +1,206 @@
+package algo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"traffic-system/model"
+	"traffic-system/utils"
+	"unicode"
+)
+
+// 重复节点检测的默认参数
+const (
+	DefaultDuplicateRadius     = 30.0 // 距离上限 (米)
+	DefaultDuplicateSimilarity = 0.8  // 名称相似度下限 (0 ~ 1)
+)
+
+// DuplicateCluster 一组疑似重复的节点
+type DuplicateCluster struct {
+	Nodes       []*model.Node // 按建议保留的优先顺序排列 (第一个为建议保留的节点)
+	MaxDistance float64       // 组内节点间的最大直线距离 (米)
+}
+
+// FindDuplicates 查找疑似重复的节点：类型相同、直线距离不超过 radius 米、名称相似度不低于 similarity
+// 两个节点都有线路经过但没有共同的线路时视为不同的站台 (如路口两个方向的公交站)，不算重复
+// 满足条件的节点对按传递关系合并成组；组内连接边最多的节点排在最前面，作为建议保留的节点
+func (g *Graph) FindDuplicates(radius, similarity float64) []DuplicateCluster {
+	if radius <= 0 || len(g.NodeList) == 0 {
+		return nil
+	}
+
+	// 按 radius 大小的网格分桶，只比较相邻格子里的节点
+	type cell struct{ x, y int }
+	metersPerLng := 111320 * math.Cos(utils.DegreesToRadians(g.NodeList[0].Lat))
+	cellOf := func(n *model.Node) cell {
+		return cell{int(math.Floor(n.Lng * metersPerLng / radius)), int(math.Floor(n.Lat * 111320 / radius))}
+	}
+	grid := make(map[cell][]int)
+	nodes := make([]*model.Node, 0, len(g.NodeList))
+	names := make([][]rune, 0, len(g.NodeList))
+	for i := range g.NodeList {
+		node := g.Nodes[g.NodeList[i].ID]
+		if node == nil || slices.Contains(nodes, node) {
+			continue
+		}
+		grid[cellOf(node)] = append(grid[cellOf(node)], len(nodes))
+		nodes = append(nodes, node)
+		names = append(names, normalizeName(node.Name))
+	}
+
+	// 并查集，members 为每组的成员
+	parent := make([]int, len(nodes))
+	members := make([][]int, len(nodes))
+	for i := range parent {
+		parent[i] = i
+		members[i] = []int{i}
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	// union 合并两组，两组中有不同站台的节点时不合并
+	union := func(i, j int) {
+		ri, rj := find(i), find(j)
+		if ri == rj {
+			return
+		}
+		for _, x := range members[ri] {
+			for _, y := range members[rj] {
+				if g.separatePlatforms(nodes[x].ID, nodes[y].ID) {
+					return
+				}
+			}
+		}
+		parent[rj] = ri
+		members[ri] = append(members[ri], members[rj]...)
+		members[rj] = nil
+	}
+
+	// 先找出所有满足条件的节点对，再按相似度从高到低合并 (结果与节点顺序无关)
+	type pair struct {
+		i, j       int
+		similarity float64
+		distance   float64
+	}
+	var pairs []pair
+	for i, a := range nodes {
+		ca := cellOf(a)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[cell{ca.x + dx, ca.y + dy}] {
+					b := nodes[j]
+					if j <= i || a.Type != b.Type {
+						continue
+					}
+					dist := utils.HaversineDistance(model.Point{Lat: a.Lat, Lng: a.Lng}, model.Point{Lat: b.Lat, Lng: b.Lng})
+					if dist > radius {
+						continue
+					}
+					if s := nameSimilarity(names[i], names[j]); s >= similarity {
+						pairs = append(pairs, pair{i, j, s, dist})
+					}
+				}
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(a, b pair) int {
+		return cmp.Or(cmp.Compare(b.similarity, a.similarity), cmp.Compare(a.distance, b.distance),
+			cmp.Compare(a.i, b.i), cmp.Compare(a.j, b.j))
+	})
+	for _, p := range pairs {
+		union(p.i, p.j)
+	}
+
+	groups := make(map[int][]*model.Node)
+	for i, node := range nodes {
+		root := find(i)
+		groups[root] = append(groups[root], node)
+	}
+
+	var clusters []DuplicateCluster
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		slices.SortFunc(group, func(a, b *model.Node) int {
+			return cmp.Or(
+				cmp.Compare(len(g.AdjList[b.ID]), len(g.AdjList[a.ID])),
+				cmp.Compare(boolRank(a.NameEn == ""), boolRank(b.NameEn == "")),
+				cmp.Compare(a.ID, b.ID),
+			)
+		})
+		cluster := DuplicateCluster{Nodes: group}
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				d := utils.HaversineDistance(model.Point{Lat: a.Lat, Lng: a.Lng}, model.Point{Lat: b.Lat, Lng: b.Lng})
+				cluster.MaxDistance = max(cluster.MaxDistance, d)
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	slices.SortFunc(clusters, func(a, b DuplicateCluster) int {
+		return cmp.Compare(a.Nodes[0].ID, b.Nodes[0].ID)
+	})
+	return clusters
+}
+
+// separatePlatforms 两个节点都有线路经过且没有共同线路
+func (g *Graph) separatePlatforms(a, b string) bool {
+	linesA, linesB := g.NodeLines[a], g.NodeLines[b]
+	if len(linesA) == 0 || len(linesB) == 0 {
+		return false
+	}
+	for _, id := range linesA {
+		if slices.Contains(linesB, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeName 名称归一化：转为小写，去掉空白和标点
+func normalizeName(name string) []rune {
+	var result []rune
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// nameSimilarity 名称相似度 (0 ~ 1)：1 - 编辑距离 / 较长名称的长度
+func nameSimilarity(a, b []rune) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance 编辑距离 (Levenshtein)
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,173 @@
+package algo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"traffic-system/model"
+)
+
+// BoundingBox 经纬度范围
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// Contains 点是否在范围内 (含边界)
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// Extent 地图范围和概要 (加载后计算一次并缓存)
+type Extent struct {
+	BBox      BoundingBox   `json:"bbox"`
+	Center    model.Point   `json:"center"`
+	Hull      []model.Point `json:"hull"` // 所有节点的凸包 (逆时针)
+	NodeCount int           `json:"nodes"`
+	EdgeCount int           `json:"edges"`   // 有向边数量 (包括自动生成的反向边)
+	Version   string        `json:"version"` // 节点、边和线路内容的哈希
+}
+
+// Extent 获取地图范围，第一次调用时计算，BuildIndex 后重新计算
+func (g *Graph) Extent() *Extent {
+	if ext := g.extent.Load(); ext != nil {
+		return ext
+	}
+	ext := g.computeExtent()
+	g.extent.Store(ext)
+	return ext
+}
+
+// Version 地图版本 (节点、边和线路内容的哈希)，数据不变时版本不变
+func (g *Graph) Version() string {
+	return g.Extent().Version
+}
+
+// computeExtent 计算包围盒、凸包、数量和版本
+func (g *Graph) computeExtent() *Extent {
+	ext := &Extent{NodeCount: len(g.Nodes)}
+	points := make([]model.Point, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		points = append(points, model.Point{Lat: node.Lat, Lng: node.Lng})
+	}
+	for _, edges := range g.AdjList {
+		ext.EdgeCount += len(edges)
+	}
+
+	if len(points) > 0 {
+		bbox := BoundingBox{MinLat: points[0].Lat, MinLng: points[0].Lng, MaxLat: points[0].Lat, MaxLng: points[0].Lng}
+		for _, p := range points[1:] {
+			bbox.MinLat, bbox.MaxLat = min(bbox.MinLat, p.Lat), max(bbox.MaxLat, p.Lat)
+			bbox.MinLng, bbox.MaxLng = min(bbox.MinLng, p.Lng), max(bbox.MaxLng, p.Lng)
+		}
+		ext.BBox = bbox
+		ext.Center = model.Point{Lat: (bbox.MinLat + bbox.MaxLat) / 2, Lng: (bbox.MinLng + bbox.MaxLng) / 2}
+	}
+	ext.Hull = convexHull(points)
+	ext.Version = g.contentHash()
+	return ext
+}
+
+// convexHull 计算点集的凸包 (Andrew 单调链算法，经度为 x、纬度为 y，结果逆时针排列)
+func convexHull(points []model.Point) []model.Point {
+	pts := slices.Clone(points)
+	slices.SortFunc(pts, func(a, b model.Point) int {
+		if a.Lng != b.Lng {
+			if a.Lng < b.Lng {
+				return -1
+			}
+			return 1
+		}
+		if a.Lat < b.Lat {
+			return -1
+		}
+		if a.Lat > b.Lat {
+			return 1
+		}
+		return 0
+	})
+	pts = slices.Compact(pts)
+	if len(pts) < 3 {
+		return pts
+	}
+
+	cross := func(o, a, b model.Point) float64 {
+		return (a.Lng-o.Lng)*(b.Lat-o.Lat) - (a.Lat-o.Lat)*(b.Lng-o.Lng)
+	}
+	hull := make([]model.Point, 0, 2*len(pts))
+	// 下凸壳
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// 上凸壳
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		p := pts[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// contentHash 节点、边和线路内容的哈希 (排序后计算，与加载顺序无关)
+func (g *Graph) contentHash() string {
+	lines := make([]string, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		line := fmt.Sprintf("n|%s|%s|%s|%.7f|%.7f|%s", node.ID, node.Name, node.NameEn, node.Lat, node.Lng, node.Type)
+		// 后来增加的属性只在设置了时追加，没有使用这些属性的地图版本不变
+		if node.Signal {
+			line += "|signal"
+		}
+		if node.Importance != 0 {
+			line += fmt.Sprintf("|i%d", node.Importance)
+		}
+		if node.Level != 0 {
+			line += fmt.Sprintf("|l%d", node.Level)
+		}
+		lines = append(lines, line)
+	}
+	for from, edges := range g.AdjList {
+		for _, e := range edges {
+			line := fmt.Sprintf("e|%s|%s|%.1f|%s|%s|%d|%g|%g|%g|%t",
+				from, e.To, e.Dist, strings.Join(e.Modes, ","), e.LineID, e.ModeMask, e.MaxHeight, e.MaxWeight, e.MaxWidth, e.NoTrucks)
+			if e.RoadClass != "" {
+				line += "|" + e.RoadClass
+			}
+			if e.Footway != "" {
+				line += "|f" + e.Footway
+			}
+			if e.Level != 0 {
+				line += fmt.Sprintf("|l%d", e.Level)
+			}
+			if e.Connector != "" {
+				line += fmt.Sprintf("|c%s|%g", e.Connector, e.ConnectorSpeed)
+			}
+			lines = append(lines, line)
+		}
+	}
+	for _, line := range g.Lines {
+		stops := make([]string, len(line.Stops))
+		for i, stop := range line.Stops {
+			stops[i] = stop.NodeID
+		}
+		lines = append(lines, fmt.Sprintf("l|%s|%s|%s|%s|%d|%s|%s|%s",
+			line.ID, line.Name, line.Mode, line.Color, line.Headway, line.FirstTime, line.LastTime, strings.Join(stops, ",")))
+	}
+	slices.Sort(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -0,0 +1,388 @@
+package gen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"traffic-system/algo"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// 合成城市生成器
+// 生成棋盘街道 + 环路 + 地铁 + 公交的城市路网，用于压测、演示和性能基准，不需要真实数据。
+// 结果与 map_data.json 格式相同，可以直接导入数据库，也可以构建成图直接用于搜索；
+// 相同的配置 (含随机种子) 总是生成相同的城市
+
+// 城市中心 (郑州高新区附近)，与 fixture 使用的原点相同
+const (
+	originLat = 34.80
+	originLng = 113.50
+)
+
+// Config 合成城市的规模
+type Config struct {
+	Rows, Cols    int     // 街道网格的行数、列数 (路口数为 Rows x Cols)
+	Spacing       float64 // 相邻路口的距离 (米)
+	ArterialEvery int     // 每隔几条街是一条主干路 (其余为支路)，主干路相交的路口有信号灯
+	Rings         int     // 环路数 (以市中心为圆心的快速路，经连接线接入最近的路口)
+
+	SubwayLines    int // 地铁线路数 (穿过市中心、方向均匀分布，在市中心附近换乘)
+	SubwayStations int // 每条地铁线路的站数
+	BusRoutes      int // 公交线路数 (沿街道随机生成)
+	BusStops       int // 每条公交线路最多的站数 (每隔两个路口一站)
+
+	Seed int64 // 随机种子
+}
+
+// Sized 边长为 size 个路口的城市的默认配置，环路、地铁和公交线路数随规模增加
+func Sized(size int) Config {
+	return Config{
+		Rows:           size,
+		Cols:           size,
+		Spacing:        200,
+		ArterialEvery:  4,
+		Rings:          max(1, size/30),
+		SubwayLines:    max(1, size/15),
+		SubwayStations: max(2, size/4),
+		BusRoutes:      max(2, size/3),
+		BusStops:       max(2, min(20, size/3)),
+		Seed:           1,
+	}
+}
+
+// validate 检查配置
+func (cfg *Config) validate() error {
+	switch {
+	case cfg.Rows < 2 || cfg.Cols < 2:
+		return errors.New("街道网格至少为 2 x 2")
+	case cfg.Spacing <= 0:
+		return errors.New("路口间距必须大于 0")
+	case cfg.ArterialEvery < 1:
+		return errors.New("主干路间隔至少为 1")
+	case cfg.Rings < 0 || cfg.SubwayLines < 0 || cfg.BusRoutes < 0:
+		return errors.New("线路数不能为负数")
+	case cfg.SubwayLines > 0 && cfg.SubwayStations < 2:
+		return errors.New("每条地铁线路至少 2 个站")
+	case cfg.BusRoutes > 0 && cfg.BusStops < 2:
+		return errors.New("每条公交线路至少 2 个站")
+	}
+	return nil
+}
+
+// 地铁线路颜色 (按线路编号循环使用)
+var subwayColors = []string{"#E4002B", "#0072CE", "#00A651", "#F7A800", "#8E3A96", "#00A3AD", "#E86A10", "#6E6E6E"}
+
+// Generate 按配置生成合成城市
+func Generate(cfg Config) (*model.MapData, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c := &city{
+		cfg:      cfg,
+		r:        rand.New(rand.NewSource(cfg.Seed)),
+		points:   make(map[string]model.Point),
+		busStops: make(map[[2]int]string),
+		data: &model.MapData{
+			Meta: map[string]interface{}{
+				"description": fmt.Sprintf("合成城市: %d x %d 路口，%d 条环路，%d 条地铁，%d 条公交", cfg.Rows, cfg.Cols, cfg.Rings, cfg.SubwayLines, cfg.BusRoutes),
+				"generator":   "algo/gen",
+				"seed":        cfg.Seed,
+			},
+		},
+	}
+	c.streets()
+	for i := 1; i <= cfg.Rings; i++ {
+		c.ring(i)
+	}
+	for i := 0; i < cfg.SubwayLines; i++ {
+		c.subway(i)
+	}
+	for i := 0; i < cfg.BusRoutes; i++ {
+		c.bus(i)
+	}
+	for _, s := range c.stations {
+		c.data.Nodes = append(c.data.Nodes, *s)
+	}
+	return c.data, nil
+}
+
+// Graph 生成合成城市并构建成图 (已建立索引和地标，可以直接用于搜索)
+func Graph(cfg Config) (*algo.Graph, error) {
+	data, err := Generate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b := algo.NewGraphBuilder()
+	for _, node := range data.Nodes {
+		b.AddNode(node)
+	}
+	for _, edge := range data.Edges {
+		b.AddEdge(edge)
+	}
+	for _, line := range data.Lines {
+		b.AddLine(line)
+	}
+	return b.Build()
+}
+
+// WriteJSON 以 map_data.json 格式写出生成的城市
+func WriteJSON(w io.Writer, data *model.MapData) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// city 生成过程的状态
+type city struct {
+	cfg      Config
+	r        *rand.Rand
+	data     *model.MapData
+	points   map[string]model.Point // 节点 ID -> 坐标
+	stations []*model.Node          // 已生成的地铁站 (换乘站被多条线路共用，生成结束时加入节点)
+	busStops map[[2]int]string      // 路口 -> 公交站 ID (多条线路共用)
+}
+
+// addNode 添加一个节点
+func (c *city) addNode(node model.Node) {
+	c.points[node.ID] = model.Point{Lat: node.Lat, Lng: node.Lng}
+	c.data.Nodes = append(c.data.Nodes, node)
+}
+
+// streets 生成棋盘街道：每隔 ArterialEvery 条街一条主干路 (可以通行货车)，其余为支路
+func (c *city) streets() {
+	cfg := c.cfg
+	for r := 0; r < cfg.Rows; r++ {
+		for col := 0; col < cfg.Cols; col++ {
+			lat, lng := c.at(float64(r)*cfg.Spacing, float64(col)*cfg.Spacing)
+			node := model.Node{ID: crossID(r, col), Name: fmt.Sprintf("路口-%d-%d", r, col), Lat: lat, Lng: lng, Type: "road_node"}
+			if c.arterial(r) && c.arterial(col) {
+				node.Signal, node.Importance = true, 3
+			}
+			c.addNode(node)
+		}
+	}
+	for r := 0; r < cfg.Rows; r++ {
+		for col := 0; col < cfg.Cols; col++ {
+			if col+1 < cfg.Cols {
+				c.street(crossID(r, col), crossID(r, col+1), c.arterial(r))
+			}
+			if r+1 < cfg.Rows {
+				c.street(crossID(r, col), crossID(r+1, col), c.arterial(col))
+			}
+		}
+	}
+}
+
+// arterial 第 i 条街是否是主干路
+func (c *city) arterial(i int) bool {
+	return i%c.cfg.ArterialEvery == 0
+}
+
+// street 添加一段双向街道
+func (c *city) street(from, to string, arterial bool) {
+	edge := model.Edge{From: from, To: to, Dist: c.cfg.Spacing, Modes: []string{"walk", "bike", "car"}, RoadClass: model.RoadLocal}
+	if arterial {
+		edge.Modes = append(edge.Modes, "truck")
+		edge.RoadClass = model.RoadArterial
+	}
+	c.data.Edges = append(c.data.Edges, edge)
+}
+
+// ring 生成第 i 条环路：半径按网格大小均分，每隔约 3 个路口间距一个节点，每个节点经连接线接入最近的路口
+func (c *city) ring(i int) {
+	cfg := c.cfg
+	radius := float64(i) / float64(cfg.Rings+1) * float64(min(cfg.Rows, cfg.Cols)-1) * cfg.Spacing / 2
+	count := max(8, int(2*math.Pi*radius/(3*cfg.Spacing)))
+	cy, cx := c.center()
+
+	ids := make([]string, count)
+	for k := range count {
+		angle := 2 * math.Pi * float64(k) / float64(count)
+		north, east := cy+radius*math.Sin(angle), cx+radius*math.Cos(angle)
+		lat, lng := c.at(north, east)
+		ids[k] = fmt.Sprintf("ring_%d_%d", i, k)
+		c.addNode(model.Node{ID: ids[k], Name: fmt.Sprintf("路口-%d环-%d", i, k), Lat: lat, Lng: lng, Type: "road_node"})
+		c.link(ids[k], c.nearestCross(north, east), []string{"walk", "bike", "car", "truck"}, model.RoadCollector)
+	}
+	for k := range count {
+		c.link(ids[k], ids[(k+1)%count], []string{"car", "truck"}, model.RoadExpressway)
+	}
+}
+
+// subway 生成第 i 条地铁线路：穿过市中心的直线，站点均匀分布，与已有站点重合的位置作为换乘站
+func (c *city) subway(i int) {
+	cfg := c.cfg
+	angle := math.Pi * (float64(i) + 0.5) / float64(cfg.SubwayLines)
+	length := 0.8 * float64(min(cfg.Rows, cfg.Cols)-1) * cfg.Spacing
+	cy, cx := c.center()
+	name := fmt.Sprintf("%d号线", i+1)
+
+	// 第 (SubwayStations-1)/2 站在市中心，所有线路在这里换乘
+	step := length / float64(cfg.SubwayStations-1)
+	var stops []string
+	for k := range cfg.SubwayStations {
+		t := float64(k-(cfg.SubwayStations-1)/2) * step
+		north, east := cy+t*math.Sin(angle), cx+t*math.Cos(angle)
+		id := c.station(north, east, fmt.Sprintf("地铁站-%s-%d", name, k+1))
+		if len(stops) == 0 || stops[len(stops)-1] != id {
+			stops = append(stops, id)
+		}
+	}
+	if len(stops) >= 2 {
+		c.line(fmt.Sprintf("METRO_%d", i+1), name, "subway", subwayColors[i%len(subwayColors)], stops, nil)
+	}
+}
+
+// station 在 (north, east) 处的地铁站，附近已有站点时共用 (换乘站)，否则新建并用步行边连接最近的路口
+func (c *city) station(north, east float64, name string) string {
+	lat, lng := c.at(north, east)
+	for _, s := range c.stations {
+		if utils.HaversineDistance(c.points[s.ID], model.Point{Lat: lat, Lng: lng}) < c.cfg.Spacing/2 {
+			s.Importance = 5
+			return s.ID
+		}
+	}
+	node := &model.Node{ID: fmt.Sprintf("metro_%d", len(c.stations)+1), Name: name, Lat: lat, Lng: lng, Type: "subway_entrance", Importance: 4}
+	c.stations = append(c.stations, node)
+	c.points[node.ID] = model.Point{Lat: lat, Lng: lng}
+	c.link(node.ID, c.nearestCross(north, east), []string{"walk"}, "")
+	return node.ID
+}
+
+// bus 生成第 i 条公交线路：从随机路口出发沿街道行驶 (不走回头路，在路口有一定概率转弯)，每隔两个路口一站
+func (c *city) bus(i int) {
+	cfg := c.cfg
+	dirs := [][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	pos := [2]int{c.r.Intn(cfg.Rows), c.r.Intn(cfg.Cols)}
+	dir := c.r.Intn(len(dirs))
+	visited := map[[2]int]bool{pos: true}
+
+	stops := []string{c.busStop(pos)}
+	var dists []float64
+	steps := 0
+	for len(stops) < cfg.BusStops {
+		// 可以前进的方向：直行优先，其次左右转
+		var next [2]int
+		moved := false
+		order := []int{dir, (dir + 1) % 4, (dir + 3) % 4}
+		if c.r.Float64() < 0.25 {
+			order[0], order[1] = order[1], order[0]
+		}
+		for _, d := range order {
+			p := [2]int{pos[0] + dirs[d][0], pos[1] + dirs[d][1]}
+			if p[0] >= 0 && p[0] < cfg.Rows && p[1] >= 0 && p[1] < cfg.Cols && !visited[p] {
+				next, dir, moved = p, d, true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+		pos = next
+		visited[pos] = true
+		steps++
+		if steps%2 == 0 {
+			stops = append(stops, c.busStop(pos))
+			dists = append(dists, 2*cfg.Spacing)
+		}
+	}
+	if len(stops) < 2 {
+		return
+	}
+	c.line(fmt.Sprintf("BUS_%d", i+1), fmt.Sprintf("%d路", i+1), "bus", "", stops, dists)
+}
+
+// busStop 路口旁的公交站 (在路口东北方向 15 米，用步行边连接路口)
+func (c *city) busStop(pos [2]int) string {
+	if id, ok := c.busStops[pos]; ok {
+		return id
+	}
+	id := fmt.Sprintf("bus_%d_%d", pos[0], pos[1])
+	lat, lng := c.at(float64(pos[0])*c.cfg.Spacing+15, float64(pos[1])*c.cfg.Spacing+15)
+	c.addNode(model.Node{ID: id, Name: fmt.Sprintf("公交站-%d-%d", pos[0], pos[1]), Lat: lat, Lng: lng, Type: "bus_stop"})
+	c.link(id, crossID(pos[0], pos[1]), []string{"walk"}, "")
+	c.busStops[pos] = id
+	return id
+}
+
+// line 添加双向运营的线路：上行、下行分别为 id_up、id_down，dists 为相邻站点间的距离 (为空时按直线距离)
+func (c *city) line(id, name, mode, color string, stops []string, dists []float64) {
+	first, last := model.DefaultOperatingHours(mode)
+	for _, dir := range []string{"up", "down"} {
+		seq := stops
+		if dir == "down" {
+			seq = reversed(stops)
+		}
+		line := model.Line{
+			ID:        id + "_" + dir,
+			Name:      name,
+			Mode:      mode,
+			Color:     color,
+			Headway:   model.DefaultHeadway(mode),
+			FirstTime: first,
+			LastTime:  last,
+		}
+		for k, stop := range seq {
+			line.Stops = append(line.Stops, model.LineStop{Seq: k + 1, NodeID: stop})
+			if k == 0 {
+				continue
+			}
+			edge := model.Edge{From: seq[k-1], To: stop, Modes: []string{mode}, LineID: line.ID, Desc: name}
+			if dists != nil {
+				if dir == "up" {
+					edge.Dist = dists[k-1]
+				} else {
+					edge.Dist = dists[len(dists)-k]
+				}
+			} else {
+				edge.Dist = c.dist(seq[k-1], stop)
+			}
+			c.data.Edges = append(c.data.Edges, edge)
+		}
+		c.data.Lines = append(c.data.Lines, line)
+	}
+}
+
+// link 用一条双向的边连接两个节点，距离按直线计算 (至少 10 米)
+func (c *city) link(from, to string, modes []string, roadClass string) {
+	c.data.Edges = append(c.data.Edges, model.Edge{From: from, To: to, Dist: max(10, c.dist(from, to)), Modes: modes, RoadClass: roadClass})
+}
+
+// dist 两个已生成节点之间的直线距离
+func (c *city) dist(a, b string) float64 {
+	return utils.HaversineDistance(c.points[a], c.points[b])
+}
+
+// center 市中心相对西南角路口的位置 (向北、向东的米数)
+func (c *city) center() (north, east float64) {
+	return float64(c.cfg.Rows-1) * c.cfg.Spacing / 2, float64(c.cfg.Cols-1) * c.cfg.Spacing / 2
+}
+
+// nearestCross 离 (north, east) 最近的路口
+func (c *city) nearestCross(north, east float64) string {
+	r := min(max(int(math.Round(north/c.cfg.Spacing)), 0), c.cfg.Rows-1)
+	col := min(max(int(math.Round(east/c.cfg.Spacing)), 0), c.cfg.Cols-1)
+	return crossID(r, col)
+}
+
+// at 西南角路口向北 north 米、向东 east 米处的坐标 (市中心在原点，小范围平面近似)
+func (c *city) at(north, east float64) (lat, lng float64) {
+	cy, cx := c.center()
+	dLat := (north - cy) / utils.EarthRadius * 180 / math.Pi
+	dLng := (east - cx) / (utils.EarthRadius * math.Cos(utils.DegreesToRadians(originLat))) * 180 / math.Pi
+	return originLat + dLat, originLng + dLng
+}
+
+func crossID(r, c int) string { return fmt.Sprintf("x_%d_%d", r, c) }
+
+func reversed(ids []string) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[len(ids)-1-i] = id
+	}
+	return out
+}
@@ -3,8 +3,10 @@ package algo
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
 	"traffic-system/db" // 引入数据库包
 	"traffic-system/model"
 	"traffic-system/utils"
@@ -15,24 +17,68 @@ type Graph struct {
 	Nodes    map[string]*model.Node   // 节点字典 (ID -> Node)
 	AdjList  map[string][]*model.Edge // 邻接表 (ID -> 边列表)
 	NodeList []model.Node             // 节点列表 (用于遍历)
+
+	Landmarks *Landmarks // ALT 启发函数的预计算数据 (可为空)
+
+	Lines     map[string]*model.Line // 线路字典 (ID -> Line，站点已按顺序排列)
+	NodeLines map[string][]string    // 节点 ID -> 经过该节点的线路 ID
+
+	// 以下为搜索使用的整数下标索引，由 BuildIndex 生成
+	nodeIndex map[string]int32 // 节点 ID -> 下标
+	nodeIDs   []string         // 下标 -> 节点 ID
+	points    []model.Point    // 下标 -> 坐标
+	signals   []bool           // 下标 -> 是否是信号灯路口
+	levels    []int            // 下标 -> 楼层
+	adj       [][]arc          // 下标 -> 出边
+	byMode    *modeAdjacency   // 按交通方式组合过滤后的出边 (按需生成)
+
+	statePool  sync.Pool                       // 复用的搜索缓冲区 (*searchState)
+	learned    atomic.Pointer[SpeedTable]      // 学习到的路段分时速度 (可为空)
+	profiles   atomic.Pointer[profileTable]    // 道路等级的分时速度系数 (可为空)
+	zones      atomic.Pointer[zoneIndex]       // 驾车区域规则 (可为空)
+	turns      atomic.Pointer[turnIndex]       // 转弯规则 (可为空)
+	extent     atomic.Pointer[Extent]          // 地图范围和版本 (第一次使用时计算)
+	aliases    atomic.Pointer[aliasIndex]      // 节点别名 (可为空)
+	popularity atomic.Pointer[popularityTable] // 节点热度 (可为空)
+	categories atomic.Pointer[categoryIndex]   // 节点分类 (可为空)
 }
 
 // NewGraph 创建一个空的图
 func NewGraph() *Graph {
 	return &Graph{
-		Nodes:   make(map[string]*model.Node),
-		AdjList: make(map[string][]*model.Edge),
+		Nodes:     make(map[string]*model.Node),
+		AdjList:   make(map[string][]*model.Edge),
+		Lines:     make(map[string]*model.Line),
+		NodeLines: make(map[string][]string),
 	}
 }
 
+// AddNode 向图中添加一个节点
+func (g *Graph) AddNode(node model.Node) {
+	g.Nodes[node.ID] = &node
+	g.NodeList = append(g.NodeList, node)
+}
+
+// AddEdge 向图中添加一条有向边 (会自动计算 ModeMask，不生成反向边)
+func (g *Graph) AddEdge(edge *model.Edge) {
+	edge.ModeMask = edge.EdgeModeMask()
+	g.AdjList[edge.From] = append(g.AdjList[edge.From], edge)
+}
+
 // LoadFromDB 从数据库加载数据构建图 (新增函数)
 func LoadFromDB() (*Graph, error) {
+	return LoadFromRepository(db.Repo)
+}
+
+// LoadFromRepository 从存储加载节点、边和线路构建图
+// 分时速度系数、区域规则、别名和分类从 db.DB 读取；db.DB 为空时 (如只使用内存存储) 使用默认值，
+// 别名取自内存存储中的地图数据
+func LoadFromRepository(repo db.Repository) (*Graph, error) {
 	g := NewGraph()
 
-	// 1. 从数据库查询所有节点
-	var dbNodes []model.Node
-	// 使用 db.DB 直接查询
-	if err := db.DB.Find(&dbNodes).Error; err != nil {
+	// 1. 查询所有节点
+	dbNodes, err := repo.Nodes()
+	if err != nil {
 		return nil, fmt.Errorf("查询节点失败: %w", err)
 	}
 
@@ -44,43 +90,114 @@ func LoadFromDB() (*Graph, error) {
 		g.NodeList = append(g.NodeList, node)
 	}
 
-	// 2. 从数据库查询所有边
-	var dbEdges []model.Edge
-	if err := db.DB.Find(&dbEdges).Error; err != nil {
+	// 2. 按批读取所有边并填入邻接表 (不一次把整张表读入内存)
+	edgeCount := 0
+	err = repo.EachEdgeBatch(EdgeLoadBatchSize, func(edges []model.Edge) error {
+		edgeCount += len(edges)
+		for i := range edges {
+			g.addDBEdge(&edges[i])
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("查询边失败: %w", err)
 	}
 
-	// 将边填入邻接表
-	for i := range dbEdges {
-		edge := &dbEdges[i]
+	// 3. 查询线路及其站点
+	dbLines, err := repo.Lines()
+	if err != nil {
+		return nil, fmt.Errorf("查询线路失败: %w", err)
+	}
+	g.SetLines(dbLines)
 
-		// 重新计算 ModeMask (因为数据库只存了字符串数组 ["walk", "car"])
-		edge.ModeMask = model.ParseModes(edge.Modes)
+	if db.DB != nil {
+		// 查询分时速度系数
+		var profiles []model.SpeedProfile
+		if err := db.DB.Find(&profiles).Error; err != nil {
+			return nil, fmt.Errorf("查询分时速度系数失败: %w", err)
+		}
+		g.SetSpeedProfiles(profiles)
 
-		// 加入邻接表
-		g.AdjList[edge.From] = append(g.AdjList[edge.From], edge)
+		// 查询驾车区域规则 (禁行、收费、限行)
+		if _, err := g.ReloadZones(); err != nil {
+			return nil, err
+		}
 
-		// 3. 处理双向道路 (自动生成反向边)
-		// 逻辑：如果支持 walk/bike/car，则认为是双向的，自动加一条反向边到内存
-		bidirectionalMask := model.ModeWalk | model.ModeBike | model.ModeCar
-		if edge.ModeMask&bidirectionalMask != 0 {
-			// 创建反向边 (仅在内存中存在，不写回数据库)
-			reverseEdge := &model.Edge{
-				From:     edge.To,
-				To:       edge.From,
-				Dist:     edge.Dist,
-				Modes:    getBidirectionalModes(edge.Modes),
-				ModeMask: edge.ModeMask & bidirectionalMask,
-				Desc:     edge.Desc + " (反向)",
-			}
-			g.AdjList[edge.To] = append(g.AdjList[edge.To], reverseEdge)
+		// 查询路口转弯规则
+		if _, err := g.ReloadTurnRestrictions(); err != nil {
+			return nil, err
+		}
+
+		// 查询节点别名和分类
+		if _, err := g.ReloadAliases(); err != nil {
+			return nil, fmt.Errorf("查询节点别名失败: %w", err)
+		}
+		if err := g.ReloadCategories(); err != nil {
+			return nil, fmt.Errorf("查询节点分类失败: %w", err)
 		}
+	} else {
+		g.SetSpeedProfiles(model.DefaultSpeedProfiles())
+		g.SetCategories(model.DefaultCategories())
+		if mem, ok := repo.(*db.MemoryRepository); ok {
+			g.SetAliases(mem.Aliases())
+		}
+	}
+
+	// 4. 为相近的站点生成步行连接，建立整数下标索引并预计算 ALT 地标
+	if n := g.AddWalkingShortcuts(WalkShortcutRadius); n > 0 {
+		slog.Info("自动生成了步行连接边", "edges", n)
 	}
+	g.BuildIndex()
+	g.PrecomputeLandmarks(DefaultLandmarkCount)
 
-	log.Printf("成功从数据库加载图: %d 个节点, %d 条基础边", len(g.Nodes), len(dbEdges))
+	slog.Info("成功从存储加载图", "nodes", len(g.Nodes), "edges", edgeCount)
 	return g, nil
 }
 
+// EdgeLoadBatchSize 从数据库加载边时每批读取的数量
+const EdgeLoadBatchSize = 5000
+
+// addDBEdge 把数据库中的一条边加入邻接表，不是单行的步行/骑行/驾车边同时生成反向边
+func (g *Graph) addDBEdge(edge *model.Edge) {
+	// 重新计算 ModeMask (因为数据库只存了字符串数组 ["walk", "car"])
+	edge.ModeMask = edge.EdgeModeMask()
+
+	// 加入邻接表
+	g.AdjList[edge.From] = append(g.AdjList[edge.From], edge)
+
+	// 处理双向道路 (自动生成反向边)
+	if reverseEdge := reverseOf(edge); reverseEdge != nil {
+		g.AdjList[edge.To] = append(g.AdjList[edge.To], reverseEdge)
+	}
+}
+
+// reverseOf 按边的 Oneway 生成反向边 (仅在内存中存在，不写回数据库)，不能反向通行时返回 nil
+// 反向边只包含可以反向通行的交通方式 (如 vehicle 单行道的反向边只能步行)
+func reverseOf(edge *model.Edge) *model.Edge {
+	mask := edge.ReverseModeMask()
+	if mask == 0 {
+		return nil
+	}
+	return &model.Edge{
+		From:      edge.To,
+		To:        edge.From,
+		Dist:      edge.Dist,
+		Modes:     getBidirectionalModes(edge.Modes, mask),
+		ModeMask:  mask,
+		Desc:      edge.Desc + " (反向)",
+		MaxHeight: edge.MaxHeight,
+		MaxWeight: edge.MaxWeight,
+		MaxWidth:  edge.MaxWidth,
+		NoTrucks:  edge.NoTrucks,
+		RoadClass: edge.RoadClass,
+		Footway:   edge.Footway,
+		Level:     edge.Level,
+
+		Connector:      edge.Connector,
+		ConnectorSpeed: edge.ConnectorSpeed,
+	}
+}
+
 // LoadFromJSON 保留旧方法作为备份 (可选)
 func LoadFromJSON(filepath string) (*Graph, error) {
 	file, err := os.ReadFile(filepath)
@@ -103,7 +220,7 @@ func LoadFromJSON(filepath string) (*Graph, error) {
 
 	for i := range data.Edges {
 		edge := &data.Edges[i]
-		edge.ModeMask = model.ParseModes(edge.Modes)
+		edge.ModeMask = edge.EdgeModeMask()
 
 		if edge.Dist == 0 {
 			from := g.Nodes[edge.From]
@@ -117,8 +234,7 @@ func LoadFromJSON(filepath string) (*Graph, error) {
 
 		g.AdjList[edge.From] = append(g.AdjList[edge.From], edge)
 
-		bidirectionalMask := model.ModeWalk | model.ModeBike | model.ModeCar
-		if edge.ModeMask&bidirectionalMask != 0 {
+		if reverseEdge := reverseOf(edge); reverseEdge != nil {
 			reverseExists := false
 			for _, existingEdge := range g.AdjList[edge.To] {
 				if existingEdge.From == edge.To && existingEdge.To == edge.From {
@@ -127,31 +243,25 @@ func LoadFromJSON(filepath string) (*Graph, error) {
 				}
 			}
 			if !reverseExists {
-				reverseEdge := &model.Edge{
-					From:     edge.To,
-					To:       edge.From,
-					Dist:     edge.Dist,
-					Modes:    getBidirectionalModes(edge.Modes),
-					ModeMask: edge.ModeMask & bidirectionalMask,
-					Desc:     edge.Desc + " (反向)",
-				}
 				g.AdjList[edge.To] = append(g.AdjList[edge.To], reverseEdge)
 			}
 		}
 	}
 
-	return g, nil
-}
-
-// GetNeighbors 获取指定节点在特定交通方式下的邻居边
-func (g *Graph) GetNeighbors(nodeID string, modeMask int) []*model.Edge {
-	var validEdges []*model.Edge
-	for _, edge := range g.AdjList[nodeID] {
-		if edge.ModeMask&modeMask != 0 {
-			validEdges = append(validEdges, edge)
-		}
+	// 没有线路定义时根据边的 line_id 推导
+	if len(data.Lines) > 0 {
+		g.SetLines(data.Lines)
+	} else {
+		g.SetLines(model.DeriveLines(data.Edges))
 	}
-	return validEdges
+	g.SetSpeedProfiles(model.DefaultSpeedProfiles())
+	g.SetAliases(data.Aliases)
+	g.SetCategories(model.DefaultCategories())
+
+	g.AddWalkingShortcuts(WalkShortcutRadius)
+	g.BuildIndex()
+	g.PrecomputeLandmarks(DefaultLandmarkCount)
+	return g, nil
 }
 
 // FindNearestNode 找到离给定坐标最近的节点
@@ -173,11 +283,11 @@ func (g *Graph) FindNearestNode(lat, lng float64) *model.Node {
 	return nearest
 }
 
-// getBidirectionalModes 辅助函数：提取双向模式
-func getBidirectionalModes(modes []string) []string {
+// getBidirectionalModes 辅助函数：提取 mask 中可以反向通行的模式
+func getBidirectionalModes(modes []string, mask int) []string {
 	bidirectional := []string{}
 	for _, m := range modes {
-		if m == "walk" || m == "bike" || m == "car" {
+		if model.GetModeMask(m)&mask != 0 {
 			bidirectional = append(bidirectional, m)
 		}
 	}
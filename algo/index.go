@@ -0,0 +1,146 @@
+package algo
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"traffic-system/model"
+)
+
+// 图的整数下标索引
+//
+// 对外 (handler、JSON) 仍然使用字符串节点 ID；搜索内部把节点 ID 映射为连续的 int32 下标，
+// 成本、前驱等状态都用切片保存，避免每次查询创建多个 map[string]... 带来的大量内存分配。
+
+// arc 紧凑邻接表中的一条边 (目标节点使用整数下标)
+type arc struct {
+	to   int32
+	edge *model.Edge
+}
+
+// BuildIndex 根据 NodeList 和 AdjList 建立整数下标索引
+// 加载函数会自动调用；手动修改节点或边之后需要重新调用
+//
+// 下标按节点 ID 的字典序分配，每个节点的出边按目标下标排序 (同一目标的多条边保持邻接表中的顺序)，
+// 因此无论节点和边以什么顺序从数据库、JSON 或快照读入，得到的索引都相同，搜索在成本相同时的选择也相同
+func (g *Graph) BuildIndex() {
+	n := len(g.NodeList)
+	g.nodeIndex = make(map[string]int32, n)
+	g.nodeIDs = make([]string, 0, n)
+	g.points = make([]model.Point, 0, n)
+	g.signals = make([]bool, 0, n)
+	g.levels = make([]int, 0, n)
+
+	ids := make([]string, 0, n)
+	for _, node := range g.NodeList {
+		ids = append(ids, node.ID)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	for _, id := range ids {
+		node := g.Nodes[id]
+		g.nodeIndex[id] = int32(len(g.nodeIDs))
+		g.nodeIDs = append(g.nodeIDs, id)
+		g.points = append(g.points, model.Point{Lat: node.Lat, Lng: node.Lng})
+		g.signals = append(g.signals, node.Signal)
+		g.levels = append(g.levels, node.Level)
+	}
+
+	g.adj = make([][]arc, len(g.nodeIDs))
+	for fromID, edges := range g.AdjList {
+		from, ok := g.nodeIndex[fromID]
+		if !ok {
+			continue
+		}
+		arcs := make([]arc, 0, len(edges))
+		for _, edge := range edges {
+			if to, ok := g.nodeIndex[edge.To]; ok {
+				arcs = append(arcs, arc{to: to, edge: edge})
+			}
+		}
+		slices.SortStableFunc(arcs, func(a, b arc) int { return cmp.Compare(a.to, b.to) })
+		g.adj[from] = arcs
+	}
+	g.byMode = &modeAdjacency{}
+	g.WarmUp(WarmModeMasks...)
+
+	// 节点或边可能变化，地图范围和版本需要重新计算
+	g.extent.Store(nil)
+}
+
+// WarmModeMasks 建立索引时预先生成邻接表的交通方式组合 (仅步行、仅驾车、步行 + 公共交通)
+var WarmModeMasks = []int{
+	model.ModeWalk,
+	model.ModeCar,
+	model.ModeWalk | model.ModeBus | model.ModeSubway,
+}
+
+// modeMaskCount 交通方式组合的数量 (6 种方式的全部组合)
+const modeMaskCount = 1 << 6
+
+// modeAdjacency 按交通方式组合过滤后的邻接表，每种组合在第一次使用时生成，之后不再修改，可以被并发的查询共享
+type modeAdjacency struct {
+	once  [modeMaskCount]sync.Once
+	arcs  [modeMaskCount][][]arc         // 下标 -> 至少支持组合中一种方式的出边
+	edges [modeMaskCount][][]*model.Edge // 下标 -> 同上 (GetNeighbors 使用，包括终点不在索引中的边)
+}
+
+// WarmUp 预先生成指定交通方式组合的邻接表 (可以与查询并发调用，已生成的组合直接跳过)
+func (g *Graph) WarmUp(masks ...int) {
+	for _, mask := range masks {
+		g.modeLists(mask)
+	}
+}
+
+// modeLists 获取交通方式组合对应的邻接表，第一次使用时生成
+func (g *Graph) modeLists(mask int) (arcs [][]arc, edges [][]*model.Edge) {
+	m := g.byMode
+	if m == nil {
+		return nil, nil
+	}
+	mask &= modeMaskCount - 1
+	m.once[mask].Do(func() {
+		m.arcs[mask] = make([][]arc, len(g.nodeIDs))
+		for u, all := range g.adj {
+			m.arcs[mask][u] = filterArcs(all, mask)
+		}
+		m.edges[mask] = make([][]*model.Edge, len(g.nodeIDs))
+		for u, id := range g.nodeIDs {
+			m.edges[mask][u] = filterEdges(g.AdjList[id], mask)
+		}
+	})
+	return m.arcs[mask], m.edges[mask]
+}
+
+// filterArcs 保留支持 mask 中任一方式的出边 (没有可保留的边时返回 nil)
+func filterArcs(all []arc, mask int) []arc {
+	var kept []arc
+	for _, a := range all {
+		if a.edge.ModeMask&mask != 0 {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+// filterEdges 同 filterArcs
+func filterEdges(all []*model.Edge, mask int) []*model.Edge {
+	var kept []*model.Edge
+	for _, edge := range all {
+		if edge.ModeMask&mask != 0 {
+			kept = append(kept, edge)
+		}
+	}
+	return kept
+}
+
+// indexOf 获取节点 ID 对应的下标
+func (g *Graph) indexOf(nodeID string) (int32, bool) {
+	idx, ok := g.nodeIndex[nodeID]
+	return idx, ok
+}
+
+// NodeCount 已建立索引的节点数量
+func (g *Graph) NodeCount() int {
+	return len(g.nodeIDs)
+}
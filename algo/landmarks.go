@@ -0,0 +1,157 @@
+package algo
+
+import (
+	"container/heap"
+	"math"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// DefaultLandmarkCount 默认选取的地标数量
+const DefaultLandmarkCount = 16
+
+// Landmarks ALT 算法 (A*, Landmarks, Triangle inequality) 的预计算数据
+//
+// 对每个地标 L 预先算出 "所有节点到 L" 和 "L 到所有节点" 的时间下界，
+// 查询时利用三角不等式得到 v 到终点 t 的下界:
+//
+//	d(v,t) >= d(L,t) - d(L,v)
+//	d(v,t) >= d(v,L) - d(t,L)
+//
+// 对公交/地铁为主的路网，这个下界远比 "直线距离 / 最高速度" 更接近真实时间
+type Landmarks struct {
+	IDs  []string    // 地标节点 ID
+	From [][]float64 // From[i][v] = 地标 i 到节点 v 的时间下界 (不可达为 +Inf)
+	To   [][]float64 // To[i][v] = 节点 v 到地标 i 的时间下界 (不可达为 +Inf)
+}
+
+// PrecomputeLandmarks 选取地标并预计算时间下界 (在 BuildIndex 之后调用)
+func (g *Graph) PrecomputeLandmarks(count int) {
+	landmarks := g.selectLandmarks(count)
+	lm := &Landmarks{}
+
+	reverse := g.reverseAdj()
+	for _, l := range landmarks {
+		lm.IDs = append(lm.IDs, g.nodeIDs[l])
+		lm.From = append(lm.From, g.lowerBoundTree(l, g.adj))
+		lm.To = append(lm.To, g.lowerBoundTree(l, reverse))
+	}
+	g.Landmarks = lm
+}
+
+// Heuristic 返回从节点 v 到节点 t 的时间下界 (秒)
+func (lm *Landmarks) Heuristic(v, t int32) float64 {
+	best := 0.0
+	for i := range lm.IDs {
+		from, to := lm.From[i], lm.To[i]
+		if !math.IsInf(from[v], 1) && !math.IsInf(from[t], 1) {
+			best = math.Max(best, from[t]-from[v])
+		}
+		if !math.IsInf(to[v], 1) && !math.IsInf(to[t], 1) {
+			best = math.Max(best, to[v]-to[t])
+		}
+	}
+	return best
+}
+
+// selectLandmarks 使用 "最远点" 策略选取地标，使地标尽量分布在路网边缘
+func (g *Graph) selectLandmarks(count int) []int32 {
+	n := len(g.points)
+	if count > n {
+		count = n
+	}
+	if count <= 0 {
+		return nil
+	}
+
+	// 第一个地标取离路网中心最远的节点
+	var center model.Point
+	for _, p := range g.points {
+		center.Lat += p.Lat
+		center.Lng += p.Lng
+	}
+	center.Lat /= float64(n)
+	center.Lng /= float64(n)
+
+	// minDist[v] = v 到已选地标的最近直线距离
+	minDist := make([]float64, n)
+	for i, p := range g.points {
+		minDist[i] = utils.HaversineDistance(center, p)
+	}
+
+	landmarks := make([]int32, 0, count)
+	for len(landmarks) < count {
+		next := int32(0)
+		for i := range minDist {
+			if minDist[i] > minDist[next] {
+				next = int32(i)
+			}
+		}
+		landmarks = append(landmarks, next)
+
+		p := g.points[next]
+		for i, q := range g.points {
+			if d := utils.HaversineDistance(p, q); d < minDist[i] || len(landmarks) == 1 {
+				minDist[i] = d
+			}
+		}
+	}
+	return landmarks
+}
+
+// reverseAdj 构建反向邻接表 (用于计算 "所有节点到地标" 的距离)
+func (g *Graph) reverseAdj() [][]arc {
+	reverse := make([][]arc, len(g.adj))
+	for from, arcs := range g.adj {
+		for _, a := range arcs {
+			reverse[a.to] = append(reverse[a.to], arc{to: int32(from), edge: a.edge})
+		}
+	}
+	return reverse
+}
+
+// lowerBoundTree 以 lowerBoundTime 为边权，计算 source 到所有节点的最短时间
+func (g *Graph) lowerBoundTree(source int32, adj [][]arc) []float64 {
+	dist := make([]float64, len(adj))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[source] = 0
+	visited := make([]bool, len(adj))
+
+	pq := make(PriorityQueue, 0)
+	heap.Push(&pq, &PriorityQueueItem{Node: source})
+	for pq.Len() > 0 {
+		u := heap.Pop(&pq).(*PriorityQueueItem).Node
+		if visited[u] {
+			continue
+		}
+		visited[u] = true
+
+		for _, a := range adj[u] {
+			newDist := dist[u] + lowerBoundTime(a.edge)
+			if newDist < dist[a.to] {
+				dist[a.to] = newDist
+				heap.Push(&pq, &PriorityQueueItem{Node: a.to, Cost: newDist, Priority: newDist})
+			}
+		}
+	}
+	return dist
+}
+
+// lowerBoundTime 通过一条边的最短可能时间 (忽略等待，使用各方式可能的最高速度)
+// 无论用户选择哪些交通方式、设置多快的步行速度，实际时间都不会低于这个值
+func lowerBoundTime(edge *model.Edge) float64 {
+	maxSpeed := 0.0
+	for _, mode := range edge.Modes {
+		speed := model.GetModeSpeed(mode)
+		if mode == "walk" {
+			speed = model.MaxWalkSpeed
+		}
+		maxSpeed = math.Max(maxSpeed, speed)
+	}
+	if maxSpeed == 0 {
+		maxSpeed = model.MaxWalkSpeed
+	}
+	return edge.Dist / maxSpeed
+}
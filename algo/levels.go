@@ -0,0 +1,19 @@
+package algo
+
+import "traffic-system/model"
+
+// level 下标 idx 节点所在的楼层 (虚拟节点按地面层)
+func (g *Graph) level(idx int32) int {
+	if int(idx) >= len(g.levels) {
+		return 0
+	}
+	return g.levels[idx]
+}
+
+// verticalTime 经过垂直通道 u -> v 升降楼层的时间，普通的边为 0
+func (g *Graph) verticalTime(u, v int32, edge *model.Edge) float64 {
+	if edge.Connector == "" {
+		return 0
+	}
+	return edge.VerticalTime(g.level(v) - g.level(u))
+}
@@ -0,0 +1,80 @@
+package algo
+
+import (
+	"sort"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// SetLines 设置图中的线路，并建立节点到线路的索引
+func (g *Graph) SetLines(lines []model.Line) {
+	g.Lines = make(map[string]*model.Line, len(lines))
+	g.NodeLines = make(map[string][]string)
+
+	for i := range lines {
+		line := lines[i]
+		sort.SliceStable(line.Stops, func(a, b int) bool {
+			return line.Stops[a].Seq < line.Stops[b].Seq
+		})
+		g.Lines[line.ID] = &line
+
+		seen := make(map[string]bool, len(line.Stops))
+		for _, stop := range line.Stops {
+			if seen[stop.NodeID] {
+				continue // 环线首尾为同一站点
+			}
+			seen[stop.NodeID] = true
+			g.NodeLines[stop.NodeID] = append(g.NodeLines[stop.NodeID], line.ID)
+		}
+	}
+
+	for _, ids := range g.NodeLines {
+		sort.Strings(ids)
+	}
+}
+
+// LineList 按 ID 排序的线路列表
+func (g *Graph) LineList() []*model.Line {
+	lines := make([]*model.Line, 0, len(g.Lines))
+	for _, line := range g.Lines {
+		lines = append(lines, line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].ID < lines[j].ID })
+	return lines
+}
+
+// StopOffset 从线路始发站到指定站点的行驶时间 (秒)，按线路上各段边的距离和该线路的交通方式估算
+// 站点不在线路上时返回 false
+func (g *Graph) StopOffset(lineID, nodeID string) (float64, bool) {
+	line := g.Lines[lineID]
+	if line == nil {
+		return 0, false
+	}
+
+	offset := 0.0
+	for i, stop := range line.Stops {
+		if stop.NodeID == nodeID {
+			return offset, true
+		}
+		if i+1 < len(line.Stops) {
+			offset += g.lineSegmentTime(line, stop.NodeID, line.Stops[i+1].NodeID)
+		}
+	}
+	return 0, false
+}
+
+// lineSegmentTime 线路上相邻两站之间的行驶时间 (秒)
+func (g *Graph) lineSegmentTime(line *model.Line, from, to string) float64 {
+	speed := model.GetModeSpeed(line.Mode)
+	for _, edge := range g.AdjList[from] {
+		if edge.To == to && edge.LineID == line.ID {
+			return edge.Dist / speed
+		}
+	}
+	// 线路定义与边数据不一致时按直线距离估算
+	a, b := g.Nodes[from], g.Nodes[to]
+	if a == nil || b == nil {
+		return 0
+	}
+	return utils.HaversineDistance(model.Point{Lat: a.Lat, Lng: a.Lng}, model.Point{Lat: b.Lat, Lng: b.Lng}) / speed
+}
@@ -0,0 +1,91 @@
+package algo
+
+import "math"
+
+// MatrixResult 多起点、多终点的时间/距离矩阵
+// Times[i][j] 为 Origins[i] 到 Destinations[j] 的预计时间 (秒)，不可达为 -1
+type MatrixResult struct {
+	Origins      []string
+	Destinations []string
+	Times        [][]float64
+}
+
+// Matrix 计算多个起点到多个终点的预计时间矩阵
+// 每个起点只做一次一对多搜索，所有终点都确定后提前结束；请求取消后剩余的起点不再搜索 (结果为 -1)
+func (g *Graph) Matrix(origins, destinations []string, opts SearchOptions) MatrixResult {
+	result := MatrixResult{
+		Origins:      origins,
+		Destinations: destinations,
+		Times:        make([][]float64, len(origins)),
+	}
+
+	// 终点下标 (不存在的终点记为 -1)
+	targets := make([]int32, len(destinations))
+	for j, id := range destinations {
+		targets[j] = -1
+		if idx, ok := g.indexOf(id); ok {
+			targets[j] = idx
+		}
+	}
+
+	for i, originID := range origins {
+		row := make([]float64, len(destinations))
+		for j := range row {
+			row[j] = -1
+		}
+		result.Times[i] = row
+
+		start, ok := g.indexOf(originID)
+		if !ok || opts.Cancelled() {
+			continue
+		}
+		g.fillMatrixRow(start, targets, opts, row)
+	}
+	return result
+}
+
+// fillMatrixRow 从 start 出发做一次一对多搜索，填充一行结果
+func (g *Graph) fillMatrixRow(start int32, targets []int32, opts SearchOptions, row []float64) {
+	// 需要确定的终点集合 (同一终点可能出现多次)
+	remaining := make(map[int32]bool, len(targets))
+	for _, t := range targets {
+		if t >= 0 {
+			remaining[t] = true
+		}
+	}
+	if len(remaining) == 0 {
+		return
+	}
+	done := func(u int32) bool {
+		delete(remaining, u)
+		return len(remaining) == 0
+	}
+
+	// 驾车需要考虑转弯时按边搜索
+	if g.turnAware(opts) {
+		state := g.expandTurns(start, opts, nil, nil, nil, done)
+		if state.cancelled {
+			return
+		}
+		for j, t := range targets {
+			if label, ok := state.settled[t]; t >= 0 && ok {
+				row[j] = state.labels[label].arrival
+			}
+		}
+		return
+	}
+
+	state := g.acquireState()
+	defer g.releaseState(state)
+
+	g.expand(state, start, opts, nil, nil, done)
+	if state.cancelled {
+		return
+	}
+
+	for j, t := range targets {
+		if t >= 0 && !math.IsInf(state.cost[t], 1) {
+			row[j] = state.arrival[t]
+		}
+	}
+}
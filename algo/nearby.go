@@ -0,0 +1,36 @@
+package algo
+
+import (
+	"cmp"
+	"slices"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// NearbyNode 附近的节点及其直线距离
+type NearbyNode struct {
+	Node     *model.Node
+	Distance float64 // 直线距离 (米)
+}
+
+// NodesNear 返回距离给定坐标 radius 米内、类型为 types 之一的节点，按距离从近到远排列
+// radius <= 0 时不限距离，types 为空时不限类型
+func (g *Graph) NodesNear(lat, lng, radius float64, types ...string) []NearbyNode {
+	target := model.Point{Lat: lat, Lng: lng}
+	var result []NearbyNode
+	for i := range g.NodeList {
+		node := g.Nodes[g.NodeList[i].ID]
+		if node == nil || (len(types) > 0 && !slices.Contains(types, node.Type)) {
+			continue
+		}
+		dist := utils.HaversineDistance(target, model.Point{Lat: node.Lat, Lng: node.Lng})
+		if radius > 0 && dist > radius {
+			continue
+		}
+		result = append(result, NearbyNode{Node: node, Distance: dist})
+	}
+	slices.SortStableFunc(result, func(a, b NearbyNode) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	return result
+}
@@ -0,0 +1,144 @@
+package algo
+
+import (
+	"context"
+	"time"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// 路径规划偏好相关的惩罚参数 (只影响搜索选择，不计入返回的预计时间)
+const (
+	HighwayPenaltyFactor = 3.0  // 避开快速路时，快速路段的时间成本放大倍数
+	AlleyPenaltyFactor   = 2.0  // 避开小路时，驾车经过小巷和内部道路的时间成本放大倍数
+	StairsPenaltyFactor  = 10.0 // 避开楼梯时，步行经过楼梯、天桥和地下通道的时间成本放大倍数
+	TransferPenalty      = 600  // 少换乘时，每次换乘额外增加的成本 (秒)
+)
+
+// minEllipseFocalDist 椭圆剪枝时起终点直线距离的下限 (米)
+// 起终点很近时避免椭圆过扁、把必要的绕行路线剪掉
+const minEllipseFocalDist = 500.0
+
+// SearchOptions 路径搜索参数
+type SearchOptions struct {
+	ModeMask       int     // 允许的交通方式位掩码
+	WalkSpeed      float64 // 步行速度 (米/秒)，0 表示使用默认值
+	AvoidHighways  bool    // 避开快速路 (仅允许机动车通行的道路)
+	AvoidTransfers bool    // 少换乘
+	AvoidAlleys    bool    // 驾车避开小巷和内部道路 (尽量走主要道路)
+	AvoidStairs    bool    // 步行避开楼梯 (推婴儿车、轮椅、携带大件行李)，尽量走电梯、扶梯和平路
+
+	// DetourRatio 椭圆剪枝的绕路比例，0 表示不剪枝
+	// 节点 v 满足 (|sv| + |vt|) > DetourRatio * |st| 时 (直线距离) 不再扩展，
+	// 即只在以起终点为焦点的椭圆内搜索。结果可能不是最优，但搜索范围小得多
+	DetourRatio float64
+
+	// DepartAt 出发时间，用于选取路段的分时速度 (学习速度和高峰系数)，
+	// 搜索时按到达每个节点的时刻推进；为空时只使用默认速度
+	DepartAt time.Time
+
+	// Vehicle 驾车使用的车辆信息，用于限高/限重/限宽、尾号限行、低排放区等规则；为空时只检查对所有车辆生效的规则
+	// 区域规则按时段生效，只在设置了 DepartAt 时检查
+	Vehicle *model.Vehicle
+
+	// Speeds 优先于学习速度使用的路段速度 (如历史回放时某一天的实际速度)，没有数据的路段仍使用学习速度
+	// 必须由同一个图的 BuildSpeedTable 生成
+	Speeds *SpeedTable
+
+	// Overlay 叠加在图上的临时节点和边 (如用户手绘的轮渡)，为空时只使用基础图
+	// 新增了边时不使用 ALT 启发函数
+	Overlay *Overlay
+
+	// Budget 单次搜索的计算时间预算，0 表示不限制
+	// 精确搜索超时后改用加权 A* 找一条近似路径 (PathResult.TimedOut 为 true)
+	Budget time.Duration
+
+	// Context 搜索所属请求的上下文，取消后 (客户端断开、服务关闭) 搜索尽快中止并返回 Cancelled；为空时不会取消
+	Context context.Context
+
+	// Weather 天气对出行的影响 (如下雨)，为空时不调整
+	Weather *WeatherAdjustment
+
+	deadline  time.Time // 本次搜索的截止时间 (由 runSearch 根据 Budget 设置)
+	budgetEnd time.Time // 整个预算的结束时间，超时后的近似搜索只能用到这个时间
+}
+
+// WeatherAdjustment 天气对各交通方式的影响
+// 速度系数只会让速度变慢 (0 ~ 1)，ALT 启发函数的下界仍然有效
+type WeatherAdjustment struct {
+	SpeedFactors map[string]float64 // 交通方式 -> 速度系数 (如雨天步行 0.9)，计入预计时间
+	CostFactors  map[string]float64 // 交通方式 -> 搜索成本放大倍数 (如雨天骑行 2，尽量不选)，不计入预计时间
+}
+
+// edgeCost 计算通过一条边的实际时间和搜索成本
+// learned 为该边在当前时刻学习到的速度 (可为空)，factor 为分时速度系数 (0 表示不调整)
+// 返回:
+//   - travelTime: 预计时间 (秒)，用于展示
+//   - cost: 搜索成本 (预计时间 + 偏好惩罚)，用于比较路径优劣
+//   - usedMode: 实际使用的交通方式
+func (opts *SearchOptions) edgeCost(edge *model.Edge, availableModes []string, prevMode, prevLineID string, learned model.ModeSpeeds, factor float64) (travelTime, cost float64, usedMode string) {
+	prefs := model.TravelPreferences{WalkSpeed: opts.WalkSpeed, Learned: learned, SpeedFactor: factor, Footway: edge.Footway}
+	if opts.Weather != nil {
+		prefs.ModeFactors = opts.Weather.SpeedFactors
+	}
+	travelTime, usedMode = model.EstimateSegmentTimeWithPrefs(
+		edge.Dist,
+		availableModes,
+		prevMode,
+		prevLineID,
+		edge.LineID,
+		prefs,
+	)
+	cost = travelTime
+
+	if opts.AvoidHighways && model.IsDrivingMode(usedMode) && edge.IsHighway() {
+		cost *= HighwayPenaltyFactor
+	}
+
+	if opts.AvoidAlleys && model.IsDrivingMode(usedMode) && edge.IsMinorRoad() {
+		cost *= AlleyPenaltyFactor
+	}
+
+	if opts.AvoidStairs && usedMode == "walk" && edge.HasStairs() {
+		cost *= StairsPenaltyFactor
+	}
+
+	if opts.AvoidTransfers && model.IsTransfer(prevMode, prevLineID, usedMode, edge.LineID) {
+		cost += TransferPenalty
+	}
+
+	if opts.Weather != nil {
+		if f := opts.Weather.CostFactors[usedMode]; f > 1 {
+			cost *= f
+		}
+	}
+
+	return travelTime, cost, usedMode
+}
+
+// ellipseFilter 返回判断节点是否在搜索椭圆内的函数，不剪枝时返回 nil
+func (g *Graph) ellipseFilter(ov *overlay, start, end int32, ratio float64) func(node int32) bool {
+	if ratio <= 0 {
+		return nil
+	}
+
+	startPt, endPt := g.point(ov, start), g.point(ov, end)
+	focal := utils.HaversineDistance(startPt, endPt)
+	if focal < minEllipseFocalDist {
+		focal = minEllipseFocalDist
+	}
+	limit := ratio * focal
+
+	return func(node int32) bool {
+		p := g.point(ov, node)
+		return utils.HaversineDistance(startPt, p)+utils.HaversineDistance(p, endPt) <= limit
+	}
+}
+
+// timeAt 到达某节点的时刻 (出发时间 + 累计时间，本地时间)，未设置出发时间时返回 false
+func (opts *SearchOptions) timeAt(elapsed float64) (time.Time, bool) {
+	if opts.DepartAt.IsZero() {
+		return time.Time{}, false
+	}
+	return opts.DepartAt.Add(time.Duration(elapsed * float64(time.Second))).Local(), true
+}
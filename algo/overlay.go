@@ -0,0 +1,250 @@
+package algo
+
+import (
+	"traffic-system/model"
+)
+
+// Overlay 叠加在图上的一层临时节点和边 (如用户手绘的轮渡、接驳车，或假设分析中的修改)，只对单次请求生效，不修改共享的图
+// 每层可以有上一层 (parent)，查询时从当前层依次查到基础图：上层可以删除下层和基础图中的节点和边
+// 创建后不应再修改，可以被多个查询并发使用
+type Overlay struct {
+	parent       *Overlay
+	nodes        []model.Node         // 本层新增的节点
+	nodeIndex    map[string]int       // 节点 ID -> nodes 中的下标
+	edges        []*model.Edge        // 本层新增的边
+	removed      map[*model.Edge]bool // 本层删除的边 (下层或基础图中的边)
+	removedNodes map[string]bool      // 本层删除的节点，进出该节点的边都不可用
+}
+
+// NewOverlay 在 parent 之上创建一个空的叠加层，parent 为空时直接叠加在基础图上
+func NewOverlay(parent *Overlay) *Overlay {
+	return &Overlay{
+		parent:       parent,
+		nodeIndex:    make(map[string]int),
+		removed:      make(map[*model.Edge]bool),
+		removedNodes: make(map[string]bool),
+	}
+}
+
+// Parent 下一层叠加层 (为空表示基础图)
+func (o *Overlay) Parent() *Overlay {
+	return o.parent
+}
+
+// AddNode 向本层加入一个节点 (调用方需保证 ID 在图和下层中不存在)
+func (o *Overlay) AddNode(node model.Node) {
+	o.nodeIndex[node.ID] = len(o.nodes)
+	o.nodes = append(o.nodes, node)
+}
+
+// AddEdge 向本层加入一条有向边 (会自动计算 ModeMask，不生成反向边)
+func (o *Overlay) AddEdge(edge *model.Edge) {
+	edge.ModeMask = edge.EdgeModeMask()
+	o.edges = append(o.edges, edge)
+}
+
+// RemoveEdge 在本层删除下层或基础图中的一条边
+func (o *Overlay) RemoveEdge(edge *model.Edge) {
+	o.removed[edge] = true
+}
+
+// RemoveNode 在本层删除一个节点，进出该节点的边一并失效
+func (o *Overlay) RemoveNode(id string) {
+	o.removedNodes[id] = true
+}
+
+// addsEdges 叠加层链中是否新增了边 (新增的边可能比原路网更快，ALT 地标下界不再可采纳)
+func (o *Overlay) addsEdges() bool {
+	for l := o; l != nil; l = l.parent {
+		if len(l.edges) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeIn 在叠加层链和基础图中查找节点，不存在或已被删除时返回 nil (ov 为空时只查基础图)
+func (g *Graph) NodeIn(ov *Overlay, id string) *model.Node {
+	for l := ov; l != nil; l = l.parent {
+		if l.removedNodes[id] {
+			return nil
+		}
+		if i, ok := l.nodeIndex[id]; ok {
+			return &l.nodes[i]
+		}
+	}
+	return g.Nodes[id]
+}
+
+// GetNeighbors 获取指定节点在特定交通方式下的邻居边
+// 直接返回预先过滤好的邻接表，调用方不应修改返回的切片
+func (g *Graph) GetNeighbors(nodeID string, modeMask int) []*model.Edge {
+	if idx, ok := g.indexOf(nodeID); ok && g.byMode != nil {
+		_, edges := g.modeLists(modeMask)
+		return edges[idx]
+	}
+	return g.NeighborsIn(nil, nodeID, modeMask)
+}
+
+// NeighborsIn 获取指定节点在特定交通方式下的邻居边，包括叠加层链中新增的边，不包括被删除的边
+func (g *Graph) NeighborsIn(ov *Overlay, nodeID string, modeMask int) []*model.Edge {
+	var validEdges []*model.Edge
+	for _, edge := range g.edgesIn(ov, nodeID) {
+		if edge.ModeMask&modeMask != 0 {
+			validEdges = append(validEdges, edge)
+		}
+	}
+	return validEdges
+}
+
+// edgesIn 节点在叠加层链中的全部出边：先取下层的出边，去掉本层删除的，再加上本层新增的
+func (g *Graph) edgesIn(ov *Overlay, nodeID string) []*model.Edge {
+	if ov == nil {
+		return g.AdjList[nodeID]
+	}
+	if ov.removedNodes[nodeID] {
+		return nil
+	}
+	below := g.edgesIn(ov.parent, nodeID)
+	edges := make([]*model.Edge, 0, len(below))
+	for _, edge := range below {
+		if !ov.removed[edge] && !ov.removedNodes[edge.To] {
+			edges = append(edges, edge)
+		}
+	}
+	for _, edge := range ov.edges {
+		if edge.From == nodeID {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// overlay 单次查询临时加入的虚拟节点和边 (吸附到边上的起终点、叠加层中的节点和边)，按整数下标存放，不修改共享的图
+type overlay struct {
+	ids    []string         // 虚拟节点 ID，下标从 len(g.nodeIDs) 开始
+	points []model.Point    // 虚拟节点坐标
+	index  map[string]int32 // 虚拟节点 ID -> 下标
+	arcs   map[int32][]arc  // 额外的出边 (虚拟起点的出边、真实节点到虚拟终点的边、叠加层新增的边)
+
+	removed      map[*model.Edge]bool // 视为不存在的边 (叠加层删除的边)
+	removedNodes map[int32]bool       // 视为不存在的节点，进出该节点的边都不可用
+}
+
+// compile 把叠加层链展开为按下标存放的 overlay (从最下层开始)，ov 为空时返回空的 overlay
+// 每次查询单独展开，调用方可以继续向其中加入虚拟节点和边
+func (g *Graph) compile(ov *Overlay) *overlay {
+	flat := &overlay{
+		index:        make(map[string]int32),
+		arcs:         make(map[int32][]arc),
+		removed:      make(map[*model.Edge]bool),
+		removedNodes: make(map[int32]bool),
+	}
+	var layers []*Overlay
+	for l := ov; l != nil; l = l.parent {
+		layers = append(layers, l)
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		for _, node := range l.nodes {
+			flat.addNode(g, node.ID, model.Point{Lat: node.Lat, Lng: node.Lng})
+		}
+		for _, edge := range l.edges {
+			from, okFrom := g.indexIn(flat, edge.From)
+			to, okTo := g.indexIn(flat, edge.To)
+			if okFrom && okTo {
+				flat.arcs[from] = append(flat.arcs[from], arc{to: to, edge: edge})
+			}
+		}
+		for edge := range l.removed {
+			flat.removed[edge] = true
+		}
+		for id := range l.removedNodes {
+			if idx, ok := g.indexIn(flat, id); ok {
+				flat.removedNodes[idx] = true
+			}
+		}
+	}
+	return flat
+}
+
+// indexIn 查找节点下标 (包括虚拟节点，不包括被删除的节点)
+func (g *Graph) indexIn(ov *overlay, id string) (int32, bool) {
+	if ov == nil {
+		return g.indexOf(id)
+	}
+	idx, ok := ov.index[id]
+	if !ok {
+		idx, ok = g.indexOf(id)
+	}
+	if !ok || ov.removedNodes[idx] {
+		return 0, false
+	}
+	return idx, true
+}
+
+// addNode 加入一个虚拟节点，返回其下标
+func (ov *overlay) addNode(g *Graph, id string, p model.Point) int32 {
+	idx := int32(len(g.nodeIDs) + len(ov.ids))
+	ov.ids = append(ov.ids, id)
+	ov.points = append(ov.points, p)
+	ov.index[id] = idx
+	return idx
+}
+
+// addArc 加入一条部分边 (复制原边的通行方式和限制，只修改起终点和距离)
+func (ov *overlay) addArc(from, to int32, edge *model.Edge, fromID, toID string, dist float64) {
+	part := *edge
+	part.ID = 0
+	part.From, part.To, part.Dist = fromID, toID, dist
+	ov.arcs[from] = append(ov.arcs[from], arc{to: to, edge: &part})
+}
+
+// nodeID 获取下标对应的节点 ID (包括虚拟节点)
+func (g *Graph) nodeID(ov *overlay, idx int32) string {
+	if n := int32(len(g.nodeIDs)); idx >= n {
+		return ov.ids[idx-n]
+	}
+	return g.nodeIDs[idx]
+}
+
+// point 获取下标对应的坐标 (包括虚拟节点)
+func (g *Graph) point(ov *overlay, idx int32) model.Point {
+	if n := int32(len(g.nodeIDs)); idx >= n {
+		return ov.points[idx-n]
+	}
+	return g.points[idx]
+}
+
+// arcs 获取节点支持 modeMask 中任一方式的出边 (包括临时加入的虚拟边，不包括临时删除的边)
+// 虚拟边不按交通方式过滤，由调用方检查
+func (g *Graph) arcs(ov *overlay, u int32, modeMask int) []arc {
+	var base []arc
+	if adj, _ := g.modeLists(modeMask); int(u) < len(adj) {
+		base = adj[u]
+	}
+	if ov == nil {
+		return base
+	}
+	if len(ov.removed) > 0 || len(ov.removedNodes) > 0 {
+		if ov.removedNodes[u] {
+			return nil
+		}
+		kept := make([]arc, 0, len(base)+len(ov.arcs[u]))
+		for _, a := range base {
+			if !ov.removed[a.edge] && !ov.removedNodes[a.to] {
+				kept = append(kept, a)
+			}
+		}
+		for _, a := range ov.arcs[u] {
+			if !ov.removed[a.edge] && !ov.removedNodes[a.to] {
+				kept = append(kept, a)
+			}
+		}
+		return kept
+	}
+	if len(ov.arcs[u]) == 0 {
+		return base
+	}
+	return append(append([]arc(nil), base...), ov.arcs[u]...)
+}
@@ -0,0 +1,84 @@
+package algo
+
+import (
+	"slices"
+	"time"
+	"traffic-system/model"
+)
+
+// 终点附近停车场的查找参数
+const (
+	DefaultParkingRadius = 1000.0 // 默认查找半径 (米)
+	maxParkingCandidates = 5      // 最多尝试的停车场数
+)
+
+// ParkingNear 返回距离给定坐标 radius 米内的停车场，按距离从近到远排列
+// radius <= 0 时返回全部停车场
+func (g *Graph) ParkingNear(lat, lng, radius float64) []*model.Node {
+	var lots []*model.Node
+	for _, n := range g.NodesNear(lat, lng, radius, model.NodeTypeParking) {
+		lots = append(lots, n.Node)
+	}
+	return lots
+}
+
+// RouteViaParking 先驾车到终点附近的停车场，再步行到终点
+// 在距离终点最近的几个停车场中选择总时间最短的一个；附近没有可到达的停车场时返回 nil
+func (g *Graph) RouteViaParking(startID, endID string, opts SearchOptions, radius float64) (PathResult, *model.Node) {
+	end := g.Nodes[endID]
+	if end == nil {
+		return PathResult{Found: false}, nil
+	}
+	if radius <= 0 {
+		radius = DefaultParkingRadius
+	}
+
+	lots := g.ParkingNear(end.Lat, end.Lng, radius)
+	if len(lots) > maxParkingCandidates {
+		lots = lots[:maxParkingCandidates]
+	}
+
+	var best PathResult
+	var bestLot *model.Node
+	for _, lot := range lots {
+		if opts.Cancelled() {
+			return PathResult{Found: false, Cancelled: true}, nil
+		}
+		drive := g.AStar(startID, lot.ID, opts)
+		if !drive.Found {
+			continue
+		}
+
+		walkOpts := SearchOptions{ModeMask: model.ModeWalk, WalkSpeed: opts.WalkSpeed, Overlay: opts.Overlay, Budget: opts.Budget, Context: opts.Context, Weather: opts.Weather}
+		if !opts.DepartAt.IsZero() {
+			walkOpts.DepartAt = opts.DepartAt.Add(time.Duration(drive.EstimatedTime * float64(time.Second)))
+		}
+		walk := g.AStar(lot.ID, endID, walkOpts)
+		if !walk.Found {
+			continue
+		}
+
+		if bestLot == nil || drive.EstimatedTime+walk.EstimatedTime < best.EstimatedTime {
+			best = joinPaths(drive, walk)
+			bestLot = lot
+		}
+	}
+	if bestLot == nil {
+		return PathResult{Found: false}, nil
+	}
+	return best, bestLot
+}
+
+// joinPaths 把两段首尾相接的路径合并为一条
+func joinPaths(a, b PathResult) PathResult {
+	path := append(slices.Clone(a.Path), b.Path[1:]...)
+	return PathResult{
+		Path:          path,
+		Segments:      append(slices.Clone(a.Segments), b.Segments...),
+		Distance:      a.Distance + b.Distance,
+		EstimatedTime: a.EstimatedTime + b.EstimatedTime,
+		Fee:           a.Fee + b.Fee,
+		Found:         true,
+		TimedOut:      a.TimedOut || b.TimedOut,
+	}
+}
@@ -0,0 +1,88 @@
+package algo
+
+import (
+	"math"
+	"traffic-system/model"
+)
+
+// searchState 单次搜索使用的缓冲区 (按节点下标存放)
+// 通过 Graph.statePool 复用，高并发查询时避免反复分配大切片给 GC 造成压力
+type searchState struct {
+	cost      []float64
+	prev      []int32
+	prevEdge  []*model.Edge
+	prevMode  []string  // 到达每个节点使用的交通方式
+	prevTime  []float64 // 到达每个节点的最后一段预计时间
+	arrival   []float64 // 从起点到每个节点的累计预计时间
+	hops      []int32   // 从起点到每个节点经过的边数
+	visited   []bool
+	touched   []int32 // 本次搜索修改过的节点，归还时只重置这些位置
+	pq        PriorityQueue
+	overlay   *overlay // 本次搜索临时加入的虚拟节点和边 (可为空)
+	timedOut  bool     // 本次搜索是否因超过时间预算而中止
+	cancelled bool     // 本次搜索是否因请求取消而中止
+}
+
+// newSearchState 创建适配 n 个节点的搜索缓冲区
+func newSearchState(n int) *searchState {
+	s := &searchState{
+		cost:     make([]float64, n),
+		prev:     make([]int32, n),
+		prevEdge: make([]*model.Edge, n),
+		prevMode: make([]string, n),
+		prevTime: make([]float64, n),
+		arrival:  make([]float64, n),
+		hops:     make([]int32, n),
+		visited:  make([]bool, n),
+	}
+	for i := 0; i < n; i++ {
+		s.cost[i] = math.Inf(1)
+		s.prev[i] = -1
+	}
+	return s
+}
+
+// touch 记录节点被修改过 (第一次修改成本时调用)
+func (s *searchState) touch(node int32) {
+	if math.IsInf(s.cost[node], 1) {
+		s.touched = append(s.touched, node)
+	}
+}
+
+// reset 把本次搜索修改过的位置恢复为初始值
+func (s *searchState) reset() {
+	for _, node := range s.touched {
+		s.cost[node] = math.Inf(1)
+		s.prev[node] = -1
+		s.prevEdge[node] = nil
+		s.prevMode[node] = ""
+		s.prevTime[node] = 0
+		s.arrival[node] = 0
+		s.hops[node] = 0
+		s.visited[node] = false
+	}
+	s.touched = s.touched[:0]
+	for i := range s.pq {
+		s.pq[i] = nil
+	}
+	s.pq = s.pq[:0]
+	s.overlay = nil
+	s.timedOut = false
+	s.cancelled = false
+}
+
+// acquireState 从池中取出与当前图大小一致的搜索缓冲区
+func (g *Graph) acquireState() *searchState {
+	n := len(g.nodeIDs)
+	if s, ok := g.statePool.Get().(*searchState); ok && len(s.cost) == n {
+		return s
+	}
+	// 池为空或图重建过索引 (节点数变化)，重新分配
+	return newSearchState(n)
+}
+
+// releaseState 重置并归还搜索缓冲区
+func (g *Graph) releaseState(s *searchState) {
+	s.reset()
+	g.statePool.Put(s)
+}
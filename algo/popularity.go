@@ -0,0 +1,39 @@
+package algo
+
+import (
+	"math"
+	"traffic-system/model"
+)
+
+// popularityTable 节点热度 (节点 ID -> 0 ~ 1)
+type popularityTable map[string]float64
+
+// SetPopularity 设置节点热度，返回匹配到路网的节点数，可以在服务运行中调用
+// 热度按 log(1 + score) / log(1 + 最大 score) 归一化到 0 ~ 1，避免个别热门节点压过其他信号
+func (g *Graph) SetPopularity(rows []model.NodePopularity) int {
+	max := 0.0
+	for _, r := range rows {
+		if g.Nodes[r.NodeID] != nil && r.Score > max {
+			max = r.Score
+		}
+	}
+
+	table := make(popularityTable)
+	if max > 0 {
+		for _, r := range rows {
+			if g.Nodes[r.NodeID] != nil && r.Score > 0 {
+				table[r.NodeID] = math.Log1p(r.Score) / math.Log1p(max)
+			}
+		}
+	}
+	g.popularity.Store(&table)
+	return len(table)
+}
+
+// Popularity 节点热度 (0 ~ 1)，没有记录时为 0
+func (g *Graph) Popularity(nodeID string) float64 {
+	if table := g.popularity.Load(); table != nil {
+		return (*table)[nodeID]
+	}
+	return 0
+}
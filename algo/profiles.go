@@ -0,0 +1,46 @@
+package algo
+
+import (
+	"time"
+	"traffic-system/model"
+)
+
+// profileTable 分时速度系数 (道路等级 -> [工作日, 周末] -> 小时 -> 系数)
+type profileTable map[string]*[2][24]float64
+
+// SetSpeedProfiles 设置分时速度系数，系数限制在 (0, 1] 之内 (保证 ALT 下界仍然可采纳)
+func (g *Graph) SetSpeedProfiles(profiles []model.SpeedProfile) {
+	table := make(profileTable)
+	for _, p := range profiles {
+		if p.Hour < 0 || p.Hour > 23 || p.Factor <= 0 {
+			continue
+		}
+		days := table[p.Class]
+		if days == nil {
+			days = new([2][24]float64)
+			table[p.Class] = days
+		}
+		day := 0
+		if p.DayType == model.DayTypeWeekend {
+			day = 1
+		}
+		days[day][p.Hour] = min(p.Factor, 1)
+	}
+	g.profiles.Store(&table)
+}
+
+// factor 查询边在指定时刻的速度系数，没有数据时返回 0 (不调整)
+func (t *profileTable) factor(edge *model.Edge, at time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	days := (*t)[edge.ProfileClass()]
+	if days == nil {
+		return 0
+	}
+	day := 0
+	if model.DayTypeOf(at) == model.DayTypeWeekend {
+		day = 1
+	}
+	return days[day][at.Hour()]
+}
@@ -0,0 +1,228 @@
+package algo
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+	"unicode"
+)
+
+// 搜索匹配程度 (数值越小越好)
+const (
+	MatchExact    = iota // 完全相同
+	MatchPrefix          // 前缀匹配
+	MatchContains        // 包含
+)
+
+// NodeMatch 节点搜索结果
+type NodeMatch struct {
+	Node    *model.Node
+	Alias   string // 通过别名匹配时为匹配到的别名
+	Quality int    // 匹配程度 (MatchExact / MatchPrefix / MatchContains)
+}
+
+// aliasIndex 节点别名 (节点 ID -> 别名列表)
+type aliasIndex map[string][]string
+
+// SetAliases 设置节点别名，返回有效的别名数 (节点不存在的别名被忽略)，可以在服务运行中调用
+func (g *Graph) SetAliases(aliases []model.NodeAlias) int {
+	index := make(aliasIndex)
+	count := 0
+	for _, a := range aliases {
+		if g.Nodes[a.NodeID] == nil || a.Alias == "" {
+			continue
+		}
+		index[a.NodeID] = append(index[a.NodeID], a.Alias)
+		count++
+	}
+	g.aliases.Store(&index)
+	return count
+}
+
+// ReloadAliases 从数据库重新加载节点别名
+func (g *Graph) ReloadAliases() (int, error) {
+	var aliases []model.NodeAlias
+	if err := db.DB.Order("id").Find(&aliases).Error; err != nil {
+		return 0, err
+	}
+	return g.SetAliases(aliases), nil
+}
+
+// Aliases 节点的别名
+func (g *Graph) Aliases(nodeID string) []string {
+	if index := g.aliases.Load(); index != nil {
+		return (*index)[nodeID]
+	}
+	return nil
+}
+
+// SearchNodes 按名称、英文名称、别名和 ID 搜索节点 (英文不区分大小写)，category 不为空时只搜索该分类下的节点
+// 结果按匹配程度排序：完全相同 > 前缀 > 包含；同一程度下热度高的优先，再按重要程度、名称匹配优先于别名匹配、名称长度
+func (g *Graph) SearchNodes(query, category string) []NodeMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var matches []NodeMatch
+	for i := range g.NodeList {
+		node := &g.NodeList[i]
+		if !g.InCategory(node, category) {
+			continue
+		}
+		best := NodeMatch{Node: node, Quality: -1}
+		consider := func(text, alias string) {
+			q := matchQuality(strings.ToLower(text), query)
+			// 同一程度下保留名称匹配 (alias 为空)
+			if q >= 0 && (best.Quality < 0 || q < best.Quality) {
+				best.Quality, best.Alias = q, alias
+			}
+		}
+		consider(node.Name, "")
+		consider(node.NameEn, "")
+		consider(node.ID, "")
+		for _, alias := range g.Aliases(node.ID) {
+			consider(alias, alias)
+		}
+		if best.Quality >= 0 {
+			matches = append(matches, best)
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b NodeMatch) int {
+		return cmp.Or(
+			cmp.Compare(a.Quality, b.Quality),
+			cmp.Compare(g.Popularity(b.Node.ID), g.Popularity(a.Node.ID)),
+			cmp.Compare(b.Node.Importance, a.Node.Importance),
+			cmp.Compare(boolRank(a.Alias != ""), boolRank(b.Alias != "")),
+			cmp.Compare(len([]rune(a.Node.Name)), len([]rune(b.Node.Name))),
+			cmp.Compare(a.Node.ID, b.Node.ID),
+		)
+	})
+	return matches
+}
+
+// matchQuality 计算 text 与 query 的匹配程度，不匹配时返回 -1 (参数都已转为小写)
+func matchQuality(text, query string) int {
+	switch {
+	case text == "":
+		return -1
+	case text == query:
+		return MatchExact
+	case strings.HasPrefix(text, query):
+		return MatchPrefix
+	case strings.Contains(text, query):
+		return MatchContains
+	default:
+		return -1
+	}
+}
+
+// boolRank false 排在 true 前面
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 自动补全的默认和最大返回数量
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 50
+)
+
+// suggestDistanceScale 距离偏好的衰减尺度 (米)：距离偏好点这么远时距离得分减半
+const suggestDistanceScale = 2000.0
+
+// suggestTypeWeight 各类节点的重要程度 (0 ~ 1)，未列出的类型为 0.5
+var suggestTypeWeight = map[string]float64{
+	"landmark":             1.0,
+	"subway_entrance":      0.9,
+	model.NodeTypeParking:  0.7,
+	model.NodeTypeCharging: 0.7,
+	"bus_stop":             0.6,
+	"road_node":            0.1,
+}
+
+// suggestQualityScore 各匹配程度的得分 (名称中某一段的前缀介于前缀和包含之间)
+var suggestQualityScore = [...]float64{MatchExact: 3, MatchPrefix: 2, MatchContains: 1}
+
+const suggestWordPrefixScore = 1.5
+
+// suggestPopularityWeight 热度 (0 ~ 1) 在得分中的权重
+const suggestPopularityWeight = 1.0
+
+// suggestImportanceWeight 重要程度 (importance / 5) 在得分中的权重
+const suggestImportanceWeight = 0.5
+
+// Suggestion 自动补全结果
+type Suggestion struct {
+	NodeMatch
+	Score    float64 // 综合得分 (越大越靠前)
+	Distance float64 // 到偏好点的直线距离 (米)，没有偏好点时为 0
+}
+
+// Suggest 输入框自动补全：在 SearchNodes 的结果上按匹配程度、节点类型、热度和到偏好点 near 的距离综合打分，
+// 返回得分最高的 limit 个结果。near 为空时不考虑距离，category 不为空时只返回该分类下的节点
+//
+// 得分 = 匹配得分 (完全相同 3，前缀 2，名称中某一段的前缀 1.5，包含 1) + 类型权重 (0 ~ 1) + 热度 (0 ~ 1)
+// + 重要程度得分 (0 ~ 0.5) + 距离得分 (0 ~ 1)，
+// 其中距离得分 = 1 / (1 + 距离 / 2000 米)
+func (g *Graph) Suggest(query, category string, near *model.Point, limit int) []Suggestion {
+	matches := g.SearchNodes(query, category)
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	suggestions := make([]Suggestion, 0, len(matches))
+	for _, m := range matches {
+		s := Suggestion{NodeMatch: m, Score: suggestQualityScore[m.Quality]}
+		if m.Quality == MatchContains && wordPrefix(m.Node, m.Alias, query) {
+			s.Score = suggestWordPrefixScore
+		}
+
+		weight, ok := suggestTypeWeight[m.Node.Type]
+		if !ok {
+			weight = 0.5
+		}
+		s.Score += weight + suggestPopularityWeight*g.Popularity(m.Node.ID)
+		s.Score += suggestImportanceWeight * float64(m.Node.Importance) / model.MaxNodeImportance
+
+		if near != nil {
+			s.Distance = utils.HaversineDistance(*near, model.Point{Lat: m.Node.Lat, Lng: m.Node.Lng})
+			s.Score += 1 / (1 + s.Distance/suggestDistanceScale)
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	// 稳定排序，得分相同时保持 SearchNodes 的顺序
+	slices.SortStableFunc(suggestions, func(a, b Suggestion) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// wordPrefix 判断 query 是否为名称 (或匹配到的别名) 中某一段的前缀，名称按 "-"、空格等分段
+// 如 "郑州" 是 "地铁站-郑州大学站" 第二段的前缀
+func wordPrefix(node *model.Node, alias, query string) bool {
+	texts := []string{node.Name, node.NameEn}
+	if alias != "" {
+		texts = []string{alias}
+	}
+	for _, text := range texts {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return r == '-' || r == '_' || r == '(' || r == ')' || unicode.IsSpace(r)
+		})
+		for _, word := range words {
+			if strings.HasPrefix(word, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package algo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// WalkShortcutDesc 自动生成的步行连接边的描述
+const WalkShortcutDesc = "步行连接 (自动生成)"
+
+// WalkShortcutRadius 各类节点自动生成步行连接的距离上限 (米)，加载路网时使用
+// 两个节点都列在其中、直线距离不超过两者上限中较小的一个、且之间没有步行边时，自动连接一条双向步行边
+// (如马路两侧的公交站、相邻的地铁口)。未列出的类型 (如道路节点) 不生成；为空表示不生成
+var WalkShortcutRadius = DefaultWalkShortcutRadius()
+
+// DefaultWalkShortcutRadius 默认的步行连接距离上限
+func DefaultWalkShortcutRadius() map[string]float64 {
+	return map[string]float64{
+		"bus_stop":             120,
+		"subway_entrance":      150,
+		model.NodeTypeBikeDock: 100,
+		model.NodeTypeParking:  100,
+		model.NodeTypeCharging: 100,
+		"landmark":             100,
+	}
+}
+
+// ParseWalkShortcutRadius 解析步行连接配置，格式为 "bus_stop=80,subway_entrance=150"，"off" 表示不生成
+func ParseWalkShortcutRadius(s string) (map[string]float64, error) {
+	radius := make(map[string]float64)
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "" {
+		return radius, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		nodeType, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("格式错误: %q", item)
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || r < 0 {
+			return nil, fmt.Errorf("无效的距离: %q", item)
+		}
+		radius[strings.TrimSpace(nodeType)] = r
+	}
+	return radius, nil
+}
+
+// AddWalkingShortcuts 按 radius 为相近的节点生成步行连接边 (只在内存中，不写回数据库)，返回生成的边数 (每个方向算一条)
+// 在 BuildIndex 之前调用
+func (g *Graph) AddWalkingShortcuts(radius map[string]float64) int {
+	maxRadius := 0.0
+	for _, r := range radius {
+		maxRadius = max(maxRadius, r)
+	}
+	if maxRadius <= 0 || len(g.NodeList) == 0 {
+		return 0
+	}
+
+	// 按 maxRadius 大小的网格分桶，只比较相邻格子里的节点
+	type cell struct{ x, y int }
+	metersPerLng := 111320 * math.Cos(utils.DegreesToRadians(g.NodeList[0].Lat))
+	cellOf := func(n *model.Node) cell {
+		return cell{int(math.Floor(n.Lng * metersPerLng / maxRadius)), int(math.Floor(n.Lat * 111320 / maxRadius))}
+	}
+	grid := make(map[cell][]*model.Node)
+	var candidates []*model.Node
+	for i := range g.NodeList {
+		node := g.Nodes[g.NodeList[i].ID]
+		if node == nil || radius[node.Type] <= 0 {
+			continue
+		}
+		grid[cellOf(node)] = append(grid[cellOf(node)], node)
+		candidates = append(candidates, node)
+	}
+
+	added := 0
+	for _, a := range candidates {
+		ca := cellOf(a)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, b := range grid[cell{ca.x + dx, ca.y + dy}] {
+					if a.ID >= b.ID {
+						continue // 每对节点只处理一次
+					}
+					limit := min(radius[a.Type], radius[b.Type])
+					dist := utils.HaversineDistance(model.Point{Lat: a.Lat, Lng: a.Lng}, model.Point{Lat: b.Lat, Lng: b.Lng})
+					if dist > limit {
+						continue
+					}
+					added += g.addWalkEdge(a.ID, b.ID, dist)
+					added += g.addWalkEdge(b.ID, a.ID, dist)
+				}
+			}
+		}
+	}
+	return added
+}
+
+// addWalkEdge 两点之间没有步行边时添加一条，返回添加的边数
+func (g *Graph) addWalkEdge(from, to string, dist float64) int {
+	for _, edge := range g.AdjList[from] {
+		if edge.To == to && edge.ModeMask&model.ModeWalk != 0 {
+			return 0
+		}
+	}
+	g.AddEdge(&model.Edge{From: from, To: to, Dist: dist, Modes: []string{"walk"}, Desc: WalkShortcutDesc})
+	return 1
+}
@@ -0,0 +1,55 @@
+package algo
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SignalDelay 经过信号灯路口 (Node.Signal) 的平均等待时间 (秒，按交通方式)，计入预计时间
+// 起点和终点不计；未列出的方式 (如公交、地铁) 不等待
+var SignalDelay = DefaultSignalDelay()
+
+// DefaultSignalDelay 默认的信号灯等待时间 (约为红灯时长的一半乘以遇到红灯的概率)
+func DefaultSignalDelay() map[string]float64 {
+	return map[string]float64{
+		"car":   20,
+		"truck": 20,
+		"bike":  15,
+		"walk":  10,
+	}
+}
+
+// ParseSignalDelay 解析信号灯等待时间配置，格式为 "car=25,bike=15,walk=10"，"off" 表示不等待
+func ParseSignalDelay(s string) (map[string]float64, error) {
+	delay := make(map[string]float64)
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "" {
+		return delay, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		mode, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("格式错误: %q", item)
+		}
+		mode = strings.TrimSpace(mode)
+		if !slices.Contains(searchModes, mode) {
+			return nil, fmt.Errorf("未知的交通方式: %q", item)
+		}
+		d, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("无效的等待时间: %q", item)
+		}
+		delay[mode] = d
+	}
+	return delay, nil
+}
+
+// signalDelay 使用 mode 穿过节点 u 时在信号灯前的等待时间 (虚拟节点没有信号灯)
+func (g *Graph) signalDelay(u int32, mode string) float64 {
+	if int(u) >= len(g.signals) || !g.signals[u] {
+		return 0
+	}
+	return SignalDelay[mode]
+}
@@ -0,0 +1,187 @@
+package algo
+
+import (
+	"math"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// 吸附到边上的起终点使用的虚拟节点 ID
+const (
+	VirtualStartID = "@start"
+	VirtualEndID   = "@end"
+)
+
+// snapEndpointTolerance 投影点离边的端点小于该距离 (米) 时直接使用端点
+const snapEndpointTolerance = 1.0
+
+// EdgeSnap 坐标在路网边上的投影
+type EdgeSnap struct {
+	Edge     *model.Edge
+	Point    model.Point // 投影点
+	Fraction float64     // 投影点在边上的位置 (0 = From, 1 = To)
+	Distance float64     // 原始坐标到投影点的距离 (米)
+}
+
+// Waypoint 路径的起点或终点：路网节点，或者吸附到边上的位置
+// Snap 不为空时优先使用 Snap，NodeID 可以填写所在边较近的一端 (供只支持节点的功能使用)
+type Waypoint struct {
+	NodeID string
+	Snap   *EdgeSnap
+}
+
+// SnapToEdge 把坐标投影到最近的可通行边上 (不包括公交/地铁线路边和楼梯、电梯等垂直通道，中途无法上下车)
+// 没有可用的边时返回 nil
+func (g *Graph) SnapToEdge(lat, lng float64, modeMask int) *EdgeSnap {
+	p := model.Point{Lat: lat, Lng: lng}
+	var best *EdgeSnap
+	for fromID, edges := range g.AdjList {
+		from := g.Nodes[fromID]
+		if from == nil {
+			continue
+		}
+		a := model.Point{Lat: from.Lat, Lng: from.Lng}
+		for _, edge := range edges {
+			if edge.LineID != "" || edge.Connector != "" || edge.ModeMask&modeMask == 0 {
+				continue
+			}
+			to := g.Nodes[edge.To]
+			if to == nil {
+				continue
+			}
+			proj, t, dist := utils.ProjectToSegment(p, a, model.Point{Lat: to.Lat, Lng: to.Lng})
+			if best == nil || dist < best.Distance {
+				best = &EdgeSnap{Edge: edge, Point: proj, Fraction: t, Distance: dist}
+			}
+		}
+	}
+	return best
+}
+
+// NearestNodeID 投影点所在边上较近的端点
+func (s *EdgeSnap) NearestNodeID() string {
+	if s.Fraction <= 0.5 {
+		return s.Edge.From
+	}
+	return s.Edge.To
+}
+
+// endpointID 投影点与边的端点重合时返回该端点 ID
+func (s *EdgeSnap) endpointID() (string, bool) {
+	if s.Fraction*s.Edge.Dist < snapEndpointTolerance {
+		return s.Edge.From, true
+	}
+	if (1-s.Fraction)*s.Edge.Dist < snapEndpointTolerance {
+		return s.Edge.To, true
+	}
+	return "", false
+}
+
+// reverseEdge 查找与 edge 方向相反的同一条道路 (不属于公交/地铁线路)
+func (g *Graph) reverseEdge(edge *model.Edge) *model.Edge {
+	for _, e := range g.AdjList[edge.To] {
+		if e.To == edge.From && e.LineID == "" {
+			return e
+		}
+	}
+	return nil
+}
+
+// RouteBetween 在两个路径端点之间规划路径，端点可以是节点或吸附到边上的位置
+// 吸附到边上的端点使用虚拟节点 (VirtualStartID / VirtualEndID)，并只走该边的一部分
+func (g *Graph) RouteBetween(start, end Waypoint, opts SearchOptions) PathResult {
+	if start.Snap != nil {
+		if id, ok := start.Snap.endpointID(); ok {
+			start = Waypoint{NodeID: id}
+		}
+	}
+	if end.Snap != nil {
+		if id, ok := end.Snap.endpointID(); ok {
+			end = Waypoint{NodeID: id}
+		}
+	}
+	if start.Snap == nil && end.Snap == nil {
+		return g.AStar(start.NodeID, end.NodeID, opts)
+	}
+
+	ov := g.compile(opts.Overlay)
+	s, okStart := g.indexIn(ov, start.NodeID)
+	t, okEnd := g.indexIn(ov, end.NodeID)
+
+	// 虚拟起点：沿边走向 To，有反向边时也可以走向 From
+	if snap := start.Snap; snap != nil {
+		s, okStart = ov.addNode(g, VirtualStartID, snap.Point), true
+		if to, ok := g.indexIn(ov, snap.Edge.To); ok {
+			ov.addArc(s, to, snap.Edge, VirtualStartID, snap.Edge.To, (1-snap.Fraction)*snap.Edge.Dist)
+		}
+		if rev := g.reverseEdge(snap.Edge); rev != nil {
+			if from, ok := g.indexIn(ov, snap.Edge.From); ok {
+				ov.addArc(s, from, rev, VirtualStartID, snap.Edge.From, snap.Fraction*snap.Edge.Dist)
+			}
+		}
+	}
+
+	// 虚拟终点：从边的 From 到达，有反向边时也可以从 To 到达
+	var approaches []int32
+	if snap := end.Snap; snap != nil {
+		t, okEnd = ov.addNode(g, VirtualEndID, snap.Point), true
+		if from, ok := g.indexIn(ov, snap.Edge.From); ok {
+			ov.addArc(from, t, snap.Edge, snap.Edge.From, VirtualEndID, snap.Fraction*snap.Edge.Dist)
+			approaches = append(approaches, from)
+		}
+		if rev := g.reverseEdge(snap.Edge); rev != nil {
+			if to, ok := g.indexIn(ov, snap.Edge.To); ok {
+				ov.addArc(to, t, rev, snap.Edge.To, VirtualEndID, (1-snap.Fraction)*snap.Edge.Dist)
+				approaches = append(approaches, to)
+			}
+		}
+
+		// 起终点在同一条道路上时可以直接沿边行走
+		if a := start.Snap; a != nil {
+			rev := g.reverseEdge(a.Edge)
+			pos, same := 0.0, false
+			switch snap.Edge {
+			case a.Edge:
+				pos, same = snap.Fraction, true
+			case rev:
+				pos, same = 1-snap.Fraction, true
+			}
+			if same && pos >= a.Fraction {
+				ov.addArc(s, t, a.Edge, VirtualStartID, VirtualEndID, (pos-a.Fraction)*a.Edge.Dist)
+			} else if same && rev != nil {
+				ov.addArc(s, t, rev, VirtualStartID, VirtualEndID, (a.Fraction-pos)*a.Edge.Dist)
+			}
+		}
+	}
+	if !okStart || !okEnd {
+		return PathResult{Found: false}
+	}
+
+	// ALT 启发函数：到虚拟终点必须经过 approaches 中的某个节点，取其中最小的下界 (仍然可采纳)
+	// 叠加层新增了边时下界不再可采纳，使用 Dijkstra
+	var heuristic func(node int32) float64
+	if lm := g.Landmarks; lm != nil && len(lm.IDs) > 0 && !opts.Overlay.addsEdges() {
+		n := int32(len(g.nodeIDs))
+		if end.Snap == nil {
+			approaches = []int32{t}
+		}
+		heuristic = func(node int32) float64 {
+			if node >= n {
+				return 0
+			}
+			best := math.Inf(1)
+			for _, a := range approaches {
+				if a >= n {
+					return 0
+				}
+				best = math.Min(best, lm.Heuristic(node, a))
+			}
+			if math.IsInf(best, 1) {
+				return 0
+			}
+			return best
+		}
+	}
+
+	return g.runSearch(s, t, opts, heuristic, ov)
+}
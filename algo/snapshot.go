@@ -0,0 +1,157 @@
+package algo
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+	"traffic-system/model"
+)
+
+// 路网快照
+//
+// 把构建好的图 (节点、包括反向边和自动生成的步行连接在内的全部有向边及其 ModeMask、线路、
+// ALT 地标下界、别名、分类、分时速度系数) 用 gob 编码并 gzip 压缩，副本启动时直接读取，
+// 不必从数据库查询并重新预计算。整数下标索引由 BuildIndex 重建 (只需线性时间)。
+// 驾车区域规则引用边的指针，不放入快照，加载后从数据库读取。
+
+// SnapshotFormat 快照格式版本，格式不兼容时加一
+// 2: 整数下标改为按节点 ID 排序 (地标下界数组按下标存放)
+const SnapshotFormat = 2
+
+// SnapshotInfo 快照概要
+type SnapshotInfo struct {
+	Format    int       `json:"format"`
+	Version   string    `json:"version"` // 地图版本 (与 Graph.Version 相同)
+	CreatedAt time.Time `json:"created_at"`
+	Nodes     int       `json:"nodes"`
+	Edges     int       `json:"edges"` // 有向边数量
+}
+
+// graphSnapshot 快照内容
+type graphSnapshot struct {
+	Info       SnapshotInfo
+	Nodes      []model.Node
+	Edges      []model.Edge // 按起点分组，组内保持邻接表中的顺序
+	Lines      []model.Line
+	Landmarks  *Landmarks
+	Aliases    []model.NodeAlias
+	Categories []model.Category
+	Profiles   map[string][2][24]float64
+}
+
+// WriteSnapshot 把图写成快照，返回快照概要
+func (g *Graph) WriteSnapshot(w io.Writer) (SnapshotInfo, error) {
+	snap := graphSnapshot{
+		Info: SnapshotInfo{
+			Format:    SnapshotFormat,
+			Version:   g.Version(),
+			CreatedAt: time.Now(),
+			Nodes:     len(g.NodeList),
+		},
+		Nodes:      g.NodeList,
+		Landmarks:  g.Landmarks,
+		Categories: g.Categories(),
+	}
+
+	// 起点排序，保证相同的图得到相同的快照
+	from := make([]string, 0, len(g.AdjList))
+	for id := range g.AdjList {
+		from = append(from, id)
+	}
+	slices.Sort(from)
+	for _, id := range from {
+		for _, edge := range g.AdjList[id] {
+			snap.Edges = append(snap.Edges, *edge)
+		}
+	}
+	snap.Info.Edges = len(snap.Edges)
+
+	for _, line := range g.LineList() {
+		snap.Lines = append(snap.Lines, *line)
+	}
+	if index := g.aliases.Load(); index != nil {
+		for _, id := range g.nodeIDs {
+			for _, alias := range (*index)[id] {
+				snap.Aliases = append(snap.Aliases, model.NodeAlias{NodeID: id, Alias: alias})
+			}
+		}
+	}
+	if table := g.profiles.Load(); table != nil {
+		snap.Profiles = make(map[string][2][24]float64, len(*table))
+		for class, days := range *table {
+			snap.Profiles[class] = *days
+		}
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(&snap); err != nil {
+		return snap.Info, fmt.Errorf("编码快照失败: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return snap.Info, err
+	}
+	return snap.Info, nil
+}
+
+// ReadSnapshot 读取快照并恢复图，地图版本与快照记录的不一致时返回错误
+// 快照中没有驾车区域规则和转弯规则，需要时调用 ReloadZones、ReloadTurnRestrictions
+func ReadSnapshot(r io.Reader) (*Graph, SnapshotInfo, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, SnapshotInfo{}, fmt.Errorf("快照格式错误: %w", err)
+	}
+	defer zr.Close()
+
+	var snap graphSnapshot
+	if err := gob.NewDecoder(zr).Decode(&snap); err != nil {
+		return nil, SnapshotInfo{}, fmt.Errorf("解码快照失败: %w", err)
+	}
+	if snap.Info.Format != SnapshotFormat {
+		return nil, snap.Info, fmt.Errorf("不支持的快照格式: %d (当前为 %d)", snap.Info.Format, SnapshotFormat)
+	}
+
+	g := NewGraph()
+	for _, node := range snap.Nodes {
+		g.AddNode(node)
+	}
+	for i := range snap.Edges {
+		edge := &snap.Edges[i]
+		g.AdjList[edge.From] = append(g.AdjList[edge.From], edge) // ModeMask 已在快照中
+	}
+	g.SetLines(snap.Lines)
+	g.SetAliases(snap.Aliases)
+	g.SetCategories(snap.Categories)
+	profiles := make(profileTable, len(snap.Profiles))
+	for class, days := range snap.Profiles {
+		profiles[class] = &days
+	}
+	g.profiles.Store(&profiles)
+
+	g.BuildIndex()
+	if lm := snap.Landmarks; lm != nil && validLandmarks(lm, g.NodeCount()) {
+		g.Landmarks = lm
+	} else {
+		g.PrecomputeLandmarks(DefaultLandmarkCount)
+	}
+
+	if version := g.Version(); version != snap.Info.Version {
+		return nil, snap.Info, fmt.Errorf("快照内容与版本不符 (记录 %s，实际 %s)", snap.Info.Version, version)
+	}
+	return g, snap.Info, nil
+}
+
+// validLandmarks 地标下界数组的长度与节点数一致
+func validLandmarks(lm *Landmarks, n int) bool {
+	if len(lm.From) != len(lm.IDs) || len(lm.To) != len(lm.IDs) {
+		return false
+	}
+	for i := range lm.IDs {
+		if len(lm.From[i]) != n || len(lm.To[i]) != n {
+			return false
+		}
+	}
+	return true
+}
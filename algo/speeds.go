@@ -0,0 +1,54 @@
+package algo
+
+import "traffic-system/model"
+
+// SpeedTable 路段分时速度 (边 -> 小时 -> 各交通方式速度)，只对创建它的图有效
+type SpeedTable map[*model.Edge]*[24]model.ModeSpeeds
+
+// SetLearnedSpeeds 设置根据历史行程学习到的路段速度，返回匹配到路网的记录数
+// 可以在服务运行中调用，正在进行的查询继续使用旧数据
+func (g *Graph) SetLearnedSpeeds(rows []model.EdgeSpeed) int {
+	table, matched := g.BuildSpeedTable(rows)
+	g.learned.Store(table)
+	return matched
+}
+
+// BuildSpeedTable 把按 (路段, 方式, 小时) 统计的速度匹配到图中的边，返回速度表和匹配到路网的记录数
+func (g *Graph) BuildSpeedTable(rows []model.EdgeSpeed) (*SpeedTable, int) {
+	table := make(SpeedTable)
+	matched := 0
+	for _, row := range rows {
+		if row.Hour < 0 || row.Hour > 23 || row.Speed <= 0 {
+			continue
+		}
+		mask := model.GetModeMask(row.Mode)
+		for _, edge := range g.AdjList[row.FromID] {
+			if edge.To != row.ToID || edge.ModeMask&mask == 0 {
+				continue
+			}
+			hours := table[edge]
+			if hours == nil {
+				hours = new([24]model.ModeSpeeds)
+				table[edge] = hours
+			}
+			if hours[row.Hour] == nil {
+				hours[row.Hour] = make(model.ModeSpeeds)
+			}
+			hours[row.Hour][row.Mode] = model.ClampLearnedSpeed(row.Mode, row.Speed)
+			matched++
+		}
+	}
+	return &table, matched
+}
+
+// lookup 查询某条边在指定小时的学习速度，没有数据时返回 nil
+func (t *SpeedTable) lookup(edge *model.Edge, hour int) model.ModeSpeeds {
+	if t == nil {
+		return nil
+	}
+	hours := (*t)[edge]
+	if hours == nil {
+		return nil
+	}
+	return hours[hour%24]
+}
@@ -0,0 +1,267 @@
+package algo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// MaxTileZoom 支持的最大缩放级别
+const MaxTileZoom = 22
+
+// tileSnapPixels 坐标吸附到的像素网格大小，两端吸附到同一格的边不再输出
+const tileSnapPixels = 2
+
+// tileNodeMinZoom 各类节点开始显示的缩放级别 (未列出的类型始终显示)
+var tileNodeMinZoom = map[string]int{
+	"road_node": 15,
+	"bus_stop":  14,
+}
+
+// tileImportanceMinZoom 标注了重要程度的节点开始显示的缩放级别 (优先于按类型的级别)
+var tileImportanceMinZoom = [model.MaxNodeImportance + 1]int{0, 16, 14, 12, 10, 0}
+
+// tileRoadMinZoom 各等级道路开始显示的缩放级别 (未列出的等级和未标注等级的边始终显示)
+var tileRoadMinZoom = map[string]int{
+	model.RoadCollector: 12,
+	model.RoadLocal:     14,
+	model.RoadAlley:     16,
+	model.RoadService:   16,
+}
+
+// TileNode 瓦片中的节点
+type TileNode struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	NameEn     string  `json:"name_en,omitempty"`
+	Type       string  `json:"type"`
+	Importance int     `json:"importance,omitempty"`
+	Level      int     `json:"level,omitempty"`
+	Lat        float64 `json:"lat"`
+	Lng        float64 `json:"lng"`
+}
+
+// TileEdge 瓦片中的边 (双向道路只输出一次)
+type TileEdge struct {
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Modes     []string     `json:"modes"`
+	LineID    string       `json:"line_id,omitempty"`
+	RoadClass string       `json:"road_class,omitempty"` // 道路等级，用于选择线宽和颜色
+	Footway   string       `json:"footway,omitempty"`    // 步行设施类型 (人行横道、天桥等)
+	Level     int          `json:"level,omitempty"`      // 所在楼层，前端可按楼层切换显示室内通道
+	Connector string       `json:"connector,omitempty"`  // 垂直通道类型 (楼梯、扶梯、电梯)
+	Coords    [][2]float64 `json:"coords"`               // [[lat, lng], ...]
+}
+
+// Tile 一个瓦片范围内的节点和边
+type Tile struct {
+	Z     int         `json:"z"`
+	X     int         `json:"x"`
+	Y     int         `json:"y"`
+	BBox  BoundingBox `json:"bbox"`
+	Nodes []TileNode  `json:"nodes"`
+	Edges []TileEdge  `json:"edges"`
+}
+
+// ValidTile 瓦片坐标是否有效
+func ValidTile(z, x, y int) bool {
+	n := 1 << z
+	return z >= 0 && z <= MaxTileZoom && x >= 0 && x < n && y >= 0 && y < n
+}
+
+// Tile 获取与瓦片相交的节点和边
+// 缩放级别低时隐藏路口、公交站等次要节点 (或重要程度低的节点) 和支路、小巷等低等级道路，经过隐藏节点的同一线路的边连成折线后用
+// Douglas–Peucker 简化 (容差 tileSnapPixels 像素)，最后坐标吸附到同样大小的像素网格上
+func (g *Graph) Tile(z, x, y int) *Tile {
+	sw, ne := utils.TileBounds(z, x, y)
+	tile := &Tile{
+		Z: z, X: x, Y: y,
+		BBox:  BoundingBox{MinLat: sw.Lat, MinLng: sw.Lng, MaxLat: ne.Lat, MaxLng: ne.Lng},
+		Nodes: make([]TileNode, 0),
+		Edges: make([]TileEdge, 0),
+	}
+	rect := []model.Point{sw, {Lat: sw.Lat, Lng: ne.Lng}, ne, {Lat: ne.Lat, Lng: sw.Lng}}
+
+	// 像素网格在 Web Mercator 平面上吸附 (与底图的像素对齐)
+	step := utils.MercatorPixelSize(z) * tileSnapPixels
+	origin := utils.ToWebMercator(sw)
+	snap := func(p model.Point) [2]float64 {
+		xy := utils.ToWebMercator(p)
+		xy.X = origin.X + math.Round((xy.X-origin.X)/step)*step
+		xy.Y = origin.Y + math.Round((xy.Y-origin.Y)/step)*step
+		s := utils.FromWebMercator(xy)
+		return [2]float64{math.Round(s.Lat*1e7) / 1e7, math.Round(s.Lng*1e7) / 1e7}
+	}
+	inside := func(p model.Point) bool {
+		return p.Lat >= sw.Lat && p.Lat <= ne.Lat && p.Lng >= sw.Lng && p.Lng <= ne.Lng
+	}
+	visible := func(node *model.Node) bool {
+		if node.Importance > 0 && node.Importance <= model.MaxNodeImportance {
+			return z >= tileImportanceMinZoom[node.Importance]
+		}
+		return z >= tileNodeMinZoom[node.Type]
+	}
+
+	for _, node := range g.Nodes {
+		p := model.Point{Lat: node.Lat, Lng: node.Lng}
+		if !inside(p) || !visible(node) {
+			continue
+		}
+		c := snap(p)
+		tile.Nodes = append(tile.Nodes, TileNode{ID: node.ID, Name: node.Name, NameEn: node.NameEn, Type: node.Type, Importance: node.Importance, Level: node.Level, Lat: c[0], Lng: c[1]})
+	}
+
+	// 1. 收集与瓦片相交的边，正反两个方向合并为一条，交通方式取并集
+	type edgeKey struct{ a, b, line string }
+	var chains []*tileChain
+	merged := make(map[edgeKey]*tileChain)
+	for _, edges := range g.AdjList {
+		for _, e := range edges {
+			if z < tileRoadMinZoom[e.RoadClass] {
+				continue
+			}
+			from, to := g.Nodes[e.From], g.Nodes[e.To]
+			if from == nil || to == nil {
+				continue
+			}
+			a := model.Point{Lat: from.Lat, Lng: from.Lng}
+			b := model.Point{Lat: to.Lat, Lng: to.Lng}
+			// 先用包围盒快速排除
+			if max(a.Lat, b.Lat) < sw.Lat || min(a.Lat, b.Lat) > ne.Lat ||
+				max(a.Lng, b.Lng) < sw.Lng || min(a.Lng, b.Lng) > ne.Lng {
+				continue
+			}
+			if !utils.SegmentIntersectsPolygon(a, b, rect) {
+				continue
+			}
+
+			key := edgeKey{e.From, e.To, e.LineID}
+			if key.a > key.b {
+				key.a, key.b = key.b, key.a
+			}
+			if chain, ok := merged[key]; ok {
+				for _, m := range e.AvailableModes(e.ModeMask) {
+					if !slices.Contains(chain.modes, m) {
+						chain.modes = append(chain.modes, m)
+					}
+				}
+				continue
+			}
+			chain := &tileChain{from: e.From, to: e.To, line: e.LineID, class: e.RoadClass, footway: e.Footway, level: e.Level, connector: e.Connector, modes: e.AvailableModes(e.ModeMask), points: []model.Point{a, b}}
+			merged[key] = chain
+			chains = append(chains, chain)
+		}
+	}
+
+	// 2. 隐藏节点上恰好有两条线路、道路等级、步行设施、楼层和交通方式都相同的边时，把这两条边连成一条折线
+	incident := make(map[string][]*tileChain)
+	for _, chain := range chains {
+		slices.Sort(chain.modes)
+		incident[chain.from] = append(incident[chain.from], chain)
+		incident[chain.to] = append(incident[chain.to], chain)
+	}
+	ids := make([]string, 0, len(incident))
+	for id := range incident {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids) // 固定合并顺序，保证同一瓦片的输出不变
+	for _, id := range ids {
+		if visible(g.Nodes[id]) {
+			continue
+		}
+		groups := make(map[string][]*tileChain)
+		var order []string
+		for _, chain := range incident[id] {
+			key := strings.Join([]string{chain.line, chain.class, chain.footway, strconv.Itoa(chain.level), chain.connector, strings.Join(chain.modes, ",")}, "|")
+			if groups[key] == nil {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], chain)
+		}
+		for _, key := range order {
+			group := groups[key]
+			if len(group) != 2 || group[0] == group[1] {
+				continue
+			}
+			c1, c2 := group[0], group[1]
+			c1.join(c2, id)
+			c2.dead = true
+			// c2 另一端 (现在是 c1.to) 的关联改为 c1
+			for i, c := range incident[c1.to] {
+				if c == c2 {
+					incident[c1.to][i] = c1
+				}
+			}
+		}
+	}
+
+	// 3. 简化、吸附到网格
+	tolerance := utils.MetersPerPixel(z, (sw.Lat+ne.Lat)/2) * tileSnapPixels
+	for _, chain := range chains {
+		if chain.dead {
+			continue
+		}
+		var coords [][2]float64
+		for _, p := range utils.SimplifyPolyline(chain.points, tolerance) {
+			c := snap(p)
+			if len(coords) == 0 || coords[len(coords)-1] != c {
+				coords = append(coords, c)
+			}
+		}
+		// 两端吸附到同一格的短边不输出
+		if len(coords) < 2 {
+			continue
+		}
+		tile.Edges = append(tile.Edges, TileEdge{
+			From:      chain.from,
+			To:        chain.to,
+			Modes:     chain.modes,
+			LineID:    chain.line,
+			RoadClass: chain.class,
+			Footway:   chain.footway,
+			Level:     chain.level,
+			Connector: chain.connector,
+			Coords:    coords,
+		})
+	}
+
+	// 输出顺序固定，便于缓存
+	slices.SortFunc(tile.Nodes, func(a, b TileNode) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(tile.Edges, func(a, b TileEdge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To), cmp.Compare(a.LineID, b.LineID))
+	})
+	return tile
+}
+
+// tileChain 瓦片中连在一起的边 (from -> ... -> to)
+type tileChain struct {
+	from, to  string
+	line      string
+	class     string // 道路等级
+	footway   string // 步行设施类型
+	level     int    // 楼层
+	connector string // 垂直通道类型
+	modes     []string
+	points    []model.Point
+	dead      bool // 已合并到其他折线
+}
+
+// join 在共同节点 id 处把 other 接到 c 上
+func (c *tileChain) join(other *tileChain, id string) {
+	// 调整方向为 c: ... -> id，other: id -> ...
+	if c.to != id {
+		c.from, c.to = c.to, c.from
+		slices.Reverse(c.points)
+	}
+	if other.from != id {
+		other.from, other.to = other.to, other.from
+		slices.Reverse(other.points)
+	}
+	c.to = other.to
+	c.points = append(c.points, other.points[1:]...)
+}
@@ -0,0 +1,382 @@
+package algo
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// 转弯方向的判断角度 (度)：方位角变化小于 straightTurnAngle 为直行，大于 uTurnAngle 为掉头
+const (
+	straightTurnAngle = 30.0
+	uTurnAngle        = 150.0
+)
+
+// TurnPenalty 驾车在路口转弯增加的时间 (秒)，计入预计时间
+type TurnPenalty struct {
+	Left  float64
+	Right float64
+	UTurn float64
+}
+
+// TurnPenalties 按转弯方向计算的默认惩罚，路口有 penalty 规则时使用规则中的值；全部为 0 且没有转弯规则时不按边搜索
+var TurnPenalties = DefaultTurnPenalties()
+
+// DefaultTurnPenalties 默认的转弯惩罚 (左转需要等待对向车流，掉头最慢)
+func DefaultTurnPenalties() TurnPenalty {
+	return TurnPenalty{Left: 15, Right: 5, UTurn: 30}
+}
+
+// ParseTurnPenalties 解析转弯惩罚配置，格式为 "left=15,right=5,u_turn=30" (未列出的方向为 0)，"off" 表示不惩罚
+func ParseTurnPenalties(s string) (TurnPenalty, error) {
+	var p TurnPenalty
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "" {
+		return p, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return p, fmt.Errorf("格式错误: %q", item)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 || v > model.MaxTurnPenalty {
+			return p, fmt.Errorf("无效的惩罚时间: %q", item)
+		}
+		switch strings.TrimSpace(name) {
+		case "left":
+			p.Left = v
+		case "right":
+			p.Right = v
+		case "u_turn":
+			p.UTurn = v
+		default:
+			return p, fmt.Errorf("未知的转弯方向: %q", item)
+		}
+	}
+	return p, nil
+}
+
+// zero 是否不惩罚任何转弯
+func (p TurnPenalty) zero() bool {
+	return p.Left == 0 && p.Right == 0 && p.UTurn == 0
+}
+
+// turnKey 一个转弯：从 from 经 via 驶向 to (节点 ID)
+type turnKey struct {
+	from, via, to string
+}
+
+// turnIndex 转弯规则索引
+type turnIndex struct {
+	banned  map[turnKey]bool              // 禁止的转弯
+	only    map[[2]string]map[string]bool // (from, via) -> 唯一允许驶向的节点
+	penalty map[turnKey]float64           // 指定了惩罚时间的转弯
+}
+
+// SetTurnRestrictions 设置转弯规则，返回生效的规则数 (只有启用的、经过的节点都存在的规则生效)，可以在服务运行中调用
+func (g *Graph) SetTurnRestrictions(rules []model.TurnRestriction) int {
+	index := &turnIndex{
+		banned:  make(map[turnKey]bool),
+		only:    make(map[[2]string]map[string]bool),
+		penalty: make(map[turnKey]float64),
+	}
+	count := 0
+	for _, r := range rules {
+		if !r.Active || g.Nodes[r.FromID] == nil || g.Nodes[r.ViaID] == nil || g.Nodes[r.ToID] == nil {
+			continue
+		}
+		key := turnKey{r.FromID, r.ViaID, r.ToID}
+		switch {
+		case r.Prohibits():
+			index.banned[key] = true
+		case r.Mandatory():
+			in := [2]string{r.FromID, r.ViaID}
+			if index.only[in] == nil {
+				index.only[in] = make(map[string]bool)
+			}
+			index.only[in][r.ToID] = true
+		case r.Type == model.TurnPenalty:
+			index.penalty[key] = r.Penalty
+		default:
+			continue
+		}
+		count++
+	}
+
+	if count == 0 {
+		index = nil
+	}
+	g.turns.Store(index)
+	return count
+}
+
+// ReloadTurnRestrictions 从数据库重新加载启用的转弯规则，返回生效的规则数
+func (g *Graph) ReloadTurnRestrictions() (int, error) {
+	var rules []model.TurnRestriction
+	if err := db.DB.Where("active = ?", true).Order("id").Find(&rules).Error; err != nil {
+		return 0, fmt.Errorf("查询转弯规则失败: %w", err)
+	}
+	return g.SetTurnRestrictions(rules), nil
+}
+
+// allows 驾车能否从边 in 转入边 out
+func (t *turnIndex) allows(in, out *model.Edge) bool {
+	if t == nil {
+		return true
+	}
+	if t.banned[turnKey{in.From, in.To, out.To}] {
+		return false
+	}
+	if only := t.only[[2]string{in.From, in.To}]; only != nil && !only[out.To] {
+		return false
+	}
+	return true
+}
+
+// turnAware 本次搜索是否需要按边搜索 (允许驾车，且有转弯惩罚或转弯规则)
+func (g *Graph) turnAware(opts SearchOptions) bool {
+	if opts.ModeMask&(model.ModeCar|model.ModeTruck) == 0 {
+		return false
+	}
+	return !TurnPenalties.zero() || g.turns.Load() != nil
+}
+
+// turnPenalty 驾车从 prev 经 via 驶向 next (边 in、out) 的转弯惩罚 (秒)
+// 路口有 penalty 规则时使用规则的值，否则按方位角的变化判断转弯方向
+func (g *Graph) turnPenalty(turns *turnIndex, ov *overlay, prev, via, next int32, in, out *model.Edge) float64 {
+	if turns != nil {
+		if p, ok := turns.penalty[turnKey{in.From, in.To, out.To}]; ok {
+			return p
+		}
+	}
+	if TurnPenalties.zero() {
+		return 0
+	}
+
+	p1, p2, p3 := g.point(ov, prev), g.point(ov, via), g.point(ov, next)
+	if p1 == p2 || p2 == p3 {
+		return 0 // 长度为 0 的边 (如吸附点与节点重合) 无法判断方向
+	}
+	delta := math.Mod(utils.Bearing(p2, p3)-utils.Bearing(p1, p2)+540, 360) - 180
+	switch {
+	case math.Abs(delta) >= uTurnAngle:
+		return TurnPenalties.UTurn
+	case delta <= -straightTurnAngle:
+		return TurnPenalties.Left
+	case delta >= straightTurnAngle:
+		return TurnPenalties.Right
+	}
+	return 0
+}
+
+// 标签的车辆状态：还没有驾车、正在驾车、驾车后已改用其他方式 (车停在了路上，不能再驾车)
+const (
+	vehicleNone uint8 = iota
+	vehicleDriving
+	vehicleParked
+)
+
+// turnLabel 按边搜索的一个标签：经过某条边、以某种车辆状态到达一个节点
+// (同一节点从不同的边、或以不同的车辆状态到达是不同的标签)
+type turnLabel struct {
+	node    int32
+	edge    *model.Edge // 到达 node 使用的边，起点为空
+	parent  int32       // 上一个标签的下标，起点为 -1
+	cost    float64
+	arrival float64 // 从起点到达的累计预计时间
+	time    float64 // 最后一段的预计时间 (含转弯惩罚)
+	mode    string
+	vehicle uint8
+	hops    int32
+	visited bool
+}
+
+// turnLabelKey 标签的索引：到达边和车辆状态
+// 只按边索引时，步行到达的标签会覆盖驾车到达的标签，驾车的后续路线 (和转弯规则的检查) 就丢失了
+type turnLabelKey struct {
+	edge    *model.Edge
+	vehicle uint8
+}
+
+// turnState 按边搜索的状态 (标签数量与边数相当，每次搜索单独分配)
+type turnState struct {
+	labels    []turnLabel
+	byEdge    map[turnLabelKey]int32 // (到达边, 车辆状态) -> 标签下标
+	settled   map[int32]int32        // 节点 -> 第一个确定的 (成本最低的) 标签
+	pq        PriorityQueue
+	overlay   *overlay
+	timedOut  bool
+	cancelled bool
+}
+
+// expandTurns 按边 (而不是按节点) 扩展的 Dijkstra / A*：到达同一节点的不同边分别记录成本，
+// 从而可以禁止特定的转弯、给转弯增加惩罚 (到达路口的方向决定了能否、以及多快驶入下一条边)
+// 参数与 expand 相同，done 在节点第一次被确定时调用
+func (g *Graph) expandTurns(start int32, opts SearchOptions, ov *overlay, heuristic func(node int32) float64, inSearchSpace func(node int32) bool, done func(u int32) bool) *turnState {
+	modeMask := opts.ModeMask
+	state := &turnState{
+		labels:  []turnLabel{{node: start, parent: -1}},
+		byEdge:  make(map[turnLabelKey]int32),
+		settled: make(map[int32]int32),
+		overlay: ov,
+	}
+	speeds, profiles, zones, turns := g.learned.Load(), g.profiles.Load(), g.zones.Load(), g.turns.Load()
+
+	pq := &state.pq
+	heap.Push(pq, &PriorityQueueItem{Node: start, Label: 0})
+
+	settled := 0
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(*PriorityQueueItem)
+		cur := &state.labels[item.Label]
+		if cur.visited {
+			continue
+		}
+		cur.visited = true
+		u, curLabel := cur.node, item.Label
+
+		settled++
+		if cancelled, timedOut := opts.interrupted(settled); cancelled || timedOut {
+			state.cancelled, state.timedOut = cancelled, timedOut
+			return state
+		}
+
+		if _, ok := state.settled[u]; !ok {
+			state.settled[u] = curLabel
+			if done(u) {
+				return state
+			}
+		}
+
+		at, timed := opts.timeAt(cur.arrival)
+		driving := cur.edge != nil && model.IsDrivingMode(cur.mode)
+
+		for _, a := range g.arcs(ov, u, modeMask) {
+			edge := a.edge
+			mask := modeMask
+			if edge.HasRestrictions() && !opts.Vehicle.Fits(edge) {
+				mask &^= model.ModeCar | model.ModeTruck
+			}
+			if timed && zones.forbids(edge, opts.Vehicle, at) {
+				mask &^= model.ModeCar | model.ModeTruck
+			}
+			if driving && !turns.allows(cur.edge, edge) {
+				mask &^= model.ModeCar | model.ModeTruck // 禁止的转弯
+			}
+			if cur.vehicle == vehicleParked {
+				mask &^= model.ModeCar | model.ModeTruck // 下车后不能再驾车 (否则步行走过禁止转弯的路段就绕过了规则)
+			}
+			if edge.ModeMask&mask == 0 {
+				continue
+			}
+			v := a.to
+			if inSearchSpace != nil && !inSearchSpace(v) {
+				continue
+			}
+			availableModes := edge.AvailableModes(mask)
+			if len(availableModes) == 0 {
+				continue
+			}
+
+			var learned model.ModeSpeeds
+			factor := 0.0
+			if timed {
+				learned, factor = opts.Speeds.lookup(edge, at.Hour()), profiles.factor(edge, at)
+				if learned == nil {
+					learned = speeds.lookup(edge, at.Hour())
+				}
+			}
+			edgeTime, edgeCost, usedMode := opts.edgeCost(edge, availableModes, cur.mode, lineOf(cur.edge), learned, factor)
+			if d := g.verticalTime(u, v, edge); d > 0 {
+				edgeTime += d
+				edgeCost += d
+			}
+			if timed && model.IsDrivingMode(usedMode) {
+				edgeCost += zones.fee(edge, opts.Vehicle, at) * ZoneFeePenalty
+			}
+			if driving && model.IsDrivingMode(usedMode) {
+				p := g.turnPenalty(turns, ov, state.labels[cur.parent].node, u, v, cur.edge, edge)
+				edgeTime += p
+				edgeCost += p
+			}
+			if cur.edge != nil {
+				d := g.signalDelay(u, usedMode)
+				edgeTime += d
+				edgeCost += d
+			}
+
+			vehicle := cur.vehicle
+			if model.IsDrivingMode(usedMode) {
+				vehicle = vehicleDriving
+			} else if vehicle == vehicleDriving {
+				vehicle = vehicleParked
+			}
+
+			newCost := cur.cost + edgeCost
+			newHops := cur.hops + 1
+			key := turnLabelKey{edge, vehicle}
+			next, seen := state.byEdge[key]
+			if seen {
+				old := &state.labels[next]
+				if old.visited || !(newCost < old.cost-costEpsilon ||
+					(newCost <= old.cost+costEpsilon && preferredTie(newHops, u, old.hops, state.labels[old.parent].node))) {
+					continue
+				}
+			} else {
+				next = int32(len(state.labels))
+				state.labels = append(state.labels, turnLabel{node: v, edge: edge, vehicle: vehicle})
+				state.byEdge[key] = next
+			}
+			// append 可能使 cur 失效，通过下标访问
+			arrival := state.labels[curLabel].arrival + edgeTime
+			l := &state.labels[next]
+			l.parent, l.cost, l.arrival, l.time, l.mode, l.hops = curLabel, newCost, arrival, edgeTime, usedMode, newHops
+			cur = &state.labels[curLabel]
+
+			priority := newCost
+			if heuristic != nil {
+				priority += heuristic(v)
+			}
+			heap.Push(pq, &PriorityQueueItem{
+				Node:     v,
+				Label:    next,
+				Cost:     newCost,
+				Priority: priority,
+				Mode:     usedMode,
+				LineID:   edge.LineID,
+				Hops:     newHops,
+			})
+		}
+	}
+	return state
+}
+
+// route 回溯到达节点 end 的路径，未到达时返回 false
+func (s *turnState) route(end int32) ([]pathStep, bool) {
+	label, ok := s.settled[end]
+	if !ok {
+		return nil, false
+	}
+	var steps []pathStep
+	for ; label != -1; label = s.labels[label].parent {
+		l := &s.labels[label]
+		steps = append(steps, pathStep{node: l.node, edge: l.edge, mode: l.mode, time: l.time})
+	}
+	slices.Reverse(steps)
+	return steps, true
+}
+
+// lineOf 边所属的线路 ID (边为空时为空)
+func lineOf(edge *model.Edge) string {
+	if edge == nil {
+		return ""
+	}
+	return edge.LineID
+}
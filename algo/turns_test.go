@@ -0,0 +1,113 @@
+package algo_test
+
+import (
+	"testing"
+	"traffic-system/algo"
+	"traffic-system/model"
+)
+
+// turnGraph 东西向的道路 A - B - C - E，A、B、C 之间可以步行和驾车，C - E 只能驾车；
+// 禁止从 A 经 B 直行到 C，驾车只能从 B 经北侧的 D 绕行到 C
+func turnGraph(t *testing.T) *algo.Graph {
+	t.Helper()
+	at := func(id string, north, east float64) model.Node {
+		return model.Node{ID: id, Lat: 34.80 + north/111195, Lng: 113.50 + east/91345}
+	}
+	road := []string{"walk", "car"}
+	g := algo.NewGraphBuilder().
+		AddNode(at("A", 0, 0)).
+		AddNode(at("B", 0, 200)).
+		AddNode(at("C", 0, 250)).
+		AddNode(at("D", 1000, 225)).
+		AddNode(at("E", 0, 3250)).
+		AddEdge(model.Edge{From: "A", To: "B", Dist: 200, Modes: road}).
+		AddEdge(model.Edge{From: "B", To: "C", Dist: 50, Modes: road}).
+		AddEdge(model.Edge{From: "B", To: "D", Dist: 2000, Modes: []string{"car"}}).
+		AddEdge(model.Edge{From: "D", To: "C", Dist: 2000, Modes: []string{"car"}}).
+		AddEdge(model.Edge{From: "C", To: "E", Dist: 3000, Modes: []string{"car"}}).
+		MustBuild()
+	if n := g.SetTurnRestrictions([]model.TurnRestriction{{FromID: "A", ViaID: "B", ToID: "C", Type: model.TurnNoStraight, Active: true}}); n != 1 {
+		t.Fatalf("生效的转弯规则 = %d, 期望 1", n)
+	}
+	return g
+}
+
+// searches 用 Dijkstra 和 A* 分别搜索
+func searches(g *algo.Graph, from, to string, opts algo.SearchOptions) map[string]algo.PathResult {
+	return map[string]algo.PathResult{
+		"Dijkstra": g.DijkstraWithOptions(from, to, opts),
+		"A*":       g.AStar(from, to, opts),
+	}
+}
+
+func TestTurnRestrictionCarDetour(t *testing.T) {
+	g := turnGraph(t)
+	for name, r := range searches(g, "A", "C", algo.SearchOptions{ModeMask: model.ModeCar}) {
+		if want := []string{"A", "B", "D", "C"}; !r.Found || len(r.Path) != len(want) || r.Path[2] != "D" {
+			t.Errorf("%s: 驾车路径 = %v，期望绕行 %v", name, r.Path, want)
+		}
+	}
+}
+
+func TestTurnRestrictionWalkCannotResumeDriving(t *testing.T) {
+	g := turnGraph(t)
+	for name, r := range searches(g, "A", "E", algo.SearchOptions{ModeMask: model.ModeCar | model.ModeWalk}) {
+		if !r.Found {
+			t.Errorf("%s: 没有找到路径", name)
+			continue
+		}
+		// 驾车到达 B 后在禁止的转弯处改为步行，再在 C 重新驾车，等于绕过了转弯规则
+		drove, parked := false, false
+		for _, seg := range r.Segments {
+			switch {
+			case model.IsDrivingMode(seg.UsedMode) && parked:
+				t.Errorf("%s: 驾车后改为步行又重新驾车: %v", name, segmentModes(r))
+			case model.IsDrivingMode(seg.UsedMode):
+				drove = true
+			case drove:
+				parked = true
+			}
+		}
+	}
+}
+
+func TestTurnSearchKeepsCarLabel(t *testing.T) {
+	// 从 S 步行到 B 比驾车绕行 K 更快，但从 B 继续驾车到 E 时已经在车上的路线更快 (不需要重新准备车辆)
+	at := func(id string, north, east float64) model.Node {
+		return model.Node{ID: id, Lat: 34.80 + north/111195, Lng: 113.50 + east/91345}
+	}
+	g := algo.NewGraphBuilder().
+		AddNode(at("S", 0, 0)).
+		AddNode(at("K", 100, 50)).
+		AddNode(at("B", 0, 100)).
+		AddNode(at("C", 0, 150)).
+		AddNode(at("E", 0, 3150)).
+		AddEdge(model.Edge{From: "S", To: "B", Dist: 100, Modes: []string{"walk"}}).
+		AddEdge(model.Edge{From: "S", To: "K", Dist: 200, Modes: []string{"car"}}).
+		AddEdge(model.Edge{From: "K", To: "B", Dist: 200, Modes: []string{"car"}}).
+		AddEdge(model.Edge{From: "B", To: "C", Dist: 50, Modes: []string{"walk", "car"}}).
+		AddEdge(model.Edge{From: "C", To: "E", Dist: 3000, Modes: []string{"car"}}).
+		MustBuild()
+
+	for name, r := range searches(g, "S", "E", algo.SearchOptions{ModeMask: model.ModeCar | model.ModeWalk}) {
+		if !r.Found {
+			t.Errorf("%s: 没有找到路径", name)
+			continue
+		}
+		for _, seg := range r.Segments {
+			if seg.UsedMode != "car" {
+				t.Errorf("%s: 路径 = %v，期望全程驾车 (步行到达 B 的标签不应覆盖驾车到达的标签)", name, segmentModes(r))
+				break
+			}
+		}
+	}
+}
+
+// segmentModes 路径各段的起终点和交通方式 (用于错误信息)
+func segmentModes(r algo.PathResult) []string {
+	var modes []string
+	for _, seg := range r.Segments {
+		modes = append(modes, seg.FromID+"-"+seg.ToID+":"+seg.UsedMode)
+	}
+	return modes
+}
@@ -0,0 +1,103 @@
+package algo
+
+import (
+	"fmt"
+	"strings"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// MapEdit 假设的地图修改 (what-if 分析、用户手绘的临时路段)，只在查询时生效，不修改共享的图和数据库
+type MapEdit struct {
+	AddNodes    []model.Node
+	AddEdges    []model.Edge // 与导入时一样，walk/bike/car/truck 的边自动生成反向边；dist 为 0 时按直线距离计算
+	RemoveNodes []string     // 删除的节点，进出该节点的边一并失效
+	RemoveEdges []db.EdgeRef // 删除的边，道路的反向边一并删除
+}
+
+// ApplyEdit 在叠加层 base (为空时为基础图) 之上新建一层，写入假设的修改；修改引用的节点或边不存在时返回错误
+// 返回的叠加层通过 SearchOptions.Overlay 使用
+func (g *Graph) ApplyEdit(base *Overlay, edit MapEdit) (*Overlay, error) {
+	ov := NewOverlay(base)
+
+	for _, node := range edit.AddNodes {
+		if node.ID == "" {
+			return nil, fmt.Errorf("新增节点缺少 ID")
+		}
+		if strings.HasPrefix(node.ID, "@") {
+			return nil, fmt.Errorf("节点 ID 不能以 @ 开头: %s", node.ID) // 保留给吸附到道路上的虚拟起终点
+		}
+		if g.NodeIn(ov, node.ID) != nil {
+			return nil, fmt.Errorf("节点已存在: %s", node.ID)
+		}
+		if !utils.ValidCoordinate(node.Lat, node.Lng) {
+			return nil, fmt.Errorf("节点坐标超出范围: %s", node.ID)
+		}
+		ov.AddNode(node)
+	}
+
+	for i := range edit.AddEdges {
+		edge := edit.AddEdges[i]
+		from, to := g.NodeIn(ov, edge.From), g.NodeIn(ov, edge.To)
+		if from == nil || to == nil {
+			return nil, fmt.Errorf("新增边的节点不存在: %s -> %s", edge.From, edge.To)
+		}
+		if edge.From == edge.To {
+			return nil, fmt.Errorf("新增边的起点和终点相同: %s", edge.From)
+		}
+		edge.ID = 0
+		if edge.EdgeModeMask() == 0 {
+			return nil, fmt.Errorf("新增边没有有效的交通方式: %s -> %s", edge.From, edge.To)
+		}
+		if !model.IsValidOneway(edge.Oneway) {
+			return nil, fmt.Errorf("新增边的 oneway 无效: %s -> %s", edge.From, edge.To)
+		}
+		if !model.IsValidRoadClass(edge.RoadClass) {
+			return nil, fmt.Errorf("新增边的 road_class 无效: %s -> %s", edge.From, edge.To)
+		}
+		if !model.IsValidFootway(edge.Footway) {
+			return nil, fmt.Errorf("新增边的 footway 无效: %s -> %s", edge.From, edge.To)
+		}
+		if !model.IsValidConnector(edge.Connector) {
+			return nil, fmt.Errorf("新增边的 connector 无效: %s -> %s", edge.From, edge.To)
+		}
+		if edge.Dist <= 0 {
+			edge.Dist = utils.HaversineDistance(model.Point{Lat: from.Lat, Lng: from.Lng}, model.Point{Lat: to.Lat, Lng: to.Lng})
+		}
+		ov.AddEdge(&edge)
+		if rev := reverseOf(&edge); rev != nil {
+			ov.AddEdge(rev)
+		}
+	}
+
+	for _, ref := range edit.RemoveEdges {
+		found := false
+		for _, edge := range g.edgesIn(base, ref.From) {
+			if edge.To != ref.To || edge.LineID != ref.LineID {
+				continue
+			}
+			found = true
+			ov.RemoveEdge(edge)
+			if edge.LineID == "" {
+				for _, rev := range g.edgesIn(base, edge.To) {
+					if rev.To == edge.From && rev.LineID == "" {
+						ov.RemoveEdge(rev)
+						break
+					}
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("边不存在: %s -> %s", ref.From, ref.To)
+		}
+	}
+
+	for _, id := range edit.RemoveNodes {
+		if g.NodeIn(base, id) == nil {
+			return nil, fmt.Errorf("节点不存在: %s", id)
+		}
+		ov.RemoveNode(id)
+	}
+	return ov, nil
+}
@@ -0,0 +1,118 @@
+package algo
+
+import (
+	"fmt"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// ZoneFeePenalty 驶入收费区域时每元费用折算的搜索成本 (秒)，只影响路线选择，不计入预计时间
+const ZoneFeePenalty = 120.0
+
+// zone 带驾车规则的区域
+type zone struct {
+	rules []model.ZoneRule
+}
+
+// zoneIndex 区域规则索引 (边 -> 经过的区域)
+type zoneIndex struct {
+	zones    []zone
+	edges    map[*model.Edge][]int // 经过 (端点在内或穿过) 区域的边
+	entering map[*model.Edge][]int // 从区域外驶入区域的边 (用于收费)
+}
+
+// SetZones 设置驾车区域规则 (禁行、收费、尾号限行、低排放区)，返回受影响的边数
+// 只有启用的围栏和规则生效，可以在服务运行中调用
+func (g *Graph) SetZones(fences []model.Geofence, rules []model.ZoneRule) int {
+	byFence := make(map[uint][]model.ZoneRule)
+	for _, r := range rules {
+		if r.Active {
+			byFence[r.GeofenceID] = append(byFence[r.GeofenceID], r)
+		}
+	}
+
+	index := &zoneIndex{
+		edges:    make(map[*model.Edge][]int),
+		entering: make(map[*model.Edge][]int),
+	}
+	for _, fence := range fences {
+		if !fence.Active || len(byFence[fence.ID]) == 0 || len(fence.Polygon) < 3 {
+			continue
+		}
+		i := len(index.zones)
+		index.zones = append(index.zones, zone{rules: byFence[fence.ID]})
+
+		for from, edges := range g.AdjList {
+			fromNode := g.Nodes[from]
+			if fromNode == nil {
+				continue
+			}
+			fromPt := model.Point{Lat: fromNode.Lat, Lng: fromNode.Lng}
+			fromInside := utils.PointInPolygon(fromPt, fence.Polygon)
+			for _, edge := range edges {
+				if edge.ModeMask&model.ModeCar == 0 {
+					continue
+				}
+				toNode := g.Nodes[edge.To]
+				if toNode == nil {
+					continue
+				}
+				toPt := model.Point{Lat: toNode.Lat, Lng: toNode.Lng}
+				if !fromInside && !utils.SegmentIntersectsPolygon(fromPt, toPt, fence.Polygon) {
+					continue
+				}
+				index.edges[edge] = append(index.edges[edge], i)
+				if !fromInside {
+					index.entering[edge] = append(index.entering[edge], i)
+				}
+			}
+		}
+	}
+
+	g.zones.Store(index)
+	return len(index.edges)
+}
+
+// ReloadZones 从数据库重新加载启用的围栏和区域规则，返回受影响的边数
+func (g *Graph) ReloadZones() (int, error) {
+	var fences []model.Geofence
+	if err := db.DB.Where("active = ?", true).Find(&fences).Error; err != nil {
+		return 0, fmt.Errorf("查询地理围栏失败: %w", err)
+	}
+	var rules []model.ZoneRule
+	if err := db.DB.Where("active = ?", true).Find(&rules).Error; err != nil {
+		return 0, fmt.Errorf("查询区域规则失败: %w", err)
+	}
+	return g.SetZones(fences, rules), nil
+}
+
+// forbids 车辆在给定时刻能否驾车通过该边
+func (t *zoneIndex) forbids(edge *model.Edge, vehicle *model.Vehicle, at time.Time) bool {
+	if t == nil {
+		return false
+	}
+	for _, i := range t.edges[edge] {
+		for _, r := range t.zones[i].rules {
+			if r.Forbids(vehicle, at) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fee 驾车经过该边驶入收费区域需要支付的费用 (元)
+func (t *zoneIndex) fee(edge *model.Edge, vehicle *model.Vehicle, at time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	total := 0.0
+	for _, i := range t.entering[edge] {
+		for _, r := range t.zones[i].rules {
+			total += r.Charges(vehicle, at)
+		}
+	}
+	return total
+}
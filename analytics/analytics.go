@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+)
+
+// 写入队列参数：事件先放入内存队列，由后台协程批量写入数据库，请求处理不等待数据库
+const (
+	queueSize = 4096
+	batchSize = 500
+)
+
+var (
+	enabled atomic.Bool
+	queue   = make(chan model.UsageEvent, queueSize)
+	dropped atomic.Int64 // 队列已满被丢弃的事件数 (下次写入时输出日志)
+)
+
+// Record 记录一条使用事件，未启动或队列已满时直接丢弃 (不影响接口响应)
+func Record(event model.UsageEvent) {
+	if !enabled.Load() {
+		return
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	select {
+	case queue <- event:
+	default:
+		dropped.Add(1)
+	}
+}
+
+// Enabled 是否正在记录使用事件
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start 开始记录使用事件：每隔 flushInterval (或攒够一批) 写入数据库，
+// retention 大于 0 时每小时删除超过保留时间的事件
+func Start(flushInterval, retention time.Duration) {
+	enabled.Store(true)
+	go writer(flushInterval)
+	if retention > 0 {
+		go func() {
+			for ; ; time.Sleep(time.Hour) {
+				result := db.DB.Where("created_at < ?", time.Now().Add(-retention)).Delete(&model.UsageEvent{})
+				if result.Error != nil {
+					slog.Error("清理使用事件失败", "error", result.Error)
+				} else if result.RowsAffected > 0 {
+					slog.Info("已清理过期的使用事件", "events", result.RowsAffected)
+				}
+			}
+		}()
+	}
+}
+
+// writer 从队列读取事件并批量写入数据库
+func writer(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.UsageEvent, 0, batchSize)
+	flush := func() {
+		if n := dropped.Swap(0); n > 0 {
+			slog.Warn("使用事件队列已满，丢弃了事件", "events", n)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := db.DB.CreateInBatches(batch, batchSize).Error; err != nil {
+			slog.Error("保存使用事件失败", "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event := <-queue:
+			batch = append(batch, event)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"gorm.io/gorm"
+)
+
+// HeatCell 热力图中的一个 geohash 网格
+type HeatCell struct {
+	Geohash string        `json:"geohash"`
+	Lat     float64       `json:"lat"` // 网格中心
+	Lng     float64       `json:"lng"`
+	Bounds  [2][2]float64 `json:"bounds"` // [[南, 西], [北, 东]]，可直接用于 Leaflet 矩形
+	Starts  int64         `json:"starts"` // 作为起点的次数
+	Ends    int64         `json:"ends"`   // 作为终点的次数
+	Total   int64         `json:"total"`
+}
+
+// Heatmap 把 [from, to) 内路径规划的起终点按 geohash 网格聚合，按次数从多到少排列
+// withStarts / withEnds 控制是否统计起点、终点，mode 不为空时只统计该主要交通方式的路线
+func Heatmap(from, to time.Time, precision int, withStarts, withEnds bool, mode string) ([]HeatCell, error) {
+	query := db.Reader().Model(&model.UsageEvent{}).
+		Select("id, start_lat, start_lng, end_lat, end_lng").
+		Where("kind = ? AND created_at >= ? AND created_at < ?", model.UsageRoute, from, to)
+	if mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+
+	cells := make(map[string]*HeatCell)
+	add := func(lat, lng float64, start bool) {
+		hash := utils.GeohashEncode(lat, lng, precision)
+		cell := cells[hash]
+		if cell == nil {
+			sw, ne := utils.GeohashBounds(hash)
+			cell = &HeatCell{
+				Geohash: hash,
+				Lat:     (sw.Lat + ne.Lat) / 2,
+				Lng:     (sw.Lng + ne.Lng) / 2,
+				Bounds:  [2][2]float64{{sw.Lat, sw.Lng}, {ne.Lat, ne.Lng}},
+			}
+			cells[hash] = cell
+		}
+		if start {
+			cell.Starts++
+		} else {
+			cell.Ends++
+		}
+		cell.Total++
+	}
+
+	var batch []model.UsageEvent
+	err := query.FindInBatches(&batch, 5000, func(tx *gorm.DB, _ int) error {
+		for _, e := range batch {
+			if withStarts {
+				add(e.StartLat, e.StartLng, true)
+			}
+			if withEnds {
+				add(e.EndLat, e.EndLng, false)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]HeatCell, 0, len(cells))
+	for _, cell := range cells {
+		result = append(result, *cell)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Geohash < result[j].Geohash
+	})
+	return result, nil
+}
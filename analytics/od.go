@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/parquet-go/parquet-go"
+	"gorm.io/gorm"
+)
+
+// ODPair 一组起终点区域间以某种主要交通方式出行的次数 (不含用户和时间信息)
+type ODPair struct {
+	Origin         string  `json:"origin" parquet:"origin"` // 起点区域 (geohash 或节点 ID)
+	OriginLat      float64 `json:"origin_lat" parquet:"origin_lat"`
+	OriginLng      float64 `json:"origin_lng" parquet:"origin_lng"`
+	Destination    string  `json:"destination" parquet:"destination"`
+	DestinationLat float64 `json:"destination_lat" parquet:"destination_lat"`
+	DestinationLng float64 `json:"destination_lng" parquet:"destination_lng"`
+	Mode           string  `json:"mode" parquet:"mode"`
+	Trips          int64   `json:"trips" parquet:"trips"`
+}
+
+// ODOptions OD 统计参数
+type ODOptions struct {
+	Precision int   // 区域的 geohash 位数；为 0 时按节点统计 (不输出坐标，由调用方按路网节点填写)
+	MinCount  int64 // 次数少于该值的组合不输出 (匿名阈值)
+}
+
+// ODMatrix 统计 [from, to) 内路径规划的 OD 对，返回达到匿名阈值的组合和被隐藏的组合数
+// 结果按次数从多到少排列
+func ODMatrix(from, to time.Time, opts ODOptions) ([]ODPair, int, error) {
+	type zone struct {
+		id       string
+		lat, lng float64
+	}
+	zoneOf := func(nodeID string, lat, lng float64) zone {
+		if opts.Precision == 0 {
+			return zone{id: nodeID} // 事件中的坐标是用户的原始位置，不能作为节点的坐标输出
+		}
+		hash := utils.GeohashEncode(lat, lng, opts.Precision)
+		sw, ne := utils.GeohashBounds(hash)
+		return zone{hash, (sw.Lat + ne.Lat) / 2, (sw.Lng + ne.Lng) / 2}
+	}
+
+	type key struct{ origin, destination, mode string }
+	pairs := make(map[key]*ODPair)
+	var batch []model.UsageEvent
+	err := db.Reader().Model(&model.UsageEvent{}).
+		Select("id, mode, start_id, end_id, start_lat, start_lng, end_lat, end_lng").
+		Where("kind = ? AND created_at >= ? AND created_at < ?", model.UsageRoute, from, to).
+		FindInBatches(&batch, 5000, func(tx *gorm.DB, _ int) error {
+			for _, e := range batch {
+				if opts.Precision == 0 && (e.StartID == "" || e.EndID == "") {
+					continue // 没有经过路网节点的路线
+				}
+				o := zoneOf(e.StartID, e.StartLat, e.StartLng)
+				d := zoneOf(e.EndID, e.EndLat, e.EndLng)
+				k := key{o.id, d.id, e.Mode}
+				pair := pairs[k]
+				if pair == nil {
+					pair = &ODPair{
+						Origin: o.id, OriginLat: o.lat, OriginLng: o.lng,
+						Destination: d.id, DestinationLat: d.lat, DestinationLng: d.lng,
+						Mode: e.Mode,
+					}
+					pairs[k] = pair
+				}
+				pair.Trips++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]ODPair, 0, len(pairs))
+	suppressed := 0
+	for _, pair := range pairs {
+		if pair.Trips < opts.MinCount {
+			suppressed++
+			continue
+		}
+		result = append(result, *pair)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Trips != b.Trips {
+			return a.Trips > b.Trips
+		}
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.Mode < b.Mode
+	})
+	return result, suppressed, nil
+}
+
+// WriteODCSV 以 CSV 格式 (带表头) 输出 OD 对
+func WriteODCSV(w io.Writer, pairs []ODPair) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"origin", "origin_lat", "origin_lng", "destination", "destination_lat", "destination_lng", "mode", "trips"})
+	coord := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for _, p := range pairs {
+		cw.Write([]string{
+			p.Origin, coord(p.OriginLat), coord(p.OriginLng),
+			p.Destination, coord(p.DestinationLat), coord(p.DestinationLng),
+			p.Mode, strconv.FormatInt(p.Trips, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteODParquet 以 Parquet 格式 (Snappy 压缩) 输出 OD 对
+func WriteODParquet(w io.Writer, pairs []ODPair) error {
+	pw := parquet.NewGenericWriter[ODPair](w, parquet.Compression(&parquet.Snappy))
+	if _, err := pw.Write(pairs); err != nil {
+		return err
+	}
+	return pw.Close()
+}
@@ -0,0 +1,142 @@
+package analytics
+
+import (
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// EndpointCount 某个接口的请求数
+type EndpointCount struct {
+	Method   string `json:"method"`
+	Endpoint string `json:"endpoint"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"` // 状态码 >= 400 的请求数
+}
+
+// NodeCount 作为起点或终点的次数
+type NodeCount struct {
+	NodeID string `json:"node_id"`
+	Name   string `json:"name,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+// ModeCount 以某种交通方式为主的路线数
+type ModeCount struct {
+	Mode   string  `json:"mode"`
+	Routes int64   `json:"routes"`
+	Share  float64 `json:"share"` // 占全部路线的比例
+}
+
+// ETAError 上报行程的实际用时与导航预计时间的误差
+type ETAError struct {
+	Samples      int64   `json:"samples"`
+	MeanAbsError float64 `json:"mean_abs_error"` // 平均绝对误差 (秒)
+	MeanError    float64 `json:"mean_error"`     // 平均误差 (秒，实际 - 预计，正数表示比预计慢)
+}
+
+// DailyUsers 某一天的活跃用户数 (有过请求的登录用户)
+type DailyUsers struct {
+	Date  string `json:"date"`
+	Users int64  `json:"users"`
+}
+
+// Stats 一段时间内的使用统计
+type Stats struct {
+	From             time.Time       `json:"from"`
+	To               time.Time       `json:"to"`
+	Requests         int64           `json:"requests"`
+	Endpoints        []EndpointCount `json:"endpoints"`
+	Routes           int64           `json:"routes"`
+	TopOrigins       []NodeCount     `json:"top_origins"`
+	TopDestinations  []NodeCount     `json:"top_destinations"`
+	Modes            []ModeCount     `json:"modes"`
+	ETAError         *ETAError       `json:"eta_error"` // 没有带预计时间的行程上报时为空
+	DailyActiveUsers []DailyUsers    `json:"daily_active_users"`
+}
+
+// Compute 统计 [from, to) 内的使用事件，热门起终点各返回前 limit 个
+func Compute(from, to time.Time, limit int) (*Stats, error) {
+	stats := &Stats{
+		From:             from,
+		To:               to,
+		Endpoints:        []EndpointCount{},
+		TopOrigins:       []NodeCount{},
+		TopDestinations:  []NodeCount{},
+		Modes:            []ModeCount{},
+		DailyActiveUsers: []DailyUsers{},
+	}
+	events := func(kind string) *gorm.DB {
+		query := db.Reader().Model(&model.UsageEvent{}).Where("created_at >= ? AND created_at < ?", from, to)
+		if kind != "" {
+			query = query.Where("kind = ?", kind)
+		}
+		return query
+	}
+
+	err := events(model.UsageRequest).
+		Select("method, endpoint, COUNT(*) AS requests, COUNT(*) FILTER (WHERE status >= 400) AS errors").
+		Group("method, endpoint").
+		Order("requests DESC, endpoint").
+		Scan(&stats.Endpoints).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range stats.Endpoints {
+		stats.Requests += e.Requests
+	}
+
+	err = events(model.UsageRoute).Where("start_id <> ''").
+		Select("start_id AS node_id, COUNT(*) AS count").
+		Group("start_id").Order("count DESC, start_id").Limit(limit).
+		Scan(&stats.TopOrigins).Error
+	if err != nil {
+		return nil, err
+	}
+	err = events(model.UsageRoute).Where("end_id <> ''").
+		Select("end_id AS node_id, COUNT(*) AS count").
+		Group("end_id").Order("count DESC, end_id").Limit(limit).
+		Scan(&stats.TopDestinations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = events(model.UsageRoute).
+		Select("mode, COUNT(*) AS routes").
+		Group("mode").Order("routes DESC, mode").
+		Scan(&stats.Modes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range stats.Modes {
+		stats.Routes += m.Routes
+	}
+	for i := range stats.Modes {
+		stats.Modes[i].Share = float64(stats.Modes[i].Routes) / float64(stats.Routes)
+	}
+
+	var eta ETAError
+	err = events(model.UsageTrip).Where("estimated_time > 0 AND actual_time > 0").
+		Select("COUNT(*) AS samples, " +
+			"COALESCE(AVG(ABS(actual_time - estimated_time)), 0) AS mean_abs_error, " +
+			"COALESCE(AVG(actual_time - estimated_time), 0) AS mean_error").
+		Scan(&eta).Error
+	if err != nil {
+		return nil, err
+	}
+	if eta.Samples > 0 {
+		stats.ETAError = &eta
+	}
+
+	day := db.DateExpr("created_at")
+	err = events("").Where("user_id <> 0").
+		Select(day + " AS date, COUNT(DISTINCT user_id) AS users").
+		Group(day).Order("date").
+		Scan(&stats.DailyActiveUsers).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,413 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/model"
+)
+
+// 压测工具
+// 按固定 QPS 发送路径规划请求 (开环：不等上一个请求返回)，统计延迟分位数和错误率，
+// 被测对象可以是运行中的服务 (HTTPTarget)，也可以是进程内的路网 (GraphTarget)
+
+// Query 一次路径规划请求，格式与 POST /api/path/find 的请求体相同
+// 也可以直接读取 usage_events 表中 kind = route 的记录 (modes 为逗号分隔的字符串)
+type Query struct {
+	StartID  string   `json:"start_id,omitempty"`
+	EndID    string   `json:"end_id,omitempty"`
+	StartLat float64  `json:"start_lat,omitempty"`
+	StartLng float64  `json:"start_lng,omitempty"`
+	EndLat   float64  `json:"end_lat,omitempty"`
+	EndLng   float64  `json:"end_lng,omitempty"`
+	Modes    []string `json:"modes,omitempty"`
+}
+
+// UnmarshalJSON 兼容 modes 为逗号分隔字符串的记录；吸附到边上的虚拟起终点按坐标重新规划
+func (q *Query) UnmarshalJSON(data []byte) error {
+	type plain Query // 没有 UnmarshalJSON 方法，避免递归
+	var raw struct {
+		plain
+		Modes json.RawMessage `json:"modes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*q = Query(raw.plain)
+	q.Modes = nil
+	if len(raw.Modes) > 0 && string(raw.Modes) != "null" {
+		var list string
+		if err := json.Unmarshal(raw.Modes, &list); err == nil {
+			for _, m := range strings.Split(list, ",") {
+				if m = strings.TrimSpace(m); m != "" {
+					q.Modes = append(q.Modes, m)
+				}
+			}
+		} else if err := json.Unmarshal(raw.Modes, &q.Modes); err != nil {
+			return fmt.Errorf("modes 格式错误: %w", err)
+		}
+	}
+	if strings.HasPrefix(q.StartID, "@") {
+		q.StartID = ""
+	}
+	if strings.HasPrefix(q.EndID, "@") {
+		q.EndID = ""
+	}
+	return nil
+}
+
+// LoadQueries 读取记录的请求 (每行一个 JSON 对象，空行忽略)
+func LoadQueries(r io.Reader) ([]Query, error) {
+	var queries []Query
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var q Query
+		if err := json.Unmarshal(text, &q); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+		if (q.StartID == "" && q.StartLat == 0) || (q.EndID == "" && q.EndLat == 0) {
+			return nil, fmt.Errorf("第 %d 行: 缺少起点或终点", line)
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("没有可用的请求")
+	}
+	return queries, nil
+}
+
+// RandomQueries 从节点 ID 中随机抽取 n 组起终点 (使用固定种子，结果可复现)
+func RandomQueries(nodeIDs []string, n int, modes []string, seed int64) []Query {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	r := rand.New(rand.NewSource(seed))
+	queries := make([]Query, n)
+	for i := range queries {
+		queries[i] = Query{
+			StartID: nodeIDs[r.Intn(len(nodeIDs))],
+			EndID:   nodeIDs[r.Intn(len(nodeIDs))],
+			Modes:   modes,
+		}
+	}
+	return queries
+}
+
+// Outcome 一次请求的结果
+type Outcome struct {
+	Found bool   // 找到路径
+	Kind  string // 结果分类，如 ok、no_path、http_429
+	Err   error  // 请求失败 (计入错误率)
+}
+
+// Target 被压测的对象
+type Target interface {
+	Do(ctx context.Context, q Query) Outcome
+}
+
+// HTTPTarget 通过 HTTP 请求运行中的服务的 /api/path/find
+type HTTPTarget struct {
+	BaseURL string       // 如 http://localhost:8080
+	APIKey  string       // X-API-Key 请求头 (可选，用于避开匿名限流)
+	Client  *http.Client // 为空时使用 http.DefaultClient
+}
+
+// Do 发送一次路径规划请求，非 2xx 响应和网络错误计为失败
+func (t *HTTPTarget) Do(ctx context.Context, q Query) Outcome {
+	body, _ := json.Marshal(q)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(t.BaseURL, "/")+"/api/v1/path/find", bytes.NewReader(body))
+	if err != nil {
+		return Outcome{Kind: "error", Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.APIKey != "" {
+		req.Header.Set("X-API-Key", t.APIKey)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Outcome{Kind: "cancelled", Err: err}
+		}
+		return Outcome{Kind: "network_error", Err: err}
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Found bool `json:"found"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Outcome{Kind: "http_" + strconv.Itoa(resp.StatusCode), Err: fmt.Errorf("HTTP %d", resp.StatusCode)}
+	}
+	if err != nil {
+		return Outcome{Kind: "bad_response", Err: err}
+	}
+	if !result.Found {
+		return Outcome{Kind: "no_path"}
+	}
+	return Outcome{Found: true, Kind: "ok"}
+}
+
+// GraphTarget 在进程内直接调用路网搜索 (不经过 HTTP、缓存和限流，用于测量算法本身)
+type GraphTarget struct {
+	Graph *algo.Graph
+}
+
+// Do 按与接口相同的方式规划路径：起终点为节点时直接搜索，为坐标时吸附到最近的边
+func (t *GraphTarget) Do(ctx context.Context, q Query) Outcome {
+	modes := q.Modes
+	if len(modes) == 0 {
+		modes = []string{"walk", "bus", "subway"}
+	}
+	opts := algo.SearchOptions{ModeMask: model.ParseModes(modes), Context: ctx}
+	start, ok := t.waypoint(q.StartID, q.StartLat, q.StartLng, opts.ModeMask)
+	if !ok {
+		return Outcome{Kind: "node_not_found", Err: fmt.Errorf("起点不存在: %s", q.StartID)}
+	}
+	end, ok := t.waypoint(q.EndID, q.EndLat, q.EndLng, opts.ModeMask)
+	if !ok {
+		return Outcome{Kind: "node_not_found", Err: fmt.Errorf("终点不存在: %s", q.EndID)}
+	}
+
+	result := t.Graph.RouteBetween(start, end, opts)
+	switch {
+	case result.Cancelled:
+		return Outcome{Kind: "cancelled", Err: ctx.Err()}
+	case result.Found:
+		return Outcome{Found: true, Kind: "ok"}
+	default:
+		return Outcome{Kind: "no_path"}
+	}
+}
+
+// waypoint 节点 ID 或坐标对应的路径端点
+func (t *GraphTarget) waypoint(id string, lat, lng float64, mask int) (algo.Waypoint, bool) {
+	if id != "" {
+		return algo.Waypoint{NodeID: id}, t.Graph.Nodes[id] != nil
+	}
+	snap := t.Graph.SnapToEdge(lat, lng, mask)
+	if snap == nil {
+		return algo.Waypoint{}, false
+	}
+	return algo.Waypoint{NodeID: snap.Edge.From, Snap: snap}, true
+}
+
+// LoadConfig 压测参数
+type LoadConfig struct {
+	QPS         float64       // 每秒发送的请求数
+	Duration    time.Duration // 持续时间
+	Concurrency int           // 同时进行的请求上限，达到上限时本次请求跳过 (计入 Dropped)
+}
+
+// LoadReport 压测结果 (时间单位为毫秒)
+type LoadReport struct {
+	QPS         float64        `json:"qps"`          // 目标 QPS
+	AchievedQPS float64        `json:"achieved_qps"` // 实际完成的请求数 / 持续时间
+	Duration    float64        `json:"duration_s"`
+	Requests    int            `json:"requests"` // 完成的请求数
+	Found       int            `json:"found"`
+	NoPath      int            `json:"no_path"`
+	Errors      int            `json:"errors"`
+	Dropped     int            `json:"dropped"` // 并发达到上限而没有发出的请求
+	ErrorRate   float64        `json:"error_rate"`
+	Latency     LatencyStats   `json:"latency_ms"`
+	Outcomes    map[string]int `json:"outcomes"` // 按结果分类的请求数
+}
+
+// LatencyStats 延迟统计 (毫秒)
+type LatencyStats struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// RunLoad 按 cfg.QPS 依次 (循环) 发送 queries 中的请求，持续 cfg.Duration 或直到 ctx 取消
+// 请求按固定间隔发出，不等待上一个请求返回，因此服务变慢时延迟会如实上升
+func RunLoad(ctx context.Context, target Target, queries []Query, cfg LoadConfig) *LoadReport {
+	report := &LoadReport{QPS: cfg.QPS, Outcomes: make(map[string]int)}
+	if len(queries) == 0 || cfg.QPS <= 0 {
+		return report
+	}
+	concurrency := max(cfg.Concurrency, 1)
+	interval := time.Duration(float64(time.Second) / cfg.QPS)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+	)
+	slots := make(chan struct{}, concurrency)
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+
+loop:
+	for i := 0; ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		if !next.Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-time.After(time.Until(next)):
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			mu.Lock()
+			report.Dropped++
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(q Query) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			begin := time.Now()
+			outcome := target.Do(ctx, q)
+			elapsed := time.Since(begin)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Requests++
+			report.Outcomes[outcome.Kind]++
+			latencies = append(latencies, elapsed)
+			switch {
+			case outcome.Err != nil:
+				report.Errors++
+			case outcome.Found:
+				report.Found++
+			default:
+				report.NoPath++
+			}
+		}(queries[i%len(queries)])
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	report.Duration = elapsed.Seconds()
+	if elapsed > 0 {
+		report.AchievedQPS = float64(report.Requests) / elapsed.Seconds()
+	}
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	}
+	report.Latency = latencyStats(latencies)
+	return report
+}
+
+// latencyStats 计算平均值和分位数 (最近秩法)
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	slices.Sort(latencies)
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	percentile := func(p float64) float64 {
+		idx := int(math.Ceil(p*float64(len(latencies)))) - 1
+		return ms(latencies[min(max(idx, 0), len(latencies)-1)])
+	}
+	var total time.Duration
+	for _, d := range latencies {
+		total += d
+	}
+	return LatencyStats{
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  percentile(0.50),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+}
+
+// SLO 延迟和错误率目标，为 0 的项不检查
+type SLO struct {
+	P50, P95, P99 time.Duration
+	MaxErrorRate  float64
+}
+
+// ParseSLO 解析 SLO，如 p95=300ms,p99=1s,errors=0.5% (errors 也可以写成小数 0.005)
+func ParseSLO(s string) (SLO, error) {
+	var slo SLO
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return slo, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return slo, fmt.Errorf("格式错误: %q", item)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "errors" {
+			rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if strings.HasSuffix(value, "%") {
+				rate /= 100
+			}
+			if err != nil || rate < 0 || rate > 1 {
+				return slo, fmt.Errorf("无效的错误率: %q", item)
+			}
+			slo.MaxErrorRate = rate
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return slo, fmt.Errorf("无效的延迟: %q", item)
+		}
+		switch name {
+		case "p50":
+			slo.P50 = d
+		case "p95":
+			slo.P95 = d
+		case "p99":
+			slo.P99 = d
+		default:
+			return slo, fmt.Errorf("未知的指标: %q (可选 p50、p95、p99、errors)", item)
+		}
+	}
+	return slo, nil
+}
+
+// CheckSLO 返回没有达到的目标 (全部达到时为空)
+func (r *LoadReport) CheckSLO(slo SLO) []string {
+	var failed []string
+	check := func(name string, limit time.Duration, actual float64) {
+		if limit > 0 && actual > float64(limit.Microseconds())/1000 {
+			failed = append(failed, fmt.Sprintf("%s 延迟 %.1fms 超过目标 %s", name, actual, limit))
+		}
+	}
+	check("p50", slo.P50, r.Latency.P50)
+	check("p95", slo.P95, r.Latency.P95)
+	check("p99", slo.P99, r.Latency.P99)
+	if slo.MaxErrorRate > 0 && r.ErrorRate > slo.MaxErrorRate {
+		failed = append(failed, fmt.Sprintf("错误率 %.2f%% 超过目标 %.2f%%", r.ErrorRate*100, slo.MaxErrorRate*100))
+	}
+	return failed
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	"traffic-system/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store 带过期时间的键值存储
+// 单实例部署使用进程内存储；设置 REDIS_URL 后使用 Redis，多个实例共享路径缓存、令牌黑名单和限流计数
+type Store interface {
+	// Get 读取键，不存在或已过期时返回 false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set 写入键，ttl 为 0 表示不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete 删除键
+	Delete(ctx context.Context, key string) error
+	// Incr 计数加一并返回新值，键新建时设置过期时间 ttl (用于固定窗口限流)
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Default 全局存储 (应在 main 中通过 Init 初始化)
+var Default Store = NewMemory()
+
+// Init 根据环境变量初始化全局存储：设置了 REDIS_URL 时连接 Redis，否则使用进程内存储
+func Init() {
+	url := config.GetString("REDIS_URL", "")
+	if url == "" {
+		return
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		log.Fatalf("REDIS_URL 配置错误: %v", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Fatalf("无法连接 Redis: %v", err)
+	}
+	Default = NewRedis(client, config.GetString("REDIS_PREFIX", "vv:"))
+	slog.Info("使用 Redis 作为共享缓存", "addr", opts.Addr)
+}
+
+// Redis 基于 Redis 的存储，所有键加上 prefix
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis 创建基于 Redis 的存储
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
+
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	key = r.prefix + key
+	n, err := r.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 第一次计数时设置过期时间 (不使用 EXPIRE NX，兼容 Redis 7 之前的版本)
+	if n == 1 && ttl > 0 {
+		if err := r.client.Expire(ctx, key, ttl).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// sweepInterval 进程内存储清理过期键的间隔
+const sweepInterval = time.Minute
+
+// Memory 进程内存储，过期的键在读取时或定期清理时删除
+type Memory struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	value     []byte
+	counter   int64
+	expiresAt time.Time // 零值表示不过期
+}
+
+// NewMemory 创建进程内存储
+func NewMemory() *Memory {
+	return &Memory{items: make(map[string]memoryItem), lastSweep: time.Now()}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.lookup(key, time.Now())
+	if ok && item.value == nil && item.counter > 0 {
+		// 计数键返回十进制字符串，与 Redis 一致
+		return []byte(strconv.FormatInt(item.counter, 10)), true, nil
+	}
+	return item.value, ok, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = memoryItem{value: value, expiresAt: expiry(now, ttl)}
+	m.sweep(now)
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.lookup(key, now)
+	if !ok {
+		item = memoryItem{expiresAt: expiry(now, ttl)}
+	}
+	item.counter++
+	m.items[key] = item
+	m.sweep(now)
+	return item.counter, nil
+}
+
+// lookup 读取未过期的键 (需持有锁)
+func (m *Memory) lookup(key string, now time.Time) (memoryItem, bool) {
+	item, ok := m.items[key]
+	if !ok {
+		return memoryItem{}, false
+	}
+	if !item.expiresAt.IsZero() && now.After(item.expiresAt) {
+		delete(m.items, key)
+		return memoryItem{}, false
+	}
+	return item, true
+}
+
+// sweep 每隔 sweepInterval 删除一次所有过期的键 (需持有锁)
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, item := range m.items {
+		if !item.expiresAt.IsZero() && now.After(item.expiresAt) {
+			delete(m.items, key)
+		}
+	}
+}
+
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
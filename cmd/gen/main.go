@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"traffic-system/algo/gen"
+)
+
+// 生成合成城市的地图数据 (map_data.json 格式)，用于压测和演示
+// 用法:
+//
+//	go run ./cmd/gen -size 60 -o city.json
+//	go run ./cmd/gen -size 150 -subway 8 -bus 60 -seed 7 -o big.json
+func main() {
+	size := flag.Int("size", 30, "街道网格边长 (路口数为 size x size)，其余参数的默认值随规模增加")
+	spacing := flag.Float64("spacing", 200, "相邻路口的距离 (米)")
+	rings := flag.Int("rings", -1, "环路数 (-1 表示按规模)")
+	subway := flag.Int("subway", -1, "地铁线路数 (-1 表示按规模)")
+	bus := flag.Int("bus", -1, "公交线路数 (-1 表示按规模)")
+	seed := flag.Int64("seed", 1, "随机种子")
+	out := flag.String("o", "", "输出文件 (为空时输出到标准输出)")
+	flag.Parse()
+
+	cfg := gen.Sized(*size)
+	cfg.Spacing, cfg.Seed = *spacing, *seed
+	if *rings >= 0 {
+		cfg.Rings = *rings
+	}
+	if *subway >= 0 {
+		cfg.SubwayLines = *subway
+	}
+	if *bus >= 0 {
+		cfg.BusRoutes = *bus
+	}
+
+	data, err := gen.Generate(cfg)
+	if err != nil {
+		log.Fatalf("生成失败: %v", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			log.Fatalf("创建输出文件失败: %v", err)
+		}
+		defer w.Close()
+	}
+	buf := bufio.NewWriter(w)
+	if err := gen.WriteJSON(buf, data); err != nil {
+		log.Fatalf("写入失败: %v", err)
+	}
+	if err := buf.Flush(); err != nil {
+		log.Fatalf("写入失败: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "已生成: %d 个节点，%d 条边，%d 条线路\n", len(data.Nodes), len(data.Edges), len(data.Lines))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+	"traffic-system/golden"
+)
+
+// 黄金路线回归检查：重新规划 golden/routes.json 中的路线并与记录的结果比较，有差异时退出码为 1
+// 用法:
+//
+//	go run ./cmd/golden                     # 检查
+//	go run ./cmd/golden -run '^map/'        # 只检查名称匹配的路线
+//	go run ./cmd/golden -update             # 确认变化符合预期后重新记录
+func main() {
+	file := flag.String("file", "golden/routes.json", "黄金路线文件")
+	run := flag.String("run", "", "只处理名称匹配该正则表达式的路线")
+	update := flag.Bool("update", false, "用当前结果重新记录期望结果")
+	flag.Parse()
+
+	// 分时速度按本地时间选取，固定为北京时间，使结果与运行环境的时区无关
+	time.Local = time.FixedZone("CST", 8*3600)
+
+	f, err := golden.Load(*file)
+	if err != nil {
+		log.Fatalf("读取黄金路线失败: %v", err)
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		if filter, err = regexp.Compile(*run); err != nil {
+			log.Fatalf("-run 无效: %v", err)
+		}
+	}
+
+	runner := golden.NewRunner()
+	if *update {
+		n, err := runner.Update(f, filter)
+		if err != nil {
+			log.Fatalf("规划失败: %v", err)
+		}
+		if err := f.Save(*file); err != nil {
+			log.Fatalf("写入黄金路线失败: %v", err)
+		}
+		fmt.Printf("已重新记录 %d 条路线\n", n)
+		return
+	}
+
+	failures, checked := runner.Check(f, filter)
+	for _, failure := range failures {
+		fmt.Printf("FAIL %s\n", failure.Route)
+		for _, reason := range failure.Reasons {
+			fmt.Printf("    %s\n", reason)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Printf("%d / %d 条路线与记录不一致\n", len(failures), checked)
+		os.Exit(1)
+	}
+	fmt.Printf("ok  %d 条路线与记录一致\n", checked)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/algo/gen"
+	"traffic-system/bench"
+)
+
+// 路径规划压测：按固定 QPS 重放记录的请求或随机起终点，输出延迟分位数和错误率，未达到 SLO 时退出码为 1
+// 用法:
+//
+//	go run ./cmd/loadtest -target inproc -graph city -size 60 -qps 200 -duration 30s
+//	go run ./cmd/loadtest -target http://localhost:8080 -queries routes.jsonl -qps 50 -slo p95=300ms,p99=1s,errors=1%
+func main() {
+	target := flag.String("target", "inproc", "被测对象: inproc (进程内直接调用路网搜索) 或服务地址 (如 http://localhost:8080)")
+	graphType := flag.String("graph", "map", "inproc 使用的路网: map (地图文件) / city (合成城市)")
+	mapFile := flag.String("map", "map_data.json", "地图数据文件 (graph=map 时使用)")
+	size := flag.Int("size", 60, "合成城市的街道网格边长 (graph=city 时使用)")
+	queryFile := flag.String("queries", "", "记录的请求文件 (每行一个 /api/path/find 请求体或 usage_events 记录)，为空时随机生成起终点")
+	pairs := flag.Int("pairs", 1000, "随机生成的起终点数量")
+	modes := flag.String("modes", "walk,bus,subway", "随机请求使用的交通方式 (逗号分隔)")
+	seed := flag.Int64("seed", 1, "随机种子")
+	qps := flag.Float64("qps", 50, "每秒请求数")
+	duration := flag.Duration("duration", 30*time.Second, "持续时间")
+	concurrency := flag.Int("concurrency", 64, "同时进行的请求上限")
+	timeout := flag.Duration("timeout", 10*time.Second, "单个请求的超时时间 (HTTP)")
+	apiKey := flag.String("api-key", "", "请求携带的 X-API-Key (HTTP)")
+	sloFlag := flag.String("slo", "", "延迟和错误率目标，如 p95=300ms,p99=1s,errors=1%")
+	jsonOut := flag.String("json", "", "把结果以 JSON 写入文件 (便于对比不同版本)")
+	flag.Parse()
+
+	slo, err := bench.ParseSLO(*sloFlag)
+	if err != nil {
+		log.Fatalf("SLO 配置错误: %v", err)
+	}
+
+	var t bench.Target
+	var nodeIDs []string
+	if *target == "inproc" {
+		g := loadGraph(*graphType, *mapFile, *size, *seed)
+		fmt.Printf("路网: %s (%d 个节点)\n", *graphType, g.NodeCount())
+		t = &bench.GraphTarget{Graph: g}
+		for _, node := range g.NodeList {
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+	} else {
+		client := &http.Client{Timeout: *timeout, Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency}}
+		t = &bench.HTTPTarget{BaseURL: *target, APIKey: *apiKey, Client: client}
+		if *queryFile == "" {
+			if nodeIDs, err = fetchNodeIDs(client, *target); err != nil {
+				log.Fatalf("获取节点列表失败: %v", err)
+			}
+		}
+	}
+
+	var queries []bench.Query
+	if *queryFile != "" {
+		f, err := os.Open(*queryFile)
+		if err != nil {
+			log.Fatalf("读取请求文件失败: %v", err)
+		}
+		queries, err = bench.LoadQueries(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("读取请求文件失败: %v", err)
+		}
+	} else {
+		queries = bench.RandomQueries(nodeIDs, *pairs, strings.Split(*modes, ","), *seed)
+		if len(queries) == 0 {
+			log.Fatal("路网中没有节点")
+		}
+	}
+	fmt.Printf("请求: %d 个，%.0f QPS，持续 %s，并发上限 %d\n", len(queries), *qps, *duration, *concurrency)
+
+	// Ctrl+C 提前结束，仍然输出已完成请求的统计
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report := bench.RunLoad(ctx, t, queries, bench.LoadConfig{QPS: *qps, Duration: *duration, Concurrency: *concurrency})
+	printReport(report)
+
+	if *jsonOut != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*jsonOut, data, 0o644); err != nil {
+			log.Fatalf("写入结果失败: %v", err)
+		}
+	}
+	if failed := report.CheckSLO(slo); len(failed) > 0 {
+		fmt.Println("未达到 SLO:")
+		for _, f := range failed {
+			fmt.Println("  - " + f)
+		}
+		os.Exit(1)
+	}
+}
+
+// loadGraph 加载进程内压测使用的路网
+func loadGraph(graphType, mapFile string, size int, seed int64) *algo.Graph {
+	switch graphType {
+	case "map":
+		g, err := algo.LoadFromJSON(mapFile)
+		if err != nil {
+			log.Fatalf("加载地图失败: %v", err)
+		}
+		return g
+	case "city":
+		cfg := gen.Sized(size)
+		cfg.Seed = seed
+		g, err := gen.Graph(cfg)
+		if err != nil {
+			log.Fatalf("生成合成城市失败: %v", err)
+		}
+		return g
+	}
+	log.Fatalf("未知的路网类型: %s", graphType)
+	return nil
+}
+
+// fetchNodeIDs 从服务的 /api/nodes 获取全部节点 ID (用于随机生成起终点)
+func fetchNodeIDs(client *http.Client, baseURL string) ([]string, error) {
+	resp, err := client.Get(strings.TrimRight(baseURL, "/") + "/api/v1/nodes")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Nodes []struct {
+			ID string `json:"id"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(body.Nodes))
+	for i, node := range body.Nodes {
+		ids[i] = node.ID
+	}
+	return ids, nil
+}
+
+func printReport(r *bench.LoadReport) {
+	fmt.Printf("完成 %d 个请求 (%.1f QPS，用时 %.1fs)，跳过 %d 个\n", r.Requests, r.AchievedQPS, r.Duration, r.Dropped)
+	fmt.Printf("找到路径 %d，无路径 %d，错误 %d (错误率 %.2f%%)\n", r.Found, r.NoPath, r.Errors, r.ErrorRate*100)
+	fmt.Printf("延迟 (ms): mean %.2f  p50 %.2f  p95 %.2f  p99 %.2f  max %.2f\n",
+		r.Latency.Mean, r.Latency.P50, r.Latency.P95, r.Latency.P99, r.Latency.Max)
+
+	kinds := make([]string, 0, len(r.Outcomes))
+	for kind := range r.Outcomes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("  %-16s %d\n", kind, r.Outcomes[kind])
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// 所有配置项均从环境变量读取 (为了 Docker 部署方便)，读取失败时使用默认值
+
+// GetString 获取字符串配置，如果不存在则返回默认值
+func GetString(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+// GetInt 获取整数配置，解析失败时返回默认值
+func GetInt(key string, defaultVal int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
+
+// GetFloat 获取浮点数配置，解析失败时返回默认值
+func GetFloat(key string, defaultVal float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+// GetBool 获取布尔配置 (支持 true/false/1/0)，解析失败时返回默认值
+func GetBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// GetDuration 获取时长配置 (如 "30m", "24h")，解析失败时返回默认值
+func GetDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,147 @@
+package crash
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+	"traffic-system/config"
+)
+
+// panic 上报
+// 接口和后台任务中的 panic 被恢复后生成一份 Report (错误值、调用栈、请求信息)，总是写入 error 日志，
+// 配置了 SENTRY_DSN 时再异步发送到 Sentry (或 GlitchTip 等兼容 Sentry 协议的服务)
+
+// Frame 调用栈中的一帧
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Report 一次 panic
+type Report struct {
+	ID     string    // 事件 ID (32 位十六进制)，返回给客户端便于对照
+	Time   time.Time // 发生时间
+	Source string    // 来源：http 或 job
+	Value  any       // recover() 得到的值
+	Stack  []Frame   // 从 panic 发生处开始的调用栈 (最内层在前)
+
+	// 请求信息 (来源为 http 时)
+	Method  string
+	URL     string
+	Route   string
+	Headers map[string]string // 已去掉认证相关的请求头
+	UserID  uint
+	TraceID string // 链路追踪的 trace ID (未启用时为空)
+	SpanID  string
+
+	Tags map[string]string // 其他标签，如后台任务的类型
+}
+
+// Sink 接收 panic 报告的服务
+type Sink interface {
+	Send(ctx context.Context, r *Report) error
+}
+
+// Default 全局上报服务，为空时只写日志 (应在 main 中通过 Init 初始化)
+var Default Sink
+
+// sendTimeout 单次上报的超时时间
+const sendTimeout = 10 * time.Second
+
+// Init 配置了 SENTRY_DSN 时向 Sentry 上报，配置错误时只写日志并继续运行
+func Init() {
+	dsn := config.GetString("SENTRY_DSN", "")
+	if dsn == "" {
+		return
+	}
+	sink, err := NewSentry(dsn, config.GetString("SENTRY_ENVIRONMENT", "production"), config.GetString("SENTRY_RELEASE", ""))
+	if err != nil {
+		slog.Error("SENTRY_DSN 配置错误，panic 只写入日志", "error", err)
+		return
+	}
+	Default = sink
+	slog.Info("panic 将上报到 Sentry", "endpoint", sink.endpoint)
+}
+
+// Capture 在 recover() 之后调用，记录错误值和 panic 发生处的调用栈
+func Capture(value any) *Report {
+	return &Report{
+		ID:    newEventID(),
+		Time:  time.Now(),
+		Value: value,
+		Stack: panicStack(),
+		Tags:  make(map[string]string),
+	}
+}
+
+// Send 写入 error 日志并异步上报 (不阻塞响应)
+func Send(r *Report) {
+	attrs := []any{"event_id", r.ID, "source", r.Source, "panic", fmt.Sprint(r.Value), "stack", r.StackString()}
+	if r.Route != "" {
+		attrs = append(attrs, "method", r.Method, "route", r.Route, "url", r.URL)
+	}
+	if r.TraceID != "" {
+		attrs = append(attrs, "trace_id", r.TraceID)
+	}
+	for k, v := range r.Tags {
+		attrs = append(attrs, k, v)
+	}
+	slog.Error("panic 已恢复", attrs...)
+
+	sink := Default
+	if sink == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := sink.Send(ctx, r); err != nil {
+			slog.Error("上报 panic 失败", "event_id", r.ID, "error", err)
+		}
+	}()
+}
+
+// StackString 调用栈的文本形式 (与 debug.Stack 类似，每帧两行)
+func (r *Report) StackString() string {
+	var b strings.Builder
+	for _, f := range r.Stack {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// panicStack 当前 goroutine 的调用栈，去掉 recover 所在的延迟函数和运行时内部的帧
+func panicStack() []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []Frame
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			stack = stack[:0] // 之前的帧是 recover 所在的延迟函数
+		case len(stack) == 0 && strings.HasPrefix(f.Function, "runtime."):
+			// 空指针等运行时错误在 gopanic 之后还有 runtime.sigpanic、runtime.panicmem 等帧
+		default:
+			stack = append(stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// newEventID 随机生成 32 位十六进制的事件 ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,147 @@
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sentryClient 上报时的客户端标识
+const sentryClient = "vv-maps/1.0"
+
+// Sentry 通过 envelope 接口把 panic 上报到 Sentry 或兼容 Sentry 协议的服务 (GlitchTip 等)
+type Sentry struct {
+	endpoint    string // 如 https://o1.ingest.sentry.io/api/42/envelope/
+	dsn         string
+	key         string
+	environment string
+	release     string
+	client      *http.Client
+}
+
+// NewSentry 解析 DSN (https://<key>@<host>/<project_id>) 并创建上报服务
+func NewSentry(dsn, environment, release string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("DSN 的协议应为 http 或 https: %s", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("DSN 缺少公钥")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	project := path[i+1:]
+	if _, err := strconv.ParseUint(project, 10, 64); err != nil {
+		return nil, fmt.Errorf("DSN 缺少项目 ID: %s", u.Path)
+	}
+	return &Sentry{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], project),
+		dsn:         dsn,
+		key:         u.User.Username(),
+		environment: environment,
+		release:     release,
+		client:      &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// Send 发送一个 event 类型的 envelope
+func (s *Sentry) Send(ctx context.Context, r *Report) error {
+	event, err := json.Marshal(s.event(r))
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]string{"event_id": r.ID, "dsn": s.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(event)})
+	var body bytes.Buffer
+	for _, line := range [][]byte{header, item, event} {
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, s.key))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// event Sentry 事件 (https://develop.sentry.dev/sdk/data-model/event-payloads/)
+func (s *Sentry) event(r *Report) map[string]any {
+	// Sentry 的调用栈最外层在前
+	frames := make([]map[string]any, 0, len(r.Stack))
+	for i := len(r.Stack) - 1; i >= 0; i-- {
+		f := r.Stack[i]
+		module, function := splitFunction(f.Function)
+		frames = append(frames, map[string]any{
+			"function": function,
+			"module":   module,
+			"abs_path": f.File,
+			"lineno":   f.Line,
+			"in_app":   module == "main" || strings.HasPrefix(module, "traffic-system"),
+		})
+	}
+	event := map[string]any{
+		"event_id":    r.ID,
+		"timestamp":   r.Time.UTC().Format(time.RFC3339Nano),
+		"platform":    "go",
+		"level":       "error",
+		"logger":      r.Source,
+		"environment": s.environment,
+		"exception": map[string]any{"values": []map[string]any{{
+			"type":       fmt.Sprintf("%T", r.Value),
+			"value":      fmt.Sprint(r.Value),
+			"mechanism":  map[string]any{"type": "recover", "handled": true},
+			"stacktrace": map[string]any{"frames": frames},
+		}}},
+		"tags": r.Tags,
+	}
+	if host, err := os.Hostname(); err == nil {
+		event["server_name"] = host
+	}
+	if s.release != "" {
+		event["release"] = s.release
+	}
+	if r.URL != "" {
+		event["request"] = map[string]any{"method": r.Method, "url": r.URL, "headers": r.Headers}
+		event["transaction"] = r.Method + " " + r.Route
+	}
+	if r.UserID != 0 {
+		event["user"] = map[string]any{"id": strconv.FormatUint(uint64(r.UserID), 10)}
+	}
+	if r.TraceID != "" {
+		event["contexts"] = map[string]any{"trace": map[string]any{"trace_id": r.TraceID, "span_id": r.SpanID}}
+	}
+	return event
+}
+
+// splitFunction 把 runtime 给出的完整函数名拆成包路径和函数名，如 traffic-system/handler.FindPath
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot], name[slash+1+dot+1:]
+	}
+	return "", name
+}
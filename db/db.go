@@ -1,14 +1,16 @@
 package db
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"time"
+	"traffic-system/config"
 	"traffic-system/model"
+	"traffic-system/tracing"
 
-	"github.com/lib/pq"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -16,36 +18,88 @@ import (
 var DB *gorm.DB
 
 func InitDB() {
-	// 从环境变量读取配置 (为了 Docker 部署方便)
-	host := getEnvOrDefault("DB_HOST", "localhost")
-	port := getEnvOrDefault("DB_PORT", "5432")
-	user := getEnvOrDefault("DB_USER", "vvuser")
-	password := getEnvOrDefault("DB_PASSWORD", "vvpassword")
-	dbname := getEnvOrDefault("DB_NAME", "vvtraffic")
-
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
-		host, user, password, dbname, port,
-	)
+	// DB_DRIVER 选择数据库：postgres (默认)、sqlite (嵌入式部署、演示)，
+	// 或 memory (不使用数据库，直接从 map_data.json 加载，便于开发和试用)
+	var dialector gorm.Dialector
+	switch driver := config.GetString("DB_DRIVER", "postgres"); driver {
+	case "postgres":
+		dialector = postgres.Open(postgresDSN())
+	case "sqlite":
+		dialector = sqlite.Open(config.GetString("SQLITE_PATH", "vvtraffic.db"))
+	case "memory":
+		initMemory("map_data.json")
+		return
+	default:
+		log.Fatalf("不支持的数据库类型: %s (应为 postgres、sqlite 或 memory)", driver)
+	}
 
 	// 带重试的数据库连接 (Docker 启动时数据库可能还没准备好)
 	var err error
 	maxRetries := 30
 	for i := 0; i < maxRetries; i++ {
-		DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		DB, err = gorm.Open(dialector, &gorm.Config{Logger: sqlLogger{}})
 		if err == nil {
 			break
 		}
-		log.Printf("等待数据库就绪... (%d/%d): %v", i+1, maxRetries, err)
+		slog.Warn("等待数据库就绪...", "attempt", i+1, "max", maxRetries, "error", err)
 		time.Sleep(2 * time.Second)
 	}
 
 	if err != nil {
 		log.Fatalf("无法连接数据库: %v", err)
 	}
+	// 通过 WithContext 传入请求 Context 的 SQL 记录到请求的链路中
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("注册链路追踪插件失败: %v", err)
+	}
+	configurePool(DB)
+	if IsSQLite() {
+		// SQLite 同时只允许一个写入者，使用单个连接避免 "database is locked"
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.SetMaxOpenConns(1)
+		}
+	}
+	connectReplica()
+	Repo = NewGormRepository(DB)
+
+	// 边的 (from, to, line_id) 唯一索引创建前清理旧数据中的重复边
+	if err := dedupeEdges(DB); err != nil {
+		log.Fatalf("清理重复边失败: %v", err)
+	}
 
 	// 自动迁移模式 (自动创建表结构)
-	err = DB.AutoMigrate(&model.User{}, &model.Node{}, &model.Edge{})
+	err = DB.AutoMigrate(
+		&model.User{},
+		&model.UserToken{},
+		&model.UserIdentity{},
+		&model.UserProfile{},
+		&model.Node{},
+		&model.NodeAlias{},
+		&model.NodeEvent{},
+		&model.NodePopularity{},
+		&model.UsageEvent{},
+		&model.Category{},
+		&model.MapChange{},
+		&model.Job{},
+		&model.Edge{},
+		&model.Line{},
+		&model.LineStop{},
+		&model.SharedRoute{},
+		&model.TripSegment{},
+		&model.EdgeSpeed{},
+		&model.SpeedProfile{},
+		&model.RouteMonitor{},
+		&model.SavedRoute{},
+		&model.Commute{},
+		&model.Feedback{},
+		&model.Contribution{},
+		&model.APIKey{},
+		&model.DailyUsage{},
+		&model.Webhook{},
+		&model.Geofence{},
+		&model.ZoneRule{},
+		&model.TurnRestriction{},
+	)
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
@@ -53,77 +107,114 @@ func InitDB() {
 	// 检查是否需要导入初始数据
 	var nodeCount int64
 	DB.Model(&model.Node{}).Count(&nodeCount)
-	if nodeCount == 0 {
-		log.Println("检测到数据库为空，正在导入 map_data.json...")
-		if err := importMapData("map_data.json"); err != nil {
-			log.Printf("警告: 导入地图数据失败: %v", err)
+	// 数据库为空，或设置了 MAP_IMPORT_ON_START 时导入 map_data.json (按自然键新增或更新，不会重复导入)
+	if nodeCount == 0 || config.GetBool("MAP_IMPORT_ON_START", false) {
+		slog.Info("正在导入 map_data.json...")
+		if summary, err := ImportMapFile("map_data.json", ImportOptions{}); err != nil {
+			slog.Warn("导入地图数据失败", "error", err)
 		} else {
-			log.Println("地图数据导入成功!")
+			slog.Info("地图数据导入成功!")
+			if summary.Changed() && OnImport != nil {
+				OnImport(summary)
+			}
 		}
 	}
 
-	log.Println("数据库连接并初始化成功！")
-}
+	// 为没有线路定义的 line_id 推导线路信息
+	if err := ensureLines(DB); err != nil {
+		slog.Warn("生成线路信息失败", "error", err)
+	}
 
-// getEnvOrDefault 获取环境变量，如果不存在则返回默认值
-func getEnvOrDefault(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+	// 把 ADMIN_USERS 中列出的用户设为管理员
+	if admins := config.GetString("ADMIN_USERS", ""); admins != "" {
+		names := strings.Split(admins, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		if err := DB.Model(&model.User{}).Where("username IN ?", names).
+			Update("role", model.RoleAdmin).Error; err != nil {
+			slog.Warn("设置管理员失败", "error", err)
+		}
 	}
-	return defaultVal
-}
 
-// importMapData 从 JSON 文件导入地图数据到数据库
-func importMapData(filepath string) error {
-	file, err := os.ReadFile(filepath)
-	if err != nil {
-		return fmt.Errorf("读取文件失败: %w", err)
+	// 没有分时速度系数时写入默认的早晚高峰曲线
+	var profileCount int64
+	DB.Model(&model.SpeedProfile{}).Count(&profileCount)
+	if profileCount == 0 {
+		if err := DB.Create(model.DefaultSpeedProfiles()).Error; err != nil {
+			slog.Warn("写入默认分时速度系数失败", "error", err)
+		}
 	}
 
-	// 使用临时结构体解析 JSON (因为 JSON 中的 Modes 是 []string)
-	var data struct {
-		Meta  map[string]interface{} `json:"meta"`
-		Nodes []model.Node           `json:"nodes"`
-		Edges []struct {
-			From   string   `json:"from"`
-			To     string   `json:"to"`
-			Dist   float64  `json:"dist"`
-			Modes  []string `json:"modes"`
-			LineID string   `json:"line_id,omitempty"`
-			Desc   string   `json:"desc,omitempty"`
-		} `json:"edges"`
+	// 没有节点分类时写入默认分类体系
+	var categoryCount int64
+	DB.Model(&model.Category{}).Count(&categoryCount)
+	if categoryCount == 0 {
+		if err := DB.Create(model.DefaultCategories()).Error; err != nil {
+			slog.Warn("写入默认节点分类失败", "error", err)
+		}
 	}
 
-	if err := json.Unmarshal(file, &data); err != nil {
-		return fmt.Errorf("解析 JSON 失败: %w", err)
+	slog.Info("数据库连接并初始化成功！")
+}
+
+// dedupeEdges 删除 (from, to, line_id) 相同的重复边，只保留 ID 最小的一条 (表不存在时跳过)
+// 早期版本的导入会重复插入同一条边，需要在创建唯一索引前清理
+func dedupeEdges(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model.Edge{}) {
+		return nil
+	}
+	if err := db.Exec(`UPDATE edges SET line_id = '' WHERE line_id IS NULL`).Error; err != nil {
+		return err
 	}
+	result := db.Exec(`DELETE FROM edges WHERE id NOT IN
+		(SELECT MIN(id) FROM edges GROUP BY "from", "to", line_id)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("删除了重复边", "edges", result.RowsAffected)
+	}
+	return nil
+}
 
-	// 批量插入节点
-	if len(data.Nodes) > 0 {
-		if err := DB.CreateInBatches(data.Nodes, 100).Error; err != nil {
-			return fmt.Errorf("插入节点失败: %w", err)
-		}
-		log.Printf("导入了 %d 个节点", len(data.Nodes))
-	}
-
-	// 批量插入边 (转换 Modes 为 pq.StringArray)
-	if len(data.Edges) > 0 {
-		edges := make([]model.Edge, len(data.Edges))
-		for i, e := range data.Edges {
-			edges[i] = model.Edge{
-				From:   e.From,
-				To:     e.To,
-				Dist:   e.Dist,
-				Modes:  pq.StringArray(e.Modes),
-				LineID: e.LineID,
-				Desc:   e.Desc,
-			}
-		}
-		if err := DB.CreateInBatches(edges, 100).Error; err != nil {
-			return fmt.Errorf("插入边失败: %w", err)
-		}
-		log.Printf("导入了 %d 条边", len(edges))
+// postgresDSN 根据环境变量生成 PostgreSQL 连接串 (为了 Docker 部署方便)
+func postgresDSN() string {
+	host := config.GetString("DB_HOST", "localhost")
+	port := config.GetString("DB_PORT", "5432")
+	user := config.GetString("DB_USER", "vvuser")
+	password := config.GetString("DB_PASSWORD", "vvpassword")
+	dbname := config.GetString("DB_NAME", "vvtraffic")
+
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
+		host, user, password, dbname, port,
+	)
+}
+
+// Enabled 是否连接了数据库 (无数据库模式下为 false)
+func Enabled() bool {
+	return DB != nil
+}
+
+// IsSQLite 当前数据库是否为 SQLite
+func IsSQLite() bool {
+	return DB != nil && DB.Dialector.Name() == "sqlite"
+}
+
+// HourExpr 返回取时间列小时数 (本地时间) 的 SQL 表达式，兼容 PostgreSQL 和 SQLite
+// SQLite 中时间按 "2006-01-02 15:04:05..." 格式的本地时间文本保存
+func HourExpr(column string) string {
+	if IsSQLite() {
+		return fmt.Sprintf("CAST(substr(%s, 12, 2) AS INTEGER)", column)
 	}
+	return fmt.Sprintf("EXTRACT(HOUR FROM %s)::int", column)
+}
 
-	return nil
+// DateExpr 返回取时间列日期 (本地时间，"2006-01-02" 格式文本) 的 SQL 表达式，兼容 PostgreSQL 和 SQLite
+func DateExpr(column string) string {
+	if IsSQLite() {
+		return fmt.Sprintf("substr(%s, 1, 10)", column)
+	}
+	return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", column)
 }
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// MapDiff 增量修改文件：新增、修改、删除节点和边
+type MapDiff struct {
+	Description string `json:"description"`
+	Nodes       struct {
+		Add    []model.Node `json:"add"`
+		Update []model.Node `json:"update"`
+		Remove []string     `json:"remove"` // 节点 ID，连接该节点的边和别名一并删除
+	} `json:"nodes"`
+	Edges struct {
+		Add    []mapEdge `json:"add"`
+		Update []mapEdge `json:"update"` // 按 (from, to, line_id) 匹配
+		Remove []EdgeRef `json:"remove"`
+	} `json:"edges"`
+}
+
+// EdgeRef 按自然键引用一条边
+type EdgeRef struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	LineID string `json:"line_id,omitempty"`
+}
+
+// DiffOptions 差异导入选项
+type DiffOptions struct {
+	DryRun         bool   // 只检查和统计，不写入数据库
+	UserID         uint   // 导入的管理员
+	VersionBefore  string // 当前地图版本 (写入变更记录)
+	ContributionID uint   // 来自用户贡献时为贡献的 ID
+}
+
+// ApplyMapDiff 在一个事务中把差异文件应用到数据库，并写入一条变更记录
+// 新增的节点/边不能已存在，修改和删除的节点/边必须存在；任何一项不满足都不修改数据。
+// 仍是线路站点的节点不能删除。DryRun 时返回统计结果，不写入
+func ApplyMapDiff(content []byte, opts DiffOptions) (model.MapChange, error) {
+	change := model.MapChange{
+		UserID:         opts.UserID,
+		ContributionID: opts.ContributionID,
+		VersionBefore:  opts.VersionBefore,
+		Diff:           string(content),
+	}
+	var diff MapDiff
+	if err := json.Unmarshal(content, &diff); err != nil {
+		return change, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+	change.Description = diff.Description
+	if err := validateMapDiff(&diff); err != nil {
+		return change, err
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyNodeDiff(tx, &diff, &change); err != nil {
+			return err
+		}
+		if err := applyEdgeDiff(tx, &diff, &change); err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return change, err
+	}
+	return change, nil
+}
+
+// applyNodeDiff 新增、修改、删除节点
+func applyNodeDiff(tx *gorm.DB, diff *MapDiff, change *model.MapChange) error {
+	exists := func(id string) (bool, error) {
+		var count int64
+		err := tx.Model(&model.Node{}).Where("id = ?", id).Count(&count).Error
+		return count > 0, err
+	}
+
+	for i := range diff.Nodes.Add {
+		node := &diff.Nodes.Add[i]
+		if ok, err := exists(node.ID); err != nil {
+			return err
+		} else if ok {
+			return fmt.Errorf("节点已存在: %s", node.ID)
+		}
+		if err := tx.Create(node).Error; err != nil {
+			return err
+		}
+		change.NodesAdded++
+	}
+
+	for i := range diff.Nodes.Update {
+		node := &diff.Nodes.Update[i]
+		if ok, err := exists(node.ID); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("节点不存在: %s", node.ID)
+		}
+		if err := tx.Save(node).Error; err != nil {
+			return err
+		}
+		change.NodesUpdated++
+	}
+
+	for _, id := range diff.Nodes.Remove {
+		if ok, err := exists(id); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("节点不存在: %s", id)
+		}
+		var stops int64
+		if err := tx.Model(&model.LineStop{}).Where("node_id = ?", id).Count(&stops).Error; err != nil {
+			return err
+		}
+		if stops > 0 {
+			return fmt.Errorf("节点仍是线路站点，不能删除: %s", id)
+		}
+
+		result := tx.Where(`"from" = ? OR "to" = ?`, id, id).Delete(&model.Edge{})
+		if result.Error != nil {
+			return result.Error
+		}
+		change.EdgesRemoved += int(result.RowsAffected)
+		if err := tx.Where("node_id = ?", id).Delete(&model.NodeAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Node{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+		change.NodesRemoved++
+	}
+	return nil
+}
+
+// applyEdgeDiff 新增、修改、删除边 (按 (from, to, line_id) 匹配)
+func applyEdgeDiff(tx *gorm.DB, diff *MapDiff, change *model.MapChange) error {
+	find := func(from, to, lineID string) (*model.Edge, error) {
+		var edges []model.Edge
+		err := tx.Where(`"from" = ? AND "to" = ? AND line_id = ?`, from, to, lineID).Limit(1).Find(&edges).Error
+		if err != nil || len(edges) == 0 {
+			return nil, err
+		}
+		return &edges[0], nil
+	}
+	nodeExists := func(id string) (bool, error) {
+		var count int64
+		err := tx.Model(&model.Node{}).Where("id = ?", id).Count(&count).Error
+		return count > 0, err
+	}
+
+	for _, e := range diff.Edges.Add {
+		for _, id := range []string{e.From, e.To} {
+			if ok, err := nodeExists(id); err != nil {
+				return err
+			} else if !ok {
+				return fmt.Errorf("边引用的节点不存在: %s", id)
+			}
+		}
+		if old, err := find(e.From, e.To, e.LineID); err != nil {
+			return err
+		} else if old != nil {
+			return fmt.Errorf("边已存在: %s -> %s", e.From, e.To)
+		}
+		edge := e.toModel()
+		if err := tx.Create(&edge).Error; err != nil {
+			return err
+		}
+		change.EdgesAdded++
+	}
+
+	for _, e := range diff.Edges.Update {
+		old, err := find(e.From, e.To, e.LineID)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			return fmt.Errorf("边不存在: %s -> %s", e.From, e.To)
+		}
+		edge := e.toModel()
+		edge.ID = old.ID
+		if err := tx.Save(&edge).Error; err != nil {
+			return err
+		}
+		change.EdgesUpdated++
+	}
+
+	for _, ref := range diff.Edges.Remove {
+		result := tx.Where(`"from" = ? AND "to" = ? AND line_id = ?`, ref.From, ref.To, ref.LineID).Delete(&model.Edge{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("边不存在: %s -> %s", ref.From, ref.To)
+		}
+		change.EdgesRemoved += int(result.RowsAffected)
+	}
+	return nil
+}
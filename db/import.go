@@ -0,0 +1,414 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+	"traffic-system/lint"
+	"traffic-system/model"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ImportCounts 导入中某一类数据的处理结果
+type ImportCounts struct {
+	Inserted int `json:"inserted"` // 新增
+	Updated  int `json:"updated"`  // 已存在但内容有变化，已更新
+	Skipped  int `json:"skipped"`  // 与数据库中相同，或在文件中重复
+}
+
+// Changed 是否有新增或更新
+func (c ImportCounts) Changed() bool {
+	return c.Inserted > 0 || c.Updated > 0
+}
+
+// ImportSummary 一次地图数据导入的结果
+type ImportSummary struct {
+	Source  string       `json:"source"`
+	DryRun  bool         `json:"dry_run,omitempty"` // 只统计不写入
+	Nodes   ImportCounts `json:"nodes"`
+	Edges   ImportCounts `json:"edges"`
+	Lines   ImportCounts `json:"lines"`
+	Aliases ImportCounts `json:"aliases"`
+	Lint    *lint.Report `json:"lint,omitempty"` // 按 lint 规则检查文件的结果 (error 级别的违规会阻止导入)
+	At      time.Time    `json:"at"`
+}
+
+// Changed 导入是否修改了数据
+func (s ImportSummary) Changed() bool {
+	return s.Nodes.Changed() || s.Edges.Changed() || s.Lines.Changed() || s.Aliases.Changed()
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun bool // 只统计新增/更新/跳过的数量，不写入数据库
+}
+
+// OnImport 地图数据导入完成后调用 (由 main 注册，用于发送 Webhook 等通知)
+var OnImport func(ImportSummary)
+
+// errDryRun 用于在试运行结束时回滚事务
+var errDryRun = errors.New("dry run")
+
+// mapFile 地图数据文件 (map_data.json 的格式)
+type mapFile struct {
+	Meta    map[string]interface{} `json:"meta"`
+	Nodes   []model.Node           `json:"nodes"`
+	Edges   []mapEdge              `json:"edges"`
+	Lines   []model.Line           `json:"lines,omitempty"`
+	Aliases []model.NodeAlias      `json:"aliases,omitempty"`
+}
+
+// mapEdge 数据文件中的一条边 (使用临时结构体解析，因为 JSON 中的 Modes 是 []string)
+type mapEdge struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Dist   float64  `json:"dist"`
+	Modes  []string `json:"modes"`
+	LineID string   `json:"line_id,omitempty"`
+	Desc   string   `json:"desc,omitempty"`
+
+	MaxHeight float64 `json:"max_height,omitempty"`
+	MaxWeight float64 `json:"max_weight,omitempty"`
+	MaxWidth  float64 `json:"max_width,omitempty"`
+	NoTrucks  bool    `json:"no_trucks,omitempty"`
+	Oneway    string  `json:"oneway,omitempty"`
+	RoadClass string  `json:"road_class,omitempty"`
+	Footway   string  `json:"footway,omitempty"`
+	Level     int     `json:"level,omitempty"`
+
+	Connector      string  `json:"connector,omitempty"`
+	ConnectorSpeed float64 `json:"connector_speed,omitempty"`
+}
+
+// toModel 转换为数据库模型
+func (e *mapEdge) toModel() model.Edge {
+	return model.Edge{
+		From:   e.From,
+		To:     e.To,
+		Dist:   e.Dist,
+		Modes:  pq.StringArray(e.Modes),
+		LineID: e.LineID,
+		Desc:   e.Desc,
+
+		MaxHeight: e.MaxHeight,
+		MaxWeight: e.MaxWeight,
+		MaxWidth:  e.MaxWidth,
+		NoTrucks:  e.NoTrucks,
+		Oneway:    e.Oneway,
+		RoadClass: e.RoadClass,
+		Footway:   e.Footway,
+		Level:     e.Level,
+
+		Connector:      e.Connector,
+		ConnectorSpeed: e.ConnectorSpeed,
+	}
+}
+
+// ImportMapFile 从 JSON 文件导入地图数据，见 ImportMapData
+func ImportMapFile(path string, opts ImportOptions) (ImportSummary, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ImportSummary{Source: path}, fmt.Errorf("读取文件失败: %w", err)
+	}
+	return ImportMapData(path, content, opts)
+}
+
+// ImportMapData 导入地图数据 (map_data.json 格式)，按自然键新增或更新，不删除文件中没有的数据：
+//   - 节点按 ID，线路按 ID (站点整体替换)，边按 (from, to, line_id)，别名按 (node_id, alias)
+//   - 内容与数据库相同的记录跳过；文件中重复的记录只取第一条
+//
+// 写入前校验引用完整性、交通方式和距离 (见 validateMapFile)，有问题时返回 *ValidationError。
+// 全部在一个事务中完成，失败时不会留下部分数据；DryRun 时只统计数量，最后回滚
+func ImportMapData(source string, content []byte, opts ImportOptions) (ImportSummary, error) {
+	summary := ImportSummary{Source: source, DryRun: opts.DryRun}
+	var data mapFile
+	if err := json.Unmarshal(content, &data); err != nil {
+		return summary, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+
+	// 先校验整个文件，有问题时不写入任何数据；写入过程中出错时整个事务回滚
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := validateMapFile(tx, &data); err != nil {
+			return err
+		}
+		summary.Lint = lintMapFile(&data)
+		if err := lintError(summary.Lint); err != nil {
+			return err
+		}
+		var err error
+		if summary.Nodes, err = upsertNodes(tx, data.Nodes); err != nil {
+			return fmt.Errorf("导入节点失败: %w", err)
+		}
+		if summary.Edges, err = upsertEdges(tx, &data); err != nil {
+			return fmt.Errorf("导入边失败: %w", err)
+		}
+		if summary.Lines, err = upsertLines(tx, data.Lines); err != nil {
+			return fmt.Errorf("导入线路失败: %w", err)
+		}
+		if summary.Aliases, err = upsertAliases(tx, data.Aliases); err != nil {
+			return fmt.Errorf("导入节点别名失败: %w", err)
+		}
+		if err := ensureLines(tx); err != nil {
+			return fmt.Errorf("生成线路信息失败: %w", err)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return summary, err
+	}
+
+	summary.At = time.Now()
+	slog.Info("地图数据导入", "source", source, "dry_run", opts.DryRun,
+		"nodes", summary.Nodes, "edges", summary.Edges, "lines", summary.Lines, "aliases", summary.Aliases)
+	return summary, nil
+}
+
+// upsertNodes 按 ID 新增或更新节点
+func upsertNodes(tx *gorm.DB, nodes []model.Node) (ImportCounts, error) {
+	var counts ImportCounts
+	var rows []model.Node
+	if err := tx.Find(&rows).Error; err != nil {
+		return counts, err
+	}
+	existing := make(map[string]*model.Node, len(rows))
+	for i := range rows {
+		existing[rows[i].ID] = &rows[i]
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	var inserts []model.Node
+	for _, node := range nodes {
+		if seen[node.ID] {
+			counts.Skipped++
+			continue
+		}
+		seen[node.ID] = true
+
+		old := existing[node.ID]
+		switch {
+		case old == nil:
+			inserts = append(inserts, node)
+			counts.Inserted++
+		case sameNode(old, &node):
+			counts.Skipped++
+		default:
+			if err := tx.Save(&node).Error; err != nil {
+				return counts, err
+			}
+			counts.Updated++
+		}
+	}
+	if len(inserts) > 0 {
+		if err := tx.CreateInBatches(inserts, 100).Error; err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// sameNode 节点内容是否相同
+func sameNode(a, b *model.Node) bool {
+	return a.Name == b.Name && a.NameEn == b.NameEn && a.Lat == b.Lat && a.Lng == b.Lng && a.Type == b.Type && a.Signal == b.Signal && a.Importance == b.Importance && a.Level == b.Level &&
+		a.Capacity == b.Capacity && a.Price == b.Price && a.Power == b.Power && slices.Equal(a.Connectors, b.Connectors)
+}
+
+// edgeKey 边的自然键
+type edgeKey struct {
+	from, to, lineID string
+}
+
+// upsertEdges 按 (from, to, line_id) 新增或更新边
+func upsertEdges(tx *gorm.DB, data *mapFile) (ImportCounts, error) {
+	var counts ImportCounts
+	var rows []model.Edge
+	if err := tx.Find(&rows).Error; err != nil {
+		return counts, err
+	}
+	existing := make(map[edgeKey]*model.Edge, len(rows))
+	for i := range rows {
+		key := edgeKey{rows[i].From, rows[i].To, rows[i].LineID}
+		if existing[key] == nil {
+			existing[key] = &rows[i]
+		}
+	}
+
+	seen := make(map[edgeKey]bool, len(data.Edges))
+	var inserts []model.Edge
+	for _, e := range data.Edges {
+		if e.From == "" && e.To == "" {
+			continue // 只有注释的条目
+		}
+		key := edgeKey{e.From, e.To, e.LineID}
+		if seen[key] {
+			counts.Skipped++
+			continue
+		}
+		seen[key] = true
+
+		edge := e.toModel()
+		old := existing[key]
+		switch {
+		case old == nil:
+			inserts = append(inserts, edge)
+			counts.Inserted++
+		case sameEdge(old, &edge):
+			counts.Skipped++
+		default:
+			edge.ID = old.ID
+			if err := tx.Save(&edge).Error; err != nil {
+				return counts, err
+			}
+			counts.Updated++
+		}
+	}
+	if len(inserts) > 0 {
+		if err := tx.CreateInBatches(inserts, 100).Error; err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// sameEdge 边的内容是否相同 (不比较自然键)
+func sameEdge(a, b *model.Edge) bool {
+	return a.Dist == b.Dist && slices.Equal(a.Modes, b.Modes) && a.Desc == b.Desc &&
+		a.MaxHeight == b.MaxHeight && a.MaxWeight == b.MaxWeight && a.MaxWidth == b.MaxWidth && a.NoTrucks == b.NoTrucks &&
+		a.Oneway == b.Oneway && a.RoadClass == b.RoadClass && a.Footway == b.Footway &&
+		a.Level == b.Level && a.Connector == b.Connector && a.ConnectorSpeed == b.ConnectorSpeed
+}
+
+// upsertLines 按 ID 新增或更新线路，线路有变化时整体替换站点
+func upsertLines(tx *gorm.DB, lines []model.Line) (ImportCounts, error) {
+	var counts ImportCounts
+	var rows []model.Line
+	if err := tx.Preload("Stops", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("seq")
+	}).Find(&rows).Error; err != nil {
+		return counts, err
+	}
+	existing := make(map[string]*model.Line, len(rows))
+	for i := range rows {
+		existing[rows[i].ID] = &rows[i]
+	}
+
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		for i := range line.Stops {
+			if line.Stops[i].Seq == 0 {
+				line.Stops[i].Seq = i + 1
+			}
+		}
+		if seen[line.ID] {
+			counts.Skipped++
+			continue
+		}
+		seen[line.ID] = true
+
+		old := existing[line.ID]
+		switch {
+		case old == nil:
+			if err := tx.Create(&line).Error; err != nil {
+				return counts, err
+			}
+			counts.Inserted++
+		case sameLine(old, &line):
+			counts.Skipped++
+		default:
+			if err := tx.Where("line_id = ?", line.ID).Delete(&model.LineStop{}).Error; err != nil {
+				return counts, err
+			}
+			if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&line).Error; err != nil {
+				return counts, err
+			}
+			counts.Updated++
+		}
+	}
+	return counts, nil
+}
+
+// sameLine 线路内容和站点顺序是否相同
+func sameLine(a, b *model.Line) bool {
+	if a.Name != b.Name || a.Mode != b.Mode || a.Color != b.Color || a.Headway != b.Headway ||
+		a.FirstTime != b.FirstTime || a.LastTime != b.LastTime || len(a.Stops) != len(b.Stops) {
+		return false
+	}
+	for i := range a.Stops {
+		if a.Stops[i].NodeID != b.Stops[i].NodeID || a.Stops[i].Seq != b.Stops[i].Seq {
+			return false
+		}
+	}
+	return true
+}
+
+// upsertAliases 新增数据库中没有的别名 (别名没有可更新的内容)
+func upsertAliases(tx *gorm.DB, aliases []model.NodeAlias) (ImportCounts, error) {
+	var counts ImportCounts
+	var rows []model.NodeAlias
+	if err := tx.Find(&rows).Error; err != nil {
+		return counts, err
+	}
+	seen := make(map[[2]string]bool, len(rows)+len(aliases))
+	for _, a := range rows {
+		seen[[2]string{a.NodeID, a.Alias}] = true
+	}
+
+	var inserts []model.NodeAlias
+	for _, a := range aliases {
+		key := [2]string{a.NodeID, a.Alias}
+		if seen[key] {
+			counts.Skipped++
+			continue
+		}
+		seen[key] = true
+		inserts = append(inserts, model.NodeAlias{NodeID: a.NodeID, Alias: a.Alias})
+		counts.Inserted++
+	}
+	if len(inserts) > 0 {
+		if err := tx.Create(&inserts).Error; err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// ensureLines 根据边的 line_id 补齐数据库中缺失的线路
+func ensureLines(tx *gorm.DB) error {
+	var edges []model.Edge
+	if err := tx.Where("line_id <> ''").Find(&edges).Error; err != nil {
+		return err
+	}
+
+	var existing []string
+	if err := tx.Model(&model.Line{}).Pluck("id", &existing).Error; err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		known[id] = true
+	}
+
+	var missing []model.Line
+	for _, line := range model.DeriveLines(edges) {
+		if !known[line.ID] {
+			missing = append(missing, line)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&missing).Error; err != nil {
+		return err
+	}
+	slog.Info("根据边数据生成了线路", "lines", len(missing))
+	return nil
+}
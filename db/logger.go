@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold 超过该时间的 SQL 按 warn 级别记录
+const slowQueryThreshold = 200 * time.Millisecond
+
+// sqlLogger 把 GORM 的日志写入 slog：执行失败为 error，慢查询为 warn，其他 SQL 为 debug
+// (日志级别调到 debug 时可以看到每条 SQL)；记录不存在不算失败
+type sqlLogger struct{}
+
+// LogMode 级别由 slog 控制，忽略 GORM 的设置
+func (l sqlLogger) LogMode(logger.LogLevel) logger.Interface { return l }
+
+func (sqlLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (sqlLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (sqlLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Trace 记录一条 SQL 的执行结果
+func (sqlLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "SQL 执行失败", "sql", sql, "rows", rows, "elapsed", elapsed, "error", err)
+	case elapsed > slowQueryThreshold:
+		sql, rows := fc()
+		slog.WarnContext(ctx, "慢查询", "sql", sql, "rows", rows, "elapsed", elapsed)
+	case slog.Default().Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		slog.DebugContext(ctx, "SQL", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
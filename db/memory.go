@@ -0,0 +1,170 @@
+package db
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"traffic-system/model"
+)
+
+// MemoryRepository 内存存储，用于嵌入式部署、演示和单元测试 (不需要数据库)
+// 路网数据创建后只读，用户可以新增；进程退出后数据丢失
+type MemoryRepository struct {
+	nodes []model.Node
+	index map[string]int // 节点 ID -> 下标
+	edges []model.Edge
+	lines []model.Line
+
+	aliases []model.NodeAlias
+
+	mu     sync.RWMutex
+	users  []model.User
+	nextID uint
+}
+
+// NewMemoryRepository 用地图数据创建内存存储
+// 与导入相同，(from, to, line_id) 重复的边只取第一条；没有线路定义时根据边的 line_id 推导
+func NewMemoryRepository(data model.MapData) *MemoryRepository {
+	r := &MemoryRepository{
+		nodes:   slices.Clone(data.Nodes),
+		index:   make(map[string]int, len(data.Nodes)),
+		edges:   make([]model.Edge, 0, len(data.Edges)),
+		aliases: slices.Clone(data.Aliases),
+		nextID:  1,
+	}
+	for i := range r.nodes {
+		r.index[r.nodes[i].ID] = i
+	}
+	seen := make(map[edgeKey]bool, len(data.Edges))
+	for _, e := range data.Edges {
+		key := edgeKey{e.From, e.To, e.LineID}
+		if (e.From == "" && e.To == "") || seen[key] {
+			continue // 只有注释的条目，或与前面 (from, to, line_id) 相同的边
+		}
+		seen[key] = true
+		e.ID = uint(len(r.edges) + 1)
+		r.edges = append(r.edges, e)
+	}
+
+	lines := data.Lines
+	if len(lines) == 0 {
+		lines = model.DeriveLines(r.edges)
+	}
+	for _, line := range lines {
+		line.Stops = slices.Clone(line.Stops)
+		for i := range line.Stops {
+			line.Stops[i].LineID = line.ID
+			if line.Stops[i].Seq == 0 {
+				line.Stops[i].Seq = i + 1
+			}
+		}
+		slices.SortStableFunc(line.Stops, func(a, b model.LineStop) int { return cmp.Compare(a.Seq, b.Seq) })
+		r.lines = append(r.lines, line)
+	}
+	return r
+}
+
+func (r *MemoryRepository) Nodes() ([]model.Node, error) {
+	return slices.Clone(r.nodes), nil
+}
+
+func (r *MemoryRepository) Node(id string) (*model.Node, error) {
+	i, ok := r.index[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	node := r.nodes[i]
+	return &node, nil
+}
+
+func (r *MemoryRepository) EachEdgeBatch(size int, fn func(edges []model.Edge) error) error {
+	for start := 0; start < len(r.edges); start += size {
+		end := min(start+size, len(r.edges))
+		if err := fn(slices.Clone(r.edges[start:end])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Lines() ([]model.Line, error) {
+	lines := make([]model.Line, len(r.lines))
+	for i, line := range r.lines {
+		line.Stops = slices.Clone(line.Stops)
+		lines[i] = line
+	}
+	return lines, nil
+}
+
+// Aliases 地图数据中的节点别名 (不属于 Repository 接口，数据库中的别名由 algo 直接读取)
+func (r *MemoryRepository) Aliases() []model.NodeAlias {
+	return slices.Clone(r.aliases)
+}
+
+func (r *MemoryRepository) UserByID(id uint) (*model.User, error) {
+	return r.findUser(func(u *model.User) bool { return u.ID == id })
+}
+
+func (r *MemoryRepository) UserByUsername(username string) (*model.User, error) {
+	return r.findUser(func(u *model.User) bool { return u.Username == username })
+}
+
+func (r *MemoryRepository) UserByEmail(email string) (*model.User, error) {
+	return r.findUser(func(u *model.User) bool { return u.Email == email })
+}
+
+// CreateUser 创建用户，用户名重复时返回错误 (与数据库的唯一索引一致)
+func (r *MemoryRepository) CreateUser(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.users {
+		if r.users[i].Username == user.Username {
+			return fmt.Errorf("用户名已存在: %s", user.Username)
+		}
+	}
+	user.ID = r.nextID
+	r.nextID++
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	if user.Role == "" {
+		user.Role = model.RoleUser
+	}
+	r.users = append(r.users, *user)
+	return nil
+}
+
+// findUser 返回第一个满足条件的用户的拷贝
+func (r *MemoryRepository) findUser(match func(*model.User) bool) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.users {
+		if match(&r.users[i]) {
+			user := r.users[i]
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// initMemory 无数据库模式 (DB_DRIVER=memory)：用地图文件创建内存存储，DB 保持为空
+// 路网只读，注册的用户保存在内存中，重启后丢失；依赖数据库的接口返回 503
+func initMemory(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("读取地图数据失败: %v", err)
+	}
+	var data model.MapData
+	if err := json.Unmarshal(content, &data); err != nil {
+		log.Fatalf("解析 %s 失败: %v", path, err)
+	}
+	repo := NewMemoryRepository(data)
+	Repo = repo
+	slog.Info("无数据库模式: 路网从地图文件加载，用户保存在内存中 (重启后丢失)",
+		"file", path, "nodes", len(repo.nodes), "edges", len(repo.edges))
+}
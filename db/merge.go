@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// MergeSummary 一次节点合并的结果
+type MergeSummary struct {
+	Keep         string   `json:"keep"`
+	Merged       []string `json:"merged"`
+	EdgesRewired int64    `json:"edges_rewired"` // 改为连接保留节点的边
+	EdgesRemoved int64    `json:"edges_removed"` // 合并后成为自环或与已有边重复而删除的边
+	StopsUpdated int64    `json:"stops_updated"` // 改为保留节点的线路站点
+	AliasesAdded int      `json:"aliases_added"` // 被合并节点的名称作为保留节点的别名
+}
+
+// MergeNodes 把 merge 中的节点合并到 keep：边、线路站点、别名以及引用节点的记录都改为指向 keep，
+// 然后删除被合并的节点。在一个事务中完成，任何一步失败都不会修改数据
+func MergeNodes(keep string, merge []string) (MergeSummary, error) {
+	summary := MergeSummary{Keep: keep, Merged: merge}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var keepNode model.Node
+		if err := tx.First(&keepNode, "id = ?", keep).Error; err != nil {
+			return fmt.Errorf("保留节点不存在: %w", err)
+		}
+		var merged []model.Node
+		if err := tx.Where("id IN ?", merge).Find(&merged).Error; err != nil {
+			return err
+		}
+		if len(merged) != len(merge) {
+			return fmt.Errorf("部分被合并节点不存在")
+		}
+
+		// 先删除改连后会成为自环或与已有边 (from, to, line_id) 重复的边，再改连其余的边
+		removed, err := conflictingEdges(tx, keep, merge)
+		if err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := tx.Delete(&model.Edge{}, removed).Error; err != nil {
+				return err
+			}
+			summary.EdgesRemoved = int64(len(removed))
+		}
+		for _, column := range []string{"from", "to"} {
+			result := tx.Model(&model.Edge{}).Where(fmt.Sprintf("%q IN ?", column), merge).Update(column, keep)
+			if result.Error != nil {
+				return result.Error
+			}
+			summary.EdgesRewired += result.RowsAffected
+		}
+
+		// 线路站点
+		result := tx.Model(&model.LineStop{}).Where("node_id IN ?", merge).Update("node_id", keep)
+		if result.Error != nil {
+			return result.Error
+		}
+		summary.StopsUpdated = result.RowsAffected
+
+		// 别名：与保留节点已有别名重复的删除，其余转给保留节点；被合并节点的名称也作为别名保留
+		var existing []string
+		if err := tx.Model(&model.NodeAlias{}).Where("node_id = ?", keep).Pluck("alias", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			if err := tx.Where("node_id IN ? AND alias IN ?", merge, existing).Delete(&model.NodeAlias{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&model.NodeAlias{}).Where("node_id IN ?", merge).Update("node_id", keep).Error; err != nil {
+			return err
+		}
+		seen := map[string]bool{keepNode.Name: true}
+		for _, alias := range existing {
+			seen[alias] = true
+		}
+		for _, node := range merged {
+			if node.Name == "" || seen[node.Name] {
+				continue
+			}
+			seen[node.Name] = true
+			var count int64
+			tx.Model(&model.NodeAlias{}).Where("node_id = ? AND alias = ?", keep, node.Name).Count(&count)
+			if count > 0 {
+				continue
+			}
+			if err := tx.Create(&model.NodeAlias{NodeID: keep, Alias: node.Name}).Error; err != nil {
+				return err
+			}
+			summary.AliasesAdded++
+		}
+
+		// 其他引用节点的记录
+		updates := []struct {
+			table  interface{}
+			column string
+		}{
+			{&model.TripSegment{}, "from_id"},
+			{&model.TripSegment{}, "to_id"},
+			{&model.RouteMonitor{}, "start_id"},
+			{&model.RouteMonitor{}, "end_id"},
+			{&model.SharedRoute{}, "start_id"},
+			{&model.SharedRoute{}, "end_id"},
+			{&model.NodeEvent{}, "node_id"},
+		}
+		for _, u := range updates {
+			if err := tx.Model(u.table).Where(u.column+" IN ?", merge).Update(u.column, keep).Error; err != nil {
+				return err
+			}
+		}
+		// 按节点统计的结果在下次统计时重新生成
+		if err := tx.Where("from_id IN ? OR to_id IN ?", merge, merge).Delete(&model.EdgeSpeed{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("node_id IN ?", merge).Delete(&model.NodePopularity{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("id IN ?", merge).Delete(&model.Node{}).Error
+	})
+	return summary, err
+}
+
+// conflictingEdges 返回把 merge 中的节点改为 keep 后成为自环或键重复的边 ID
+// 重复的边保留 ID 最小的一条 (通常是保留节点原有的边)
+func conflictingEdges(tx *gorm.DB, keep string, merge []string) ([]uint, error) {
+	ids := append([]string{keep}, merge...)
+	var edges []model.Edge
+	if err := tx.Select("id", "from", "to", "line_id").
+		Where(`"from" IN ? OR "to" IN ?`, ids, ids).Order("id").Find(&edges).Error; err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]bool, len(merge))
+	for _, id := range merge {
+		merged[id] = true
+	}
+	rewire := func(id string) string {
+		if merged[id] {
+			return keep
+		}
+		return id
+	}
+
+	type key struct{ from, to, line string }
+	seen := make(map[key]bool, len(edges))
+	var removed []uint
+	for _, e := range edges {
+		k := key{rewire(e.From), rewire(e.To), e.LineID}
+		if k.from == k.to || seen[k] {
+			removed = append(removed, e.ID)
+			continue
+		}
+		seen[k] = true
+	}
+	return removed, nil
+}
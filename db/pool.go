@@ -0,0 +1,67 @@
+package db
+
+import (
+	"log"
+	"log/slog"
+	"time"
+	"traffic-system/config"
+	"traffic-system/tracing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Replica 只读副本 (配置了 DB_REPLICA_DSN 且连接成功时设置)，写入总是使用 DB
+var Replica *gorm.DB
+
+// Reader 读取量大、允许稍有延迟的查询 (启动时加载路网、使用统计、速度和热度统计) 使用的连接：
+// 有只读副本时为副本，否则为 DB。刚写入的数据 (如导入后重新构建路网) 应从 DB 读取
+func Reader() *gorm.DB {
+	if Replica != nil {
+		return Replica
+	}
+	return DB
+}
+
+// ReadRepo 启动时加载路网使用的存储：有只读副本时从副本读取节点、边和线路，否则为 Repo
+func ReadRepo() Repository {
+	if Replica != nil {
+		return NewGormRepository(Replica)
+	}
+	return Repo
+}
+
+// configurePool 按 DB_MAX_OPEN_CONNS 等环境变量设置连接池
+func configurePool(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("获取数据库连接池失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(config.GetInt("DB_MAX_OPEN_CONNS", 25))
+	sqlDB.SetMaxIdleConns(config.GetInt("DB_MAX_IDLE_CONNS", 10))
+	sqlDB.SetConnMaxLifetime(config.GetDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute))
+	sqlDB.SetConnMaxIdleTime(config.GetDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute))
+}
+
+// connectReplica 连接 DB_REPLICA_DSN 指定的只读副本 (只支持 PostgreSQL)
+// 副本连接失败时不影响启动，读取改为使用主库
+func connectReplica() {
+	dsn := config.GetString("DB_REPLICA_DSN", "")
+	if dsn == "" {
+		return
+	}
+	if DB.Dialector.Name() != "postgres" {
+		log.Fatalf("DB_REPLICA_DSN 配置错误: 只读副本只支持 PostgreSQL")
+	}
+	replica, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: sqlLogger{}})
+	if err != nil {
+		slog.Warn("连接只读副本失败，读取改为使用主库", "error", err)
+		return
+	}
+	if err := replica.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("注册链路追踪插件失败: %v", err)
+	}
+	configurePool(replica)
+	Replica = replica
+	slog.Info("已连接只读副本")
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 记录不存在 (与 gorm.ErrRecordNotFound 相同，可以用 errors.Is 判断)
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Repository 路网 (节点、边、线路) 和用户数据的存储接口
+// PostgreSQL 和 SQLite 使用 GormRepository，嵌入式场景和单元测试可以使用 MemoryRepository
+type Repository interface {
+	// Nodes 返回全部节点
+	Nodes() ([]model.Node, error)
+	// Node 按 ID 查找节点，不存在时返回 ErrNotFound
+	Node(id string) (*model.Node, error)
+	// EachEdgeBatch 按 ID 顺序分批读取全部边，每批最多 size 条；fn 收到的切片不会被复用，可以直接保留
+	EachEdgeBatch(size int, fn func(edges []model.Edge) error) error
+	// Lines 返回全部线路，站点按顺序排列
+	Lines() ([]model.Line, error)
+
+	// UserByID 按 ID 查找用户，不存在时返回 ErrNotFound
+	UserByID(id uint) (*model.User, error)
+	// UserByUsername 按用户名查找用户，不存在时返回 ErrNotFound
+	UserByUsername(username string) (*model.User, error)
+	// UserByEmail 按邮箱查找用户，不存在时返回 ErrNotFound
+	UserByEmail(email string) (*model.User, error)
+	// CreateUser 创建用户并填写 ID 和创建时间
+	CreateUser(user *model.User) error
+}
+
+// Repo 当前使用的存储，由 InitDB 设置
+var Repo Repository
+
+// GormRepository 基于 GORM 的存储 (PostgreSQL、SQLite)
+type GormRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRepository 创建基于 GORM 的存储
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) Nodes() ([]model.Node, error) {
+	var nodes []model.Node
+	err := r.db.Find(&nodes).Error
+	return nodes, err
+}
+
+func (r *GormRepository) Node(id string) (*model.Node, error) {
+	var node model.Node
+	if err := r.db.First(&node, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func (r *GormRepository) EachEdgeBatch(size int, fn func(edges []model.Edge) error) error {
+	var batch []model.Edge
+	return r.db.FindInBatches(&batch, size, func(_ *gorm.DB, _ int) error {
+		// batch 在批次之间复用，拷贝一份再交给调用方
+		edges := make([]model.Edge, len(batch))
+		copy(edges, batch)
+		return fn(edges)
+	}).Error
+}
+
+func (r *GormRepository) Lines() ([]model.Line, error) {
+	var lines []model.Line
+	err := r.db.Preload("Stops", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("seq")
+	}).Find(&lines).Error
+	return lines, err
+}
+
+func (r *GormRepository) UserByID(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormRepository) UserByUsername(username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormRepository) UserByEmail(email string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormRepository) CreateUser(user *model.User) error {
+	return r.db.Create(user).Error
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"traffic-system/lint"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"gorm.io/gorm"
+)
+
+// maxValidationProblems 校验最多报告的问题数
+const maxValidationProblems = 100
+
+// ValidationError 导入数据校验失败，Problems 为发现的问题 (最多 maxValidationProblems 条)
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("数据校验失败 (%d 个问题): %s", len(e.Problems), e.Problems[0])
+}
+
+// validator 收集校验问题
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	if len(v.problems) < maxValidationProblems {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// err 没有问题时返回 nil
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// node 检查节点 ID 和坐标
+func (v *validator) node(where string, node *model.Node) {
+	if node.ID == "" {
+		v.addf("%s: 节点缺少 id", where)
+	}
+	if !utils.ValidCoordinate(node.Lat, node.Lng) {
+		v.addf("%s: 节点 %s 坐标无效 (%g, %g)", where, node.ID, node.Lat, node.Lng)
+	}
+	if node.Importance < 0 || node.Importance > model.MaxNodeImportance {
+		v.addf("%s: 节点 %s 的 importance 超出范围 (0 ~ 5): %d", where, node.ID, node.Importance)
+	}
+}
+
+// edge 检查边的端点、交通方式、距离和通行限制；nodeExists 不为空时检查端点是否存在
+func (v *validator) edge(where string, e *mapEdge, nodeExists func(string) bool) {
+	name := e.From + " -> " + e.To
+	if e.From == "" || e.To == "" {
+		v.addf("%s: 边 %s 缺少 from 或 to", where, name)
+	} else if e.From == e.To {
+		v.addf("%s: 边 %s 的起点和终点相同", where, name)
+	}
+	if nodeExists != nil {
+		for _, id := range []string{e.From, e.To} {
+			if id != "" && !nodeExists(id) {
+				v.addf("%s: 边 %s 引用的节点不存在: %s", where, name, id)
+			}
+		}
+	}
+	if len(e.Modes) == 0 {
+		v.addf("%s: 边 %s 没有交通方式", where, name)
+	}
+	for _, mode := range e.Modes {
+		if model.GetModeMask(mode) == 0 {
+			v.addf("%s: 边 %s 的交通方式无效: %s", where, name, mode)
+		}
+	}
+	if !(e.Dist > 0) || math.IsInf(e.Dist, 0) {
+		v.addf("%s: 边 %s 的距离必须大于 0", where, name)
+	}
+	if e.MaxHeight < 0 || e.MaxWeight < 0 || e.MaxWidth < 0 {
+		v.addf("%s: 边 %s 的通行限制不能为负数", where, name)
+	}
+	if !model.IsValidOneway(e.Oneway) {
+		v.addf("%s: 边 %s 的 oneway 无效: %s (可选 yes、vehicle)", where, name, e.Oneway)
+	}
+	if !model.IsValidRoadClass(e.RoadClass) {
+		v.addf("%s: 边 %s 的 road_class 无效: %s", where, name, e.RoadClass)
+	}
+	if !model.IsValidFootway(e.Footway) {
+		v.addf("%s: 边 %s 的 footway 无效: %s (可选 crosswalk、footbridge、underpass、station_corridor)", where, name, e.Footway)
+	}
+	if e.Connector != "" {
+		switch {
+		case !model.IsValidConnector(e.Connector):
+			v.addf("%s: 边 %s 的 connector 无效: %s (可选 stairs、escalator、elevator)", where, name, e.Connector)
+		case len(e.Modes) != 1 || e.Modes[0] != "walk":
+			v.addf("%s: 边 %s 是垂直通道，只能步行", where, name)
+		case e.Connector == model.ConnectorEscalator && e.Oneway != model.OnewayYes:
+			v.addf("%s: 边 %s 是自动扶梯，必须设置 oneway: yes (按运行方向 from -> to)", where, name)
+		}
+	}
+	if e.ConnectorSpeed < 0 || math.IsInf(e.ConnectorSpeed, 0) || math.IsNaN(e.ConnectorSpeed) {
+		v.addf("%s: 边 %s 的 connector_speed 无效", where, name)
+	}
+}
+
+// validateMapFile 导入前检查整个文件：节点坐标、边的引用完整性 (端点在文件或数据库中存在)、交通方式和距离，
+// 以及线路站点和别名引用的节点
+func validateMapFile(tx *gorm.DB, data *mapFile) error {
+	var ids []string
+	if err := tx.Model(&model.Node{}).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(ids)+len(data.Nodes))
+	for _, id := range ids {
+		known[id] = true
+	}
+	for i := range data.Nodes {
+		known[data.Nodes[i].ID] = true
+	}
+	exists := func(id string) bool { return known[id] }
+
+	var v validator
+	for i := range data.Nodes {
+		v.node(fmt.Sprintf("nodes[%d]", i), &data.Nodes[i])
+	}
+	for i := range data.Edges {
+		e := &data.Edges[i]
+		if e.From == "" && e.To == "" {
+			continue // 只有注释的条目
+		}
+		v.edge(fmt.Sprintf("edges[%d]", i), e, exists)
+	}
+	for i, line := range data.Lines {
+		where := fmt.Sprintf("lines[%d]", i)
+		if line.ID == "" {
+			v.addf("%s: 线路缺少 id", where)
+		}
+		if line.Mode != "bus" && line.Mode != "subway" {
+			v.addf("%s: 线路 %s 的交通方式无效: %s (应为 bus 或 subway)", where, line.ID, line.Mode)
+		}
+		for _, stop := range line.Stops {
+			if !known[stop.NodeID] {
+				v.addf("%s: 线路 %s 的站点不存在: %s", where, line.ID, stop.NodeID)
+			}
+		}
+	}
+	for i, alias := range data.Aliases {
+		if alias.Alias == "" || !known[alias.NodeID] {
+			v.addf("aliases[%d]: 别名为空或节点不存在: %s", i, alias.NodeID)
+		}
+	}
+	return v.err()
+}
+
+// lintMapFile 按 lint.Current 检查文件中的节点和边
+func lintMapFile(data *mapFile) *lint.Report {
+	edges := make([]model.Edge, 0, len(data.Edges))
+	for i := range data.Edges {
+		if e := &data.Edges[i]; e.From != "" || e.To != "" {
+			edges = append(edges, e.toModel())
+		}
+	}
+	return lint.Current.Check(data.Nodes, edges)
+}
+
+// lintError 有 error 级别的违规时返回 *ValidationError (最多列出 maxValidationProblems 条)
+func lintError(report *lint.Report) error {
+	if report.Errors == 0 {
+		return nil
+	}
+	problems := report.Problems()
+	if len(problems) == 0 {
+		problems = []string{fmt.Sprintf("%d 个 lint 规则错误", report.Errors)}
+	}
+	if len(problems) > maxValidationProblems {
+		problems = problems[:maxValidationProblems]
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// validateMapDiff 检查差异文件中新增和修改的节点、边 (是否存在在应用时检查)，
+// 以及 lint 规则中 error 级别的违规
+func validateMapDiff(diff *MapDiff) error {
+	var v validator
+	for i := range diff.Nodes.Add {
+		v.node(fmt.Sprintf("nodes.add[%d]", i), &diff.Nodes.Add[i])
+	}
+	for i := range diff.Nodes.Update {
+		v.node(fmt.Sprintf("nodes.update[%d]", i), &diff.Nodes.Update[i])
+	}
+	for i := range diff.Edges.Add {
+		v.edge(fmt.Sprintf("edges.add[%d]", i), &diff.Edges.Add[i], nil)
+	}
+	for i := range diff.Edges.Update {
+		v.edge(fmt.Sprintf("edges.update[%d]", i), &diff.Edges.Update[i], nil)
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	nodes := slices.Concat(diff.Nodes.Add, diff.Nodes.Update)
+	var edges []model.Edge
+	for _, list := range [][]mapEdge{diff.Edges.Add, diff.Edges.Update} {
+		for i := range list {
+			edges = append(edges, list[i].toModel())
+		}
+	}
+	return lintError(lint.Current.Check(nodes, edges))
+}
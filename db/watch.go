@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// MapState 数据库中节点表和边表的内容，用于检测直接修改数据库 (SQL、其他服务) 带来的路网变化
+type MapState struct {
+	nodes map[string]*model.Node
+	edges map[edgeKey]*model.Edge
+}
+
+// MapStateDiff 两次读取之间新增、删除和修改的节点与边的数量
+type MapStateDiff struct {
+	NodesAdded, NodesUpdated, NodesRemoved int
+	EdgesAdded, EdgesUpdated, EdgesRemoved int
+}
+
+// Total 变化的节点和边的总数
+func (d MapStateDiff) Total() int {
+	return d.NodesAdded + d.NodesUpdated + d.NodesRemoved + d.EdgesAdded + d.EdgesUpdated + d.EdgesRemoved
+}
+
+func (d MapStateDiff) String() string {
+	return fmt.Sprintf("节点 新增 %d 修改 %d 删除 %d, 边 新增 %d 修改 %d 删除 %d",
+		d.NodesAdded, d.NodesUpdated, d.NodesRemoved, d.EdgesAdded, d.EdgesUpdated, d.EdgesRemoved)
+}
+
+// LoadMapState 读取主库中节点表和边表的当前内容
+func LoadMapState() (*MapState, error) {
+	return loadMapState(DB)
+}
+
+// LoadReadMapState 从只读副本 (未配置时为主库) 读取节点表和边表，与 ReadRepo 构建路网时看到的内容一致
+func LoadReadMapState() (*MapState, error) {
+	return loadMapState(Reader())
+}
+
+func loadMapState(conn *gorm.DB) (*MapState, error) {
+	if conn == nil {
+		return nil, errors.New("数据库未初始化")
+	}
+	var nodes []model.Node
+	if err := conn.Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	var edges []model.Edge
+	if err := conn.Find(&edges).Error; err != nil {
+		return nil, err
+	}
+
+	state := &MapState{
+		nodes: make(map[string]*model.Node, len(nodes)),
+		edges: make(map[edgeKey]*model.Edge, len(edges)),
+	}
+	for i := range nodes {
+		state.nodes[nodes[i].ID] = &nodes[i]
+	}
+	for i := range edges {
+		key := edgeKey{edges[i].From, edges[i].To, edges[i].LineID}
+		if state.edges[key] == nil {
+			state.edges[key] = &edges[i]
+		}
+	}
+	return state, nil
+}
+
+// Diff 与更早读取的状态 old 相比的变化 (节点按 ID、边按 (from, to, line_id) 对应)
+func (s *MapState) Diff(old *MapState) MapStateDiff {
+	var d MapStateDiff
+	for id, node := range s.nodes {
+		if prev, ok := old.nodes[id]; !ok {
+			d.NodesAdded++
+		} else if !sameNode(prev, node) {
+			d.NodesUpdated++
+		}
+	}
+	for id := range old.nodes {
+		if _, ok := s.nodes[id]; !ok {
+			d.NodesRemoved++
+		}
+	}
+	for key, edge := range s.edges {
+		if prev, ok := old.edges[key]; !ok {
+			d.EdgesAdded++
+		} else if !sameEdge(prev, edge) {
+			d.EdgesUpdated++
+		}
+	}
+	for key := range old.edges {
+		if _, ok := s.edges[key]; !ok {
+			d.EdgesRemoved++
+		}
+	}
+	return d
+}
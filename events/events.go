@@ -0,0 +1,96 @@
+package events
+
+import (
+	"sync"
+	"time"
+	"traffic-system/webhook"
+)
+
+// historySize 保留的最近事件数量 (客户端断线重连时按 Last-Event-ID 补发)
+const historySize = 100
+
+// Event 推送给前端的事件
+type Event struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"` // 与 Webhook 事件名称相同，如 "traffic.updated"
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Broker 进程内的事件广播
+// 订阅者处理不过来时丢弃事件，不会阻塞发布方
+type Broker struct {
+	mu      sync.RWMutex
+	subs    map[chan Event]struct{}
+	history []Event
+	nextID  uint64
+}
+
+// NewBroker 创建事件广播
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Event]struct{})}
+}
+
+// Default 全局事件广播
+var Default = NewBroker()
+
+// Publish 发布事件：推送给实时订阅者 (SSE)，并投递给订阅了该事件的 Webhook
+func Publish(eventType string, data interface{}) {
+	Default.Publish(eventType, data)
+	webhook.Dispatch(eventType, data)
+}
+
+// Publish 向所有订阅者广播事件
+func (b *Broker) Publish(eventType string, data interface{}) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Time: time.Now(), Data: data}
+	b.history = append(b.history, event)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default: // 订阅者缓冲区已满，丢弃
+		}
+	}
+	return event
+}
+
+// Subscribe 订阅事件，返回的通道需要在结束时通过 Unsubscribe 释放
+// afterID 大于 0 时先补发该 ID 之后的历史事件
+func (b *Broker) Subscribe(buffer int, afterID uint64) chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	for _, e := range b.history {
+		if afterID > 0 && e.ID > afterID {
+			missed = append(missed, e)
+		}
+	}
+	if buffer < len(missed) {
+		buffer = len(missed)
+	}
+
+	ch := make(chan Event, buffer)
+	for _, e := range missed {
+		ch <- e
+	}
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe 取消订阅并关闭通道
+func (b *Broker) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
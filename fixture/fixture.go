@@ -0,0 +1,105 @@
+package fixture
+
+import (
+	"fmt"
+	"traffic-system/algo"
+	"traffic-system/model"
+)
+
+// 确定性的小型测试路网
+// 坐标、距离和线路都是固定的，不读取 JSON 文件或数据库，相同的调用总是得到相同的图，
+// 可以在测试中直接断言路径、距离和时间，也可以赋给 handler.SetGraph 测试接口
+
+// 生成路网的参考原点 (郑州高新区附近)，与 algo/gen 使用的原点相同
+const (
+	originLat = 34.80
+	originLng = 113.50
+)
+
+// 每米对应的纬度/经度 (原点附近的平面近似)
+const (
+	latPerMeter = 1 / 111195.0
+	lngPerMeter = 1 / 91345.0
+)
+
+// TownSpacing Town 中相邻路口的距离 (米)
+const TownSpacing = 200
+
+// Town 中的公交线路和站点
+const (
+	TownBusLine = "town_bus"
+	TownStation = "town_station" // 公交总站 (位于 1-0 路口西侧，只能步行到达)
+)
+
+// Town 3 x 3 的棋盘路网 (路口 ID 为 TownNode(r, c))，道路支持步行、骑行和驾车，
+// 中间一行有一条公交线路: 总站 -> 1-0 -> 1-1 -> 1-2，发车间隔 10 分钟
+func Town() *algo.Graph {
+	b := algo.NewGraphBuilder()
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			b.AddNode(node(TownNode(r, c), fmt.Sprintf("路口 %d-%d", r, c), r*TownSpacing, c*TownSpacing))
+		}
+	}
+	b.AddNode(model.Node{ID: TownStation, Name: "公交总站", Lat: originLat + TownSpacing*latPerMeter, Lng: originLng - 100*lngPerMeter, Type: "bus_stop"})
+
+	road := []string{"walk", "bike", "car"}
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			if c+1 < 3 {
+				b.AddEdge(model.Edge{From: TownNode(r, c), To: TownNode(r, c+1), Dist: TownSpacing, Modes: road})
+			}
+			if r+1 < 3 {
+				b.AddEdge(model.Edge{From: TownNode(r, c), To: TownNode(r+1, c), Dist: TownSpacing, Modes: road})
+			}
+		}
+	}
+	b.AddEdge(model.Edge{From: TownStation, To: TownNode(1, 0), Dist: 100, Modes: []string{"walk"}})
+
+	stops := []string{TownStation, TownNode(1, 0), TownNode(1, 1), TownNode(1, 2)}
+	line := model.Line{ID: TownBusLine, Name: "1 路", Mode: "bus", Headway: 600, FirstTime: "06:00", LastTime: "22:00"}
+	for i, id := range stops {
+		line.Stops = append(line.Stops, model.LineStop{LineID: TownBusLine, Seq: i + 1, NodeID: id})
+		if i+1 < len(stops) {
+			b.AddOneWay(model.Edge{From: id, To: stops[i+1], Modes: []string{"bus"}, LineID: TownBusLine})
+		}
+	}
+	b.AddLine(line)
+
+	return b.MustBuild()
+}
+
+// TownNode Town 中第 r 行第 c 列路口的 ID
+func TownNode(r, c int) string {
+	return fmt.Sprintf("town_%d_%d", r, c)
+}
+
+// OneWay 中的节点
+const (
+	OneWayA = "oneway_a"
+	OneWayB = "oneway_b"
+	OneWayC = "oneway_c"
+)
+
+// OneWay 三角形路网: A -> B 是 300 米的单行道 (只能驾车)，A - C - B 是绕行的双向道路 (各 400 米，可以步行和驾车)
+// 驾车从 A 到 B 走单行道，从 B 到 A 只能绕行；步行两个方向都绕行
+func OneWay() *algo.Graph {
+	return algo.NewGraphBuilder().
+		AddNode(node(OneWayA, "A", 0, 0)).
+		AddNode(node(OneWayB, "B", 0, 300)).
+		AddNode(node(OneWayC, "C", 260, 150)).
+		AddOneWay(model.Edge{From: OneWayA, To: OneWayB, Dist: 300, Modes: []string{"car"}, Desc: "单行道"}).
+		AddEdge(model.Edge{From: OneWayA, To: OneWayC, Dist: 400, Modes: []string{"walk", "car"}}).
+		AddEdge(model.Edge{From: OneWayC, To: OneWayB, Dist: 400, Modes: []string{"walk", "car"}}).
+		MustBuild()
+}
+
+// node 从原点向北 north 米、向东 east 米处的路口
+func node(id, name string, north, east int) model.Node {
+	return model.Node{
+		ID:   id,
+		Name: name,
+		Lat:  originLat + float64(north)*latPerMeter,
+		Lng:  originLng + float64(east)*lngPerMeter,
+		Type: "road_node",
+	}
+}
@@ -0,0 +1,248 @@
+package golden
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/algo/gen"
+	"traffic-system/fixture"
+	"traffic-system/model"
+)
+
+// 黄金路线回归检查
+// 对一组精选的起终点记录期望的路线 (路径哈希、距离、预计时间)，修改代价模型或搜索算法后重新规划并比较：
+// 路径变化或预计时间超出容差时报告失败，确认变化符合预期后用 Update 重新记录。
+// 只使用内置的默认参数 (不读取环境变量)，相同的代码总是得到相同的结果
+
+// File 黄金路线文件 (golden/routes.json)
+type File struct {
+	Tolerance Tolerance `json:"tolerance"`
+	Routes    []Route   `json:"routes"`
+}
+
+// Tolerance 预计时间的容差：相对误差和绝对误差 (秒) 满足其一即可
+type Tolerance struct {
+	ETARelative float64 `json:"eta_relative"` // 如 0.01 表示 1%
+	ETASeconds  float64 `json:"eta_seconds"`
+}
+
+// Route 一组起终点和规划参数，以及期望的结果 (Expected 为空表示还没有记录)
+type Route struct {
+	Name           string     `json:"name"`
+	Map            string     `json:"map"` // 使用的路网，见 Maps
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	Modes          []string   `json:"modes"`
+	DepartAt       *time.Time `json:"depart_at,omitempty"` // 出发时间 (使用分时速度)，为空时只使用默认速度
+	AvoidHighways  bool       `json:"avoid_highways,omitempty"`
+	AvoidTransfers bool       `json:"avoid_transfers,omitempty"`
+	AvoidAlleys    bool       `json:"avoid_alleys,omitempty"`
+	AvoidStairs    bool       `json:"avoid_stairs,omitempty"`
+	Tolerance      *Tolerance `json:"tolerance,omitempty"` // 覆盖文件的容差
+
+	Expected *Result `json:"expected,omitempty"`
+}
+
+// Result 一次规划的结果
+type Result struct {
+	Found    bool     `json:"found"`
+	PathHash string   `json:"path_hash,omitempty"` // 经过的节点、交通方式和线路的哈希
+	Legs     []string `json:"legs,omitempty"`      // 按交通方式和线路合并的分段，如 walk、bus:BUS_Kexue_E (便于阅读差异)
+	Path     []string `json:"path,omitempty"`
+	Distance float64  `json:"distance"` // 米
+	ETA      float64  `json:"eta"`      // 秒
+}
+
+// Maps 可用的路网：fixture 中的小路网、map_data.json 和固定种子的合成城市
+var Maps = map[string]func() (*algo.Graph, error){
+	"town":   func() (*algo.Graph, error) { return fixture.Town(), nil },
+	"oneway": func() (*algo.Graph, error) { return fixture.OneWay(), nil },
+	"map":    func() (*algo.Graph, error) { return algo.LoadFromJSON("map_data.json") },
+	"city":   func() (*algo.Graph, error) { return gen.Graph(gen.Sized(20)) },
+}
+
+// Load 读取黄金路线文件
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save 写回黄金路线文件
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Failure 一条路线的检查失败
+type Failure struct {
+	Route   string
+	Reasons []string
+}
+
+// Runner 按需加载并缓存路网，规划黄金路线
+type Runner struct {
+	graphs map[string]*algo.Graph
+}
+
+// NewRunner 创建 Runner
+func NewRunner() *Runner {
+	return &Runner{graphs: make(map[string]*algo.Graph)}
+}
+
+// graph 名为 name 的路网 (第一次使用时加载)
+func (r *Runner) graph(name string) (*algo.Graph, error) {
+	if g, ok := r.graphs[name]; ok {
+		return g, nil
+	}
+	load, ok := Maps[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的路网: %s", name)
+	}
+	g, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("加载路网 %s 失败: %w", name, err)
+	}
+	r.graphs[name] = g
+	return g, nil
+}
+
+// Plan 按路线的参数规划一次 (与路径规划接口相同，使用 A*)
+func (r *Runner) Plan(route *Route) (*Result, error) {
+	g, err := r.graph(route.Map)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range []string{route.From, route.To} {
+		if g.Nodes[id] == nil {
+			return nil, fmt.Errorf("路网 %s 中没有节点 %s", route.Map, id)
+		}
+	}
+	mask := model.ParseModes(route.Modes)
+	if mask == 0 {
+		return nil, fmt.Errorf("交通方式无效: %v", route.Modes)
+	}
+	opts := algo.SearchOptions{
+		ModeMask:       mask,
+		AvoidHighways:  route.AvoidHighways,
+		AvoidTransfers: route.AvoidTransfers,
+		AvoidAlleys:    route.AvoidAlleys,
+		AvoidStairs:    route.AvoidStairs,
+	}
+	if route.DepartAt != nil {
+		opts.DepartAt = *route.DepartAt
+	}
+
+	res := g.AStar(route.From, route.To, opts)
+	if !res.Found {
+		return &Result{}, nil
+	}
+	result := &Result{Found: true, Path: res.Path, Distance: round(res.Distance), ETA: round(res.EstimatedTime)}
+	h := sha256.New()
+	for _, seg := range res.Segments {
+		fmt.Fprintf(h, "%s>%s:%s:%s\n", seg.FromID, seg.ToID, seg.UsedMode, seg.LineID)
+		leg := seg.UsedMode
+		if seg.LineID != "" {
+			leg += ":" + seg.LineID
+		}
+		if n := len(result.Legs); n == 0 || result.Legs[n-1] != leg {
+			result.Legs = append(result.Legs, leg)
+		}
+	}
+	result.PathHash = hex.EncodeToString(h.Sum(nil))[:16]
+	return result, nil
+}
+
+// Check 重新规划所有 (名称匹配 filter 的) 路线并与期望结果比较，返回失败的路线
+// 还没有记录期望结果的路线也算失败，需要先用 Update 记录
+func (r *Runner) Check(f *File, filter *regexp.Regexp) (failures []Failure, checked int) {
+	for i := range f.Routes {
+		route := &f.Routes[i]
+		if filter != nil && !filter.MatchString(route.Name) {
+			continue
+		}
+		checked++
+		got, err := r.Plan(route)
+		if err != nil {
+			failures = append(failures, Failure{Route: route.Name, Reasons: []string{err.Error()}})
+			continue
+		}
+		tol := f.Tolerance
+		if route.Tolerance != nil {
+			tol = *route.Tolerance
+		}
+		if reasons := compare(route.Expected, got, tol); len(reasons) > 0 {
+			failures = append(failures, Failure{Route: route.Name, Reasons: reasons})
+		}
+	}
+	return failures, checked
+}
+
+// Update 用当前的规划结果重新记录 (名称匹配 filter 的) 路线的期望结果，返回更新的路线数
+func (r *Runner) Update(f *File, filter *regexp.Regexp) (int, error) {
+	updated := 0
+	for i := range f.Routes {
+		route := &f.Routes[i]
+		if filter != nil && !filter.MatchString(route.Name) {
+			continue
+		}
+		got, err := r.Plan(route)
+		if err != nil {
+			return updated, fmt.Errorf("%s: %w", route.Name, err)
+		}
+		route.Expected = got
+		updated++
+	}
+	return updated, nil
+}
+
+// compare 比较期望和实际结果，返回不一致的原因
+func compare(want, got *Result, tol Tolerance) []string {
+	if want == nil {
+		return []string{"还没有记录期望结果 (使用 -update 记录)"}
+	}
+	if want.Found != got.Found {
+		return []string{fmt.Sprintf("是否找到路径: 期望 %v，实际 %v", want.Found, got.Found)}
+	}
+	if !want.Found {
+		return nil
+	}
+
+	var reasons []string
+	if want.PathHash != got.PathHash {
+		reasons = append(reasons,
+			fmt.Sprintf("路径变化 (%s -> %s)", want.PathHash, got.PathHash),
+			"  期望分段: "+strings.Join(want.Legs, " -> "),
+			"  实际分段: "+strings.Join(got.Legs, " -> "),
+			"  期望路径: "+strings.Join(want.Path, " -> "),
+			"  实际路径: "+strings.Join(got.Path, " -> "),
+		)
+	}
+	diff := got.ETA - want.ETA
+	if math.Abs(diff) > tol.ETASeconds && math.Abs(diff) > tol.ETARelative*want.ETA {
+		reasons = append(reasons, fmt.Sprintf("预计时间 %.1fs -> %.1fs (%+.1f%%，容差 %.1f%% 或 %.0fs)",
+			want.ETA, got.ETA, diff/want.ETA*100, tol.ETARelative*100, tol.ETASeconds))
+	}
+	return reasons
+}
+
+// round 保留一位小数，避免浮点误差带来的无意义差异
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,29 @@
+package golden
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRoutes 重新规划 routes.json 中的所有路线 (与 go run ./cmd/golden 相同)，每条不一致的路线报告一个错误
+func TestRoutes(t *testing.T) {
+	// map 路网从工作目录读取 map_data.json，切换到仓库根目录
+	t.Chdir("..")
+	// 与 cmd/golden 相同，分时速度按北京时间选取
+	local := time.Local
+	time.Local = time.FixedZone("CST", 8*3600)
+	t.Cleanup(func() { time.Local = local })
+
+	f, err := Load("golden/routes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures, checked := NewRunner().Check(f, nil)
+	if checked == 0 {
+		t.Fatal("没有检查任何路线")
+	}
+	for _, failure := range failures {
+		t.Errorf("%s: %s", failure.Route, strings.Join(failure.Reasons, "; "))
+	}
+}
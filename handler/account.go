@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// DeleteAccountRequest 删除账号请求
+// 需要当前密码；只通过第三方登录的用户 (没有设置过密码) 可以改为在 confirm 中填写用户名
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+// AccountExport 账号数据导出
+type AccountExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	User          ExportedUser         `json:"user"`
+	Profile       *model.UserProfile   `json:"profile,omitempty"`
+	Identities    []ExportedIdentity   `json:"identities"`
+	SavedRoutes   []ExportedRoute      `json:"saved_routes"`
+	Commutes      []model.Commute      `json:"commutes"`
+	Monitors      []model.RouteMonitor `json:"monitors"`
+	Shares        []model.SharedRoute  `json:"shares"`
+	Trips         []model.TripSegment  `json:"trips"` // 上报的行程
+	Feedback      []model.Feedback     `json:"feedback"`
+	Contributions []model.Contribution `json:"contributions"`
+}
+
+// ExportedUser 导出的账号信息 (不含密码)
+type ExportedUser struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ExportedIdentity 导出的第三方登录身份
+type ExportedIdentity struct {
+	Provider  string    `json:"provider"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedRoute 导出的收藏路线，包含规划参数和路线结果
+type ExportedRoute struct {
+	model.SavedRoute
+	Request json.RawMessage `json:"request,omitempty"`
+	Route   json.RawMessage `json:"route,omitempty"`
+}
+
+// ChangePassword 修改密码 (需登录)：校验旧密码，成功后注销其他设备的登录状态，并返回新的 Token
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if !utils.CheckPassword(user.Password, req.OldPassword) {
+		respondError(c, http.StatusUnauthorized, CodeInvalidCredentials, "旧密码错误")
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "新密码不能与旧密码相同")
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "密码加密失败")
+		return
+	}
+	if err := db.DB.Model(&model.User{}).Where("id = ?", user.ID).Update("password", hashedPassword).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "修改密码失败")
+		return
+	}
+
+	// 新 Token 与注销时间在同一秒签发，仍然有效
+	if err := revokeUserSessions(c.Request.Context(), user.ID, time.Now()); err != nil {
+		slog.Error("注销用户的登录状态失败", "user_id", user.ID, "error", err)
+	}
+	token, err := generateToken(user)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成 Token 失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:    token,
+		Username: user.Username,
+		Message:  tr(c, "密码已修改，其他设备需要重新登录"),
+	})
+}
+
+// DeleteAccount 删除当前账号 (需登录)
+// 删除账号及其收藏路线、通勤计划、路线监控、分享、行程记录、出行偏好、每日用量和第三方登录身份；
+// 反馈、地图修改建议和使用统计保留但不再关联到用户。删除后所有 Token 立即失效
+func DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	if !confirmDeletion(user, &req) {
+		respondError(c, http.StatusUnauthorized, CodeInvalidCredentials, "密码错误，无法删除账号")
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		owned := []interface{}{
+			&model.SavedRoute{}, &model.Commute{}, &model.RouteMonitor{}, &model.SharedRoute{},
+			&model.TripSegment{}, &model.UserProfile{}, &model.UserIdentity{}, &model.UserToken{},
+		}
+		for _, m := range owned {
+			if err := tx.Where("user_id = ?", user.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("subject = ? AND subject_id = ?", model.UsageSubjectUser, user.ID).Delete(&model.DailyUsage{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&model.Feedback{}, &model.Contribution{}, &model.UsageEvent{}} {
+			if err := tx.Model(m).Where("user_id = ?", user.ID).Update("user_id", 0).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&model.User{}, user.ID).Error
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除账号失败")
+		return
+	}
+
+	// 包括当前这一秒签发的 Token
+	if err := revokeUserSessions(c.Request.Context(), user.ID, time.Now().Add(time.Second)); err != nil {
+		slog.Error("注销用户的登录状态失败", "user_id", user.ID, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "账号已删除")})
+}
+
+// ExportAccount 导出当前账号的全部数据 (需登录，JSON 附件)
+func ExportAccount(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	export := AccountExport{
+		ExportedAt: time.Now(),
+		User: ExportedUser{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			Role:          user.Role,
+			CreatedAt:     user.CreatedAt,
+		},
+		Identities:    []ExportedIdentity{},
+		SavedRoutes:   []ExportedRoute{},
+		Commutes:      []model.Commute{},
+		Monitors:      []model.RouteMonitor{},
+		Shares:        []model.SharedRoute{},
+		Trips:         []model.TripSegment{},
+		Feedback:      []model.Feedback{},
+		Contributions: []model.Contribution{},
+	}
+
+	if profile, err := loadUserProfile(c.Request.Context(), user.ID); err == nil {
+		export.Profile = profile
+	}
+
+	var identities []model.UserIdentity
+	var routes []model.SavedRoute
+	queries := []struct {
+		dest  interface{}
+		order string
+	}{
+		{&identities, "id"},
+		{&routes, "id"},
+		{&export.Commutes, "id"},
+		{&export.Monitors, "id"},
+		{&export.Shares, "id"},
+		{&export.Trips, "entered_at"},
+		{&export.Feedback, "id"},
+		{&export.Contributions, "id"},
+	}
+	for _, q := range queries {
+		if err := db.DB.Where("user_id = ?", user.ID).Order(q.order).Find(q.dest).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+			return
+		}
+	}
+
+	for _, identity := range identities {
+		export.Identities = append(export.Identities, ExportedIdentity{Provider: identity.Provider, CreatedAt: identity.CreatedAt})
+	}
+	for _, route := range routes {
+		exported := ExportedRoute{SavedRoute: route}
+		if json.Valid([]byte(route.Request)) {
+			exported.Request = json.RawMessage(route.Request)
+		}
+		if json.Valid([]byte(route.Route)) {
+			exported.Route = json.RawMessage(route.Route)
+		}
+		export.SavedRoutes = append(export.SavedRoutes, exported)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="vv-maps-%s.json"`, user.Username))
+	c.JSON(http.StatusOK, export)
+}
+
+// loadCurrentUser 读取当前登录的用户，不存在时写入错误响应
+func loadCurrentUser(c *gin.Context) (*model.User, bool) {
+	user, err := db.Repo.UserByID(c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "用户不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// confirmDeletion 校验删除账号的确认信息：密码正确，或者账号关联了第三方登录且 confirm 为用户名
+func confirmDeletion(user *model.User, req *DeleteAccountRequest) bool {
+	if req.Password != "" {
+		return utils.CheckPassword(user.Password, req.Password)
+	}
+	if req.Confirm == "" || req.Confirm != user.Username {
+		return false
+	}
+	var identities int64
+	db.DB.Model(&model.UserIdentity{}).Where("user_id = ?", user.ID).Count(&identities)
+	return identities > 0
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AliasRequest 添加节点别名请求
+type AliasRequest struct {
+	NodeID string `json:"node_id" binding:"required"`
+	Alias  string `json:"alias" binding:"required"`
+}
+
+// GetAliases 获取节点别名 (管理员，可按 ?node_id= 过滤)
+func GetAliases(c *gin.Context) {
+	query := db.DB.Order("id")
+	if id := c.Query("node_id"); id != "" {
+		query = query.Where("node_id = ?", id)
+	}
+	var aliases []model.NodeAlias
+	if err := query.Find(&aliases).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(aliases),
+		"aliases": aliases,
+	})
+}
+
+// CreateAlias 为节点添加别名 (管理员)，保存后立即对搜索生效
+func CreateAlias(c *gin.Context) {
+	g := requestGraph(c)
+	var req AliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req.Alias = strings.TrimSpace(req.Alias)
+	if req.Alias == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "别名不能为空")
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	if g.Nodes[req.NodeID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在: "+req.NodeID)
+		return
+	}
+
+	var count int64
+	db.DB.Model(&model.NodeAlias{}).Where("node_id = ? AND alias = ?", req.NodeID, req.Alias).Count(&count)
+	if count > 0 {
+		respondError(c, http.StatusConflict, CodeConflict, "别名已存在")
+		return
+	}
+
+	alias := model.NodeAlias{NodeID: req.NodeID, Alias: req.Alias}
+	if err := db.DB.Create(&alias).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存别名失败")
+		return
+	}
+	reloadAliases()
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// DeleteAlias 删除节点别名 (管理员)
+func DeleteAlias(c *gin.Context) {
+	result := db.DB.Delete(&model.NodeAlias{}, c.Param("id"))
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除别名失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "别名不存在")
+		return
+	}
+	reloadAliases()
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "别名已删除")})
+}
+
+// reloadAliases 别名变化后重新加载到内存中的图
+func reloadAliases() {
+	g := CurrentGraph()
+	if g == nil {
+		return
+	}
+	if _, err := g.ReloadAliases(); err != nil {
+		slog.Warn("重新加载节点别名失败", "error", err)
+	}
+}
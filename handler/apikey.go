@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix API Key 的固定前缀 (便于在日志、代码仓库中识别泄露的密钥)
+const apiKeyPrefix = "vvk_"
+
+// apiKeyContextKey 请求上下文中保存 API Key 的键
+const apiKeyContextKey = "api_key"
+
+// CreateAPIKeyRequest 创建 API Key 请求 (管理员)
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required,max=100"`
+	Scopes     []string   `json:"scopes" binding:"required,min=1"`
+	RateLimit  int        `json:"rate_limit" binding:"min=0"`  // 每个限流窗口的请求上限，0 表示使用 API_KEY_RATE_LIMIT
+	DailyQuota int        `json:"daily_quota" binding:"min=0"` // 每天的请求上限，0 表示使用 API_KEY_DAILY_QUOTA
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// APIKeyMiddleware 识别 X-API-Key 请求头：密钥有效时按密钥限流并保存到上下文，无效时返回 401
+// 没有该请求头的请求不受影响 (继续使用 JWT 或匿名访问)
+func APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.GetHeader("X-API-Key")
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		if !db.Enabled() {
+			respondNoDatabase(c)
+			return
+		}
+
+		var key model.APIKey
+		err := db.DB.WithContext(c.Request.Context()).Where("key_hash = ?", utils.HashToken(raw)).First(&key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的 API Key")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+			return
+		}
+		now := time.Now()
+		if !key.IsActive(now) {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "API Key 已撤销或已过期")
+			return
+		}
+		if !allowAPIKey(c, &key, now) {
+			return
+		}
+
+		// 最近使用时间每分钟最多更新一次
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+			if err := db.DB.Model(&key).Update("last_used_at", now).Error; err != nil {
+				slog.Error("更新 API Key 使用时间失败", "error", err)
+			}
+		}
+
+		c.Set(apiKeyContextKey, &key)
+		c.Next()
+	}
+}
+
+// allowAPIKey 按密钥固定窗口限流 (窗口为 RATE_LIMIT_WINDOW)，超过上限时返回 429
+func allowAPIKey(c *gin.Context, key *model.APIKey, now time.Time) bool {
+	limit := key.RateLimit
+	if limit == 0 {
+		limit = config.GetInt("API_KEY_RATE_LIMIT", 600)
+	}
+	window := config.GetDuration("RATE_LIMIT_WINDOW", time.Minute)
+	if limit <= 0 || window <= 0 {
+		return true
+	}
+
+	start := now.Truncate(window)
+	count, err := cache.Default.Incr(c.Request.Context(), fmt.Sprintf("rate:apikey:%d:%d", key.ID, start.Unix()), window)
+	if err != nil {
+		slog.Error("限流计数失败", "error", err)
+		return true
+	}
+
+	header := c.Writer.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("X-RateLimit-Remaining", strconv.FormatInt(max(int64(limit)-count, 0), 10))
+	if count > int64(limit) {
+		retry := int(start.Add(window).Sub(now).Seconds()) + 1
+		header.Set("Retry-After", strconv.Itoa(retry))
+		respondErrorDetails(c, http.StatusTooManyRequests, CodeRateLimited, "请求过于频繁，请稍后再试", gin.H{"retry_after": retry})
+		return false
+	}
+	return true
+}
+
+// currentAPIKey 当前请求使用的 API Key，没有使用时为 nil
+func currentAPIKey(c *gin.Context) *model.APIKey {
+	if v, ok := c.Get(apiKeyContextKey); ok {
+		return v.(*model.APIKey)
+	}
+	return nil
+}
+
+// RequireScope 使用 API Key 访问时要求密钥拥有权限范围 scope；不使用 API Key 的请求不受影响
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := currentAPIKey(c); key != nil && !key.HasScope(scope) {
+			respondErrorDetails(c, http.StatusForbidden, CodeForbidden, "API Key 没有该接口的权限", gin.H{"scope": scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CreateAPIKey 创建 API Key (管理员)，原始密钥只在响应中出现这一次
+func CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !model.IsValidScope(scope) {
+			respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "无效的权限范围", gin.H{"scopes": model.APIScopes})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "过期时间必须晚于当前时间")
+		return
+	}
+
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成 API Key 失败")
+		return
+	}
+	raw := apiKeyPrefix + token
+
+	key := model.APIKey{
+		Name:       req.Name,
+		Prefix:     raw[:len(apiKeyPrefix)+6],
+		KeyHash:    utils.HashToken(raw),
+		Scopes:     req.Scopes,
+		RateLimit:  req.RateLimit,
+		DailyQuota: req.DailyQuota,
+		CreatedBy:  c.GetUint("user_id"),
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := db.DB.Create(&key).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存 API Key 失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     raw,
+		"message": tr(c, "请妥善保存密钥，之后无法再次查看"),
+	})
+}
+
+// GetAPIKeys API Key 列表 (管理员，不含密钥本身)
+func GetAPIKeys(c *gin.Context) {
+	var keys []model.APIKey
+	if err := db.DB.Order("id").Find(&keys).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(keys), "api_keys": keys})
+}
+
+// RevokeAPIKey 撤销 API Key (管理员)，立即生效；记录保留用于审计
+func RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的 API Key ID")
+		return
+	}
+
+	var key model.APIKey
+	if err := db.DB.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "API Key 不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return
+	}
+	if key.RevokedAt != nil {
+		respondError(c, http.StatusConflict, CodeConflict, "API Key 已撤销")
+		return
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "撤销 API Key 失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "API Key 已撤销")})
+}
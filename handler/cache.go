@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 地图数据 (节点、瓦片、线路) 只在地图版本变化时改变，用版本号作为 ETag
+const (
+	// 带 ?v=<当前版本> 的地址内容永远不变，可以长期缓存
+	versionedCacheControl = "public, max-age=31536000, immutable"
+	// 其他地址每次都要用 ETag 向服务器确认 (未变化时返回 304)
+	mapCacheControl = "public, no-cache"
+)
+
+// MapCacheMiddleware 地图数据的缓存控制
+// 设置 ETag (地图版本) 和 Cache-Control，请求的 If-None-Match 与当前版本一致时直接返回 304
+// 只能用于结果完全由地图数据和请求地址决定的 GET 接口 (不能用于实时车辆、到站等接口)
+func MapCacheMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		g := requestGraph(c)
+		if g == nil {
+			c.Next()
+			return
+		}
+
+		// 节点名称、错误信息随 Accept-Language 变化，ETag 也要区分语言
+		version := g.Version()
+		etag := `W/"` + version + "-" + language(c) + `"`
+		header := c.Writer.Header()
+		header.Set("ETag", etag)
+		header.Add("Vary", "Accept-Language")
+		header.Set("X-Map-Version", version)
+		if c.Query("v") == version {
+			header.Set("Cache-Control", versionedCacheControl)
+		} else {
+			header.Set("Cache-Control", mapCacheControl)
+		}
+
+		if etagMatch(c.GetHeader("If-None-Match"), etag) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+		c.Next()
+	}
+}
+
+// etagMatch 判断 If-None-Match 是否包含指定的 ETag (弱比较，忽略 W/ 前缀)
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
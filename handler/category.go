@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"traffic-system/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryInfo 分类树中的一个分类
+type CategoryInfo struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Path      string         `json:"path"`       // 完整路径，如 "transport/subway_entrance"，可用于 ?category= 过滤
+	NodeCount int            `json:"node_count"` // 该分类及下级分类中的节点数
+	Children  []CategoryInfo `json:"children,omitempty"`
+}
+
+// GetCategories 获取节点分类树
+func GetCategories(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	// 每个分类 (含上级) 的节点数
+	counts := make(map[string]int)
+	for i := range g.NodeList {
+		for _, id := range g.CategoryPath(g.NodeList[i].Type) {
+			counts[id]++
+		}
+	}
+
+	lang := language(c)
+	categories := g.Categories()
+	var build func(parentID string) []CategoryInfo
+	build = func(parentID string) []CategoryInfo {
+		var result []CategoryInfo
+		for _, cat := range categories {
+			if cat.ParentID != parentID || cat.ID == parentID {
+				continue
+			}
+			result = append(result, CategoryInfo{
+				ID:        cat.ID,
+				Name:      i18n.Pick(lang, cat.Name, cat.NameEn),
+				Path:      strings.Join(g.CategoryPath(cat.ID), "/"),
+				NodeCount: counts[cat.ID],
+				Children:  build(cat.ID),
+			})
+		}
+		return result
+	}
+
+	tree := build("")
+	if tree == nil {
+		tree = []CategoryInfo{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count":      len(categories),
+		"categories": tree,
+	})
+}
+
+// queryCategory 解析 ?category= 参数 (分类 ID 或完整路径)，无效时写入错误响应并返回 false
+func queryCategory(c *gin.Context) (string, bool) {
+	g := requestGraph(c)
+	s := c.Query("category")
+	if s == "" {
+		return "", true
+	}
+	id, ok := g.ResolveCategory(s)
+	if !ok {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "分类不存在: "+s)
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 通勤计划的限制
+const (
+	maxCommutesPerUser   = 10
+	defaultCommuteBuffer = 5 // 默认富余时间 (分钟)
+)
+
+// defaultCommuteDays 未指定星期时默认周一到周五通勤
+var defaultCommuteDays = []int64{1, 2, 3, 4, 5}
+
+// CommuteRequest 创建通勤计划请求
+type CommuteRequest struct {
+	Name      string   `json:"name"`
+	StartID   string   `json:"start_id" binding:"required"`
+	EndID     string   `json:"end_id" binding:"required"`
+	Modes     []string `json:"modes"`                                           // 为空时使用用户偏好
+	Days      []int64  `json:"days" binding:"omitempty,max=7,dive,min=1,max=7"` // 1 = 周一 ... 7 = 周日，默认周一到周五
+	ArriveBy  string   `json:"arrive_by" binding:"required"`                    // 如 "08:30"
+	Buffer    *int     `json:"buffer" binding:"omitempty,min=0,max=120"`        // 富余时间 (分钟)，默认 5
+	NotifyURL string   `json:"notify_url" binding:"omitempty,url"`
+}
+
+// TodayCommute 今天的通勤安排
+type TodayCommute struct {
+	monitor.CommutePlan
+	LeaveIn  float64 `json:"leave_in"` // 距离建议出发时间还有多少秒 (已过为负数)
+	Notified bool    `json:"notified"` // 今天是否已发送提醒
+}
+
+// CreateCommute 创建通勤计划
+func CreateCommute(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var req CommuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if g.Nodes[req.StartID] == nil || g.Nodes[req.EndID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "起点或终点不存在")
+		return
+	}
+	if _, err := model.ParseClock(req.ArriveBy); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "到达时间格式错误，应为 HH:MM")
+		return
+	}
+	if req.NotifyURL != "" {
+		if u, err := url.Parse(req.NotifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "回调地址必须是 http 或 https")
+			return
+		}
+	}
+
+	if len(req.Modes) == 0 {
+		if profile, err := loadUserProfile(c.Request.Context(), userID); err == nil {
+			req.Modes = profile.DefaultModes
+		}
+	}
+	if model.ParseModes(req.Modes) == 0 {
+		respondError(c, http.StatusBadRequest, CodeModeInvalid, "未指定有效的交通方式")
+		return
+	}
+	if len(req.Days) == 0 {
+		req.Days = defaultCommuteDays
+	}
+
+	var count int64
+	db.DB.Model(&model.Commute{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxCommutesPerUser {
+		respondError(c, http.StatusConflict, CodeConflict, "通勤计划数量已达上限")
+		return
+	}
+
+	commute := model.Commute{
+		UserID:    userID,
+		Name:      req.Name,
+		StartID:   req.StartID,
+		EndID:     req.EndID,
+		Modes:     req.Modes,
+		Days:      req.Days,
+		ArriveBy:  req.ArriveBy,
+		Buffer:    defaultCommuteBuffer,
+		NotifyURL: req.NotifyURL,
+		Active:    true,
+	}
+	if req.Buffer != nil {
+		commute.Buffer = *req.Buffer
+	}
+
+	now := time.Now()
+	if _, found := monitor.PlanCommute(g, &commute, now, now); !found {
+		respondError(c, http.StatusUnprocessableEntity, CodeRouteNotFound, "未找到符合条件的路径，无法创建通勤计划")
+		return
+	}
+
+	if err := db.DB.Create(&commute).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存通勤计划失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, commute)
+}
+
+// GetCommutes 获取当前用户的通勤计划
+func GetCommutes(c *gin.Context) {
+	var commutes []model.Commute
+	if err := db.DB.Where("user_id = ?", c.GetUint("user_id")).Order("id").Find(&commutes).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(commutes),
+		"commutes": commutes,
+	})
+}
+
+// DeleteCommute 删除通勤计划
+func DeleteCommute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的通勤计划 ID")
+		return
+	}
+
+	result := db.DB.Where("id = ? AND user_id = ?", id, c.GetUint("user_id")).Delete(&model.Commute{})
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除通勤计划失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "通勤计划不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "通勤计划已删除")})
+}
+
+// GetTodayCommutes 今天的通勤安排和建议出发时间
+// 后台今天已经计算过的直接返回，否则按当前路况计算并保存
+func GetTodayCommutes(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var commutes []model.Commute
+	if err := db.DB.Where("user_id = ? AND active = ?", c.GetUint("user_id"), true).Order("id").Find(&commutes).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	now := time.Now()
+	today := []TodayCommute{}
+	for i := range commutes {
+		commute := &commutes[i]
+		if !commute.ScheduledOn(now) {
+			continue
+		}
+
+		plan, ok := monitor.StoredPlan(commute, now)
+		if !ok {
+			var found bool
+			if plan, found = monitor.PlanCommute(g, commute, now, now); !found {
+				continue
+			}
+			if err := monitor.SavePlan(commute, plan); err != nil {
+				respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存通勤计划失败")
+				return
+			}
+		}
+
+		today = append(today, TodayCommute{
+			CommutePlan: plan,
+			LeaveIn:     plan.DepartAt.Sub(now).Seconds(),
+			Notified:    commute.NotifiedOn(now),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":     now.Format("2006-01-02"),
+		"count":    len(today),
+		"commutes": today,
+	})
+}
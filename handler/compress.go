@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipPool 复用的 gzip 压缩器
+var gzipPool = sync.Pool{
+	New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	},
+}
+
+// GzipMiddleware 客户端支持时用 gzip 压缩响应
+// 是否压缩在写第一段响应体时决定：事件流 (SSE)、图片和已经编码过的响应不压缩
+func GzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		w := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer w.close()
+		c.Next()
+	}
+}
+
+// gzipWriter 按需压缩的 ResponseWriter
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+// decide 写响应体前判断是否压缩
+func (w *gzipWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if header.Get("Content-Encoding") != "" ||
+		strings.HasPrefix(contentType, "text/event-stream") ||
+		strings.HasPrefix(contentType, "image/") ||
+		!bodyAllowed(w.Status()) {
+		return
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.gz = gzipPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.gz.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 先把已压缩的数据写出再刷新
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close 写完 gzip 尾部并归还压缩器
+func (w *gzipWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipPool.Put(w.gz)
+	w.gz = nil
+}
+
+// bodyAllowed 该状态码是否允许有响应体
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 用户贡献的限制
+const (
+	maxContributionSize     = 256 << 10 // 差异文件大小上限 (字节)
+	maxContributionItems    = 50        // 一次最多修改的节点和边数
+	maxPendingContributions = 20        // 每个用户待审核的贡献数上限
+)
+
+// ReviewContributionRequest 审核贡献请求 (管理员)
+type ReviewContributionRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// SubmitContribution 提交地图修改建议 (格式与 /api/admin/import/diff 相同，description 必填)
+// 提交时按当前地图试运行一次，有问题时直接返回，通过后进入待审核状态
+func SubmitContribution(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxContributionSize))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "读取请求体失败 (最大 256 KB)")
+		return
+	}
+	var diff db.MapDiff
+	if err := json.Unmarshal(content, &diff); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "解析 JSON 失败")
+		return
+	}
+	diff.Description = strings.TrimSpace(diff.Description)
+	if diff.Description == "" || len([]rune(diff.Description)) > 500 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请填写修改说明 (不超过 500 字)")
+		return
+	}
+	items := len(diff.Nodes.Add) + len(diff.Nodes.Update) + len(diff.Nodes.Remove) +
+		len(diff.Edges.Add) + len(diff.Edges.Update) + len(diff.Edges.Remove)
+	if items == 0 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "没有任何修改")
+		return
+	}
+	if items > maxContributionItems {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "一次修改的节点和边过多 (最多 50 个)")
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var pending int64
+	db.DB.Model(&model.Contribution{}).Where("user_id = ? AND status = ?", userID, model.ContributionPending).Count(&pending)
+	if pending >= maxPendingContributions {
+		respondError(c, http.StatusConflict, CodeConflict, "待审核的贡献数量已达上限")
+		return
+	}
+
+	change, err := db.ApplyMapDiff(content, db.DiffOptions{DryRun: true, UserID: userID, VersionBefore: g.Version()})
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+
+	contribution := model.Contribution{
+		UserID:       userID,
+		Description:  diff.Description,
+		Status:       model.ContributionPending,
+		NodesAdded:   change.NodesAdded,
+		NodesUpdated: change.NodesUpdated,
+		NodesRemoved: change.NodesRemoved,
+		EdgesAdded:   change.EdgesAdded,
+		EdgesUpdated: change.EdgesUpdated,
+		EdgesRemoved: change.EdgesRemoved,
+		MapVersion:   change.VersionBefore,
+		Diff:         string(content),
+	}
+	if err := db.DB.Create(&contribution).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存贡献失败")
+		return
+	}
+
+	contribution.Diff = ""
+	c.JSON(http.StatusCreated, contribution)
+}
+
+// GetMyContributions 当前用户提交的贡献 (最近的在前) 和统计
+func GetMyContributions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	var list []model.Contribution
+	if err := db.DB.Omit("diff").Where("user_id = ?", userID).Order("id DESC").Limit(100).Find(&list).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	stats, err := contributorStats(db.DB.Where("user_id = ?", userID))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	mine := model.ContributorStats{UserID: userID}
+	if len(stats) > 0 {
+		mine = stats[0]
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(list), "stats": mine, "contributions": list})
+}
+
+// GetContributions 贡献列表 (管理员，默认只列出待审核的，最早的在前；?status= 为 all 时列出全部，最近的在前)
+func GetContributions(c *gin.Context) {
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	query := db.DB.Omit("diff").Limit(limit)
+	switch status := c.DefaultQuery("status", model.ContributionPending); status {
+	case "all":
+		query = query.Order("id DESC")
+	case model.ContributionPending:
+		query = query.Where("status = ?", status).Order("id")
+	default:
+		query = query.Where("status = ?", status).Order("id DESC")
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var list []model.Contribution
+	if err := query.Find(&list).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(list), "contributions": list})
+}
+
+// GetContributionByID 贡献详情，包含差异文件原文 (管理员)
+func GetContributionByID(c *gin.Context) {
+	contribution, ok := findContribution(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, contribution)
+}
+
+// AcceptContribution 采纳贡献 (管理员)：把差异应用到数据库、写入变更记录并重新加载路网
+// 提交后地图已发生变化、差异不再适用时返回 409，贡献保持待审核
+func AcceptContribution(c *gin.Context) {
+	g := requestGraph(c)
+	var req ReviewContributionRequest
+	if !bindReview(c, &req) {
+		return
+	}
+	contribution, ok := findContribution(c)
+	if !ok {
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	// 先占用状态，避免两个管理员同时采纳
+	if !claimContribution(c, contribution.ID, model.ContributionAccepted) {
+		return
+	}
+
+	adminID := c.GetUint("user_id")
+	change, err := db.ApplyMapDiff([]byte(contribution.Diff), db.DiffOptions{
+		UserID:         adminID,
+		VersionBefore:  g.Version(),
+		ContributionID: contribution.ID,
+	})
+	if err != nil {
+		db.DB.Model(contribution).Update("status", model.ContributionPending)
+		var verr *db.ValidationError
+		if errors.As(err, &verr) {
+			respondErrorDetails(c, http.StatusConflict, CodeConflict, "贡献与当前地图冲突", gin.H{"errors": verr.Problems})
+		} else {
+			respondErrorDetails(c, http.StatusConflict, CodeConflict, "贡献与当前地图冲突", gin.H{"errors": []string{err.Error()}})
+		}
+		return
+	}
+
+	now := time.Now()
+	err = db.DB.Model(contribution).Updates(map[string]interface{}{
+		"reviewed_by": adminID,
+		"reviewed_at": now,
+		"review_note": req.Note,
+		"change_id":   change.ID,
+	}).Error
+	if err != nil {
+		slog.Warn("更新贡献失败", "id", contribution.ID, "error", err)
+	}
+
+	if err := activateChange(&change); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "数据已导入，但重新加载路网失败")
+		return
+	}
+
+	change.Diff = ""
+	contribution.Diff = ""
+	contribution.Status, contribution.ReviewedBy, contribution.ReviewedAt = model.ContributionAccepted, adminID, &now
+	contribution.ReviewNote, contribution.ChangeID = req.Note, change.ID
+	c.JSON(http.StatusOK, gin.H{"contribution": contribution, "change": change})
+}
+
+// RejectContribution 拒绝贡献 (管理员)，note 为给提交者的说明
+func RejectContribution(c *gin.Context) {
+	var req ReviewContributionRequest
+	if !bindReview(c, &req) {
+		return
+	}
+	contribution, ok := findContribution(c)
+	if !ok {
+		return
+	}
+	if !claimContribution(c, contribution.ID, model.ContributionRejected) {
+		return
+	}
+
+	now := time.Now()
+	err := db.DB.Model(contribution).Updates(map[string]interface{}{
+		"reviewed_by": c.GetUint("user_id"),
+		"reviewed_at": now,
+		"review_note": req.Note,
+	}).Error
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "更新贡献失败")
+		return
+	}
+
+	contribution.Diff = ""
+	contribution.Status, contribution.ReviewedBy, contribution.ReviewedAt = model.ContributionRejected, c.GetUint("user_id"), &now
+	contribution.ReviewNote = req.Note
+	c.JSON(http.StatusOK, contribution)
+}
+
+// GetContributorStats 贡献者统计 (管理员)，按采纳数从多到少
+func GetContributorStats(c *gin.Context) {
+	stats, err := contributorStats(db.DB)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	ids := make([]uint, len(stats))
+	for i := range stats {
+		ids[i] = stats[i].UserID
+	}
+	var users []model.User
+	db.DB.Select("id", "username").Where("id IN ?", ids).Find(&users)
+	names := make(map[uint]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	for i := range stats {
+		stats[i].Username = names[stats[i].UserID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": len(stats), "contributors": stats})
+}
+
+// contributorStats 按用户统计 query 范围内的贡献
+func contributorStats(query *gorm.DB) ([]model.ContributorStats, error) {
+	var stats []model.ContributorStats
+	err := query.Model(&model.Contribution{}).
+		Select("user_id, COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS pending, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS accepted, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS rejected",
+			model.ContributionPending, model.ContributionAccepted, model.ContributionRejected).
+		Group("user_id").
+		Order("accepted DESC, total DESC, user_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		s := &stats[i]
+		if reviewed := s.Accepted + s.Rejected; reviewed > 0 {
+			s.AcceptanceRate = float64(s.Accepted) / float64(reviewed)
+		}
+	}
+	return stats, nil
+}
+
+// bindReview 解析审核请求 (请求体可以为空)
+func bindReview(c *gin.Context, req *ReviewContributionRequest) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+	return true
+}
+
+// claimContribution 把待审核的贡献改为 status；已被审核时返回 409
+func claimContribution(c *gin.Context, id uint, status string) bool {
+	result := db.DB.Model(&model.Contribution{}).
+		Where("id = ? AND status = ?", id, model.ContributionPending).
+		Update("status", status)
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "更新贡献失败")
+		return false
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusConflict, CodeConflict, "贡献已审核")
+		return false
+	}
+	return true
+}
+
+// findContribution 按路径参数 id 查找贡献，找不到时写入错误响应
+func findContribution(c *gin.Context) (*model.Contribution, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的贡献 ID")
+		return nil, false
+	}
+
+	var contribution model.Contribution
+	if err := db.DB.First(&contribution, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "贡献不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return nil, false
+	}
+	return &contribution, true
+}
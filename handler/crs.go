@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求坐标系
+// 路径规划、重新规划、节点搜索和自动补全可以指定 crs (wgs84 或 gcj02)：
+// 请求中的坐标按 crs 解释，先转换为 WGS-84 再规划；响应中的坐标再转换回 crs，与客户端的底图一致
+
+// parseCRS 解析坐标系参数，无效时写入错误响应并返回 false
+func parseCRS(c *gin.Context, s string) (utils.CRS, bool) {
+	crs, err := utils.ParseCRS(s)
+	if err != nil {
+		respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "坐标系无效 (可选 wgs84、gcj02)", gin.H{"crs": s})
+		return "", false
+	}
+	return crs, true
+}
+
+// queryCRS 解析查询参数 ?crs=
+func queryCRS(c *gin.Context) (utils.CRS, bool) {
+	return parseCRS(c, c.Query("crs"))
+}
+
+// toWGS84 把请求中的坐标 (起终点、临时节点) 从 req.CRS 转换为 WGS-84，并清空 req.CRS
+// 转换后的请求与直接使用 WGS-84 的请求相同 (共用缓存，保存的路线也是 WGS-84)
+func (req *PathRequest) toWGS84(crs utils.CRS) {
+	req.CRS = ""
+	if crs == utils.CRSWGS84 {
+		return
+	}
+	// 坐标为 0 表示未提供 (使用节点 ID)
+	if req.StartLat != 0 || req.StartLng != 0 {
+		req.StartLat, req.StartLng = latLng(crs.ToWGS84(model.Point{Lat: req.StartLat, Lng: req.StartLng}))
+	}
+	if req.EndLat != 0 || req.EndLng != 0 {
+		req.EndLat, req.EndLng = latLng(crs.ToWGS84(model.Point{Lat: req.EndLat, Lng: req.EndLng}))
+	}
+	if req.Overlay != nil {
+		for i := range req.Overlay.Nodes {
+			n := &req.Overlay.Nodes[i]
+			n.Lat, n.Lng = latLng(crs.ToWGS84(model.Point{Lat: n.Lat, Lng: n.Lng}))
+		}
+	}
+}
+
+// inCRS 把响应中的坐标从 WGS-84 转换到 crs (修改 resp 本身，应在写入缓存和保存路线之后调用)
+func (resp *PathResponse) inCRS(crs utils.CRS) {
+	if resp == nil || crs == utils.CRSWGS84 {
+		return
+	}
+	convert := func(lat, lng *float64) {
+		*lat, *lng = latLng(crs.FromWGS84(model.Point{Lat: *lat, Lng: *lng}))
+	}
+	for i := range resp.Path {
+		convert(&resp.Path[i].Lat, &resp.Path[i].Lng)
+	}
+	for i := range resp.Geometry {
+		convert(&resp.Geometry[i][0], &resp.Geometry[i][1])
+	}
+	for i := range resp.Transfers {
+		convert(&resp.Transfers[i].Lat, &resp.Transfers[i].Lng)
+	}
+	for _, snap := range []*SnapInfo{resp.SnappedStart, resp.SnappedEnd} {
+		if snap != nil {
+			convert(&snap.Lat, &snap.Lng)
+		}
+	}
+	if resp.Parking != nil {
+		convert(&resp.Parking.Lat, &resp.Parking.Lng)
+	}
+	for i := range resp.ChargeStops {
+		convert(&resp.ChargeStops[i].Lat, &resp.ChargeStops[i].Lng)
+	}
+	if b := resp.Bounds; b != nil {
+		convert(&b.MinLat, &b.MinLng)
+		convert(&b.MaxLat, &b.MaxLng)
+	}
+}
+
+// inCRS 把节点坐标从 WGS-84 转换到 crs
+func (n *PathNode) inCRS(crs utils.CRS) {
+	n.Lat, n.Lng = latLng(crs.FromWGS84(model.Point{Lat: n.Lat, Lng: n.Lng}))
+}
+
+// latLng 拆分坐标
+func latLng(p model.Point) (float64, float64) {
+	return p.Lat, p.Lng
+}
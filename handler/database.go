@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireDatabase 依赖数据库的接口在无数据库模式 (DB_DRIVER=memory) 下返回 503
+func RequireDatabase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !db.Enabled() {
+			respondNoDatabase(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// respondNoDatabase 当前为无数据库模式，该功能不可用
+func respondNoDatabase(c *gin.Context) {
+	respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "该功能需要数据库 (当前为无数据库模式)")
+}
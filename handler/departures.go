@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"runtime"
+	"sync"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/i18n"
+)
+
+// maxDepartureTimes 一次请求最多比较的出发时间数
+const maxDepartureTimes = 6
+
+// DepartureOption 按某个出发时间规划的结果 (用于 "现在出发 / 8:00 出发 / 8:30 出发" 的比较)
+type DepartureOption struct {
+	DepartAt      time.Time  `json:"depart_at"`
+	Found         bool       `json:"found"`
+	ArriveAt      *time.Time `json:"arrive_at,omitempty"`
+	EstimatedTime float64    `json:"estimated_time,omitempty"` // 预计时间 (秒)
+	DurationText  string     `json:"duration_text,omitempty"`
+	Distance      float64    `json:"distance,omitempty"`
+	Fingerprint   string     `json:"fingerprint,omitempty"` // 与主路线的 fingerprint 相同时路线不变，只是用时不同
+	Approximate   bool       `json:"approximate,omitempty"`
+}
+
+// planDepartures 按 times 中的每个出发时间重新搜索 (并行，最多同时使用 GOMAXPROCS 个协程)，返回与 times 顺序相同的结果
+// 参数校验、坐标吸附、临时路段和用户偏好与主路线共用，只有搜索本身按出发时间重新执行；
+// parking 为 true 时与主路线一样先开到终点附近的停车场
+func planDepartures(g *algo.Graph, times []time.Time, start, end algo.Waypoint, opts algo.SearchOptions, parking bool, parkingRadius float64, lang string, now time.Time) []DepartureOption {
+	options := make([]DepartureOption, len(times))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, departAt := range times {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			o := opts
+			o.DepartAt = departAt
+			if o.Weather != nil && !withinWeatherHorizon(departAt, now) {
+				o.Weather = nil // 当前天气只适用于临近的出发时间
+			}
+			var result algo.PathResult
+			if parking {
+				result, _ = g.RouteViaParking(start.NodeID, end.NodeID, o, parkingRadius)
+			} else {
+				result = g.RouteBetween(start, end, o)
+			}
+			options[i] = departureOption(g, result, departAt, lang, now)
+		}()
+	}
+	wg.Wait()
+	return options
+}
+
+// departureOption 由一次搜索的结果构建出发时间选项 (与主路线一样按实时车辆数据修正等待时间)
+func departureOption(g *algo.Graph, result algo.PathResult, departAt time.Time, lang string, now time.Time) DepartureOption {
+	option := DepartureOption{DepartAt: departAt, Found: result.Found, Approximate: result.TimedOut}
+	if !result.Found {
+		return option
+	}
+	segments := make([]PathSegment, len(result.Segments))
+	for i, seg := range result.Segments {
+		segments[i] = PathSegment{FromID: seg.FromID, ToID: seg.ToID, Time: seg.Time, UsedMode: seg.UsedMode, LineID: seg.LineID}
+	}
+	eta := result.EstimatedTime + applyRealtime(g, segments, departAt, now)
+	arriveAt := departAt.Add(time.Duration(eta * float64(time.Second)))
+	option.ArriveAt = &arriveAt
+	option.EstimatedTime = eta
+	option.DurationText = i18n.FormatDuration(lang, eta)
+	option.Distance = result.Distance
+	option.Fingerprint = routeFingerprint(segments)
+	return option
+}
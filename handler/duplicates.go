@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DuplicateNode 疑似重复组中的一个节点
+type DuplicateNode struct {
+	PathNode
+	Edges int      `json:"edges"`           // 出边数量 (含自动生成的反向边)
+	Lines []string `json:"lines,omitempty"` // 经过的线路
+}
+
+// DuplicateGroup 一组疑似重复的节点
+type DuplicateGroup struct {
+	Keep        string          `json:"keep"`         // 建议保留的节点 (连接最多)
+	MaxDistance float64         `json:"max_distance"` // 组内最大直线距离 (米)
+	Nodes       []DuplicateNode `json:"nodes"`
+}
+
+// MergeNodesRequest 合并节点请求
+type MergeNodesRequest struct {
+	Keep  string   `json:"keep" binding:"required"`               // 保留的节点
+	Merge []string `json:"merge" binding:"required,min=1,max=50"` // 合并到 keep 后删除的节点
+}
+
+// maxDuplicateRadius 重复节点检测和合并的距离上限 (米)
+const maxDuplicateRadius = 200.0
+
+// FindDuplicateNodes 查找疑似重复的节点 (管理员)
+// 参数: radius (距离上限，默认 30 米，最大 200 米)，similarity (名称相似度下限 0 ~ 1，默认 0.8)
+func FindDuplicateNodes(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	radius := algo.DefaultDuplicateRadius
+	if s := c.Query("radius"); s != "" {
+		r, err := strconv.ParseFloat(s, 64)
+		if err != nil || r <= 0 || r > maxDuplicateRadius {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "查询半径超出范围 (0 ~ 200 米)")
+			return
+		}
+		radius = r
+	}
+	similarity := algo.DefaultDuplicateSimilarity
+	if s := c.Query("similarity"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 1 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "相似度超出范围 (0 ~ 1)")
+			return
+		}
+		similarity = v
+	}
+
+	lang := language(c)
+	clusters := g.FindDuplicates(radius, similarity)
+	groups := make([]DuplicateGroup, 0, len(clusters))
+	for _, cluster := range clusters {
+		group := DuplicateGroup{Keep: cluster.Nodes[0].ID, MaxDistance: cluster.MaxDistance}
+		for _, node := range cluster.Nodes {
+			group.Nodes = append(group.Nodes, DuplicateNode{
+				PathNode: buildPathNode(node, lang),
+				Edges:    len(g.AdjList[node.ID]),
+				Lines:    g.NodeLines[node.ID],
+			})
+		}
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"radius":     radius,
+		"similarity": similarity,
+		"count":      len(groups),
+		"groups":     groups,
+	})
+}
+
+// MergeNodes 合并重复节点 (管理员)
+// 被合并节点的边、线路站点、别名等改为指向保留节点，名称作为保留节点的别名，然后删除被合并节点并重新加载路网
+func MergeNodes(c *gin.Context) {
+	g := requestGraph(c)
+	var req MergeNodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	slices.Sort(req.Merge)
+	req.Merge = slices.Compact(req.Merge)
+	if slices.Contains(req.Merge, req.Keep) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "保留节点不能同时被合并")
+		return
+	}
+	keep := g.Nodes[req.Keep]
+	if keep == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在: "+req.Keep)
+		return
+	}
+	var maxDistance float64
+	for _, id := range req.Merge {
+		node := g.Nodes[id]
+		if node == nil {
+			respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在: "+id)
+			return
+		}
+		maxDistance = max(maxDistance, utils.HaversineDistance(model.Point{Lat: keep.Lat, Lng: keep.Lng}, model.Point{Lat: node.Lat, Lng: node.Lng}))
+	}
+	// 防止误操作把相距很远的节点合并
+	if maxDistance > maxDuplicateRadius {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "被合并节点与保留节点相距过远 (超过 200 米)")
+		return
+	}
+
+	summary, err := db.MergeNodes(req.Keep, req.Merge)
+	if err != nil {
+		slog.Error("合并节点失败", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "合并节点失败")
+		return
+	}
+	if _, err := ReloadGraph(); err != nil {
+		slog.Warn("合并节点后重新加载路网失败", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "节点已合并，但重新加载路网失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": tr(c, "节点已合并"),
+		"summary": summary,
+	})
+}
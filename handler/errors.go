@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 机器可读的错误码 (message 为按 Accept-Language 翻译的说明，可能调整，客户端应根据 code 判断)
+const (
+	CodeInvalidRequest       = "INVALID_REQUEST"        // 请求参数错误
+	CodeOutOfRange           = "VALUE_OUT_OF_RANGE"     // 参数超出允许范围
+	CodeCoordinateInvalid    = "COORDINATE_INVALID"     // 坐标超出范围
+	CodeModeInvalid          = "MODE_INVALID"           // 交通方式无效
+	CodeNodeNotFound         = "NODE_NOT_FOUND"         // 节点 (起点、终点、站点) 不存在
+	CodeLineNotFound         = "LINE_NOT_FOUND"         // 线路不存在
+	CodeNotFound             = "NOT_FOUND"              // 其他资源不存在
+	CodeRouteNotFound        = "ROUTE_NOT_FOUND"        // 没有符合条件的路径
+	CodeExpired              = "EXPIRED"                // 资源已过期
+	CodeConflict             = "CONFLICT"               // 资源已存在或数量已达上限
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED" // Idempotency-Key 已用于其他请求
+	CodeUnauthorized         = "UNAUTHORIZED"           // 未登录或令牌无效
+	CodeInvalidCredentials   = "INVALID_CREDENTIALS"    // 用户名或密码错误
+	CodeForbidden            = "FORBIDDEN"              // 权限不足
+	CodeGraphNotLoaded       = "GRAPH_NOT_LOADED"       // 地图数据未加载
+	CodeDatabaseError        = "DATABASE_ERROR"         // 数据库读写失败
+	CodeInternalError        = "INTERNAL_ERROR"         // 其他服务端错误
+	CodeUpstreamError        = "UPSTREAM_ERROR"         // 第三方服务出错
+	CodeUnavailable          = "UNAVAILABLE"            // 功能未启用
+	CodeRateLimited          = "RATE_LIMITED"           // 请求过于频繁
+	CodeQuotaExceeded        = "QUOTA_EXCEEDED"         // 今日请求次数已达配额上限
+	CodeLoginLocked          = "LOGIN_LOCKED"           // 登录失败次数过多，暂时锁定
+	CodeCaptchaRequired      = "CAPTCHA_REQUIRED"       // 需要完成人机验证
+	CodeCancelled            = "CANCELLED"              // 请求已取消 (客户端断开或服务正在关闭)
+)
+
+// ErrorResponse 统一的错误响应
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+	Error   string `json:"error"` // 与 message 相同，兼容旧版客户端
+}
+
+// respondError 写入错误响应并中止后续处理
+func respondError(c *gin.Context, status int, code, message string) {
+	respondErrorDetails(c, status, code, message, nil)
+}
+
+// respondErrorDetails 写入带详细信息 (如参数校验错误) 的错误响应并中止后续处理
+func respondErrorDetails(c *gin.Context, status int, code, message string, details any) {
+	message = tr(c, message)
+	c.AbortWithStatusJSON(status, ErrorResponse{Code: code, Message: message, Details: details, Error: message})
+}
+
+// respondBindError 请求参数解析或校验失败
+func respondBindError(c *gin.Context, err error) {
+	respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误", err.Error())
+}
+
+// checkCoordinates 检查坐标是否在有效范围内，无效时写入错误响应 (details 为第一个无效坐标的下标) 并返回 false
+func checkCoordinates(c *gin.Context, points ...model.Point) bool {
+	for i, p := range points {
+		if !utils.ValidCoordinate(p.Lat, p.Lng) {
+			respondErrorDetails(c, http.StatusBadRequest, CodeCoordinateInvalid, "坐标超出范围 (纬度 -90 ~ 90，经度 -180 ~ 180)", gin.H{"index": i, "lat": p.Lat, "lng": p.Lng})
+			return false
+		}
+	}
+	return true
+}
+
+// respondCancelled 请求已取消 (客户端已断开时收不到响应，主要用于服务关闭时通知仍在等待的客户端)
+func respondCancelled(c *gin.Context) {
+	respondError(c, http.StatusServiceUnavailable, CodeCancelled, "请求已取消")
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"traffic-system/events"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat SSE 心跳间隔 (防止代理因空闲断开连接)
+const sseHeartbeat = 15 * time.Second
+
+// StreamEvents 以 Server-Sent Events 推送路况、交通事件等更新
+// 可选参数 types 按事件类型过滤 (逗号分隔)；断线重连时浏览器自动携带 Last-Event-ID 补发错过的事件
+func StreamEvents(c *gin.Context) {
+	var filter map[string]bool
+	if types := c.Query("types"); types != "" {
+		filter = make(map[string]bool)
+		for _, t := range strings.Split(types, ",") {
+			filter[strings.TrimSpace(t)] = true
+		}
+	}
+
+	lastID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	ch := events.Default.Subscribe(32, lastID)
+	defer events.Default.Unsubscribe(ch)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+
+	// 立即发送响应头，客户端不必等到第一条事件才确认连接成功
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if filter != nil && !filter[event.Type] {
+				return true
+			}
+			c.Render(-1, sse.Event{
+				Event: event.Type,
+				Id:    strconv.FormatUint(event.ID, 10),
+				Data:  event,
+			})
+			return true
+		}
+	})
+}
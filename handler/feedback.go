@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FeedbackRequest 提交反馈请求
+// 至少指定 route_id (来自 /api/path/find)、node_id、from_id + to_id (路段) 或 lat + lng 中的一项
+type FeedbackRequest struct {
+	Type       string   `json:"type" binding:"required"`
+	Comment    string   `json:"comment" binding:"max=1000"`
+	RouteID    string   `json:"route_id"`
+	ActualTime float64  `json:"actual_time" binding:"min=0"` // 实际用时 (秒)，用于 bad_eta
+	NodeID     string   `json:"node_id"`
+	FromID     string   `json:"from_id"`
+	ToID       string   `json:"to_id"`
+	Lat        *float64 `json:"lat"`
+	Lng        *float64 `json:"lng"`
+}
+
+// UpdateFeedbackRequest 处理反馈请求 (管理员)
+type UpdateFeedbackRequest struct {
+	Status    string  `json:"status" binding:"required"`
+	AdminNote *string `json:"admin_note" binding:"omitempty,max=1000"`
+}
+
+// SubmitFeedback 提交路线或地图反馈 (无需登录，登录时记录用户)
+func SubmitFeedback(c *gin.Context) {
+	g := requestGraph(c)
+	var req FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	if !model.IsValidFeedbackType(req.Type) {
+		respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "无效的反馈类型", gin.H{"types": model.FeedbackTypes})
+		return
+	}
+	if (req.FromID == "") != (req.ToID == "") || (req.Lat == nil) != (req.Lng == nil) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "路段需要同时指定 from_id 和 to_id，位置需要同时指定 lat 和 lng")
+		return
+	}
+	if req.RouteID == "" && req.NodeID == "" && req.FromID == "" && req.Lat == nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请指定路线、节点、路段或位置")
+		return
+	}
+
+	feedback := model.Feedback{
+		UserID:     currentUserID(c),
+		Type:       req.Type,
+		Status:     model.FeedbackOpen,
+		Comment:    req.Comment,
+		ActualTime: req.ActualTime,
+		NodeID:     req.NodeID,
+		FromID:     req.FromID,
+		ToID:       req.ToID,
+		MapVersion: g.Version(),
+	}
+
+	if req.NodeID != "" && g.Nodes[req.NodeID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在")
+		return
+	}
+	if req.FromID != "" {
+		if g.Nodes[req.FromID] == nil || g.Nodes[req.ToID] == nil {
+			respondError(c, http.StatusBadRequest, CodeNodeNotFound, "起点或终点不存在")
+			return
+		}
+		// 缺少连接的反馈针对的正是地图中没有的路段
+		if req.Type != model.FeedbackMissingConnection && !hasEdge(g, req.FromID, req.ToID) {
+			respondError(c, http.StatusBadRequest, CodeNotFound, "路段不存在")
+			return
+		}
+	}
+	if req.Lat != nil {
+		if !checkCoordinates(c, model.Point{Lat: *req.Lat, Lng: *req.Lng}) {
+			return
+		}
+		feedback.Lat, feedback.Lng = req.Lat, req.Lng
+	}
+
+	if req.RouteID != "" {
+		stored, err := loadRoute(c.Request.Context(), req.RouteID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternalError, "读取路线失败")
+			return
+		}
+		if stored == nil || stored.Route == nil {
+			respondError(c, http.StatusNotFound, CodeNotFound, "路线不存在或已过期")
+			return
+		}
+		routeJSON, err := json.Marshal(stored.Route)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternalError, "保存反馈失败")
+			return
+		}
+		feedback.RouteID = req.RouteID
+		feedback.Fingerprint = routeFingerprint(stored.Route.Segments)
+		feedback.EstimatedTime = stored.Route.EstimatedTime
+		feedback.Route = string(routeJSON)
+	}
+
+	if err := db.DB.Create(&feedback).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存反馈失败")
+		return
+	}
+
+	// 只通知管理员配置的 Webhook，不在公开的事件流中广播
+	webhook.Dispatch(webhook.EventFeedbackCreated, feedback)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      feedback.ID,
+		"status":  feedback.Status,
+		"message": tr(c, "感谢反馈，我们会尽快核实"),
+	})
+}
+
+// GetFeedback 反馈列表 (管理员)，可按 status、type 筛选，最新的在前
+func GetFeedback(c *gin.Context) {
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	query := db.DB.Order("id DESC").Limit(limit)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind := c.Query("type"); kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+	var list []model.Feedback
+	if err := query.Find(&list).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	var open int64
+	db.DB.Model(&model.Feedback{}).Where("status = ?", model.FeedbackOpen).Count(&open)
+	c.JSON(http.StatusOK, gin.H{"count": len(list), "open": open, "feedback": list})
+}
+
+// GetFeedbackByID 反馈详情 (管理员)，包含反馈时的路线
+func GetFeedbackByID(c *gin.Context) {
+	feedback, ok := findFeedback(c)
+	if !ok {
+		return
+	}
+
+	var route *PathResponse
+	if feedback.Route != "" {
+		route = &PathResponse{}
+		if err := json.Unmarshal([]byte(feedback.Route), route); err != nil {
+			route = nil
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"feedback": feedback, "route": route})
+}
+
+// UpdateFeedback 更新反馈的处理状态 (管理员)，改为 fixed 或 rejected 时记录处理人和时间
+func UpdateFeedback(c *gin.Context) {
+	var req UpdateFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !model.IsValidFeedbackStatus(req.Status) {
+		respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "无效的处理状态", gin.H{"statuses": model.FeedbackStatuses})
+		return
+	}
+
+	feedback, ok := findFeedback(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{"status": req.Status}
+	if req.AdminNote != nil {
+		updates["admin_note"] = *req.AdminNote
+	}
+	if req.Status == model.FeedbackFixed || req.Status == model.FeedbackRejected {
+		updates["resolved_by"] = c.GetUint("user_id")
+		updates["resolved_at"] = time.Now()
+	} else {
+		updates["resolved_by"] = 0
+		updates["resolved_at"] = nil
+	}
+	if err := db.DB.Model(feedback).Updates(updates).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "更新反馈失败")
+		return
+	}
+	if err := db.DB.First(feedback, feedback.ID).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// findFeedback 按路径参数 id 查找反馈，找不到时写入错误响应
+func findFeedback(c *gin.Context) (*model.Feedback, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的反馈 ID")
+		return nil, false
+	}
+
+	var feedback model.Feedback
+	if err := db.DB.First(&feedback, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "反馈不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return nil, false
+	}
+	return &feedback, true
+}
+
+// hasEdge 当前地图中是否有 from 到 to 的边 (任意交通方式)
+func hasEdge(g *algo.Graph, from, to string) bool {
+	for _, edge := range g.AdjList[from] {
+		if edge.To == to {
+			return true
+		}
+	}
+	return false
+}
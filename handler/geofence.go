@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeofenceRequest 创建/更新地理围栏请求
+type GeofenceRequest struct {
+	Name    string        `json:"name" binding:"required"`
+	Kind    string        `json:"kind"`
+	Polygon model.Polygon `json:"polygon" binding:"required,min=3"` // 至少 3 个顶点
+	Active  *bool         `json:"active"`                           // 默认启用
+}
+
+// GeofenceCheckRequest 围栏检查请求 (点、坐标序列、节点路径三选一或组合)
+type GeofenceCheckRequest struct {
+	Point *model.Point  `json:"point"` // 检查点位于哪些围栏内
+	Route []model.Point `json:"route"` // 检查坐标序列经过哪些围栏
+	Path  []string      `json:"path"`  // 检查节点路径 (如路径规划结果中的节点 ID) 经过哪些围栏
+	Kind  string        `json:"kind"`  // 只检查指定类型的围栏
+}
+
+// GeofenceInfo 围栏概要 (检查结果中使用，不含多边形)
+type GeofenceInfo struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// GeofenceEvent 路线进出围栏的事件
+type GeofenceEvent struct {
+	GeofenceInfo
+	Type    string  `json:"type"`    // "enter" 或 "exit"
+	Segment int     `json:"segment"` // 发生在第几段 (从 0 开始，第 i 段为 route[i] -> route[i+1])
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+}
+
+// GetGeofences 获取启用的地理围栏 (可按 ?kind= 过滤)
+func GetGeofences(c *gin.Context) {
+	fences, err := loadGeofences(c.Query("kind"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(fences),
+		"geofences": fences,
+	})
+}
+
+// GetGeofenceByID 获取地理围栏详情
+func GetGeofenceByID(c *gin.Context) {
+	var fence model.Geofence
+	if err := db.DB.First(&fence, c.Param("id")).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "围栏不存在")
+		return
+	}
+	c.JSON(http.StatusOK, fence)
+}
+
+// CreateGeofence 创建地理围栏 (管理员)
+func CreateGeofence(c *gin.Context) {
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !checkCoordinates(c, req.Polygon...) {
+		return
+	}
+
+	fence := model.Geofence{Name: req.Name, Kind: req.Kind, Polygon: req.Polygon, Active: true}
+	if req.Active != nil {
+		fence.Active = *req.Active
+	}
+	if err := db.DB.Create(&fence).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存围栏失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, fence)
+}
+
+// UpdateGeofence 更新地理围栏 (管理员)
+func UpdateGeofence(c *gin.Context) {
+	var fence model.Geofence
+	if err := db.DB.First(&fence, c.Param("id")).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "围栏不存在")
+		return
+	}
+
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !checkCoordinates(c, req.Polygon...) {
+		return
+	}
+
+	fence.Name = req.Name
+	fence.Kind = req.Kind
+	fence.Polygon = req.Polygon
+	if req.Active != nil {
+		fence.Active = *req.Active
+	}
+	if err := db.DB.Save(&fence).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存围栏失败")
+		return
+	}
+	reloadZones()
+
+	c.JSON(http.StatusOK, fence)
+}
+
+// DeleteGeofence 删除地理围栏 (管理员)
+func DeleteGeofence(c *gin.Context) {
+	result := db.DB.Delete(&model.Geofence{}, c.Param("id"))
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除围栏失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "围栏不存在")
+		return
+	}
+	db.DB.Where("geofence_id = ?", c.Param("id")).Delete(&model.ZoneRule{})
+	reloadZones()
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "围栏已删除")})
+}
+
+// CheckGeofences 检查点位于哪些围栏内、路线经过哪些围栏以及进出位置
+func CheckGeofences(c *gin.Context) {
+	g := requestGraph(c)
+	var req GeofenceCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// 节点路径转换为坐标序列
+	route := req.Route
+	if len(route) == 0 && len(req.Path) > 0 {
+		if g == nil {
+			respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+			return
+		}
+		for _, id := range req.Path {
+			node := g.Nodes[id]
+			if node == nil {
+				respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在: "+id)
+				return
+			}
+			route = append(route, model.Point{Lat: node.Lat, Lng: node.Lng})
+		}
+	}
+	if req.Point != nil && !checkCoordinates(c, *req.Point) {
+		return
+	}
+	if !checkCoordinates(c, route...) {
+		return
+	}
+	if req.Point == nil && len(route) == 0 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "需要提供 point、route 或 path")
+		return
+	}
+
+	fences, err := loadGeofences(req.Kind)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	inside := make([]GeofenceInfo, 0)
+	intersects := make([]GeofenceInfo, 0)
+	events := make([]GeofenceEvent, 0)
+	for i := range fences {
+		fence := &fences[i]
+		info := GeofenceInfo{ID: fence.ID, Name: fence.Name, Kind: fence.Kind}
+
+		if req.Point != nil && utils.PointInPolygon(*req.Point, fence.Polygon) {
+			inside = append(inside, info)
+		}
+		if len(route) > 0 {
+			if fenceEvents, hit := routeGeofenceEvents(route, fence.Polygon, info); hit {
+				intersects = append(intersects, info)
+				events = append(events, fenceEvents...)
+			}
+		}
+	}
+
+	resp := gin.H{}
+	if req.Point != nil {
+		resp["inside"] = inside
+	}
+	if len(route) > 0 {
+		resp["intersects"] = intersects
+		resp["events"] = events
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// routeGeofenceEvents 计算路线进出围栏的事件，并返回路线是否与围栏有交集
+// 起点已在围栏内时不产生 enter 事件；某一段穿过围栏但两端都在外面时，同时产生 enter 和 exit
+func routeGeofenceEvents(route []model.Point, polygon model.Polygon, info GeofenceInfo) ([]GeofenceEvent, bool) {
+	var events []GeofenceEvent
+	state := utils.PointInPolygon(route[0], polygon)
+	hit := state
+
+	event := func(kind string, segment int, p model.Point) {
+		events = append(events, GeofenceEvent{GeofenceInfo: info, Type: kind, Segment: segment, Lat: p.Lat, Lng: p.Lng})
+	}
+	for i := 1; i < len(route); i++ {
+		in := utils.PointInPolygon(route[i], polygon)
+		switch {
+		case in && !state:
+			event("enter", i-1, route[i])
+		case !in && state:
+			event("exit", i-1, route[i])
+		case !in && utils.SegmentIntersectsPolygon(route[i-1], route[i], polygon):
+			event("enter", i-1, route[i-1])
+			event("exit", i-1, route[i])
+		}
+		hit = hit || in || len(events) > 0
+		state = in
+	}
+	return events, hit
+}
+
+// loadGeofences 读取启用的围栏，kind 不为空时只返回该类型
+func loadGeofences(kind string) ([]model.Geofence, error) {
+	query := db.DB.Where("active = ?", true)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var fences []model.Geofence
+	err := query.Order("id").Find(&fences).Error
+	return fences, err
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/events"
+	"traffic-system/model"
+	"traffic-system/popularity"
+	"traffic-system/speeds"
+	"traffic-system/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentGraph 各接口使用的图
+// 重新加载时整体替换 (不修改原来的图)，每个请求开始时读取一次 (requestGraph)，同一请求中始终使用同一张图
+var currentGraph atomic.Pointer[algo.Graph]
+
+// reloadMu 串行执行 ReloadGraph (管理接口、导入、后台任务和数据库监听可能同时触发)
+var reloadMu sync.Mutex
+
+// graphKey 请求使用的图在 gin.Context 中的键
+const graphKey = "graph"
+
+// CurrentGraph 当前的图 (未加载时为 nil)，用于后台任务等不属于某个请求的代码
+func CurrentGraph() *algo.Graph {
+	return currentGraph.Load()
+}
+
+// requestGraph 本次请求使用的图：第一次调用时读取当前的图并保存在请求中，之后返回同一张图，
+// 处理请求期间路网被重新加载也不会混用新旧两张图
+func requestGraph(c *gin.Context) *algo.Graph {
+	if g, ok := c.Get(graphKey); ok {
+		return g.(*algo.Graph)
+	}
+	g := currentGraph.Load()
+	c.Set(graphKey, g)
+	return g
+}
+
+// SetGraph 直接替换各接口使用的图并返回原来的图 (不发送事件、不发布快照)
+// 用于启动时设置读取到的路网，测试中也可以换成 fixture 构建的小路网，结束后再换回
+func SetGraph(g *algo.Graph) *algo.Graph {
+	return currentGraph.Swap(g)
+}
+
+// loadedMapState 当前路网构建时读取到的节点和边 (StartGraphWatcher 用来判断是否需要重新构建)
+// 路网从只读副本构建，记录的也是副本的内容：副本落后于主库时，下次检查仍有差异，会再次构建
+var loadedMapState atomic.Pointer[db.MapState]
+
+// ReloadGraph 从数据库重新构建路网并替换当前的图 (节点或边在数据库中变化后调用)
+// 学习到的路段速度和节点热度一并重新加载，完成后发送 map.activated 事件，并在后台发布路网快照
+// 返回本次构建并替换上的图 (其他重新加载可能紧接着替换掉它，需要版本号等信息时应使用返回值而不是 CurrentGraph)
+func ReloadGraph() (*algo.Graph, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return reloadGraphLocked()
+}
+
+// reloadGraphIfChanged 在 reloadMu 内重新读取数据库中的节点和边，与当前路网构建时相比仍有变化才重新构建
+// (检测到变化后等待期间，导入接口或后台任务可能已经重新加载过)；没有变化时返回的图为 nil
+func reloadGraphIfChanged() (*algo.Graph, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	state, err := db.LoadMapState()
+	if err != nil {
+		return nil, err
+	}
+	diff := state.Diff(loadedMapState.Load())
+	if diff.Total() == 0 {
+		return nil, nil
+	}
+	slog.Info("检测到数据库中的路网变化，重新构建路网", "diff", diff.String())
+	return reloadGraphLocked()
+}
+
+// reloadGraphLocked 从只读副本重新构建并替换路网 (与启动时相同)，调用方需持有 reloadMu
+func reloadGraphLocked() (*algo.Graph, error) {
+	state, err := db.LoadReadMapState()
+	if err != nil {
+		slog.Warn("读取节点和边失败", "error", err)
+	}
+	g, err := algo.LoadFromRepository(db.ReadRepo())
+	if err != nil {
+		return nil, err
+	}
+	if rows, err := speeds.Load(); err != nil {
+		slog.Warn("加载路段速度失败", "error", err)
+	} else {
+		g.SetLearnedSpeeds(rows)
+	}
+	if rows, err := popularity.Load(); err != nil {
+		slog.Warn("加载节点热度失败", "error", err)
+	} else {
+		g.SetPopularity(rows)
+	}
+
+	currentGraph.Store(g)
+	if state != nil {
+		loadedMapState.Store(state)
+	}
+	events.Publish(webhook.EventMapActivated, gin.H{"version": g.Version(), "nodes": len(g.Nodes)})
+	go PublishSnapshot(g)
+	return g, nil
+}
+
+// StartGraphWatcher 每隔 interval 检查数据库中的节点和边，有变化时重新构建路网 (ReloadGraph)
+// 检测到变化后等到连续 debounce 时间内不再变化再构建，批量修改只构建一次；
+// 与其他重新加载依次执行，构建前在锁内重新比较，通过导入接口或后台任务已经重新加载过的变化不会重复构建
+func StartGraphWatcher(interval, debounce time.Duration) {
+	go func() {
+		if state, err := db.LoadReadMapState(); err != nil {
+			slog.Warn("读取节点和边失败", "error", err)
+		} else {
+			loadedMapState.CompareAndSwap(nil, state)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			state, err := db.LoadMapState()
+			if err != nil {
+				slog.Error("检查路网变化失败", "error", err)
+				continue
+			}
+			loaded := loadedMapState.Load()
+			if loaded == nil {
+				loadedMapState.CompareAndSwap(nil, state)
+				continue
+			}
+			if state.Diff(loaded).Total() == 0 {
+				continue
+			}
+			if _, err := waitMapStable(state, debounce); err != nil {
+				slog.Error("检查路网变化失败", "error", err)
+				continue
+			}
+
+			start := time.Now()
+			g, err := reloadGraphIfChanged()
+			if err != nil {
+				slog.Error("重新构建路网失败", "error", err)
+				continue
+			}
+			if g == nil {
+				continue
+			}
+			slog.Info("路网已重新构建", "version", g.Version(), "nodes", len(g.Nodes), "elapsed", time.Since(start).Round(time.Millisecond))
+		}
+	}()
+}
+
+// waitMapStable 每隔 debounce 重新读取一次，直到两次读取之间没有变化，返回最后的状态
+func waitMapStable(state *db.MapState, debounce time.Duration) (*db.MapState, error) {
+	for {
+		time.Sleep(debounce)
+		next, err := db.LoadMapState()
+		if err != nil {
+			return nil, err
+		}
+		if next.Diff(state).Total() == 0 {
+			return next, nil
+		}
+		state = next
+	}
+}
+
+// ApplyLearnedSpeeds 把重新统计的路段速度应用到当前路网并发送 traffic.updated 事件，返回匹配到路网的记录数
+func ApplyLearnedSpeeds(rows []model.EdgeSpeed) int {
+	matched := CurrentGraph().SetLearnedSpeeds(rows)
+	events.Publish(webhook.EventTrafficUpdated, gin.H{"edge_speeds": len(rows), "matched": matched})
+	return matched
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"traffic-system/i18n"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// language 根据 Accept-Language 请求头选择响应语言
+func language(c *gin.Context) string {
+	return i18n.Parse(c.GetHeader("Accept-Language"))
+}
+
+// tr 把消息翻译为请求的语言
+func tr(c *gin.Context, msg string) string {
+	return i18n.T(language(c), msg)
+}
+
+// localName 节点在指定语言下的名称 (没有译名时使用中文名)
+func localName(lang string, node *model.Node) string {
+	return i18n.Pick(lang, node.Name, node.NameEn)
+}
+
+// buildPathNode 构建节点信息，name 使用请求的语言
+func buildPathNode(node *model.Node, lang string) PathNode {
+	return PathNode{
+		ID:     node.ID,
+		Name:   localName(lang, node),
+		NameEn: node.NameEn,
+		Lat:    node.Lat,
+		Lng:    node.Lng,
+		Type:   node.Type,
+		Level:  node.Level,
+	}
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 幂等键的限制
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBody       = maxImportSize + 1 // 超过导入上限的请求体由接口本身拒绝
+	maxIdempotentResponse   = 1 << 20           // 超过 1 MB 的响应不保存
+	idempotencyLockTTL      = 10 * time.Minute  // 处理中的标记的过期时间 (进程异常退出时不会一直占用)
+)
+
+// idempotentResponse 保存的响应，以及产生它的请求的摘要
+type idempotentResponse struct {
+	RequestHash string `json:"request_hash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// IdempotencyMiddleware 支持 Idempotency-Key 请求头：同一个调用方用同一个键重试时直接返回第一次的响应 (响应头 Idempotent-Replayed: true)，
+// 不会重复注册、收藏或导入。只保存成功 (2xx) 的响应，失败的请求可以用同一个键重试；
+// 同一个键用于不同的请求 (方法、路径、参数或请求体不同) 时返回 422，第一次的请求还在处理时返回 409。
+// 响应保存在共享缓存中 IDEMPOTENCY_TTL (默认 24 小时)；GET 请求和没有该请求头的请求不受影响
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Idempotency-Key 过长 (最多 255 个字符)")
+			return
+		}
+
+		// 读取请求体计算摘要，再放回去给接口使用
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "读取请求体失败")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := idempotencyRequestHash(c, body)
+
+		ctx := c.Request.Context()
+		cacheKey := idempotencyCacheKey(c, key)
+		replayed, err := replayIdempotent(c, cacheKey, hash)
+		if err != nil {
+			slog.Error("读取幂等记录失败", "error", err)
+			c.Next()
+			return
+		}
+		if replayed {
+			return
+		}
+
+		// 标记为处理中，同时到达的重试请求返回 409
+		lockKey := cacheKey + ":lock"
+		if n, err := cache.Default.Incr(ctx, lockKey, idempotencyLockTTL); err == nil && n > 1 {
+			c.Header("Retry-After", "1")
+			respondError(c, http.StatusConflict, CodeConflict, "相同 Idempotency-Key 的请求正在处理")
+			return
+		}
+		defer func() {
+			if err := cache.Default.Delete(context.WithoutCancel(ctx), lockKey); err != nil {
+				slog.Error("删除幂等处理标记失败", "error", err)
+			}
+		}()
+		// 第一次的请求可能恰好在读取记录之后、标记之前完成
+		if replayed, _ := replayIdempotent(c, cacheKey, hash); replayed {
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		status := w.Status()
+		if status < 200 || status >= 300 || w.overflow {
+			return
+		}
+		data, err := json.Marshal(idempotentResponse{
+			RequestHash: hash,
+			Status:      status,
+			ContentType: w.Header().Get("Content-Type"),
+			Location:    w.Header().Get("Location"),
+			Body:        w.body.Bytes(),
+		})
+		if err != nil {
+			return
+		}
+		ttl := config.GetDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+		if err := cache.Default.Set(context.WithoutCancel(ctx), cacheKey, data, ttl); err != nil {
+			slog.Error("保存幂等记录失败", "error", err)
+		}
+	}
+}
+
+// replayIdempotent 有保存的响应时返回它 (请求与第一次不同时返回 422)，已写入响应时返回 true
+func replayIdempotent(c *gin.Context, cacheKey, hash string) (bool, error) {
+	data, ok, err := cache.Default.Get(c.Request.Context(), cacheKey)
+	if err != nil || !ok {
+		return false, err
+	}
+	var saved idempotentResponse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return false, nil
+	}
+	if saved.RequestHash != hash {
+		respondError(c, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key 已用于其他请求")
+		return true, nil
+	}
+	if saved.Location != "" {
+		c.Header("Location", saved.Location)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(saved.Status, saved.ContentType, saved.Body)
+	c.Abort()
+	return true, nil
+}
+
+// idempotencyCacheKey 幂等记录在共享缓存中的键：按调用方 (API Key、登录用户或匿名请求的客户端 IP) 区分，
+// 不同调用方使用相同的键互不影响
+func idempotencyCacheKey(c *gin.Context, key string) string {
+	caller := "ip:" + c.ClientIP()
+	if subject, id, _ := quotaSubject(c); subject != "" {
+		caller = fmt.Sprintf("%s:%d", subject, id)
+	}
+	sum := sha256.Sum256([]byte(caller + "\n" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// idempotencyRequestHash 请求的摘要：方法、路由 (与版本号无关)、查询参数和请求体
+func idempotencyRequestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", c.Request.Method, unversionedPath(c.Request.URL.Path), c.Request.URL.RawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter 记录写出的响应体 (超过 maxIdempotentResponse 后不再记录)
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxIdempotentResponse {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
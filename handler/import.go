@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize 上传的地图数据大小上限 (字节)
+const maxImportSize = 64 << 20
+
+// ImportMap 导入地图数据 (管理员)
+// 请求体为 map_data.json 格式的 JSON；请求体为空时导入服务器上的 map_data.json
+// 按自然键新增或更新，返回新增/更新/跳过的数量；?dry_run=true 时只统计不写入，?async=true 时作为后台任务执行
+func ImportMap(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	content, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "读取请求体失败 (最大 64 MB)")
+		return
+	}
+
+	// ?async=true 时作为后台任务执行，返回任务 ID
+	if c.Query("async") == "true" {
+		params := ImportJobParams{DryRun: dryRun}
+		if len(content) > 0 {
+			if !json.Valid(content) {
+				respondError(c, http.StatusBadRequest, CodeInvalidRequest, "导入失败: 解析 JSON 失败")
+				return
+			}
+			params.Data = content
+		}
+		enqueueJob(c, JobImport, params)
+		return
+	}
+
+	summary, err := importMap(content, dryRun)
+	if errors.Is(err, errGraphReload) {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "数据已导入，但重新加载路网失败")
+		return
+	}
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// errGraphReload 数据已写入，但重新加载路网失败
+var errGraphReload = errors.New("重新加载路网失败")
+
+// importMap 导入地图数据 (content 为空时导入服务器上的 map_data.json)
+// 有数据变化时重新加载路网并通过 db.OnImport 通知；重新加载失败时返回 errGraphReload
+func importMap(content []byte, dryRun bool) (db.ImportSummary, error) {
+	var summary db.ImportSummary
+	var err error
+	if len(content) > 0 {
+		summary, err = db.ImportMapData("upload", content, db.ImportOptions{DryRun: dryRun})
+	} else {
+		summary, err = db.ImportMapFile("map_data.json", db.ImportOptions{DryRun: dryRun})
+	}
+	if err != nil {
+		return summary, err
+	}
+
+	if !dryRun && summary.Changed() {
+		if _, err := ReloadGraph(); err != nil {
+			slog.Warn("导入后重新加载路网失败", "error", err)
+			return summary, fmt.Errorf("%w: %v", errGraphReload, err)
+		}
+		if db.OnImport != nil {
+			db.OnImport(summary)
+		}
+	}
+	return summary, nil
+}
+
+// ImportMapDiff 导入增量修改文件 (管理员)
+// 在一个事务中新增/修改/删除节点和边，写入变更记录，然后重新加载路网；?dry_run=true 时只检查和统计
+func ImportMapDiff(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	content, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "读取请求体失败 (最大 64 MB)")
+		return
+	}
+	if len(content) == 0 {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求体不能为空")
+		return
+	}
+
+	change, err := db.ApplyMapDiff(content, db.DiffOptions{
+		DryRun:        dryRun,
+		UserID:        c.GetUint("user_id"),
+		VersionBefore: g.Version(),
+	})
+	if err != nil {
+		respondImportError(c, err)
+		return
+	}
+	change.Diff = ""
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "change": change})
+		return
+	}
+
+	if err := activateChange(&change); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "数据已导入，但重新加载路网失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"change": change})
+}
+
+// activateChange 差异写入数据库后重新加载路网，并在变更记录中写入修改后的地图版本
+func activateChange(change *model.MapChange) error {
+	g, err := ReloadGraph()
+	if err != nil {
+		slog.Warn("导入差异后重新加载路网失败", "error", err)
+		return err
+	}
+	change.VersionAfter = g.Version()
+	if err := db.DB.Model(&model.MapChange{}).Where("id = ?", change.ID).Update("version_after", change.VersionAfter).Error; err != nil {
+		slog.Warn("更新变更记录失败", "error", err)
+	}
+	return nil
+}
+
+// GetMapChanges 地图变更记录 (管理员，最近的在前，?limit= 默认 20，最多 100)
+func GetMapChanges(c *gin.Context) {
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	var changes []model.MapChange
+	if err := db.DB.Omit("diff").Order("id DESC").Limit(limit).Find(&changes).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(changes),
+		"changes": changes,
+	})
+}
+
+// GetMapChangeByID 一条地图变更记录，包含差异文件原文 (管理员)
+func GetMapChangeByID(c *gin.Context) {
+	var change model.MapChange
+	if err := db.DB.First(&change, c.Param("id")).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "变更记录不存在")
+		return
+	}
+	c.JSON(http.StatusOK, change)
+}
+
+// respondImportError 返回导入错误；数据校验失败时在 details.errors 中列出所有问题
+func respondImportError(c *gin.Context, err error) {
+	var verr *db.ValidationError
+	if errors.As(err, &verr) {
+		respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "数据校验失败", gin.H{"errors": verr.Problems})
+		return
+	}
+	respondError(c, http.StatusBadRequest, CodeInvalidRequest, "导入失败: "+err.Error())
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/jobs"
+	"traffic-system/model"
+	"traffic-system/popularity"
+	"traffic-system/snapshot"
+	"traffic-system/speeds"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 后台任务类型
+const (
+	JobImport      = "import"       // 导入地图数据 (参数见 ImportJobParams)
+	JobReloadGraph = "reload_graph" // 从数据库重新构建路网 (含 ALT 地标预计算)
+	JobSpeeds      = "speeds"       // 重新统计路段分时速度
+	JobPopularity  = "popularity"   // 重新统计节点热度
+	JobSnapshot    = "snapshot"     // 发布路网快照到 GRAPH_SNAPSHOT_PUBLISH
+)
+
+// ImportJobParams 导入任务的参数
+type ImportJobParams struct {
+	DryRun bool            `json:"dry_run"`
+	Data   json.RawMessage `json:"data,omitempty"` // map_data.json 格式的数据，为空时导入服务器上的 map_data.json
+}
+
+// JobRequest 创建后台任务请求
+type JobRequest struct {
+	Kind   string          `json:"kind" binding:"required"`
+	Params json.RawMessage `json:"params"`
+}
+
+// RegisterJobs 注册后台任务类型 (在 jobs.Start 之前调用)
+func RegisterJobs() {
+	jobs.Register(JobImport, func(_ context.Context, raw json.RawMessage) (any, error) {
+		var params ImportJobParams
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, err
+			}
+		}
+		return importMap(params.Data, params.DryRun)
+	})
+	jobs.Register(JobReloadGraph, func(context.Context, json.RawMessage) (any, error) {
+		g, err := ReloadGraph()
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"version": g.Version(), "nodes": len(g.Nodes)}, nil
+	})
+	jobs.Register(JobSpeeds, func(context.Context, json.RawMessage) (any, error) {
+		rows, err := speeds.Recompute(config.GetDuration("SPEED_LEARN_WINDOW", 30*24*time.Hour),
+			config.GetInt("SPEED_LEARN_MIN_SAMPLES", 5))
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"edge_speeds": len(rows), "matched": ApplyLearnedSpeeds(rows)}, nil
+	})
+	jobs.Register(JobPopularity, func(context.Context, json.RawMessage) (any, error) {
+		rows, err := popularity.Recompute(config.GetDuration("POPULARITY_WINDOW", 90*24*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"nodes": len(rows), "matched": CurrentGraph().SetPopularity(rows)}, nil
+	})
+	jobs.Register(JobSnapshot, func(context.Context, json.RawMessage) (any, error) {
+		location := config.GetString("GRAPH_SNAPSHOT_PUBLISH", "")
+		if location == "" {
+			return nil, errors.New("未配置快照发布位置 (GRAPH_SNAPSHOT_PUBLISH)")
+		}
+		info, size, err := snapshot.Publish(CurrentGraph(), location)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"snapshot": info, "size": size}, nil
+	})
+}
+
+// CreateJob 创建后台任务 (管理员)，立即返回 202 和任务信息，通过 GET /api/admin/jobs/:id 查询进度
+func CreateJob(c *gin.Context) {
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	var params any
+	if len(req.Params) > 0 {
+		params = req.Params
+	}
+	enqueueJob(c, req.Kind, params)
+}
+
+// enqueueJob 创建任务并写入 202 响应
+func enqueueJob(c *gin.Context, kind string, params any) {
+	job, err := jobs.Enqueue(kind, params, c.GetUint("user_id"))
+	if errors.Is(err, jobs.ErrUnknownKind) {
+		kinds := jobs.Kinds()
+		slices.Sort(kinds)
+		respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "未知的任务类型: "+kind, gin.H{"kinds": kinds})
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "创建任务失败")
+		return
+	}
+	c.Header("Location", APIPrefix+"/admin/jobs/"+strconv.FormatUint(uint64(job.ID), 10))
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// GetJobs 最近的后台任务 (管理员)，可按 ?status=、?kind= 过滤，?limit= 默认 20，最多 100；不含参数和结果
+func GetJobs(c *gin.Context) {
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	query := db.DB.Omit("params", "result").Order("id DESC").Limit(limit)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var list []model.Job
+	if err := query.Find(&list).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(list), "jobs": list})
+}
+
+// GetJobByID 后台任务的状态和结果 (管理员)
+func GetJobByID(c *gin.Context) {
+	var job model.Job
+	if err := db.DB.First(&job, c.Param("id")).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "任务不存在")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
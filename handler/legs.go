@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+	"traffic-system/algo"
+	"traffic-system/i18n"
+	"traffic-system/model"
+)
+
+// RouteLeg 合并后的行程段 (连续使用同一交通方式、同一线路的路段合并为一段)
+type RouteLeg struct {
+	Mode         string        `json:"mode"`              // 交通方式
+	LineID       string        `json:"line_id,omitempty"` // 线路ID (公交/地铁)
+	FromID       string        `json:"from_id"`
+	FromName     string        `json:"from_name"`
+	ToID         string        `json:"to_id"`
+	ToName       string        `json:"to_name"`
+	Distance     float64       `json:"distance"`                // 距离 (米)
+	Time         float64       `json:"time"`                    // 预计时间 (秒)
+	DistanceText string        `json:"distance_text"`           // 格式化的距离，如 "1.2 公里"
+	DurationText string        `json:"duration_text"`           // 格式化的时间，如 "15 分钟"
+	Stops        int           `json:"stops"`                   // 经过的站数 (公交/地铁) 或路段数
+	DelaySeconds int           `json:"delay_seconds,omitempty"` // 线路当前晚点秒数 (实时数据)
+	Realtime     bool          `json:"realtime,omitempty"`      // 等待时间是否按实时车辆位置估算
+	Ascent       float64       `json:"ascent,omitempty"`        // 步行上升的高度 (米，楼梯、扶梯、电梯、天桥)
+	Descent      float64       `json:"descent,omitempty"`       // 步行下降的高度 (米)
+	StairSteps   int           `json:"stair_steps,omitempty"`   // 步行走过的台阶数 (估算)
+	Instruction  string        `json:"instruction"`             // 文字说明，如 "乘坐 METRO_Line_1 经过 2 站"
+	Steps        []PathSegment `json:"steps"`                   // 原始的逐段详情
+}
+
+// buildLegs 把逐边的路径段合并为行程段，文字说明使用指定语言和单位制
+func buildLegs(segments []PathSegment, lang, units string) []RouteLeg {
+	legs := []RouteLeg{}
+	for _, seg := range segments {
+		if n := len(legs); n > 0 && legs[n-1].Mode == seg.UsedMode && legs[n-1].LineID == seg.LineID {
+			leg := &legs[n-1]
+			leg.ToID = seg.ToID
+			leg.ToName = seg.ToName
+			leg.Distance += seg.Distance
+			leg.Time += seg.Time
+			leg.Stops++
+			leg.Steps = append(leg.Steps, seg)
+			continue
+		}
+
+		legs = append(legs, RouteLeg{
+			Mode:         seg.UsedMode,
+			LineID:       seg.LineID,
+			FromID:       seg.FromID,
+			FromName:     seg.FromName,
+			ToID:         seg.ToID,
+			ToName:       seg.ToName,
+			Distance:     seg.Distance,
+			Time:         seg.Time,
+			Stops:        1,
+			DelaySeconds: seg.DelaySeconds,
+			Realtime:     seg.Realtime,
+			Steps:        []PathSegment{seg},
+		})
+	}
+
+	for i := range legs {
+		if legs[i].Mode == "walk" {
+			legVertical(&legs[i])
+		}
+		legs[i].DistanceText = i18n.FormatDistance(lang, units, legs[i].Distance)
+		legs[i].DurationText = i18n.FormatDuration(lang, legs[i].Time)
+		legs[i].Instruction = legInstruction(&legs[i], lang)
+	}
+	return legs
+}
+
+// legVertical 统计步行段的上升、下降高度和台阶数
+func legVertical(leg *RouteLeg) {
+	for _, step := range leg.Steps {
+		ascent, descent, steps := model.Vertical(step.Connector, step.Footway, step.ToLevel-step.FromLevel)
+		leg.Ascent += ascent
+		leg.Descent += descent
+		leg.StairSteps += steps
+	}
+}
+
+// legInstruction 生成行程段的文字说明
+func legInstruction(leg *RouteLeg, lang string) string {
+	switch leg.Mode {
+	case "bus", "subway":
+		line := leg.LineID
+		if line == "" {
+			line = i18n.T(lang, modeLabel(leg.Mode))
+		}
+		return i18n.Sprintf(lang, "在 %s 乘坐 %s 经过 %d 站，到 %s 下车", leg.FromName, line, leg.Stops, leg.ToName)
+	case "walk":
+		s := i18n.Sprintf(lang, "%s %s 到 %s", i18n.T(lang, modeLabel(leg.Mode)), leg.DistanceText, leg.ToName)
+		if via := legFootways(leg, lang); via != "" {
+			s += i18n.Sprintf(lang, "，经过%s", via)
+		}
+		for _, step := range leg.Steps {
+			if step.Connector != "" && step.FromLevel != step.ToLevel {
+				s += i18n.Sprintf(lang, connectorFormat(step.Connector), levelLabel(lang, step.ToLevel))
+			}
+		}
+		return s
+	default:
+		return i18n.Sprintf(lang, "%s %s 到 %s", i18n.T(lang, modeLabel(leg.Mode)), leg.DistanceText, leg.ToName)
+	}
+}
+
+// connectorFormat 经过垂直通道到达某一层的说明格式
+func connectorFormat(connector string) string {
+	switch connector {
+	case model.ConnectorEscalator:
+		return "，乘自动扶梯到%s"
+	case model.ConnectorElevator:
+		return "，乘电梯到%s"
+	default:
+		return "，走楼梯到%s"
+	}
+}
+
+// levelLabel 楼层的名称：0 为地面层，地上按习惯从 2 楼开始，地下为 "地下 1 层" 等
+func levelLabel(lang string, level int) string {
+	switch {
+	case level < 0:
+		return i18n.Sprintf(lang, "地下 %d 层", -level)
+	case level > 0:
+		return i18n.Sprintf(lang, "%d 楼", level+1)
+	default:
+		return i18n.T(lang, "地面层")
+	}
+}
+
+// legFootways 步行段经过的步行设施 (按经过顺序去重)，如 "人行横道、过街天桥"
+func legFootways(leg *RouteLeg, lang string) string {
+	var labels []string
+	for _, step := range leg.Steps {
+		if step.Footway == "" {
+			continue
+		}
+		label := i18n.T(lang, footwayLabel(step.Footway))
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, i18n.T(lang, "、"))
+}
+
+// footwayLabel 步行设施类型的中文名称
+func footwayLabel(footway string) string {
+	switch footway {
+	case model.FootwayCrosswalk:
+		return "人行横道"
+	case model.FootwayFootbridge:
+		return "过街天桥"
+	case model.FootwayUnderpass:
+		return "地下通道"
+	case model.FootwayStationCorridor:
+		return "站内通道"
+	default:
+		return footway
+	}
+}
+
+// modeLabel 交通方式的中文名称
+func modeLabel(mode string) string {
+	switch mode {
+	case "walk":
+		return "步行"
+	case "bike":
+		return "骑行"
+	case "car":
+		return "驾车"
+	case "truck":
+		return "货车"
+	case "bus":
+		return "公交"
+	case "subway":
+		return "地铁"
+	default:
+		return mode
+	}
+}
+
+// Transfer 换乘信息 (两段非步行行程之间的方式/线路切换)
+type Transfer struct {
+	FromMode     string  `json:"from_mode"`
+	FromLineID   string  `json:"from_line_id,omitempty"`
+	ToMode       string  `json:"to_mode"`
+	ToLineID     string  `json:"to_line_id,omitempty"`
+	AlightID     string  `json:"alight_id"` // 下车站点
+	AlightName   string  `json:"alight_name"`
+	BoardID      string  `json:"board_id"` // 上车站点 (换乘地点)
+	BoardName    string  `json:"board_name"`
+	Lat          float64 `json:"lat"`
+	Lng          float64 `json:"lng"`
+	WalkDistance float64 `json:"walk_distance"` // 两个站台之间的步行距离 (米)
+	WalkTime     float64 `json:"walk_time"`     // 步行时间 (秒)
+	WaitTime     float64 `json:"wait_time"`     // 预计等待/准备时间 (秒)
+}
+
+// buildTransfers 从行程段中找出换乘
+// 步行段不算换乘，只记录为换乘时的站间步行；第一次上车不算换乘
+func buildTransfers(g *algo.Graph, legs []RouteLeg) []Transfer {
+	transfers := []Transfer{}
+	var last *RouteLeg // 上一段非步行行程
+	walkDist, walkTime := 0.0, 0.0
+
+	for i := range legs {
+		leg := &legs[i]
+		if leg.Mode == "walk" {
+			walkDist += leg.Distance
+			walkTime += leg.Time
+			continue
+		}
+
+		if last != nil {
+			t := Transfer{
+				FromMode:     last.Mode,
+				FromLineID:   last.LineID,
+				ToMode:       leg.Mode,
+				ToLineID:     leg.LineID,
+				AlightID:     last.ToID,
+				AlightName:   last.ToName,
+				BoardID:      leg.FromID,
+				BoardName:    leg.FromName,
+				WalkDistance: walkDist,
+				WalkTime:     walkTime,
+				WaitTime:     model.GetModeWaitTime(leg.Mode),
+			}
+			if leg.Realtime {
+				t.WaitTime = leg.Steps[0].WaitTime
+			}
+			if node := g.Nodes[leg.FromID]; node != nil {
+				t.Lat, t.Lng = node.Lat, node.Lng
+			}
+			transfers = append(transfers, t)
+		}
+
+		last = leg
+		walkDist, walkTime = 0, 0
+	}
+	return transfers
+}
@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LineInfo 线路概要信息
+type LineInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Mode      string `json:"mode"`
+	Color     string `json:"color,omitempty"`
+	Headway   int    `json:"headway"`    // 发车间隔 (秒)
+	FirstTime string `json:"first_time"` // 首班车时间
+	LastTime  string `json:"last_time"`  // 末班车时间
+	StopCount int    `json:"stop_count"`
+	FromName  string `json:"from_name,omitempty"` // 始发站
+	ToName    string `json:"to_name,omitempty"`   // 终点站
+}
+
+// LineStopInfo 线路站点信息
+type LineStopInfo struct {
+	Seq  int     `json:"seq"`
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+// LineDetail 线路详情 (包含按顺序排列的站点)
+type LineDetail struct {
+	LineInfo
+	Stops []LineStopInfo `json:"stops"`
+}
+
+// GetLines 获取所有线路，可按交通方式过滤 (?mode=bus)
+func GetLines(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	mode := c.Query("mode")
+	lang := language(c)
+	lines := make([]LineInfo, 0, len(g.Lines))
+	for _, line := range g.LineList() {
+		if mode != "" && line.Mode != mode {
+			continue
+		}
+		lines = append(lines, buildLineInfo(g, line, lang))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(lines),
+		"lines": lines,
+	})
+}
+
+// GetLineByID 获取线路详情
+func GetLineByID(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	line := g.Lines[c.Param("id")]
+	if line == nil {
+		respondError(c, http.StatusNotFound, CodeLineNotFound, "线路不存在")
+		return
+	}
+
+	lang := language(c)
+	stops := make([]LineStopInfo, 0, len(line.Stops))
+	for _, stop := range line.Stops {
+		info := LineStopInfo{Seq: stop.Seq, ID: stop.NodeID, Name: stop.NodeID}
+		if node := g.Nodes[stop.NodeID]; node != nil {
+			info.Name = localName(lang, node)
+			info.Lat = node.Lat
+			info.Lng = node.Lng
+		}
+		stops = append(stops, info)
+	}
+
+	c.JSON(http.StatusOK, LineDetail{
+		LineInfo: buildLineInfo(g, line, lang),
+		Stops:    stops,
+	})
+}
+
+// GetNodeLines 获取经过指定节点的线路
+func GetNodeLines(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	nodeID := c.Param("id")
+	if g.Nodes[nodeID] == nil {
+		respondError(c, http.StatusNotFound, CodeNodeNotFound, "节点不存在")
+		return
+	}
+
+	lang := language(c)
+	lines := make([]LineInfo, 0)
+	for _, lineID := range g.NodeLines[nodeID] {
+		if line := g.Lines[lineID]; line != nil {
+			lines = append(lines, buildLineInfo(g, line, lang))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_id": nodeID,
+		"count":   len(lines),
+		"lines":   lines,
+	})
+}
+
+// 到站查询返回的班次数量
+const (
+	defaultDepartureCount = 3
+	maxDepartureCount     = 10
+)
+
+// Departure 某线路的一个到站班次
+type Departure struct {
+	Time        time.Time `json:"time"`         // 预计到站时间
+	WaitSeconds int       `json:"wait_seconds"` // 距查询时间的秒数
+	WaitMinutes int       `json:"wait_minutes"` // 距查询时间的分钟数 (向上取整，用于 "3 分钟后到站")
+}
+
+// LineDepartures 某线路在站点的后续班次
+type LineDepartures struct {
+	LineID     string      `json:"line_id"`
+	LineName   string      `json:"line_name"`
+	Mode       string      `json:"mode"`
+	Headway    int         `json:"headway"`
+	ToName     string      `json:"to_name,omitempty"` // 开往方向 (终点站)
+	Departures []Departure `json:"departures"`
+}
+
+// GetStopDepartures 获取站点各线路的后续到站班次
+// 可选参数: at (RFC3339 或 "HH:MM"，默认当前时间)，limit (每条线路返回的班次数)
+func GetStopDepartures(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	stopID := c.Param("id")
+	stop := g.Nodes[stopID]
+	if stop == nil {
+		respondError(c, http.StatusNotFound, CodeNodeNotFound, "站点不存在")
+		return
+	}
+
+	at, err := parseQueryTime(c.Query("at"), time.Now())
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "时间格式错误，应为 RFC3339 或 HH:MM")
+		return
+	}
+
+	limit := defaultDepartureCount
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxDepartureCount {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 10)")
+			return
+		}
+	}
+
+	lang := language(c)
+	result := make([]LineDepartures, 0)
+	for _, lineID := range g.NodeLines[stopID] {
+		line := g.Lines[lineID]
+		if line == nil {
+			continue
+		}
+		offset, ok := g.StopOffset(lineID, stopID)
+		if !ok {
+			continue
+		}
+
+		info := buildLineInfo(g, line, lang)
+		item := LineDepartures{
+			LineID:     line.ID,
+			LineName:   line.Name,
+			Mode:       line.Mode,
+			Headway:    line.Headway,
+			ToName:     info.ToName,
+			Departures: make([]Departure, 0, limit),
+		}
+		for _, t := range line.NextDepartures(at, time.Duration(offset*float64(time.Second)), limit) {
+			wait := t.Sub(at)
+			item.Departures = append(item.Departures, Departure{
+				Time:        t,
+				WaitSeconds: int(wait.Seconds()),
+				WaitMinutes: int((wait + time.Minute - 1) / time.Minute),
+			})
+		}
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stop_id":   stop.ID,
+		"stop_name": localName(lang, stop),
+		"at":        at,
+		"lines":     result,
+	})
+}
+
+// parseQueryTime 解析查询参数中的时间 (RFC3339 或当天的 "HH:MM")，为空时返回 def
+func parseQueryTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	minutes, err := model.ParseClock(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	midnight := time.Date(def.Year(), def.Month(), def.Day(), 0, 0, 0, 0, def.Location())
+	return midnight.Add(time.Duration(minutes) * time.Minute), nil
+}
+
+// buildLineInfo 构建线路概要 (始发站、终点站使用指定语言的节点名称)
+func buildLineInfo(g *algo.Graph, line *model.Line, lang string) LineInfo {
+	info := LineInfo{
+		ID:        line.ID,
+		Name:      line.Name,
+		Mode:      line.Mode,
+		Color:     line.Color,
+		Headway:   line.Headway,
+		FirstTime: line.FirstTime,
+		LastTime:  line.LastTime,
+		StopCount: len(line.Stops),
+	}
+	if len(line.Stops) > 0 {
+		info.FromName = nodeName(g, line.Stops[0].NodeID, lang)
+		info.ToName = nodeName(g, line.Stops[len(line.Stops)-1].NodeID, lang)
+	}
+	return info
+}
+
+// nodeName 节点在指定语言下的名称 (节点不存在时返回 ID)
+func nodeName(g *algo.Graph, id, lang string) string {
+	if node := g.Nodes[id]; node != nil {
+		return localName(lang, node)
+	}
+	return id
+}
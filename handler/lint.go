@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/db"
+	"traffic-system/lint"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LintMap 按当前规则 (LINT_RULES) 检查数据库中的全部节点和边 (管理员)
+func LintMap(c *gin.Context) {
+	var nodes []model.Node
+	if err := db.DB.Find(&nodes).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	var edges []model.Edge
+	if err := db.DB.Find(&edges).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, lint.Current.Check(nodes, edges))
+}
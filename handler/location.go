@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"traffic-system/i18n"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 周边概况的查询参数
+const (
+	defaultSummaryRadius = 500.0  // 默认半径 (米)
+	maxSummaryRadius     = 2000.0 // 最大半径 (米)
+	defaultSummaryLimit  = 5      // 每类默认返回数量
+	maxSummaryLimit      = 20     // 每类最多返回数量
+)
+
+// NearbyPlace 周边的一个地点
+type NearbyPlace struct {
+	PathNode
+	Distance     float64 `json:"distance"`           // 直线距离 (米)
+	DistanceText string  `json:"distance_text"`      // 格式化的距离
+	Capacity     int     `json:"capacity,omitempty"` // 车位数 / 桩位数
+	Price        float64 `json:"price,omitempty"`    // 停车收费 (元/小时)
+}
+
+// NearbyStop 周边的地铁口或公交站，附带经过的线路
+type NearbyStop struct {
+	NearbyPlace
+	Lines []LineInfo `json:"lines"`
+}
+
+// GetLocationSummary 某个位置周边的出行概况：最近的地铁口、公交站 (含线路)、共享单车停放点和停车场
+// 参数: lat、lng，radius (默认 500 米，最大 2000 米)，limit (每类返回数量，默认 5，最多 20)，units (距离单位制)
+func GetLocationSummary(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil {
+		respondError(c, http.StatusBadRequest, CodeCoordinateInvalid, "缺少或无效的坐标 (lat, lng)")
+		return
+	}
+	if !checkCoordinates(c, model.Point{Lat: lat, Lng: lng}) {
+		return
+	}
+
+	radius := defaultSummaryRadius
+	if s := c.Query("radius"); s != "" {
+		r, err := strconv.ParseFloat(s, 64)
+		if err != nil || r <= 0 || r > maxSummaryRadius {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "查询半径超出范围 (0 ~ 2000 米)")
+			return
+		}
+		radius = r
+	}
+
+	limit := defaultSummaryLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxSummaryLimit {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 20)")
+			return
+		}
+		limit = n
+	}
+
+	units := c.Query("units")
+	if !i18n.ValidUnits(units) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的单位制，应为 metric 或 imperial")
+		return
+	}
+
+	lang := language(c)
+	places := func(nodeType string) []NearbyPlace {
+		nearby := g.NodesNear(lat, lng, radius, nodeType)
+		if len(nearby) > limit {
+			nearby = nearby[:limit]
+		}
+		result := make([]NearbyPlace, 0, len(nearby))
+		for _, n := range nearby {
+			result = append(result, NearbyPlace{
+				PathNode:     buildPathNode(n.Node, lang),
+				Distance:     n.Distance,
+				DistanceText: i18n.FormatDistance(lang, units, n.Distance),
+				Capacity:     n.Node.Capacity,
+				Price:        n.Node.Price,
+			})
+		}
+		return result
+	}
+	stops := func(nodeType string) []NearbyStop {
+		result := make([]NearbyStop, 0)
+		for _, place := range places(nodeType) {
+			lines := make([]LineInfo, 0)
+			for _, lineID := range g.NodeLines[place.ID] {
+				if line := g.Lines[lineID]; line != nil {
+					lines = append(lines, buildLineInfo(g, line, lang))
+				}
+			}
+			result = append(result, NearbyStop{NearbyPlace: place, Lines: lines})
+		}
+		return result
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"location":   model.Point{Lat: lat, Lng: lng},
+		"radius":     radius,
+		"subway":     stops("subway_entrance"),
+		"bus":        stops("bus_stop"),
+		"bike_docks": places(model.NodeTypeBikeDock),
+		"parking":    places(model.NodeTypeParking),
+	})
+}
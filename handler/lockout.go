@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/captcha"
+	"traffic-system/config"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lockoutPolicy 登录失败的锁定策略 (环境变量)
+type lockoutPolicy struct {
+	threshold      int           // 同一账号连续失败多少次后开始锁定
+	ipThreshold    int           // 同一 IP 失败多少次后开始锁定 (撞库时会尝试大量不同账号)
+	base           time.Duration // 第一次锁定的时长，之后每失败一次翻倍
+	max            time.Duration // 锁定时长上限
+	window         time.Duration // 失败次数的统计窗口，窗口内没有登录成功则一直累计
+	captchaAfter   int           // 同一账号失败多少次后要求人机验证 (需配置 CAPTCHA_SECRET)，0 表示不要求
+	ipCaptchaAfter int           // 同一 IP 失败多少次后要求人机验证，0 表示不要求
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		threshold:      config.GetInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		ipThreshold:    config.GetInt("LOGIN_IP_LOCKOUT_THRESHOLD", 20),
+		base:           config.GetDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		max:            config.GetDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		window:         config.GetDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		captchaAfter:   config.GetInt("LOGIN_CAPTCHA_AFTER", 3),
+		ipCaptchaAfter: config.GetInt("LOGIN_IP_CAPTCHA_AFTER", 10),
+	}
+}
+
+// limits kind ("user" 或 "ip") 对应的锁定阈值和人机验证阈值
+func (p lockoutPolicy) limits(kind string) (lockAfter, captchaAfter int) {
+	if kind == "ip" {
+		return p.ipThreshold, p.ipCaptchaAfter
+	}
+	return p.threshold, p.captchaAfter
+}
+
+// lockDuration 第 failures 次失败后的锁定时长：达到 threshold 时为 base，之后每次翻倍，不超过 max
+func (p lockoutPolicy) lockDuration(failures int64, threshold int) time.Duration {
+	if threshold <= 0 || failures < int64(threshold) {
+		return 0
+	}
+	lock := p.base
+	for i := int64(threshold); i < failures && lock < p.max; i++ {
+		lock *= 2
+	}
+	return min(lock, p.max)
+}
+
+// loginGuard 一次登录请求的失败计数和锁定状态
+// 按提交的用户名 (不论账号是否存在，避免泄露哪些用户名已注册) 和客户端 IP 分别计数，保存在共享缓存中
+type loginGuard struct {
+	policy  lockoutPolicy
+	account string // 用户名摘要
+	ip      string
+}
+
+func newLoginGuard(c *gin.Context, username string) *loginGuard {
+	return &loginGuard{
+		policy:  loadLockoutPolicy(),
+		account: utils.HashToken(username)[:16],
+		ip:      c.ClientIP(),
+	}
+}
+
+func (g *loginGuard) failKey(kind string) string {
+	if kind == "ip" {
+		return "login-fail:ip:" + g.ip
+	}
+	return "login-fail:user:" + g.account
+}
+
+func (g *loginGuard) lockKey(kind string) string {
+	if kind == "ip" {
+		return "login-lock:ip:" + g.ip
+	}
+	return "login-lock:user:" + g.account
+}
+
+// allow 检查是否允许这次登录尝试：账号或 IP 被锁定时返回 429；失败次数较多且启用了人机验证时校验 captchaToken
+// 不允许时已写入错误响应。缓存出错时放行 (与限流相同)
+func (g *loginGuard) allow(c *gin.Context, captchaToken string) bool {
+	ctx := c.Request.Context()
+	for _, kind := range []string{"user", "ip"} {
+		until, err := readInt(ctx, g.lockKey(kind))
+		if err != nil {
+			slog.Error("读取登录锁定状态失败", "error", err)
+			return true
+		}
+		if wait := time.Until(time.Unix(until, 0)); wait > 0 {
+			retry := int(wait.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retry))
+			respondErrorDetails(c, http.StatusTooManyRequests, CodeLoginLocked, "登录失败次数过多，请稍后再试", gin.H{"retry_after": retry})
+			return false
+		}
+	}
+
+	if !g.captchaRequired(ctx) {
+		return true
+	}
+	err := captcha.Verify(ctx, captchaToken, g.ip)
+	if errors.Is(err, captcha.ErrInvalid) {
+		respondErrorDetails(c, http.StatusUnauthorized, CodeCaptchaRequired, "请完成人机验证", gin.H{"captcha_required": true})
+		return false
+	}
+	if err != nil {
+		slog.Error("人机验证失败", "error", err)
+		respondError(c, http.StatusBadGateway, CodeUpstreamError, "人机验证服务暂时不可用")
+		return false
+	}
+	return true
+}
+
+// captchaRequired 账号或 IP 的失败次数是否已达到需要人机验证的次数
+func (g *loginGuard) captchaRequired(ctx context.Context) bool {
+	if !captcha.Enabled() {
+		return false
+	}
+	for _, kind := range []string{"user", "ip"} {
+		_, after := g.policy.limits(kind)
+		if after <= 0 {
+			continue
+		}
+		failures, err := readInt(ctx, g.failKey(kind))
+		if err != nil {
+			slog.Error("读取登录失败次数失败", "error", err)
+			return false
+		}
+		if failures >= int64(after) {
+			return true
+		}
+	}
+	return false
+}
+
+// fail 记录一次失败，达到阈值时锁定账号或 IP；返回附加在错误响应中的信息 (下次是否需要人机验证、锁定时长)
+func (g *loginGuard) fail(c *gin.Context) any {
+	ctx := c.Request.Context()
+	captchaNext, locked := false, time.Duration(0)
+	for _, kind := range []string{"user", "ip"} {
+		failures, err := cache.Default.Incr(ctx, g.failKey(kind), g.policy.window)
+		if err != nil {
+			slog.Error("记录登录失败次数失败", "error", err)
+			return nil
+		}
+		lockAfter, captchaAfter := g.policy.limits(kind)
+		if captcha.Enabled() && captchaAfter > 0 && failures >= int64(captchaAfter) {
+			captchaNext = true
+		}
+
+		lock := g.policy.lockDuration(failures, lockAfter)
+		if lock <= 0 {
+			continue
+		}
+		until := strconv.FormatInt(time.Now().Add(lock).Unix(), 10)
+		if err := cache.Default.Set(ctx, g.lockKey(kind), []byte(until), lock); err != nil {
+			slog.Error("写入登录锁定状态失败", "error", err)
+			continue
+		}
+		locked = max(locked, lock)
+	}
+
+	switch {
+	case locked > 0:
+		return gin.H{"retry_after": int(locked.Seconds())}
+	case captchaNext:
+		return gin.H{"captcha_required": true}
+	}
+	return nil
+}
+
+// succeed 登录成功后清除账号的失败次数 (IP 的计数保留，避免撞库时用一个有效账号清零)
+func (g *loginGuard) succeed(c *gin.Context) {
+	if err := cache.Default.Delete(c.Request.Context(), g.failKey("user")); err != nil {
+		slog.Error("清除登录失败次数失败", "error", err)
+	}
+}
+
+// readInt 读取缓存中的整数，不存在时为 0
+func readInt(ctx context.Context, key string) (int64, error) {
+	value, ok, err := cache.Default.Get(ctx, key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	n, _ := strconv.ParseInt(string(value), 10, 64)
+	return n, nil
+}
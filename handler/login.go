@@ -2,19 +2,23 @@ package handler
 
 import (
 	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 	"traffic-system/db"
+	"traffic-system/jwtkeys"
 	"traffic-system/model"
 	"traffic-system/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"gorm.io/gorm"
 )
 
-// JWT 密钥 (生产环境应从环境变量读取)
-var jwtSecret = []byte("your-secret-key-change-in-production")
+// tokenTTL 登录 Token 的有效期
+const tokenTTL = 24 * time.Hour
+
+// errTokenRevoked Token 已注销
+var errTokenRevoked = errors.New("Token 已注销")
 
 // Claims JWT 载荷
 type Claims struct {
@@ -25,8 +29,9 @@ type Claims struct {
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	CaptchaToken string `json:"captcha_token"` // 失败次数较多时需要 (启用人机验证时)
 }
 
 // LoginResponse 登录响应
@@ -40,50 +45,39 @@ type LoginResponse struct {
 func Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
 		return
 	}
 
-	// 1. 从数据库查找用户
-	var user model.User
-	// 使用 Where 查询，First 获取第一条记录
-	if err := db.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库查询出错"})
-		}
+	// 1. 检查失败次数：账号或 IP 被锁定时拒绝，失败较多时要求人机验证
+	guard := newLoginGuard(c, req.Username)
+	if !guard.allow(c, req.CaptchaToken) {
 		return
 	}
 
-	// 2. 验证密码
-	if !utils.CheckPassword(user.Password, req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+	// 2. 从存储查找用户并验证密码 (用户不存在和密码错误同样计为失败)
+	user, err := db.Repo.UserByUsername(req.Username)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
 		return
 	}
-
-	// 3. 生成 JWT Token
-	claims := &Claims{
-		UserID:   user.ID, // 使用数据库生成的 ID (uint)
-		Username: user.Username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "traffic-system",
-		},
+	if err != nil || !utils.CheckPassword(user.Password, req.Password) {
+		respondErrorDetails(c, http.StatusUnauthorized, CodeInvalidCredentials, "用户名或密码错误", guard.fail(c))
+		return
 	}
+	guard.succeed(c)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	// 3. 生成 JWT Token
+	tokenString, err := generateToken(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成 Token 失败"})
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成 Token 失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, LoginResponse{
 		Token:    tokenString,
 		Username: user.Username,
-		Message:  "登录成功",
+		Message:  tr(c, "登录成功"),
 	})
 }
 
@@ -92,26 +86,25 @@ func Register(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required,min=6"`
-		Email    string `json:"email"`
+		Email    string `json:"email" binding:"omitempty,email"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
 		return
 	}
 
 	// 1. 检查用户是否已存在
-	var existingUser model.User
 	// 如果能查到记录，说明用户已存在
-	if err := db.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+	if _, err := db.Repo.UserByUsername(req.Username); err == nil {
+		respondError(c, http.StatusConflict, CodeConflict, "用户名已存在")
 		return
 	}
 
 	// 2. 加密密码
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "密码加密失败")
 		return
 	}
 
@@ -123,49 +116,143 @@ func Register(c *gin.Context) {
 		Email:    req.Email,
 	}
 
-	// 插入数据库
-	if err := db.DB.Create(&newUser).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "注册用户失败"})
+	// 写入存储
+	if err := db.Repo.CreateUser(&newUser); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "注册用户失败")
 		return
 	}
 
+	// 4. 填写了邮箱则发送验证邮件 (发送失败不影响注册结果；无数据库模式下无法保存验证令牌，不发送)
+	if newUser.Email != "" && db.Enabled() {
+		if err := sendVerificationEmail(&newUser); err != nil {
+			slog.Error("发送验证邮件失败", "error", err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message":  "注册成功",
+		"message":  tr(c, "注册成功"),
 		"username": newUser.Username,
 		"id":       newUser.ID, // 返回生成的数据库 ID
 	})
 }
 
 // AuthMiddleware JWT 认证中间件
+// API Key 不代表任何用户，使用 API Key 访问用户接口时返回 403
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供 Token"})
-			c.Abort()
+		if currentAPIKey(c) != nil {
+			respondError(c, http.StatusForbidden, CodeForbidden, "API Key 不能访问用户接口")
 			return
 		}
-
-		// 移除 "Bearer " 前缀
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
+		if !authenticateUser(c) {
+			return
 		}
+		c.Next()
+	}
+}
 
-		// 解析 Token
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 Token"})
-			c.Abort()
+// AdminMiddleware 管理员权限校验 (需放在 AuthMiddleware 之后)
+// 角色从数据库读取，撤销管理员后立即生效
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdminRole(c) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		// 将用户信息存入上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
+// AdminAuthMiddleware 管理员接口认证：带 admin 权限的 API Key，或管理员用户的 Token
+func AdminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := currentAPIKey(c); key != nil {
+			if !key.HasScope(model.ScopeAdmin) {
+				respondErrorDetails(c, http.StatusForbidden, CodeForbidden, "API Key 没有该接口的权限", gin.H{"scope": model.ScopeAdmin})
+				return
+			}
+			c.Next()
+			return
+		}
+		if !authenticateUser(c) || !requireAdminRole(c) {
+			return
+		}
 		c.Next()
 	}
 }
+
+// authenticateUser 校验 Token 并把用户信息存入上下文，失败时写入错误响应
+func authenticateUser(c *gin.Context) bool {
+	tokenString := extractToken(c)
+	if tokenString == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未提供 Token")
+		return false
+	}
+
+	// 解析 Token (已注销的 Token 视为无效)
+	claims, err := authenticate(c.Request.Context(), tokenString)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的 Token")
+		return false
+	}
+
+	// 将用户信息存入上下文
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	return true
+}
+
+// requireAdminRole 检查当前用户是否是管理员，不是时写入错误响应
+func requireAdminRole(c *gin.Context) bool {
+	user, err := db.Repo.UserByID(c.GetUint("user_id"))
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "用户不存在")
+		return false
+	}
+	if user.Role != model.RoleAdmin {
+		respondError(c, http.StatusForbidden, CodeForbidden, "需要管理员权限")
+		return false
+	}
+	return true
+}
+
+// generateToken 为用户签发 JWT Token (密码登录和第三方登录共用)
+func generateToken(user *model.User) (string, error) {
+	claims := &Claims{
+		UserID:   user.ID, // 使用数据库生成的 ID (uint)
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "traffic-system",
+		},
+	}
+
+	return jwtkeys.Default.Sign(claims)
+}
+
+// extractToken 从 Authorization 请求头中取出 Token (移除 "Bearer " 前缀)
+func extractToken(c *gin.Context) string {
+	tokenString := c.GetHeader("Authorization")
+	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+		tokenString = tokenString[7:]
+	}
+	return tokenString
+}
+
+// GetJWKS 公开 RS256 公钥 (JWK Set)，其他服务可以据此校验本服务签发的 Token；使用 HS256 时为空
+func GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": jwtkeys.Default.JWKS()})
+}
+
+// parseToken 解析并校验 JWT Token (按头部的 kid 选择密钥)
+func parseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, jwtkeys.Default.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("无效的 Token")
+	}
+	return claims, nil
+}
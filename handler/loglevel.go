@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"traffic-system/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLogLevelDuration 临时日志级别的最长持续时间
+const maxLogLevelDuration = 24 * time.Hour
+
+// LogLevelRequest 修改日志级别的请求
+type LogLevelRequest struct {
+	Level    string `json:"level" binding:"required"` // debug、info、warn、error
+	Duration string `json:"duration"`                 // 如 15m，到期后恢复原来的级别；为空表示一直生效 (直到重启)
+}
+
+// GetLogLevel 当前的日志级别 (管理员)
+func GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevelResponse())
+}
+
+// SetLogLevel 修改本实例的日志级别 (管理员)，可以指定持续时间，到期后自动恢复
+func SetLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "日志级别无效 (可选 debug、info、warn、error)")
+		return
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "持续时间格式错误 (如 15m、1h)")
+			return
+		}
+		if d > maxLogLevelDuration {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "持续时间超出范围 (最长 24 小时)")
+			return
+		}
+	}
+
+	logging.SetLevel(level, d)
+	slog.Warn("日志级别已修改", "level", strings.ToLower(level.String()), "duration", d, "user_id", c.GetUint("user_id"))
+	c.JSON(http.StatusOK, logLevelResponse())
+}
+
+// logLevelResponse 当前级别和临时级别的到期时间
+func logLevelResponse() gin.H {
+	level, until := logging.Level()
+	resp := gin.H{"level": strings.ToLower(level.String())}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	return resp
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"traffic-system/algo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMapExtent 获取地图范围 (包围盒、中心点、凸包)、节点/边数量和地图版本
+// 前端可以用来自动居中和限制拖动范围
+func GetMapExtent(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	c.JSON(http.StatusOK, g.Extent())
+}
+
+// GetTile 获取与瓦片 z/x/y 相交的节点和边 (坐标按缩放级别简化)
+// y 可以带 .json 后缀，便于直接作为 Leaflet 图层 URL 模板使用
+func GetTile(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	z, errZ := strconv.Atoi(c.Param("z"))
+	x, errX := strconv.Atoi(c.Param("x"))
+	y, errY := strconv.Atoi(strings.TrimSuffix(c.Param("y"), ".json"))
+	if errZ != nil || errX != nil || errY != nil || !algo.ValidTile(z, x, y) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的瓦片坐标")
+		return
+	}
+
+	c.JSON(http.StatusOK, g.Tile(z, x, y))
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/monitor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 路线监控的限制
+const (
+	maxMonitorsPerUser      = 20
+	defaultMonitorThreshold = 0.2
+)
+
+// MonitorRequest 创建路线监控请求
+type MonitorRequest struct {
+	Name        string   `json:"name"`
+	StartID     string   `json:"start_id" binding:"required"`
+	EndID       string   `json:"end_id" binding:"required"`
+	Modes       []string `json:"modes"`                                    // 为空时使用用户偏好
+	WindowStart string   `json:"window_start" binding:"required"`          // 如 "07:30"
+	WindowEnd   string   `json:"window_end" binding:"required"`            // 如 "09:00"
+	Threshold   float64  `json:"threshold" binding:"omitempty,gt=0,lte=5"` // 默认 0.2 (变差 20% 时通知)
+	NotifyURL   string   `json:"notify_url" binding:"omitempty,url"`
+}
+
+// CreateMonitor 创建路线监控
+func CreateMonitor(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var req MonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if g.Nodes[req.StartID] == nil || g.Nodes[req.EndID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "起点或终点不存在")
+		return
+	}
+	if _, err := model.ParseClock(req.WindowStart); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "时间窗口格式错误，应为 HH:MM")
+		return
+	}
+	if _, err := model.ParseClock(req.WindowEnd); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "时间窗口格式错误，应为 HH:MM")
+		return
+	}
+	if req.NotifyURL != "" {
+		if u, err := url.Parse(req.NotifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "回调地址必须是 http 或 https")
+			return
+		}
+	}
+
+	if len(req.Modes) == 0 {
+		if profile, err := loadUserProfile(c.Request.Context(), userID); err == nil {
+			req.Modes = profile.DefaultModes
+		}
+	}
+	if model.ParseModes(req.Modes) == 0 {
+		respondError(c, http.StatusBadRequest, CodeModeInvalid, "未指定有效的交通方式")
+		return
+	}
+
+	var count int64
+	db.DB.Model(&model.RouteMonitor{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxMonitorsPerUser {
+		respondError(c, http.StatusConflict, CodeConflict, "路线监控数量已达上限")
+		return
+	}
+
+	m := model.RouteMonitor{
+		UserID:      userID,
+		Name:        req.Name,
+		StartID:     req.StartID,
+		EndID:       req.EndID,
+		Modes:       req.Modes,
+		WindowStart: req.WindowStart,
+		WindowEnd:   req.WindowEnd,
+		Threshold:   req.Threshold,
+		NotifyURL:   req.NotifyURL,
+		Active:      true,
+	}
+	if m.Threshold == 0 {
+		m.Threshold = defaultMonitorThreshold
+	}
+
+	// 基准时间不考虑高峰和实时路况
+	baseline, found := monitor.Evaluate(g, &m, time.Time{})
+	if !found {
+		respondError(c, http.StatusUnprocessableEntity, CodeRouteNotFound, "未找到符合条件的路径，无法监控")
+		return
+	}
+	m.BaselineTime = baseline
+	m.LastTime = baseline
+
+	if err := db.DB.Create(&m).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存路线监控失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, m)
+}
+
+// GetMonitors 获取当前用户的路线监控
+func GetMonitors(c *gin.Context) {
+	var monitors []model.RouteMonitor
+	if err := db.DB.Where("user_id = ?", c.GetUint("user_id")).Order("id").Find(&monitors).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(monitors),
+		"monitors": monitors,
+	})
+}
+
+// DeleteMonitor 删除路线监控
+func DeleteMonitor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的监控 ID")
+		return
+	}
+
+	result := db.DB.Where("id = ? AND user_id = ?", id, c.GetUint("user_id")).Delete(&model.RouteMonitor{})
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除路线监控失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "路线监控不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "路线监控已删除")})
+}
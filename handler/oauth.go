@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/oauth"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OAuthLoginRequest 第三方登录请求
+type OAuthLoginRequest struct {
+	Provider    string `json:"provider" binding:"required"` // "wechat", "github", "google"
+	Code        string `json:"code" binding:"required"`     // 前端授权回调拿到的授权码
+	RedirectURI string `json:"redirect_uri"`                // 授权时使用的回调地址 (部分平台校验)
+}
+
+// OAuthLogin 第三方登录
+// 1. 已关联的第三方身份: 直接登录对应的本地账号
+// 2. 请求携带了有效 Token: 把第三方身份关联到当前登录的账号
+// 3. 其他情况: 自动创建一个本地账号并关联
+// 最终都签发与密码登录相同的 JWT
+func OAuthLogin(c *gin.Context) {
+	var req OAuthLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
+		return
+	}
+
+	provider, ok := oauth.Get(req.Provider)
+	if !ok {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "不支持的登录方式: "+req.Provider)
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), req.Code, req.RedirectURI)
+	if err != nil {
+		respondErrorDetails(c, http.StatusUnauthorized, CodeUnauthorized, "第三方授权失败", err.Error())
+		return
+	}
+
+	// 如果携带了 Token，则视为关联到当前账号
+	var currentUserID uint
+	if tokenString := extractToken(c); tokenString != "" {
+		if claims, err := parseToken(tokenString); err == nil {
+			currentUserID = claims.UserID
+		}
+	}
+
+	user, err := findOrCreateOAuthUser(identity, currentUserID)
+	if err != nil {
+		if errors.Is(err, errIdentityLinked) {
+			respondError(c, http.StatusConflict, CodeConflict, "该第三方账号已关联其他用户")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "第三方登录失败")
+		return
+	}
+
+	tokenString, err := generateToken(user)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成 Token 失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:    tokenString,
+		Username: user.Username,
+		Message:  tr(c, "登录成功"),
+	})
+}
+
+// GetOAuthProviders 获取已启用的第三方登录方式
+func GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oauth.Names()})
+}
+
+// errIdentityLinked 第三方身份已关联到其他本地账号
+var errIdentityLinked = errors.New("第三方身份已关联其他用户")
+
+// findOrCreateOAuthUser 根据第三方身份查找、关联或创建本地用户
+func findOrCreateOAuthUser(identity *oauth.Identity, currentUserID uint) (*model.User, error) {
+	var user model.User
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 1. 查找已有的关联
+		var link model.UserIdentity
+		err := tx.Where("provider = ? AND provider_user_id = ?", identity.Provider, identity.ProviderUserID).
+			First(&link).Error
+		if err == nil {
+			if currentUserID != 0 && currentUserID != link.UserID {
+				return errIdentityLinked
+			}
+			return tx.First(&user, link.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 2. 关联到当前登录的账号，或创建新账号
+		if currentUserID != 0 {
+			if err := tx.First(&user, currentUserID).Error; err != nil {
+				return err
+			}
+		} else {
+			// 第三方账号没有本地密码，存一个随机密码的哈希占位
+			randomPassword, err := utils.GenerateToken(16)
+			if err != nil {
+				return err
+			}
+			hashedPassword, err := utils.HashPassword(randomPassword)
+			if err != nil {
+				return err
+			}
+			user = model.User{
+				Username:      fmt.Sprintf("%s_%s", identity.Provider, identity.ProviderUserID),
+				Password:      hashedPassword,
+				Email:         identity.Email,
+				EmailVerified: identity.EmailVerified,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&model.UserIdentity{
+			UserID:         user.ID,
+			Provider:       identity.Provider,
+			ProviderUserID: identity.ProviderUserID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
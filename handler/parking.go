@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"traffic-system/algo"
+	"traffic-system/i18n"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 停车场查询半径上限 (米)
+const maxParkingRadius = 5000.0
+
+// ParkingInfo 停车场信息
+type ParkingInfo struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Lat      float64 `json:"lat"`
+	Lng      float64 `json:"lng"`
+	Capacity int     `json:"capacity"`
+	Price    float64 `json:"price"`              // 收费 (元/小时)
+	Distance float64 `json:"distance,omitempty"` // 到查询位置的直线距离 (米)
+
+	DistanceText string `json:"distance_text,omitempty"` // 格式化的距离 (按语言和 ?units= 单位制)
+}
+
+// GetParking 获取停车场列表
+// 传入 ?lat=&lng= 时只返回 radius (默认 1000 米) 内的停车场，按距离排序
+func GetParking(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	near := errLat == nil && errLng == nil
+	if near && !checkCoordinates(c, model.Point{Lat: lat, Lng: lng}) {
+		return
+	}
+
+	radius := 0.0
+	if near {
+		radius = algo.DefaultParkingRadius
+		if s := c.Query("radius"); s != "" {
+			r, err := strconv.ParseFloat(s, 64)
+			if err != nil || r <= 0 || r > maxParkingRadius {
+				respondError(c, http.StatusBadRequest, CodeOutOfRange, "查询半径超出范围 (0 ~ 5000 米)")
+				return
+			}
+			radius = r
+		}
+	}
+
+	units := c.Query("units")
+	if !i18n.ValidUnits(units) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的单位制，应为 metric 或 imperial")
+		return
+	}
+
+	lang := language(c)
+	lots := g.ParkingNear(lat, lng, radius)
+	results := make([]ParkingInfo, 0, len(lots))
+	for _, lot := range lots {
+		info := buildParkingInfo(lot, lang)
+		if near {
+			info.Distance = utils.HaversineDistance(model.Point{Lat: lat, Lng: lng}, model.Point{Lat: lot.Lat, Lng: lot.Lng})
+			info.DistanceText = i18n.FormatDistance(lang, units, info.Distance)
+		}
+		results = append(results, info)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(results),
+		"parking": results,
+	})
+}
+
+// buildParkingInfo 构建停车场信息
+func buildParkingInfo(node *model.Node, lang string) ParkingInfo {
+	return ParkingInfo{
+		ID:       node.ID,
+		Name:     localName(lang, node),
+		Lat:      node.Lat,
+		Lng:      node.Lng,
+		Capacity: node.Capacity,
+		Price:    node.Price,
+	}
+}
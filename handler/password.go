@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/mail"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ForgotPasswordRequest 找回密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ForgotPassword 发送密码重置邮件
+// 无论邮箱是否存在都返回相同的响应，避免被用来探测已注册的邮箱
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
+		return
+	}
+
+	user, err := db.Repo.UserByEmail(req.Email)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	if err == nil {
+		ttl := config.GetDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+		token, err := issueUserToken(user.ID, model.TokenPurposePasswordReset, ttl)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternalError, "生成重置令牌失败")
+			return
+		}
+
+		link := fmt.Sprintf("%s/static/index.html#/reset?token=%s", appBaseURL(), token)
+		body := fmt.Sprintf("你好 %s:\n\n请在 %.0f 分钟内打开以下链接重置密码:\n%s\n\n如果不是你本人操作，请忽略本邮件。",
+			user.Username, ttl.Minutes(), link)
+		if err := mail.Send(user.Email, "VV Maps 密码重置", body); err != nil {
+			slog.Error("发送密码重置邮件失败", "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "如果该邮箱已注册，重置邮件已发送")})
+}
+
+// ResetPassword 使用令牌重置密码
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
+		return
+	}
+
+	token, err := findValidUserToken(req.Token, model.TokenPurposePasswordReset)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "重置链接无效或已过期")
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "密码加密失败")
+		return
+	}
+
+	// 使用令牌 (同一令牌并发请求时只有一个成功)，更新密码并作废该用户所有未使用的重置令牌
+	now := time.Now()
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := consumeUserToken(tx, token, now); err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", token.UserID).
+			Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, model.TokenPurposePasswordReset).
+			Update("used_at", now).Error
+	})
+	if errors.Is(err, errTokenUsed) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "重置链接无效或已过期")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "重置密码失败")
+		return
+	}
+	if err := revokeUserSessions(c.Request.Context(), token.UserID, now); err != nil {
+		slog.Error("注销用户的登录状态失败", "user_id", token.UserID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "密码重置成功，请重新登录")})
+}
+
+// VerifyEmail 验证注册邮箱 (用户点击邮件中的链接)
+func VerifyEmail(c *gin.Context) {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "缺少验证令牌")
+		return
+	}
+
+	token, err := findValidUserToken(tokenString, model.TokenPurposeEmailVerify)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "验证链接无效或已过期")
+		return
+	}
+
+	now := time.Now()
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := consumeUserToken(tx, token, now); err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", token.UserID).
+			Update("email_verified", true).Error
+	})
+	if errors.Is(err, errTokenUsed) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "验证链接无效或已过期")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "邮箱验证失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "邮箱验证成功")})
+}
+
+// sendVerificationEmail 为新注册用户发送邮箱验证邮件
+func sendVerificationEmail(user *model.User) error {
+	ttl := config.GetDuration("EMAIL_VERIFY_TTL", 24*time.Hour)
+	token, err := issueUserToken(user.ID, model.TokenPurposeEmailVerify, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s%s/email/verify?token=%s", appBaseURL(), APIPrefix, token)
+	body := fmt.Sprintf("你好 %s:\n\n感谢注册 VV Maps，请打开以下链接验证邮箱:\n%s", user.Username, link)
+	return mail.Send(user.Email, "VV Maps 邮箱验证", body)
+}
+
+// issueUserToken 生成一次性令牌并保存其摘要，返回原始令牌
+func issueUserToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	record := model.UserToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findValidUserToken 查找指定用途且仍然有效的令牌
+func findValidUserToken(token, purpose string) (*model.UserToken, error) {
+	var record model.UserToken
+	if err := db.DB.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&record).Error; err != nil {
+		return nil, err
+	}
+	if !record.IsValid(time.Now()) {
+		return nil, errors.New("令牌已失效")
+	}
+	return &record, nil
+}
+
+// errTokenUsed 令牌已被其他请求使用
+var errTokenUsed = errors.New("令牌已被使用")
+
+// consumeUserToken 在事务中把令牌标记为已使用；令牌已被使用 (如同一令牌的并发请求) 时返回 errTokenUsed
+func consumeUserToken(tx *gorm.DB, token *model.UserToken, now time.Time) error {
+	result := tx.Model(&model.UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errTokenUsed
+	}
+	return nil
+}
+
+// appBaseURL 对外访问地址 (用于拼接邮件中的链接)
+func appBaseURL() string {
+	return config.GetString("APP_BASE_URL", "http://localhost:8080")
+}
@@ -1,44 +1,138 @@
 package handler
 
 import (
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 	"traffic-system/algo"
+	"traffic-system/config"
+	"traffic-system/i18n"
 	"traffic-system/model"
+	"traffic-system/tracing"
+	"traffic-system/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// Graph 全局图对象 (应在 main 中初始化)
-var Graph *algo.Graph
+// 椭圆剪枝绕路比例的允许范围
+const (
+	minDetourRatio = 1.1
+	maxDetourRatio = 5.0
+)
+
+// maxPathTimeoutMs 请求中 timeout_ms 的上限
+const maxPathTimeoutMs = 60000
+
+// 路线坐标简化参数
+const (
+	maxSimplifyTolerance = 1000.0 // 最大容差 (米)
+	geometryZoomPixels   = 2      // 按缩放级别简化时的容差 (像素)
+)
 
 // PathRequest 路径规划请求
 type PathRequest struct {
-	StartID   string   `json:"start_id"`             // 起点节点 ID
-	EndID     string   `json:"end_id"`               // 终点节点 ID
-	StartLat  float64  `json:"start_lat,omitempty"`  // 起点纬度 (可选)
-	StartLng  float64  `json:"start_lng,omitempty"`  // 起点经度 (可选)
-	EndLat    float64  `json:"end_lat,omitempty"`    // 终点纬度 (可选)
-	EndLng    float64  `json:"end_lng,omitempty"`    // 终点经度 (可选)
-	Modes     []string `json:"modes" binding:"required"` // 交通方式: ["walk", "bike", "car", "bus", "subway"]
+	StartID  string   `json:"start_id"`            // 起点节点 ID
+	EndID    string   `json:"end_id"`              // 终点节点 ID
+	StartLat float64  `json:"start_lat,omitempty"` // 起点纬度 (可选)
+	StartLng float64  `json:"start_lng,omitempty"` // 起点经度 (可选)
+	EndLat   float64  `json:"end_lat,omitempty"`   // 终点纬度 (可选)
+	EndLng   float64  `json:"end_lng,omitempty"`   // 终点经度 (可选)
+	Modes    []string `json:"modes"`               // 交通方式: ["walk", "bike", "car", "truck", "bus", "subway"]，为空时使用用户偏好
+
+	// 以下偏好参数为空时，登录用户使用其保存的出行偏好
+	WalkSpeed      *float64 `json:"walk_speed,omitempty"`      // 步行速度 (米/秒)
+	AvoidHighways  *bool    `json:"avoid_highways,omitempty"`  // 避开快速路
+	AvoidTransfers *bool    `json:"avoid_transfers,omitempty"` // 少换乘
+	AvoidAlleys    *bool    `json:"avoid_alleys,omitempty"`    // 驾车避开小巷和内部道路
+	AvoidStairs    *bool    `json:"avoid_stairs,omitempty"`    // 步行避开楼梯
+
+	Vehicle *model.Vehicle `json:"vehicle,omitempty"` // 车辆信息 (货车尺寸、尾号限行、低排放区)
+
+	// 驾车时先开到终点附近的停车场，再步行到终点
+	ParkNearDestination bool    `json:"park_near_destination,omitempty"`
+	ParkingRadius       float64 `json:"parking_radius,omitempty"` // 停车场查找半径 (米)，默认 1000
+
+	EV *model.EV `json:"ev,omitempty"` // 电动车信息，续航不足时插入充电站
+
+	DetourRatio float64    `json:"detour_ratio,omitempty"` // 椭圆剪枝绕路比例 (如 1.5)，不填表示精确搜索
+	DepartAt    *time.Time `json:"depart_at,omitempty"`    // 出发时间 (RFC3339)，默认当前时间，用于选取分时速度
+
+	// 比较多个出发时间 (最多 6 个)：除按 depart_at 规划的路线外，响应的 departures 中返回按每个时间出发的预计时间
+	DepartureTimes []time.Time `json:"departure_times,omitempty"`
+
+	// 历史回放：按过去某天某时出发规划，路段速度使用当天上报行程的实际速度 (忽略 depart_at 和实时车辆数据)
+	AsOf *time.Time `json:"as_of,omitempty"`
+
+	// 用户手绘的临时路段 (如轮渡、接驳车)，只对本次规划生效
+	Overlay *PathOverlay `json:"overlay,omitempty"`
+
+	// 返回简化后的路线坐标 geometry：指定容差 (米)，或指定地图缩放级别 (容差为该级别下 2 个像素)
+	Simplify float64 `json:"simplify,omitempty"`
+	Zoom     *int    `json:"zoom,omitempty"`
+
+	Units string `json:"units,omitempty"` // 文字中的距离单位: "metric" (默认) 或 "imperial"
+
+	CRS string `json:"crs,omitempty"` // 请求和响应中坐标的坐标系: "wgs84" (默认) 或 "gcj02"
+
+	TimeoutMs int `json:"timeout_ms,omitempty"` // 计算时间预算 (毫秒)，不能超过 PATH_TIMEOUT；超时后返回近似路径
+}
+
+// PathOverlay 本次规划临时叠加在地图上的节点和边
+type PathOverlay struct {
+	Nodes []model.Node `json:"nodes,omitempty" binding:"max=100"` // 新增节点 (如码头)，ID 不能与已有节点重复
+	Edges []model.Edge `json:"edges" binding:"max=200"`           // 新增的边，dist 为 0 时按直线距离计算；walk/bike/car/truck 的边自动生成反向边
 }
 
 // PathResponse 路径规划响应
 type PathResponse struct {
 	Found         bool          `json:"found"`
+	RouteID       string        `json:"route_id,omitempty"`    // 路线 ID，导航中偏离路线时用于 /api/path/reroute
+	Fingerprint   string        `json:"fingerprint,omitempty"` // 路线指纹 (经过的路段和方式相同时不变)
 	Path          []PathNode    `json:"path,omitempty"`
-	Segments      []PathSegment `json:"segments,omitempty"`      // 路径段详情
-	Distance      float64       `json:"distance,omitempty"`      // 总距离 (米)
+	Segments      []PathSegment `json:"segments,omitempty"`       // 路径段详情 (逐边)
+	Legs          []RouteLeg    `json:"legs,omitempty"`           // 合并后的行程段 (同一方式、同一线路合并)
+	Geometry      [][2]float64  `json:"geometry,omitempty"`       // 简化后的路线坐标 [[lat, lng], ...] (指定 simplify 或 zoom 时返回)
+	Transfers     []Transfer    `json:"transfers,omitempty"`      // 换乘列表
+	Distance      float64       `json:"distance,omitempty"`       // 总距离 (米)
 	EstimatedTime float64       `json:"estimated_time,omitempty"` // 预计时间 (秒)
+	DistanceText  string        `json:"distance_text,omitempty"`  // 格式化的总距离 (按语言和单位制)，如 "3.2 公里"
+	DurationText  string        `json:"duration_text,omitempty"`  // 格式化的预计时间，如 "1 小时 5 分钟"
+	Fee           float64       `json:"fee,omitempty"`            // 驶入收费区域的总费用 (元)
+	SnappedStart  *SnapInfo     `json:"snapped_start,omitempty"`  // 起点坐标吸附到的道路位置
+	SnappedEnd    *SnapInfo     `json:"snapped_end,omitempty"`    // 终点坐标吸附到的道路位置
+	Parking       *ParkingInfo  `json:"parking,omitempty"`        // 停车的停车场 (park_near_destination 时)
+	ChargeStops   []ChargeStop  `json:"charge_stops,omitempty"`   // 途经的充电站 (电动车，充电时间已计入预计时间)
+	FinalCharge   *float64      `json:"final_charge,omitempty"`   // 到达终点时的电量 (%)
+	Replay        *ReplayInfo   `json:"replay,omitempty"`         // 历史回放使用的数据 (as_of 时)
+	Approximate   bool          `json:"approximate,omitempty"`    // 精确搜索超时，返回的是近似路径 (可能不是最快的)
 	Message       string        `json:"message,omitempty"`
+
+	Departures []DepartureOption `json:"departures,omitempty"`       // 按 departure_times 中每个时间出发的结果 (顺序相同)
+	Weather    *WeatherAdvisory  `json:"weather_advisory,omitempty"` // 天气提示 (按雨雪天气调整了规划时)
+
+	// 路线摘要，客户端可以直接用于缩放地图和显示摘要卡片
+	Bounds    *algo.BoundingBox `json:"bounds,omitempty"`     // 路线经过的所有节点的范围
+	ModesUsed []string          `json:"modes_used,omitempty"` // 实际使用的交通方式 (按首次使用的顺序)
+	LegCount  int               `json:"leg_count,omitempty"`  // 行程段数 (即 legs 的长度)
+	StartName string            `json:"start_name,omitempty"` // 起点名称 (坐标吸附的起点为 "起点")
+	EndName   string            `json:"end_name,omitempty"`   // 终点名称
+
+	timedOut bool // 搜索超时 (结果不写入缓存)
 }
 
 // PathNode 路径节点信息
 type PathNode struct {
-	ID   string  `json:"id"`
-	Name string  `json:"name"`
-	Lat  float64 `json:"lat"`
-	Lng  float64 `json:"lng"`
-	Type string  `json:"type"`
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`              // 按 Accept-Language 选择的名称
+	NameEn string  `json:"name_en,omitempty"` // 英文名称
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Type   string  `json:"type"`
+	Level  int     `json:"level,omitempty"` // 所在楼层 (0 为地面层)
 }
 
 // PathSegment 路径段信息
@@ -53,99 +147,373 @@ type PathSegment struct {
 	UsedMode string   `json:"used_mode"` // 实际使用的交通方式
 	LineID   string   `json:"line_id,omitempty"`
 	Desc     string   `json:"desc,omitempty"`
+	Fee      float64  `json:"fee,omitempty"`     // 驶入收费区域的费用 (元)
+	Footway  string   `json:"footway,omitempty"` // 步行设施类型 (人行横道、天桥、地下通道、站内通道)
+
+	// 楼层：两端楼层不同的路段是楼梯、扶梯或电梯 (Connector)
+	Connector string `json:"connector,omitempty"`
+	FromLevel int    `json:"from_level,omitempty"`
+	ToLevel   int    `json:"to_level,omitempty"`
+
+	// 以下字段仅在有实时车辆数据时填写 (公交/地铁上车段)
+	WaitTime     float64 `json:"wait_time,omitempty"`     // 按实时车辆位置估算的等待时间 (秒)，已计入 Time
+	DelaySeconds int     `json:"delay_seconds,omitempty"` // 线路当前晚点秒数
+	Realtime     bool    `json:"realtime,omitempty"`      // 是否使用了实时数据
+}
+
+// SnapInfo 坐标吸附到道路上的位置
+type SnapInfo struct {
+	Lat      float64 `json:"lat"`
+	Lng      float64 `json:"lng"`
+	Distance float64 `json:"distance"` // 原始坐标到道路的距离 (米)
+	FromID   string  `json:"from_id"`  // 所在道路的两端节点
+	ToID     string  `json:"to_id"`
+	Desc     string  `json:"desc,omitempty"`
+}
+
+// ChargeStop 路线中插入的充电站
+type ChargeStop struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Lat          float64  `json:"lat"`
+	Lng          float64  `json:"lng"`
+	Power        float64  `json:"power,omitempty"` // 充电功率 (kW)
+	Connectors   []string `json:"connectors,omitempty"`
+	ArriveCharge float64  `json:"arrive_charge"` // 到达时电量 (%)
+	DepartCharge float64  `json:"depart_charge"` // 充电后电量 (%)
+	ChargeTime   float64  `json:"charge_time"`   // 充电时间 (秒)
 }
 
 // FindPath 路径规划接口
 func FindPath(c *gin.Context) {
 	var req PathRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+	_, parse := tracing.Start(c.Request.Context(), "path.parse")
+	err := c.ShouldBindJSON(&req)
+	parse.End()
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	crs, ok := parseCRS(c, req.CRS)
+	if !ok {
 		return
 	}
+	req.toWGS84(crs)
 
-	if Graph == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "地图数据未加载"})
+	resp, ok := cachedPlanPath(c, &req)
+	if !ok {
 		return
 	}
+	if resp.Found && req.AsOf == nil { // 历史回放不是实际出行需求，不计入热度和统计
+		recordRouteEndpoints(&req)
+		recordRoute(c, resp)
+		resp.RouteID = saveRoute(c.Request.Context(), &req, resp)
+	}
+	resp.inCRS(crs)
+	c.JSON(http.StatusOK, versionedRoute(c, resp, req.Units))
+}
 
-	// 如果提供了坐标，找到最近的节点
-	startID := req.StartID
-	endID := req.EndID
+// pathPlan 校验后的规划参数：吸附后的起终点和搜索选项 (路径规划、全天用时曲线等共用)
+type pathPlan struct {
+	graph      *algo.Graph // 本次请求使用的图 (后续搜索和构建响应都使用这张图)
+	start, end algo.Waypoint
+	opts       algo.SearchOptions
+	lang       string
+	now        time.Time
+	departAt   time.Time
+	replay     *ReplayInfo      // 历史回放 (as_of) 时不为空
+	advisory   *WeatherAdvisory // 按天气调整了规划时不为空
+}
 
-	if req.StartLat != 0 && req.StartLng != 0 {
-		nearestStart := Graph.FindNearestNode(req.StartLat, req.StartLng)
-		if nearestStart != nil {
-			startID = nearestStart.ID
-		}
+// preparePath 校验请求参数 (补全用户偏好)，吸附起终点并构建搜索选项
+// 参数错误时直接写入错误响应并返回 false
+func preparePath(c *gin.Context, req *PathRequest) (*pathPlan, bool) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return nil, false
 	}
 
-	if req.EndLat != 0 && req.EndLng != 0 {
-		nearestEnd := Graph.FindNearestNode(req.EndLat, req.EndLng)
-		if nearestEnd != nil {
-			endID = nearestEnd.ID
-		}
+	// 登录用户未显式指定的参数使用其出行偏好
+	applyProfileDefaults(c, req)
+
+	if !checkCoordinates(c, model.Point{Lat: req.StartLat, Lng: req.StartLng}, model.Point{Lat: req.EndLat, Lng: req.EndLng}) {
+		return nil, false
 	}
 
+	// 解析交通方式
+	modeMask := model.ParseModes(req.Modes)
+	if modeMask == 0 {
+		respondError(c, http.StatusBadRequest, CodeModeInvalid, "未指定有效的交通方式")
+		return nil, false
+	}
+
+	// 如果提供了坐标，吸附到最近的可通行道路上
+	_, snap := tracing.Start(c.Request.Context(), "path.snap")
+	start := resolveWaypoint(g, req.StartID, req.StartLat, req.StartLng, modeMask)
+	end := resolveWaypoint(g, req.EndID, req.EndLat, req.EndLng, modeMask)
+	startID, endID := start.NodeID, end.NodeID
+	snap.SetAttributes(attribute.Bool("snap.start", start.Snap != nil), attribute.Bool("snap.end", end.Snap != nil))
+	snap.End()
+
 	// 验证起点和终点
 	if startID == "" || endID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "起点或终点未指定"})
-		return
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "起点或终点未指定")
+		return nil, false
 	}
 
-	if Graph.Nodes[startID] == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "起点不存在: " + startID})
-		return
+	if g.Nodes[startID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "起点不存在: "+startID)
+		return nil, false
 	}
 
-	if Graph.Nodes[endID] == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "终点不存在: " + endID})
-		return
+	if g.Nodes[endID] == nil {
+		respondError(c, http.StatusBadRequest, CodeNodeNotFound, "终点不存在: "+endID)
+		return nil, false
 	}
 
-	// 解析交通方式
-	modeMask := model.ParseModes(req.Modes)
-	if modeMask == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未指定有效的交通方式"})
-		return
+	if req.DetourRatio != 0 && (req.DetourRatio < minDetourRatio || req.DetourRatio > maxDetourRatio) {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "绕路比例超出范围 (1.1 ~ 5)")
+		return nil, false
+	}
+	// 速度过低时路段成本无穷大，过高时 ALT 启发函数的下界不再成立
+	if req.WalkSpeed != nil && (*req.WalkSpeed < model.MinWalkSpeed || *req.WalkSpeed > model.MaxWalkSpeed) {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "步行速度超出范围 (0.5 ~ 3.0 米/秒)")
+		return nil, false
+	}
+	if req.ParkingRadius < 0 || req.ParkingRadius > maxParkingRadius {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "停车场查找半径超出范围 (0 ~ 5000 米)")
+		return nil, false
+	}
+
+	if req.EV != nil {
+		if req.EV.Range <= 0 || req.EV.Charge < 0 || req.EV.Charge > 100 || req.EV.Battery < 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "电动车参数错误 (续航需大于 0，电量 0 ~ 100)")
+			return nil, false
+		}
+		if req.ParkNearDestination {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "电动车充电规划暂不支持与停车场同时使用")
+			return nil, false
+		}
 	}
 
+	if req.Simplify < 0 || req.Simplify > maxSimplifyTolerance {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "简化容差超出范围 (0 ~ 1000 米)")
+		return nil, false
+	}
+	if req.Zoom != nil && (*req.Zoom < 0 || *req.Zoom > algo.MaxTileZoom) {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "缩放级别超出范围 (0 ~ 22)")
+		return nil, false
+	}
+
+	if !i18n.ValidUnits(req.Units) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的单位制，应为 metric 或 imperial")
+		return nil, false
+	}
+
+	if req.TimeoutMs < 0 || req.TimeoutMs > maxPathTimeoutMs {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "timeout_ms 超出范围 (0 ~ 60000)")
+		return nil, false
+	}
+
+	if len(req.DepartureTimes) > maxDepartureTimes {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "departure_times 最多 6 个")
+		return nil, false
+	}
+	if len(req.DepartureTimes) > 0 && (req.EV != nil || req.AsOf != nil) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "比较出发时间暂不支持电动车充电规划和历史回放")
+		return nil, false
+	}
+
+	if req.Vehicle != nil {
+		if err := validateVehicle(req.Vehicle); err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return nil, false
+		}
+	}
+
+	var layer *algo.Overlay
+	if req.Overlay != nil {
+		var err error
+		layer, err = g.ApplyEdit(nil, algo.MapEdit{AddNodes: req.Overlay.Nodes, AddEdges: req.Overlay.Edges})
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的临时路段: "+err.Error())
+			return nil, false
+		}
+	}
+
+	lang := language(c)
+	now := time.Now()
+	departAt := now
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+	opts := algo.SearchOptions{ModeMask: modeMask, DetourRatio: req.DetourRatio, DepartAt: departAt, Overlay: layer, Budget: pathBudget(req)}
+	opts.Context = c.Request.Context() // 客户端断开或服务关闭时中止搜索
+
+	var replay *ReplayInfo
+	if req.AsOf != nil {
+		if !req.AsOf.Before(now) {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "回放时间必须早于当前时间")
+			return nil, false
+		}
+		rows, err := replaySpeeds(*req.AsOf)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "读取历史路况失败")
+			return nil, false
+		}
+		table, matched := g.BuildSpeedTable(rows)
+		departAt = *req.AsOf
+		opts.DepartAt = departAt
+		opts.Speeds = table
+		replay = &ReplayInfo{Date: departAt.Local().Format("2006-01-02"), Records: matched}
+	}
+	if req.WalkSpeed != nil {
+		opts.WalkSpeed = *req.WalkSpeed
+	}
+	if req.AvoidHighways != nil {
+		opts.AvoidHighways = *req.AvoidHighways
+	}
+	if req.AvoidTransfers != nil {
+		opts.AvoidTransfers = *req.AvoidTransfers
+	}
+	if req.AvoidAlleys != nil {
+		opts.AvoidAlleys = *req.AvoidAlleys
+	}
+	if req.AvoidStairs != nil {
+		opts.AvoidStairs = *req.AvoidStairs
+	}
+	opts.Vehicle = req.Vehicle
+
+	// 下雨、下雪时调整步行和骑行 (未启用天气数据时不调整)
+	var advisory *WeatherAdvisory
+	opts.Weather, advisory = weatherAdjustment(requestWeather(c.Request.Context(), g, req, departAt, now), modeMask, lang)
+	return &pathPlan{graph: g, start: start, end: end, opts: opts, lang: lang, now: now, departAt: departAt, replay: replay, advisory: advisory}, true
+}
+
+// planPath 执行路径规划并构建响应 (路径规划、分享等接口共用)
+// 参数错误时直接写入错误响应并返回 false
+func planPath(c *gin.Context, req *PathRequest) (*PathResponse, bool) {
+	plan, ok := preparePath(c, req)
+	if !ok {
+		return nil, false
+	}
+	g := plan.graph
+	start, end, opts, lang := plan.start, plan.end, plan.opts, plan.lang
+	now, departAt, replay := plan.now, plan.departAt, plan.replay
+	startID, endID := start.NodeID, end.NodeID
+
 	// 执行路径规划
-	result := Graph.Dijkstra(startID, endID, modeMask)
+	began := time.Now()
+	var result algo.PathResult
+	var parking *ParkingInfo
+	var chargeStops []ChargeStop
+	var finalCharge *float64
+	message := "路径规划成功"
+	driving := opts.ModeMask&(model.ModeCar|model.ModeTruck) != 0
+	if req.EV != nil && driving {
+		var stops []algo.ChargeStop
+		var final float64
+		result, stops, final = g.RouteWithCharging(startID, endID, opts, *req.EV)
+		if result.Cancelled {
+			respondCancelled(c)
+			return nil, false
+		}
+		if !result.Found {
+			return &PathResponse{
+				Found:   false,
+				Message: tr(c, "剩余电量不足，且沿途没有可用的充电站"),
+			}, true
+		}
+		chargeStops = buildChargeStops(g, stops, lang)
+		finalCharge = &final
+	} else if req.ParkNearDestination && driving && g.Nodes[endID].Type != model.NodeTypeParking {
+		var lot *model.Node
+		result, lot = g.RouteViaParking(startID, endID, opts, req.ParkingRadius)
+		if lot != nil {
+			info := buildParkingInfo(lot, lang)
+			parking = &info
+		} else {
+			message = "终点附近没有可到达的停车场，已按普通路线规划"
+		}
+	}
+	if parking == nil && finalCharge == nil {
+		result = g.RouteBetween(start, end, opts)
+	}
+	slog.Debug("路径规划", "start", startID, "end", endID, "modes", req.Modes,
+		"found", result.Found, "timed_out", result.TimedOut, "cancelled", result.Cancelled,
+		"distance", result.Distance, "eta", result.EstimatedTime, "nodes", len(result.Path),
+		"elapsed", time.Since(began))
+	if result.Cancelled {
+		respondCancelled(c)
+		return nil, false
+	}
 
 	if !result.Found {
-		c.JSON(http.StatusOK, PathResponse{
-			Found:   false,
-			Message: "未找到符合条件的路径",
-		})
-		return
+		message := "未找到符合条件的路径"
+		if result.TimedOut {
+			message = "路径规划超时，请缩小范围后重试"
+		}
+		return &PathResponse{
+			Found:    false,
+			Message:  tr(c, message),
+			timedOut: result.TimedOut,
+		}, true
+	}
+	if result.TimedOut {
+		message = "计算超时，返回的是近似路径"
+	}
+
+	// 构建响应 (节点名称、路段、实时等待时间、分段和折线)
+	_, build := tracing.Start(c.Request.Context(), "path.build")
+	defer build.End()
+
+	// 吸附到道路上的起终点和临时路段中新增的节点 (不在地图中)
+	virtual := make(map[string]PathNode)
+	if req.Overlay != nil {
+		for i := range req.Overlay.Nodes {
+			node := buildPathNode(&req.Overlay.Nodes[i], lang)
+			if node.Type == "" {
+				node.Type = "virtual"
+			}
+			virtual[node.ID] = node
+		}
+	}
+	if start.Snap != nil {
+		virtual[algo.VirtualStartID] = PathNode{ID: algo.VirtualStartID, Name: i18n.T(lang, "起点"), Lat: start.Snap.Point.Lat, Lng: start.Snap.Point.Lng, Type: "virtual", Level: start.Snap.Edge.Level}
+	}
+	if end.Snap != nil {
+		virtual[algo.VirtualEndID] = PathNode{ID: algo.VirtualEndID, Name: i18n.T(lang, "终点"), Lat: end.Snap.Point.Lat, Lng: end.Snap.Point.Lng, Type: "virtual", Level: end.Snap.Edge.Level}
 	}
 
 	// 构建路径节点信息
 	pathNodes := make([]PathNode, 0, len(result.Path))
 	for _, nodeID := range result.Path {
-		node := Graph.Nodes[nodeID]
-		if node != nil {
-			pathNodes = append(pathNodes, PathNode{
-				ID:   node.ID,
-				Name: node.Name,
-				Lat:  node.Lat,
-				Lng:  node.Lng,
-				Type: node.Type,
-			})
+		if v, ok := virtual[nodeID]; ok {
+			pathNodes = append(pathNodes, v)
+			continue
+		}
+		if node := g.Nodes[nodeID]; node != nil {
+			pathNodes = append(pathNodes, buildPathNode(node, lang))
 		}
 	}
 
 	// 构建路径段信息（包含节点名称）
 	segments := make([]PathSegment, 0, len(result.Segments))
 	for _, seg := range result.Segments {
-		fromNode := Graph.Nodes[seg.FromID]
-		toNode := Graph.Nodes[seg.ToID]
+		fromNode := g.Nodes[seg.FromID]
+		toNode := g.Nodes[seg.ToID]
 		fromName, toName := seg.FromID, seg.ToID
+		fromLevel, toLevel := 0, 0
 		if fromNode != nil {
-			fromName = fromNode.Name
+			fromName, fromLevel = localName(lang, fromNode), fromNode.Level
+		} else if v, ok := virtual[seg.FromID]; ok {
+			fromName, fromLevel = v.Name, v.Level
 		}
 		if toNode != nil {
-			toName = toNode.Name
+			toName, toLevel = localName(lang, toNode), toNode.Level
+		} else if v, ok := virtual[seg.ToID]; ok {
+			toName, toLevel = v.Name, v.Level
 		}
 		segments = append(segments, PathSegment{
 			FromID:   seg.FromID,
@@ -158,36 +526,216 @@ func FindPath(c *gin.Context) {
 			UsedMode: seg.UsedMode,
 			LineID:   seg.LineID,
 			Desc:     seg.Desc,
+			Fee:      seg.Fee,
+			Footway:  seg.Footway,
+
+			Connector: seg.Connector,
+			FromLevel: fromLevel,
+			ToLevel:   toLevel,
 		})
 	}
 
-	c.JSON(http.StatusOK, PathResponse{
+	// 有实时车辆数据时修正公交/地铁的等待时间 (历史回放不使用实时数据)
+	estimatedTime := result.EstimatedTime
+	if replay == nil {
+		estimatedTime += applyRealtime(g, segments, departAt, now)
+	}
+
+	legs := buildLegs(segments, lang, req.Units)
+
+	resp := &PathResponse{
 		Found:         true,
+		Fingerprint:   routeFingerprint(segments),
 		Path:          pathNodes,
 		Segments:      segments,
+		Legs:          legs,
+		Geometry:      buildGeometry(pathNodes, req.Simplify, req.Zoom),
+		Transfers:     buildTransfers(g, legs),
 		Distance:      result.Distance,
-		EstimatedTime: result.EstimatedTime,
-		Message:       "路径规划成功",
-	})
+		EstimatedTime: estimatedTime,
+		DistanceText:  i18n.FormatDistance(lang, req.Units, result.Distance),
+		DurationText:  i18n.FormatDuration(lang, estimatedTime),
+		Fee:           result.Fee,
+		SnappedStart:  buildSnapInfo(start.Snap),
+		SnappedEnd:    buildSnapInfo(end.Snap),
+		Parking:       parking,
+		ChargeStops:   chargeStops,
+		FinalCharge:   finalCharge,
+		Replay:        replay,
+		Approximate:   result.TimedOut,
+		Message:       tr(c, message),
+		Weather:       plan.advisory,
+		timedOut:      result.TimedOut,
+	}
+	if len(req.DepartureTimes) > 0 {
+		resp.Departures = planDepartures(g, req.DepartureTimes, start, end, opts, parking != nil, req.ParkingRadius, lang, now)
+	}
+	resp.summarize()
+	return resp, true
 }
 
-// GetNodes 获取所有节点信息
-func GetNodes(c *gin.Context) {
-	if Graph == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "地图数据未加载"})
+// pathBudget 单次搜索的时间预算：PATH_TIMEOUT (默认 1 秒，0 表示不限制)，请求中的 timeout_ms 只能更短
+func pathBudget(req *PathRequest) time.Duration {
+	budget := config.GetDuration("PATH_TIMEOUT", time.Second)
+	if req.TimeoutMs > 0 {
+		if t := time.Duration(req.TimeoutMs) * time.Millisecond; budget <= 0 || t < budget {
+			budget = t
+		}
+	}
+	return budget
+}
+
+// buildGeometry 构建简化后的路线坐标，未指定容差和缩放级别时返回 nil
+func buildGeometry(path []PathNode, tolerance float64, zoom *int) [][2]float64 {
+	if len(path) == 0 || (tolerance == 0 && zoom == nil) {
+		return nil
+	}
+	points := make([]model.Point, len(path))
+	for i, node := range path {
+		points[i] = model.Point{Lat: node.Lat, Lng: node.Lng}
+	}
+	if zoom != nil {
+		tolerance = utils.MetersPerPixel(*zoom, points[0].Lat) * geometryZoomPixels
+	}
+
+	simplified := utils.SimplifyPolyline(points, tolerance)
+	geometry := make([][2]float64, len(simplified))
+	for i, p := range simplified {
+		geometry[i] = [2]float64{p.Lat, p.Lng}
+	}
+	return geometry
+}
+
+// summarize 根据路径节点和行程段填充路线摘要 (范围、使用的交通方式、行程段数、起终点名称)
+func (resp *PathResponse) summarize() {
+	if len(resp.Path) == 0 {
 		return
 	}
+	first, last := resp.Path[0], resp.Path[len(resp.Path)-1]
+	bounds := algo.BoundingBox{MinLat: first.Lat, MinLng: first.Lng, MaxLat: first.Lat, MaxLng: first.Lng}
+	for _, node := range resp.Path[1:] {
+		bounds.MinLat, bounds.MaxLat = min(bounds.MinLat, node.Lat), max(bounds.MaxLat, node.Lat)
+		bounds.MinLng, bounds.MaxLng = min(bounds.MinLng, node.Lng), max(bounds.MaxLng, node.Lng)
+	}
+	resp.Bounds = &bounds
+
+	var modes []string
+	for _, leg := range resp.Legs {
+		if !slices.Contains(modes, leg.Mode) {
+			modes = append(modes, leg.Mode)
+		}
+	}
+	resp.ModesUsed = modes
+	resp.LegCount = len(resp.Legs)
+	resp.StartName, resp.EndName = first.Name, last.Name
+}
+
+// resolveWaypoint 解析路径端点：提供坐标时吸附到最近的可通行道路上 (没有道路时使用最近的节点)，否则使用节点 ID
+// 吸附到道路时 NodeID 为所在道路较近的一端，供停车场、充电站等按节点规划的功能使用
+func resolveWaypoint(g *algo.Graph, nodeID string, lat, lng float64, modeMask int) algo.Waypoint {
+	if lat == 0 || lng == 0 {
+		return algo.Waypoint{NodeID: nodeID}
+	}
+	if snap := g.SnapToEdge(lat, lng, modeMask); snap != nil {
+		return algo.Waypoint{NodeID: snap.NearestNodeID(), Snap: snap}
+	}
+	if nearest := g.FindNearestNode(lat, lng); nearest != nil {
+		return algo.Waypoint{NodeID: nearest.ID}
+	}
+	return algo.Waypoint{NodeID: nodeID}
+}
+
+// buildSnapInfo 构建道路吸附信息
+func buildSnapInfo(snap *algo.EdgeSnap) *SnapInfo {
+	if snap == nil {
+		return nil
+	}
+	return &SnapInfo{
+		Lat:      snap.Point.Lat,
+		Lng:      snap.Point.Lng,
+		Distance: snap.Distance,
+		FromID:   snap.Edge.From,
+		ToID:     snap.Edge.To,
+		Desc:     snap.Edge.Desc,
+	}
+}
 
-	nodes := make([]PathNode, 0, len(Graph.NodeList))
-	for _, node := range Graph.NodeList {
-		nodes = append(nodes, PathNode{
-			ID:   node.ID,
-			Name: node.Name,
-			Lat:  node.Lat,
-			Lng:  node.Lng,
-			Type: node.Type,
+// buildChargeStops 构建充电站信息
+func buildChargeStops(g *algo.Graph, stops []algo.ChargeStop, lang string) []ChargeStop {
+	result := make([]ChargeStop, 0, len(stops))
+	for _, stop := range stops {
+		node := g.Nodes[stop.NodeID]
+		if node == nil {
+			continue
+		}
+		result = append(result, ChargeStop{
+			ID:           node.ID,
+			Name:         localName(lang, node),
+			Lat:          node.Lat,
+			Lng:          node.Lng,
+			Power:        node.Power,
+			Connectors:   node.Connectors,
+			ArriveCharge: stop.ArriveCharge,
+			DepartCharge: stop.DepartCharge,
+			ChargeTime:   stop.ChargeTime,
 		})
 	}
+	return result
+}
+
+// applyProfileDefaults 用登录用户的出行偏好填充请求中未指定的参数
+func applyProfileDefaults(c *gin.Context, req *PathRequest) {
+	userID := currentUserID(c)
+	if userID == 0 {
+		return
+	}
+	profile, err := loadUserProfile(c.Request.Context(), userID)
+	if err != nil {
+		return
+	}
+
+	if len(req.Modes) == 0 {
+		req.Modes = profile.DefaultModes
+	}
+	if req.WalkSpeed == nil && profile.WalkSpeed > 0 {
+		req.WalkSpeed = &profile.WalkSpeed
+	}
+	if req.AvoidHighways == nil {
+		req.AvoidHighways = &profile.AvoidHighways
+	}
+	if req.AvoidTransfers == nil {
+		req.AvoidTransfers = &profile.AvoidTransfers
+	}
+	if req.AvoidAlleys == nil {
+		req.AvoidAlleys = &profile.AvoidAlleys
+	}
+	if req.AvoidStairs == nil {
+		req.AvoidStairs = &profile.AvoidStairs
+	}
+	if req.Vehicle == nil && profile.Vehicle != (model.Vehicle{}) {
+		req.Vehicle = &profile.Vehicle
+	}
+}
+
+// GetNodes 获取所有节点信息 (可按 ?category= 过滤)
+func GetNodes(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	category, ok := queryCategory(c)
+	if !ok {
+		return
+	}
+
+	lang := language(c)
+	nodes := make([]PathNode, 0, len(g.NodeList))
+	for i := range g.NodeList {
+		if g.InCategory(&g.NodeList[i], category) {
+			nodes = append(nodes, buildPathNode(&g.NodeList[i], lang))
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"count": len(nodes),
@@ -197,77 +745,235 @@ func GetNodes(c *gin.Context) {
 
 // GetNodeByID 根据 ID 获取节点信息
 func GetNodeByID(c *gin.Context) {
+	g := requestGraph(c)
 	nodeID := c.Param("id")
 
-	if Graph == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "地图数据未加载"})
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
 		return
 	}
 
-	node := Graph.Nodes[nodeID]
+	node := g.Nodes[nodeID]
 	if node == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+		respondError(c, http.StatusNotFound, CodeNodeNotFound, "节点不存在")
 		return
 	}
 
-	c.JSON(http.StatusOK, PathNode{
-		ID:   node.ID,
-		Name: node.Name,
-		Lat:  node.Lat,
-		Lng:  node.Lng,
-		Type: node.Type,
-	})
+	c.JSON(http.StatusOK, buildPathNode(node, language(c)))
 }
 
-// SearchNodes 搜索节点 (根据名称模糊匹配)
+// SearchResult 节点搜索结果
+type SearchResult struct {
+	PathNode
+	Alias string `json:"alias,omitempty"` // 通过别名匹配时为匹配到的别名
+}
+
+// 节点搜索分页
+const maxSearchLimit = 100
+
+// SearchNodes 搜索节点 (名称、英文名称、别名、ID)，按匹配程度排序
+// 过滤: category (分类)，type (节点类型，逗号分隔)，bbox (min_lat,min_lng,max_lat,max_lng)
+// 分页: offset (默认 0)，limit (1 ~ 100，不指定时返回全部)；total 为过滤后的匹配总数
+// crs: bbox 和结果坐标的坐标系 (wgs84 或 gcj02)
 func SearchNodes(c *gin.Context) {
+	g := requestGraph(c)
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少搜索关键词"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "缺少搜索关键词")
 		return
 	}
 
-	if Graph == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "地图数据未加载"})
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
 		return
 	}
 
-	results := make([]PathNode, 0)
-	for _, node := range Graph.NodeList {
-		// 简单的名称匹配 (可以改进为更复杂的搜索算法)
-		if contains(node.Name, query) || contains(node.ID, query) {
-			results = append(results, PathNode{
-				ID:   node.ID,
-				Name: node.Name,
-				Lat:  node.Lat,
-				Lng:  node.Lng,
-				Type: node.Type,
-			})
+	category, ok := queryCategory(c)
+	if !ok {
+		return
+	}
+	crs, ok := queryCRS(c)
+	if !ok {
+		return
+	}
+	var types []string
+	if s := c.Query("type"); s != "" {
+		types = strings.Split(s, ",")
+	}
+	var bbox *algo.BoundingBox
+	if s := c.Query("bbox"); s != "" {
+		b, ok := parseBBox(s)
+		if !ok {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "bbox 格式错误，应为 min_lat,min_lng,max_lat,max_lng")
+			return
+		}
+		// 城市范围内偏移近似不变，转换两个角即可
+		b.MinLat, b.MinLng = latLng(crs.ToWGS84(model.Point{Lat: b.MinLat, Lng: b.MinLng}))
+		b.MaxLat, b.MaxLng = latLng(crs.ToWGS84(model.Point{Lat: b.MaxLat, Lng: b.MaxLng}))
+		bbox = &b
+	}
+	offset := 0
+	if s := c.Query("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "offset 不能为负数")
+			return
 		}
+		offset = n
+	}
+	limit := -1
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	matches := g.SearchNodes(query, category)
+	matches = slices.DeleteFunc(matches, func(m algo.NodeMatch) bool {
+		return (len(types) > 0 && !slices.Contains(types, m.Node.Type)) ||
+			(bbox != nil && !bbox.Contains(m.Node.Lat, m.Node.Lng))
+	})
+	total := len(matches)
+	page := matches[min(offset, total):]
+	if limit >= 0 && len(page) > limit {
+		page = page[:limit]
+	}
+
+	lang := language(c)
+	results := make([]SearchResult, 0, len(page))
+	for _, m := range page {
+		result := SearchResult{PathNode: buildPathNode(m.Node, lang), Alias: m.Alias}
+		result.inCRS(crs)
+		results = append(results, result)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"query":  query,
-		"count":  len(results),
+		"query":   query,
+		"count":   len(results),
+		"total":   total,
+		"offset":  offset,
 		"results": results,
 	})
 }
 
-// contains 检查字符串是否包含子串 (不区分大小写)
-func contains(s, substr string) bool {
-	// 简单的包含检查 (可以使用 strings.Contains)
-	return len(s) >= len(substr) && (s == substr ||
-		len(s) > len(substr) && (
-			s[:len(substr)] == substr ||
-			s[len(s)-len(substr):] == substr ||
-			findSubstring(s, substr)))
+// parseBBox 解析 "min_lat,min_lng,max_lat,max_lng" 格式的范围
+func parseBBox(s string) (algo.BoundingBox, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return algo.BoundingBox{}, false
+	}
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return algo.BoundingBox{}, false
+		}
+		v[i] = f
+	}
+	b := algo.BoundingBox{MinLat: v[0], MinLng: v[1], MaxLat: v[2], MaxLng: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLng > b.MaxLng ||
+		!utils.ValidCoordinate(b.MinLat, b.MinLng) || !utils.ValidCoordinate(b.MaxLat, b.MaxLng) {
+		return algo.BoundingBox{}, false
+	}
+	return b, true
+}
+
+// Suggestion 自动补全结果
+type Suggestion struct {
+	SearchResult
+	Distance     float64 `json:"distance,omitempty"`      // 到 near 的直线距离 (米)
+	DistanceText string  `json:"distance_text,omitempty"` // 格式化的距离 (按语言和 ?units= 单位制)
 }
 
-func findSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+// SuggestNodes 输入框自动补全
+// 参数: q (输入内容)，near (可选，"lat,lng"，偏向附近的节点)，limit (默认 10，最多 50)，units (距离单位制)，category (分类)，
+// crs (near 和结果坐标的坐标系)
+// 结果按匹配程度、节点重要程度 (类型) 和到 near 的距离综合排序
+func SuggestNodes(c *gin.Context) {
+	g := requestGraph(c)
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "缺少搜索关键词")
+		return
+	}
+
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	crs, ok := queryCRS(c)
+	if !ok {
+		return
+	}
+	var near *model.Point
+	if s := c.Query("near"); s != "" {
+		p, ok := parseLatLng(s)
+		if !ok {
+			respondError(c, http.StatusBadRequest, CodeCoordinateInvalid, "near 格式错误，应为 lat,lng")
+			return
+		}
+		if !checkCoordinates(c, p) {
+			return
+		}
+		p = crs.ToWGS84(p)
+		near = &p
+	}
+
+	limit := algo.DefaultSuggestLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > algo.MaxSuggestLimit {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 50)")
+			return
+		}
+		limit = n
+	}
+
+	units := c.Query("units")
+	if !i18n.ValidUnits(units) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的单位制，应为 metric 或 imperial")
+		return
+	}
+
+	category, ok := queryCategory(c)
+	if !ok {
+		return
+	}
+
+	lang := language(c)
+	suggestions := g.Suggest(query, category, near, limit)
+	results := make([]Suggestion, 0, len(suggestions))
+	for _, s := range suggestions {
+		result := Suggestion{SearchResult: SearchResult{PathNode: buildPathNode(s.Node, lang), Alias: s.Alias}}
+		if near != nil {
+			result.Distance = s.Distance
+			result.DistanceText = i18n.FormatDistance(lang, units, s.Distance)
 		}
+		result.inCRS(crs)
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":       query,
+		"count":       len(results),
+		"suggestions": results,
+	})
+}
+
+// parseLatLng 解析 "lat,lng" 格式的坐标
+func parseLatLng(s string) (model.Point, bool) {
+	latStr, lngStr, ok := strings.Cut(s, ",")
+	if !ok {
+		return model.Point{}, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+	if errLat != nil || errLng != nil {
+		return model.Point{}, false
 	}
-	return false
+	return model.Point{Lat: lat, Lng: lng}, true
 }
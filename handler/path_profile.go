@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 全天用时曲线
+// 按一天中不同时刻出发分别规划 (分时速度、早晚高峰系数和公交/地铁的运营时间)，
+// 通勤用户可以看出什么时候出发用时最短
+
+// 采样参数的范围
+const (
+	defaultProfileInterval = 15 // 分钟
+	minProfileInterval     = 5
+	maxProfileInterval     = 120
+	maxProfileSamples      = 96
+)
+
+// PathProfileRequest 全天用时曲线请求：起终点和规划参数与路径规划相同 (depart_at 和 departure_times 不使用)
+type PathProfileRequest struct {
+	PathRequest
+	Date     string `json:"date,omitempty"`     // 日期 (YYYY-MM-DD，服务器时区)，默认今天
+	From     string `json:"from,omitempty"`     // 第一个出发时刻 (HH:MM)，默认 00:00
+	To       string `json:"to,omitempty"`       // 结束时刻 (HH:MM，不含)，默认 24:00
+	Interval int    `json:"interval,omitempty"` // 采样间隔 (分钟)，默认 15
+}
+
+// PathProfileResponse 全天用时曲线
+type PathProfileResponse struct {
+	Date     string            `json:"date"`
+	Interval int               `json:"interval"`
+	Samples  []DepartureOption `json:"samples"`         // 按出发时间排列
+	Best     *DepartureOption  `json:"best,omitempty"`  // 用时最短的出发时间 (相同时取较早的)
+	Worst    *DepartureOption  `json:"worst,omitempty"` // 用时最长的出发时间
+}
+
+// PathProfile 按一天中的多个出发时间规划同一起终点，返回每个时间出发的预计用时
+func PathProfile(c *gin.Context) {
+	var req PathProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.EV != nil || req.AsOf != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "全天用时曲线暂不支持电动车充电规划和历史回放")
+		return
+	}
+	times, ok := profileTimes(c, &req)
+	if !ok {
+		return
+	}
+	crs, ok := parseCRS(c, req.CRS)
+	if !ok {
+		return
+	}
+	req.toWGS84(crs)
+	req.DepartAt, req.DepartureTimes = nil, nil
+
+	plan, ok := preparePath(c, &req.PathRequest)
+	if !ok {
+		return
+	}
+	g := plan.graph
+	driving := plan.opts.ModeMask&(model.ModeCar|model.ModeTruck) != 0
+	parking := req.ParkNearDestination && driving && g.Nodes[plan.end.NodeID].Type != model.NodeTypeParking
+
+	samples := planDepartures(g, times, plan.start, plan.end, plan.opts, parking, req.ParkingRadius, plan.lang, plan.now)
+	resp := PathProfileResponse{Date: times[0].Format(time.DateOnly), Interval: req.Interval, Samples: samples}
+	for i := range samples {
+		s := &samples[i]
+		if !s.Found {
+			continue
+		}
+		if resp.Best == nil || s.EstimatedTime < resp.Best.EstimatedTime {
+			resp.Best = s
+		}
+		if resp.Worst == nil || s.EstimatedTime > resp.Worst.EstimatedTime {
+			resp.Worst = s
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// profileTimes 按日期、时间范围和间隔生成出发时间，参数错误时写入错误响应并返回 false
+func profileTimes(c *gin.Context, req *PathProfileRequest) ([]time.Time, bool) {
+	day := time.Now()
+	if req.Date != "" {
+		d, err := time.ParseInLocation(time.DateOnly, req.Date, time.Local)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "日期格式错误，应为 YYYY-MM-DD")
+			return nil, false
+		}
+		day = d
+	}
+
+	from, to := 0, 24*60
+	for _, clock := range []struct {
+		s   string
+		dst *int
+	}{{req.From, &from}, {req.To, &to}} {
+		if clock.s == "" {
+			continue
+		}
+		m, ok := parseClock(clock.s)
+		if !ok {
+			respondErrorDetails(c, http.StatusBadRequest, CodeInvalidRequest, "时刻格式错误，应为 HH:MM", gin.H{"value": clock.s})
+			return nil, false
+		}
+		*clock.dst = m
+	}
+	if from >= to {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "结束时刻必须晚于开始时刻")
+		return nil, false
+	}
+
+	if req.Interval == 0 {
+		req.Interval = defaultProfileInterval
+	}
+	if req.Interval < minProfileInterval || req.Interval > maxProfileInterval {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "采样间隔超出范围 (5 ~ 120 分钟)")
+		return nil, false
+	}
+	if (to-from+req.Interval-1)/req.Interval > maxProfileSamples {
+		respondError(c, http.StatusBadRequest, CodeOutOfRange, "采样点过多 (最多 96 个)，请增大间隔或缩小时间范围")
+		return nil, false
+	}
+
+	var times []time.Time
+	for m := from; m < to; m += req.Interval {
+		times = append(times, time.Date(day.Year(), day.Month(), day.Day(), m/60, m%60, 0, 0, time.Local))
+	}
+	return times, true
+}
+
+// parseClock 解析 HH:MM 格式的时刻 (00:00 ~ 24:00)，返回从 0 点开始的分钟数
+func parseClock(s string) (int, bool) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, false
+	}
+	hour, errH := strconv.Atoi(h)
+	minute, errM := strconv.Atoi(m)
+	if errH != nil || errM != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/config"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pathCacheKey 路径缓存的键，由地图版本、语言、用户 (决定出行偏好)、出发时间和请求参数决定
+// 未指定出发时间时按当前分钟计算，缓存时间不应超过一两分钟
+func pathCacheKey(c *gin.Context, req *PathRequest) (string, error) {
+	g := requestGraph(c)
+	departAt := time.Now().Truncate(time.Minute)
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+	body, err := json.Marshal(struct {
+		Version  string       `json:"v"`
+		Lang     string       `json:"l"`
+		UserID   uint         `json:"u"`
+		DepartAt time.Time    `json:"t"`
+		Weather  string       `json:"w,omitempty"`
+		Request  *PathRequest `json:"r"`
+	}{g.Version(), language(c), currentUserID(c), departAt, weatherCacheKey(c, req, departAt), req})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return "path:" + hex.EncodeToString(sum[:]), nil
+}
+
+// cachedPlanPath 先查共享缓存，没有时规划路径并写入缓存 (PATH_CACHE_TTL 为 0 时不缓存)
+// 响应头 X-Cache 为 HIT 或 MISS；缓存出错时直接规划
+func cachedPlanPath(c *gin.Context, req *PathRequest) (*PathResponse, bool) {
+	g := requestGraph(c)
+	ttl := config.GetDuration("PATH_CACHE_TTL", time.Minute)
+	if ttl <= 0 || g == nil {
+		return planPath(c, req)
+	}
+	key, err := pathCacheKey(c, req)
+	if err != nil {
+		return planPath(c, req)
+	}
+
+	ctx := c.Request.Context()
+	if data, ok, err := cache.Default.Get(ctx, key); err != nil {
+		slog.Error("读取路径缓存失败", "error", err)
+	} else if ok {
+		var resp PathResponse
+		if err := json.Unmarshal(data, &resp); err == nil {
+			c.Header("X-Cache", "HIT")
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", true))
+			slog.Debug("路径缓存命中", "key", key)
+			return &resp, true
+		}
+	}
+
+	resp, ok := planPath(c, req)
+	if !ok {
+		return nil, false
+	}
+	c.Header("X-Cache", "MISS")
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", false))
+	if resp.timedOut { // 超时的结果与服务器负载有关，不缓存
+		return resp, true
+	}
+	if data, err := json.Marshal(resp); err == nil {
+		if err := cache.Default.Set(ctx, key, data, ttl); err != nil {
+			slog.Error("写入路径缓存失败", "error", err)
+		}
+	}
+	return resp, true
+}
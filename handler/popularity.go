@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"traffic-system/model"
+	"traffic-system/popularity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchSelectRequest 上报在搜索结果中选中的节点
+type SearchSelectRequest struct {
+	NodeID string `json:"node_id" binding:"required"`
+	Query  string `json:"query" binding:"max=100"` // 选中时的搜索关键词
+}
+
+// SelectSearchResult 上报用户在搜索/自动补全结果中选中了哪个节点，用于统计节点热度
+func SelectSearchResult(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var req SearchSelectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if g.Nodes[req.NodeID] == nil {
+		respondError(c, http.StatusNotFound, CodeNodeNotFound, "节点不存在")
+		return
+	}
+
+	popularity.Record(req.NodeID, model.NodeEventSelect, req.Query)
+	c.JSON(http.StatusAccepted, gin.H{"message": tr(c, "已记录")})
+}
+
+// recordRouteEndpoints 记录路径规划指定的起终点 (只记录按节点 ID 指定的，坐标吸附的不算)
+func recordRouteEndpoints(req *PathRequest) {
+	if req.StartID != "" {
+		popularity.Record(req.StartID, model.NodeEventRouteStart, "")
+	}
+	if req.EndID != "" {
+		popularity.Record(req.EndID, model.NodeEventRouteEnd, "")
+	}
+}
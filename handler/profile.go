@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProfileResponse 用户资料响应
+type ProfileResponse struct {
+	Username      string             `json:"username"`
+	Email         string             `json:"email"`
+	EmailVerified bool               `json:"email_verified"`
+	Preferences   *model.UserProfile `json:"preferences"`
+}
+
+// UpdateProfileRequest 更新出行偏好请求 (未提供的字段保持不变)
+type UpdateProfileRequest struct {
+	DefaultModes   []string `json:"default_modes"`
+	WalkSpeed      *float64 `json:"walk_speed"`
+	AvoidHighways  *bool    `json:"avoid_highways"`
+	AvoidTransfers *bool    `json:"avoid_transfers"`
+	AvoidAlleys    *bool    `json:"avoid_alleys"`
+	AvoidStairs    *bool    `json:"avoid_stairs"`
+
+	Vehicle *model.Vehicle `json:"vehicle"` // 车辆信息 (整体替换)
+}
+
+// GetProfile 获取当前用户资料和出行偏好
+func GetProfile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var user model.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "用户不存在")
+		return
+	}
+
+	profile, err := loadUserProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileResponse{
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Preferences:   profile,
+	})
+}
+
+// UpdateProfile 更新当前用户的出行偏好
+func UpdateProfile(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "请求参数错误")
+		return
+	}
+
+	profile, err := loadUserProfile(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	if req.DefaultModes != nil {
+		for _, mode := range req.DefaultModes {
+			if model.GetModeMask(mode) == 0 {
+				respondError(c, http.StatusBadRequest, CodeModeInvalid, "无效的交通方式: "+mode)
+				return
+			}
+		}
+		profile.DefaultModes = req.DefaultModes
+	}
+	if req.WalkSpeed != nil {
+		speed := *req.WalkSpeed
+		if speed != 0 && (speed < model.MinWalkSpeed || speed > model.MaxWalkSpeed) {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "步行速度超出范围 (0.5 ~ 3.0 米/秒)")
+			return
+		}
+		profile.WalkSpeed = speed
+	}
+	if req.AvoidHighways != nil {
+		profile.AvoidHighways = *req.AvoidHighways
+	}
+	if req.AvoidTransfers != nil {
+		profile.AvoidTransfers = *req.AvoidTransfers
+	}
+	if req.AvoidAlleys != nil {
+		profile.AvoidAlleys = *req.AvoidAlleys
+	}
+	if req.AvoidStairs != nil {
+		profile.AvoidStairs = *req.AvoidStairs
+	}
+	if req.Vehicle != nil {
+		if err := validateVehicle(req.Vehicle); err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		profile.Vehicle = *req.Vehicle
+	}
+
+	if err := db.DB.Save(profile).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存偏好失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     tr(c, "偏好已更新"),
+		"preferences": profile,
+	})
+}
+
+// validateVehicle 检查车辆信息是否有效
+func validateVehicle(v *model.Vehicle) error {
+	if v.EmissionStandard < 0 || v.EmissionStandard > model.MaxEmissionStandard {
+		return errors.New("排放标准超出范围 (1 ~ 6，0 表示未知)")
+	}
+	if v.Height < 0 || v.Weight < 0 || v.Width < 0 {
+		return errors.New("车辆尺寸不能为负数")
+	}
+	return nil
+}
+
+// loadUserProfile 读取用户出行偏好，不存在时 (或无数据库模式下) 返回一个未保存的默认偏好
+func loadUserProfile(ctx context.Context, userID uint) (*model.UserProfile, error) {
+	if !db.Enabled() {
+		return &model.UserProfile{UserID: userID}, nil
+	}
+	var profile model.UserProfile
+	err := db.DB.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.UserProfile{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// currentUserID 获取当前请求的登录用户 ID
+// 公开接口没有经过 AuthMiddleware，此时尝试解析可选的 Token；未登录返回 0
+func currentUserID(c *gin.Context) uint {
+	if userID := c.GetUint("user_id"); userID != 0 {
+		return userID
+	}
+	if tokenString := extractToken(c); tokenString != "" {
+		if claims, err := authenticate(c.Request.Context(), tokenString); err == nil {
+			return claims.UserID
+		}
+	}
+	return 0
+}
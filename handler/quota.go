@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// usageDayLayout 每日用量的日期格式 (服务器时区)
+const usageDayLayout = "2006-01-02"
+
+// usageCounterTTL 当天计数在缓存中的保留时间 (跨过零点后仍可查询前一天)
+const usageCounterTTL = 48 * time.Hour
+
+// 用量查询的天数
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 90
+)
+
+// quotaExempt 不统计、也不受配额限制的接口 (用完配额后仍可查询用量)
+var quotaExempt = map[string]bool{
+	"/api/user/usage": true,
+}
+
+// usageKey 一个统计对象某一天的用量
+type usageKey struct {
+	subject string
+	id      uint
+	day     string
+}
+
+// pendingUsage 还未写入数据库的请求次数，由 StartUsageCounter 定期累加到 daily_usages 表
+var pendingUsage = struct {
+	sync.Mutex
+	counts map[usageKey]int64
+}{counts: make(map[usageKey]int64)}
+
+// UsageToday 当天的用量和配额
+type UsageToday struct {
+	Day       string    `json:"day"`
+	Requests  int64     `json:"requests"`
+	Quota     int64     `json:"quota"`               // 0 表示不限
+	Remaining *int64    `json:"remaining,omitempty"` // 不限时省略
+	ResetAt   time.Time `json:"reset_at"`            // 计数清零的时间 (服务器时区的下一个零点)
+}
+
+// UsageReport 用量查询结果
+type UsageReport struct {
+	Subject   string             `json:"subject"` // user 或 api_key
+	SubjectID uint               `json:"subject_id"`
+	Today     UsageToday         `json:"today"`
+	Days      []model.DailyUsage `json:"days"` // 按日期从新到旧，没有请求的日期省略
+	Total     int64              `json:"total"`
+}
+
+// QuotaMiddleware 统计 API Key 和登录用户每天的请求次数，超过每日配额时返回 429 (到零点前不再放行)
+// 配额：API Key 的 daily_quota (为 0 时使用 API_KEY_DAILY_QUOTA)，登录用户为 USER_DAILY_QUOTA，0 表示不限；
+// 计数保存在共享缓存中，缓存出错时放行。匿名请求不统计 (按 IP 限流)
+func QuotaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if quotaExempt[unversionedPath(c.FullPath())] {
+			c.Next()
+			return
+		}
+		subject, id, quota := quotaSubject(c)
+		if subject == "" {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		day := now.Format(usageDayLayout)
+		count, err := cache.Default.Incr(c.Request.Context(), usageCacheKey(subject, id, day), usageCounterTTL)
+		if err != nil {
+			slog.Error("用量计数失败", "error", err)
+			c.Next()
+			return
+		}
+
+		if quota > 0 {
+			reset := nextMidnight(now)
+			header := c.Writer.Header()
+			header.Set("X-Quota-Limit", strconv.FormatInt(quota, 10))
+			header.Set("X-Quota-Remaining", strconv.FormatInt(max(quota-count, 0), 10))
+			header.Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if count > quota {
+				retry := int(reset.Sub(now).Seconds()) + 1
+				header.Set("Retry-After", strconv.Itoa(retry))
+				respondErrorDetails(c, http.StatusTooManyRequests, CodeQuotaExceeded, "今日请求次数已达配额上限", gin.H{
+					"quota":       quota,
+					"reset_at":    reset,
+					"retry_after": retry,
+				})
+				return
+			}
+		}
+
+		pendingUsage.Lock()
+		pendingUsage.counts[usageKey{subject, id, day}]++
+		pendingUsage.Unlock()
+		c.Next()
+	}
+}
+
+// quotaSubject 当前请求按哪个对象统计用量及其每日配额：API Key 优先，其次是登录用户，匿名请求返回空
+func quotaSubject(c *gin.Context) (string, uint, int64) {
+	if key := currentAPIKey(c); key != nil {
+		quota := int64(key.DailyQuota)
+		if quota == 0 {
+			quota = int64(config.GetInt("API_KEY_DAILY_QUOTA", 0))
+		}
+		return model.UsageSubjectAPIKey, key.ID, quota
+	}
+	if userID := currentUserID(c); userID != 0 {
+		return model.UsageSubjectUser, userID, int64(config.GetInt("USER_DAILY_QUOTA", 0))
+	}
+	return "", 0, 0
+}
+
+// usageCacheKey 当天计数在缓存中的键
+func usageCacheKey(subject string, id uint, day string) string {
+	return fmt.Sprintf("usage:%s:%d:%s", subject, id, day)
+}
+
+// nextMidnight t 之后的下一个零点 (t 所在时区)
+func nextMidnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// StartUsageCounter 每隔 interval 把各个对象新增的请求次数累加到数据库
+func StartUsageCounter(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			FlushUsage()
+		}
+	}()
+}
+
+// FlushUsage 立即把未写入的请求次数累加到数据库 (关闭服务前调用)，失败时留到下次重试
+func FlushUsage() {
+	pendingUsage.Lock()
+	counts := pendingUsage.counts
+	pendingUsage.counts = make(map[usageKey]int64)
+	pendingUsage.Unlock()
+	if len(counts) == 0 || !db.Enabled() {
+		return // 无数据库模式下只在缓存中计数 (用于配额)
+	}
+
+	rows := make([]model.DailyUsage, 0, len(counts))
+	for k, n := range counts {
+		rows = append(rows, model.DailyUsage{Day: k.day, Subject: k.subject, SubjectID: k.id, Requests: n})
+	}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "subject"}, {Name: "subject_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":   gorm.Expr("daily_usages.requests + excluded.requests"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&rows).Error
+	if err == nil {
+		return
+	}
+
+	slog.Error("保存每日用量失败", "error", err)
+	pendingUsage.Lock()
+	for k, n := range counts {
+		pendingUsage.counts[k] += n
+	}
+	pendingUsage.Unlock()
+}
+
+// GetUsage 查询每日请求次数和配额 (登录用户查询自己的用量，使用 API Key 时查询该密钥的用量)
+// days 为查询的天数 (含今天)，默认 30，最多 90
+func GetUsage(c *gin.Context) {
+	days := defaultUsageDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUsageDays {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "days 超出范围 (1 ~ 90)")
+			return
+		}
+		days = n
+	}
+
+	subject, id, quota := quotaSubject(c)
+	if subject == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未提供 Token")
+		return
+	}
+
+	now := time.Now()
+	today := now.Format(usageDayLayout)
+	since := now.AddDate(0, 0, 1-days).Format(usageDayLayout)
+
+	var rows []model.DailyUsage
+	err := db.DB.Where("subject = ? AND subject_id = ? AND day >= ?", subject, id, since).
+		Order("day DESC").Find(&rows).Error
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	// 当天以缓存中的计数为准 (数据库中的数字定期更新)，超出配额后被拒绝的请求不计入
+	report := UsageReport{Subject: subject, SubjectID: id, Days: []model.DailyUsage{}}
+	report.Today = UsageToday{Day: today, Quota: quota, ResetAt: nextMidnight(now)}
+	for _, row := range rows {
+		if row.Day == today {
+			report.Today.Requests = row.Requests
+			continue
+		}
+		report.Days = append(report.Days, row)
+		report.Total += row.Requests
+	}
+	if v, ok, err := cache.Default.Get(c.Request.Context(), usageCacheKey(subject, id, today)); err == nil && ok {
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			report.Today.Requests = n
+		}
+	}
+	if quota > 0 {
+		report.Today.Requests = min(report.Today.Requests, quota)
+		remaining := quota - report.Today.Requests
+		report.Today.Remaining = &remaining
+	}
+	if report.Today.Requests > 0 {
+		report.Days = append([]model.DailyUsage{{Day: today, Subject: subject, SubjectID: id, Requests: report.Today.Requests, UpdatedAt: now}}, report.Days...)
+		report.Total += report.Today.Requests
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware 按客户端 IP 固定窗口限流：每个 window 内最多 limit 次请求，超过时返回 429
+// 计数保存在共享缓存中 (配置 Redis 时多个实例共用)；limit <= 0 表示不限流，缓存出错时放行
+// 使用 API Key 的请求已按密钥限流，不再按 IP 限流
+func RateLimitMiddleware(name string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || window <= 0 || currentAPIKey(c) != nil {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		start := now.Truncate(window)
+		key := fmt.Sprintf("rate:%s:%s:%d", name, c.ClientIP(), start.Unix())
+		count, err := cache.Default.Incr(c.Request.Context(), key, window)
+		if err != nil {
+			slog.Error("限流计数失败", "error", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		header.Set("X-RateLimit-Remaining", strconv.FormatInt(max(int64(limit)-count, 0), 10))
+		if count > int64(limit) {
+			retry := int(start.Add(window).Sub(now).Seconds()) + 1
+			header.Set("Retry-After", strconv.Itoa(retry))
+			respondErrorDetails(c, http.StatusTooManyRequests, CodeRateLimited, "请求过于频繁，请稍后再试", gin.H{"retry_after": retry})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/config"
+	"traffic-system/model"
+	"traffic-system/realtime"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VehicleFeedRequest 车辆位置上报请求 (一次可以上报多辆车)
+type VehicleFeedRequest struct {
+	Vehicles []realtime.VehiclePosition `json:"vehicles" binding:"required,dive"`
+}
+
+// IngestVehicles 接收车辆实时位置
+// 上报方需要在请求头 X-Feed-Token 中携带 REALTIME_FEED_TOKEN，未配置该变量时接口不可用
+func IngestVehicles(c *gin.Context) {
+	g := requestGraph(c)
+	expected := config.GetString("REALTIME_FEED_TOKEN", "")
+	if expected == "" {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "实时数据接入未启用")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Feed-Token")), []byte(expected)) != 1 {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的数据源令牌")
+		return
+	}
+
+	var req VehicleFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	accepted, ignored := 0, 0
+	for _, v := range req.Vehicles {
+		// 忽略未知线路和坐标无效的车辆
+		if (g != nil && g.Lines[v.LineID] == nil) || !utils.ValidCoordinate(v.Lat, v.Lng) {
+			ignored++
+			continue
+		}
+		if realtime.Vehicles.Update(v) {
+			accepted++
+		} else {
+			ignored++
+		}
+	}
+	realtime.Vehicles.Prune(time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"accepted": accepted,
+		"ignored":  ignored,
+	})
+}
+
+// GetLineVehicles 获取线路上车辆的实时位置
+func GetLineVehicles(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	lineID := c.Param("id")
+	if g.Lines[lineID] == nil {
+		respondError(c, http.StatusNotFound, CodeLineNotFound, "线路不存在")
+		return
+	}
+
+	vehicles := realtime.Vehicles.ByLine(lineID, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"line_id":  lineID,
+		"count":    len(vehicles),
+		"vehicles": vehicles,
+	})
+}
+
+// applyRealtime 用实时车辆数据修正公交/地铁上车段的等待时间，并标注线路晚点
+// segments 从 depart 时刻出发依次经过，now 用于判断车辆数据是否过期，返回预计总时间的变化量 (秒)
+func applyRealtime(g *algo.Graph, segments []PathSegment, depart, now time.Time) float64 {
+	if g == nil {
+		return 0
+	}
+
+	clock := depart
+	change := 0.0
+	for i := range segments {
+		seg := &segments[i]
+		if (seg.UsedMode == "bus" || seg.UsedMode == "subway") && seg.LineID != "" {
+			delay, ok := realtime.Vehicles.LineDelay(seg.LineID, now)
+			if ok {
+				seg.DelaySeconds = delay
+				// 同一线路的后续站点沿用上车段的结果
+				boarding := i == 0 || segments[i-1].UsedMode != seg.UsedMode || segments[i-1].LineID != seg.LineID
+				if boarding {
+					if wait, found := realtimeWait(g, seg.LineID, seg.FromID, clock, now); found {
+						diff := wait - model.GetModeWaitTime(seg.UsedMode)
+						seg.Time += diff
+						seg.WaitTime = wait
+						seg.Realtime = true
+						change += diff
+					}
+				} else {
+					seg.Realtime = segments[i-1].Realtime
+				}
+			}
+		}
+		clock = clock.Add(time.Duration(seg.Time * float64(time.Second)))
+	}
+	return change
+}
+
+// realtimeWait 按线路上车辆的实时位置估算在 stopID 站点 (arriveAt 时刻到站) 的等待时间 (秒)
+// 取到达该站时间不早于 arriveAt 的最近一辆车；没有合适的车辆时返回 false
+func realtimeWait(g *algo.Graph, lineID, stopID string, arriveAt, now time.Time) (float64, bool) {
+	line := g.Lines[lineID]
+	if line == nil {
+		return 0, false
+	}
+	stopOffset, ok := g.StopOffset(lineID, stopID)
+	if !ok {
+		return 0, false
+	}
+	speed := model.GetModeSpeed(line.Mode)
+
+	best := math.Inf(1)
+	for _, v := range realtime.Vehicles.ByLine(lineID, now) {
+		nextID := v.NextStopID
+		if _, onLine := g.StopOffset(lineID, nextID); !onLine {
+			nextID = nearestLineStop(g, line, v.Lat, v.Lng)
+		}
+		nextOffset, _ := g.StopOffset(lineID, nextID)
+		if nextOffset > stopOffset {
+			continue // 车辆已经驶过该站
+		}
+
+		// 车辆到达下一站的时间 + 下一站到上车站的行驶时间
+		eta := v.Timestamp
+		if next := g.Nodes[nextID]; next != nil {
+			dist := utils.HaversineDistance(model.Point{Lat: v.Lat, Lng: v.Lng}, model.Point{Lat: next.Lat, Lng: next.Lng})
+			eta = eta.Add(time.Duration(dist / speed * float64(time.Second)))
+		}
+		eta = eta.Add(time.Duration((stopOffset - nextOffset) * float64(time.Second)))
+
+		if wait := eta.Sub(arriveAt).Seconds(); wait >= 0 && wait < best {
+			best = wait
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0, false
+	}
+	return best, true
+}
+
+// nearestLineStop 离给定坐标最近的线路站点 (车辆未上报下一站时近似作为其下一站)
+func nearestLineStop(g *algo.Graph, line *model.Line, lat, lng float64) string {
+	target := model.Point{Lat: lat, Lng: lng}
+	nearest, minDist := "", math.Inf(1)
+	for _, stop := range line.Stops {
+		node := g.Nodes[stop.NodeID]
+		if node == nil {
+			continue
+		}
+		if d := utils.HaversineDistance(target, model.Point{Lat: node.Lat, Lng: node.Lng}); d < minDist {
+			nearest, minDist = stop.NodeID, d
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"traffic-system/crash"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sensitiveHeaders 上报 panic 时不包含的请求头
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+}
+
+// RecoveryMiddleware 恢复处理请求时的 panic (如路网未加载时的空指针)：记录调用栈并上报 (见 crash 包)，
+// 返回统一格式的 500 错误响应，details 中的 event_id 用于对照日志和 Sentry 中的事件
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r) // net/http 用于中止响应的约定，交给 http.Server 处理
+			}
+			if brokenPipe(r) {
+				// 客户端已断开，不需要也无法写入响应
+				slog.Warn("客户端连接已断开", "path", c.Request.URL.Path, "error", r)
+				c.Abort()
+				return
+			}
+
+			report := crash.Capture(r)
+			report.Source = "http"
+			report.Method = c.Request.Method
+			report.URL = c.Request.URL.String()
+			report.Route = c.FullPath()
+			report.UserID = c.GetUint("user_id")
+			report.Headers = make(map[string]string)
+			for name, values := range c.Request.Header {
+				if !sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+					report.Headers[name] = strings.Join(values, ", ")
+				}
+			}
+			span := trace.SpanFromContext(c.Request.Context())
+			if sc := span.SpanContext(); sc.IsValid() {
+				report.TraceID, report.SpanID = sc.TraceID().String(), sc.SpanID().String()
+			}
+			span.RecordError(fmt.Errorf("panic: %v", r))
+			span.SetStatus(codes.Error, "panic")
+			crash.Send(report)
+
+			if c.Writer.Written() {
+				c.Abort() // 响应已经开始写入，只能中止
+				return
+			}
+			respondErrorDetails(c, http.StatusInternalServerError, CodeInternalError, "服务器内部错误", gin.H{"event_id": report.ID})
+		}()
+		c.Next()
+	}
+}
+
+// brokenPipe panic 是否由客户端断开连接引起 (写入响应时的 broken pipe / connection reset)
+func brokenPipe(r any) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var sysErr *os.SyscallError
+	if !errors.As(opErr, &sysErr) {
+		return false
+	}
+	msg := strings.ToLower(sysErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
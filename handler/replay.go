@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"sync"
+	"time"
+	"traffic-system/model"
+	"traffic-system/speeds"
+)
+
+// maxReplayDays 缓存的历史日期数 (超过时清空重新缓存)
+const maxReplayDays = 31
+
+// ReplayInfo 历史回放使用的数据
+type ReplayInfo struct {
+	Date    string `json:"date"`    // 回放的日期
+	Records int    `json:"records"` // 当天匹配到路网的 (路段, 方式, 小时) 速度记录数，没有记录的路段使用学习速度和高峰系数
+}
+
+// replayDays 已统计的历史日期的路段速度 (日期开始时刻 -> 速度)
+// 过去的日期不会再有新的行程，统计一次即可；当天的数据还在增加，不缓存
+var replayDays = struct {
+	sync.Mutex
+	rows map[time.Time][]model.EdgeSpeed
+}{rows: make(map[time.Time][]model.EdgeSpeed)}
+
+// replaySpeeds 读取 asOf 所在那一天 (本地时间) 的路段分时速度
+func replaySpeeds(asOf time.Time) ([]model.EdgeSpeed, error) {
+	asOf = asOf.Local()
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.Local)
+	final := time.Now().After(day.AddDate(0, 0, 1))
+
+	replayDays.Lock()
+	rows, ok := replayDays.rows[day]
+	replayDays.Unlock()
+	if ok {
+		return rows, nil
+	}
+
+	rows, err := speeds.Day(day)
+	if err != nil {
+		return nil, err
+	}
+	if final {
+		replayDays.Lock()
+		if len(replayDays.rows) >= maxReplayDays {
+			clear(replayDays.rows)
+		}
+		replayDays.rows[day] = rows
+		replayDays.Unlock()
+	}
+	return rows, nil
+}
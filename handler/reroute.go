@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/cache"
+	"traffic-system/config"
+	"traffic-system/i18n"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeIDLength 路线 ID 的长度
+const routeIDLength = 12
+
+// storedRoute 保存在共享缓存中的路线 (供导航客户端偏离路线后重新规划)
+type storedRoute struct {
+	Request PathRequest   `json:"request"` // 已填充用户偏好的请求参数
+	Route   *PathResponse `json:"route"`
+}
+
+// RerouteRequest 重新规划请求
+type RerouteRequest struct {
+	RouteID string  `json:"route_id" binding:"required"`
+	Lat     float64 `json:"lat" binding:"required"` // 当前位置
+	Lng     float64 `json:"lng" binding:"required"`
+	CRS     string  `json:"crs,omitempty"` // 当前位置和返回路线的坐标系 (wgs84 或 gcj02)
+}
+
+// RerouteResponse 重新规划结果
+type RerouteResponse struct {
+	RouteID   string        `json:"route_id,omitempty"` // 仍在原路线上时不变，重新规划后为新路线的 ID
+	OnRoute   bool          `json:"on_route"`           // 当前位置是否仍在原路线上
+	Deviation float64       `json:"deviation"`          // 当前位置到原路线的距离 (米)
+	LeftAt    string        `json:"left_at,omitempty"`  // 偏离路线时，离开原路线前经过的最后一个节点
+	ArriveAt  time.Time     `json:"arrive_at"`          // 按剩余时间计算的预计到达时间
+	Route     *PathResponse `json:"route"`              // 从当前位置到终点的路线
+}
+
+// routeKey 路线在共享缓存中的键
+func routeKey(id string) string {
+	return "route:" + id
+}
+
+// saveRoute 保存规划结果并返回路线 ID (保存 ROUTE_TTL，默认 2 小时)，失败时返回空字符串
+func saveRoute(ctx context.Context, req *PathRequest, resp *PathResponse) string {
+	id, err := utils.GenerateShortCode(routeIDLength)
+	if err != nil {
+		return ""
+	}
+	data, err := json.Marshal(storedRoute{Request: *req, Route: resp})
+	if err != nil {
+		return ""
+	}
+	ttl := config.GetDuration("ROUTE_TTL", 2*time.Hour)
+	if err := cache.Default.Set(ctx, routeKey(id), data, ttl); err != nil {
+		slog.Error("保存路线失败", "error", err)
+		return ""
+	}
+	return id
+}
+
+// loadRoute 读取保存的路线，不存在或已过期时返回 nil
+func loadRoute(ctx context.Context, id string) (*storedRoute, error) {
+	data, ok, err := cache.Default.Get(ctx, routeKey(id))
+	if err != nil || !ok {
+		return nil, err
+	}
+	var route storedRoute
+	if err := json.Unmarshal(data, &route); err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+// Reroute 导航中按当前位置更新路线：仍在原路线上时直接截取剩余部分 (不重新搜索)，
+// 偏离超过 REROUTE_DEVIATION (默认 50 米) 时从当前位置出发，按原请求的参数重新规划到终点
+func Reroute(c *gin.Context) {
+	var req RerouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !checkCoordinates(c, model.Point{Lat: req.Lat, Lng: req.Lng}) {
+		return
+	}
+	crs, ok := parseCRS(c, req.CRS)
+	if !ok {
+		return
+	}
+	req.Lat, req.Lng = latLng(crs.ToWGS84(model.Point{Lat: req.Lat, Lng: req.Lng}))
+
+	ctx := c.Request.Context()
+	stored, err := loadRoute(ctx, req.RouteID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "读取路线失败")
+		return
+	}
+	if stored == nil || stored.Route == nil || len(stored.Route.Segments) == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "路线不存在或已过期")
+		return
+	}
+
+	now := time.Now()
+	lang := language(c)
+	pos := model.Point{Lat: req.Lat, Lng: req.Lng}
+	seg, fraction, deviation := locateOnRoute(stored.Route, pos)
+	if deviation < 0 {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "路线数据损坏")
+		return
+	}
+
+	if deviation <= config.GetFloat("REROUTE_DEVIATION", 50) {
+		route := remainingRoute(requestGraph(c), stored.Route, seg, fraction, lang, &stored.Request)
+		route.Message = tr(c, "仍在原路线上")
+		route.inCRS(crs)
+		c.JSON(http.StatusOK, RerouteResponse{
+			RouteID:   req.RouteID,
+			OnRoute:   true,
+			Deviation: deviation,
+			ArriveAt:  now.Add(time.Duration(route.EstimatedTime * float64(time.Second))),
+			Route:     route,
+		}.versioned(c, stored.Request.Units))
+		return
+	}
+
+	// 从当前位置出发重新规划 (出发时间为当前时间)
+	next := stored.Request
+	next.StartID, next.StartLat, next.StartLng = "", req.Lat, req.Lng
+	next.DepartAt, next.AsOf, next.DepartureTimes = nil, nil, nil
+	route, ok := planPath(c, &next)
+	if !ok {
+		return
+	}
+	resp := RerouteResponse{
+		OnRoute:   false,
+		Deviation: deviation,
+		LeftAt:    stored.Route.Segments[seg].FromID,
+		ArriveAt:  now.Add(time.Duration(route.EstimatedTime * float64(time.Second))),
+		Route:     route,
+	}
+	if route.Found {
+		resp.RouteID = saveRoute(ctx, &next, route)
+	}
+	route.inCRS(crs)
+	c.JSON(http.StatusOK, resp.versioned(c, next.Units))
+}
+
+// locateOnRoute 找到离 pos 最近的路径段，返回段下标、投影点在段上的位置 (0 ~ 1) 和 pos 到路线的距离 (米)
+// 距离相同时取靠前的路径段；路径中没有可用的坐标时距离为 -1
+func locateOnRoute(route *PathResponse, pos model.Point) (seg int, fraction, deviation float64) {
+	deviation = -1
+	for i := range route.Segments {
+		a, okA := routePoint(route, i)
+		b, okB := routePoint(route, i+1)
+		if !okA || !okB {
+			continue
+		}
+		_, t, d := utils.ProjectToSegment(pos, a, b)
+		if deviation < 0 || d < deviation {
+			seg, fraction, deviation = i, t, d
+		}
+	}
+	return seg, fraction, deviation
+}
+
+// routePoint 路径中第 i 个节点的坐标
+func routePoint(route *PathResponse, i int) (model.Point, bool) {
+	if i < 0 || i >= len(route.Path) {
+		return model.Point{}, false
+	}
+	return model.Point{Lat: route.Path[i].Lat, Lng: route.Path[i].Lng}, true
+}
+
+// remainingRoute 截取原路线从第 seg 段 fraction 处到终点的部分
+// 所在路径段按剩余比例计算距离和时间 (等待时间视为已经过去)，之后的路径段保持不变
+func remainingRoute(g *algo.Graph, route *PathResponse, seg int, fraction float64, lang string, req *PathRequest) *PathResponse {
+	a, _ := routePoint(route, seg)
+	b, _ := routePoint(route, seg+1)
+	current := PathNode{
+		ID:    algo.VirtualStartID,
+		Name:  i18n.T(lang, "当前位置"),
+		Lat:   a.Lat + (b.Lat-a.Lat)*fraction,
+		Lng:   a.Lng + (b.Lng-a.Lng)*fraction,
+		Type:  "virtual",
+		Level: route.Path[seg].Level,
+	}
+
+	first := route.Segments[seg]
+	rest := 1 - fraction
+	first.FromID, first.FromName = current.ID, current.Name
+	first.Distance *= rest
+	first.Time = (first.Time - first.WaitTime) * rest
+	first.WaitTime, first.Fee = 0, 0
+
+	segments := append([]PathSegment{first}, route.Segments[seg+1:]...)
+	path := append([]PathNode{current}, route.Path[seg+1:]...)
+
+	var distance, estimated, fee float64
+	for _, s := range segments {
+		distance += s.Distance
+		estimated += s.Time
+		fee += s.Fee
+	}
+	legs := buildLegs(segments, lang, req.Units)
+	resp := &PathResponse{
+		Found:         true,
+		Fingerprint:   routeFingerprint(segments),
+		Path:          path,
+		Segments:      segments,
+		Legs:          legs,
+		Geometry:      buildGeometry(path, req.Simplify, req.Zoom),
+		Transfers:     buildTransfers(g, legs),
+		Distance:      distance,
+		EstimatedTime: estimated,
+		DistanceText:  i18n.FormatDistance(lang, req.Units, distance),
+		DurationText:  i18n.FormatDuration(lang, estimated),
+		Fee:           fee,
+	}
+	resp.summarize()
+	return resp
+}
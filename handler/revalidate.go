@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevalidateRequest 路线复核请求
+type RevalidateRequest struct {
+	RouteID string `json:"route_id" binding:"required"`
+}
+
+// RevalidateResponse 路线复核结果
+type RevalidateResponse struct {
+	RouteID         string        `json:"route_id,omitempty"`         // 仍是最优路线时不变，否则为新路线的 ID
+	Fingerprint     string        `json:"fingerprint"`                // 原路线的指纹
+	Valid           bool          `json:"valid"`                      // 原路线经过的路段在当前地图中是否都还能按原方式通行
+	InvalidSegments []int         `json:"invalid_segments,omitempty"` // 不能再通行的路径段下标
+	Optimal         bool          `json:"optimal"`                    // 按当前路况重新规划的结果是否仍是原路线
+	TimeSaved       float64       `json:"time_saved,omitempty"`       // 新路线比原路线规划时的预计时间少多少秒 (可能为负)
+	MapVersion      string        `json:"map_version"`
+	Route           *PathResponse `json:"route,omitempty"` // 原路线失效或不再最优时返回的新路线
+	Message         string        `json:"message"`
+}
+
+// routeFingerprint 路线的指纹：按顺序对每段的起终点、使用的交通方式和线路取哈希
+// 经过的路段和方式相同的路线指纹相同，与语言、单位制和预计时间无关
+func routeFingerprint(segments []PathSegment) string {
+	h := sha256.New()
+	for _, seg := range segments {
+		h.Write([]byte(seg.FromID + "|" + seg.ToID + "|" + seg.UsedMode + "|" + seg.LineID + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Revalidate 复核之前规划的路线：检查原路线在当前地图中是否仍可通行，并按当前时间和路况重新规划，
+// 结果与原路线相同时返回 optimal=true，否则返回新路线 (新路线同样保存，可用于导航和再次复核)
+func Revalidate(c *gin.Context) {
+	g := requestGraph(c)
+	var req RevalidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored, err := loadRoute(ctx, req.RouteID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "读取路线失败")
+		return
+	}
+	if stored == nil || stored.Route == nil || len(stored.Route.Segments) == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "路线不存在或已过期")
+		return
+	}
+
+	// 出发时间已过的按当前时间重新规划
+	next := stored.Request
+	if next.DepartAt != nil && next.DepartAt.Before(time.Now()) {
+		next.DepartAt = nil
+	}
+	next.AsOf, next.DepartureTimes = nil, nil
+
+	old := stored.Route
+	resp := RevalidateResponse{
+		Fingerprint:     routeFingerprint(old.Segments),
+		InvalidSegments: invalidSegments(g, old.Segments, next.Overlay),
+		MapVersion:      g.Version(),
+	}
+	resp.Valid = len(resp.InvalidSegments) == 0
+
+	route, ok := planPath(c, &next)
+	if !ok {
+		return
+	}
+	resp.Optimal = resp.Valid && route.Found && route.Fingerprint == resp.Fingerprint
+
+	switch {
+	case resp.Optimal:
+		resp.RouteID = req.RouteID
+		resp.Message = tr(c, "原路线仍是最优路线")
+	case !route.Found:
+		resp.Route = route
+		resp.Message = tr(c, "原路线已失效，且未找到新的路线")
+		if resp.Valid {
+			resp.Message = tr(c, "按当前路况未找到路线")
+		}
+	default:
+		resp.RouteID = saveRoute(ctx, &next, route)
+		resp.Route = route
+		resp.TimeSaved = old.EstimatedTime - route.EstimatedTime
+		resp.Message = tr(c, "已有更合适的路线")
+		if !resp.Valid {
+			resp.Message = tr(c, "原路线部分路段已无法通行，已重新规划")
+		}
+	}
+	c.JSON(http.StatusOK, resp.versioned(c, next.Units))
+}
+
+// invalidSegments 找出原路线中在当前地图 (及原请求的临时路段) 里已不存在、或不再支持原交通方式的路径段
+// 吸附到道路上的起终点所在的部分路段 (虚拟节点) 不检查
+func invalidSegments(g *algo.Graph, segments []PathSegment, overlay *PathOverlay) []int {
+	var layer *algo.Overlay
+	if overlay != nil {
+		layer, _ = g.ApplyEdit(nil, algo.MapEdit{AddNodes: overlay.Nodes, AddEdges: overlay.Edges})
+	}
+
+	var invalid []int
+	for i, seg := range segments {
+		if strings.HasPrefix(seg.FromID, "@") || strings.HasPrefix(seg.ToID, "@") {
+			continue
+		}
+		found := false
+		for _, edge := range g.NeighborsIn(layer, seg.FromID, model.GetModeMask(seg.UsedMode)) {
+			if edge.To == seg.ToID && edge.LineID == seg.LineID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			invalid = append(invalid, i)
+		}
+	}
+	return invalid
+}
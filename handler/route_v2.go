@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"traffic-system/algo"
+	"traffic-system/i18n"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// v2 路线格式 (/api/v2)
+// 结构与 Google / 高德 Directions API 相同：legs 为同一交通方式、同一线路的连续行程，每个 leg 的 steps 为逐段详情，
+// 距离和时间为 {value, text}，位置为 {lat, lng}，客户端已有的路线渲染代码可以直接复用。
+// 内部仍按 PathResponse 规划、缓存和保存路线，只在返回时转换，同一条路线可以用两种格式读取
+
+// TextValue 数值及其格式化文字 (按语言和单位制)
+type TextValue struct {
+	Value float64 `json:"value"` // 距离为米，时间为秒
+	Text  string  `json:"text"`
+}
+
+// RouteV2 v2 的路线
+type RouteV2 struct {
+	Found         bool              `json:"found"`
+	RouteID       string            `json:"route_id,omitempty"`
+	Fingerprint   string            `json:"fingerprint,omitempty"`
+	StartName     string            `json:"start_name,omitempty"`
+	EndName       string            `json:"end_name,omitempty"`
+	StartLocation *model.Point      `json:"start_location,omitempty"`
+	EndLocation   *model.Point      `json:"end_location,omitempty"`
+	Bounds        *algo.BoundingBox `json:"bounds,omitempty"`
+	Distance      *TextValue        `json:"distance,omitempty"`
+	Duration      *TextValue        `json:"duration,omitempty"`
+	Fee           float64           `json:"fee,omitempty"`
+	ModesUsed     []string          `json:"modes_used,omitempty"`
+	Legs          []LegV2           `json:"legs,omitempty"`
+	Transfers     []Transfer        `json:"transfers,omitempty"`
+	// 整条路线的坐标 [[lat, lng], ...]，指定 simplify 或 zoom 时为简化后的坐标
+	OverviewPolyline [][2]float64 `json:"overview_polyline,omitempty"`
+
+	SnappedStart *SnapInfo    `json:"snapped_start,omitempty"`
+	SnappedEnd   *SnapInfo    `json:"snapped_end,omitempty"`
+	Parking      *ParkingInfo `json:"parking,omitempty"`
+	ChargeStops  []ChargeStop `json:"charge_stops,omitempty"`
+	FinalCharge  *float64     `json:"final_charge,omitempty"`
+	Replay       *ReplayInfo  `json:"replay,omitempty"`
+	Approximate  bool         `json:"approximate,omitempty"`
+	Message      string       `json:"message,omitempty"`
+
+	Departures []DepartureOption `json:"departures,omitempty"`
+	Weather    *WeatherAdvisory  `json:"weather_advisory,omitempty"`
+}
+
+// LegV2 一段行程 (同一交通方式、同一线路)
+type LegV2 struct {
+	TravelMode    string      `json:"travel_mode"`
+	LineID        string      `json:"line_id,omitempty"`
+	StartID       string      `json:"start_id"`
+	StartName     string      `json:"start_name"`
+	EndID         string      `json:"end_id"`
+	EndName       string      `json:"end_name"`
+	StartLocation model.Point `json:"start_location"`
+	EndLocation   model.Point `json:"end_location"`
+	Distance      TextValue   `json:"distance"`
+	Duration      TextValue   `json:"duration"`
+	NumStops      int         `json:"num_stops,omitempty"` // 经过的站数 (公交/地铁)
+	Instruction   string      `json:"instruction"`
+	DelaySeconds  int         `json:"delay_seconds,omitempty"`
+	Realtime      bool        `json:"realtime,omitempty"`
+	Ascent        float64     `json:"ascent,omitempty"`
+	Descent       float64     `json:"descent,omitempty"`
+	StairSteps    int         `json:"stair_steps,omitempty"`
+	Steps         []StepV2    `json:"steps"`
+}
+
+// StepV2 行程中的一个路段
+type StepV2 struct {
+	TravelMode     string       `json:"travel_mode"`
+	LineID         string       `json:"line_id,omitempty"`
+	StartID        string       `json:"start_id"`
+	StartName      string       `json:"start_name"`
+	EndID          string       `json:"end_id"`
+	EndName        string       `json:"end_name"`
+	StartLocation  model.Point  `json:"start_location"`
+	EndLocation    model.Point  `json:"end_location"`
+	Distance       TextValue    `json:"distance"`
+	Duration       TextValue    `json:"duration"`
+	Instruction    string       `json:"instruction,omitempty"`
+	AvailableModes []string     `json:"available_modes,omitempty"` // 路段上可用的交通方式
+	Fee            float64      `json:"fee,omitempty"`
+	Footway        string       `json:"footway,omitempty"`
+	Connector      string       `json:"connector,omitempty"`
+	StartLevel     int          `json:"start_level,omitempty"`
+	EndLevel       int          `json:"end_level,omitempty"`
+	WaitTime       float64      `json:"wait_time,omitempty"`
+	DelaySeconds   int          `json:"delay_seconds,omitempty"`
+	Realtime       bool         `json:"realtime,omitempty"`
+	Polyline       [][2]float64 `json:"polyline"` // 路段坐标 [[lat, lng], ...]
+}
+
+// buildRouteV2 把路线转换为 v2 格式，距离和时间的文字使用指定语言和单位制
+func buildRouteV2(resp *PathResponse, lang, units string) *RouteV2 {
+	if resp == nil {
+		return nil
+	}
+	route := &RouteV2{
+		Found:        resp.Found,
+		RouteID:      resp.RouteID,
+		Fingerprint:  resp.Fingerprint,
+		StartName:    resp.StartName,
+		EndName:      resp.EndName,
+		Bounds:       resp.Bounds,
+		Fee:          resp.Fee,
+		ModesUsed:    resp.ModesUsed,
+		Transfers:    resp.Transfers,
+		SnappedStart: resp.SnappedStart,
+		SnappedEnd:   resp.SnappedEnd,
+		Parking:      resp.Parking,
+		ChargeStops:  resp.ChargeStops,
+		FinalCharge:  resp.FinalCharge,
+		Replay:       resp.Replay,
+		Approximate:  resp.Approximate,
+		Message:      resp.Message,
+		Departures:   resp.Departures,
+		Weather:      resp.Weather,
+	}
+	if !resp.Found {
+		return route
+	}
+	route.Distance = &TextValue{Value: resp.Distance, Text: resp.DistanceText}
+	route.Duration = &TextValue{Value: resp.EstimatedTime, Text: resp.DurationText}
+	if start, ok := routePoint(resp, 0); ok {
+		end, _ := routePoint(resp, len(resp.Path)-1)
+		route.StartLocation, route.EndLocation = &start, &end
+	}
+
+	// 第 i 个路径段从 Path[i] 到 Path[i+1]
+	i := 0
+	route.Legs = make([]LegV2, 0, len(resp.Legs))
+	for _, leg := range resp.Legs {
+		v := LegV2{
+			TravelMode:   leg.Mode,
+			LineID:       leg.LineID,
+			StartID:      leg.FromID,
+			StartName:    leg.FromName,
+			EndID:        leg.ToID,
+			EndName:      leg.ToName,
+			Distance:     TextValue{Value: leg.Distance, Text: leg.DistanceText},
+			Duration:     TextValue{Value: leg.Time, Text: leg.DurationText},
+			Instruction:  leg.Instruction,
+			DelaySeconds: leg.DelaySeconds,
+			Realtime:     leg.Realtime,
+			Ascent:       leg.Ascent,
+			Descent:      leg.Descent,
+			StairSteps:   leg.StairSteps,
+			Steps:        make([]StepV2, 0, len(leg.Steps)),
+		}
+		if leg.LineID != "" {
+			v.NumStops = leg.Stops
+		}
+		v.StartLocation, _ = routePoint(resp, i)
+		for _, seg := range leg.Steps {
+			from, _ := routePoint(resp, i)
+			to, _ := routePoint(resp, i+1)
+			v.Steps = append(v.Steps, StepV2{
+				TravelMode:     seg.UsedMode,
+				LineID:         seg.LineID,
+				StartID:        seg.FromID,
+				StartName:      seg.FromName,
+				EndID:          seg.ToID,
+				EndName:        seg.ToName,
+				StartLocation:  from,
+				EndLocation:    to,
+				Distance:       TextValue{Value: seg.Distance, Text: i18n.FormatDistance(lang, units, seg.Distance)},
+				Duration:       TextValue{Value: seg.Time, Text: i18n.FormatDuration(lang, seg.Time)},
+				Instruction:    seg.Desc,
+				AvailableModes: seg.Modes,
+				Fee:            seg.Fee,
+				Footway:        seg.Footway,
+				Connector:      seg.Connector,
+				StartLevel:     seg.FromLevel,
+				EndLevel:       seg.ToLevel,
+				WaitTime:       seg.WaitTime,
+				DelaySeconds:   seg.DelaySeconds,
+				Realtime:       seg.Realtime,
+				Polyline:       [][2]float64{{from.Lat, from.Lng}, {to.Lat, to.Lng}},
+			})
+			i++
+		}
+		v.EndLocation, _ = routePoint(resp, i)
+		route.Legs = append(route.Legs, v)
+	}
+
+	route.OverviewPolyline = resp.Geometry
+	if route.OverviewPolyline == nil {
+		route.OverviewPolyline = make([][2]float64, len(resp.Path))
+		for j, node := range resp.Path {
+			route.OverviewPolyline[j] = [2]float64{node.Lat, node.Lng}
+		}
+	}
+	return route
+}
+
+// versionedRoute 按请求的接口版本返回路线：v1 原样返回，v2 转换为 legs -> steps 结构
+func versionedRoute(c *gin.Context, resp *PathResponse, units string) any {
+	if apiVersion(c) < 2 {
+		return resp
+	}
+	return buildRouteV2(resp, language(c), units)
+}
+
+// RerouteResponseV2 v2 的重新规划结果 (route 为 v2 格式)
+type RerouteResponseV2 struct {
+	RerouteResponse
+	Route *RouteV2 `json:"route"`
+}
+
+// versioned 按请求的接口版本返回重新规划结果
+func (r RerouteResponse) versioned(c *gin.Context, units string) any {
+	if apiVersion(c) < 2 {
+		return r
+	}
+	return RerouteResponseV2{RerouteResponse: r, Route: buildRouteV2(r.Route, language(c), units)}
+}
+
+// RevalidateResponseV2 v2 的路线复核结果 (route 为 v2 格式)
+type RevalidateResponseV2 struct {
+	RevalidateResponse
+	Route *RouteV2 `json:"route,omitempty"`
+}
+
+// versioned 按请求的接口版本返回路线复核结果
+func (r RevalidateResponse) versioned(c *gin.Context, units string) any {
+	if apiVersion(c) < 2 {
+		return r
+	}
+	return RevalidateResponseV2{RevalidateResponse: r, Route: buildRouteV2(r.Route, language(c), units)}
+}
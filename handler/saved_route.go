@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/i18n"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每个用户最多收藏的路线数
+const maxSavedRoutesPerUser = 50
+
+// BundleFormat 离线包格式版本，格式不兼容时加一
+const BundleFormat = 1
+
+// SaveRouteRequest 收藏路线请求
+// 指定 route_id 时收藏之前规划的路线 (来自 /api/path/find)，否则按其余参数 (与路径规划接口相同) 重新规划后收藏
+type SaveRouteRequest struct {
+	PathRequest
+	Name    string `json:"name" binding:"required,max=100"`
+	RouteID string `json:"route_id,omitempty"`
+}
+
+// OfflineBundle 路线离线包：包含离线导航所需的全部数据 (节点、完整坐标、文字说明、所乘线路)
+type OfflineBundle struct {
+	Format        int                 `json:"format"`
+	ID            uint                `json:"id"`
+	Name          string              `json:"name"`
+	SavedAt       time.Time           `json:"saved_at"`
+	GeneratedAt   time.Time           `json:"generated_at"`
+	MapVersion    string              `json:"map_version"` // 保存时的地图版本
+	Fingerprint   string              `json:"fingerprint"`
+	Bounds        algo.BoundingBox    `json:"bounds"` // 路线范围 (用于预先下载地图瓦片)
+	Distance      float64             `json:"distance"`
+	EstimatedTime float64             `json:"estimated_time"`
+	DistanceText  string              `json:"distance_text"`
+	DurationText  string              `json:"duration_text"`
+	Nodes         []PathNode          `json:"nodes"`    // 按经过顺序排列的节点
+	Geometry      [][2]float64        `json:"geometry"` // 未简化的路线坐标 [[lat, lng], ...]
+	Instructions  []BundleInstruction `json:"instructions"`
+	Lines         []model.Line        `json:"lines,omitempty"` // 所乘的公交/地铁线路 (不含站点列表)
+	Segments      []PathSegment       `json:"segments"`
+	Request       *PathRequest        `json:"request,omitempty"` // 规划参数 (联网后可用于重新规划)
+}
+
+// BundleInstruction 离线包中的一条文字说明 (对应一个行程段)
+type BundleInstruction struct {
+	Mode         string  `json:"mode"`
+	LineID       string  `json:"line_id,omitempty"`
+	FromID       string  `json:"from_id"`
+	ToID         string  `json:"to_id"`
+	Distance     float64 `json:"distance"`
+	Time         float64 `json:"time"`
+	DistanceText string  `json:"distance_text"`
+	DurationText string  `json:"duration_text"`
+	Text         string  `json:"text"`
+}
+
+// SaveRoute 收藏一条路线
+func SaveRoute(c *gin.Context) {
+	g := requestGraph(c)
+	var req SaveRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var count int64
+	db.DB.Model(&model.SavedRoute{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxSavedRoutesPerUser {
+		respondError(c, http.StatusConflict, CodeConflict, "收藏的路线数量已达上限")
+		return
+	}
+
+	params, route := &req.PathRequest, (*PathResponse)(nil)
+	if req.RouteID != "" {
+		stored, err := loadRoute(c.Request.Context(), req.RouteID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternalError, "读取路线失败")
+			return
+		}
+		if stored == nil || stored.Route == nil {
+			respondError(c, http.StatusNotFound, CodeNotFound, "路线不存在或已过期")
+			return
+		}
+		params, route = &stored.Request, stored.Route
+	} else {
+		var ok bool
+		if route, ok = planPath(c, params); !ok {
+			return
+		}
+	}
+	if !route.Found || len(route.Path) == 0 {
+		respondError(c, http.StatusUnprocessableEntity, CodeRouteNotFound, "未找到符合条件的路径，无法收藏")
+		return
+	}
+
+	requestJSON, err := json.Marshal(params)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "保存路线失败")
+		return
+	}
+	routeJSON, err := json.Marshal(route)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "保存路线失败")
+		return
+	}
+
+	first, last := route.Path[0], route.Path[len(route.Path)-1]
+	saved := model.SavedRoute{
+		UserID:        userID,
+		Name:          req.Name,
+		StartID:       first.ID,
+		StartName:     first.Name,
+		EndID:         last.ID,
+		EndName:       last.Name,
+		Fingerprint:   routeFingerprint(route.Segments),
+		MapVersion:    g.Version(),
+		Distance:      route.Distance,
+		EstimatedTime: route.EstimatedTime,
+		Request:       string(requestJSON),
+		Route:         string(routeJSON),
+	}
+	if err := db.DB.Create(&saved).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存路线失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetSavedRoutes 获取当前用户收藏的路线 (不含路线详情，详情通过离线包下载)
+func GetSavedRoutes(c *gin.Context) {
+	var routes []model.SavedRoute
+	if err := db.DB.Where("user_id = ?", c.GetUint("user_id")).Order("id").Find(&routes).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":  len(routes),
+		"routes": routes,
+	})
+}
+
+// DeleteSavedRoute 删除收藏的路线
+func DeleteSavedRoute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的路线 ID")
+		return
+	}
+
+	result := db.DB.Where("id = ? AND user_id = ?", id, c.GetUint("user_id")).Delete(&model.SavedRoute{})
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除路线失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "收藏的路线不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "收藏的路线已删除")})
+}
+
+// GetRouteBundle 下载收藏路线的离线包 (JSON 附件)，名称和文字说明使用请求的语言
+func GetRouteBundle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的路线 ID")
+		return
+	}
+	serveRouteBundle(c, id, c.GetUint("user_id"), language(c))
+}
+
+// serveRouteBundle 返回用户 userID 收藏的路线 id 的离线包，名称和文字说明使用 lang
+func serveRouteBundle(c *gin.Context, id uint64, userID uint, lang string) {
+	var saved model.SavedRoute
+	if err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&saved).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "收藏的路线不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return
+	}
+
+	var route PathResponse
+	if err := json.Unmarshal([]byte(saved.Route), &route); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "路线数据损坏")
+		return
+	}
+	var params PathRequest
+	if err := json.Unmarshal([]byte(saved.Request), &params); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "路线数据损坏")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="route-%d.json"`, saved.ID))
+	c.JSON(http.StatusOK, buildOfflineBundle(requestGraph(c), &saved, &route, &params, lang))
+}
+
+// buildOfflineBundle 根据保存的路线生成离线包
+// 仍在地图中的节点按 lang 重新取名称，文字说明按 lang 和原请求的单位制重新生成
+func buildOfflineBundle(g *algo.Graph, saved *model.SavedRoute, route *PathResponse, params *PathRequest, lang string) *OfflineBundle {
+	bundle := &OfflineBundle{
+		Format:        BundleFormat,
+		ID:            saved.ID,
+		Name:          saved.Name,
+		SavedAt:       saved.CreatedAt,
+		GeneratedAt:   time.Now(),
+		MapVersion:    saved.MapVersion,
+		Fingerprint:   saved.Fingerprint,
+		Distance:      route.Distance,
+		EstimatedTime: route.EstimatedTime,
+		DistanceText:  i18n.FormatDistance(lang, params.Units, route.Distance),
+		DurationText:  i18n.FormatDuration(lang, route.EstimatedTime),
+		Request:       params,
+	}
+
+	names := make(map[string]string, len(route.Path))
+	for i, node := range route.Path {
+		if n := g.Nodes[node.ID]; n != nil {
+			node = buildPathNode(n, lang)
+		}
+		names[node.ID] = node.Name
+		bundle.Nodes = append(bundle.Nodes, node)
+		bundle.Geometry = append(bundle.Geometry, [2]float64{node.Lat, node.Lng})
+		if i == 0 {
+			bundle.Bounds = algo.BoundingBox{MinLat: node.Lat, MinLng: node.Lng, MaxLat: node.Lat, MaxLng: node.Lng}
+			continue
+		}
+		b := &bundle.Bounds
+		b.MinLat, b.MaxLat = min(b.MinLat, node.Lat), max(b.MaxLat, node.Lat)
+		b.MinLng, b.MaxLng = min(b.MinLng, node.Lng), max(b.MaxLng, node.Lng)
+	}
+
+	seen := make(map[string]bool)
+	for _, seg := range route.Segments {
+		if name, ok := names[seg.FromID]; ok {
+			seg.FromName = name
+		}
+		if name, ok := names[seg.ToID]; ok {
+			seg.ToName = name
+		}
+		bundle.Segments = append(bundle.Segments, seg)
+
+		if seg.LineID == "" || seen[seg.LineID] {
+			continue
+		}
+		seen[seg.LineID] = true
+		if line := g.Lines[seg.LineID]; line != nil {
+			info := *line
+			info.Stops = nil
+			bundle.Lines = append(bundle.Lines, info)
+		}
+	}
+
+	for _, leg := range buildLegs(bundle.Segments, lang, params.Units) {
+		bundle.Instructions = append(bundle.Instructions, BundleInstruction{
+			Mode:         leg.Mode,
+			LineID:       leg.LineID,
+			FromID:       leg.FromID,
+			ToID:         leg.ToID,
+			Distance:     leg.Distance,
+			Time:         leg.Time,
+			DistanceText: leg.DistanceText,
+			DurationText: leg.DurationText,
+			Text:         leg.Instruction,
+		})
+	}
+	return bundle
+}
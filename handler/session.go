@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/cache"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revokedKey 令牌黑名单的键 (只保存令牌摘要)
+func revokedKey(token string) string {
+	return "revoked:" + utils.HashToken(token)
+}
+
+// revokedBeforeKey 用户级注销时间的键：该时间之前签发的 Token 都视为已注销
+func revokedBeforeKey(userID uint) string {
+	return "revoked-before:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// authenticate 解析 Token 并检查是否已注销 (单个 Token 注销或用户级注销)
+// 黑名单保存在共享缓存中 (配置 Redis 时各实例一致)，缓存不可用时返回错误
+func authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	_, revoked, err := cache.Default.Get(ctx, revokedKey(tokenString))
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errTokenRevoked
+	}
+
+	before, ok, err := cache.Default.Get(ctx, revokedBeforeKey(claims.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if ok && claims.IssuedAt != nil {
+		if t, err := strconv.ParseInt(string(before), 10, 64); err == nil && claims.IssuedAt.Unix() < t {
+			return nil, errTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// revokeUserSessions 注销用户在 before 之前签发的所有 Token (修改密码、删除账号时)
+// Token 的签发时间精确到秒；记录保留一个 Token 有效期，之后旧 Token 已经自然过期
+func revokeUserSessions(ctx context.Context, userID uint, before time.Time) error {
+	value := strconv.FormatInt(before.Unix(), 10)
+	return cache.Default.Set(ctx, revokedBeforeKey(userID), []byte(value), tokenTTL)
+}
+
+// Logout 注销当前 Token：加入黑名单直到过期 (需登录)
+func Logout(c *gin.Context) {
+	tokenString := extractToken(c)
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的 Token")
+		return
+	}
+
+	ttl := time.Minute
+	if claims.ExpiresAt != nil {
+		ttl = max(time.Until(claims.ExpiresAt.Time), time.Second)
+	}
+	if err := cache.Default.Set(c.Request.Context(), revokedKey(tokenString), []byte{1}, ttl); err != nil {
+		slog.Error("写入令牌黑名单失败", "error", err)
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "注销失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "已退出登录")})
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 分享链接有效期上限
+const maxShareTTL = 7 * 24 * time.Hour
+
+// ShareRequest 创建分享请求
+// 路线参数与路径规划接口相同，由服务端规划后保存
+type ShareRequest struct {
+	PathRequest     // 出发时间 depart_at 同时用于规划和计算 ETA
+	ExpiresInH  int `json:"expires_in_h,omitempty"` // 有效期 (小时)，默认使用配置 SHARE_TTL
+}
+
+// ShareResponse 分享链接详情
+type ShareResponse struct {
+	Token            string        `json:"token"`
+	URL              string        `json:"url"`
+	DepartAt         time.Time     `json:"depart_at"`
+	ArriveAt         time.Time     `json:"arrive_at"`         // 预计到达时间
+	RemainingSeconds float64       `json:"remaining_seconds"` // 距离到达还剩多少秒 (已到达为 0)
+	Status           string        `json:"status"`            // "not_started" / "en_route" / "arrived"
+	ExpiresAt        time.Time     `json:"expires_at"`
+	Route            *PathResponse `json:"route"`
+}
+
+// CreateShare 规划路线并生成分享链接
+func CreateShare(c *gin.Context) {
+	var req ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	route, ok := planPath(c, &req.PathRequest)
+	if !ok {
+		return
+	}
+	if !route.Found {
+		respondError(c, http.StatusUnprocessableEntity, CodeRouteNotFound, "未找到符合条件的路径，无法分享")
+		return
+	}
+
+	routeJSON, err := json.Marshal(route)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存路线失败")
+		return
+	}
+
+	now := time.Now()
+	departAt := now
+	if req.DepartAt != nil {
+		departAt = *req.DepartAt
+	}
+	ttl := config.GetDuration("SHARE_TTL", 24*time.Hour)
+	if req.ExpiresInH > 0 {
+		ttl = time.Duration(req.ExpiresInH) * time.Hour
+	}
+	if ttl > maxShareTTL {
+		ttl = maxShareTTL
+	}
+
+	token, err := utils.GenerateShortCode(8)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成分享令牌失败")
+		return
+	}
+
+	share := model.SharedRoute{
+		Token:         token,
+		UserID:        currentUserID(c),
+		StartID:       route.Path[0].ID,
+		EndID:         route.Path[len(route.Path)-1].ID,
+		Route:         string(routeJSON),
+		EstimatedTime: route.EstimatedTime,
+		DepartAt:      departAt,
+		ExpiresAt:     now.Add(ttl),
+	}
+	if err := db.DB.Create(&share).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存分享失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildShareResponse(&share, route, now))
+}
+
+// GetShare 查看分享的路线和实时 ETA (公开接口)
+func GetShare(c *gin.Context) {
+	var share model.SharedRoute
+	if err := db.DB.Where("token = ?", c.Param("token")).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "分享不存在")
+		} else {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		}
+		return
+	}
+
+	now := time.Now()
+	if now.After(share.ExpiresAt) {
+		respondError(c, http.StatusGone, CodeExpired, "分享已过期")
+		return
+	}
+
+	var route PathResponse
+	if err := json.Unmarshal([]byte(share.Route), &route); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "路线数据损坏")
+		return
+	}
+
+	c.JSON(http.StatusOK, buildShareResponse(&share, &route, now))
+}
+
+// buildShareResponse 根据当前时间计算到达状态和剩余时间
+func buildShareResponse(share *model.SharedRoute, route *PathResponse, now time.Time) ShareResponse {
+	arriveAt := share.ArriveAt()
+
+	status := "en_route"
+	remaining := arriveAt.Sub(now).Seconds()
+	switch {
+	case now.Before(share.DepartAt):
+		status = "not_started"
+	case remaining <= 0:
+		status = "arrived"
+		remaining = 0
+	}
+
+	// 配置了签名密钥时返回签名链接 (与分享同时过期)
+	path := "/api/share/" + share.Token
+	link := appBaseURL() + CurrentAPIPath(path)
+	if signingEnabled() {
+		link += "?" + signQuery(path, nil, share.ExpiresAt)
+	}
+
+	return ShareResponse{
+		Token:            share.Token,
+		URL:              link,
+		DepartAt:         share.DepartAt,
+		ArriveAt:         arriveAt,
+		RemainingSeconds: remaining,
+		Status:           status,
+		ExpiresAt:        share.ExpiresAt,
+		Route:            route,
+	}
+}
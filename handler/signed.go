@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 签名链接的有效期
+const (
+	defaultSignedURLTTL = time.Hour
+	maxSignedURLTTL     = 7 * 24 * time.Hour
+)
+
+// TileSignScope 瓦片签名覆盖的范围：一个签名可用于所有瓦片 (地图图层的 URL 模板只需签名一次)
+const TileSignScope = "/api/tiles/*"
+
+// 签名链接校验失败的原因
+var (
+	errUnsigned         = errors.New("链接未签名")
+	errSignatureInvalid = errors.New("链接签名无效")
+	errSignatureExpired = errors.New("链接已过期")
+)
+
+// SignURLRequest 生成签名链接请求
+type SignURLRequest struct {
+	ExpiresIn int `json:"expires_in" binding:"min=0"` // 有效期 (秒)，默认 SIGNED_URL_TTL，最长 7 天
+}
+
+// SignedURL 签名链接
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// urlSigningKey 签名密钥 (URL_SIGNING_SECRET)，未配置时不支持签名链接
+func urlSigningKey() []byte {
+	return []byte(config.GetString("URL_SIGNING_SECRET", ""))
+}
+
+// signingEnabled 是否配置了签名密钥
+func signingEnabled() bool {
+	return len(urlSigningKey()) > 0
+}
+
+// urlSignature 对签名范围 scope (通常是路径)、查询参数和过期时间计算 HMAC-SHA256 (URL 安全的 Base64)
+// params 不含 expires 和 signature，按参数名排序后参与签名
+func urlSignature(scope string, params url.Values, expires int64) string {
+	mac := hmac.New(sha256.New, urlSigningKey())
+	mac.Write([]byte(scope))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(params.Encode()))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signQuery 生成带过期时间和签名的查询字符串 (含 params)
+func signQuery(scope string, params url.Values, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", urlSignature(scope, params, expires))
+	return query.Encode()
+}
+
+// verifySignedQuery 校验请求的签名：没有 signature 参数时返回 errUnsigned
+// 除 expires 和 signature 外，其余查询参数都必须参与了签名 (防止在签名链接上追加参数)
+func verifySignedQuery(c *gin.Context, scope string) error {
+	query := c.Request.URL.Query()
+	signature := query.Get("signature")
+	if signature == "" {
+		return errUnsigned
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !signingEnabled() {
+		return errSignatureInvalid
+	}
+	query.Del("signature")
+	query.Del("expires")
+	if !hmac.Equal([]byte(signature), []byte(urlSignature(scope, query, expires))) {
+		return errSignatureInvalid
+	}
+	if time.Now().Unix() > expires {
+		return errSignatureExpired
+	}
+	return nil
+}
+
+// respondSignatureError 签名校验失败的响应：过期返回 410，未签名或签名无效返回 403
+func respondSignatureError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errSignatureExpired):
+		respondError(c, http.StatusGone, CodeExpired, "链接已过期")
+	case errors.Is(err, errUnsigned):
+		respondError(c, http.StatusForbidden, CodeForbidden, "需要签名链接")
+	default:
+		respondError(c, http.StatusForbidden, CodeForbidden, "链接签名无效")
+	}
+}
+
+// SignedURLMiddleware 校验签名链接：带签名的请求必须签名有效且未过期；
+// required 为 true 时不带签名的请求返回 403，否则照常处理。scope 为空时按请求路径 (去掉版本号) 签名
+func SignedURLMiddleware(scope string, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signed := scope
+		if signed == "" {
+			signed = unversionedPath(c.Request.URL.Path)
+		}
+		if err := verifySignedQuery(c, signed); err != nil && (required || !errors.Is(err, errUnsigned)) {
+			respondSignatureError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// signedURLExpiry 根据请求的有效期 (秒) 计算过期时间，为 0 时使用 SIGNED_URL_TTL，最长 7 天
+func signedURLExpiry(expiresIn int, now time.Time) time.Time {
+	ttl := config.GetDuration("SIGNED_URL_TTL", defaultSignedURLTTL)
+	if expiresIn > 0 {
+		ttl = time.Duration(expiresIn) * time.Second
+	}
+	return now.Add(min(ttl, maxSignedURLTTL))
+}
+
+// bindSignRequest 读取可选的请求体并检查是否已配置签名密钥，失败时写入错误响应
+func bindSignRequest(c *gin.Context) (SignURLRequest, bool) {
+	var req SignURLRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return req, false
+		}
+	}
+	if !signingEnabled() {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "未配置签名密钥")
+		return req, false
+	}
+	return req, true
+}
+
+// SignTiles 生成瓦片的签名 URL 模板 (需登录或使用 API Key)，可直接作为地图图层的 URL 使用
+func SignTiles(c *gin.Context) {
+	if currentAPIKey(c) == nil && currentUserID(c) == 0 {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未提供 Token")
+		return
+	}
+	req, ok := bindSignRequest(c)
+	if !ok {
+		return
+	}
+
+	expiresAt := signedURLExpiry(req.ExpiresIn, time.Now())
+	c.JSON(http.StatusOK, SignedURL{
+		URL:       appBaseURL() + APIPrefix + "/tiles/{z}/{x}/{y}.json?" + signQuery(TileSignScope, nil, expiresAt),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// CreateBundleLink 生成收藏路线离线包的签名下载链接 (需登录)，持有链接即可下载，不需要 Token
+// 名称和文字说明使用生成链接时请求的语言
+func CreateBundleLink(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的路线 ID")
+		return
+	}
+	req, ok := bindSignRequest(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	var count int64
+	if err := db.DB.Model(&model.SavedRoute{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	if count == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "收藏的路线不存在")
+		return
+	}
+
+	path := fmt.Sprintf("/api/bundles/%d", id)
+	params := url.Values{
+		"user": {strconv.FormatUint(uint64(userID), 10)},
+		"lang": {language(c)},
+	}
+	expiresAt := signedURLExpiry(req.ExpiresIn, time.Now())
+	c.JSON(http.StatusOK, SignedURL{
+		URL:       appBaseURL() + CurrentAPIPath(path) + "?" + signQuery(path, params, expiresAt),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// GetSignedRouteBundle 通过签名链接下载离线包 (公开接口，需放在 SignedURLMiddleware 之后)
+func GetSignedRouteBundle(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的路线 ID")
+		return
+	}
+	userID, err := strconv.ParseUint(c.Query("user"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusForbidden, CodeForbidden, "链接签名无效")
+		return
+	}
+	serveRouteBundle(c, id, uint(userID), c.Query("lang"))
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"traffic-system/algo"
+	"traffic-system/config"
+	"traffic-system/snapshot"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishSnapshot 把图的快照发布到 GRAPH_SNAPSHOT_PUBLISH (未配置时不做任何事)
+// 从数据库构建图之后调用，副本启动时通过 GRAPH_SNAPSHOT 读取
+func PublishSnapshot(g *algo.Graph) {
+	location := config.GetString("GRAPH_SNAPSHOT_PUBLISH", "")
+	if location == "" {
+		return
+	}
+	info, size, err := snapshot.Publish(g, location)
+	if err != nil {
+		slog.Warn("发布路网快照失败", "error", err)
+		return
+	}
+	slog.Info("路网快照已发布", "version", info.Version, "nodes", info.Nodes, "edges", info.Edges, "bytes", size)
+}
+
+// DownloadSnapshot 下载当前路网的快照 (管理员)
+func DownloadSnapshot(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	var buf bytes.Buffer
+	info, err := g.WriteSnapshot(&buf)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成快照失败")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="graph-`+info.Version+`.snapshot"`)
+	c.Header("X-Map-Version", info.Version)
+	c.Header("Content-Length", strconv.Itoa(buf.Len()))
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
+
+// CreateSnapshot 立即把当前路网的快照发布到 GRAPH_SNAPSHOT_PUBLISH (管理员)
+func CreateSnapshot(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	location := config.GetString("GRAPH_SNAPSHOT_PUBLISH", "")
+	if location == "" {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "未配置快照发布位置 (GRAPH_SNAPSHOT_PUBLISH)")
+		return
+	}
+
+	info, size, err := snapshot.Publish(g, location)
+	if err != nil {
+		slog.Error("发布路网快照失败", "error", err)
+		respondError(c, http.StatusBadGateway, CodeUpstreamError, "发布快照失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"snapshot": info, "size": size})
+}
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/analytics"
+	"traffic-system/config"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultStatsRange 统计接口未指定 from 时的默认时间范围
+const defaultStatsRange = 7 * 24 * time.Hour
+
+// UsageMiddleware 记录每个 API 请求的路由模板、方法、状态码和登录用户 (用于管理员统计)
+func UsageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			return // 未匹配到路由
+		}
+		analytics.Record(model.UsageEvent{
+			Kind:     model.UsageRequest,
+			Endpoint: endpoint,
+			Method:   c.Request.Method,
+			Status:   c.Writer.Status(),
+			UserID:   c.GetUint("user_id"),
+		})
+	}
+}
+
+// recordRoute 记录一次成功的路径规划 (起终点、主要交通方式和预计时间)
+// 起终点 ID 为经过的第一个和最后一个路网节点 (从坐标出发时不记录吸附的虚拟节点)
+func recordRoute(c *gin.Context, resp *PathResponse) {
+	if !analytics.Enabled() || len(resp.Path) == 0 {
+		return
+	}
+	start, end := resp.Path[0], resp.Path[len(resp.Path)-1]
+	startID, endID := graphEndpoints(resp.Path)
+	mode, modes := routeModes(resp.Segments)
+	analytics.Record(model.UsageEvent{
+		Kind:          model.UsageRoute,
+		UserID:        currentUserID(c),
+		Mode:          mode,
+		Modes:         strings.Join(modes, ","),
+		StartID:       startID,
+		EndID:         endID,
+		StartLat:      start.Lat,
+		StartLng:      start.Lng,
+		EndLat:        end.Lat,
+		EndLng:        end.Lng,
+		EstimatedTime: resp.EstimatedTime,
+	})
+}
+
+// graphEndpoints 路线经过的第一个和最后一个路网节点 (跳过虚拟节点)，起终点吸附在同一条边上时为空
+func graphEndpoints(path []PathNode) (string, string) {
+	var start, end string
+	for _, node := range path {
+		if node.ID == algo.VirtualStartID || node.ID == algo.VirtualEndID {
+			continue
+		}
+		if start == "" {
+			start = node.ID
+		}
+		end = node.ID
+	}
+	return start, end
+}
+
+// routeModes 返回路线中距离最长的交通方式，以及按使用顺序排列的全部交通方式
+func routeModes(segments []PathSegment) (string, []string) {
+	dist := make(map[string]float64)
+	var modes []string
+	for _, seg := range segments {
+		if _, ok := dist[seg.UsedMode]; !ok {
+			modes = append(modes, seg.UsedMode)
+		}
+		dist[seg.UsedMode] += seg.Distance
+	}
+	main := ""
+	for _, m := range modes {
+		if main == "" || dist[m] > dist[main] {
+			main = m
+		}
+	}
+	return main, modes
+}
+
+// GetStats 使用统计 (管理员)：各接口请求数、热门起终点、交通方式分布、ETA 误差和每日活跃用户
+// ?from=&to= 为日期 (2006-01-02) 或 RFC3339 时间，默认最近 7 天；?limit= 为热门起终点数量，默认 10，最多 100
+func GetStats(c *gin.Context) {
+	g := requestGraph(c)
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+	limit := 10
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "limit 超出范围 (1 ~ 100)")
+			return
+		}
+		limit = n
+	}
+
+	stats, err := analytics.Compute(from, to, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	lang := language(c)
+	for _, list := range [][]analytics.NodeCount{stats.TopOrigins, stats.TopDestinations} {
+		for i := range list {
+			if g == nil {
+				break
+			}
+			if node := g.Nodes[list[i].NodeID]; node != nil {
+				list[i].Name = localName(lang, node)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// heatmap 网格精度 (geohash 字符数)：默认 7 位约 150 米，最多 9 位约 5 米
+const (
+	defaultHeatmapPrecision = 7
+	maxHeatmapPrecision     = 9
+)
+
+// GetHeatmap 路径规划起终点的热力图数据 (管理员)，按 geohash 网格聚合
+// ?from=&to= 同统计接口；?precision= 网格精度；?kind=start|end 只统计起点或终点；?mode= 只统计主要交通方式为该方式的路线
+func GetHeatmap(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+	precision := defaultHeatmapPrecision
+	if s := c.Query("precision"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxHeatmapPrecision {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "precision 超出范围 (1 ~ 9)")
+			return
+		}
+		precision = n
+	}
+	kind := c.Query("kind")
+	if kind != "" && kind != "start" && kind != "end" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "kind 应为 start 或 end")
+		return
+	}
+	mode := c.Query("mode")
+	if mode != "" && model.GetModeMask(mode) == 0 {
+		respondError(c, http.StatusBadRequest, CodeModeInvalid, "无效的交通方式: "+mode)
+		return
+	}
+
+	cells, err := analytics.Heatmap(from, to, precision, kind != "end", kind != "start", mode)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	var peak int64
+	if len(cells) > 0 {
+		peak = cells[0].Total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"precision": precision,
+		"max":       peak, // 单个网格的最大次数 (用于归一化颜色)
+		"count":     len(cells),
+		"cells":     cells,
+	})
+}
+
+// OD 导出的默认区域精度 (geohash 6 位约 1.2 公里 × 0.6 公里)
+const defaultODPrecision = 6
+
+// ExportOD 导出一段时间内路径规划的匿名 OD 对 (管理员)
+// ?from=&to= 同统计接口；?format=csv (默认) 或 parquet；?zone=node 按节点统计，默认按 ?precision= 位的 geohash 区域统计；
+// 次数少于 OD_MIN_COUNT (?min_count= 只能调高) 的组合不输出
+func ExportOD(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "parquet" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "format 应为 csv 或 parquet")
+		return
+	}
+	opts := analytics.ODOptions{
+		Precision: defaultODPrecision,
+		MinCount:  int64(config.GetInt("OD_MIN_COUNT", 5)),
+	}
+	switch c.Query("zone") {
+	case "", "geohash":
+		if s := c.Query("precision"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxHeatmapPrecision {
+				respondError(c, http.StatusBadRequest, CodeOutOfRange, "precision 超出范围 (1 ~ 9)")
+				return
+			}
+			opts.Precision = n
+		}
+	case "node":
+		opts.Precision = 0
+	default:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "zone 应为 geohash 或 node")
+		return
+	}
+	if s := c.Query("min_count"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < opts.MinCount {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "min_count 不能低于匿名阈值: "+strconv.FormatInt(opts.MinCount, 10))
+			return
+		}
+		opts.MinCount = n
+	}
+
+	pairs, suppressed, err := analytics.ODMatrix(from, to, opts)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+	if opts.Precision == 0 {
+		pairs = locateNodePairs(requestGraph(c), pairs)
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "parquet" {
+		contentType = "application/vnd.apache.parquet"
+		err = analytics.WriteODParquet(&buf, pairs)
+	} else {
+		err = analytics.WriteODCSV(&buf, pairs)
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成导出文件失败")
+		return
+	}
+
+	name := "od_" + from.Format("20060102") + "_" + to.Format("20060102") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("X-OD-Pairs", strconv.Itoa(len(pairs)))
+	c.Header("X-OD-Suppressed", strconv.Itoa(suppressed)) // 低于匿名阈值被隐藏的组合数
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// locateNodePairs 按节点统计时使用路网节点的坐标 (不使用事件中记录的原始坐标)，
+// 不在当前路网中的节点 (已删除的节点、旧版本记录的虚拟节点) 不输出
+func locateNodePairs(g *algo.Graph, pairs []analytics.ODPair) []analytics.ODPair {
+	if g == nil {
+		return nil
+	}
+	located := pairs[:0]
+	for _, p := range pairs {
+		origin, destination := g.Nodes[p.Origin], g.Nodes[p.Destination]
+		if origin == nil || destination == nil {
+			continue
+		}
+		p.OriginLat, p.OriginLng = origin.Lat, origin.Lng
+		p.DestinationLat, p.DestinationLng = destination.Lat, destination.Lng
+		located = append(located, p)
+	}
+	return located
+}
+
+// parseDateRange 解析统计接口的 ?from=&to= 参数 (日期或 RFC3339 时间)
+// to 为日期时包含当天；默认 to 为当前时间，from 为 to 之前 7 天。参数错误时写入错误响应并返回 false
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	parse := func(name string, endOfDay bool) (time.Time, bool) {
+		value := c.Query(name)
+		if value == "" {
+			return time.Time{}, true
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的时间: "+name)
+			return time.Time{}, false
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, true
+	}
+
+	from, ok := parse("from", false)
+	if !ok {
+		return from, from, false
+	}
+	to, ok := parse("to", true)
+	if !ok {
+		return from, to, false
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultStatsRange)
+	}
+	if !from.Before(to) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "开始时间必须早于结束时间")
+		return from, to, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/analytics"
+	"traffic-system/db"
+	"traffic-system/fixture"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestExportODNodeZoneSnappedRoute 从坐标出发的路线按节点导出时，使用路网节点的 ID 和坐标，不泄露请求中的原始坐标
+func TestExportODNodeZoneSnappedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接是独立的
+	}
+	if err := conn.AutoMigrate(&model.UsageEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	db.DB = conn
+	t.Cleanup(func() { db.DB = nil })
+
+	g := fixture.Town()
+	old := SetGraph(g)
+	t.Cleanup(func() { SetGraph(old) })
+	t.Setenv("OD_MIN_COUNT", "1")
+	analytics.Start(10*time.Millisecond, 0)
+
+	// 起点在 0-0 与 0-1 之间的道路北侧，终点在 1-2 与 2-2 之间的道路东侧 (都会吸附到边上)
+	a, b := g.Nodes[fixture.TownNode(0, 0)], g.Nodes[fixture.TownNode(0, 1)]
+	c, d := g.Nodes[fixture.TownNode(1, 2)], g.Nodes[fixture.TownNode(2, 2)]
+	req := PathRequest{
+		StartLat: (a.Lat+b.Lat)/2 + 0.0001, StartLng: (a.Lng+b.Lng)/2 + 0.0002,
+		EndLat: (c.Lat+d.Lat)/2 + 0.0003, EndLng: c.Lng + 0.0001,
+		Modes: []string{"walk"},
+	}
+
+	r := gin.New()
+	r.POST("/path/find", FindPath)
+	r.GET("/od/export", ExportOD)
+
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/path/find", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("路径规划返回 %d: %s", w.Code, w.Body.String())
+	}
+	var resp PathResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Found || resp.Path[0].ID != algo.VirtualStartID || resp.Path[len(resp.Path)-1].ID != algo.VirtualEndID {
+		t.Fatalf("起终点应吸附到边上 (虚拟节点)，得到 %+v", resp.Path)
+	}
+	wantOrigin, wantDestination := resp.Path[1].ID, resp.Path[len(resp.Path)-2].ID
+
+	// 等待事件写入数据库
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int64
+		db.DB.Model(&model.UsageEvent{}).Where("kind = ?", model.UsageRoute).Count(&count)
+		if count > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("路径规划事件没有写入数据库")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/od/export?zone=node", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("导出返回 %d: %s", w.Code, w.Body.String())
+	}
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("期望表头和一个 OD 对，得到 %v", rows)
+	}
+	row := rows[1]
+	if row[0] != wantOrigin || row[3] != wantDestination {
+		t.Errorf("OD 对 = %s -> %s，期望路网节点 %s -> %s", row[0], row[3], wantOrigin, wantDestination)
+	}
+	coord := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for i, node := range []*model.Node{g.Nodes[wantOrigin], g.Nodes[wantDestination]} {
+		if node == nil {
+			continue
+		}
+		if lat, lng := row[1+3*i], row[2+3*i]; lat != coord(node.Lat) || lng != coord(node.Lng) {
+			t.Errorf("%s 的坐标 = (%s, %s)，期望节点坐标 (%s, %s)", node.ID, lat, lng, coord(node.Lat), coord(node.Lng))
+		}
+	}
+	for _, raw := range []float64{req.StartLat, req.StartLng, req.EndLat, req.EndLng} {
+		if strings.Contains(w.Body.String(), coord(raw)) {
+			t.Errorf("导出中包含请求的原始坐标 %s", coord(raw))
+		}
+	}
+}
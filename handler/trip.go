@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/analytics"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTripSpeedFactor 上报速度超过默认速度的倍数时视为定位噪声，不参与统计
+const maxTripSpeedFactor = 3.0
+
+// TripRequest 上报一次已完成的导航行程
+type TripRequest struct {
+	Segments      []TripSegmentInput `json:"segments" binding:"required,min=1,max=1000,dive"`
+	EstimatedTime float64            `json:"estimated_time" binding:"gte=0"` // 导航开始时的预计时间 (秒，可选)，用于统计 ETA 误差
+}
+
+// TripSegmentInput 行程中实际通过的一段路
+type TripSegmentInput struct {
+	FromID    string    `json:"from_id" binding:"required"`
+	ToID      string    `json:"to_id" binding:"required"`
+	Mode      string    `json:"mode" binding:"required,oneof=walk bike car bus subway"`
+	EnteredAt time.Time `json:"entered_at" binding:"required"`    // 进入路段的时间
+	Duration  float64   `json:"duration" binding:"required,gt=0"` // 通过该路段用时 (秒)
+}
+
+// SubmitTrip 上报已完成的行程，用于学习各路段不同时段的实际速度
+func SubmitTrip(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var req TripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	records := make([]model.TripSegment, 0, len(req.Segments))
+	for _, seg := range req.Segments {
+		edge := findEdge(g, seg.FromID, seg.ToID, seg.Mode)
+		if edge == nil {
+			continue // 路段不存在或不允许该交通方式
+		}
+		if edge.Dist/seg.Duration > model.GetModeSpeed(seg.Mode)*maxTripSpeedFactor {
+			continue
+		}
+		records = append(records, model.TripSegment{
+			UserID:    userID,
+			FromID:    seg.FromID,
+			ToID:      seg.ToID,
+			Mode:      seg.Mode,
+			Distance:  edge.Dist,
+			Duration:  seg.Duration,
+			EnteredAt: seg.EnteredAt,
+		})
+	}
+
+	if len(records) > 0 {
+		if err := db.DB.CreateInBatches(records, 100).Error; err != nil {
+			respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存行程失败")
+			return
+		}
+	}
+
+	if req.EstimatedTime > 0 {
+		recordTrip(userID, &req)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accepted": len(records),
+		"ignored":  len(req.Segments) - len(records),
+	})
+}
+
+// recordTrip 记录行程的实际用时和导航预计时间，用于统计 ETA 误差
+// 实际用时为进入第一段到离开最后一段的时间 (含等车、换乘)，时间不连续时退回为各段用时之和
+func recordTrip(userID uint, req *TripRequest) {
+	first, last := req.Segments[0], req.Segments[len(req.Segments)-1]
+	actual := last.EnteredAt.Sub(first.EnteredAt).Seconds() + last.Duration
+	if actual <= 0 {
+		actual = 0
+		for _, seg := range req.Segments {
+			actual += seg.Duration
+		}
+	}
+	analytics.Record(model.UsageEvent{
+		Kind:          model.UsageTrip,
+		UserID:        userID,
+		StartID:       first.FromID,
+		EndID:         last.ToID,
+		EstimatedTime: req.EstimatedTime,
+		ActualTime:    actual,
+	})
+}
+
+// findEdge 查找允许指定交通方式的路段
+func findEdge(g *algo.Graph, fromID, toID, mode string) *model.Edge {
+	for _, edge := range g.GetNeighbors(fromID, model.GetModeMask(mode)) {
+		if edge.To == toID {
+			return edge
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TurnRestrictionRequest 创建转弯规则请求
+type TurnRestrictionRequest struct {
+	FromID      string  `json:"from_id" binding:"required"` // 驶入路口的边的起点
+	ViaID       string  `json:"via_id" binding:"required"`  // 路口
+	ToID        string  `json:"to_id" binding:"required"`   // 驶出路口的边的终点
+	Type        string  `json:"type" binding:"required"`    // no_left_turn / only_straight_on / penalty 等
+	Penalty     float64 `json:"penalty"`                    // 转弯惩罚 (秒)，penalty 规则使用
+	Description string  `json:"description"`
+}
+
+// GetTurnRestrictions 获取所有转弯规则 (管理员，可按 ?via_id= 过滤)
+func GetTurnRestrictions(c *gin.Context) {
+	query := db.DB.Order("id")
+	if id := c.Query("via_id"); id != "" {
+		query = query.Where("via_id = ?", id)
+	}
+	var rules []model.TurnRestriction
+	if err := query.Find(&rules).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(rules),
+		"rules": rules,
+	})
+}
+
+// CreateTurnRestriction 创建路口转弯规则 (管理员)，保存后立即对驾车路径规划生效
+// 两条边 from -> via、via -> to 都必须存在且可以驾车
+func CreateTurnRestriction(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+
+	var req TurnRestrictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if !model.IsValidTurnType(req.Type) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的转弯规则类型: "+req.Type)
+		return
+	}
+	if req.Type == model.TurnPenalty {
+		if req.Penalty <= 0 || req.Penalty > model.MaxTurnPenalty {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "转弯惩罚超出范围 (0 ~ 600 秒)")
+			return
+		}
+	} else {
+		req.Penalty = 0
+	}
+	for _, id := range []string{req.FromID, req.ViaID, req.ToID} {
+		if g.Nodes[id] == nil {
+			respondError(c, http.StatusBadRequest, CodeNodeNotFound, "节点不存在: "+id)
+			return
+		}
+	}
+	if !hasDrivingEdge(g, req.FromID, req.ViaID) || !hasDrivingEdge(g, req.ViaID, req.ToID) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "路口前后没有可以驾车的边")
+		return
+	}
+
+	rule := model.TurnRestriction{
+		FromID:      req.FromID,
+		ViaID:       req.ViaID,
+		ToID:        req.ToID,
+		Type:        req.Type,
+		Penalty:     req.Penalty,
+		Description: req.Description,
+		Active:      true,
+	}
+	if err := db.DB.Create(&rule).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存规则失败")
+		return
+	}
+	reloadTurnRestrictions()
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteTurnRestriction 删除转弯规则 (管理员)
+func DeleteTurnRestriction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的规则 ID")
+		return
+	}
+
+	result := db.DB.Delete(&model.TurnRestriction{}, id)
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除规则失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "规则不存在")
+		return
+	}
+	reloadTurnRestrictions()
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "规则已删除")})
+}
+
+// hasDrivingEdge 两个节点之间是否有可以驾车的边 (包括双向道路的反向边)
+func hasDrivingEdge(g *algo.Graph, from, to string) bool {
+	for _, edge := range g.AdjList[from] {
+		if edge.To == to && edge.ModeMask&(model.ModeCar|model.ModeTruck) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadTurnRestrictions 转弯规则变更后重新加载到路网
+func reloadTurnRestrictions() {
+	g := CurrentGraph()
+	if g == nil {
+		return
+	}
+	if _, err := g.ReloadTurnRestrictions(); err != nil {
+		slog.Warn("重新加载转弯规则失败", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口版本：不兼容的修改 (如新的响应格式) 只出现在新版本中，旧版路径保留一段时间后停用
+const (
+	APIPrefix       = "/api/v1" // 当前版本
+	APIPrefixV2     = "/api/v2" // 路线为 legs -> steps 结构 (见 RouteV2)，其他接口与 v1 相同
+	LegacyAPIPrefix = "/api"    // 引入版本号之前的路径，作为 /api/v1 的兼容别名保留 (已弃用)
+)
+
+// apiVersionKey 请求的接口版本在 gin.Context 中的键
+const apiVersionKey = "api_version"
+
+// APIVersionMiddleware 记录请求的接口版本 (未设置时为 v1)
+func APIVersionMiddleware(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// apiVersion 请求的接口版本
+func apiVersion(c *gin.Context) int {
+	if v := c.GetInt(apiVersionKey); v > 0 {
+		return v
+	}
+	return 1
+}
+
+// LegacyDeprecatedAt 旧版路径的弃用时间 (引入 /api/v1 的时间)
+var LegacyDeprecatedAt = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+// CurrentAPIPath 旧版路径对应的当前版本路径，如 /api/path/find -> /api/v1/path/find
+func CurrentAPIPath(path string) string {
+	if strings.HasPrefix(path, APIPrefix+"/") {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, LegacyAPIPrefix+"/"); ok {
+		return APIPrefix + "/" + rest
+	}
+	return path
+}
+
+// unversionedPath 去掉版本号的路径，如 /api/v1/share/x -> /api/share/x
+// 用作签名链接的签名范围、配额豁免等与版本无关的判断 (各版本路径的签名相同，旧版签发的链接继续有效)
+func unversionedPath(path string) string {
+	for _, prefix := range []string{APIPrefix, APIPrefixV2} {
+		if rest, ok := strings.CutPrefix(path, prefix+"/"); ok {
+			return LegacyAPIPrefix + "/" + rest
+		}
+	}
+	return path
+}
+
+// DeprecationMiddleware 标记已弃用的接口：Deprecation 头 (RFC 9745) 为弃用时间，
+// sunset 不为零时 Sunset 头 (RFC 8594) 为计划停用的时间，successor 不为空时 Link 头指向替代的接口
+func DeprecationMiddleware(deprecatedAt, sunset time.Time, successor func(path string) string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successor != nil {
+			if path := successor(c.Request.URL.Path); path != c.Request.URL.Path {
+				header.Add("Link", "<"+path+`>; rel="successor-version"`)
+			}
+		}
+		c.Next()
+	}
+}
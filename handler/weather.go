@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/config"
+	"traffic-system/i18n"
+	"traffic-system/model"
+	"traffic-system/weather"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 天气调整
+// 启用天气数据 (WEATHER_PROVIDER) 后，按起点的当前天气调整路径规划：
+// 下雨、下雪时步行和骑行变慢 (计入预计时间)，骑行的搜索成本加倍 (尽量改用其他方式)。
+// 只在出发时间接近当前时间时调整 (WEATHER_HORIZON)，历史回放不调整
+
+// weatherTimeout 规划时查询天气的最长等待时间，超时按无天气数据处理
+const weatherTimeout = 2 * time.Second
+
+// WeatherAdvisory 天气提示 (按天气调整了规划时返回)
+type WeatherAdvisory struct {
+	Condition     string   `json:"condition"`               // rain、snow 等
+	Precipitation float64  `json:"precipitation,omitempty"` // 降水量 (毫米/小时)
+	AdjustedModes []string `json:"adjusted_modes"`          // 调整了速度的交通方式
+	Message       string   `json:"message"`
+}
+
+// requestWeather 查询请求起点的当前天气；未启用、历史回放、出发时间超出 WEATHER_HORIZON 或查询失败时返回 nil
+func requestWeather(ctx context.Context, g *algo.Graph, req *PathRequest, departAt, now time.Time) *weather.Conditions {
+	if !weather.Enabled() || req.AsOf != nil || !withinWeatherHorizon(departAt, now) {
+		return nil
+	}
+	lat, lng := req.StartLat, req.StartLng
+	if lat == 0 && lng == 0 {
+		node := g.Nodes[req.StartID]
+		if node == nil {
+			return nil
+		}
+		lat, lng = node.Lat, node.Lng
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, weatherTimeout)
+	defer cancel()
+	conditions, err := weather.Current(ctx, lat, lng)
+	if err != nil {
+		slog.Warn("查询天气失败，不按天气调整", "error", err)
+		return nil
+	}
+	return conditions
+}
+
+// withinWeatherHorizon 出发时间是否在当前天气的有效范围内 (前后 WEATHER_HORIZON，默认 1 小时)
+func withinWeatherHorizon(departAt, now time.Time) bool {
+	horizon := config.GetDuration("WEATHER_HORIZON", time.Hour)
+	d := departAt.Sub(now)
+	return d < horizon && d > -horizon
+}
+
+// weatherAdjustment 按天气构建搜索调整和天气提示；没有降水或未使用步行、骑行时返回 nil
+func weatherAdjustment(conditions *weather.Conditions, modeMask int, lang string) (*algo.WeatherAdjustment, *WeatherAdvisory) {
+	if !conditions.Wet() || modeMask&(model.ModeWalk|model.ModeBike) == 0 {
+		return nil, nil
+	}
+	adj := &algo.WeatherAdjustment{SpeedFactors: map[string]float64{}, CostFactors: map[string]float64{}}
+	advisory := &WeatherAdvisory{Condition: conditions.Condition, Precipitation: conditions.Precipitation}
+	if modeMask&model.ModeWalk != 0 {
+		adj.SpeedFactors["walk"] = weatherFactor("WEATHER_RAIN_WALK_FACTOR", 0.9)
+		advisory.AdjustedModes = append(advisory.AdjustedModes, "walk")
+	}
+	message := "雨雪天气：步行的预计时间已延长"
+	if modeMask&model.ModeBike != 0 {
+		adj.SpeedFactors["bike"] = weatherFactor("WEATHER_RAIN_BIKE_FACTOR", 0.75)
+		if penalty := config.GetFloat("WEATHER_RAIN_BIKE_PENALTY", 2); penalty > 1 {
+			adj.CostFactors["bike"] = penalty
+		}
+		advisory.AdjustedModes = append(advisory.AdjustedModes, "bike")
+		message = "雨雪天气：步行和骑行的预计时间已延长，并尽量避免骑行"
+	}
+	advisory.Message = i18n.T(lang, message)
+	return adj, advisory
+}
+
+// weatherFactor 读取速度系数，超出 (0, 1] 时按 1 处理 (天气只会让速度变慢)
+func weatherFactor(key string, def float64) float64 {
+	f := config.GetFloat(key, def)
+	if f <= 0 || f > 1 {
+		return 1
+	}
+	return f
+}
+
+// weatherCacheKey 路径缓存键中的天气部分：按天气调整规划时为天气状况，否则为空
+func weatherCacheKey(c *gin.Context, req *PathRequest, departAt time.Time) string {
+	conditions := requestWeather(c.Request.Context(), requestGraph(c), req, departAt, time.Now())
+	if !conditions.Wet() {
+		return ""
+	}
+	return conditions.Condition
+}
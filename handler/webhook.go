@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+	"traffic-system/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest 创建 Webhook 请求
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Events      []string `json:"events"` // 为空表示订阅全部事件
+	Description string   `json:"description"`
+}
+
+// CreateWebhook 创建 Webhook (管理员)
+// 签名密钥只在本次响应中返回
+func CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "回调地址必须是 http 或 https")
+		return
+	}
+	for _, event := range req.Events {
+		if !webhook.IsValidEvent(event) {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的事件: "+event)
+			return
+		}
+	}
+
+	secret, err := utils.GenerateToken(32)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternalError, "生成签名密钥失败")
+		return
+	}
+
+	hook := model.Webhook{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      true,
+	}
+	if err := db.DB.Create(&hook).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存 Webhook 失败")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": hook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks 获取所有 Webhook 及可订阅的事件 (管理员)
+func GetWebhooks(c *gin.Context) {
+	var hooks []model.Webhook
+	if err := db.DB.Order("id").Find(&hooks).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(hooks),
+		"webhooks": hooks,
+		"events":   webhook.Events,
+	})
+}
+
+// DeleteWebhook 删除 Webhook (管理员)
+func DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的 Webhook ID")
+		return
+	}
+
+	result := db.DB.Delete(&model.Webhook{}, id)
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除 Webhook 失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "Webhook 不存在")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Webhook 已删除")})
+}
+
+// TestWebhook 向 Webhook 发送一次 ping 事件 (管理员)
+func TestWebhook(c *gin.Context) {
+	var hook model.Webhook
+	if err := db.DB.First(&hook, c.Param("id")).Error; err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "Webhook 不存在")
+		return
+	}
+
+	status, err := webhook.Ping(&hook)
+	if err != nil {
+		respondErrorDetails(c, http.StatusBadGateway, CodeUpstreamError, "投递失败: "+err.Error(), gin.H{"status": status})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": tr(c, "投递成功"),
+		"status":  status,
+	})
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 假设分析的抽样参数
+const (
+	defaultWhatIfSample = 50
+	whatIfUnchanged     = 1.0 // 预计时间变化小于该值 (秒) 视为不变
+)
+
+// WhatIfRequest 假设分析请求：对路网做假设的修改 (如新增一条地铁线)，比较一组 OD 对修改前后的预计时间
+type WhatIfRequest struct {
+	AddNodes    []model.Node `json:"add_nodes" binding:"max=1000"`
+	AddEdges    []model.Edge `json:"add_edges" binding:"max=5000"`
+	RemoveNodes []string     `json:"remove_nodes" binding:"max=1000"`
+	RemoveEdges []db.EdgeRef `json:"remove_edges" binding:"max=5000"`
+	Pairs       []WhatIfPair `json:"pairs" binding:"max=500,dive"`   // 为空时随机抽样
+	Sample      int          `json:"sample" binding:"gte=0,lte=500"` // 随机抽样的 OD 对数量，默认 50
+	Seed        uint64       `json:"seed"`                           // 随机种子，相同的种子抽到相同的 OD 对
+	Modes       []string     `json:"modes" binding:"required,min=1"`
+	DepartAt    *time.Time   `json:"depart_at,omitempty"`
+}
+
+// WhatIfPair 参与比较的 OD 对
+type WhatIfPair struct {
+	StartID string `json:"start_id" binding:"required"`
+	EndID   string `json:"end_id" binding:"required"`
+}
+
+// WhatIfResult 一个 OD 对修改前后的预计时间 (秒)，不可达为 null
+type WhatIfResult struct {
+	StartID     string   `json:"start_id"`
+	EndID       string   `json:"end_id"`
+	Before      *float64 `json:"before"`
+	After       *float64 `json:"after"`
+	Change      *float64 `json:"change,omitempty"`       // after - before，负数表示变快
+	ChangeRatio *float64 `json:"change_ratio,omitempty"` // change / before
+}
+
+// WhatIf 假设分析 (管理员)：在当前路网上叠加假设的修改，报告抽样 OD 对的预计时间变化，不修改路网和数据库
+func WhatIf(c *gin.Context) {
+	g := requestGraph(c)
+	if g == nil {
+		respondError(c, http.StatusInternalServerError, CodeGraphNotLoaded, "地图数据未加载")
+		return
+	}
+	var req WhatIfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	modeMask := model.ParseModes(req.Modes)
+	if modeMask == 0 {
+		respondError(c, http.StatusBadRequest, CodeModeInvalid, "未指定有效的交通方式")
+		return
+	}
+
+	edited, err := g.ApplyEdit(nil, algo.MapEdit{
+		AddNodes:    req.AddNodes,
+		AddEdges:    req.AddEdges,
+		RemoveNodes: req.RemoveNodes,
+		RemoveEdges: req.RemoveEdges,
+	})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的修改: "+err.Error())
+		return
+	}
+
+	pairs := req.Pairs
+	if len(pairs) == 0 {
+		sample := req.Sample
+		if sample == 0 {
+			sample = defaultWhatIfSample
+		}
+		pairs = sampleODPairs(g, sample, req.Seed)
+	}
+	opts := algo.SearchOptions{ModeMask: modeMask, Context: c.Request.Context()}
+	if req.DepartAt != nil {
+		opts.DepartAt = *req.DepartAt
+	}
+	editedOpts := opts
+	editedOpts.Overlay = edited
+
+	results := make([]WhatIfResult, 0, len(pairs))
+	var improved, worsened, unchanged, gained, lost, compared int
+	var totalChange float64
+	for _, pair := range pairs {
+		if opts.Cancelled() {
+			respondCancelled(c)
+			return
+		}
+		r := WhatIfResult{StartID: pair.StartID, EndID: pair.EndID}
+		if before := g.AStar(pair.StartID, pair.EndID, opts); before.Found {
+			r.Before = &before.EstimatedTime
+		}
+		if after := g.AStar(pair.StartID, pair.EndID, editedOpts); after.Found {
+			r.After = &after.EstimatedTime
+		}
+
+		switch {
+		case r.Before != nil && r.After != nil:
+			change := *r.After - *r.Before
+			r.Change = &change
+			if *r.Before > 0 {
+				ratio := change / *r.Before
+				r.ChangeRatio = &ratio
+			}
+			compared++
+			totalChange += change
+			switch {
+			case math.Abs(change) < whatIfUnchanged:
+				unchanged++
+			case change < 0:
+				improved++
+			default:
+				worsened++
+			}
+		case r.After != nil:
+			gained++
+		case r.Before != nil:
+			lost++
+		}
+		results = append(results, r)
+	}
+	if opts.Cancelled() {
+		respondCancelled(c)
+		return
+	}
+
+	meanChange := 0.0
+	if compared > 0 {
+		meanChange = totalChange / float64(compared)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"pairs":           len(results),
+		"improved":        improved,
+		"worsened":        worsened,
+		"unchanged":       unchanged,
+		"newly_reachable": gained, // 修改后才可达
+		"unreachable":     lost,   // 修改后不再可达
+		"mean_change":     meanChange,
+		"results":         results,
+	})
+}
+
+// sampleODPairs 从路网中随机抽取 n 个起终点不同的 OD 对 (不含道路节点)，相同的 seed 结果相同
+func sampleODPairs(g *algo.Graph, n int, seed uint64) []WhatIfPair {
+	var candidates []string
+	for _, node := range g.NodeList {
+		if node.Type != "road_node" {
+			candidates = append(candidates, node.ID)
+		}
+	}
+	if len(candidates) < 2 {
+		return nil
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	pairs := make([]WhatIfPair, 0, n)
+	for len(pairs) < n {
+		i, j := rng.IntN(len(candidates)), rng.IntN(len(candidates))
+		if i != j {
+			pairs = append(pairs, WhatIfPair{StartID: candidates[i], EndID: candidates[j]})
+		}
+	}
+	return pairs
+}
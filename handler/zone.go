@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// ZoneRuleRequest 创建区域规则请求
+type ZoneRuleRequest struct {
+	GeofenceID      uint    `json:"geofence_id" binding:"required"`
+	Type            string  `json:"type" binding:"required"` // forbidden / charge / plate / emission
+	Days            []int64 `json:"days"`                    // 生效的星期 (0=周日 ... 6=周六)，为空表示每天
+	StartTime       string  `json:"start_time"`              // 如 "07:00"，为空表示全天
+	EndTime         string  `json:"end_time"`                // 如 "20:00"
+	Fee             float64 `json:"fee"`
+	PlateDigits     []int64 `json:"plate_digits"`
+	MinEmission     int     `json:"min_emission"`
+	ExemptNewEnergy bool    `json:"exempt_new_energy"`
+	Description     string  `json:"description"`
+}
+
+// GetZoneRules 获取所有区域规则 (管理员，可按 ?geofence_id= 过滤)
+func GetZoneRules(c *gin.Context) {
+	query := db.DB.Order("id")
+	if id := c.Query("geofence_id"); id != "" {
+		query = query.Where("geofence_id = ?", id)
+	}
+	var rules []model.ZoneRule
+	if err := query.Find(&rules).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "数据库查询出错")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(rules),
+		"rules": rules,
+	})
+}
+
+// CreateZoneRule 为地理围栏添加驾车规则 (管理员)，保存后立即对路径规划生效
+func CreateZoneRule(c *gin.Context) {
+	var req ZoneRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var fence model.Geofence
+	if err := db.DB.First(&fence, req.GeofenceID).Error; err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "围栏不存在")
+		return
+	}
+
+	switch req.Type {
+	case model.ZoneRuleForbidden:
+	case model.ZoneRuleCharge:
+		if req.Fee <= 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "收费规则需要指定费用")
+			return
+		}
+	case model.ZoneRulePlate:
+		if len(req.PlateDigits) == 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "限行规则需要指定尾号")
+			return
+		}
+		for _, d := range req.PlateDigits {
+			if d < 0 || d > 9 {
+				respondError(c, http.StatusBadRequest, CodeInvalidRequest, "限行尾号必须是 0 ~ 9")
+				return
+			}
+		}
+	case model.ZoneRuleEmission:
+		if req.MinEmission < 1 || req.MinEmission > model.MaxEmissionStandard {
+			respondError(c, http.StatusBadRequest, CodeOutOfRange, "排放标准超出范围 (1 ~ 6)")
+			return
+		}
+	default:
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的规则类型: "+req.Type)
+		return
+	}
+
+	for _, d := range req.Days {
+		if d < 0 || d > 6 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "星期必须是 0 ~ 6")
+			return
+		}
+	}
+	if (req.StartTime == "") != (req.EndTime == "") {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "开始和结束时间需要同时指定")
+		return
+	}
+	if req.StartTime != "" {
+		if _, err := model.ParseClock(req.StartTime); err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "开始时间格式错误 (HH:MM)")
+			return
+		}
+		if _, err := model.ParseClock(req.EndTime); err != nil {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "结束时间格式错误 (HH:MM)")
+			return
+		}
+	}
+
+	rule := model.ZoneRule{
+		GeofenceID:      req.GeofenceID,
+		Type:            req.Type,
+		Days:            pq.Int64Array(req.Days),
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		Fee:             req.Fee,
+		PlateDigits:     pq.Int64Array(req.PlateDigits),
+		MinEmission:     req.MinEmission,
+		ExemptNewEnergy: req.ExemptNewEnergy,
+		Description:     req.Description,
+		Active:          true,
+	}
+	if err := db.DB.Create(&rule).Error; err != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "保存规则失败")
+		return
+	}
+	reloadZones()
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteZoneRule 删除区域规则 (管理员)
+func DeleteZoneRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "无效的规则 ID")
+		return
+	}
+
+	result := db.DB.Delete(&model.ZoneRule{}, id)
+	if result.Error != nil {
+		respondError(c, http.StatusInternalServerError, CodeDatabaseError, "删除规则失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondError(c, http.StatusNotFound, CodeNotFound, "规则不存在")
+		return
+	}
+	reloadZones()
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "规则已删除")})
+}
+
+// reloadZones 围栏或区域规则变更后重新加载到路网
+func reloadZones() {
+	g := CurrentGraph()
+	if g == nil {
+		return
+	}
+	if _, err := g.ReloadZones(); err != nil {
+		slog.Warn("重新加载区域规则失败", "error", err)
+	}
+}
@@ -0,0 +1,309 @@
+package i18n
+
+// english 英文译文
+var english = map[string]string{
+	// 通用错误
+	"请求参数错误":  "Invalid request parameters",
+	"地图数据未加载": "Map data is not loaded",
+	"数据库查询出错": "Database query failed",
+	"坐标超出范围 (纬度 -90 ~ 90，经度 -180 ~ 180)": "Coordinate out of range (latitude -90 ~ 90, longitude -180 ~ 180)",
+	"坐标系无效 (可选 wgs84、gcj02)":             "Invalid coordinate system (wgs84 or gcj02)",
+	"departure_times 最多 6 个":             "departure_times accepts at most 6 times",
+	"比较出发时间暂不支持电动车充电规划和历史回放":             "Comparing departure times is not supported with EV charging or history replay",
+	"雨雪天气：步行的预计时间已延长":                    "Rain or snow: walking times have been extended",
+	"雨雪天气：步行和骑行的预计时间已延长，并尽量避免骑行":         "Rain or snow: walking and cycling times have been extended, and cycling is avoided where possible",
+	"全天用时曲线暂不支持电动车充电规划和历史回放":             "The travel-time profile does not support EV charging or history replay",
+	"日期格式错误，应为 YYYY-MM-DD":               "Invalid date, expected YYYY-MM-DD",
+	"时刻格式错误，应为 HH:MM":                    "Invalid time of day, expected HH:MM",
+	"结束时刻必须晚于开始时刻":                       "The end time must be later than the start time",
+	"采样间隔超出范围 (5 ~ 120 分钟)":              "Sampling interval out of range (5 ~ 120 minutes)",
+	"采样点过多 (最多 96 个)，请增大间隔或缩小时间范围":       "Too many samples (at most 96); increase the interval or narrow the time range",
+	"无效的交通方式":                            "Invalid travel mode",
+	"未指定有效的交通方式":                         "No valid travel mode specified",
+	"无效的瓦片坐标":                            "Invalid tile coordinates",
+	"缺少搜索关键词":                            "Missing search keyword",
+	"limit 超出范围 (1 ~ 10)":                "limit out of range (1 ~ 10)",
+	"已记录":                                "Recorded",
+	"分类不存在":                              "Category not found",
+	"查询半径超出范围 (0 ~ 200 米)":               "Search radius out of range (0 ~ 200 m)",
+	"相似度超出范围 (0 ~ 1)":                    "Similarity out of range (0 ~ 1)",
+	"保留节点不能同时被合并":                        "The kept node cannot also be merged",
+	"被合并节点与保留节点相距过远 (超过 200 米)":          "Merged nodes are too far from the kept node (over 200 m)",
+	"合并节点失败":                             "Failed to merge nodes",
+	"节点已合并，但重新加载路网失败":                    "Nodes merged, but reloading the road network failed",
+	"节点已合并":                              "Nodes merged",
+	"读取请求体失败 (最大 64 MB)":                 "Failed to read request body (max 64 MB)",
+	"导入失败":                               "Import failed",
+	"数据校验失败":                             "Data validation failed",
+	"已退出登录":                              "Logged out",
+	"注销失败":                               "Failed to log out",
+	"请求过于频繁，请稍后再试":                       "Too many requests, please try again later",
+	"生成快照失败":                             "Failed to create snapshot",
+	"未配置快照发布位置 (GRAPH_SNAPSHOT_PUBLISH)": "Snapshot publish location is not configured (GRAPH_SNAPSHOT_PUBLISH)",
+	"发布快照失败":                             "Failed to publish snapshot",
+	"未知的任务类型":                            "Unknown job kind",
+	"创建任务失败":                             "Failed to create job",
+	"任务不存在":                              "Job not found",
+	"无效的时间":                              "Invalid time",
+	"开始时间必须早于结束时间":                       "Start time must be before end time",
+	"precision 超出范围 (1 ~ 9)":             "precision out of range (1 ~ 9)",
+	"kind 应为 start 或 end":                "kind must be start or end",
+	"format 应为 csv 或 parquet":            "format must be csv or parquet",
+	"zone 应为 geohash 或 node":             "zone must be geohash or node",
+	"min_count 不能低于匿名阈值":                 "min_count cannot be below the anonymity threshold",
+	"生成导出文件失败":                           "Failed to generate export file",
+	"回放时间必须早于当前时间":                       "Replay time must be in the past",
+	"读取历史路况失败":                           "Failed to load historical traffic",
+	"无效的修改":                              "Invalid edit",
+	"无效的临时路段":                            "Invalid overlay",
+	"仍在原路线上":                             "Still on the original route",
+	"读取路线失败":                             "Failed to load route",
+	"路线不存在或已过期":                          "Route not found or expired",
+	"路径规划超时，请缩小范围后重试":                    "Route planning timed out, please narrow the search and try again",
+	"计算超时，返回的是近似路径":                      "Computation timed out, an approximate route is returned",
+	"请求已取消":                              "Request cancelled",
+	"原路线仍是最优路线":                          "The original route is still the best route",
+	"原路线已失效，且未找到新的路线":                    "The original route is no longer valid and no new route was found",
+	"按当前路况未找到路线":                         "No route found under current conditions",
+	"已有更合适的路线":                           "A better route is available",
+	"原路线部分路段已无法通行，已重新规划":                 "Part of the original route is no longer passable; a new route has been planned",
+	"收藏的路线数量已达上限":                        "You have reached the maximum number of saved routes",
+	"未找到符合条件的路径，无法收藏":                    "No route found matching the criteria; it cannot be saved",
+	"无效的路线 ID":                           "Invalid route ID",
+	"删除路线失败":                             "Failed to delete route",
+	"收藏的路线不存在":                           "Saved route not found",
+	"收藏的路线已删除":                           "Saved route deleted",
+	"到达时间格式错误，应为 HH:MM":                  "Invalid arrival time format, expected HH:MM",
+	"通勤计划数量已达上限":                         "You have reached the maximum number of commutes",
+	"未找到符合条件的路径，无法创建通勤计划":                "No route found matching the criteria; the commute cannot be created",
+	"保存通勤计划失败":                           "Failed to save commute",
+	"无效的通勤计划 ID":                         "Invalid commute ID",
+	"删除通勤计划失败":                           "Failed to delete commute",
+	"通勤计划不存在":                            "Commute not found",
+	"通勤计划已删除":                            "Commute deleted",
+	"感谢反馈，我们会尽快核实":                       "Thanks for your feedback, we will look into it shortly",
+	"无效的反馈类型":                            "Invalid feedback type",
+	"路段需要同时指定 from_id 和 to_id，位置需要同时指定 lat 和 lng": "A road segment needs both from_id and to_id, and a location needs both lat and lng",
+	"请指定路线、节点、路段或位置":                              "Specify a route, node, road segment or location",
+	"路段不存在":                 "Road segment not found",
+	"保存反馈失败":                "Failed to save feedback",
+	"无效的处理状态":               "Invalid feedback status",
+	"更新反馈失败":                "Failed to update feedback",
+	"无效的反馈 ID":              "Invalid feedback ID",
+	"反馈不存在":                 "Feedback not found",
+	"读取请求体失败 (最大 256 KB)":   "Failed to read request body (max 256 KB)",
+	"解析 JSON 失败":            "Failed to parse JSON",
+	"请填写修改说明 (不超过 500 字)":   "Please provide a description of the change (up to 500 characters)",
+	"没有任何修改":                "The change is empty",
+	"一次修改的节点和边过多 (最多 50 个)": "Too many nodes and edges in one change (max 50)",
+	"待审核的贡献数量已达上限":          "Too many contributions awaiting review",
+	"保存贡献失败":                "Failed to save contribution",
+	"贡献与当前地图冲突":             "The contribution conflicts with the current map",
+	"更新贡献失败":                "Failed to update contribution",
+	"贡献已审核":                 "The contribution has already been reviewed",
+	"无效的贡献 ID":              "Invalid contribution ID",
+	"贡献不存在":                 "Contribution not found",
+	"旧密码错误":                 "Old password is incorrect",
+	"新密码不能与旧密码相同":           "The new password must differ from the old one",
+	"修改密码失败":                "Failed to change password",
+	"密码已修改，其他设备需要重新登录":      "Password changed; other devices need to sign in again",
+	"密码错误，无法删除账号":           "Incorrect password, account not deleted",
+	"删除账号失败":                "Failed to delete account",
+	"账号已删除":                 "Account deleted",
+	"登录失败次数过多，请稍后再试":        "Too many failed sign-in attempts, please try again later",
+	"请完成人机验证":               "Please complete the captcha",
+	"人机验证服务暂时不可用":           "Captcha verification is temporarily unavailable",
+	"无效的 API Key":           "Invalid API key",
+	"API Key 已撤销或已过期":       "The API key has been revoked or has expired",
+	"API Key 没有该接口的权限":      "The API key is not allowed to access this endpoint",
+	"API Key 不能访问用户接口":      "API keys cannot access user endpoints",
+	"无效的权限范围":               "Invalid scope",
+	"过期时间必须晚于当前时间":          "The expiry time must be in the future",
+	"生成 API Key 失败":         "Failed to generate API key",
+	"保存 API Key 失败":         "Failed to save API key",
+	"请妥善保存密钥，之后无法再次查看":      "Store the key securely; it cannot be shown again",
+	"无效的 API Key ID":        "Invalid API key ID",
+	"API Key 不存在":           "API key not found",
+	"API Key 已撤销":           "API key revoked",
+	"撤销 API Key 失败":         "Failed to revoke API key",
+	"今日请求次数已达配额上限":          "Daily request quota exceeded",
+	"days 超出范围 (1 ~ 90)":    "days is out of range (1 ~ 90)",
+	"链接已过期":                 "The link has expired",
+	"需要签名链接":                "A signed link is required",
+	"链接签名无效":                "Invalid link signature",
+	"未配置签名密钥":               "URL signing is not configured",
+	"无效的转弯规则类型":             "Invalid turn restriction type",
+	"转弯惩罚超出范围 (0 ~ 600 秒)":  "Turn penalty out of range (0 ~ 600 seconds)",
+	"路口前后没有可以驾车的边":          "No drivable edges into and out of the junction",
+	"请求体不能为空":               "Request body must not be empty",
+	"limit 超出范围 (1 ~ 100)":  "limit out of range (1 ~ 100)",
+	"变更记录不存在":               "Change record not found",
+	"数据已导入，但重新加载路网失败":       "Data imported, but reloading the road network failed",
+	"limit 超出范围 (1 ~ 50)":   "limit out of range (1 ~ 50)",
+	"near 格式错误，应为 lat,lng":  "Invalid near, expected lat,lng",
+
+	// 节点、线路
+	"节点不存在":    "Node not found",
+	"站点不存在":    "Stop not found",
+	"线路不存在":    "Line not found",
+	"起点不存在":    "Start node not found",
+	"终点不存在":    "End node not found",
+	"起点或终点不存在": "Start or end node not found",
+	"起点或终点未指定": "Start or end not specified",
+
+	// 路径规划
+	"路径规划成功":                            "Route planned successfully",
+	"未找到符合条件的路径":                        "No route matches the given conditions",
+	"未找到符合条件的路径，无法分享":                   "No route matches the given conditions, cannot share",
+	"未找到符合条件的路径，无法监控":                   "No route matches the given conditions, cannot monitor",
+	"终点附近没有可到达的停车场，已按普通路线规划":            "No reachable parking lot near the destination, planned a regular route instead",
+	"剩余电量不足，且沿途没有可用的充电站":                "Insufficient charge and no usable charging station along the way",
+	"绕路比例超出范围 (1.1 ~ 5)":                "Detour ratio out of range (1.1 ~ 5)",
+	"停车场查找半径超出范围 (0 ~ 5000 米)":          "Parking search radius out of range (0 ~ 5000 m)",
+	"查询半径超出范围 (0 ~ 2000 米)":             "Search radius out of range (0 ~ 2000 m)",
+	"limit 超出范围 (1 ~ 20)":               "limit out of range (1 ~ 20)",
+	"缺少或无效的坐标 (lat, lng)":               "Missing or invalid coordinates (lat, lng)",
+	"查询半径超出范围 (0 ~ 5000 米)":             "Search radius out of range (0 ~ 5000 m)",
+	"简化容差超出范围 (0 ~ 1000 米)":             "Simplification tolerance out of range (0 ~ 1000 m)",
+	"缩放级别超出范围 (0 ~ 22)":                 "Zoom level out of range (0 ~ 22)",
+	"步行速度超出范围 (0.5 ~ 3.0 米/秒)":          "Walking speed out of range (0.5 ~ 3.0 m/s)",
+	"电动车参数错误 (续航需大于 0，电量 0 ~ 100)":      "Invalid EV parameters (range must be positive, charge 0 ~ 100)",
+	"电动车充电规划暂不支持与停车场同时使用":               "EV charging cannot be combined with parking yet",
+	"排放标准超出范围 (1 ~ 6，0 表示未知)":           "Emission standard out of range (1 ~ 6, 0 means unknown)",
+	"车辆尺寸不能为负数":                         "Vehicle dimensions must not be negative",
+	"时间格式错误，应为 RFC3339 或 HH:MM":         "Invalid time format, expected RFC3339 or HH:MM",
+	"无效的单位制，应为 metric 或 imperial":       "Invalid units, expected metric or imperial",
+	"timeout_ms 超出范围 (0 ~ 60000)":       "timeout_ms out of range (0 ~ 60000)",
+	"日志级别无效 (可选 debug、info、warn、error)": "Invalid log level (debug, info, warn or error)",
+	"持续时间格式错误 (如 15m、1h)":               "Invalid duration (e.g. 15m, 1h)",
+	"持续时间超出范围 (最长 24 小时)":               "Duration out of range (at most 24 hours)",
+	"服务器内部错误":                           "Internal server error",
+	"起点":                                "Start",
+	"当前位置":                              "Current location",
+	"终点":                                "End",
+
+	// 行程说明
+	"在 %s 乘坐 %s 经过 %d 站，到 %s 下车": "Take %[2]s from %[1]s for %[3]d stops, get off at %[4]s",
+	"%s %s 到 %s":  "%s %s to %s",
+	"，经过%s":       ", via %s",
+	"，走楼梯到%s":     ", take the stairs to %s",
+	"，乘自动扶梯到%s":   ", take the escalator to %s",
+	"，乘电梯到%s":     ", take the elevator to %s",
+	"地面层":         "ground level",
+	"%d 楼":        "floor %d",
+	"地下 %d 层":     "basement level %d",
+	"%.0f 米":      "%.0f m",
+	"%.1f 公里":     "%.1f km",
+	"%.0f 英尺":     "%.0f ft",
+	"%.1f 英里":     "%.1f mi",
+	"%d 分钟":       "%d min",
+	"%d 小时":       "%d h",
+	"%d 小时 %d 分钟": "%d h %d min",
+	"步行":          "Walk",
+	"骑行":          "Cycle",
+	"驾车":          "Drive",
+	"货车":          "Truck",
+	"公交":          "Bus",
+	"地铁":          "Subway",
+	"人行横道":        "crosswalk",
+	"过街天桥":        "footbridge",
+	"地下通道":        "underpass",
+	"站内通道":        "station corridor",
+	"、":           ", ",
+
+	// 节点别名
+	"别名不能为空": "Alias must not be empty",
+	"别名已存在":  "Alias already exists",
+	"别名不存在":  "Alias not found",
+	"别名已删除":  "Alias deleted",
+	"保存别名失败": "Failed to save alias",
+	"删除别名失败": "Failed to delete alias",
+
+	// 用户
+	"用户名或密码错误":         "Incorrect username or password",
+	"用户名已存在":           "Username already exists",
+	"用户不存在":            "User not found",
+	"注册成功":             "Registered successfully",
+	"登录成功":             "Logged in successfully",
+	"注册用户失败":           "Failed to register user",
+	"密码加密失败":           "Failed to hash password",
+	"生成 Token 失败":      "Failed to generate token",
+	"未提供 Token":        "Token not provided",
+	"无效的 Token":        "Invalid token",
+	"令牌已失效":            "Token has been revoked",
+	"需要管理员权限":          "Administrator permission required",
+	"不支持的登录方式":         "Unsupported login provider",
+	"第三方授权失败":          "Third-party authorization failed",
+	"第三方登录失败":          "Third-party login failed",
+	"该第三方账号已关联其他用户":    "This third-party account is linked to another user",
+	"第三方身份已关联其他用户":     "This third-party identity is linked to another user",
+	"偏好已更新":            "Preferences updated",
+	"保存偏好失败":           "Failed to save preferences",
+	"如果该邮箱已注册，重置邮件已发送": "If the email is registered, a reset email has been sent",
+	"生成重置令牌失败":         "Failed to generate reset token",
+	"重置链接无效或已过期":       "Reset link is invalid or expired",
+	"重置密码失败":           "Failed to reset password",
+	"密码重置成功，请重新登录":     "Password reset, please log in again",
+	"缺少验证令牌":           "Missing verification token",
+	"验证链接无效或已过期":       "Verification link is invalid or expired",
+	"邮箱验证失败":           "Email verification failed",
+	"邮箱验证成功":           "Email verified",
+
+	// 行程、分享、监控
+	"保存行程失败":            "Failed to save trip",
+	"保存分享失败":            "Failed to save share",
+	"生成分享令牌失败":          "Failed to generate share token",
+	"分享不存在":             "Share not found",
+	"分享已过期":             "Share has expired",
+	"保存路线失败":            "Failed to save route",
+	"路线数据损坏":            "Route data is corrupted",
+	"保存路线监控失败":          "Failed to save route monitor",
+	"删除路线监控失败":          "Failed to delete route monitor",
+	"路线监控不存在":           "Route monitor not found",
+	"路线监控已删除":           "Route monitor deleted",
+	"路线监控数量已达上限":        "Route monitor limit reached",
+	"无效的监控 ID":          "Invalid monitor ID",
+	"时间窗口格式错误，应为 HH:MM": "Invalid time window format, expected HH:MM",
+
+	// 围栏、区域规则
+	"围栏不存在":                   "Geofence not found",
+	"围栏已删除":                   "Geofence deleted",
+	"保存围栏失败":                  "Failed to save geofence",
+	"删除围栏失败":                  "Failed to delete geofence",
+	"需要提供 point、route 或 path": "point, route or path is required",
+	"规则不存在":                   "Rule not found",
+	"规则已删除":                   "Rule deleted",
+	"保存规则失败":                  "Failed to save rule",
+	"删除规则失败":                  "Failed to delete rule",
+	"无效的规则 ID":                "Invalid rule ID",
+	"无效的规则类型":                 "Invalid rule type",
+	"收费规则需要指定费用":              "Charge rules require a fee",
+	"限行规则需要指定尾号":              "Plate rules require plate digits",
+	"限行尾号必须是 0 ~ 9":           "Plate digits must be 0 ~ 9",
+	"排放标准超出范围 (1 ~ 6)":        "Emission standard out of range (1 ~ 6)",
+	"星期必须是 0 ~ 6":             "Days must be 0 ~ 6",
+	"开始时间格式错误 (HH:MM)":        "Invalid start time (HH:MM)",
+	"结束时间格式错误 (HH:MM)":        "Invalid end time (HH:MM)",
+	"开始和结束时间需要同时指定":           "Start and end time must be specified together",
+
+	// Webhook、实时数据
+	"Webhook 不存在":          "Webhook not found",
+	"Webhook 已删除":          "Webhook deleted",
+	"保存 Webhook 失败":        "Failed to save webhook",
+	"删除 Webhook 失败":        "Failed to delete webhook",
+	"无效的 Webhook ID":       "Invalid webhook ID",
+	"无效的事件":                "Invalid event",
+	"回调地址必须是 http 或 https": "Callback URL must be http or https",
+	"生成签名密钥失败":             "Failed to generate signing secret",
+	"投递成功":                 "Delivered successfully",
+	"投递失败":                 "Delivery failed",
+	"实时数据接入未启用":            "Realtime feed is not enabled",
+	"该功能需要数据库 (当前为无数据库模式)":                         "This feature requires a database (running without one)",
+	"Idempotency-Key 过长 (最多 255 个字符)":              "Idempotency-Key is too long (at most 255 characters)",
+	"相同 Idempotency-Key 的请求正在处理":                   "A request with the same Idempotency-Key is still being processed",
+	"Idempotency-Key 已用于其他请求":                      "Idempotency-Key was already used for a different request",
+	"读取请求体失败":                                      "Failed to read the request body",
+	"bbox 格式错误，应为 min_lat,min_lng,max_lat,max_lng": "Invalid bbox, expected min_lat,min_lng,max_lat,max_lng",
+	"offset 不能为负数":                                 "offset must not be negative",
+	"无效的数据源令牌":                                     "Invalid feed token",
+}
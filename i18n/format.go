@@ -0,0 +1,49 @@
+package i18n
+
+import "math"
+
+// 单位制
+const (
+	Metric   = "metric"   // 米、公里
+	Imperial = "imperial" // 英尺、英里
+)
+
+const (
+	metersPerFoot = 0.3048
+	metersPerMile = 1609.344
+)
+
+// ValidUnits 单位制是否有效 (空字符串表示默认的公制)
+func ValidUnits(units string) bool {
+	return units == "" || units == Metric || units == Imperial
+}
+
+// FormatDistance 格式化距离
+// 公制不足 1 公里显示米；英制不足 0.1 英里显示英尺
+func FormatDistance(lang, units string, meters float64) string {
+	if units == Imperial {
+		if meters < 0.1*metersPerMile {
+			return Sprintf(lang, "%.0f 英尺", meters/metersPerFoot)
+		}
+		return Sprintf(lang, "%.1f 英里", meters/metersPerMile)
+	}
+	if meters < 1000 {
+		return Sprintf(lang, "%.0f 米", meters)
+	}
+	return Sprintf(lang, "%.1f 公里", meters/1000)
+}
+
+// FormatDuration 格式化时长 (按分钟四舍五入，不足 1 分钟显示 1 分钟)
+func FormatDuration(lang string, seconds float64) string {
+	minutes := int(math.Round(seconds / 60))
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes < 60 {
+		return Sprintf(lang, "%d 分钟", minutes)
+	}
+	if minutes%60 == 0 {
+		return Sprintf(lang, "%d 小时", minutes/60)
+	}
+	return Sprintf(lang, "%d 小时 %d 分钟", minutes/60, minutes%60)
+}
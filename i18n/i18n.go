@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言 (消息原文为中文，其他语言在 catalogs 中查找译文)
+const (
+	Chinese = "zh"
+	English = "en"
+)
+
+// Default 默认语言
+const Default = Chinese
+
+// catalogs 语言 -> (中文原文 -> 译文)
+var catalogs = map[string]map[string]string{
+	English: english,
+}
+
+// Parse 根据 Accept-Language 请求头选择语言 (按 q 值从高到低取第一个支持的语言)
+// 如 "en-US,en;q=0.9,zh;q=0.8" 返回 "en"，不支持或为空时返回默认语言
+func Parse(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// 只看主语言 (zh-CN、zh-TW 都视为中文)
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && (base == Chinese || catalogs[base] != nil) {
+			candidates = append(candidates, candidate{base, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// T 翻译消息，没有译文时返回原文
+// "前缀: 详情" 形式的消息 (如 "节点不存在: xxx") 只翻译前缀
+func T(lang, msg string) string {
+	catalog := catalogs[lang]
+	if catalog == nil {
+		return msg
+	}
+	if s, ok := catalog[msg]; ok {
+		return s
+	}
+	if prefix, detail, ok := strings.Cut(msg, ": "); ok {
+		if s, ok := catalog[prefix]; ok {
+			return s + ": " + detail
+		}
+	}
+	return msg
+}
+
+// Sprintf 翻译格式串后再格式化
+func Sprintf(lang, format string, args ...any) string {
+	return fmt.Sprintf(T(lang, format), args...)
+}
+
+// Pick 多语言字段取值：指定语言有值时使用，否则使用中文
+func Pick(lang, zh, en string) string {
+	if lang == English && en != "" {
+		return en
+	}
+	return zh
+}
@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	"traffic-system/crash"
+	"traffic-system/db"
+	"traffic-system/model"
+)
+
+// Func 任务的执行函数，返回值序列化为 JSON 保存到 Result
+type Func func(ctx context.Context, params json.RawMessage) (any, error)
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Func)
+	wake     = make(chan struct{}, 1)
+)
+
+// ErrUnknownKind 没有注册的任务类型
+var ErrUnknownKind = errors.New("未知的任务类型")
+
+// Register 注册任务类型 (应在 Start 之前调用)
+func Register(kind string, fn Func) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[kind] = fn
+}
+
+// Kinds 已注册的任务类型
+func Kinds() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+func lookup(kind string) (Func, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fn, ok := registry[kind]
+	return fn, ok
+}
+
+// Enqueue 创建一个排队中的任务并唤醒本实例的执行器
+func Enqueue(kind string, params any, userID uint) (*model.Job, error) {
+	if _, ok := lookup(kind); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	job := model.Job{Kind: kind, Status: model.JobQueued, UserID: userID}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		job.Params = model.JSONText(data)
+	}
+	if err := db.DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return &job, nil
+}
+
+// Start 启动 workers 个执行器，每隔 poll 检查一次排队中的任务 (本实例创建任务时立即检查)
+// 执行超过 timeout 的任务 (如执行中的实例已退出) 标记为失败
+func Start(workers int, poll, timeout time.Duration) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(poll)
+			defer ticker.Stop()
+			for {
+				failStale(timeout)
+				// 一次把排队的任务做完
+				for runNext(timeout) {
+				}
+				select {
+				case <-ticker.C:
+				case <-wake:
+				}
+			}
+		}()
+	}
+}
+
+// runNext 领取并执行最早排队的任务，没有任务时返回 false
+func runNext(timeout time.Duration) bool {
+	job, err := claim()
+	if err != nil {
+		slog.Error("领取后台任务失败", "error", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+	run(job, timeout)
+	return true
+}
+
+// claim 把最早排队的任务改为执行中；多个执行器 (包括其他实例) 同时领取时只有一个成功
+func claim() (*model.Job, error) {
+	for {
+		var job model.Job
+		err := db.DB.Where("status = ?", model.JobQueued).Order("id").Limit(1).Find(&job).Error
+		if err != nil || job.ID == 0 {
+			return nil, err
+		}
+		now := time.Now()
+		result := db.DB.Model(&model.Job{}).Where("id = ? AND status = ?", job.ID, model.JobQueued).
+			Updates(map[string]interface{}{"status": model.JobRunning, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = model.JobRunning
+			job.StartedAt = &now
+			return &job, nil
+		}
+		// 被其他执行器抢先领取，继续找下一个
+	}
+}
+
+// run 执行任务并保存结果，执行函数 panic 时任务记为失败
+func run(job *model.Job, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var result any
+	var err error
+	if fn, ok := lookup(job.Kind); !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownKind, job.Kind)
+	} else {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					report := crash.Capture(r)
+					report.Source = "job"
+					report.Tags["job_id"] = strconv.FormatUint(uint64(job.ID), 10)
+					report.Tags["job_kind"] = job.Kind
+					crash.Send(report)
+					err = fmt.Errorf("任务执行出错: %v (event_id=%s)", r, report.ID)
+				}
+			}()
+			result, err = fn(ctx, json.RawMessage(job.Params))
+		}()
+	}
+
+	updates := map[string]interface{}{"status": model.JobSucceeded, "finished_at": time.Now()}
+	if err != nil {
+		updates["status"] = model.JobFailed
+		updates["error"] = err.Error()
+		slog.Error("后台任务失败", "job_id", job.ID, "kind", job.Kind, "error", err)
+	} else {
+		if result != nil {
+			if data, err := json.Marshal(result); err == nil {
+				updates["result"] = string(data)
+			}
+		}
+		slog.Info("后台任务完成", "job_id", job.ID, "kind", job.Kind)
+	}
+	if err := db.DB.Model(&model.Job{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		slog.Error("保存后台任务的结果失败", "job_id", job.ID, "error", err)
+	}
+}
+
+// failStale 把执行超时 (执行中的实例可能已经退出) 的任务标记为失败
+func failStale(timeout time.Duration) {
+	err := db.DB.Model(&model.Job{}).
+		Where("status = ? AND started_at < ?", model.JobRunning, time.Now().Add(-timeout-time.Minute)).
+		Updates(map[string]interface{}{"status": model.JobFailed, "error": "执行超时或服务中断", "finished_at": time.Now()}).Error
+	if err != nil {
+		slog.Error("检查超时的后台任务失败", "error", err)
+	}
+}
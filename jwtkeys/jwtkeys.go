@@ -0,0 +1,221 @@
+package jwtkeys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"traffic-system/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key 一个签名密钥，ID 写入 Token 头部的 kid
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   interface{} // HS256 为 []byte，RS256 为 *rsa.PrivateKey；只用于校验的旧密钥为 nil
+	verify interface{} // HS256 为 []byte，RS256 为 *rsa.PublicKey
+}
+
+// Set 当前用于签发 Token 的密钥，以及轮换期间仍然接受的旧密钥
+type Set struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// Default 全局密钥集 (应在 main 中通过 Init 初始化)，未初始化时使用进程启动时随机生成的密钥
+var Default = randomSet()
+
+// Init 根据环境变量加载密钥，配置错误时退出
+func Init() {
+	set, err := Load()
+	if err != nil {
+		log.Fatalf("JWT 密钥配置错误: %v", err)
+	}
+	if set == nil {
+		slog.Warn("未配置 JWT_SECRET，使用随机生成的密钥 (重启后所有登录失效，多个实例之间不通用)")
+		return
+	}
+	Default = set
+	slog.Info("JWT 签发密钥已加载", "alg", set.current.Method.Alg(), "kid", set.current.ID, "keys", len(set.keys))
+}
+
+// Load 读取密钥配置，没有配置任何签发密钥时返回 nil：
+//   - JWT_ALGORITHM 为 HS256 (默认) 时使用 JWT_SECRET，或 JWT_SECRET_FILE 指向的文件 (如由 KMS / 密钥管理服务挂载)
+//   - JWT_ALGORITHM 为 RS256 时使用 JWT_PRIVATE_KEY_FILE (PEM)，其他服务可以通过公钥校验 Token
+//   - JWT_KEY_ID 为当前密钥的 kid (默认 "1")
+//   - 轮换时把旧密钥放入 JWT_PREVIOUS_SECRETS (HS256，"kid=secret,...") 或
+//     JWT_PREVIOUS_PUBLIC_KEYS (RS256，"kid=公钥 PEM 文件,...")，旧密钥签发的 Token 到期前仍然有效
+func Load() (*Set, error) {
+	id := config.GetString("JWT_KEY_ID", "1")
+	var current *Key
+	switch alg := strings.ToUpper(config.GetString("JWT_ALGORITHM", "HS256")); alg {
+	case "HS256":
+		secret, err := readSecret()
+		if err != nil || secret == nil {
+			return nil, err
+		}
+		current = &Key{ID: id, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+	case "RS256":
+		path := config.GetString("JWT_PRIVATE_KEY_FILE", "")
+		if path == "" {
+			return nil, errors.New("JWT_ALGORITHM=RS256 时必须配置 JWT_PRIVATE_KEY_FILE")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %w", err)
+		}
+		current = &Key{ID: id, Method: jwt.SigningMethodRS256, sign: private, verify: &private.PublicKey}
+	default:
+		return nil, fmt.Errorf("不支持的 JWT_ALGORITHM: %s", alg)
+	}
+
+	set := &Set{current: current, keys: map[string]*Key{id: current}}
+	previous, err := parsePairs(config.GetString("JWT_PREVIOUS_SECRETS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_PREVIOUS_SECRETS: %w", err)
+	}
+	for kid, secret := range previous {
+		if err := set.add(&Key{ID: kid, Method: jwt.SigningMethodHS256, verify: []byte(secret)}); err != nil {
+			return nil, err
+		}
+	}
+	previous, err = parsePairs(config.GetString("JWT_PREVIOUS_PUBLIC_KEYS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("JWT_PREVIOUS_PUBLIC_KEYS: %w", err)
+	}
+	for kid, path := range previous {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("读取公钥 %s 失败: %w", path, err)
+		}
+		if err := set.add(&Key{ID: kid, Method: jwt.SigningMethodRS256, verify: public}); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// readSecret 读取 HS256 密钥：JWT_SECRET 优先，其次是 JWT_SECRET_FILE (去掉首尾空白)，都没有配置时返回 nil
+func readSecret() ([]byte, error) {
+	if secret := config.GetString("JWT_SECRET", ""); secret != "" {
+		return []byte(secret), nil
+	}
+	path := config.GetString("JWT_SECRET_FILE", "")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return nil, fmt.Errorf("%s 为空", path)
+	}
+	return []byte(secret), nil
+}
+
+// parsePairs 解析 "kid=value,kid=value" 格式的配置
+func parsePairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, value, ok := strings.Cut(item, "=")
+		if !ok || kid == "" || value == "" {
+			return nil, fmt.Errorf("格式错误 (应为 kid=value): %s", item)
+		}
+		pairs[kid] = value
+	}
+	return pairs, nil
+}
+
+// add 加入一个只用于校验的旧密钥，kid 不能重复
+func (s *Set) add(key *Key) error {
+	if _, ok := s.keys[key.ID]; ok {
+		return fmt.Errorf("密钥 kid=%s 重复", key.ID)
+	}
+	s.keys[key.ID] = key
+	return nil
+}
+
+// randomSet 随机生成的 HS256 密钥 (未配置密钥时使用)
+func randomSet() *Set {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	key := &Key{ID: "random", Method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+	return &Set{current: key, keys: map[string]*Key{key.ID: key}}
+}
+
+// Sign 用当前密钥签发 Token，头部带有 kid
+func (s *Set) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.current.Method, claims)
+	token.Header["kid"] = s.current.ID
+	return token.SignedString(s.current.sign)
+}
+
+// Keyfunc 按 Token 头部的 kid 选择校验密钥 (没有 kid 时使用当前密钥)，签名算法必须与密钥一致
+func (s *Set) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := s.current
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key = s.keys[kid]; key == nil {
+			return nil, fmt.Errorf("未知的密钥 kid=%s", kid)
+		}
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("签名算法 %s 与密钥不符", token.Method.Alg())
+	}
+	return key.verify, nil
+}
+
+// JWK 公钥 (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS 所有 RS256 密钥的公钥 (当前和轮换中的旧密钥)，HS256 密钥不公开
+func (s *Set) JWKS() []JWK {
+	keys := []JWK{}
+	for _, key := range s.keys {
+		public, ok := key.verify.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		keys = append(keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			Kid: key.ID,
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
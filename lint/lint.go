@@ -0,0 +1,271 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"traffic-system/model"
+)
+
+// 规则名称
+const (
+	RuleMaxEdgeLength = "max_edge_length" // 边的长度超过其交通方式的上限
+	RuleNodeName      = "node_name"       // 节点名称不符合其类型的命名规范
+	RuleRequiredModes = "required_modes"  // 边缺少其类型 (道路等级、步行设施、垂直通道) 必须包含的交通方式
+	RuleBBox          = "bbox"            // 节点坐标不在城市范围内
+)
+
+// 违规的严重程度：error 阻止导入，warning 只报告，off 不检查
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityOff     = "off"
+)
+
+// maxViolations 一次检查最多报告的违规数
+const maxViolations = 1000
+
+// Config 检查规则配置 (JSON 文件，见 Load)
+type Config struct {
+	// 按交通方式的边长上限 (米)；有多种方式的边按其中最大的上限检查
+	MaxEdgeLength map[string]float64 `json:"max_edge_length,omitempty"`
+	// 节点命名规范，按顺序匹配类型，type 为空时适用于所有节点
+	NodeNames []NamePattern `json:"node_names,omitempty"`
+	// 边的类型 (road_class、footway 或 connector 的取值) -> 必须包含的交通方式
+	RequiredModes map[string][]string `json:"required_modes,omitempty"`
+	// 城市范围，节点必须在范围内 (为空时不检查)
+	BBox *BBox `json:"bbox,omitempty"`
+	// 规则 -> 严重程度，未列出的规则为 warning
+	Severity map[string]string `json:"severity,omitempty"`
+}
+
+// NamePattern 某一类节点的名称必须匹配的正则表达式
+type NamePattern struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// BBox 经纬度范围
+type BBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLng float64 `json:"min_lng"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLng float64 `json:"max_lng"`
+}
+
+// Violation 一条违规，节点违规填写 NodeID，边违规填写 From、To 和 LineID
+type Violation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	NodeID   string `json:"node_id,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	LineID   string `json:"line_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Report 检查结果
+type Report struct {
+	Errors     int         `json:"errors"`
+	Warnings   int         `json:"warnings"`
+	Truncated  bool        `json:"truncated,omitempty"` // 违规超过 1000 条，只列出前 1000 条
+	Violations []Violation `json:"violations"`
+}
+
+// Current 当前使用的规则 (应在 main 中根据 LINT_RULES 设置)
+var Current = Default()
+
+// Default 默认规则：与种子数据的命名习惯一致，全部为 warning，不阻止导入
+func Default() *Config {
+	cfg := &Config{
+		MaxEdgeLength: map[string]float64{
+			"walk":   3000,
+			"bike":   5000,
+			"car":    20000,
+			"truck":  20000,
+			"bus":    5000,
+			"subway": 10000,
+		},
+		NodeNames: []NamePattern{
+			{Type: "subway_entrance", Pattern: `^地铁站-`},
+			{Type: "bus_stop", Pattern: `^公交站-`},
+			{Type: model.NodeTypeParking, Pattern: `^停车场-`},
+			{Type: model.NodeTypeCharging, Pattern: `^充电站-`},
+			{Type: "road_node", Pattern: `^路口-`},
+			{Pattern: `\S`},
+		},
+		RequiredModes: map[string][]string{
+			model.RoadExpressway:         {"car"},
+			model.RoadArterial:           {"car"},
+			model.FootwayCrosswalk:       {"walk"},
+			model.FootwayFootbridge:      {"walk"},
+			model.FootwayUnderpass:       {"walk"},
+			model.FootwayStationCorridor: {"walk"},
+			model.ConnectorStairs:        {"walk"},
+			model.ConnectorEscalator:     {"walk"},
+			model.ConnectorElevator:      {"walk"},
+		},
+	}
+	if err := cfg.compile(); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// Load 读取 JSON 格式的规则文件，未出现的配置项使用默认值
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析规则文件失败: %w", err)
+	}
+	if err := cfg.compile(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// compile 检查配置并编译命名规范的正则表达式
+func (cfg *Config) compile() error {
+	for i := range cfg.NodeNames {
+		re, err := regexp.Compile(cfg.NodeNames[i].Pattern)
+		if err != nil {
+			return fmt.Errorf("node_names[%d] 的 pattern 无效: %w", i, err)
+		}
+		cfg.NodeNames[i].re = re
+	}
+	for mode, limit := range cfg.MaxEdgeLength {
+		if model.GetModeMask(mode) == 0 || limit <= 0 {
+			return fmt.Errorf("max_edge_length 无效: %s=%g", mode, limit)
+		}
+	}
+	for kind, modes := range cfg.RequiredModes {
+		for _, mode := range modes {
+			if model.GetModeMask(mode) == 0 {
+				return fmt.Errorf("required_modes.%s 的交通方式无效: %s", kind, mode)
+			}
+		}
+	}
+	if b := cfg.BBox; b != nil && (b.MinLat >= b.MaxLat || b.MinLng >= b.MaxLng) {
+		return fmt.Errorf("bbox 无效: 最小值必须小于最大值")
+	}
+	for rule, severity := range cfg.Severity {
+		switch rule {
+		case RuleMaxEdgeLength, RuleNodeName, RuleRequiredModes, RuleBBox:
+		default:
+			return fmt.Errorf("未知的规则: %s", rule)
+		}
+		if severity != SeverityError && severity != SeverityWarning && severity != SeverityOff {
+			return fmt.Errorf("规则 %s 的严重程度无效: %s (可选 error、warning、off)", rule, severity)
+		}
+	}
+	return nil
+}
+
+// severity 规则的严重程度 (未配置时为 warning)
+func (cfg *Config) severity(rule string) string {
+	if s, ok := cfg.Severity[rule]; ok {
+		return s
+	}
+	return SeverityWarning
+}
+
+// Check 按规则检查节点和边 (边的端点可以不在 nodes 中)
+func (cfg *Config) Check(nodes []model.Node, edges []model.Edge) *Report {
+	r := &checker{cfg: cfg, report: &Report{Violations: []Violation{}}}
+	for i := range nodes {
+		r.node(&nodes[i])
+	}
+	for i := range edges {
+		r.edge(&edges[i])
+	}
+	return r.report
+}
+
+// checker 一次检查的状态
+type checker struct {
+	cfg    *Config
+	report *Report
+}
+
+// add 记录一条违规 (规则为 off 时忽略)
+func (r *checker) add(rule string, v Violation, format string, args ...any) {
+	severity := r.cfg.severity(rule)
+	if severity == SeverityOff {
+		return
+	}
+	if severity == SeverityError {
+		r.report.Errors++
+	} else {
+		r.report.Warnings++
+	}
+	if len(r.report.Violations) >= maxViolations {
+		r.report.Truncated = true
+		return
+	}
+	v.Rule, v.Severity, v.Message = rule, severity, fmt.Sprintf(format, args...)
+	r.report.Violations = append(r.report.Violations, v)
+}
+
+// node 检查节点的命名和坐标
+func (r *checker) node(node *model.Node) {
+	at := Violation{NodeID: node.ID}
+	for _, p := range r.cfg.NodeNames {
+		if p.Type != "" && p.Type != node.Type {
+			continue
+		}
+		if !p.re.MatchString(node.Name) {
+			r.add(RuleNodeName, at, "节点 %s 的名称 %q 不符合命名规范 %s", node.ID, node.Name, p.Pattern)
+		}
+		break
+	}
+	if b := r.cfg.BBox; b != nil && (node.Lat < b.MinLat || node.Lat > b.MaxLat || node.Lng < b.MinLng || node.Lng > b.MaxLng) {
+		r.add(RuleBBox, at, "节点 %s 的坐标 (%g, %g) 不在城市范围内", node.ID, node.Lat, node.Lng)
+	}
+}
+
+// edge 检查边的长度和交通方式
+func (r *checker) edge(e *model.Edge) {
+	at := Violation{From: e.From, To: e.To, LineID: e.LineID}
+	limit := 0.0
+	for _, mode := range e.Modes {
+		limit = max(limit, r.cfg.MaxEdgeLength[mode])
+	}
+	if limit > 0 && e.Dist > limit {
+		r.add(RuleMaxEdgeLength, at, "边 %s -> %s 长 %.0f 米，超过上限 %.0f 米", e.From, e.To, e.Dist, limit)
+	}
+
+	for _, kind := range []string{e.RoadClass, e.Footway, e.Connector} {
+		if kind == "" {
+			continue
+		}
+		var missing []string
+		for _, mode := range r.cfg.RequiredModes[kind] {
+			if !slices.Contains(e.Modes, mode) {
+				missing = append(missing, mode)
+			}
+		}
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			r.add(RuleRequiredModes, at, "边 %s -> %s 的类型为 %s，缺少交通方式 %v", e.From, e.To, kind, missing)
+		}
+	}
+}
+
+// Problems 所有 error 级别违规的说明 (用于阻止导入时的错误信息)
+func (rep *Report) Problems() []string {
+	var problems []string
+	for _, v := range rep.Violations {
+		if v.Severity == SeverityError {
+			problems = append(problems, v.Rule+": "+v.Message)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"traffic-system/config"
+)
+
+// 结构化日志 (log/slog)
+// 日志级别保存在 LevelVar 中，运行时可以通过 PUT /api/admin/loglevel 临时调整 (如排查问题时打开路径规划的 debug 日志)，
+// 标准库 log 包的输出也经过同一个 Handler (按 info 级别)
+
+var (
+	level = new(slog.LevelVar)
+
+	mu      sync.Mutex
+	timer   *time.Timer // 临时级别到期后恢复
+	until   time.Time   // 临时级别的到期时间，零值表示没有临时级别
+	restore slog.Level  // 临时级别到期后恢复的级别
+	output  io.Writer   = os.Stderr
+)
+
+// Config 日志配置
+type Config struct {
+	Level      string // debug、info、warn、error
+	Format     string // text 或 json
+	File       string // 日志文件，为空时输出到标准错误
+	MaxSize    int64  // 单个日志文件的大小上限 (字节)，超过后轮转
+	MaxBackups int    // 保留的旧日志文件数
+}
+
+// Init 根据环境变量初始化日志，配置错误时退出
+func Init() {
+	cfg := Config{
+		Level:      config.GetString("LOG_LEVEL", "info"),
+		Format:     config.GetString("LOG_FORMAT", "text"),
+		File:       config.GetString("LOG_FILE", ""),
+		MaxSize:    int64(config.GetInt("LOG_MAX_SIZE", 100)) << 20,
+		MaxBackups: config.GetInt("LOG_MAX_BACKUPS", 5),
+	}
+	if err := Setup(cfg); err != nil {
+		log.Fatalf("日志配置错误: %v", err)
+	}
+}
+
+// Setup 按配置设置默认的 slog Logger
+func Setup(cfg Config) error {
+	l, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stderr
+	if cfg.File != "" {
+		f, err := OpenRotating(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("LOG_FORMAT 无效: %s (可选 text、json)", cfg.Format)
+	}
+	level.Set(l)
+	mu.Lock()
+	output = w
+	mu.Unlock()
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// Output 日志的输出位置 (如 Gin 的访问日志也写入同一个文件)
+func Output() io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+	return output
+}
+
+// ParseLevel 解析日志级别 (不区分大小写，warning 等同于 warn)
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("日志级别无效: %s (可选 debug、info、warn、error)", s)
+}
+
+// Level 当前的日志级别，以及临时级别的到期时间 (没有临时级别时为零值)
+func Level() (slog.Level, time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	return level.Level(), until
+}
+
+// SetLevel 修改日志级别；d > 0 时为临时修改，d 之后恢复为修改前的级别
+// 再次调用会取消之前的临时修改 (临时修改期间再次临时修改时，到期后恢复为最初的级别)
+func SetLevel(l slog.Level, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := level.Level()
+	if timer != nil {
+		timer.Stop()
+		previous = restore
+		timer = nil
+	}
+	level.Set(l)
+	until = time.Time{}
+	if d <= 0 {
+		return
+	}
+	restore = previous
+	until = time.Now().Add(d)
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != t {
+			return
+		}
+		level.Set(restore)
+		timer, until = nil, time.Time{}
+		slog.Info("临时日志级别已到期", "level", strings.ToLower(restore.String()))
+	})
+	timer = t
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile 按大小轮转的日志文件：当前文件超过 maxSize 时重命名为 path.1 (已有的 path.1 依次后移为 path.2 …)，
+// 只保留 maxBackups 个旧文件，再重新创建 path
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotating 打开 (追加写入) 日志文件，maxSize <= 0 时不轮转
+func OpenRotating(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 0)}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open 打开 path 并记录当前大小
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// Write 写入一条日志，写入后会超过大小上限时先轮转 (一条日志不会被拆到两个文件中)
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			// 轮转失败时继续写入当前文件，不丢日志
+			fmt.Fprintf(os.Stderr, "日志文件轮转失败: %v\n", err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件，旧文件依次后移，再重新创建 path
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.maxBackups == 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return r.reopen(err)
+		}
+		return r.open()
+	}
+	os.Remove(backupName(r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(r.path, i), backupName(r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return r.reopen(err)
+		}
+	}
+	if err := os.Rename(r.path, backupName(r.path, 1)); err != nil {
+		return r.reopen(err)
+	}
+	return r.open()
+}
+
+// reopen 轮转中途失败时重新打开当前文件，返回原来的错误
+func (r *RotatingFile) reopen(cause error) error {
+	if err := r.open(); err != nil {
+		return err
+	}
+	return cause
+}
+
+// Close 关闭当前文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// backupName 第 i 个旧文件的名称
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"traffic-system/config"
+)
+
+// Sender 邮件发送接口 (可替换为 SMTP、第三方邮件服务或测试用实现)
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// DefaultSender 全局邮件发送器 (应在 main 中通过 Init 初始化)
+var DefaultSender Sender = LogSender{}
+
+// Init 根据环境变量选择邮件发送器
+// 配置了 SMTP_HOST 时使用 SMTP 发送，否则只把邮件内容打印到日志 (开发环境)
+func Init() {
+	host := config.GetString("SMTP_HOST", "")
+	if host == "" {
+		slog.Warn("未配置 SMTP_HOST，邮件将只输出到日志")
+		DefaultSender = LogSender{}
+		return
+	}
+
+	DefaultSender = &SMTPSender{
+		Host:     host,
+		Port:     config.GetString("SMTP_PORT", "587"),
+		Username: config.GetString("SMTP_USER", ""),
+		Password: config.GetString("SMTP_PASSWORD", ""),
+		From:     config.GetString("SMTP_FROM", "noreply@vvmaps.local"),
+	}
+}
+
+// Send 使用全局发送器发送邮件
+func Send(to, subject, body string) error {
+	return DefaultSender.Send(to, subject, body)
+}
+
+// LogSender 把邮件打印到日志，不真正发送
+type LogSender struct{}
+
+func (LogSender) Send(to, subject, body string) error {
+	slog.Info("邮件 (未发送)", "to", to, "subject", subject, "body", body)
+	return nil
+}
+
+// SMTPSender 通过 SMTP 服务器发送邮件
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(to, subject, body string) error {
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.From, to, subject, body,
+	)
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	if err := smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
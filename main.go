@@ -1,11 +1,42 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // 注册 /debug/pprof 处理器到 http.DefaultServeMux
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"traffic-system/algo"
+	"traffic-system/analytics"
+	"traffic-system/cache"
+	"traffic-system/captcha"
+	"traffic-system/config"
+	"traffic-system/crash"
 	"traffic-system/db"
+	"traffic-system/events"
 	"traffic-system/handler"
+	"traffic-system/jobs"
+	"traffic-system/jwtkeys"
+	"traffic-system/lint"
+	"traffic-system/logging"
+	"traffic-system/mail"
+	"traffic-system/model"
+	"traffic-system/monitor"
+	"traffic-system/oauth"
+	"traffic-system/popularity"
+	"traffic-system/realtime"
+	"traffic-system/snapshot"
+	"traffic-system/speeds"
+	"traffic-system/tracing"
+	"traffic-system/weather"
+	"traffic-system/webhook"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,25 +44,74 @@ import (
 func main() {
 	fmt.Println("=== 欢迎使用 VV Maps - 智能交通导航系统 ===")
 
+	// 0. 初始化日志 (级别、格式、输出文件和轮转)，之后的日志都经过 slog
+	logging.Init()
+	tracing.Init()
+	crash.Init()
+
 	// 1. 初始化数据库
 	// 连接 PostgreSQL，自动迁移表结构
 	// 如果是第一次运行，会自动将 map_data.json 的数据导入数据库
+	db.OnImport = func(summary db.ImportSummary) {
+		events.Publish(webhook.EventImportCompleted, summary)
+	}
 	db.InitDB()
+	cache.Init()
+	mail.Init()
+	captcha.Init()
+	weather.Init()
+	jwtkeys.Init()
+	oauth.Init()
+	realtime.Init()
 
 	// 2. 加载地图数据 (从数据库加载)
-	// 注意：这里已经改为 LoadFromDB，不再读取本地 JSON 文件
-	fmt.Println("正在从数据库构建图...")
-	graph, err := algo.LoadFromDB()
-	if err != nil {
-		log.Fatalf("从数据库加载地图失败: %v", err)
+	if s := config.GetString("WALK_SHORTCUTS", ""); s != "" {
+		radius, err := algo.ParseWalkShortcutRadius(s)
+		if err != nil {
+			log.Fatalf("WALK_SHORTCUTS 配置错误: %v", err)
+		}
+		algo.WalkShortcutRadius = radius
 	}
-	fmt.Printf("地图加载成功! 节点数: %d\n", len(graph.Nodes))
+	if s := config.GetString("SIGNAL_DELAY", ""); s != "" {
+		delay, err := algo.ParseSignalDelay(s)
+		if err != nil {
+			log.Fatalf("SIGNAL_DELAY 配置错误: %v", err)
+		}
+		algo.SignalDelay = delay
+	}
+	if s := config.GetString("TURN_PENALTIES", ""); s != "" {
+		penalties, err := algo.ParseTurnPenalties(s)
+		if err != nil {
+			log.Fatalf("TURN_PENALTIES 配置错误: %v", err)
+		}
+		algo.TurnPenalties = penalties
+	}
+	if s := config.GetString("LINT_RULES", ""); s != "" {
+		rules, err := lint.Load(s)
+		if err != nil {
+			log.Fatalf("LINT_RULES 配置错误: %v", err)
+		}
+		lint.Current = rules
+	}
+	graph := loadGraph()
+	slog.Info("地图加载成功!", "nodes", len(graph.Nodes))
 
 	// 3. 将图对象传递给 handler (用于路径规划接口)
-	handler.Graph = graph
+	handler.SetGraph(graph)
+
+	// 注册后台任务的处理函数 (导入、路网重建、速度统计、快照发布等)
+	handler.RegisterJobs()
 
-	// 4. 初始化 Gin 引擎
-	r := gin.Default()
+	// 无数据库模式下没有历史数据，也不运行依赖数据库的后台任务
+	if db.Enabled() {
+		startWorkers(graph)
+	}
+
+	// 4. 初始化 Gin 引擎 (访问日志与其他日志写到同一个位置；panic 由 RecoveryMiddleware 处理)
+	gin.DefaultWriter = logging.Output()
+	gin.DefaultErrorWriter = logging.Output()
+	r := gin.New()
+	r.Use(gin.Logger())
 
 	// 5. 配置路由
 	setupRoutes(r)
@@ -40,27 +120,237 @@ func main() {
 	fmt.Println("\n服务器启动中...")
 	fmt.Println("访问地址: http://localhost:8080")
 	fmt.Println("前端页面: http://localhost:8080/static/")
-	fmt.Println("API 文档:")
+	fmt.Println("API 文档 (当前版本为 /api/v1/...，/api/v2/... 的路线为 legs -> steps 结构；下列旧版路径仍可使用但已弃用):")
 	fmt.Println("  - POST   /api/login          - 用户登录")
+	fmt.Println("  - GET    /.well-known/jwks.json - Token 校验公钥 (RS256)")
+	fmt.Println("  - POST   /api/login/oauth    - 第三方登录")
 	fmt.Println("  - POST   /api/register       - 用户注册")
+	fmt.Println("  - POST   /api/password/forgot - 找回密码")
+	fmt.Println("  - POST   /api/password/reset  - 重置密码")
+	fmt.Println("  - GET    /api/email/verify   - 邮箱验证")
 	fmt.Println("  - POST   /api/path/find      - 路径规划")
+	fmt.Println("  - POST   /api/path/reroute   - 导航中按当前位置更新路线")
+	fmt.Println("  - POST   /api/path/profile   - 全天用时曲线 (按不同时刻出发的预计用时)")
+	fmt.Println("  - POST   /api/path/revalidate - 复核之前规划的路线是否仍然有效、最优")
+	fmt.Println("  - POST   /api/feedback       - 反馈路线或地图问题")
+	fmt.Println("  - POST   /api/contrib        - 提交地图修改建议 (需要认证)")
+	fmt.Println("  - GET    /api/contrib        - 我提交的地图修改建议 (需要认证)")
 	fmt.Println("  - GET    /api/nodes          - 获取所有节点")
 	fmt.Println("  - GET    /api/nodes/:id      - 获取指定节点")
+	fmt.Println("  - GET    /api/categories     - 节点分类树")
 	fmt.Println("  - GET    /api/nodes/search   - 搜索节点")
+	fmt.Println("  - GET    /api/nodes/suggest  - 输入框自动补全")
+	fmt.Println("  - POST   /api/nodes/search/select - 上报选中的搜索结果")
+	fmt.Println("  - GET    /api/nodes/:id/lines - 经过节点的线路")
+	fmt.Println("  - GET    /api/map/extent     - 地图范围、节点/边数量和版本")
+	fmt.Println("  - GET    /api/tiles/:z/:x/:y - 瓦片范围内的节点和边")
+	fmt.Println("  - POST   /api/tiles/sign     - 生成瓦片签名 URL (需登录或 API Key)")
+	fmt.Println("  - GET    /api/lines          - 获取所有线路")
+	fmt.Println("  - GET    /api/lines/:id      - 线路详情 (站点、发车间隔、运营时间)")
+	fmt.Println("  - GET    /api/lines/:id/vehicles - 线路车辆实时位置")
+	fmt.Println("  - GET    /api/stops/:id/departures - 站点后续到站班次")
+	fmt.Println("  - POST   /api/realtime/vehicles - 上报车辆实时位置")
+	fmt.Println("  - GET    /api/parking        - 停车场列表 (可按位置查询附近)")
+	fmt.Println("  - GET    /api/location/summary - 周边出行概况")
+	fmt.Println("  - GET    /api/geofences      - 地理围栏列表")
+	fmt.Println("  - POST   /api/geofences/check - 检查点/路线与围栏的关系")
+	fmt.Println("  - GET    /api/events/stream  - 路况与交通事件推送 (SSE)")
+	fmt.Println("  - POST   /api/share          - 创建 ETA 分享链接")
+	fmt.Println("  - GET    /api/share/:token   - 查看分享的路线和 ETA")
+	fmt.Println("  - GET    /api/bundles/:id    - 通过签名链接下载离线包")
+	fmt.Println("  - POST   /api/user/logout    - 退出登录 (注销 Token)")
+	fmt.Println("  - PUT    /api/user/password  - 修改密码 (需登录)")
+	fmt.Println("  - DELETE /api/user           - 删除账号 (需登录)")
+	fmt.Println("  - GET    /api/user/export    - 导出账号数据 (需登录)")
+	fmt.Println("  - GET    /api/user/usage     - 每日用量和配额 (需登录或 API Key)")
+	fmt.Println("  - GET    /api/user/profile   - 获取出行偏好 (需登录)")
+	fmt.Println("  - PUT    /api/user/profile   - 更新出行偏好 (需登录)")
+	fmt.Println("  - POST   /api/user/trips     - 上报完成的行程 (需登录)")
+	fmt.Println("  - GET    /api/user/monitors  - 路线监控列表 (需登录)")
+	fmt.Println("  - POST   /api/user/monitors  - 创建路线监控 (需登录)")
+	fmt.Println("  - DELETE /api/user/monitors/:id - 删除路线监控 (需登录)")
+	fmt.Println("  - GET    /api/user/routes    - 收藏的路线列表 (需登录)")
+	fmt.Println("  - POST   /api/user/routes    - 收藏路线 (需登录)")
+	fmt.Println("  - DELETE /api/user/routes/:id - 删除收藏的路线 (需登录)")
+	fmt.Println("  - GET    /api/user/routes/:id/bundle - 下载路线离线包 (需登录)")
+	fmt.Println("  - POST   /api/user/routes/:id/bundle/link - 生成离线包签名链接 (需登录)")
+	fmt.Println("  - GET    /api/user/commutes  - 通勤计划列表 (需登录)")
+	fmt.Println("  - POST   /api/user/commutes  - 创建通勤计划 (需登录)")
+	fmt.Println("  - GET    /api/user/commutes/today - 今天的建议出发时间 (需登录)")
+	fmt.Println("  - DELETE /api/user/commutes/:id - 删除通勤计划 (需登录)")
+	fmt.Println("  - GET    /api/admin/apikeys - API Key 列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/apikeys - 创建 API Key (管理员)")
+	fmt.Println("  - DELETE /api/admin/apikeys/:id - 撤销 API Key (管理员)")
+	fmt.Println("  - GET    /api/admin/webhooks - Webhook 列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/webhooks - 创建 Webhook (管理员)")
+	fmt.Println("  - DELETE /api/admin/webhooks/:id - 删除 Webhook (管理员)")
+	fmt.Println("  - POST   /api/admin/webhooks/:id/test - 测试 Webhook (管理员)")
+	fmt.Println("  - POST   /api/admin/geofences - 创建地理围栏 (管理员)")
+	fmt.Println("  - PUT    /api/admin/geofences/:id - 更新地理围栏 (管理员)")
+	fmt.Println("  - DELETE /api/admin/geofences/:id - 删除地理围栏 (管理员)")
+	fmt.Println("  - GET    /api/admin/zone-rules - 区域驾车规则列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/zone-rules - 创建区域驾车规则 (管理员)")
+	fmt.Println("  - DELETE /api/admin/zone-rules/:id - 删除区域驾车规则 (管理员)")
+	fmt.Println("  - GET    /api/admin/turn-restrictions - 路口转弯规则列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/turn-restrictions - 创建路口转弯规则 (管理员)")
+	fmt.Println("  - DELETE /api/admin/turn-restrictions/:id - 删除路口转弯规则 (管理员)")
+	fmt.Println("  - GET    /api/admin/lint     - 按检查规则检查地图数据 (管理员)")
+	fmt.Println("  - GET    /api/admin/loglevel - 当前日志级别 (管理员)")
+	fmt.Println("  - PUT    /api/admin/loglevel - 临时修改日志级别 (管理员)")
+	fmt.Println("  - GET    /api/admin/aliases  - 节点别名列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/aliases  - 添加节点别名 (管理员)")
+	fmt.Println("  - DELETE /api/admin/aliases/:id - 删除节点别名 (管理员)")
+	fmt.Println("  - GET    /api/admin/nodes/duplicates - 疑似重复节点 (管理员)")
+	fmt.Println("  - POST   /api/admin/nodes/merge - 合并重复节点 (管理员)")
+	fmt.Println("  - POST   /api/admin/import   - 导入地图数据 (管理员，支持 dry_run)")
+	fmt.Println("  - POST   /api/admin/import/diff - 导入增量修改 (管理员，支持 dry_run)")
+	fmt.Println("  - GET    /api/admin/changelog - 地图变更记录 (管理员)")
+	fmt.Println("  - GET    /api/admin/jobs     - 后台任务列表 (管理员)")
+	fmt.Println("  - POST   /api/admin/jobs     - 创建后台任务 (管理员)")
+	fmt.Println("  - GET    /api/admin/jobs/:id - 后台任务状态 (管理员)")
+	fmt.Println("  - GET    /api/admin/feedback - 用户反馈列表 (管理员)")
+	fmt.Println("  - PUT    /api/admin/feedback/:id - 处理用户反馈 (管理员)")
+	fmt.Println("  - GET    /api/admin/contrib  - 待审核的地图修改建议 (管理员)")
+	fmt.Println("  - POST   /api/admin/contrib/:id/accept - 采纳地图修改建议 (管理员)")
+	fmt.Println("  - POST   /api/admin/contrib/:id/reject - 拒绝地图修改建议 (管理员)")
+	fmt.Println("  - GET    /api/admin/graph/snapshot - 下载路网快照 (管理员)")
+	fmt.Println("  - POST   /api/admin/graph/snapshot - 发布路网快照 (管理员)")
+	fmt.Println("  - GET    /api/admin/stats    - 使用统计 (管理员)")
+	fmt.Println("  - GET    /api/admin/heatmap  - 路径规划起终点热力图 (管理员)")
+	fmt.Println("  - GET    /api/admin/od/export - 导出匿名 OD 矩阵 (管理员，CSV / Parquet)")
+	fmt.Println("  - POST   /api/admin/whatif   - 假设修改路网的预计时间影响分析 (管理员)")
 	fmt.Println("\n按 Ctrl+C 退出")
 
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("服务器启动失败: %v", err)
+	// 收到 SIGINT / SIGTERM 后取消所有请求的上下文 (正在进行的路径搜索尽快中止)，
+	// 再等待处理中的请求结束，最多等待 SHUTDOWN_TIMEOUT
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Addr:        ":8080",
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("服务器启动失败: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("正在关闭服务...")
+	cancelRequests()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.GetDuration("SHUTDOWN_TIMEOUT", 10*time.Second))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("关闭服务失败", "error", err)
+	}
+	handler.FlushUsage()
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		slog.Error("导出剩余的链路数据失败", "error", err)
 	}
 }
 
+// startWorkers 加载历史数据 (学习到的速度、节点热度) 并启动依赖数据库的后台任务
+func startWorkers(graph *algo.Graph) {
+	// 加载根据历史行程学习到的路段速度，并定期重新统计
+	if rows, err := speeds.Load(); err != nil {
+		slog.Warn("加载路段速度失败", "error", err)
+	} else {
+		graph.SetLearnedSpeeds(rows)
+	}
+	if interval := config.GetDuration("SPEED_LEARN_INTERVAL", time.Hour); interval > 0 {
+		speeds.StartWorker(interval,
+			config.GetDuration("SPEED_LEARN_WINDOW", 30*24*time.Hour),
+			config.GetInt("SPEED_LEARN_MIN_SAMPLES", 5),
+			handler.ApplyLearnedSpeeds)
+	}
+
+	// 加载节点热度 (搜索排序使用)，并定期重新统计
+	if rows, err := popularity.Load(); err != nil {
+		slog.Warn("加载节点热度失败", "error", err)
+	} else {
+		graph.SetPopularity(rows)
+	}
+	if interval := config.GetDuration("POPULARITY_INTERVAL", time.Hour); interval > 0 {
+		popularity.StartWorker(interval,
+			config.GetDuration("POPULARITY_WINDOW", 90*24*time.Hour),
+			func(rows []model.NodePopularity) int {
+				return handler.CurrentGraph().SetPopularity(rows)
+			})
+	}
+
+	// 直接修改数据库中的节点和边后自动重新构建路网 (GRAPH_WATCH_INTERVAL=0 时不检查)
+	if interval := config.GetDuration("GRAPH_WATCH_INTERVAL", 0); interval > 0 {
+		handler.StartGraphWatcher(interval, config.GetDuration("GRAPH_WATCH_DEBOUNCE", 10*time.Second))
+	}
+
+	// 定期检查用户订阅的路线 (预计时间明显变差时通知) 和今天的通勤计划 (临近出发时提醒)
+	if interval := config.GetDuration("MONITOR_INTERVAL", 5*time.Minute); interval > 0 {
+		monitor.Start(interval, handler.CurrentGraph)
+	}
+
+	// 后台任务执行器 (导入、路网重建、速度统计、快照发布等)，JOB_WORKERS=0 时本实例不执行任务
+	if workers := config.GetInt("JOB_WORKERS", 1); workers > 0 {
+		jobs.Start(workers,
+			config.GetDuration("JOB_POLL_INTERVAL", 5*time.Second),
+			config.GetDuration("JOB_TIMEOUT", 30*time.Minute))
+	}
+
+	// 记录接口请求、路径规划和行程上报事件 (管理员统计使用)
+	if config.GetBool("ANALYTICS_ENABLED", true) {
+		analytics.Start(config.GetDuration("ANALYTICS_FLUSH_INTERVAL", 5*time.Second),
+			config.GetDuration("ANALYTICS_RETENTION", 90*24*time.Hour))
+	}
+
+	// 登录用户和 API Key 的每日请求次数 (配额在缓存中计数，定期累加到数据库)
+	handler.StartUsageCounter(config.GetDuration("USAGE_FLUSH_INTERVAL", 30*time.Second))
+}
+
+// loadGraph 配置了 GRAPH_SNAPSHOT 时从快照加载 (多个副本共用同一份预先构建的路网)，
+// 未配置或读取失败时从数据库 (有只读副本时从副本) 构建，并把结果发布到 GRAPH_SNAPSHOT_PUBLISH
+func loadGraph() *algo.Graph {
+	if location := config.GetString("GRAPH_SNAPSHOT", ""); location != "" {
+		slog.Info("正在读取路网快照...", "location", location)
+		graph, info, err := snapshot.Load(location)
+		if err == nil {
+			// 驾车区域规则和转弯规则不在快照中 (无数据库模式下没有这些规则)
+			if db.Enabled() {
+				if _, err := graph.ReloadZones(); err != nil {
+					slog.Warn("加载驾车区域规则失败", "error", err)
+				}
+				if _, err := graph.ReloadTurnRestrictions(); err != nil {
+					slog.Warn("加载转弯规则失败", "error", err)
+				}
+			}
+			slog.Info("已从快照加载路网", "version", info.Version, "created_at", info.CreatedAt.Format(time.RFC3339))
+			return graph
+		}
+		slog.Warn("读取路网快照失败，改为从数据库构建", "error", err)
+	}
+
+	slog.Info("正在从存储构建图...")
+	graph, err := algo.LoadFromRepository(db.ReadRepo())
+	if err != nil {
+		log.Fatalf("从数据库加载地图失败: %v", err)
+	}
+	handler.PublishSnapshot(graph)
+	return graph
+}
+
 // setupRoutes 配置路由
 func setupRoutes(r *gin.Engine) {
+	// 链路追踪 (未配置 OTLP 导出地址时不记录)
+	r.Use(tracing.Middleware())
+
+	// 恢复 panic：上报调用栈并返回 500 错误响应
+	r.Use(handler.RecoveryMiddleware())
+
 	// CORS 跨域中间件
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, Idempotency-Key, traceparent, tracestate")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -68,8 +358,13 @@ func setupRoutes(r *gin.Engine) {
 		c.Next()
 	})
 
-	// 静态文件服务 - 提供前端页面
-	r.Static("/static", "./static")
+	// 响应压缩
+	if config.GetBool("GZIP_ENABLED", true) {
+		r.Use(handler.GzipMiddleware())
+	}
+
+	// 静态文件服务 - 提供前端页面 (默认使用编译进程序的页面，见 staticFS)
+	r.StaticFS("/static", staticFS())
 
 	// 健康检查
 	r.GET("/ping", func(c *gin.Context) {
@@ -79,29 +374,202 @@ func setupRoutes(r *gin.Engine) {
 		})
 	})
 
+	// 性能分析 (仅在 PPROF_ENABLED=true 时开启，不要对公网暴露)
+	if config.GetBool("PPROF_ENABLED", false) {
+		r.GET("/debug/pprof/*any", gin.WrapH(http.DefaultServeMux))
+	}
+
+	// Token 校验公钥 (RS256)
+	r.GET("/.well-known/jwks.json", handler.GetJWKS)
+
 	// 根路径重定向到前端页面
 	r.GET("/", func(c *gin.Context) {
 		c.Redirect(302, "/static/index.html")
 	})
 
-	// API 路由组
-	api := r.Group("/api")
+	// API 路由组：当前版本为 /api/v1；/api/v2 的路线为 legs -> steps 结构，其他接口与 v1 相同；
+	// 旧版路径 /api/... 作为兼容别名保留，响应带 Deprecation、Sunset (API_LEGACY_SUNSET) 头和指向 /api/v1 的 Link 头
+	var sunset time.Time
+	if s := config.GetString("API_LEGACY_SUNSET", ""); s != "" {
+		t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			log.Fatalf("API_LEGACY_SUNSET 配置错误: %v", err)
+		}
+		sunset = t
+	}
+	registerAPI(r.Group(handler.APIPrefix))
+	v2 := r.Group(handler.APIPrefixV2)
+	v2.Use(handler.APIVersionMiddleware(2))
+	registerAPI(v2)
+	legacy := r.Group(handler.LegacyAPIPrefix)
+	legacy.Use(handler.DeprecationMiddleware(handler.LegacyDeprecatedAt, sunset, handler.CurrentAPIPath))
+	registerAPI(legacy)
+}
+
+// registerAPI 在 api 路由组 (/api/v1、/api/v2 或旧版的 /api) 下注册所有接口
+func registerAPI(api *gin.RouterGroup) {
+	api.Use(handler.UsageMiddleware(), handler.APIKeyMiddleware(), handler.QuotaMiddleware())
 	{
+		// 按客户端 IP 限流 (计数保存在共享缓存中)
+		window := config.GetDuration("RATE_LIMIT_WINDOW", time.Minute)
+		authLimit := handler.RateLimitMiddleware("auth", config.GetInt("RATE_LIMIT_AUTH", 20), window)
+		pathLimit := handler.RateLimitMiddleware("path", config.GetInt("RATE_LIMIT_PATH", 0), window)
+		feedbackLimit := handler.RateLimitMiddleware("feedback", config.GetInt("RATE_LIMIT_FEEDBACK", 10), window)
+
+		// 使用 API Key 访问时需要的权限范围
+		routeScope := handler.RequireScope(model.ScopeRoute)
+		searchScope := handler.RequireScope(model.ScopeSearch)
+
+		// 依赖数据库的接口在无数据库模式 (DB_DRIVER=memory) 下返回 503
+		needDB := handler.RequireDatabase()
+
+		// 带 Idempotency-Key 的重试请求返回第一次的响应 (注册、反馈、贡献、用户和管理员的修改)
+		idempotent := handler.IdempotencyMiddleware()
+
 		// 公开接口 (无需认证)
-		api.POST("/login", handler.Login)
-		api.POST("/register", handler.Register)
+		api.POST("/login", authLimit, handler.Login)
+		api.POST("/login/oauth", needDB, authLimit, handler.OAuthLogin)
+		api.GET("/login/oauth/providers", handler.GetOAuthProviders)
+		api.POST("/register", authLimit, idempotent, handler.Register)
+		api.POST("/password/forgot", needDB, authLimit, handler.ForgotPassword)
+		api.POST("/password/reset", needDB, authLimit, handler.ResetPassword)
+		api.GET("/email/verify", needDB, handler.VerifyEmail)
 
 		// 地图相关接口
-		api.POST("/path/find", handler.FindPath)
-		api.GET("/nodes", handler.GetNodes)
-		api.GET("/nodes/search", handler.SearchNodes)
-		api.GET("/nodes/:id", handler.GetNodeByID)
-
-		// 如果将来需要认证，可以解开下面的注释
-		// authorized := api.Group("/")
-		// authorizclaudeed.Use(handler.AuthMiddleware())
-		// {
-		//     authorized.POST("/path/find", handler.FindPath)
-		// }
+		api.POST("/path/find", routeScope, pathLimit, handler.FindPath)
+		api.POST("/path/reroute", routeScope, pathLimit, handler.Reroute)
+		api.POST("/path/profile", routeScope, pathLimit, handler.PathProfile)
+		api.POST("/path/revalidate", routeScope, pathLimit, handler.Revalidate)
+		api.POST("/feedback", needDB, feedbackLimit, idempotent, handler.SubmitFeedback)
+		api.GET("/categories", handler.GetCategories)
+		api.GET("/nodes/search", searchScope, handler.SearchNodes)
+		api.GET("/nodes/suggest", searchScope, handler.SuggestNodes)
+		api.POST("/nodes/search/select", searchScope, handler.SelectSearchResult)
+
+		// 只随地图版本变化的数据 (ETag + Cache-Control)
+		mapCache := handler.MapCacheMiddleware()
+		api.GET("/nodes", mapCache, handler.GetNodes)
+		api.GET("/nodes/:id", mapCache, handler.GetNodeByID)
+		api.GET("/nodes/:id/lines", mapCache, handler.GetNodeLines)
+		api.GET("/map/extent", mapCache, handler.GetMapExtent)
+		api.GET("/tiles/:z/:x/:y", handler.SignedURLMiddleware(handler.TileSignScope, config.GetBool("TILES_REQUIRE_SIGNATURE", false)), mapCache, handler.GetTile)
+		api.POST("/tiles/sign", handler.SignTiles)
+
+		// 公交/地铁线路
+		api.GET("/lines", mapCache, handler.GetLines)
+		api.GET("/lines/:id", mapCache, handler.GetLineByID)
+		api.GET("/lines/:id/vehicles", handler.GetLineVehicles)
+		api.GET("/stops/:id/departures", handler.GetStopDepartures)
+
+		// 停车场
+		api.GET("/parking", handler.GetParking)
+
+		// 周边出行概况
+		api.GET("/location/summary", handler.GetLocationSummary)
+
+		// 地理围栏
+		api.GET("/geofences", needDB, handler.GetGeofences)
+		api.GET("/geofences/:id", needDB, handler.GetGeofenceByID)
+		api.POST("/geofences/check", needDB, handler.CheckGeofences)
+
+		// 路况、交通事件推送 (Server-Sent Events)
+		api.GET("/events/stream", handler.StreamEvents)
+
+		// 实时车辆位置上报 (需要数据源令牌)
+		api.POST("/realtime/vehicles", handler.IngestVehicles)
+
+		// 路线分享
+		api.POST("/share", needDB, handler.CreateShare)
+		api.GET("/share/:token", needDB, handler.SignedURLMiddleware("", config.GetBool("SHARE_REQUIRE_SIGNATURE", false)), handler.GetShare)
+
+		// 签名链接下载离线包 (不需要 Token)
+		api.GET("/bundles/:id", needDB, handler.SignedURLMiddleware("", true), handler.GetSignedRouteBundle)
+
+		// 地图修改建议 (需要登录，管理员审核后生效)
+		contrib := api.Group("/contrib")
+		contrib.Use(needDB, handler.AuthMiddleware(), idempotent)
+		{
+			contrib.POST("", handler.SubmitContribution)
+			contrib.GET("", handler.GetMyContributions)
+		}
+
+		// 每日用量和配额 (登录用户或 API Key)
+		api.GET("/user/usage", needDB, handler.GetUsage)
+
+		// 需要登录的用户接口
+		user := api.Group("/user")
+		user.Use(needDB, handler.AuthMiddleware(), idempotent)
+		{
+			user.POST("/logout", handler.Logout)
+			user.PUT("/password", authLimit, handler.ChangePassword)
+			user.DELETE("", authLimit, handler.DeleteAccount)
+			user.GET("/export", handler.ExportAccount)
+			user.GET("/profile", handler.GetProfile)
+			user.PUT("/profile", handler.UpdateProfile)
+			user.POST("/trips", handler.SubmitTrip)
+			user.GET("/monitors", handler.GetMonitors)
+			user.POST("/monitors", handler.CreateMonitor)
+			user.DELETE("/monitors/:id", handler.DeleteMonitor)
+			user.GET("/routes", handler.GetSavedRoutes)
+			user.POST("/routes", pathLimit, handler.SaveRoute)
+			user.DELETE("/routes/:id", handler.DeleteSavedRoute)
+			user.GET("/routes/:id/bundle", handler.GetRouteBundle)
+			user.POST("/routes/:id/bundle/link", handler.CreateBundleLink)
+			user.GET("/commutes", handler.GetCommutes)
+			user.POST("/commutes", handler.CreateCommute)
+			user.GET("/commutes/today", handler.GetTodayCommutes)
+			user.DELETE("/commutes/:id", handler.DeleteCommute)
+		}
+
+		// 管理员接口
+		admin := api.Group("/admin")
+		admin.Use(needDB, handler.AdminAuthMiddleware(), idempotent)
+		{
+			admin.GET("/apikeys", handler.GetAPIKeys)
+			admin.POST("/apikeys", handler.CreateAPIKey)
+			admin.DELETE("/apikeys/:id", handler.RevokeAPIKey)
+			admin.GET("/webhooks", handler.GetWebhooks)
+			admin.POST("/webhooks", handler.CreateWebhook)
+			admin.DELETE("/webhooks/:id", handler.DeleteWebhook)
+			admin.POST("/webhooks/:id/test", handler.TestWebhook)
+			admin.POST("/geofences", handler.CreateGeofence)
+			admin.PUT("/geofences/:id", handler.UpdateGeofence)
+			admin.DELETE("/geofences/:id", handler.DeleteGeofence)
+			admin.GET("/zone-rules", handler.GetZoneRules)
+			admin.POST("/zone-rules", handler.CreateZoneRule)
+			admin.DELETE("/zone-rules/:id", handler.DeleteZoneRule)
+			admin.GET("/turn-restrictions", handler.GetTurnRestrictions)
+			admin.POST("/turn-restrictions", handler.CreateTurnRestriction)
+			admin.DELETE("/turn-restrictions/:id", handler.DeleteTurnRestriction)
+			admin.GET("/lint", handler.LintMap)
+			admin.GET("/loglevel", handler.GetLogLevel)
+			admin.PUT("/loglevel", handler.SetLogLevel)
+			admin.GET("/aliases", handler.GetAliases)
+			admin.POST("/aliases", handler.CreateAlias)
+			admin.DELETE("/aliases/:id", handler.DeleteAlias)
+			admin.GET("/nodes/duplicates", handler.FindDuplicateNodes)
+			admin.POST("/nodes/merge", handler.MergeNodes)
+			admin.POST("/import", handler.ImportMap)
+			admin.POST("/import/diff", handler.ImportMapDiff)
+			admin.GET("/changelog", handler.GetMapChanges)
+			admin.GET("/changelog/:id", handler.GetMapChangeByID)
+			admin.GET("/jobs", handler.GetJobs)
+			admin.POST("/jobs", handler.CreateJob)
+			admin.GET("/jobs/:id", handler.GetJobByID)
+			admin.GET("/feedback", handler.GetFeedback)
+			admin.GET("/feedback/:id", handler.GetFeedbackByID)
+			admin.PUT("/feedback/:id", handler.UpdateFeedback)
+			admin.GET("/contrib", handler.GetContributions)
+			admin.GET("/contrib/stats", handler.GetContributorStats)
+			admin.GET("/contrib/:id", handler.GetContributionByID)
+			admin.POST("/contrib/:id/accept", handler.AcceptContribution)
+			admin.POST("/contrib/:id/reject", handler.RejectContribution)
+			admin.GET("/graph/snapshot", handler.DownloadSnapshot)
+			admin.POST("/graph/snapshot", handler.CreateSnapshot)
+			admin.GET("/stats", handler.GetStats)
+			admin.GET("/heatmap", handler.GetHeatmap)
+			admin.GET("/od/export", handler.ExportOD)
+			admin.POST("/whatif", handler.WhatIf)
+		}
 	}
 }
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// API Key 的权限范围
+const (
+	ScopeRoute  = "route"  // 路径规划 (/api/path/*)
+	ScopeSearch = "search" // 节点搜索 (/api/nodes/search、/api/nodes/suggest)
+	ScopeAdmin  = "admin"  // 管理员接口 (/api/admin/*)
+)
+
+// APIScopes 所有权限范围
+var APIScopes = []string{ScopeRoute, ScopeSearch, ScopeAdmin}
+
+// APIKey 供服务端调用的 API Key (通过 X-API-Key 请求头使用，不代表任何用户)
+// 数据库中只保存密钥的 SHA-256 摘要，原始密钥只在创建时返回一次
+type APIKey struct {
+	ID         uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string         `json:"name" gorm:"size:100;not null"`
+	Prefix     string         `json:"prefix"` // 密钥开头的几个字符，用于在列表中识别
+	KeyHash    string         `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]"`
+	RateLimit  int            `json:"rate_limit"`  // 每个限流窗口的请求上限，0 表示使用 API_KEY_RATE_LIMIT
+	DailyQuota int            `json:"daily_quota"` // 每天的请求上限，0 表示使用 API_KEY_DAILY_QUOTA
+	CreatedBy  uint           `json:"created_by"`  // 创建的管理员
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// HasScope 是否拥有权限范围 scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsActive 是否仍然可用 (未撤销且未过期)
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// IsValidScope 是否是有效的权限范围
+func IsValidScope(scope string) bool {
+	return slices.Contains(APIScopes, scope)
+}
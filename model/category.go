@@ -0,0 +1,37 @@
+package model
+
+// Category 节点分类 (树状结构，如 交通 > 地铁口)
+// 叶子分类的 ID 与节点的 type 相同，节点属于其 type 对应分类及该分类的所有上级分类
+type Category struct {
+	ID       string `json:"id" gorm:"primaryKey"`
+	ParentID string `json:"parent_id,omitempty" gorm:"index"` // 上级分类，顶级分类为空
+	Name     string `json:"name" gorm:"not null"`
+	NameEn   string `json:"name_en,omitempty"`
+	Sort     int    `json:"sort"` // 同级分类的显示顺序
+}
+
+// DefaultCategories 默认的分类体系 (覆盖地图数据中出现的节点类型)
+func DefaultCategories() []Category {
+	return []Category{
+		{ID: "transport", Name: "交通", NameEn: "Transport", Sort: 1},
+		{ID: "subway_entrance", ParentID: "transport", Name: "地铁口", NameEn: "Subway entrance", Sort: 1},
+		{ID: "bus_stop", ParentID: "transport", Name: "公交站", NameEn: "Bus stop", Sort: 2},
+		{ID: NodeTypeBikeDock, ParentID: "transport", Name: "共享单车", NameEn: "Bike dock", Sort: 3},
+
+		{ID: "vehicle", Name: "车辆服务", NameEn: "Vehicle services", Sort: 2},
+		{ID: NodeTypeParking, ParentID: "vehicle", Name: "停车场", NameEn: "Parking", Sort: 1},
+		{ID: NodeTypeCharging, ParentID: "vehicle", Name: "充电站", NameEn: "Charging station", Sort: 2},
+
+		{ID: "place", Name: "地点", NameEn: "Places", Sort: 3},
+		{ID: "landmark", ParentID: "place", Name: "地标", NameEn: "Landmark", Sort: 1},
+		{ID: "school", ParentID: "place", Name: "学校", NameEn: "School", Sort: 2},
+		{ID: "hospital", ParentID: "place", Name: "医院", NameEn: "Hospital", Sort: 3},
+
+		{ID: "food", Name: "餐饮", NameEn: "Food & drink", Sort: 4},
+		{ID: "restaurant", ParentID: "food", Name: "餐厅", NameEn: "Restaurant", Sort: 1},
+		{ID: "cafe", ParentID: "food", Name: "咖啡馆", NameEn: "Cafe", Sort: 2},
+
+		{ID: "road", Name: "道路", NameEn: "Roads", Sort: 5},
+		{ID: "road_node", ParentID: "road", Name: "道路节点", NameEn: "Road node", Sort: 1},
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// MapChange 一次增量地图修改 (差异文件导入) 的变更记录
+type MapChange struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uint      `json:"user_id" gorm:"index"`      // 导入的管理员
+	ContributionID uint      `json:"contribution_id,omitempty"` // 来自用户贡献时为贡献的 ID
+	Description    string    `json:"description"`
+	NodesAdded     int       `json:"nodes_added"`
+	NodesUpdated   int       `json:"nodes_updated"`
+	NodesRemoved   int       `json:"nodes_removed"`
+	EdgesAdded     int       `json:"edges_added"`
+	EdgesUpdated   int       `json:"edges_updated"`
+	EdgesRemoved   int       `json:"edges_removed"`                   // 含随节点一起删除的边
+	VersionBefore  string    `json:"version_before"`                  // 修改前的地图版本
+	VersionAfter   string    `json:"version_after,omitempty"`         // 修改后的地图版本 (路网重新加载后填写)
+	Diff           string    `json:"diff,omitempty" gorm:"type:text"` // 差异文件原文
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
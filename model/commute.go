@@ -0,0 +1,65 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Commute 用户的通勤计划 (固定的起终点、星期和到达时间)
+// 通勤日的早上后台按当时的路况反推建议出发时间，临近出发时通知用户
+type Commute struct {
+	ID            uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint           `json:"-" gorm:"index;not null"`
+	Name          string         `json:"name"`
+	StartID       string         `json:"start_id" gorm:"not null"`
+	EndID         string         `json:"end_id" gorm:"not null"`
+	Modes         pq.StringArray `json:"modes" gorm:"type:text[]"`
+	Days          pq.Int64Array  `json:"days" gorm:"type:integer[]"` // 通勤的星期 (1 = 周一 ... 7 = 周日)
+	ArriveBy      string         `json:"arrive_by"`                  // 最晚到达时间，如 "08:30"
+	Buffer        int            `json:"buffer"`                     // 预留的富余时间 (分钟)
+	NotifyURL     string         `json:"notify_url,omitempty"`       // 通知回调地址 (可选，POST JSON)
+	DepartAt      *time.Time     `json:"depart_at"`                  // 最近一次计算的建议出发时间
+	EstimatedTime float64        `json:"estimated_time"`             // 最近一次计算的预计时间 (秒)
+	PlannedAt     *time.Time     `json:"planned_at"`                 // 最近一次计算时间
+	NotifiedAt    *time.Time     `json:"notified_at"`                // 最近一次通知时间
+	Active        bool           `json:"active" gorm:"default:true"` // 是否启用
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ScheduledOn 给定日期是否是通勤日
+func (c *Commute) ScheduledOn(t time.Time) bool {
+	day := int64(t.Weekday())
+	if day == 0 {
+		day = 7
+	}
+	return slices.Contains(c.Days, day)
+}
+
+// ArrivalOn 给定日期 (按 t 的时区) 的目标到达时刻
+func (c *Commute) ArrivalOn(t time.Time) (time.Time, error) {
+	minutes, err := ParseClock(c.ArriveBy)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, minutes/60, minutes%60, 0, 0, t.Location()), nil
+}
+
+// PlannedOn 给定日期是否已经计算过建议出发时间
+func (c *Commute) PlannedOn(t time.Time) bool {
+	return c.PlannedAt != nil && sameDay(*c.PlannedAt, t)
+}
+
+// NotifiedOn 给定日期是否已经发送过通知 (每个通勤日最多通知一次)
+func (c *Commute) NotifiedOn(t time.Time) bool {
+	return c.NotifiedAt != nil && sameDay(*c.NotifiedAt, t)
+}
+
+// sameDay a 和 b 是否是 (按 b 的时区) 同一天
+func sameDay(a, b time.Time) bool {
+	y1, m1, d1 := a.In(b.Location()).Date()
+	y2, m2, d2 := b.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
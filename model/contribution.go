@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// 用户贡献的审核状态
+const (
+	ContributionPending  = "pending"  // 待审核
+	ContributionAccepted = "accepted" // 已采纳并应用到地图
+	ContributionRejected = "rejected" // 已拒绝
+)
+
+// Contribution 用户提交的地图修改建议 (与 /api/admin/import/diff 相同格式的差异文件)，管理员审核后应用到地图
+type Contribution struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `json:"user_id" gorm:"index"`
+	Description  string     `json:"description"`
+	Status       string     `json:"status" gorm:"size:16;index;not null;default:pending"`
+	NodesAdded   int        `json:"nodes_added"` // 以下为提交时试运行的统计
+	NodesUpdated int        `json:"nodes_updated"`
+	NodesRemoved int        `json:"nodes_removed"`
+	EdgesAdded   int        `json:"edges_added"`
+	EdgesUpdated int        `json:"edges_updated"`
+	EdgesRemoved int        `json:"edges_removed"`
+	MapVersion   string     `json:"map_version"` // 提交时的地图版本
+	Diff         string     `json:"diff,omitempty" gorm:"type:text"`
+	ReviewedBy   uint       `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote   string     `json:"review_note,omitempty"`
+	ChangeID     uint       `json:"change_id,omitempty"` // 采纳后对应的地图变更记录
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ContributorStats 一个用户的贡献统计
+type ContributorStats struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Total    int    `json:"total"`
+	Pending  int    `json:"pending"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	// AcceptanceRate 已审核的贡献中被采纳的比例，没有已审核的贡献时为 0
+	AcceptanceRate float64 `json:"acceptance_rate" gorm:"-"`
+}
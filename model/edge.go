@@ -1,16 +1,41 @@
 package model
 
-import "github.com/lib/pq"
+import (
+	"slices"
 
-// Edge 对应两点之间的一条连线
+	"github.com/lib/pq"
+)
+
+// Edge 对应两点之间的一条连线，(from, to, line_id) 唯一
 type Edge struct {
 	ID     uint           `json:"-" gorm:"primaryKey;autoIncrement"`
-	From   string         `json:"from" gorm:"index;not null"`
-	To     string         `json:"to" gorm:"index;not null"`
-	Dist   float64        `json:"dist"`                     // 距离 (米), 已经算好
-	Modes  pq.StringArray `json:"modes" gorm:"type:text[]"` // 原始模式列表: ["car", "bus"]
-	LineID string         `json:"line_id,omitempty"`        // 线路ID, 仅公交/地铁有
-	Desc   string         `json:"desc,omitempty"`           // 描述
+	From   string         `json:"from" gorm:"index;uniqueIndex:idx_edges_key,priority:1;not null"`
+	To     string         `json:"to" gorm:"index;uniqueIndex:idx_edges_key,priority:2;not null"`
+	Dist   float64        `json:"dist"`                                                                                    // 距离 (米), 已经算好
+	Modes  pq.StringArray `json:"modes" gorm:"type:text[]"`                                                                // 原始模式列表: ["car", "bus"]
+	LineID string         `json:"line_id,omitempty" gorm:"index;uniqueIndex:idx_edges_key,priority:3;not null;default:''"` // 线路ID, 仅公交/地铁有
+	Desc   string         `json:"desc,omitempty"`                                                                          // 描述
+
+	// 通行限制 (货车)，0 表示不限制
+	MaxHeight float64 `json:"max_height,omitempty"` // 限高 (米)
+	MaxWeight float64 `json:"max_weight,omitempty"` // 限重 (吨)
+	MaxWidth  float64 `json:"max_width,omitempty"`  // 限宽 (米)
+	NoTrucks  bool    `json:"no_trucks,omitempty"`  // 禁止货车通行
+
+	// 单行，见 OnewayYes / OnewayVehicle，为空表示双向
+	Oneway string `json:"oneway,omitempty" gorm:"size:16;not null;default:''"`
+
+	// 道路等级，见 RoadExpressway 等，为空表示未标注
+	RoadClass string `json:"road_class,omitempty" gorm:"size:16;not null;default:''"`
+
+	// 步行设施类型，见 FootwayCrosswalk 等，为空表示普通道路或人行道
+	Footway string `json:"footway,omitempty" gorm:"size:32;not null;default:''"`
+
+	// 所在楼层 (见 model/level.go)，0 为地面层
+	Level int `json:"level,omitempty" gorm:"not null;default:0"`
+	// 垂直通道类型，见 ConnectorStairs 等，为空表示普通的边；垂直通道连接的两个节点楼层不同
+	Connector      string  `json:"connector,omitempty" gorm:"size:16;not null;default:''"`
+	ConnectorSpeed float64 `json:"connector_speed,omitempty"` // 垂直速度 (米/秒)，0 表示使用默认值
 
 	// --- 下面这个字段 JSON 里没有，是我们在加载数据后算出来的 ---
 	ModeMask int `json:"-" gorm:"-"` // 位掩码，用于算法中毫秒级判断通行权限
@@ -18,9 +43,76 @@ type Edge struct {
 
 // MapData 用于解析整个 JSON 文件
 type MapData struct {
-	Meta  map[string]interface{} `json:"meta"` // 存版本号等元数据
-	Nodes []Node                 `json:"nodes"`
-	Edges []Edge                 `json:"edges"`
+	Meta    map[string]interface{} `json:"meta"` // 存版本号等元数据
+	Nodes   []Node                 `json:"nodes"`
+	Edges   []Edge                 `json:"edges"`
+	Lines   []Line                 `json:"lines,omitempty"`   // 线路定义 (可选，缺省时根据边的 line_id 推导)
+	Aliases []NodeAlias            `json:"aliases,omitempty"` // 节点别名 (可选)
+}
+
+// 边的通行方向 (Edge.Oneway)
+// 步行/骑行/驾车/货车边默认双向，加载时生成反向边；公交/地铁边表示线路的一个方向，总是单向
+const (
+	OnewayNo      = ""        // 双向
+	OnewayYes     = "yes"     // 所有交通方式都只能从 From 到 To (如单向匝道、自动扶梯)
+	OnewayVehicle = "vehicle" // 骑行和机动车只能从 From 到 To，步行双向 (常见的单行道)
+)
+
+// IsValidOneway 是否是有效的通行方向
+func IsValidOneway(oneway string) bool {
+	return oneway == OnewayNo || oneway == OnewayYes || oneway == OnewayVehicle
+}
+
+// 道路等级 (Edge.RoadClass)，按城市道路分级
+const (
+	RoadExpressway = "expressway" // 快速路
+	RoadArterial   = "arterial"   // 主干路
+	RoadCollector  = "collector"  // 次干路
+	RoadLocal      = "local"      // 支路
+	RoadAlley      = "alley"      // 小巷、胡同
+	RoadService    = "service"    // 小区、校园内部道路
+)
+
+// IsValidRoadClass 是否是有效的道路等级 (空表示未标注)
+func IsValidRoadClass(class string) bool {
+	switch class {
+	case "", RoadExpressway, RoadArterial, RoadCollector, RoadLocal, RoadAlley, RoadService:
+		return true
+	}
+	return false
+}
+
+// IsMinorRoad 是否是小巷或内部道路 (驾车选择 "避开小路" 时尽量不走)
+func (e *Edge) IsMinorRoad() bool {
+	return e.RoadClass == RoadAlley || e.RoadClass == RoadService
+}
+
+// 步行设施类型 (Edge.Footway)，步行经过时按各自的速度系数和固定耗时计算 (其他交通方式不受影响)
+const (
+	FootwayCrosswalk       = "crosswalk"        // 人行横道 (信号灯另按 Node.Signal 计算)
+	FootwayFootbridge      = "footbridge"       // 过街天桥
+	FootwayUnderpass       = "underpass"        // 地下通道
+	FootwayStationCorridor = "station_corridor" // 车站内的通道、站厅 (换乘通道)
+)
+
+// FootwayCost 步行设施的速度系数 (乘以步行速度) 和每次通过的固定耗时 (秒)
+type FootwayCost struct {
+	SpeedFactor float64
+	Delay       float64
+}
+
+// FootwayCosts 各类步行设施的耗时：人行横道等待车流间隙，天桥和地下通道上下楼梯，车站通道人流密集
+var FootwayCosts = map[string]FootwayCost{
+	FootwayCrosswalk:       {SpeedFactor: 1.0, Delay: 10},
+	FootwayFootbridge:      {SpeedFactor: 0.7, Delay: 20},
+	FootwayUnderpass:       {SpeedFactor: 0.7, Delay: 20},
+	FootwayStationCorridor: {SpeedFactor: 0.8, Delay: 0},
+}
+
+// IsValidFootway 是否是有效的步行设施类型 (空表示普通道路)
+func IsValidFootway(footway string) bool {
+	_, ok := FootwayCosts[footway]
+	return footway == "" || ok
 }
 
 // 定义通行模式的二进制位 (Bitmask)
@@ -32,6 +124,7 @@ const (
 	ModeCar    = 1 << 2 // 4  (二进制 00100)
 	ModeBus    = 1 << 3 // 8  (二进制 01000)
 	ModeSubway = 1 << 4 // 16 (二进制 10000)
+	ModeTruck  = 1 << 5 // 32 (二进制 100000)，可以驾车且未禁止货车的道路都允许货车通行
 )
 
 // 各交通方式的平均速度 (米/秒)
@@ -41,6 +134,13 @@ const (
 	SpeedCar    = 8.3  // 驾车: 约 30 km/h (城市道路)
 	SpeedBus    = 5.5  // 公交: 约 20 km/h (含停靠)
 	SpeedSubway = 10.0 // 地铁: 约 36 km/h (含停靠)
+	SpeedTruck  = 6.9  // 货车: 约 25 km/h (城市道路)
+)
+
+// 用户可设置的步行速度范围 (米/秒)
+const (
+	MinWalkSpeed = 0.5
+	MaxWalkSpeed = 3.0
 )
 
 // 各交通方式的平均等待/准备时间 (秒)
@@ -51,6 +151,7 @@ const (
 	WaitTimeCar    = 60  // 驾车: 找车位、启动等 (约1分钟)
 	WaitTimeBus    = 300 // 公交: 平均等待时间 (约5分钟，假设10分钟一班)
 	WaitTimeSubway = 180 // 地铁: 平均等待时间 (约3分钟，假设6分钟一班)
+	WaitTimeTruck  = 60  // 货车: 同驾车
 )
 
 // ParseModes 将字符串数组转换为位掩码
@@ -69,6 +170,8 @@ func ParseModes(modes []string) int {
 			mask |= ModeBus
 		case "subway":
 			mask |= ModeSubway
+		case "truck":
+			mask |= ModeTruck
 		}
 	}
 	return mask
@@ -87,6 +190,8 @@ func GetModeSpeed(mode string) float64 {
 		return SpeedBus
 	case "subway":
 		return SpeedSubway
+	case "truck":
+		return SpeedTruck
 	default:
 		return SpeedWalk // 默认步行速度
 	}
@@ -105,6 +210,8 @@ func GetModeWaitTime(mode string) float64 {
 		return WaitTimeBus
 	case "subway":
 		return WaitTimeSubway
+	case "truck":
+		return WaitTimeTruck
 	default:
 		return 0
 	}
@@ -123,6 +230,8 @@ func GetModeMask(mode string) int {
 		return ModeBus
 	case "subway":
 		return ModeSubway
+	case "truck":
+		return ModeTruck
 	default:
 		return 0
 	}
@@ -141,6 +250,47 @@ func FilterModesByMask(edgeModes []string, userModeMask int) []string {
 	return filtered
 }
 
+// EdgeModeMask 计算边的通行位掩码 (可以驾车且未禁止货车的道路同时允许货车)
+func (e *Edge) EdgeModeMask() int {
+	mask := ParseModes(e.Modes)
+	if mask&ModeCar != 0 {
+		mask |= ModeTruck
+	}
+	if e.NoTrucks {
+		mask &^= ModeTruck
+	}
+	return mask
+}
+
+// AvailableModes 根据用户选择的 modeMask 返回该边实际可用的交通方式 (包括由驾车道路推导出的货车)
+func (e *Edge) AvailableModes(userModeMask int) []string {
+	modes := FilterModesByMask(e.Modes, userModeMask&^ModeTruck)
+	if userModeMask&e.ModeMask&ModeTruck != 0 && !slices.Contains(modes, "truck") {
+		modes = append(modes, "truck")
+	}
+	return modes
+}
+
+// ReverseModeMask 可以从 To 反向走到 From 的交通方式 (按 Oneway 计算，公交/地铁不能反向)
+func (e *Edge) ReverseModeMask() int {
+	mask := e.ModeMask
+	if mask == 0 {
+		mask = e.EdgeModeMask()
+	}
+	switch e.Oneway {
+	case OnewayYes:
+		return 0
+	case OnewayVehicle:
+		return mask & ModeWalk
+	}
+	return mask & (ModeWalk | ModeBike | ModeCar | ModeTruck)
+}
+
+// HasRestrictions 边是否有限高、限重、限宽或禁止货车的限制
+func (e *Edge) HasRestrictions() bool {
+	return e.MaxHeight > 0 || e.MaxWeight > 0 || e.MaxWidth > 0 || e.NoTrucks
+}
+
 // EstimateTime 根据距离和交通方式估算行驶时间 (秒)
 // 注意: 此函数只计算行驶时间，不含等待时间
 // 如果有多种交通方式，选择最快的
@@ -165,6 +315,70 @@ func EstimateTime(distance float64, modes []string) float64 {
 	return distance / maxSpeed
 }
 
+// IsHighway 是否为快速路 (道路等级为 expressway，或仅允许机动车通行，步行和骑行都不可用)
+func (e *Edge) IsHighway() bool {
+	if e.RoadClass == RoadExpressway {
+		return true
+	}
+	mask := e.ModeMask
+	if mask == 0 {
+		mask = e.EdgeModeMask()
+	}
+	return mask&ModeCar != 0 && mask&(ModeWalk|ModeBike) == 0
+}
+
+// IsTransfer 判断从上一段切换到当前段是否属于换乘
+// 第一段不算换乘；步行不算换乘；公交/地铁换线或者从其他方式换到公交/地铁都算换乘
+func IsTransfer(prevMode, prevLineID, mode, lineID string) bool {
+	if prevMode == "" || mode == "walk" {
+		return false
+	}
+	if mode == "bus" || mode == "subway" {
+		return prevMode != mode || (prevLineID != lineID && lineID != "")
+	}
+	return prevMode != mode && prevMode != "walk"
+}
+
+// TravelPreferences 用户出行偏好，影响路段时间估算
+type TravelPreferences struct {
+	WalkSpeed   float64    // 步行速度 (米/秒)，0 表示使用默认值 SpeedWalk
+	Learned     ModeSpeeds // 该路段学习到的速度 (可为空)，步行始终以用户设置为准
+	SpeedFactor float64    // 分时速度系数 (驾车、公交)，0 表示不调整；有学习速度时以学习速度为准
+	Footway     string     // 路段的步行设施类型 (见 FootwayCosts)，只影响步行
+	// 按交通方式的速度系数 (如雨天步行、骑行变慢)，在以上速度的基础上再乘以该系数；未列出的方式不调整
+	ModeFactors map[string]float64
+}
+
+// Speed 获取指定交通方式在该偏好下的速度 (米/秒)
+func (p TravelPreferences) Speed(mode string) float64 {
+	speed := p.baseSpeed(mode)
+	if f := p.ModeFactors[mode]; f > 0 {
+		speed *= f
+	}
+	return speed
+}
+
+// baseSpeed 不考虑 ModeFactors 的速度
+func (p TravelPreferences) baseSpeed(mode string) float64 {
+	if mode == "walk" {
+		speed := SpeedWalk
+		if p.WalkSpeed > 0 {
+			speed = p.WalkSpeed
+		}
+		if cost, ok := FootwayCosts[p.Footway]; ok {
+			speed *= cost.SpeedFactor
+		}
+		return speed
+	}
+	if speed := p.Learned[mode]; speed > 0 && mode != "walk" {
+		return speed
+	}
+	if p.SpeedFactor > 0 && IsRoadVehicle(mode) {
+		return GetModeSpeed(mode) * p.SpeedFactor
+	}
+	return GetModeSpeed(mode)
+}
+
 // EstimateSegmentTime 估算路段时间，考虑实际使用的交通方式和换乘等待
 // 参数:
 //   - distance: 路段距离 (米)
@@ -177,8 +391,13 @@ func EstimateTime(distance float64, modes []string) float64 {
 //   - time: 预计时间 (秒)
 //   - usedMode: 实际使用的交通方式
 func EstimateSegmentTime(distance float64, availableModes []string, prevMode string, prevLineID string, currentLineID string) (time float64, usedMode string) {
+	return EstimateSegmentTimeWithPrefs(distance, availableModes, prevMode, prevLineID, currentLineID, TravelPreferences{})
+}
+
+// EstimateSegmentTimeWithPrefs 与 EstimateSegmentTime 相同，但使用用户偏好中的速度
+func EstimateSegmentTimeWithPrefs(distance float64, availableModes []string, prevMode string, prevLineID string, currentLineID string, prefs TravelPreferences) (time float64, usedMode string) {
 	if len(availableModes) == 0 {
-		return distance / SpeedWalk, "walk"
+		return distance / prefs.Speed("walk"), "walk"
 	}
 
 	// 计算每种交通方式的总时间 (行驶时间 + 可能的等待时间)
@@ -187,7 +406,7 @@ func EstimateSegmentTime(distance float64, availableModes []string, prevMode str
 	bestMode := ""
 
 	for _, mode := range availableModes {
-		speed := GetModeSpeed(mode)
+		speed := prefs.Speed(mode)
 		travelTime := distance / speed
 
 		// 计算等待时间
@@ -196,9 +415,9 @@ func EstimateSegmentTime(distance float64, availableModes []string, prevMode str
 
 		switch mode {
 		case "walk":
-			// 步行不需要等待
-			needWait = false
-		case "bike", "car":
+			// 步行不需要等待，经过人行横道、天桥等设施时加上固定耗时
+			waitTime = FootwayCosts[prefs.Footway].Delay
+		case "bike", "car", "truck":
 			// 骑行/驾车: 只有第一次使用或换乘时才需要准备时间
 			if prevMode != mode {
 				needWait = true
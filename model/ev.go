@@ -0,0 +1,66 @@
+package model
+
+import "slices"
+
+// 电动车充电估算参数
+const (
+	DefaultBattery     = 60.0  // 默认电池容量 (kWh)
+	DefaultChargePower = 60.0  // 充电站未填写功率时的默认功率 (kW)
+	EVReserveRatio     = 0.1   // 到达充电站或终点时至少保留的电量比例
+	EVChargeTarget     = 0.8   // 充电到的电量比例 (快充超过 80% 后明显变慢)
+	ChargeOverheadTime = 300.0 // 每次充电的额外时间 (找桩、插枪、结算，秒)
+	MaxEVChargeStops   = 3     // 一条路线最多插入的充电次数
+)
+
+// EV 电动车信息 (用于插入充电站)
+type EV struct {
+	Range     float64 `json:"range"`               // 满电续航 (公里)
+	Charge    float64 `json:"charge"`              // 当前电量 (%)
+	Connector string  `json:"connector,omitempty"` // 充电接口，如 "gb_dc"、"gb_ac"，为空表示都可以
+	Battery   float64 `json:"battery,omitempty"`   // 电池容量 (kWh)，默认 60
+}
+
+// FullRange 满电续航 (米)
+func (ev *EV) FullRange() float64 {
+	return ev.Range * 1000
+}
+
+// RangeAt 电量为 percent% 时扣除保留电量后的可用续航 (米)
+func (ev *EV) RangeAt(percent float64) float64 {
+	return max(percent/100-EVReserveRatio, 0) * ev.FullRange()
+}
+
+// ChargeAfter 行驶 distance 米后剩余的电量 (%)
+func (ev *EV) ChargeAfter(percent, distance float64) float64 {
+	if ev.Range <= 0 {
+		return percent
+	}
+	return max(percent-distance/ev.FullRange()*100, 0)
+}
+
+// ChargeTime 在功率为 power (kW) 的充电站从 from% 充到 to% 需要的时间 (秒，含额外时间)
+func (ev *EV) ChargeTime(from, to, power float64) float64 {
+	if to <= from {
+		return 0
+	}
+	battery := ev.Battery
+	if battery <= 0 {
+		battery = DefaultBattery
+	}
+	if power <= 0 {
+		power = DefaultChargePower
+	}
+	energy := (to - from) / 100 * battery
+	return energy/power*3600 + ChargeOverheadTime
+}
+
+// CanChargeAt 充电站是否有车辆可用的接口 (充电站未填写接口时认为都可以)
+func (ev *EV) CanChargeAt(node *Node) bool {
+	if node.Type != NodeTypeCharging {
+		return false
+	}
+	if ev.Connector == "" || len(node.Connectors) == 0 {
+		return true
+	}
+	return slices.Contains(node.Connectors, ev.Connector)
+}
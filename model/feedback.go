@@ -0,0 +1,73 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// 反馈类型
+const (
+	FeedbackWrongDirection    = "wrong_direction"    // 道路方向错误 (如单行道标反)
+	FeedbackMissingConnection = "missing_connection" // 缺少道路或节点之间的连接
+	FeedbackRoadClosed        = "road_closed"        // 道路已封闭或无法通行
+	FeedbackBadETA            = "bad_eta"            // 预计时间与实际相差很大
+	FeedbackWrongLocation     = "wrong_location"     // 节点位置或名称错误
+	FeedbackOther             = "other"
+)
+
+// FeedbackTypes 所有反馈类型
+var FeedbackTypes = []string{
+	FeedbackWrongDirection,
+	FeedbackMissingConnection,
+	FeedbackRoadClosed,
+	FeedbackBadETA,
+	FeedbackWrongLocation,
+	FeedbackOther,
+}
+
+// 反馈处理状态
+const (
+	FeedbackOpen     = "open"     // 待处理
+	FeedbackAccepted = "accepted" // 已确认，等待修改地图
+	FeedbackFixed    = "fixed"    // 已修复
+	FeedbackRejected = "rejected" // 不成立或无法处理
+)
+
+// FeedbackStatuses 所有处理状态
+var FeedbackStatuses = []string{FeedbackOpen, FeedbackAccepted, FeedbackFixed, FeedbackRejected}
+
+// Feedback 用户对路线或地图的反馈
+// 关联一条规划过的路线 (保存当时的路线结果，路线过期后仍可查看) 或地图中的节点、路段、位置
+type Feedback struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint       `json:"user_id,omitempty" gorm:"index"` // 未登录为 0
+	Type          string     `json:"type" gorm:"size:32;index;not null"`
+	Status        string     `json:"status" gorm:"size:16;index;not null;default:open"`
+	Comment       string     `json:"comment,omitempty" gorm:"type:text"`
+	RouteID       string     `json:"route_id,omitempty" gorm:"size:16"`
+	Fingerprint   string     `json:"fingerprint,omitempty"`    // 路线指纹
+	EstimatedTime float64    `json:"estimated_time,omitempty"` // 路线规划时的预计时间 (秒)
+	ActualTime    float64    `json:"actual_time,omitempty"`    // 用户报告的实际用时 (秒)
+	NodeID        string     `json:"node_id,omitempty" gorm:"index"`
+	FromID        string     `json:"from_id,omitempty"` // 路段起点
+	ToID          string     `json:"to_id,omitempty"`   // 路段终点
+	Lat           *float64   `json:"lat,omitempty"`
+	Lng           *float64   `json:"lng,omitempty"`
+	MapVersion    string     `json:"map_version"`        // 反馈时的地图版本
+	Route         string     `json:"-" gorm:"type:text"` // 反馈时的路线规划结果 (JSON)
+	AdminNote     string     `json:"admin_note,omitempty" gorm:"type:text"`
+	ResolvedBy    uint       `json:"resolved_by,omitempty"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// IsValidFeedbackType 是否是有效的反馈类型
+func IsValidFeedbackType(t string) bool {
+	return slices.Contains(FeedbackTypes, t)
+}
+
+// IsValidFeedbackStatus 是否是有效的处理状态
+func IsValidFeedbackStatus(s string) bool {
+	return slices.Contains(FeedbackStatuses, s)
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 常用的地理围栏类型 (也可以使用其他自定义类型)
+const (
+	GeofenceDelivery   = "delivery"   // 配送范围
+	GeofenceParking    = "parking"    // 停车区 (共享单车/电动车)
+	GeofenceRestricted = "restricted" // 禁行/限制区域
+)
+
+// Polygon 多边形顶点 (首尾不需要重复)，在数据库中以 JSON 保存
+type Polygon []Point
+
+// Value 实现 driver.Valuer
+func (p Polygon) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	return string(b), err
+}
+
+// Scan 实现 sql.Scanner
+func (p *Polygon) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case []byte:
+		return json.Unmarshal(v, p)
+	case nil:
+		*p = nil
+		return nil
+	default:
+		return errors.New("无法解析多边形数据")
+	}
+}
+
+// Geofence 命名的地理围栏 (多边形区域)
+type Geofence struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"not null"`
+	Kind      string    `json:"kind" gorm:"index"` // 围栏类型，如 "delivery"、"parking"、"restricted"
+	Polygon   Polygon   `json:"polygon" gorm:"type:text;not null"`
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
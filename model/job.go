@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// 后台任务状态
+const (
+	JobQueued    = "queued"    // 等待执行
+	JobRunning   = "running"   // 执行中
+	JobSucceeded = "succeeded" // 成功
+	JobFailed    = "failed"    // 失败 (Error 为原因)
+)
+
+// Job 后台任务 (导入、路网重建、速度统计、快照发布等耗时操作)
+// 保存在数据库中，任意实例的任务执行器都可以领取；服务重启后排队中的任务继续执行
+type Job struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Kind       string     `json:"kind" gorm:"index;not null"`
+	Status     string     `json:"status" gorm:"index;not null"`
+	Params     JSONText   `json:"params,omitempty" gorm:"type:text"` // 任务参数
+	Result     JSONText   `json:"result,omitempty" gorm:"type:text"` // 执行结果
+	Error      string     `json:"error,omitempty"`
+	UserID     uint       `json:"user_id" gorm:"index"` // 创建任务的管理员
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JSONText 以文本保存的 JSON，输出到响应时原样嵌入 (不转成字符串)
+type JSONText string
+
+func (t JSONText) MarshalJSON() ([]byte, error) {
+	if t == "" {
+		return []byte("null"), nil
+	}
+	return []byte(t), nil
+}
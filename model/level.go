@@ -0,0 +1,82 @@
+package model
+
+import "math"
+
+// 楼层 (Node.Level / Edge.Level)：0 为地面层，正数为地上楼层，负数为地下楼层 (如地下站厅 -1、站台 -2)
+
+// 垂直通道类型 (Edge.Connector)，连接不同楼层的两个节点，只允许步行
+const (
+	ConnectorStairs    = "stairs"    // 楼梯
+	ConnectorEscalator = "escalator" // 自动扶梯 (单向运行，必须设置 oneway: yes，方向为 from -> to)
+	ConnectorElevator  = "elevator"  // 电梯
+)
+
+// LevelHeight 相邻楼层之间的高度 (米)
+const LevelHeight = 4.5
+
+// ConnectorCost 垂直通道的默认垂直速度 (米/秒) 和每次使用的固定耗时 (秒，如等电梯)
+type ConnectorCost struct {
+	Speed float64
+	Delay float64
+}
+
+// ConnectorCosts 各类垂直通道的默认耗时，边设置了 ConnectorSpeed 时以边为准
+var ConnectorCosts = map[string]ConnectorCost{
+	ConnectorStairs:    {Speed: 0.2, Delay: 0},
+	ConnectorEscalator: {Speed: 0.25, Delay: 0},
+	ConnectorElevator:  {Speed: 1.0, Delay: 30},
+}
+
+// IsValidConnector 是否是有效的垂直通道类型 (空表示普通的边)
+func IsValidConnector(connector string) bool {
+	_, ok := ConnectorCosts[connector]
+	return connector == "" || ok
+}
+
+// StepHeight 每级台阶的高度 (米)，用于估算台阶数
+const StepHeight = 0.15
+
+// IsStairs 垂直通道类型为 connector、步行设施类型为 footway 的边是否需要走楼梯
+// (楼梯，以及按上下楼梯计算的过街天桥和地下通道)
+func IsStairs(connector, footway string) bool {
+	return connector == ConnectorStairs || footway == FootwayFootbridge || footway == FootwayUnderpass
+}
+
+// HasStairs 步行通过这条边是否需要走楼梯
+func (e *Edge) HasStairs() bool {
+	return IsStairs(e.Connector, e.Footway)
+}
+
+// Vertical 通过一条边的上升、下降高度 (米) 和走过的台阶数，levels 为楼层变化 (终点 - 起点)
+// 垂直通道按楼层变化计算；过街天桥和地下通道没有楼层数据，按先上 (下) 一层再下 (上) 一层计算
+func Vertical(connector, footway string, levels int) (ascent, descent float64, steps int) {
+	switch {
+	case connector != "":
+		rise := float64(levels) * LevelHeight
+		if rise > 0 {
+			ascent = rise
+		} else {
+			descent = -rise
+		}
+	case footway == FootwayFootbridge || footway == FootwayUnderpass:
+		ascent, descent = LevelHeight, LevelHeight
+	}
+	if IsStairs(connector, footway) {
+		steps = int(math.Round((ascent + descent) / StepHeight))
+	}
+	return ascent, descent, steps
+}
+
+// VerticalTime 通过垂直通道升降 levels 层 (正负均可) 的时间 (秒)，不是垂直通道时为 0
+// 水平方向的距离仍按步行速度另外计算
+func (e *Edge) VerticalTime(levels int) float64 {
+	cost, ok := ConnectorCosts[e.Connector]
+	if !ok {
+		return 0
+	}
+	speed := cost.Speed
+	if e.ConnectorSpeed > 0 {
+		speed = e.ConnectorSpeed
+	}
+	return cost.Delay + math.Abs(float64(levels))*LevelHeight/speed
+}
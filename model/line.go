@@ -0,0 +1,247 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Line 公交/地铁线路
+// 线路 ID 与边上的 line_id 对应
+type Line struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	Name      string     `json:"name"`
+	Mode      string     `json:"mode"`            // "bus" 或 "subway"
+	Color     string     `json:"color,omitempty"` // 线路颜色 (用于前端绘制)
+	Headway   int        `json:"headway"`         // 发车间隔 (秒)
+	FirstTime string     `json:"first_time"`      // 首班车时间，如 "06:00"
+	LastTime  string     `json:"last_time"`       // 末班车时间，如 "22:00"
+	Stops     []LineStop `json:"stops,omitempty" gorm:"foreignKey:LineID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// LineStop 线路上的一个站点 (按 Seq 排序)
+type LineStop struct {
+	ID     uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	LineID string `json:"-" gorm:"index;not null"`
+	Seq    int    `json:"seq"`
+	NodeID string `json:"node_id" gorm:"index;not null"`
+}
+
+// DefaultHeadway 各交通方式的默认发车间隔 (秒)
+// 平均等待时间约为发车间隔的一半，与 WaitTimeBus / WaitTimeSubway 保持一致
+func DefaultHeadway(mode string) int {
+	return int(GetModeWaitTime(mode) * 2)
+}
+
+// DefaultOperatingHours 各交通方式的默认运营时间
+func DefaultOperatingHours(mode string) (first, last string) {
+	if mode == "subway" {
+		return "06:00", "23:00"
+	}
+	return "06:00", "22:00"
+}
+
+// ServiceWindow 指定日期的首末班车发车时间 (以 day 所在时区计算)
+func (l *Line) ServiceWindow(day time.Time) (first, last time.Time, err error) {
+	firstMin, err := ParseClock(l.FirstTime)
+	if err != nil {
+		return first, last, err
+	}
+	lastMin, err := ParseClock(l.LastTime)
+	if err != nil {
+		return first, last, err
+	}
+	if lastMin < firstMin {
+		lastMin += 24 * 60 // 末班车在次日凌晨
+	}
+
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	first = midnight.Add(time.Duration(firstMin) * time.Minute)
+	last = midnight.Add(time.Duration(lastMin) * time.Minute)
+	return first, last, nil
+}
+
+// NextDepartures 按发车间隔推算到达某站点的后续班次
+// offset 为从始发站到该站点的行驶时间，at 之后 (含) 最多返回 n 个班次，当天无车时顺延到次日首班
+func (l *Line) NextDepartures(at time.Time, offset time.Duration, n int) []time.Time {
+	if l.Headway <= 0 || n <= 0 {
+		return nil
+	}
+	headway := time.Duration(l.Headway) * time.Second
+
+	var result []time.Time
+	// 前一天的末班车可能在凌晨仍在运行，因此从前一天开始计算
+	for d := -1; d <= 1 && len(result) < n; d++ {
+		first, last, err := l.ServiceWindow(at.AddDate(0, 0, d))
+		if err != nil {
+			return nil
+		}
+
+		// 跳过 at 之前的班次
+		dep := first
+		if earliest := at.Add(-offset); earliest.After(first) {
+			k := (earliest.Sub(first) + headway - 1) / headway
+			dep = first.Add(k * headway)
+		}
+		for ; !dep.After(last) && len(result) < n; dep = dep.Add(headway) {
+			result = append(result, dep.Add(offset))
+		}
+	}
+	return result
+}
+
+// ParseClock 解析 "HH:MM" 格式的时刻，返回从零点起的分钟数
+func ParseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil {
+		return 0, fmt.Errorf("时间格式错误: %q", s)
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("时间超出范围: %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// DeriveLines 根据带 line_id 的边推导线路和站点顺序
+// 地图数据中没有单独的线路定义时使用
+func DeriveLines(edges []Edge) []Line {
+	byLine := make(map[string][]Edge)
+	var order []string
+	for _, e := range edges {
+		if e.LineID == "" || e.From == "" || e.To == "" {
+			continue
+		}
+		if _, ok := byLine[e.LineID]; !ok {
+			order = append(order, e.LineID)
+		}
+		byLine[e.LineID] = append(byLine[e.LineID], e)
+	}
+	sort.Strings(order)
+
+	lines := make([]Line, 0, len(order))
+	for _, id := range order {
+		lineEdges := byLine[id]
+		mode := lineMode(lineEdges)
+		first, last := DefaultOperatingHours(mode)
+
+		line := Line{
+			ID:        id,
+			Name:      lineName(id, lineEdges),
+			Mode:      mode,
+			Headway:   DefaultHeadway(mode),
+			FirstTime: first,
+			LastTime:  last,
+		}
+		for i, nodeID := range OrderLineStops(lineEdges) {
+			line.Stops = append(line.Stops, LineStop{LineID: id, Seq: i + 1, NodeID: nodeID})
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// OrderLineStops 根据线路的边确定站点顺序
+// 单向线路从没有入边的站点开始；双向共用 ID 的线路从度为 1 的端点开始
+func OrderLineStops(edges []Edge) []string {
+	if len(edges) == 0 {
+		return nil
+	}
+
+	out := make(map[string][]string)        // 有向出边
+	undirected := make(map[string][]string) // 无向邻居
+	inDegree := make(map[string]int)
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, e := range edges {
+		out[e.From] = append(out[e.From], e.To)
+		undirected[e.From] = append(undirected[e.From], e.To)
+		undirected[e.To] = append(undirected[e.To], e.From)
+		inDegree[e.To]++
+		for _, id := range []string{e.From, e.To} {
+			if !seen[id] {
+				seen[id] = true
+				nodes = append(nodes, id)
+			}
+		}
+	}
+
+	// 选择起点
+	start := ""
+	for _, id := range nodes {
+		if inDegree[id] == 0 {
+			start = id
+			break
+		}
+	}
+	if start == "" {
+		for _, id := range nodes {
+			if len(uniqueStrings(undirected[id])) == 1 {
+				start = id
+				break
+			}
+		}
+	}
+	if start == "" {
+		start = nodes[0] // 环线
+	}
+
+	// 沿线路前进，优先沿有向边
+	stops := []string{start}
+	visited := map[string]bool{start: true}
+	for current := start; ; {
+		next := ""
+		for _, candidates := range [][]string{out[current], undirected[current]} {
+			for _, id := range candidates {
+				if !visited[id] {
+					next = id
+					break
+				}
+			}
+			if next != "" {
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		visited[next] = true
+		stops = append(stops, next)
+		current = next
+	}
+	return stops
+}
+
+// lineMode 线路的交通方式 (取边上第一个公交/地铁方式)
+func lineMode(edges []Edge) string {
+	for _, e := range edges {
+		for _, m := range e.Modes {
+			if m == "bus" || m == "subway" {
+				return m
+			}
+		}
+	}
+	return "bus"
+}
+
+// lineName 从边的描述中提取线路名称 (如 "1号线: A -> B" 取 "1号线")，否则使用线路 ID
+func lineName(id string, edges []Edge) string {
+	for _, e := range edges {
+		if name, _, ok := strings.Cut(e.Desc, ":"); ok && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return id
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RouteMonitor 用户订阅的路线监控 (如每天的通勤路线)
+// 在出行时间窗口内定期重新规划，预计时间比基准变差超过阈值时通知用户
+type RouteMonitor struct {
+	ID             uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uint           `json:"-" gorm:"index;not null"`
+	Name           string         `json:"name"`
+	StartID        string         `json:"start_id" gorm:"not null"`
+	EndID          string         `json:"end_id" gorm:"not null"`
+	Modes          pq.StringArray `json:"modes" gorm:"type:text[]"`
+	WindowStart    string         `json:"window_start"`               // 出行时间窗口开始，如 "07:30"
+	WindowEnd      string         `json:"window_end"`                 // 出行时间窗口结束，如 "09:00"
+	Threshold      float64        `json:"threshold"`                  // 预计时间比基准增加的比例超过该值时通知 (如 0.2)
+	NotifyURL      string         `json:"notify_url,omitempty"`       // 通知回调地址 (可选，POST JSON)
+	BaselineTime   float64        `json:"baseline_time"`              // 基准预计时间 (秒，不考虑实时路况)
+	LastTime       float64        `json:"last_time"`                  // 最近一次检查的预计时间 (秒)
+	LastCheckedAt  *time.Time     `json:"last_checked_at"`            // 最近一次检查时间
+	LastNotifiedAt *time.Time     `json:"last_notified_at"`           // 最近一次通知时间
+	Active         bool           `json:"active" gorm:"default:true"` // 是否启用
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// InWindow 给定时刻是否在出行时间窗口内
+func (m *RouteMonitor) InWindow(t time.Time) bool {
+	start, err := ParseClock(m.WindowStart)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(m.WindowEnd)
+	if err != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if end < start { // 跨越零点的窗口
+		return now >= start || now <= end
+	}
+	return now >= start && now <= end
+}
+
+// NotifiedToday 当天是否已经发送过通知 (每个窗口最多通知一次)
+func (m *RouteMonitor) NotifiedToday(t time.Time) bool {
+	if m.LastNotifiedAt == nil {
+		return false
+	}
+	y1, m1, d1 := m.LastNotifiedAt.In(t.Location()).Date()
+	y2, m2, d2 := t.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
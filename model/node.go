@@ -1,9 +1,15 @@
 package model
 
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
 // Point 代表一个经纬度点 (WGS84)
 type Point struct {
-	Lat float64 // 纬度
-	Lng float64 // 经度
+	Lat float64 `json:"lat"` // 纬度
+	Lng float64 `json:"lng"` // 经度
 }
 
 // PointXY 代表平面坐标系中的一个点
@@ -14,9 +20,42 @@ type PointXY struct {
 
 // Node 对应地图上的一个点 (站点、路口、地标)
 type Node struct {
-	ID   string  `json:"id" gorm:"primaryKey"`
-	Name string  `json:"name" gorm:"index"`
-	Lat  float64 `json:"lat"`
-	Lng  float64 `json:"lng"`
-	Type string  `json:"type" gorm:"index"` // 如: "landmark", "subway_entrance", "bus_stop"
+	ID   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"index"`
+	// 英文名称 (可选，请求 Accept-Language: en 时代替 name 返回)
+	NameEn string  `json:"name_en,omitempty"`
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Type   string  `json:"type" gorm:"index"` // 如: "landmark", "subway_entrance", "bus_stop", "parking", "charging"
+	Signal bool    `json:"signal,omitempty"`  // 是否是信号灯路口 (穿过时按交通方式增加等待时间)
+
+	// 重要程度 (1 ~ 5，越大越重要，0 表示未标注)，用于搜索排序和瓦片中节点的显示级别
+	Importance int `json:"importance,omitempty"`
+
+	// 所在楼层 (0 为地面层，负数为地下，如地铁站厅 -1、站台 -2)
+	Level int `json:"level,omitempty" gorm:"not null;default:0"`
+
+	// 停车场、充电站属性
+	Capacity   int            `json:"capacity,omitempty"`                      // 车位数 (单车停放点: 桩位数)
+	Price      float64        `json:"price,omitempty"`                         // 收费 (停车场: 元/小时，充电站: 元/度)
+	Connectors pq.StringArray `json:"connectors,omitempty" gorm:"type:text[]"` // 充电接口，如 ["gb_dc", "gb_ac"]
+	Power      float64        `json:"power,omitempty"`                         // 充电功率 (kW)
+}
+
+// MaxNodeImportance 节点重要程度的上限
+const MaxNodeImportance = 5
+
+// 特殊节点类型
+const (
+	NodeTypeParking  = "parking"   // 停车场
+	NodeTypeCharging = "charging"  // 充电站
+	NodeTypeBikeDock = "bike_dock" // 共享单车停放点
+)
+
+// NodeAlias 节点别名 (简称、俗称，如 "郑大" -> 郑州大学北门)，用于搜索
+type NodeAlias struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NodeID    string    `json:"node_id" gorm:"not null;uniqueIndex:idx_node_alias"`
+	Alias     string    `json:"alias" gorm:"not null;index;uniqueIndex:idx_node_alias"`
+	CreatedAt time.Time `json:"created_at"`
 }
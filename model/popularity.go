@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// 节点使用记录的类型
+const (
+	NodeEventSelect     = "select"      // 用户在搜索结果中选中了该节点
+	NodeEventRouteStart = "route_start" // 作为路径规划的起点
+	NodeEventRouteEnd   = "route_end"   // 作为路径规划的终点
+)
+
+// NodeEvent 一次节点使用记录 (用于统计节点热度)
+type NodeEvent struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	NodeID    string    `json:"node_id" gorm:"index;not null"`
+	Kind      string    `json:"kind" gorm:"not null"`
+	Query     string    `json:"query,omitempty"` // 选中时的搜索关键词
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// NodePopularity 根据使用记录统计的节点热度
+type NodePopularity struct {
+	NodeID      string    `json:"node_id" gorm:"primaryKey"`
+	Selections  int       `json:"selections"`   // 被选中次数
+	RouteStarts int       `json:"route_starts"` // 作为起点次数
+	RouteEnds   int       `json:"route_ends"`   // 作为终点次数
+	Score       float64   `json:"score"`        // 热度 (各类次数的加权和)
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// 道路等级 (用于选取分时速度曲线)
+const (
+	RoadClassHighway = "highway" // 快速路 (仅允许机动车)
+	RoadClassStreet  = "street"  // 普通道路
+)
+
+// 日期类型
+const (
+	DayTypeWeekday = "weekday"
+	DayTypeWeekend = "weekend"
+)
+
+// SpeedProfile 分时速度系数：某一道路等级在工作日/周末某个小时的速度倍数
+// 只作用于道路上的机动车 (驾车、公交)，系数不大于 1 (默认速度视为畅通速度)
+type SpeedProfile struct {
+	ID      uint    `json:"-" gorm:"primaryKey;autoIncrement"`
+	Class   string  `json:"class" gorm:"uniqueIndex:idx_speed_profile;not null"`
+	DayType string  `json:"day_type" gorm:"uniqueIndex:idx_speed_profile;not null"`
+	Hour    int     `json:"hour" gorm:"uniqueIndex:idx_speed_profile"` // 0-23 (本地时间)
+	Factor  float64 `json:"factor"`
+}
+
+// ProfileClass 边使用哪一类分时速度曲线 (快速路或普通道路)
+func (e *Edge) ProfileClass() string {
+	if e.IsHighway() {
+		return RoadClassHighway
+	}
+	return RoadClassStreet
+}
+
+// DayTypeOf 日期类型 (周六、周日为周末)
+func DayTypeOf(t time.Time) string {
+	switch t.Weekday() {
+	case time.Saturday, time.Sunday:
+		return DayTypeWeekend
+	default:
+		return DayTypeWeekday
+	}
+}
+
+// IsRoadVehicle 是否为受道路拥堵影响的交通方式
+func IsRoadVehicle(mode string) bool {
+	return mode == "car" || mode == "truck" || mode == "bus"
+}
+
+// DefaultSpeedProfiles 默认的分时速度系数 (早晚高峰)
+// 工作日 7-9 点、17-19 点为高峰，周末白天轻微拥堵，其余时段为 1
+func DefaultSpeedProfiles() []SpeedProfile {
+	weekday := map[int][2]float64{ // 小时 -> {普通道路, 快速路}
+		7: {0.6, 0.7}, 8: {0.6, 0.7}, 9: {0.8, 0.85},
+		16: {0.8, 0.85}, 17: {0.6, 0.7}, 18: {0.6, 0.7}, 19: {0.8, 0.85},
+	}
+
+	var profiles []SpeedProfile
+	for hour := 0; hour < 24; hour++ {
+		street, highway := 1.0, 1.0
+		if f, ok := weekday[hour]; ok {
+			street, highway = f[0], f[1]
+		}
+		profiles = append(profiles,
+			SpeedProfile{Class: RoadClassStreet, DayType: DayTypeWeekday, Hour: hour, Factor: street},
+			SpeedProfile{Class: RoadClassHighway, DayType: DayTypeWeekday, Hour: hour, Factor: highway},
+		)
+
+		street, highway = 1.0, 1.0
+		if hour >= 10 && hour <= 19 {
+			street, highway = 0.85, 0.9
+		}
+		profiles = append(profiles,
+			SpeedProfile{Class: RoadClassStreet, DayType: DayTypeWeekend, Hour: hour, Factor: street},
+			SpeedProfile{Class: RoadClassHighway, DayType: DayTypeWeekend, Hour: hour, Factor: highway},
+		)
+	}
+	return profiles
+}
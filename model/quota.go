@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// 每日用量的统计对象
+const (
+	UsageSubjectUser   = "user"    // 登录用户
+	UsageSubjectAPIKey = "api_key" // API Key
+)
+
+// DailyUsage 用户或 API Key 某一天的请求次数 (用于配额和用量查询)
+// 当天的计数保存在共享缓存中，定期累加到数据库；匿名请求不统计
+type DailyUsage struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	Day       string    `json:"day" gorm:"size:10;not null;uniqueIndex:idx_daily_usage,priority:3"` // 服务器时区的日期，如 2026-10-16
+	Subject   string    `json:"subject" gorm:"size:16;not null;uniqueIndex:idx_daily_usage,priority:1"`
+	SubjectID uint      `json:"subject_id" gorm:"not null;uniqueIndex:idx_daily_usage,priority:2"`
+	Requests  int64     `json:"requests"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// SavedRoute 用户收藏的路线
+// 规划参数和路线规划结果以 JSON 形式保存，可以随时下载离线包，不需要重新规划
+type SavedRoute struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint      `json:"-" gorm:"index;not null"`
+	Name          string    `json:"name" gorm:"size:100;not null"`
+	StartID       string    `json:"start_id"`
+	StartName     string    `json:"start_name"`
+	EndID         string    `json:"end_id"`
+	EndName       string    `json:"end_name"`
+	Fingerprint   string    `json:"fingerprint"`        // 路线指纹
+	MapVersion    string    `json:"map_version"`        // 保存时的地图版本
+	Distance      float64   `json:"distance"`           // 总距离 (米)
+	EstimatedTime float64   `json:"estimated_time"`     // 保存时的预计时间 (秒)
+	Request       string    `json:"-" gorm:"type:text"` // 规划参数 (JSON)
+	Route         string    `json:"-" gorm:"type:text"` // 路线规划结果 (JSON)
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// SharedRoute 分享的路线 (ETA 分享链接)
+// 路线规划结果以 JSON 形式保存，分享页面不需要重新规划
+type SharedRoute struct {
+	ID            uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	Token         string    `json:"token" gorm:"uniqueIndex;size:16;not null"` // 短链接令牌
+	UserID        uint      `json:"-" gorm:"index"`                            // 分享者 (未登录为 0)
+	StartID       string    `json:"start_id"`
+	EndID         string    `json:"end_id"`
+	Route         string    `json:"-" gorm:"type:text"` // 路线规划结果 (JSON)
+	EstimatedTime float64   `json:"estimated_time"`     // 预计总时间 (秒)
+	DepartAt      time.Time `json:"depart_at"`          // 出发时间
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ArriveAt 预计到达时间
+func (s *SharedRoute) ArriveAt() time.Time {
+	return s.DepartAt.Add(time.Duration(s.EstimatedTime * float64(time.Second)))
+}
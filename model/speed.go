@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// TripSegment 用户完成导航后上报的一段实际行程 (用于学习路段速度)
+type TripSegment struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `json:"-" gorm:"index"`
+	FromID    string    `json:"from_id" gorm:"index:idx_trip_edge;not null"`
+	ToID      string    `json:"to_id" gorm:"index:idx_trip_edge;not null"`
+	Mode      string    `json:"mode" gorm:"not null"`
+	Distance  float64   `json:"distance"`                // 路段距离 (米)，以上报时的路网为准
+	Duration  float64   `json:"duration"`                // 实际通过时间 (秒)
+	EnteredAt time.Time `json:"entered_at" gorm:"index"` // 进入路段的时间
+	CreatedAt time.Time `json:"-"`
+}
+
+// EdgeSpeed 根据历史行程统计的路段分时平均速度
+type EdgeSpeed struct {
+	ID        uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	FromID    string    `json:"from_id" gorm:"uniqueIndex:idx_edge_speed;not null"`
+	ToID      string    `json:"to_id" gorm:"uniqueIndex:idx_edge_speed;not null"`
+	Mode      string    `json:"mode" gorm:"uniqueIndex:idx_edge_speed;not null"`
+	Hour      int       `json:"hour" gorm:"uniqueIndex:idx_edge_speed"` // 0-23 (本地时间)
+	Speed     float64   `json:"speed"`                                  // 平均速度 (米/秒)
+	Samples   int       `json:"samples"`                                // 样本数量
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ModeSpeeds 某一路段各交通方式的速度 (米/秒)，用于覆盖默认速度
+type ModeSpeeds map[string]float64
+
+// 学习速度的取值范围：不超过默认速度 (保证 ALT 下界仍然可采纳)，也不低于 MinLearnedSpeed
+const MinLearnedSpeed = 0.5
+
+// ClampLearnedSpeed 把学习到的速度限制在 [MinLearnedSpeed, 默认速度] 之内
+func ClampLearnedSpeed(mode string, speed float64) float64 {
+	if max := GetModeSpeed(mode); speed > max {
+		return max
+	}
+	if speed < MinLearnedSpeed {
+		return MinLearnedSpeed
+	}
+	return speed
+}
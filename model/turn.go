@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// 转弯规则类型 (只约束驾车，步行不受限制)
+// no_* 禁止从 FromID 经 ViaID 驶向 ToID；only_* 从 FromID 驶到 ViaID 后只能驶向 ToID；
+// penalty 不禁止通行，只给这个转弯增加 Penalty 秒 (如需要等待专用信号灯的左转)
+const (
+	TurnNoLeft       = "no_left_turn"
+	TurnNoRight      = "no_right_turn"
+	TurnNoStraight   = "no_straight_on"
+	TurnNoUTurn      = "no_u_turn"
+	TurnOnlyLeft     = "only_left_turn"
+	TurnOnlyRight    = "only_right_turn"
+	TurnOnlyStraight = "only_straight_on"
+	TurnPenalty      = "penalty"
+)
+
+// MaxTurnPenalty 单个转弯惩罚的上限 (秒)
+const MaxTurnPenalty = 600
+
+// TurnRestriction 路口的转弯规则：从边 FromID -> ViaID 驶入路口 ViaID，再驶入边 ViaID -> ToID
+type TurnRestriction struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FromID      string    `json:"from_id" gorm:"not null"`
+	ViaID       string    `json:"via_id" gorm:"index;not null"`
+	ToID        string    `json:"to_id" gorm:"not null"`
+	Type        string    `json:"type" gorm:"size:32;not null"`
+	Penalty     float64   `json:"penalty,omitempty"` // 转弯惩罚 (秒)，penalty 规则使用
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsValidTurnType 是否是有效的转弯规则类型
+func IsValidTurnType(t string) bool {
+	switch t {
+	case TurnNoLeft, TurnNoRight, TurnNoStraight, TurnNoUTurn,
+		TurnOnlyLeft, TurnOnlyRight, TurnOnlyStraight, TurnPenalty:
+		return true
+	}
+	return false
+}
+
+// Prohibits 是否禁止这个转弯 (no_*)
+func (r *TurnRestriction) Prohibits() bool {
+	switch r.Type {
+	case TurnNoLeft, TurnNoRight, TurnNoStraight, TurnNoUTurn:
+		return true
+	}
+	return false
+}
+
+// Mandatory 是否是只能这样转弯的规则 (only_*)
+func (r *TurnRestriction) Mandatory() bool {
+	switch r.Type {
+	case TurnOnlyLeft, TurnOnlyRight, TurnOnlyStraight:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 使用事件的类型
+const (
+	UsageRequest = "request" // 一次 API 请求
+	UsageRoute   = "route"   // 一次成功的路径规划
+	UsageTrip    = "trip"    // 用户上报的完成行程 (带导航时的预计时间)
+)
+
+// UsageEvent 一条使用事件 (用于管理员统计)
+// 只记录统计需要的字段，不保存请求参数和客户端 IP
+type UsageEvent struct {
+	ID       uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	Kind     string `json:"kind" gorm:"index:idx_usage_kind_time,priority:1;not null"`
+	Endpoint string `json:"endpoint,omitempty"` // 接口路由模板，如 /api/nodes/:id (request)
+	Method   string `json:"method,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	UserID   uint   `json:"user_id,omitempty" gorm:"index"` // 未登录为 0
+
+	// 以下字段用于 route 和 trip
+	Mode          string  `json:"mode,omitempty"`      // 主要交通方式 (距离最长)
+	Modes         string  `json:"modes,omitempty"`     // 使用的全部交通方式 (按使用顺序，逗号分隔)
+	StartID       string  `json:"start_id,omitempty"`  // 起点节点
+	EndID         string  `json:"end_id,omitempty"`    // 终点节点
+	StartLat      float64 `json:"start_lat,omitempty"` // 起点坐标
+	StartLng      float64 `json:"start_lng,omitempty"`
+	EndLat        float64 `json:"end_lat,omitempty"` // 终点坐标
+	EndLng        float64 `json:"end_lng,omitempty"`
+	EstimatedTime float64 `json:"estimated_time,omitempty"` // 预计时间 (秒)
+	ActualTime    float64 `json:"actual_time,omitempty"`    // 实际用时 (秒，trip)
+
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_usage_kind_time,priority:2"`
+}
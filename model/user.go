@@ -1,11 +1,72 @@
 package model
 
 // User 用户结构体 (用于登录认证)
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	Username string `json:"username" gorm:"uniqueIndex;not null"` // 用户名唯一且不为空
-	Password string `json:"password" gorm:"not null"`             // 加密后的密码
-	Email    string `json:"email"`
+	Username      string `json:"username" gorm:"uniqueIndex;not null"` // 用户名唯一且不为空
+	Password      string `json:"password" gorm:"not null"`             // 加密后的密码
+	Email         string `json:"email" gorm:"index"`
+	EmailVerified bool   `json:"email_verified" gorm:"default:false"` // 邮箱是否已验证
+	Role          string `json:"role" gorm:"default:user;not null"`   // 角色: "user" 或 "admin"
+}
+
+// 用户角色
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// 令牌用途
+const (
+	TokenPurposePasswordReset = "password_reset" // 找回密码
+	TokenPurposeEmailVerify   = "email_verify"   // 邮箱验证
+)
+
+// UserToken 一次性令牌 (找回密码、邮箱验证)
+// 数据库中只保存令牌的 SHA-256 摘要，原始令牌只出现在发给用户的邮件里
+type UserToken struct {
+	ID        uint       `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	Purpose   string     `json:"purpose" gorm:"index;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"` // 为空表示尚未使用
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsValid 令牌是否仍然可用 (未使用且未过期)
+func (t *UserToken) IsValid(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// UserIdentity 第三方登录身份，与本地用户关联
+// 同一平台的同一用户只能关联一个本地账号
+type UserIdentity struct {
+	ID             uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID         uint      `json:"user_id" gorm:"index;not null"`
+	Provider       string    `json:"provider" gorm:"uniqueIndex:idx_provider_user;not null"`
+	ProviderUserID string    `json:"provider_user_id" gorm:"uniqueIndex:idx_provider_user;not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UserProfile 用户出行偏好
+// 路径规划请求没有显式指定对应参数时，使用这里保存的默认值
+type UserProfile struct {
+	ID             uint           `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID         uint           `json:"-" gorm:"uniqueIndex;not null"`
+	DefaultModes   pq.StringArray `json:"default_modes" gorm:"type:text[]"`                // 默认交通方式
+	WalkSpeed      float64        `json:"walk_speed"`                                      // 步行速度 (米/秒)，0 表示使用系统默认值
+	AvoidHighways  bool           `json:"avoid_highways"`                                  // 避开快速路
+	AvoidTransfers bool           `json:"avoid_transfers"`                                 // 少换乘
+	AvoidAlleys    bool           `json:"avoid_alleys"`                                    // 驾车避开小巷和内部道路
+	AvoidStairs    bool           `json:"avoid_stairs"`                                    // 步行避开楼梯
+	Vehicle        Vehicle        `json:"vehicle" gorm:"embedded;embeddedPrefix:vehicle_"` // 车辆信息 (限行、低排放区)
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
@@ -0,0 +1,51 @@
+package model
+
+import "strings"
+
+// MaxEmissionStandard 排放标准上限 (国六)
+const MaxEmissionStandard = 6
+
+// Vehicle 用户车辆信息 (用于限行、低排放区等规则判断)
+type Vehicle struct {
+	PlateNumber      string `json:"plate_number,omitempty"`      // 车牌号，如 "豫A12345"
+	EmissionStandard int    `json:"emission_standard,omitempty"` // 排放标准 (国几)，0 表示未知
+	NewEnergy        bool   `json:"new_energy,omitempty"`        // 是否为新能源车
+
+	// 车辆尺寸 (货车)，0 表示未知，不检查对应的限制
+	Height float64 `json:"height,omitempty"` // 高度 (米)
+	Weight float64 `json:"weight,omitempty"` // 总重 (吨)
+	Width  float64 `json:"width,omitempty"`  // 宽度 (米)
+}
+
+// IsDrivingMode 是否为驾车类交通方式 (驾车、货车)，区域规则和车辆限制只作用于这些方式
+func IsDrivingMode(mode string) bool {
+	return mode == "car" || mode == "truck"
+}
+
+// Fits 车辆尺寸是否满足边的限高、限重、限宽 (车辆为空时不检查)
+func (v *Vehicle) Fits(e *Edge) bool {
+	if v == nil {
+		return true
+	}
+	if e.MaxHeight > 0 && v.Height > e.MaxHeight {
+		return false
+	}
+	if e.MaxWeight > 0 && v.Weight > e.MaxWeight {
+		return false
+	}
+	if e.MaxWidth > 0 && v.Width > e.MaxWidth {
+		return false
+	}
+	return true
+}
+
+// PlateDigit 车牌尾号 (最后一位数字，尾号为字母时向前取)，没有数字时返回 -1
+func (v *Vehicle) PlateDigit() int {
+	plate := strings.TrimSpace(v.PlateNumber)
+	for i := len(plate) - 1; i >= 0; i-- {
+		if c := plate[i]; c >= '0' && c <= '9' {
+			return int(c - '0')
+		}
+	}
+	return -1
+}
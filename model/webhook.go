@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook 外部系统订阅的事件回调
+type Webhook struct {
+	ID              uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	URL             string         `json:"url" gorm:"not null"`
+	Secret          string         `json:"-" gorm:"not null"`         // 签名密钥 (只在创建时返回一次)
+	Events          pq.StringArray `json:"events" gorm:"type:text[]"` // 订阅的事件，为空表示全部
+	Description     string         `json:"description,omitempty"`
+	Active          bool           `json:"active" gorm:"default:true"`
+	LastStatus      int            `json:"last_status,omitempty"`       // 最近一次投递的 HTTP 状态码
+	LastError       string         `json:"last_error,omitempty"`        // 最近一次投递失败的原因
+	LastDeliveredAt *time.Time     `json:"last_delivered_at,omitempty"` // 最近一次投递时间
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// Subscribes 是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 区域规则类型 (只约束驾车)
+const (
+	ZoneRuleForbidden = "forbidden" // 禁止驾车通行
+	ZoneRuleCharge    = "charge"    // 驶入收费 (拥堵费)
+	ZoneRulePlate     = "plate"     // 尾号限行
+	ZoneRuleEmission  = "emission"  // 低排放区 (排放标准不达标禁止驶入)
+)
+
+// ZoneRule 作用于某个地理围栏的驾车规则
+type ZoneRule struct {
+	ID              uint          `json:"id" gorm:"primaryKey;autoIncrement"`
+	GeofenceID      uint          `json:"geofence_id" gorm:"index;not null"`
+	Type            string        `json:"type" gorm:"not null"`
+	Days            pq.Int64Array `json:"days" gorm:"type:integer[]"`         // 生效的星期 (0=周日 ... 6=周六)，为空表示每天
+	StartTime       string        `json:"start_time,omitempty"`               // 生效时段开始，如 "07:00"，为空表示全天
+	EndTime         string        `json:"end_time,omitempty"`                 // 生效时段结束，如 "20:00"
+	Fee             float64       `json:"fee,omitempty"`                      // 驶入费用 (元)，charge 规则使用
+	PlateDigits     pq.Int64Array `json:"plate_digits" gorm:"type:integer[]"` // 限行尾号，plate 规则使用
+	MinEmission     int           `json:"min_emission,omitempty"`             // 最低排放标准，emission 规则使用
+	ExemptNewEnergy bool          `json:"exempt_new_energy"`                  // 新能源车是否豁免
+	Description     string        `json:"description,omitempty"`
+	Active          bool          `json:"active" gorm:"default:true"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// ActiveAt 规则在给定时刻是否生效
+func (r *ZoneRule) ActiveAt(t time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	if len(r.Days) > 0 {
+		found := false
+		for _, d := range r.Days {
+			if int(d) == int(t.Weekday()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.StartTime == "" || r.EndTime == "" {
+		return true
+	}
+	start, err1 := ParseClock(r.StartTime)
+	end, err2 := ParseClock(r.EndTime)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	now := t.Hour()*60 + t.Minute()
+	if end < start { // 跨越零点
+		return now >= start || now < end
+	}
+	return now >= start && now < end
+}
+
+// Forbids 规则在给定时刻是否禁止该车辆驶入
+// 车辆信息未知 (为空或缺少对应字段) 时，尾号和排放规则不生效
+func (r *ZoneRule) Forbids(v *Vehicle, t time.Time) bool {
+	if !r.ActiveAt(t) {
+		return false
+	}
+	if v != nil && v.NewEnergy && r.ExemptNewEnergy {
+		return false
+	}
+
+	switch r.Type {
+	case ZoneRuleForbidden:
+		return true
+	case ZoneRulePlate:
+		if v == nil {
+			return false
+		}
+		digit := v.PlateDigit()
+		for _, d := range r.PlateDigits {
+			if int(d) == digit {
+				return true
+			}
+		}
+		return false
+	case ZoneRuleEmission:
+		return v != nil && v.EmissionStandard > 0 && v.EmissionStandard < r.MinEmission
+	default:
+		return false
+	}
+}
+
+// Charges 规则在给定时刻对该车辆收取的驶入费用 (元)
+func (r *ZoneRule) Charges(v *Vehicle, t time.Time) float64 {
+	if r.Type != ZoneRuleCharge || !r.ActiveAt(t) {
+		return 0
+	}
+	if v != nil && v.NewEnergy && r.ExemptNewEnergy {
+		return 0
+	}
+	return r.Fee
+}
@@ -0,0 +1,179 @@
+package monitor
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/mail"
+	"traffic-system/model"
+)
+
+// commuteIterations 反推出发时间的最多迭代次数 (分时速度按小时变化，通常两三次即可收敛)
+const commuteIterations = 4
+
+// CommutePlan 某个通勤日的建议出发时间
+type CommutePlan struct {
+	CommuteID     uint      `json:"commute_id"`
+	Name          string    `json:"name"`
+	StartID       string    `json:"start_id"`
+	EndID         string    `json:"end_id"`
+	ArriveBy      time.Time `json:"arrive_by"`      // 目标到达时刻
+	DepartAt      time.Time `json:"depart_at"`      // 建议出发时刻 (已扣除富余时间)
+	EstimatedTime float64   `json:"estimated_time"` // 按建议出发时刻的预计时间 (秒)
+	Buffer        int       `json:"buffer"`         // 富余时间 (分钟)
+	PlannedAt     time.Time `json:"planned_at"`
+}
+
+// PlanCommute 按 day 当天的分时路况反推建议出发时间：出发时间 = 到达时间 - 预计时间 - 富余时间，
+// 预计时间取决于出发时刻，因此从到达时间开始反复迭代直到出发时间不再变化。找不到路径时返回 false
+func PlanCommute(g *algo.Graph, c *model.Commute, day, now time.Time) (CommutePlan, bool) {
+	arrive, err := c.ArrivalOn(day)
+	if err != nil {
+		return CommutePlan{}, false
+	}
+	buffer := time.Duration(c.Buffer) * time.Minute
+
+	opts := algo.SearchOptions{ModeMask: model.ParseModes(c.Modes)}
+	depart, estimated := arrive.Add(-buffer), 0.0
+	for i := 0; i < commuteIterations; i++ {
+		opts.DepartAt = depart
+		result := g.AStar(c.StartID, c.EndID, opts)
+		if !result.Found {
+			return CommutePlan{}, false
+		}
+		estimated = result.EstimatedTime
+		next := arrive.Add(-buffer - time.Duration(estimated*float64(time.Second))).Truncate(time.Minute)
+		if next.Equal(depart) {
+			break
+		}
+		depart = next
+	}
+
+	return CommutePlan{
+		CommuteID:     c.ID,
+		Name:          commuteName(c),
+		StartID:       c.StartID,
+		EndID:         c.EndID,
+		ArriveBy:      arrive,
+		DepartAt:      depart,
+		EstimatedTime: estimated,
+		Buffer:        c.Buffer,
+		PlannedAt:     now,
+	}, true
+}
+
+// StoredPlan day 当天已经计算并保存的建议出发时间，没有时返回 false
+func StoredPlan(c *model.Commute, day time.Time) (CommutePlan, bool) {
+	if !c.PlannedOn(day) || c.DepartAt == nil {
+		return CommutePlan{}, false
+	}
+	arrive, err := c.ArrivalOn(day)
+	if err != nil {
+		return CommutePlan{}, false
+	}
+	return CommutePlan{
+		CommuteID:     c.ID,
+		Name:          commuteName(c),
+		StartID:       c.StartID,
+		EndID:         c.EndID,
+		ArriveBy:      arrive,
+		DepartAt:      *c.DepartAt,
+		EstimatedTime: c.EstimatedTime,
+		Buffer:        c.Buffer,
+		PlannedAt:     *c.PlannedAt,
+	}, true
+}
+
+// SavePlan 把计算结果写回通勤计划
+func SavePlan(c *model.Commute, plan CommutePlan) error {
+	c.DepartAt, c.EstimatedTime, c.PlannedAt = &plan.DepartAt, plan.EstimatedTime, &plan.PlannedAt
+	return db.DB.Model(c).Updates(map[string]interface{}{
+		"depart_at":      plan.DepartAt,
+		"estimated_time": plan.EstimatedTime,
+		"planned_at":     plan.PlannedAt,
+	}).Error
+}
+
+// CheckCommutes 检查今天的通勤计划：到达时间前 COMMUTE_LEAD (默认 3 小时) 内按当前路况重新计算建议出发时间，
+// 距离建议出发时间不到 COMMUTE_NOTIFY_BEFORE (默认 30 分钟) 时通知用户 (每天一次)
+func CheckCommutes(g *algo.Graph, now time.Time) {
+	var commutes []model.Commute
+	if err := db.DB.Where("active = ?", true).Find(&commutes).Error; err != nil {
+		slog.Error("查询通勤计划失败", "error", err)
+		return
+	}
+
+	lead := config.GetDuration("COMMUTE_LEAD", 3*time.Hour)
+	notifyBefore := config.GetDuration("COMMUTE_NOTIFY_BEFORE", 30*time.Minute)
+	for i := range commutes {
+		c := &commutes[i]
+		arrive, err := c.ArrivalOn(now)
+		if err != nil || !c.ScheduledOn(now) || now.Before(arrive.Add(-lead)) || !now.Before(arrive) {
+			continue
+		}
+
+		plan, found := PlanCommute(g, c, now, now)
+		if !found {
+			continue
+		}
+		if err := SavePlan(c, plan); err != nil {
+			slog.Error("更新通勤计划失败", "commute", c.ID, "error", err)
+		}
+
+		if c.NotifiedOn(now) || now.Before(plan.DepartAt.Add(-notifyBefore)) {
+			continue
+		}
+		if err := notifyCommute(c, plan); err != nil {
+			slog.Error("发送通勤提醒失败", "commute", c.ID, "error", err)
+			continue
+		}
+		if err := db.DB.Model(c).Update("notified_at", now).Error; err != nil {
+			slog.Error("更新通勤计划失败", "commute", c.ID, "error", err)
+		}
+	}
+}
+
+// notifyCommute 通过邮件和回调地址发送建议出发时间 (任一方式成功即可)
+func notifyCommute(c *model.Commute, plan CommutePlan) error {
+	var lastErr error
+	sent := false
+
+	var user model.User
+	if err := db.DB.First(&user, c.UserID).Error; err == nil && user.Email != "" {
+		subject := fmt.Sprintf("VV Maps 通勤提醒: %s", commuteName(c))
+		body := fmt.Sprintf("你好 %s:\n\n按当前路况，%s 预计需要 %.0f 分钟。想在 %s 前到达，建议 %s 出发 (已预留 %d 分钟)。",
+			user.Username, commuteName(c), plan.EstimatedTime/60, plan.ArriveBy.Format("15:04"), plan.DepartAt.Format("15:04"), plan.Buffer)
+		if err := mail.Send(user.Email, subject, body); err != nil {
+			lastErr = err
+		} else {
+			sent = true
+		}
+	}
+
+	if c.NotifyURL != "" {
+		if err := postJSON(c.NotifyURL, plan); err != nil {
+			lastErr = err
+		} else {
+			sent = true
+		}
+	}
+
+	if sent {
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的通知方式")
+	}
+	return lastErr
+}
+
+// commuteName 通勤计划的显示名称
+func commuteName(c *model.Commute) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.StartID + " -> " + c.EndID
+}
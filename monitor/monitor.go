@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"traffic-system/algo"
+	"traffic-system/db"
+	"traffic-system/mail"
+	"traffic-system/model"
+)
+
+// Alert 预计时间变差时发出的通知内容
+type Alert struct {
+	MonitorID    uint      `json:"monitor_id"`
+	Name         string    `json:"name"`
+	StartID      string    `json:"start_id"`
+	EndID        string    `json:"end_id"`
+	BaselineTime float64   `json:"baseline_time"` // 基准预计时间 (秒)
+	CurrentTime  float64   `json:"current_time"`  // 当前预计时间 (秒)
+	Increase     float64   `json:"increase"`      // 增加比例
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Evaluate 规划监控路线并返回当前预计时间 (秒)，找不到路径时返回 false
+// departAt 为空时不使用分时速度，结果可作为基准时间
+func Evaluate(g *algo.Graph, m *model.RouteMonitor, departAt time.Time) (float64, bool) {
+	opts := algo.SearchOptions{ModeMask: model.ParseModes(m.Modes), DepartAt: departAt}
+	result := g.AStar(m.StartID, m.EndID, opts)
+	return result.EstimatedTime, result.Found
+}
+
+// Start 在后台定期检查处于出行时间窗口内的监控路线和今天的通勤计划
+// graph 返回当前使用的路网 (路网可能被重新加载)
+func Start(interval time.Duration, graph func() *algo.Graph) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if g := graph(); g != nil {
+				CheckAll(g, now)
+				CheckCommutes(g, now)
+			}
+		}
+	}()
+}
+
+// CheckAll 检查所有启用且处于时间窗口内的监控路线，预计时间变差超过阈值时发送通知
+func CheckAll(g *algo.Graph, now time.Time) {
+	var monitors []model.RouteMonitor
+	if err := db.DB.Where("active = ?", true).Find(&monitors).Error; err != nil {
+		slog.Error("查询路线监控失败", "error", err)
+		return
+	}
+
+	for i := range monitors {
+		m := &monitors[i]
+		if !m.InWindow(now) {
+			continue
+		}
+
+		current, found := Evaluate(g, m, now)
+		updates := map[string]interface{}{"last_checked_at": now}
+		if found {
+			updates["last_time"] = current
+		}
+
+		if found && m.BaselineTime > 0 && !m.NotifiedToday(now) {
+			increase := (current - m.BaselineTime) / m.BaselineTime
+			if increase > m.Threshold {
+				alert := Alert{
+					MonitorID:    m.ID,
+					Name:         m.Name,
+					StartID:      m.StartID,
+					EndID:        m.EndID,
+					BaselineTime: m.BaselineTime,
+					CurrentTime:  current,
+					Increase:     increase,
+					CheckedAt:    now,
+				}
+				if err := notify(m, alert); err != nil {
+					slog.Error("发送路线监控通知失败", "monitor", m.ID, "error", err)
+				} else {
+					updates["last_notified_at"] = now
+				}
+			}
+		}
+
+		if err := db.DB.Model(m).Updates(updates).Error; err != nil {
+			slog.Error("更新路线监控失败", "monitor", m.ID, "error", err)
+		}
+	}
+}
+
+// notify 通过邮件和回调地址通知用户 (任一方式成功即可)
+func notify(m *model.RouteMonitor, alert Alert) error {
+	var lastErr error
+	sent := false
+
+	var user model.User
+	if err := db.DB.First(&user, m.UserID).Error; err == nil && user.Email != "" {
+		subject := fmt.Sprintf("VV Maps 路线提醒: %s", monitorName(m))
+		body := fmt.Sprintf("你好 %s:\n\n你关注的路线 %s 当前预计需要 %.0f 分钟，比平时的 %.0f 分钟多 %.0f%%，建议提前出发或改变路线。",
+			user.Username, monitorName(m), alert.CurrentTime/60, alert.BaselineTime/60, alert.Increase*100)
+		if err := mail.Send(user.Email, subject, body); err != nil {
+			lastErr = err
+		} else {
+			sent = true
+		}
+	}
+
+	if m.NotifyURL != "" {
+		if err := postJSON(m.NotifyURL, alert); err != nil {
+			lastErr = err
+		} else {
+			sent = true
+		}
+	}
+
+	if sent {
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的通知方式")
+	}
+	return lastErr
+}
+
+// postJSON 向回调地址发送 JSON
+func postJSON(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("回调地址返回 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// monitorName 监控路线的显示名称
+func monitorName(m *model.RouteMonitor) string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.StartID + " -> " + m.EndID
+}
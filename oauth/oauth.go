@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"traffic-system/config"
+)
+
+// Identity 第三方平台返回的用户身份
+type Identity struct {
+	Provider       string // 平台名称: "wechat", "github", "google"
+	ProviderUserID string // 用户在该平台的唯一 ID (微信为 openid/unionid)
+	Nickname       string
+	Email          string
+	EmailVerified  bool
+}
+
+// Provider 第三方登录平台抽象
+// 前端完成授权跳转后把授权码 (code) 交给后端，由后端换取用户身份
+type Provider interface {
+	Name() string
+	Exchange(ctx context.Context, code, redirectURI string) (*Identity, error)
+}
+
+// providers 已启用的登录平台 (平台名 -> 实现)
+var providers = make(map[string]Provider)
+
+// httpClient 调用第三方接口使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Init 根据环境变量注册已配置的第三方登录平台
+// 只有同时配置了 ID 和 Secret 的平台才会启用
+func Init() {
+	if id, secret := config.GetString("WECHAT_APP_ID", ""), config.GetString("WECHAT_APP_SECRET", ""); id != "" && secret != "" {
+		Register(&WeChatProvider{AppID: id, AppSecret: secret})
+	}
+	if id, secret := config.GetString("GITHUB_CLIENT_ID", ""), config.GetString("GITHUB_CLIENT_SECRET", ""); id != "" && secret != "" {
+		Register(&GitHubProvider{ClientID: id, ClientSecret: secret})
+	}
+	if id, secret := config.GetString("GOOGLE_CLIENT_ID", ""), config.GetString("GOOGLE_CLIENT_SECRET", ""); id != "" && secret != "" {
+		Register(&GoogleProvider{ClientID: id, ClientSecret: secret})
+	}
+}
+
+// Register 注册一个登录平台 (同名平台会被覆盖)
+func Register(p Provider) {
+	providers[p.Name()] = p
+}
+
+// Get 获取指定名称的登录平台
+func Get(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 返回所有已启用的平台名称
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// getJSON 发送 GET 请求并把 JSON 响应解析到 out
+func getJSON(ctx context.Context, rawURL string, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return doJSON(req, out)
+}
+
+// postForm 发送表单 POST 请求并把 JSON 响应解析到 out
+func postForm(ctx context.Context, rawURL string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return doJSON(req, out)
+}
+
+func doJSON(req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回状态码 %d: %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// WeChatProvider 微信网页授权登录
+type WeChatProvider struct {
+	AppID     string
+	AppSecret string
+}
+
+func (p *WeChatProvider) Name() string { return "wechat" }
+
+func (p *WeChatProvider) Exchange(ctx context.Context, code, redirectURI string) (*Identity, error) {
+	// 1. 用 code 换取 access_token 和 openid
+	var token struct {
+		AccessToken string `json:"access_token"`
+		OpenID      string `json:"openid"`
+		UnionID     string `json:"unionid"`
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+	}
+	q := url.Values{
+		"appid":      {p.AppID},
+		"secret":     {p.AppSecret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}
+	if err := getJSON(ctx, "https://api.weixin.qq.com/sns/oauth2/access_token?"+q.Encode(), nil, &token); err != nil {
+		return nil, err
+	}
+	// 微信接口出错时仍返回 200，需要检查 errcode
+	if token.ErrCode != 0 {
+		return nil, fmt.Errorf("微信授权失败: %d %s", token.ErrCode, token.ErrMsg)
+	}
+
+	// 2. 获取用户昵称
+	var info struct {
+		Nickname string `json:"nickname"`
+		UnionID  string `json:"unionid"`
+		ErrCode  int    `json:"errcode"`
+	}
+	q = url.Values{"access_token": {token.AccessToken}, "openid": {token.OpenID}}
+	if err := getJSON(ctx, "https://api.weixin.qq.com/sns/userinfo?"+q.Encode(), nil, &info); err != nil {
+		return nil, err
+	}
+
+	// 优先使用 unionid (同一开放平台下多个应用共用)，否则使用 openid
+	userID := token.OpenID
+	if token.UnionID != "" {
+		userID = token.UnionID
+	} else if info.UnionID != "" {
+		userID = info.UnionID
+	}
+
+	return &Identity{
+		Provider:       p.Name(),
+		ProviderUserID: userID,
+		Nickname:       info.Nickname,
+	}, nil
+}
+
+// GitHubProvider GitHub OAuth 登录
+type GitHubProvider struct {
+	ClientID     string
+	ClientSecret string
+}
+
+func (p *GitHubProvider) Name() string { return "github" }
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code, redirectURI string) (*Identity, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	form := url.Values{
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+	}
+	if err := postForm(ctx, "https://github.com/login/oauth/access_token", form, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("GitHub 授权失败: %s", token.Error)
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	header := http.Header{
+		"Authorization": {"Bearer " + token.AccessToken},
+		"Accept":        {"application/vnd.github+json"},
+	}
+	if err := getJSON(ctx, "https://api.github.com/user", header, &user); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:       p.Name(),
+		ProviderUserID: strconv.FormatInt(user.ID, 10),
+		Nickname:       user.Login,
+		Email:          user.Email,
+	}, nil
+}
+
+// GoogleProvider Google OAuth 登录
+type GoogleProvider struct {
+	ClientID     string
+	ClientSecret string
+}
+
+func (p *GoogleProvider) Name() string { return "google" }
+
+func (p *GoogleProvider) Exchange(ctx context.Context, code, redirectURI string) (*Identity, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	form := url.Values{
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"grant_type":    {"authorization_code"},
+	}
+	if err := postForm(ctx, "https://oauth2.googleapis.com/token", form, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("Google 授权失败: 未返回 access_token")
+	}
+
+	var user struct {
+		Sub           string `json:"sub"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + token.AccessToken}}
+	if err := getJSON(ctx, "https://openidconnect.googleapis.com/v1/userinfo", header, &user); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:       p.Name(),
+		ProviderUserID: user.Sub,
+		Nickname:       user.Name,
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
+	}, nil
+}
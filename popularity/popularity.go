@@ -0,0 +1,94 @@
+package popularity
+
+import (
+	"log/slog"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// 各类使用记录在热度中的权重：终点最能说明 "常去的地方"
+const (
+	selectWeight     = 1.0
+	routeStartWeight = 0.5
+	routeEndWeight   = 2.0
+)
+
+// Record 异步保存一条节点使用记录，失败只记录日志 (不影响接口响应)；没有数据库时忽略
+func Record(nodeID, kind, query string) {
+	if db.DB == nil {
+		return
+	}
+	event := model.NodeEvent{NodeID: nodeID, Kind: kind, Query: query}
+	go func() {
+		if err := db.DB.Create(&event).Error; err != nil {
+			slog.Error("保存节点使用记录失败", "error", err)
+		}
+	}()
+}
+
+// Recompute 根据最近 window 内的使用记录重新统计节点热度，并替换 node_popularities 表
+func Recompute(window time.Duration) ([]model.NodePopularity, error) {
+	var rows []model.NodePopularity
+	err := db.Reader().Model(&model.NodeEvent{}).
+		Select("node_id, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS selections, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS route_starts, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS route_ends",
+			model.NodeEventSelect, model.NodeEventRouteStart, model.NodeEventRouteEnd).
+		Where("created_at >= ?", time.Now().Add(-window)).
+		Group("node_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range rows {
+		r := &rows[i]
+		r.Score = float64(r.Selections)*selectWeight + float64(r.RouteStarts)*routeStartWeight + float64(r.RouteEnds)*routeEndWeight
+		r.UpdatedAt = now
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.NodePopularity{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Load 读取已统计的节点热度
+func Load() ([]model.NodePopularity, error) {
+	var rows []model.NodePopularity
+	if err := db.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// StartWorker 在后台定期重新统计节点热度，并通过 apply 应用到搜索排序
+func StartWorker(interval, window time.Duration, apply func([]model.NodePopularity) int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			rows, err := Recompute(window)
+			if err != nil {
+				slog.Error("统计节点热度失败", "error", err)
+				continue
+			}
+			matched := apply(rows)
+			slog.Info("节点热度已更新", "nodes", len(rows), "matched", matched)
+		}
+	}()
+}
@@ -0,0 +1,108 @@
+package realtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"traffic-system/config"
+)
+
+// VehiclePosition 公交/地铁车辆的实时位置
+type VehiclePosition struct {
+	VehicleID  string    `json:"vehicle_id" binding:"required"`
+	LineID     string    `json:"line_id" binding:"required"`
+	Lat        float64   `json:"lat" binding:"required"`
+	Lng        float64   `json:"lng" binding:"required"`
+	Bearing    float64   `json:"bearing,omitempty"`       // 行驶方向 (度，正北为 0)
+	Speed      float64   `json:"speed,omitempty"`         // 速度 (米/秒)
+	NextStopID string    `json:"next_stop_id,omitempty"`  // 下一站节点 ID
+	Delay      int       `json:"delay_seconds,omitempty"` // 相对时刻表的晚点秒数 (提前为负数)
+	Timestamp  time.Time `json:"timestamp"`               // 定位时间，为空时使用接收时间
+}
+
+// Store 在内存中保存每辆车的最新位置
+// 超过 ttl 未更新的车辆视为已下线，查询时不返回
+type Store struct {
+	mu       sync.RWMutex
+	vehicles map[string]VehiclePosition // 车辆 ID -> 最新位置
+	ttl      time.Duration
+}
+
+// NewStore 创建车辆位置存储
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		vehicles: make(map[string]VehiclePosition),
+		ttl:      ttl,
+	}
+}
+
+// Vehicles 全局车辆位置存储 (应在 main 中通过 Init 初始化)
+var Vehicles = NewStore(2 * time.Minute)
+
+// Init 根据环境变量初始化全局存储
+func Init() {
+	Vehicles = NewStore(config.GetDuration("REALTIME_TTL", 2*time.Minute))
+}
+
+// Update 写入一条位置，比已保存的位置更旧的数据会被忽略
+func (s *Store) Update(p VehiclePosition) bool {
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.vehicles[p.VehicleID]; ok && old.Timestamp.After(p.Timestamp) {
+		return false
+	}
+	s.vehicles[p.VehicleID] = p
+	return true
+}
+
+// ByLine 指定线路上仍然在线的车辆 (按车辆 ID 排序)
+func (s *Store) ByLine(lineID string, now time.Time) []VehiclePosition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]VehiclePosition, 0)
+	for _, v := range s.vehicles {
+		if v.LineID == lineID && s.fresh(v, now) {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].VehicleID < result[j].VehicleID })
+	return result
+}
+
+// LineDelay 线路上在线车辆的平均晚点秒数，没有在线车辆时返回 false
+func (s *Store) LineDelay(lineID string, now time.Time) (int, bool) {
+	vehicles := s.ByLine(lineID, now)
+	if len(vehicles) == 0 {
+		return 0, false
+	}
+	total := 0
+	for _, v := range vehicles {
+		total += v.Delay
+	}
+	return total / len(vehicles), true
+}
+
+// Prune 删除过期的车辆，返回删除数量
+func (s *Store) Prune(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, v := range s.vehicles {
+		if !s.fresh(v, now) {
+			delete(s.vehicles, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// fresh 位置是否仍在有效期内
+func (s *Store) fresh(v VehiclePosition, now time.Time) bool {
+	return s.ttl <= 0 || now.Sub(v.Timestamp) <= s.ttl
+}
@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"traffic-system/algo"
+)
+
+// 快照位置可以是本地文件路径，也可以是 http(s) 地址：
+// 读取时 GET，发布时 PUT (适用于 S3 / OSS / MinIO 的预签名地址或任何支持 PUT 的对象存储网关)
+
+// httpClient 下载和上传快照使用的客户端 (快照可能较大，超时时间较长)
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+// isURL 位置是否为 http(s) 地址
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// Load 从文件或 http(s) 地址读取快照并恢复图
+func Load(location string) (*algo.Graph, algo.SnapshotInfo, error) {
+	var r io.ReadCloser
+	if isURL(location) {
+		resp, err := httpClient.Get(location)
+		if err != nil {
+			return nil, algo.SnapshotInfo{}, fmt.Errorf("下载快照失败: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, algo.SnapshotInfo{}, fmt.Errorf("下载快照失败: HTTP %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, algo.SnapshotInfo{}, fmt.Errorf("打开快照失败: %w", err)
+		}
+		r = f
+	}
+	defer r.Close()
+	return algo.ReadSnapshot(r)
+}
+
+// Publish 生成快照并写入文件或 PUT 到 http(s) 地址，返回快照概要和大小 (字节)
+// 写文件时先写临时文件再重命名，读取方不会读到写了一半的快照
+func Publish(g *algo.Graph, location string) (algo.SnapshotInfo, int64, error) {
+	var buf bytes.Buffer
+	info, err := g.WriteSnapshot(&buf)
+	if err != nil {
+		return info, 0, err
+	}
+	size := int64(buf.Len())
+
+	if isURL(location) {
+		req, err := http.NewRequest(http.MethodPut, location, &buf)
+		if err != nil {
+			return info, size, err
+		}
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return info, size, fmt.Errorf("上传快照失败: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return info, size, fmt.Errorf("上传快照失败: HTTP %d", resp.StatusCode)
+		}
+		return info, size, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(location), ".snapshot-*")
+	if err != nil {
+		return info, size, fmt.Errorf("写入快照失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := buf.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return info, size, fmt.Errorf("写入快照失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return info, size, err
+	}
+	if err := os.Rename(tmp.Name(), location); err != nil {
+		return info, size, fmt.Errorf("写入快照失败: %w", err)
+	}
+	return info, size, nil
+}
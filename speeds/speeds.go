@@ -0,0 +1,86 @@
+package speeds
+
+import (
+	"log/slog"
+	"time"
+	"traffic-system/db"
+	"traffic-system/model"
+
+	"gorm.io/gorm"
+)
+
+// Recompute 根据最近 window 内上报的行程重新统计路段分时平均速度，并替换 edge_speeds 表
+// 样本数少于 minSamples 的 (路段, 方式, 小时) 组合不参与统计
+func Recompute(window time.Duration, minSamples int) ([]model.EdgeSpeed, error) {
+	var rows []model.EdgeSpeed
+	err := db.Reader().Model(&model.TripSegment{}).
+		Select("from_id, to_id, mode, "+db.HourExpr("entered_at")+" AS hour, "+
+			"SUM(distance) / SUM(duration) AS speed, COUNT(*) AS samples").
+		Where("entered_at >= ? AND duration > 0", time.Now().Add(-window)).
+		Group("from_id, to_id, mode, hour").
+		Having("COUNT(*) >= ?", minSamples).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range rows {
+		rows[i].UpdatedAt = now
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.EdgeSpeed{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Day 统计 date 所在那一天 (本地时间，与分时速度的小时一致) 上报的行程的路段分时平均速度，用于按历史某天的实际路况回放
+// 与 Recompute 不同，不要求最少样本数，也不写入 edge_speeds 表
+func Day(date time.Time) ([]model.EdgeSpeed, error) {
+	date = date.Local()
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	var rows []model.EdgeSpeed
+	err := db.Reader().Model(&model.TripSegment{}).
+		Select("from_id, to_id, mode, "+db.HourExpr("entered_at")+" AS hour, "+
+			"SUM(distance) / SUM(duration) AS speed, COUNT(*) AS samples").
+		Where("entered_at >= ? AND entered_at < ? AND duration > 0", start, start.AddDate(0, 0, 1)).
+		Group("from_id, to_id, mode, hour").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Load 读取已统计的路段速度
+func Load() ([]model.EdgeSpeed, error) {
+	var rows []model.EdgeSpeed
+	if err := db.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// StartWorker 在后台定期重新统计路段速度，并通过 apply 应用到路网
+func StartWorker(interval, window time.Duration, minSamples int, apply func([]model.EdgeSpeed) int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			rows, err := Recompute(window, minSamples)
+			if err != nil {
+				slog.Error("统计路段速度失败", "error", err)
+				continue
+			}
+			matched := apply(rows)
+			slog.Info("路段速度已更新", "rows", len(rows), "matched", matched)
+		}
+	}()
+}
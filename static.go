@@ -0,0 +1,29 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"traffic-system/config"
+)
+
+// 前端页面编译进程序，单个二进制文件即可部署 (不需要再复制 static 目录)
+//
+//go:embed static
+var staticFiles embed.FS
+
+// staticFS 前端页面的文件系统：设置了 STATIC_DIR 时从磁盘读取 (开发时修改页面后刷新即可，不需要重新编译)，
+// 否则使用编译进程序的 static 目录
+func staticFS() http.FileSystem {
+	if dir := config.GetString("STATIC_DIR", ""); dir != "" {
+		slog.Info("前端页面从磁盘读取", "dir", dir)
+		return http.Dir(dir)
+	}
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		log.Fatalf("读取内置前端页面失败: %v", err)
+	}
+	return http.FS(sub)
+}
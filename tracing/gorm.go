@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey 保存在 GORM 语句中的 span
+const spanKey = "tracing:span"
+
+// GormPlugin 为每条 SQL 创建一个 span (db.query、db.create 等)，记录语句、影响行数和错误
+// 只在语句的 Context 中已有 span 时记录 (即通过 db.DB.WithContext 传入了请求的 Context)，后台任务的 SQL 不产生孤立的链路
+type GormPlugin struct{}
+
+// Name 插件名称
+func (GormPlugin) Name() string { return "tracing" }
+
+// Initialize 在 GORM 的各类操作前后注册回调
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name          string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"db.create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"db.query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"db.update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"db.delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"db.row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"db.raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("tracing:before_"+h.name, p.before(h.name)); err != nil {
+			return err
+		}
+		if err := h.after("tracing:after_"+h.name, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// before 开始 span，并把带 span 的 Context 交给后续的驱动调用
+func (GormPlugin) before(name string) func(*gorm.DB) {
+	tracer := otel.Tracer(instrumentationName)
+	return func(tx *gorm.DB) {
+		if !Active(tx.Statement.Context) {
+			return
+		}
+		ctx, span := tracer.Start(tx.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", tx.Dialector.Name())))
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+// after 记录语句和结果，结束 span
+func (GormPlugin) after(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("db.query.text", tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", tx.Statement.Table))
+	}
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"traffic-system/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetry 链路追踪
+// 一次请求依次产生 HTTP 请求 (Middleware)、请求解析、坐标吸附、路径搜索 (algo)、响应构建和 SQL (GormPlugin) 的 span，
+// 通过 OTLP/HTTP 导出到 Collector / Jaeger / Tempo 等，便于把慢请求按阶段拆开分析。
+// 未配置导出地址时使用 OpenTelemetry 默认的空实现，span 不记录也不导出
+
+// instrumentationName 本服务创建的 span 所属的 tracer 名称
+const instrumentationName = "traffic-system"
+
+// defaultServiceName 未设置 OTEL_SERVICE_NAME 时上报的服务名
+const defaultServiceName = "vv-maps"
+
+// provider 已启用时的 TracerProvider (用于退出时导出剩余的 span)
+var provider *sdktrace.TracerProvider
+
+// Init 配置了 OTEL_EXPORTER_OTLP_ENDPOINT (或 OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) 时启用链路追踪，配置错误时退出
+// 导出器的其他参数 (请求头、超时、压缩) 和采样率 (OTEL_TRACES_SAMPLER) 使用 OpenTelemetry 的标准环境变量
+func Init() {
+	endpoint := config.GetString("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", config.GetString("OTEL_EXPORTER_OTLP_ENDPOINT", ""))
+	if endpoint == "" {
+		return
+	}
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		log.Fatalf("OTLP 导出配置错误: %v", err)
+	}
+	attrs := []attribute.KeyValue{}
+	if config.GetString("OTEL_SERVICE_NAME", "") == "" {
+		attrs = append(attrs, attribute.String("service.name", defaultServiceName))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+	if err != nil {
+		log.Fatalf("OpenTelemetry 资源配置错误: %v", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	slog.Info("已启用 OpenTelemetry 链路追踪", "endpoint", endpoint)
+}
+
+// Shutdown 导出剩余的 span 并停止导出 (未启用时什么也不做)
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start 创建一个子 span (ctx 中没有 span 时为新的根 span)，调用方负责 End
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Active ctx 中是否有正在记录的 span (用于跳过没有请求上下文的后台任务)
+func Active(ctx context.Context) bool {
+	return ctx != nil && trace.SpanFromContext(ctx).IsRecording()
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"traffic-system/model"
+)
+
+// 坐标系转换
+// 内部数据和路网都使用 WGS-84；国内底图 (高德、腾讯等) 的瓦片和定位结果使用 GCJ-02 (偏移了几百米)，
+// 客户端直接把 GCJ-02 坐标当作 WGS-84 传入会吸附到错误的道路上，需要先转换
+
+// CRS 坐标系
+type CRS string
+
+// 支持的坐标系
+const (
+	CRSWGS84 CRS = "wgs84" // GPS 原始坐标 (默认)
+	CRSGCJ02 CRS = "gcj02" // 国测局坐标 (国内底图)
+)
+
+// gcj02 的参考椭球 (克拉索夫斯基椭球) 参数
+const (
+	krasovskyA  = 6378245.0
+	krasovskyEE = 0.00669342162296594323
+)
+
+// ParseCRS 解析坐标系参数 (不区分大小写，空字符串为 wgs84)
+func ParseCRS(s string) (CRS, error) {
+	switch CRS(strings.ToLower(strings.TrimSpace(s))) {
+	case "", CRSWGS84:
+		return CRSWGS84, nil
+	case CRSGCJ02:
+		return CRSGCJ02, nil
+	}
+	return "", fmt.Errorf("坐标系无效: %s (可选 wgs84、gcj02)", s)
+}
+
+// ToWGS84 把坐标系 crs 下的坐标转换为 WGS-84
+func (crs CRS) ToWGS84(p model.Point) model.Point {
+	if crs == CRSGCJ02 {
+		return GCJ02ToWGS84(p)
+	}
+	return p
+}
+
+// FromWGS84 把 WGS-84 坐标转换到坐标系 crs 下
+func (crs CRS) FromWGS84(p model.Point) model.Point {
+	if crs == CRSGCJ02 {
+		return WGS84ToGCJ02(p)
+	}
+	return p
+}
+
+// OutOfChina 坐标是否在国内范围之外 (范围外的坐标不做 GCJ-02 偏移)
+func OutOfChina(p model.Point) bool {
+	return p.Lng < 72.004 || p.Lng > 137.8347 || p.Lat < 0.8293 || p.Lat > 55.8271
+}
+
+// WGS84ToGCJ02 WGS-84 转 GCJ-02
+func WGS84ToGCJ02(p model.Point) model.Point {
+	if OutOfChina(p) {
+		return p
+	}
+	dLat, dLng := gcj02Offset(p)
+	return model.Point{Lat: p.Lat + dLat, Lng: p.Lng + dLng}
+}
+
+// GCJ02ToWGS84 GCJ-02 转 WGS-84
+// 偏移没有解析的逆变换，从 p 减去 p 处的偏移开始迭代修正，误差小于 1e-9 度 (约 0.1 毫米)
+func GCJ02ToWGS84(p model.Point) model.Point {
+	if OutOfChina(p) {
+		return p
+	}
+	dLat, dLng := gcj02Offset(p)
+	w := model.Point{Lat: p.Lat - dLat, Lng: p.Lng - dLng}
+	for range 10 {
+		g := WGS84ToGCJ02(w)
+		eLat, eLng := g.Lat-p.Lat, g.Lng-p.Lng
+		if math.Abs(eLat) < 1e-9 && math.Abs(eLng) < 1e-9 {
+			break
+		}
+		w.Lat -= eLat
+		w.Lng -= eLng
+	}
+	return w
+}
+
+// gcj02Offset WGS-84 坐标 p 在 GCJ-02 中的偏移量 (度)
+func gcj02Offset(p model.Point) (dLat, dLng float64) {
+	x, y := p.Lng-105, p.Lat-35
+	dLat = -100 + 2*x + 3*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x)) +
+		(20*math.Sin(6*x*math.Pi)+20*math.Sin(2*x*math.Pi))*2/3 +
+		(20*math.Sin(y*math.Pi)+40*math.Sin(y/3*math.Pi))*2/3 +
+		(160*math.Sin(y/12*math.Pi)+320*math.Sin(y*math.Pi/30))*2/3
+	dLng = 300 + x + 2*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x)) +
+		(20*math.Sin(6*x*math.Pi)+20*math.Sin(2*x*math.Pi))*2/3 +
+		(20*math.Sin(x*math.Pi)+40*math.Sin(x/3*math.Pi))*2/3 +
+		(150*math.Sin(x/12*math.Pi)+300*math.Sin(x/30*math.Pi))*2/3
+
+	radLat := DegreesToRadians(p.Lat)
+	magic := math.Sin(radLat)
+	magic = 1 - krasovskyEE*magic*magic
+	sqrtMagic := math.Sqrt(magic)
+	dLat = dLat * 180 / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLng = dLng * 180 / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLat, dLng
+}
@@ -34,3 +34,175 @@ func HaversineDistance(p1, p2 model.Point) float64 {
 
 	return EarthRadius * c
 }
+
+// MercatorMaxLat Web Mercator 的纬度上限 (度)，超出的纬度按上限计算
+const MercatorMaxLat = 85.05112878
+
+// ToWebMercator 经纬度转 Web Mercator (EPSG:3857) 平面坐标 (米，原点为赤道与本初子午线的交点)
+func ToWebMercator(p model.Point) model.PointXY {
+	lat := DegreesToRadians(max(-MercatorMaxLat, min(MercatorMaxLat, p.Lat)))
+	return model.PointXY{
+		X: DegreesToRadians(p.Lng) * EarthRadius,
+		Y: math.Log(math.Tan(math.Pi/4+lat/2)) * EarthRadius,
+	}
+}
+
+// FromWebMercator Web Mercator 平面坐标转经纬度
+func FromWebMercator(xy model.PointXY) model.Point {
+	return model.Point{
+		Lat: RadiansToDegrees(2*math.Atan(math.Exp(xy.Y/EarthRadius)) - math.Pi/2),
+		Lng: RadiansToDegrees(xy.X / EarthRadius),
+	}
+}
+
+// LocalProjection 以 Origin 为原点的局部平面坐标 (东-北，ENU 忽略高度)
+// 按原点纬度的等距圆柱投影，城市范围 (几十公里) 内的距离误差很小，用于点到线段投影、折线简化等平面计算
+type LocalProjection struct {
+	Origin model.Point
+	cosLat float64
+}
+
+// NewLocalProjection 创建以 origin 为原点的局部投影
+func NewLocalProjection(origin model.Point) LocalProjection {
+	return LocalProjection{Origin: origin, cosLat: math.Cos(DegreesToRadians(origin.Lat))}
+}
+
+// ToENU 经纬度转局部平面坐标 (米，X 向东，Y 向北)
+func (lp LocalProjection) ToENU(p model.Point) model.PointXY {
+	return model.PointXY{
+		X: DegreesToRadians(p.Lng-lp.Origin.Lng) * lp.cosLat * EarthRadius,
+		Y: DegreesToRadians(p.Lat-lp.Origin.Lat) * EarthRadius,
+	}
+}
+
+// FromENU 局部平面坐标转经纬度
+func (lp LocalProjection) FromENU(xy model.PointXY) model.Point {
+	return model.Point{
+		Lat: lp.Origin.Lat + RadiansToDegrees(xy.Y/EarthRadius),
+		Lng: lp.Origin.Lng + RadiansToDegrees(xy.X/(lp.cosLat*EarthRadius)),
+	}
+}
+
+// ProjectToSegment 把点 p 投影到线段 a-b 上 (以 a 为原点的局部投影，适用于城市范围内的短线段)
+// 返回投影点、投影点在线段上的位置 t (0 = a, 1 = b) 以及 p 到投影点的距离 (米)
+func ProjectToSegment(p, a, b model.Point) (proj model.Point, t float64, dist float64) {
+	lp := NewLocalProjection(a)
+	t = segmentPosition(lp.ToENU(p), model.PointXY{}, lp.ToENU(b))
+	proj = model.Point{
+		Lat: a.Lat + t*(b.Lat-a.Lat),
+		Lng: a.Lng + t*(b.Lng-a.Lng),
+	}
+	return proj, t, HaversineDistance(p, proj)
+}
+
+// segmentPosition 平面上点 p 在线段 a-b 上的投影位置 t (0 = a, 1 = b)
+func segmentPosition(p, a, b model.PointXY) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/lenSq))
+}
+
+// segmentDistanceXY 平面上点 p 到线段 a-b 的距离
+func segmentDistanceXY(p, a, b model.PointXY) float64 {
+	t := segmentPosition(p, a, b)
+	return math.Hypot(p.X-(a.X+t*(b.X-a.X)), p.Y-(a.Y+t*(b.Y-a.Y)))
+}
+
+// RadiansToDegrees 弧度转角度
+func RadiansToDegrees(r float64) float64 {
+	return r * 180.0 / math.Pi
+}
+
+// Bearing 从 p1 指向 p2 的初始方位角 (大圆航向，度，正北为 0，顺时针 0 ~ 360)
+func Bearing(p1, p2 model.Point) float64 {
+	lat1 := DegreesToRadians(p1.Lat)
+	lat2 := DegreesToRadians(p2.Lat)
+	dLon := DegreesToRadians(p2.Lng - p1.Lng)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(RadiansToDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// DestinationPoint 从 p 出发沿方位角 bearingDeg (度) 行进 distM 米后到达的点 (大圆)
+func DestinationPoint(p model.Point, bearingDeg, distM float64) model.Point {
+	lat1 := DegreesToRadians(p.Lat)
+	lon1 := DegreesToRadians(p.Lng)
+	brng := DegreesToRadians(bearingDeg)
+	delta := distM / EarthRadius // 角距离
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(brng))
+	lon2 := lon1 + math.Atan2(math.Sin(brng)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	// 经度归一化到 [-180, 180)
+	lng := math.Mod(RadiansToDegrees(lon2)+540, 360) - 180
+	return model.Point{Lat: RadiansToDegrees(lat2), Lng: lng}
+}
+
+// PointToSegmentDistance 点 p 到线段 a-b 的最短距离 (米)
+func PointToSegmentDistance(p, a, b model.Point) float64 {
+	_, _, dist := ProjectToSegment(p, a, b)
+	return dist
+}
+
+// PolylineLength 折线总长度 (米)，少于 2 个点时为 0
+func PolylineLength(points []model.Point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += HaversineDistance(points[i-1], points[i])
+	}
+	return total
+}
+
+// SimplifyPolyline Douglas–Peucker 折线简化，保留首尾点，删除离简化后折线不超过 tolerance (米) 的点
+// 先把所有点换算到以第一个点为原点的局部平面坐标，再在平面上计算距离
+// tolerance <= 0 或少于 3 个点时原样返回 (不复制)
+func SimplifyPolyline(points []model.Point, tolerance float64) []model.Point {
+	if tolerance <= 0 || len(points) < 3 {
+		return points
+	}
+	lp := NewLocalProjection(points[0])
+	xy := make([]model.PointXY, len(points))
+	for i, p := range points {
+		xy[i] = lp.ToENU(p)
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	// 用栈代替递归，避免很长的路线递归过深
+	type span struct{ first, last int }
+	stack := []span{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDist := -1, tolerance
+		for i := s.first + 1; i < s.last; i++ {
+			if d := segmentDistanceXY(xy[i], xy[s.first], xy[s.last]); d > maxDist {
+				farthest, maxDist = i, d
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+		keep[farthest] = true
+		stack = append(stack, span{s.first, farthest}, span{farthest, s.last})
+	}
+
+	simplified := make([]model.Point, 0, len(points))
+	for i, p := range points {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+// ValidCoordinate 经纬度是否在有效范围内 (纬度 -90 ~ 90，经度 -180 ~ 180)
+func ValidCoordinate(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"traffic-system/model"
+)
+
+// 郑州附近的参考点
+var testOrigin = model.Point{Lat: 34.80, Lng: 113.50}
+
+func TestBearingCardinal(t *testing.T) {
+	cases := []struct {
+		name string
+		to   model.Point
+		want float64
+	}{
+		{"北", model.Point{Lat: 34.81, Lng: 113.50}, 0},
+		{"东", model.Point{Lat: 34.80, Lng: 113.51}, 90},
+		{"南", model.Point{Lat: 34.79, Lng: 113.50}, 180},
+		{"西", model.Point{Lat: 34.80, Lng: 113.49}, 270},
+	}
+	for _, c := range cases {
+		// 同一纬度向东/西的大圆方位角略偏离 90/270 度 (约 0.003 度)
+		if got := Bearing(testOrigin, c.to); math.Abs(got-c.want) > 0.01 {
+			t.Errorf("%s: Bearing = %.4f, 期望 %.0f", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDestinationPointRoundTrip(t *testing.T) {
+	for _, bearing := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+		for _, dist := range []float64{1, 250, 5000, 100000} {
+			dest := DestinationPoint(testOrigin, bearing, dist)
+			if got := HaversineDistance(testOrigin, dest); math.Abs(got-dist) > 1e-6*dist+1e-6 {
+				t.Errorf("方位 %.0f 距离 %.0f: 到达点的距离 = %.6f", bearing, dist, got)
+			}
+			if got := Bearing(testOrigin, dest); math.Abs(math.Remainder(got-bearing, 360)) > 1e-6 {
+				t.Errorf("方位 %.0f 距离 %.0f: 到达点的方位 = %.6f", bearing, dist, got)
+			}
+		}
+	}
+}
+
+func TestDestinationPointWrapsLongitude(t *testing.T) {
+	p := DestinationPoint(model.Point{Lat: 0, Lng: 179.999}, 90, 1000)
+	if p.Lng < -180 || p.Lng >= 180 || p.Lng > -179 {
+		t.Errorf("跨越 180 度经线后经度 = %v，期望在 [-180, -179] 内", p.Lng)
+	}
+}
+
+func TestPointToSegmentDistance(t *testing.T) {
+	a := testOrigin
+	b := model.Point{Lat: 34.80, Lng: 113.51}
+	mid := model.Point{Lat: 34.80, Lng: 113.505}
+
+	// 线段中点正北方 100 米的点
+	north := DestinationPoint(mid, 0, 100)
+	if got := PointToSegmentDistance(north, a, b); math.Abs(got-100) > 0.5 {
+		t.Errorf("中点正北 100 米: 距离 = %.3f", got)
+	}
+	// 超出端点时为到端点的距离
+	beyond := model.Point{Lat: 34.80, Lng: 113.52}
+	if got, want := PointToSegmentDistance(beyond, a, b), HaversineDistance(beyond, b); math.Abs(got-want) > 1e-6 {
+		t.Errorf("超出端点: 距离 = %.3f, 期望 %.3f", got, want)
+	}
+	// 线段上的点距离为 0
+	if got := PointToSegmentDistance(mid, a, b); got > 1e-6 {
+		t.Errorf("线段上的点: 距离 = %v", got)
+	}
+}
+
+func TestPointToSegmentDistanceDegenerate(t *testing.T) {
+	// a == b 时退化为到该点的距离
+	p := model.Point{Lat: 34.81, Lng: 113.51}
+	got := PointToSegmentDistance(p, testOrigin, testOrigin)
+	if want := HaversineDistance(p, testOrigin); math.IsNaN(got) || math.Abs(got-want) > 1e-6 {
+		t.Errorf("退化线段: 距离 = %v, 期望 %v", got, want)
+	}
+	if got := PointToSegmentDistance(testOrigin, testOrigin, testOrigin); got != 0 {
+		t.Errorf("点与退化线段重合: 距离 = %v", got)
+	}
+}
+
+func TestPolylineLength(t *testing.T) {
+	if got := PolylineLength(nil); got != 0 {
+		t.Errorf("空折线: 长度 = %v", got)
+	}
+	if got := PolylineLength([]model.Point{testOrigin}); got != 0 {
+		t.Errorf("单点折线: 长度 = %v", got)
+	}
+
+	b := DestinationPoint(testOrigin, 90, 300)
+	c := DestinationPoint(b, 0, 400)
+	if got := PolylineLength([]model.Point{testOrigin, b, c}); math.Abs(got-700) > 1e-3 {
+		t.Errorf("两段折线: 长度 = %.4f, 期望 700", got)
+	}
+}
+
+func TestWebMercatorRoundTrip(t *testing.T) {
+	points := []model.Point{
+		testOrigin,
+		{Lat: 0, Lng: 0},
+		{Lat: -33.8688, Lng: 151.2093},
+		{Lat: 60.1699, Lng: -179.9999},
+		{Lat: MercatorMaxLat - 1e-6, Lng: 24.9384},
+		{Lat: -(MercatorMaxLat - 1e-6), Lng: -70},
+	}
+	const tolerance = 1e-9 // 度 (约 0.1 毫米)
+	for _, p := range points {
+		got := FromWebMercator(ToWebMercator(p))
+		if math.Abs(got.Lat-p.Lat) > tolerance || math.Abs(got.Lng-p.Lng) > tolerance {
+			t.Errorf("%v -> Mercator -> %v，超出容差 %g 度", p, got, tolerance)
+		}
+	}
+}
+
+func TestWebMercatorClampsLatitude(t *testing.T) {
+	const tolerance = 1e-9
+	for _, lat := range []float64{89.9, 90, -90} {
+		got := FromWebMercator(ToWebMercator(model.Point{Lat: lat, Lng: 10}))
+		want := math.Copysign(MercatorMaxLat, lat)
+		if math.Abs(got.Lat-want) > tolerance || math.Abs(got.Lng-10) > tolerance {
+			t.Errorf("纬度 %v: 往返后 = %v，期望纬度被限制为 %v", lat, got, want)
+		}
+	}
+	// 纬度上限对应的 Y 坐标等于赤道周长的一半 (瓦片地图为正方形)
+	if y := ToWebMercator(model.Point{Lat: MercatorMaxLat}).Y; math.Abs(y-math.Pi*EarthRadius) > 1e-3 {
+		t.Errorf("纬度上限的 Y = %.6f, 期望 %.6f", y, math.Pi*EarthRadius)
+	}
+}
+
+func TestLocalProjectionRoundTrip(t *testing.T) {
+	const tolerance = 1e-9 // 度
+	for _, o := range []model.Point{testOrigin, {Lat: 0, Lng: 0}, {Lat: 70, Lng: -150}, {Lat: -45, Lng: 170}} {
+		lp := NewLocalProjection(o)
+		for _, d := range []model.PointXY{{}, {X: 120, Y: -80}, {X: -5000, Y: 3000}, {X: 20000, Y: 20000}} {
+			p := lp.FromENU(d)
+			if back := lp.ToENU(p); math.Abs(back.X-d.X) > 1e-6 || math.Abs(back.Y-d.Y) > 1e-6 {
+				t.Errorf("原点 %v: %v -> 经纬度 -> %v，超出容差 1e-6 米", o, d, back)
+			}
+			if got := lp.FromENU(lp.ToENU(p)); math.Abs(got.Lat-p.Lat) > tolerance || math.Abs(got.Lng-p.Lng) > tolerance {
+				t.Errorf("原点 %v: %v -> ENU -> %v，超出容差 %g 度", o, p, got, tolerance)
+			}
+		}
+	}
+}
+
+func TestLocalProjectionDistance(t *testing.T) {
+	// 城市范围内平面距离与大圆距离的差别很小 (5 公里内小于 0.1%)
+	lp := NewLocalProjection(testOrigin)
+	for _, bearing := range []float64{0, 60, 135, 250} {
+		p := DestinationPoint(testOrigin, bearing, 5000)
+		xy := lp.ToENU(p)
+		if got := math.Hypot(xy.X, xy.Y); math.Abs(got-5000) > 5 {
+			t.Errorf("方位 %.0f: 平面距离 = %.3f, 期望约 5000", bearing, got)
+		}
+	}
+}
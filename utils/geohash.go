@@ -0,0 +1,80 @@
+package utils
+
+import "traffic-system/model"
+
+// Geohash 编码 (base32，经纬度交替二分)，相同前缀的点位于同一个网格
+
+// MaxGeohashPrecision 支持的最大 geohash 长度 (12 位约 4 厘米)
+const MaxGeohashPrecision = 12
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeohashEncode 计算点所在网格的 geohash (precision 为字符数，1 ~ 12)
+func GeohashEncode(lat, lng float64, precision int) string {
+	latMin, latMax := -90.0, 90.0
+	lngMin, lngMax := -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	even := true // 偶数位编码经度
+	bit, ch := 0, 0
+	for len(hash) < precision {
+		if even {
+			mid := (lngMin + lngMax) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				lngMin = mid
+			} else {
+				lngMax = mid
+			}
+		} else {
+			mid := (latMin + latMax) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latMin = mid
+			} else {
+				latMax = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// GeohashBounds 返回 geohash 网格的西南角和东北角坐标 (无效字符按 0 处理)
+func GeohashBounds(hash string) (sw, ne model.Point) {
+	latMin, latMax := -90.0, 90.0
+	lngMin, lngMax := -180.0, 180.0
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := 0
+		for j := 0; j < len(geohashBase32); j++ {
+			if geohashBase32[j] == hash[i] {
+				ch = j
+				break
+			}
+		}
+		for bit := 4; bit >= 0; bit-- {
+			on := ch&(1<<bit) != 0
+			if even {
+				mid := (lngMin + lngMax) / 2
+				if on {
+					lngMin = mid
+				} else {
+					lngMax = mid
+				}
+			} else {
+				mid := (latMin + latMax) / 2
+				if on {
+					latMin = mid
+				} else {
+					latMax = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return model.Point{Lat: latMin, Lng: lngMin}, model.Point{Lat: latMax, Lng: lngMax}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import "traffic-system/model"
+
+// 多边形相关计算
+// 城市范围内直接把经度、纬度当作平面坐标 (x = 经度, y = 纬度)，
+// 只判断位置关系 (在内/相交)，不涉及距离，因此不需要投影
+
+// PointInPolygon 判断点是否在多边形内 (射线法，多边形首尾不需要重复)
+func PointInPolygon(p model.Point, polygon []model.Point) bool {
+	inside := false
+	n := len(polygon)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// SegmentsIntersect 判断线段 p1-p2 与 p3-p4 是否相交 (包括端点接触)
+func SegmentsIntersect(p1, p2, p3, p4 model.Point) bool {
+	d1 := cross(p3, p4, p1)
+	d2 := cross(p3, p4, p2)
+	d3 := cross(p1, p2, p3)
+	d4 := cross(p1, p2, p4)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(p3, p4, p1)) ||
+		(d2 == 0 && onSegment(p3, p4, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, p3)) ||
+		(d4 == 0 && onSegment(p1, p2, p4))
+}
+
+// SegmentIntersectsPolygon 判断线段是否与多边形有交集 (端点在内部或穿过边界)
+func SegmentIntersectsPolygon(a, b model.Point, polygon []model.Point) bool {
+	if PointInPolygon(a, polygon) || PointInPolygon(b, polygon) {
+		return true
+	}
+	n := len(polygon)
+	for i := 0; i < n; i++ {
+		if SegmentsIntersect(a, b, polygon[i], polygon[(i+1)%n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// cross 向量 (b - a) 与 (c - a) 的叉积
+func cross(a, b, c model.Point) float64 {
+	return (b.Lng-a.Lng)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lng-a.Lng)
+}
+
+// onSegment 已知 c 与线段 a-b 共线时，判断 c 是否在线段范围内
+func onSegment(a, b, c model.Point) bool {
+	return min(a.Lng, b.Lng) <= c.Lng && c.Lng <= max(a.Lng, b.Lng) &&
+		min(a.Lat, b.Lat) <= c.Lat && c.Lat <= max(a.Lat, b.Lat)
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"math"
+	"traffic-system/model"
+)
+
+// 瓦片坐标 (Web Mercator / slippy map，与 Leaflet、OSM 的 z/x/y 相同)
+
+// TileSize 瓦片边长 (像素)
+const TileSize = 256
+
+// mercatorHalfWorld Web Mercator 平面的半宽 (米)，瓦片 0/0/0 覆盖 [-mercatorHalfWorld, mercatorHalfWorld]
+const mercatorHalfWorld = math.Pi * EarthRadius
+
+// TileBounds 瓦片的西南角和东北角坐标
+func TileBounds(z, x, y int) (sw, ne model.Point) {
+	n := math.Exp2(float64(z))
+	corner := func(x, y int) model.Point {
+		lat := FromWebMercator(model.PointXY{Y: mercatorHalfWorld * (1 - 2*float64(y)/n)}).Lat
+		return model.Point{Lat: lat, Lng: float64(x)/n*360 - 180} // 经度直接按比例计算，边界是精确的 ±180
+	}
+	return corner(x, y+1), corner(x+1, y)
+}
+
+// TileForPoint 点所在的瓦片坐标
+func TileForPoint(p model.Point, z int) (x, y int) {
+	n := math.Exp2(float64(z))
+	xy := ToWebMercator(p)
+	x = int(math.Floor((xy.X + mercatorHalfWorld) / (2 * mercatorHalfWorld) * n))
+	y = int(math.Floor((mercatorHalfWorld - xy.Y) / (2 * mercatorHalfWorld) * n))
+	last := int(n) - 1
+	return max(0, min(x, last)), max(0, min(y, last))
+}
+
+// MercatorPixelSize 指定缩放级别下一个像素在 Web Mercator 平面上的边长 (米，不随纬度变化)
+func MercatorPixelSize(z int) float64 {
+	return 2 * mercatorHalfWorld / (TileSize * math.Exp2(float64(z)))
+}
+
+// MetersPerPixel 指定缩放级别下一个像素对应的地面距离 (米)
+func MetersPerPixel(z int, lat float64) float64 {
+	return MercatorPixelSize(z) * math.Cos(DegreesToRadians(lat))
+}
@@ -0,0 +1,199 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+	"traffic-system/config"
+)
+
+// 天气数据
+// 路径规划时查询起点附近的当前天气，下雨、下雪时按配置降低步行和骑行的速度，并尽量不选骑行
+
+// 天气状况
+const (
+	ConditionClear  = "clear"
+	ConditionCloudy = "cloudy"
+	ConditionFog    = "fog"
+	ConditionRain   = "rain"
+	ConditionSnow   = "snow"
+)
+
+// Conditions 某个位置的当前天气
+type Conditions struct {
+	Condition     string  `json:"condition"`               // 见 Condition* 常量
+	Precipitation float64 `json:"precipitation,omitempty"` // 降水量 (毫米/小时)
+}
+
+// Wet 是否有降水 (雨或雪)
+func (c *Conditions) Wet() bool {
+	return c != nil && (c.Condition == ConditionRain || c.Condition == ConditionSnow || c.Precipitation > 0)
+}
+
+// Provider 天气数据接口 (可替换为其他天气服务或测试用实现)
+type Provider interface {
+	// Current 查询 (lat, lng) 附近的当前天气
+	Current(ctx context.Context, lat, lng float64) (*Conditions, error)
+}
+
+// Default 全局天气数据源，为空表示未启用 (应在 main 中通过 Init 初始化)
+var Default Provider
+
+// httpClient 调用天气服务使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+// Init 根据环境变量选择天气数据源：
+// WEATHER_PROVIDER 为 open-meteo 时查询 Open-Meteo (WEATHER_URL)，为 static 时固定为 WEATHER_STATIC_CONDITION (用于测试和手动设置)，为空时不启用
+func Init() {
+	var p Provider
+	switch name := config.GetString("WEATHER_PROVIDER", ""); name {
+	case "":
+		return
+	case "open-meteo":
+		p = &OpenMeteo{URL: config.GetString("WEATHER_URL", "https://api.open-meteo.com/v1/forecast")}
+	case "static":
+		p = &Static{Conditions: Conditions{
+			Condition:     config.GetString("WEATHER_STATIC_CONDITION", ConditionRain),
+			Precipitation: config.GetFloat("WEATHER_STATIC_PRECIPITATION", 0),
+		}}
+	default:
+		log.Fatalf("WEATHER_PROVIDER 配置错误: %s (可选 open-meteo、static)", name)
+	}
+	Default = NewCached(p, config.GetDuration("WEATHER_CACHE_TTL", 10*time.Minute))
+	slog.Info("已启用天气数据", "provider", config.GetString("WEATHER_PROVIDER", ""))
+}
+
+// Enabled 是否启用了天气数据
+func Enabled() bool {
+	return Default != nil
+}
+
+// Current 使用全局数据源查询当前天气；未启用时返回 nil
+func Current(ctx context.Context, lat, lng float64) (*Conditions, error) {
+	if Default == nil {
+		return nil, nil
+	}
+	return Default.Current(ctx, lat, lng)
+}
+
+// Static 固定的天气
+type Static struct {
+	Conditions Conditions
+}
+
+func (s *Static) Current(ctx context.Context, lat, lng float64) (*Conditions, error) {
+	c := s.Conditions
+	return &c, nil
+}
+
+// Cached 按约 0.1 度 (十公里左右) 的网格缓存天气，同一网格在 TTL 内只查询一次
+type Cached struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[[2]int]cachedEntry
+}
+
+// cachedEntry 一个网格的缓存
+type cachedEntry struct {
+	conditions *Conditions
+	expires    time.Time
+}
+
+// NewCached 创建带缓存的数据源，ttl <= 0 时不缓存
+func NewCached(p Provider, ttl time.Duration) *Cached {
+	return &Cached{Provider: p, TTL: ttl, entries: make(map[[2]int]cachedEntry)}
+}
+
+func (c *Cached) Current(ctx context.Context, lat, lng float64) (*Conditions, error) {
+	if c.TTL <= 0 {
+		return c.Provider.Current(ctx, lat, lng)
+	}
+	cell := [2]int{int(math.Floor(lat * 10)), int(math.Floor(lng * 10))}
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[cell]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.conditions, nil
+	}
+
+	conditions, err := c.Provider.Current(ctx, lat, lng)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for k, old := range c.entries { // 顺便清理过期的网格
+		if !now.Before(old.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[cell] = cachedEntry{conditions: conditions, expires: now.Add(c.TTL)}
+	c.mu.Unlock()
+	return conditions, nil
+}
+
+// OpenMeteo 通过 Open-Meteo 的预报接口查询当前天气 (免费，不需要 API Key)
+type OpenMeteo struct {
+	URL string
+}
+
+func (o *OpenMeteo) Current(ctx context.Context, lat, lng float64) (*Conditions, error) {
+	query := url.Values{
+		"latitude":  {strconv.FormatFloat(lat, 'f', 4, 64)},
+		"longitude": {strconv.FormatFloat(lng, 'f', 4, 64)},
+		"current":   {"precipitation,weather_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.URL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求天气服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("天气服务返回 %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Current struct {
+			Precipitation float64 `json:"precipitation"`
+			WeatherCode   int     `json:"weather_code"`
+		} `json:"current"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析天气数据失败: %w", err)
+	}
+	return &Conditions{
+		Condition:     wmoCondition(result.Current.WeatherCode),
+		Precipitation: result.Current.Precipitation,
+	}, nil
+}
+
+// wmoCondition 把 WMO 天气代码归为几类天气状况
+func wmoCondition(code int) string {
+	switch {
+	case code == 0:
+		return ConditionClear
+	case code <= 3:
+		return ConditionCloudy
+	case code == 45 || code == 48:
+		return ConditionFog
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return ConditionSnow
+	case code >= 51 && code <= 67, code >= 80 && code <= 82, code >= 95:
+		return ConditionRain
+	}
+	return ConditionCloudy
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"traffic-system/config"
+	"traffic-system/db"
+	"traffic-system/model"
+	"traffic-system/utils"
+)
+
+// 事件类型
+const (
+	EventPing            = "ping"             // 测试事件
+	EventImportCompleted = "import.completed" // 地图数据导入完成
+	EventMapActivated    = "map.activated"    // 新版本地图生效
+	EventTrafficUpdated  = "traffic.updated"  // 路段速度 (路况) 更新
+	EventIncidentCreated = "incident.created" // 新增交通事件
+	EventIncidentExpired = "incident.expired" // 交通事件结束
+	EventFeedbackCreated = "feedback.created" // 用户提交了路线或地图反馈
+)
+
+// Events 所有可以订阅的事件
+var Events = []string{
+	EventPing,
+	EventImportCompleted,
+	EventMapActivated,
+	EventTrafficUpdated,
+	EventIncidentCreated,
+	EventIncidentExpired,
+	EventFeedbackCreated,
+}
+
+// Payload 投递给订阅方的请求体
+type Payload struct {
+	ID        string      `json:"id"` // 投递 ID (重试时不变，订阅方可用于去重)
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// IsValidEvent 事件名称是否有效 ("*" 表示全部)
+func IsValidEvent(event string) bool {
+	if event == "*" {
+		return true
+	}
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch 向订阅了该事件的所有 Webhook 异步投递
+func Dispatch(event string, data interface{}) {
+	if db.DB == nil {
+		return
+	}
+	var hooks []model.Webhook
+	if err := db.DB.Where("active = ?", true).Find(&hooks).Error; err != nil {
+		slog.Error("查询 Webhook 失败", "error", err)
+		return
+	}
+
+	payload, err := newPayload(event, data)
+	if err != nil {
+		slog.Error("生成 Webhook 事件失败", "error", err)
+		return
+	}
+	for i := range hooks {
+		if hooks[i].Subscribes(event) {
+			go Deliver(&hooks[i], payload)
+		}
+	}
+}
+
+// Deliver 投递一次事件，失败时按指数退避重试 (WEBHOOK_MAX_RETRIES 次)
+// 返回最后一次的 HTTP 状态码和错误，并记录到 Webhook 上
+func Deliver(hook *model.Webhook, payload Payload) (int, error) {
+	return deliver(hook, payload, config.GetInt("WEBHOOK_MAX_RETRIES", 3))
+}
+
+func deliver(hook *model.Webhook, payload Payload, retries int) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	backoff := time.Second
+	var status int
+	for attempt := 0; ; attempt++ {
+		status, err = send(hook, payload, body)
+		if err == nil || attempt >= retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_status": status, "last_error": "", "last_delivered_at": now}
+	if err != nil {
+		updates["last_error"] = err.Error()
+		slog.Error("Webhook 投递失败", "id", hook.ID, "event", payload.Event, "error", err)
+	}
+	if db.DB != nil && hook.ID != 0 {
+		db.DB.Model(hook).Updates(updates)
+	}
+	return status, err
+}
+
+// send 发送一次请求
+// 签名为 HMAC-SHA256(secret, timestamp + "." + body)，订阅方应校验签名并拒绝时间戳过旧的请求
+func send(hook *model.Webhook, payload Payload, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-VV-Event", payload.Event)
+	req.Header.Set("X-VV-Delivery", payload.ID)
+	req.Header.Set("X-VV-Timestamp", timestamp)
+	req.Header.Set("X-VV-Signature", "sha256="+Sign(hook.Secret, timestamp, body))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("订阅方返回 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算请求签名 (十六进制)
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newPayload 生成事件请求体
+func newPayload(event string, data interface{}) (Payload, error) {
+	id, err := utils.GenerateToken(16)
+	if err != nil {
+		return Payload{}, err
+	}
+	return Payload{ID: id, Event: event, CreatedAt: time.Now(), Data: data}, nil
+}
+
+// Ping 同步发送测试事件，不重试 (用于管理员验证配置)
+func Ping(hook *model.Webhook) (int, error) {
+	payload, err := newPayload(EventPing, map[string]interface{}{"webhook_id": hook.ID})
+	if err != nil {
+		return 0, err
+	}
+	return deliver(hook, payload, 0)
+}